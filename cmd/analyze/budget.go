@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+)
+
+// BudgetConfig sets the budget that cost analysis is checked against
+type BudgetConfig struct {
+	Limit         float64           `json:"limit"`
+	Thresholds    []BudgetThreshold `json:"thresholds"`
+	Notifications []notify.Channel  `json:"notifications"`
+}
+
+// BudgetThreshold fires when spend reaches Percent of the budget. Basis is
+// "current" for spend to date or "forecast" for projected spend.
+type BudgetThreshold struct {
+	Percent float64 `json:"percent"`
+	Basis   string  `json:"basis"`
+}
+
+// BudgetAlert is a crossed budget threshold
+type BudgetAlert struct {
+	Threshold BudgetThreshold `json:"threshold"`
+	Spend     float64         `json:"spend"`
+	Percent   float64         `json:"percent"`
+	Severity  string          `json:"severity"`
+	Message   string          `json:"message"`
+}
+
+// defaultBudgetThresholds warn as spend approaches the budget and when the
+// forecast overruns it
+var defaultBudgetThresholds = []BudgetThreshold{
+	{Percent: 80, Basis: "current"},
+	{Percent: 100, Basis: "current"},
+	{Percent: 100, Basis: "forecast"},
+	{Percent: 120, Basis: "forecast"},
+}
+
+// evaluateBudget returns an alert for every threshold that current or
+// forecast spend has reached, most severe first
+func evaluateBudget(budget BudgetAnalysis) ([]BudgetAlert, error) {
+	if budget.BudgetLimit <= 0 {
+		return nil, nil
+	}
+
+	var alerts []BudgetAlert
+	for _, threshold := range budget.AlertThresholds {
+		var spend float64
+		switch threshold.Basis {
+		case "", "current":
+			threshold.Basis = "current"
+			spend = budget.CurrentSpend
+		case "forecast":
+			spend = budget.Forecast
+		default:
+			return nil, fmt.Errorf("unknown budget threshold basis %q (use current or forecast)", threshold.Basis)
+		}
+
+		percent := spend / budget.BudgetLimit * 100
+		if percent < threshold.Percent {
+			continue
+		}
+
+		severity := "warning"
+		if threshold.Percent >= 100 {
+			severity = "critical"
+		}
+
+		alerts = append(alerts, BudgetAlert{
+			Threshold: threshold,
+			Spend:     spend,
+			Percent:   percent,
+			Severity:  severity,
+			Message: fmt.Sprintf("%s spend of %.2f is %.1f%% of the %.2f budget (threshold %.0f%%)",
+				threshold.Basis, spend, percent, budget.BudgetLimit, threshold.Percent),
+		})
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].Threshold.Percent > alerts[j].Threshold.Percent
+	})
+	return alerts, nil
+}
+
+// budgetRecommendations turns budget alerts into findings
+func budgetRecommendations(alerts []BudgetAlert) []Recommendation {
+	var recommendations []Recommendation
+	for _, alert := range alerts {
+		priority := "medium"
+		if alert.Severity == "critical" {
+			priority = "critical"
+		}
+
+		recommendations = append(recommendations, Recommendation{
+			ID:          fmt.Sprintf("budget-%s-%.0f", alert.Threshold.Basis, alert.Threshold.Percent),
+			Type:        "cost",
+			Category:    "budget",
+			Priority:    priority,
+			Title:       fmt.Sprintf("Budget %s spend reached %.0f%%", alert.Threshold.Basis, alert.Threshold.Percent),
+			Description: alert.Message,
+			Actions:     []string{"Review top spenders and cost optimizations", "Adjust the budget or scale down non-critical resources"},
+			Timeline:    "immediate",
+			Details: map[string]interface{}{
+				"spend":     alert.Spend,
+				"percent":   alert.Percent,
+				"threshold": alert.Threshold.Percent,
+				"basis":     alert.Threshold.Basis,
+			},
+		})
+	}
+	return recommendations
+}
+
+// notifyBudgetAlerts sends one notification per alert to every channel. A
+// failed notification doesn't hold back the rest; the failures are returned
+// together.
+func notifyBudgetAlerts(ctx context.Context, channels []notify.Channel, projectID string, alerts []BudgetAlert) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, alert := range alerts {
+		err := notify.Send(ctx, channels, notify.Message{
+			Title:    fmt.Sprintf("Budget alert for %s", projectID),
+			Text:     alert.Message,
+			Severity: alert.Severity,
+			Source:   "analyze",
+			Fields: map[string]interface{}{
+				"project":   projectID,
+				"basis":     alert.Threshold.Basis,
+				"threshold": alert.Threshold.Percent,
+				"percent":   alert.Percent,
+			},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %g%% alert: %w", alert.Threshold.Basis, alert.Threshold.Percent, err))
+		}
+	}
+	return errors.Join(errs...)
+}
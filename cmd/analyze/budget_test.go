@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+)
+
+func TestEvaluateBudgetCurrentAndForecastThresholds(t *testing.T) {
+	// 85% spent to date, forecast at 110%
+	budget := BudgetAnalysis{
+		CurrentSpend:    850,
+		BudgetLimit:     1000,
+		Forecast:        1100,
+		AlertThresholds: defaultBudgetThresholds,
+	}
+
+	alerts, err := evaluateBudget(budget)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+
+	assert.Equal(t, BudgetThreshold{Percent: 100, Basis: "forecast"}, alerts[0].Threshold)
+	assert.Equal(t, "critical", alerts[0].Severity)
+	assert.InDelta(t, 110, alerts[0].Percent, 0.001)
+	assert.Equal(t, "forecast spend of 1100.00 is 110.0% of the 1000.00 budget (threshold 100%)", alerts[0].Message)
+
+	assert.Equal(t, BudgetThreshold{Percent: 80, Basis: "current"}, alerts[1].Threshold)
+	assert.Equal(t, "warning", alerts[1].Severity)
+	assert.InDelta(t, 850, alerts[1].Spend, 0.001)
+
+	recommendations := budgetRecommendations(alerts)
+	require.Len(t, recommendations, 2)
+	assert.Equal(t, "budget-forecast-100", recommendations[0].ID)
+	assert.Equal(t, "critical", recommendations[0].Priority)
+	assert.Equal(t, "budget", recommendations[1].Category)
+}
+
+func TestEvaluateBudgetBelowThresholds(t *testing.T) {
+	alerts, err := evaluateBudget(BudgetAnalysis{
+		CurrentSpend:    500,
+		BudgetLimit:     1000,
+		Forecast:        900,
+		AlertThresholds: defaultBudgetThresholds,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	// Without a budget there is nothing to evaluate
+	alerts, err = evaluateBudget(BudgetAnalysis{CurrentSpend: 500, AlertThresholds: defaultBudgetThresholds})
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	_, err = evaluateBudget(BudgetAnalysis{
+		CurrentSpend:    500,
+		BudgetLimit:     1000,
+		AlertThresholds: []BudgetThreshold{{Percent: 50, Basis: "yearly"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestNotifyBudgetAlerts(t *testing.T) {
+	var mu sync.Mutex
+	var received []notify.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.Message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	alerts, err := evaluateBudget(BudgetAnalysis{
+		CurrentSpend:    850,
+		BudgetLimit:     1000,
+		Forecast:        1100,
+		AlertThresholds: defaultBudgetThresholds,
+	})
+	require.NoError(t, err)
+
+	channels := []notify.Channel{{Type: "webhook", Config: map[string]interface{}{"url": server.URL}}}
+	require.NoError(t, notifyBudgetAlerts(context.Background(), channels, "my-project", alerts))
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "Budget alert for my-project", received[0].Title)
+	assert.Equal(t, "critical", received[0].Severity)
+	assert.Equal(t, "forecast", received[0].Fields["basis"])
+	assert.Equal(t, "current", received[1].Fields["basis"])
+}
+
+func TestNotifyBudgetAlertsKeepsGoingAfterAFailure(t *testing.T) {
+	var mu sync.Mutex
+	received := 0
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received++
+		mu.Unlock()
+	}))
+	defer working.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	alerts, err := evaluateBudget(BudgetAnalysis{
+		CurrentSpend:    850,
+		BudgetLimit:     1000,
+		Forecast:        1100,
+		AlertThresholds: defaultBudgetThresholds,
+	})
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+
+	channels := []notify.Channel{
+		{Type: "webhook", Config: map[string]interface{}{"url": failing.URL}},
+		{Type: "webhook", Config: map[string]interface{}{"url": working.URL}},
+	}
+	err = notifyBudgetAlerts(context.Background(), channels, "my-project", alerts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "forecast")
+	assert.Contains(t, err.Error(), "current")
+	assert.Equal(t, 2, received, "every alert still reaches the working channel")
+}
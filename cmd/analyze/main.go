@@ -3,16 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
 )
 
+// stdout receives progress output; emoji and colors are stripped from it
+// when color output is disabled
+var stdout io.Writer = os.Stdout
+
 type AnalysisConfig struct {
 	ProjectID    string                 `json:"project_id"`
 	Region       string                 `json:"region"`
@@ -22,6 +31,7 @@ type AnalysisConfig struct {
 	Timeframe    TimeframeConfig        `json:"timeframe"`
 	Analysis     AnalysisSettings       `json:"analysis"`
 	Output       OutputSettings         `json:"output"`
+	Budget       BudgetConfig           `json:"budget"`
 }
 
 type TimeframeConfig struct {
@@ -45,6 +55,8 @@ type OutputSettings struct {
 	IncludeGraphs bool   `json:"include_graphs"`
 	IncludeRaw    bool   `json:"include_raw"`
 	DetailLevel   string `json:"detail_level"`
+	// Webhook receives the analysis summary and recommendations
+	Webhook *notify.Webhook `json:"webhook,omitempty"`
 }
 
 type AnalysisResult struct {
@@ -61,6 +73,10 @@ type AnalysisResult struct {
 	Recommendations  []Recommendation               `json:"recommendations"`
 	Metrics          map[string]interface{}         `json:"metrics"`
 	RawData          map[string]interface{}         `json:"raw_data,omitempty"`
+	// TimedOut is set when the -timeout deadline cut the analysis short;
+	// Incomplete lists the analyses that did not finish
+	TimedOut   bool     `json:"timed_out,omitempty"`
+	Incomplete []string `json:"incomplete_analyses,omitempty"`
 }
 
 type AnalysisSummary struct {
@@ -117,11 +133,13 @@ type CostOptimizationItem struct {
 }
 
 type BudgetAnalysis struct {
-	CurrentSpend   float64 `json:"current_spend"`
-	BudgetLimit    float64 `json:"budget_limit"`
-	Utilization    float64 `json:"utilization"`
-	Forecast       float64 `json:"forecast"`
-	AlertThreshold float64 `json:"alert_threshold"`
+	CurrentSpend    float64           `json:"current_spend"`
+	BudgetLimit     float64           `json:"budget_limit"`
+	Utilization     float64           `json:"utilization"`
+	Forecast        float64           `json:"forecast"`
+	AlertThreshold  float64           `json:"alert_threshold"`
+	AlertThresholds []BudgetThreshold `json:"alert_thresholds,omitempty"`
+	Alerts          []BudgetAlert     `json:"alerts,omitempty"`
 }
 
 type PerformanceAnalysis struct {
@@ -448,6 +466,7 @@ func main() {
 	var (
 		configFile   = flag.String("config", "", "Path to analysis configuration file")
 		projectID    = flag.String("project", "", "GCP Project ID")
+		projectList  = flag.String("projects", "", "Comma-separated GCP project IDs to analyze concurrently into one combined report")
 		region       = flag.String("region", "us-central1", "GCP Region")
 		scope        = flag.String("scope", "all", "Analysis scope (all, compute, storage, network, iam, security)")
 		timeframe    = flag.Duration("timeframe", 24*time.Hour, "Analysis timeframe")
@@ -457,15 +476,51 @@ func main() {
 		security     = flag.Bool("security", true, "Include security analysis")
 		compliance   = flag.Bool("compliance", false, "Include compliance analysis")
 		optimize     = flag.Bool("optimize", true, "Include optimization recommendations")
-		format       = flag.String("format", "json", "Output format (json, text, html)")
+		format       = flag.String("format", "json", "Output format (json, text, html, prometheus)")
 		output       = flag.String("output", "", "Output file (default: stdout)")
+		outputDir    = flag.String("output-dir", "", "Write each section to its own file in this directory, with an index (json, html)")
 		verbose      = flag.Bool("verbose", false, "Enable verbose output")
 		parallel     = flag.Int("parallel", 4, "Number of parallel analysis operations")
 		timeout      = flag.Duration("timeout", 30*time.Minute, "Analysis timeout")
+		noColor      = flag.Bool("no-color", false, "Disable colored and emoji output (also honors NO_COLOR)")
+		webhookURL   = flag.String("webhook-url", "", "POST the analysis summary to this webhook (signed with $"+notify.WebhookSecretEnv+")")
+		webhookTmpl  = flag.String("webhook-template", "", "Template file rendering the webhook payload")
+		validateCfg  = flag.Bool("validate-config", false, "Validate the -config file against its schema and exit")
+		configSchema = flag.Bool("config-schema", false, "Print the JSON Schema of the config file and exit")
+		configFormat = flag.String("config-format", "auto", "Config file format (auto, json, yaml); auto uses the file extension")
 	)
 	flag.Parse()
 
-	if *projectID == "" {
+	if *configSchema {
+		configschema.Print(os.Stdout, AnalysisConfig{})
+		return
+	}
+	if *validateCfg {
+		os.Exit(configschema.ValidateFile(os.Stderr, *configFile, *configFormat, AnalysisConfig{}))
+	}
+
+	stdout = termcolor.NewWriter(os.Stdout, termcolor.Enabled(*noColor, os.Stdout))
+
+	if *output != "" && *outputDir != "" {
+		fmt.Fprintf(os.Stderr, "Error: -output and -output-dir are mutually exclusive\n")
+		os.Exit(1)
+	}
+	if *outputDir != "" && *format != "json" && *format != "html" {
+		fmt.Fprintf(os.Stderr, "Error: -output-dir supports the json and html formats\n")
+		os.Exit(1)
+	}
+
+	projects := parseProjects(*projectList)
+	if len(projects) > 0 {
+		if *outputDir != "" {
+			fmt.Fprintf(os.Stderr, "Error: -output-dir is not supported with -projects\n")
+			os.Exit(1)
+		}
+		if *format == "html" {
+			fmt.Fprintf(os.Stderr, "Error: -projects supports the json, text and prometheus formats\n")
+			os.Exit(1)
+		}
+	} else if *projectID == "" {
 		*projectID = os.Getenv("GCP_PROJECT_ID")
 		if *projectID == "" {
 			fmt.Fprintf(os.Stderr, "Error: Project ID must be specified via -project flag or GCP_PROJECT_ID environment variable\n")
@@ -477,18 +532,6 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
-	// Initialize GCP client
-	client, err := gcp.NewClient(ctx, &gcp.ClientConfig{
-		ProjectID: *projectID,
-		Region:    *region,
-		LogLevel:  getLogLevel(*verbose),
-	})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating GCP client: %v\n", err)
-		os.Exit(1)
-	}
-	defer client.Close()
-
 	// Load analysis configuration
 	var analysisConfig AnalysisConfig
 	if *configFile != "" {
@@ -498,7 +541,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := json.Unmarshal(configData, &analysisConfig); err != nil {
+		if err := configschema.DecodeFormat(configData, configschema.DetectFormat(*configFile, *configFormat), &analysisConfig); err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing config file: %v\n", err)
 			os.Exit(1)
 		}
@@ -514,6 +557,67 @@ func main() {
 	analysisConfig.Analysis.IncludeCompliance = *compliance
 	analysisConfig.Analysis.IncludeOptimization = *optimize
 	analysisConfig.Output.Format = *format
+	if *webhookURL != "" {
+		webhook, err := notify.NewWebhook(*webhookURL, *webhookTmpl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		analysisConfig.Output.Webhook = webhook
+	}
+
+	if len(projects) > 0 {
+		result := analyzeProjects(ctx, projects, *parallel, func(ctx context.Context, projectID string) (*AnalysisResult, error) {
+			client, err := newAnalysisClient(ctx, projectID, *region, *verbose)
+			if err != nil {
+				return nil, fmt.Errorf("creating GCP client: %w", err)
+			}
+			defer client.Close()
+
+			services, err := initializeAnalysisServices(client)
+			if err != nil {
+				return nil, fmt.Errorf("initializing services: %w", err)
+			}
+			config := analysisConfig
+			config.ProjectID = projectID
+			if *verbose {
+				fmt.Fprintf(stdout, "🔍 Starting analysis for project: %s\n", projectID)
+			}
+			// Projects already run concurrently, so each analyzes serially
+			return performAnalysis(ctx, services, &config, &analysisOptions{Parallel: 1, Verbose: *verbose})
+		})
+
+		outputFile := os.Stdout
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+			outputFile = file
+		}
+		outputMultiProjectResults(termcolor.NewReportWriter(outputFile, *format, termcolor.Enabled(*noColor, outputFile)), result, *format)
+
+		if webhook := analysisConfig.Output.Webhook; webhook != nil {
+			if err := webhook.Export(ctx, multiProjectWebhookPayload(result)); err != nil {
+				fmt.Fprintf(os.Stderr, "Webhook export failed: %v\n", err)
+			}
+		}
+		if result.Totals.Analyzed == 0 {
+			fmt.Fprintf(os.Stderr, "Analysis failed for all %d projects\n", result.Totals.Projects)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Initialize GCP client
+	client, err := newAnalysisClient(ctx, *projectID, *region, *verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating GCP client: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
 
 	// Initialize services
 	services, err := initializeAnalysisServices(client)
@@ -533,10 +637,11 @@ func main() {
 		defer file.Close()
 		outputFile = file
 	}
+	reportOut := termcolor.NewReportWriter(outputFile, *format, termcolor.Enabled(*noColor, outputFile))
 
 	if *verbose {
-		fmt.Printf("🔍 Starting analysis for project: %s\n", analysisConfig.ProjectID)
-		fmt.Printf("📊 Scope: %s, Depth: %s, Timeframe: %s\n",
+		fmt.Fprintf(stdout, "🔍 Starting analysis for project: %s\n", analysisConfig.ProjectID)
+		fmt.Fprintf(stdout, "📊 Scope: %s, Depth: %s, Timeframe: %s\n",
 			strings.Join(analysisConfig.Scope, ","), analysisConfig.Analysis.AnalysisDepth, *timeframe)
 	}
 
@@ -551,12 +656,49 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *verbose {
-		fmt.Printf("✅ Analysis completed in %v\n", time.Since(startTime))
+	if result.TimedOut {
+		fmt.Fprintf(os.Stderr, "Analysis timed out after %v; incomplete: %s\n", *timeout, strings.Join(result.Incomplete, ", "))
+	} else if *verbose {
+		fmt.Fprintf(stdout, "✅ Analysis completed in %v\n", time.Since(startTime))
 	}
 
 	// Output results
-	outputAnalysisResults(outputFile, result, *format, *verbose)
+	if *outputDir != "" {
+		written, err := writeAnalysisDir(*outputDir, result, *format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			os.Exit(1)
+		}
+		if *verbose {
+			fmt.Fprintf(stdout, "📁 Wrote %d files to %s\n", len(written), *outputDir)
+		}
+	} else {
+		outputAnalysisResults(reportOut, result, *format, *verbose)
+	}
+
+	if webhook := analysisConfig.Output.Webhook; webhook != nil {
+		if err := webhook.Export(ctx, analysisWebhookPayload(result)); err != nil {
+			fmt.Fprintf(os.Stderr, "Webhook export failed: %v\n", err)
+		}
+	}
+}
+
+// analysisWebhookPayload is the part of an analysis exported to webhooks
+func analysisWebhookPayload(result *AnalysisResult) map[string]interface{} {
+	return map[string]interface{}{
+		"project_id":      result.ProjectID,
+		"timestamp":       result.Timestamp,
+		"summary":         result.Summary,
+		"recommendations": result.Recommendations,
+	}
+}
+
+func newAnalysisClient(ctx context.Context, projectID, region string, verbose bool) (*gcp.Client, error) {
+	return gcp.NewClient(ctx, &gcp.ClientConfig{
+		ProjectID: projectID,
+		Region:    region,
+		LogLevel:  getLogLevel(verbose),
+	})
 }
 
 type analysisServices struct {
@@ -689,67 +831,79 @@ func performAnalysis(ctx context.Context, services *analysisServices, config *An
 	// Build resource inventory
 	inventory, err := buildResourceInventory(ctx, services, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build resource inventory: %v", err)
+		if ctx.Err() == nil {
+			return nil, fmt.Errorf("failed to build resource inventory: %v", err)
+		}
+		result.markIncomplete(ctx, "inventory")
 	}
 	result.ResourceInventory = inventory
 
-	// Perform cost analysis
-	if config.Analysis.IncludeCosts {
-		costAnalysis, err := performCostAnalysis(ctx, services, config, inventory)
-		if err != nil {
-			if opts.Verbose {
-				fmt.Printf("⚠️ Cost analysis failed: %v\n", err)
+	steps := []struct {
+		name    string
+		title   string
+		enabled bool
+		run     func() error
+	}{
+		{"cost", "Cost", config.Analysis.IncludeCosts, func() error {
+			costAnalysis, err := performCostAnalysis(ctx, services, config, inventory)
+			if err != nil {
+				return err
 			}
-		} else {
 			result.CostAnalysis = costAnalysis
-		}
-	}
 
-	// Perform performance analysis
-	if config.Analysis.IncludePerformance {
-		perfAnalysis, err := performPerformanceAnalysis(ctx, services, config, inventory)
-		if err != nil {
-			if opts.Verbose {
-				fmt.Printf("⚠️ Performance analysis failed: %v\n", err)
+			alerts := costAnalysis.BudgetAnalysis.Alerts
+			if err := notifyBudgetAlerts(ctx, config.Budget.Notifications, config.ProjectID, alerts); err != nil {
+				fmt.Fprintf(stdout, "⚠️ Budget notification failed: %v\n", err)
 			}
-		} else {
-			result.PerformanceData = perfAnalysis
-		}
-	}
-
-	// Perform security analysis
-	if config.Analysis.IncludeSecurity {
-		secAnalysis, err := performSecurityAnalysis(ctx, services, config, inventory)
-		if err != nil {
-			if opts.Verbose {
-				fmt.Printf("⚠️ Security analysis failed: %v\n", err)
+			return nil
+		}},
+		{"performance", "Performance", config.Analysis.IncludePerformance, func() error {
+			perfAnalysis, err := performPerformanceAnalysis(ctx, services, config, inventory)
+			if err == nil {
+				result.PerformanceData = perfAnalysis
 			}
-		} else {
-			result.SecurityFindings = secAnalysis
-		}
-	}
-
-	// Perform compliance analysis
-	if config.Analysis.IncludeCompliance {
-		compAnalysis, err := performComplianceAnalysis(ctx, services, config, inventory)
-		if err != nil {
-			if opts.Verbose {
-				fmt.Printf("⚠️ Compliance analysis failed: %v\n", err)
+			return err
+		}},
+		{"security", "Security", config.Analysis.IncludeSecurity, func() error {
+			secAnalysis, err := performSecurityAnalysis(ctx, services, config, inventory)
+			if err == nil {
+				result.SecurityFindings = secAnalysis
 			}
-		} else {
-			result.ComplianceReport = compAnalysis
-		}
+			return err
+		}},
+		{"compliance", "Compliance", config.Analysis.IncludeCompliance, func() error {
+			compAnalysis, err := performComplianceAnalysis(ctx, services, config, inventory)
+			if err == nil {
+				result.ComplianceReport = compAnalysis
+			}
+			return err
+		}},
+		{"optimization", "Optimization", config.Analysis.IncludeOptimization, func() error {
+			optAnalysis, err := performOptimizationAnalysis(ctx, services, config, inventory)
+			if err == nil {
+				result.Optimization = optAnalysis
+			}
+			return err
+		}},
 	}
 
-	// Perform optimization analysis
-	if config.Analysis.IncludeOptimization {
-		optAnalysis, err := performOptimizationAnalysis(ctx, services, config, inventory)
-		if err != nil {
-			if opts.Verbose {
-				fmt.Printf("⚠️ Optimization analysis failed: %v\n", err)
+	// Run the enabled analyses in order. Once the context is done the
+	// remaining ones are skipped and reported as incomplete, so a timeout
+	// still yields whatever finished in time.
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		if ctx.Err() != nil {
+			result.markIncomplete(ctx, step.name)
+			continue
+		}
+		if err := step.run(); err != nil {
+			if ctx.Err() != nil {
+				result.markIncomplete(ctx, step.name)
+			} else if opts.Verbose {
+				fmt.Fprintf(stdout, "⚠️ %s analysis failed: %v\n", step.title, err)
 			}
-		} else {
-			result.Optimization = optAnalysis
 		}
 	}
 
@@ -768,6 +922,14 @@ func performAnalysis(ctx context.Context, services *analysisServices, config *An
 	return result, nil
 }
 
+// markIncomplete records an analysis the context cut short
+func (r *AnalysisResult) markIncomplete(ctx context.Context, name string) {
+	r.Incomplete = append(r.Incomplete, name)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		r.TimedOut = true
+	}
+}
+
 func buildResourceInventory(ctx context.Context, services *analysisServices, config *AnalysisConfig) (map[string]ResourceInventory, error) {
 	inventory := make(map[string]ResourceInventory)
 
@@ -775,6 +937,9 @@ func buildResourceInventory(ctx context.Context, services *analysisServices, con
 	// In a real implementation, this would query all GCP services
 
 	if containsScope(config.Scope, "compute") {
+		if err := ctx.Err(); err != nil {
+			return inventory, err
+		}
 		inventory["compute"] = ResourceInventory{
 			Count: 15,
 			Resources: []ResourceDetails{
@@ -800,6 +965,9 @@ func buildResourceInventory(ctx context.Context, services *analysisServices, con
 	}
 
 	if containsScope(config.Scope, "storage") {
+		if err := ctx.Err(); err != nil {
+			return inventory, err
+		}
 		inventory["storage"] = ResourceInventory{
 			Count: 8,
 			Resources: []ResourceDetails{
@@ -823,6 +991,7 @@ func buildResourceInventory(ctx context.Context, services *analysisServices, con
 		}
 	}
 
+	resolveReferences(inventory)
 	return inventory, nil
 }
 
@@ -839,7 +1008,11 @@ func performCostAnalysis(ctx context.Context, services *analysisServices, config
 	// Simulated cost analysis
 	// In a real implementation, this would use the Billing API
 
-	return &CostAnalysis{
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	costs := &CostAnalysis{
 		CurrentCosts: CostBreakdown{
 			Total:     1250.75,
 			ByService: map[string]float64{
@@ -888,13 +1061,34 @@ func performCostAnalysis(ctx context.Context, services *analysisServices, config
 			Forecast:       1380.50,
 			AlertThreshold: 80.0,
 		},
-	}, nil
+	}
+
+	budget := &costs.BudgetAnalysis
+	if config.Budget.Limit > 0 {
+		budget.BudgetLimit = config.Budget.Limit
+		budget.Utilization = budget.CurrentSpend / budget.BudgetLimit * 100
+	}
+	budget.AlertThresholds = config.Budget.Thresholds
+	if len(budget.AlertThresholds) == 0 {
+		budget.AlertThresholds = defaultBudgetThresholds
+	}
+	alerts, err := evaluateBudget(*budget)
+	if err != nil {
+		return nil, err
+	}
+	budget.Alerts = alerts
+
+	return costs, nil
 }
 
 func performPerformanceAnalysis(ctx context.Context, services *analysisServices, config *AnalysisConfig, inventory map[string]ResourceInventory) (*PerformanceAnalysis, error) {
 	// Simulated performance analysis
 	// In a real implementation, this would query monitoring metrics
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return &PerformanceAnalysis{
 		Overview: PerformanceOverview{
 			OverallScore: 87.5,
@@ -938,6 +1132,10 @@ func performSecurityAnalysis(ctx context.Context, services *analysisServices, co
 	// Simulated security analysis
 	// In a real implementation, this would use Security Command Center
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return &SecurityAnalysis{
 		Overview: SecurityOverview{
 			SecurityScore: 82.5,
@@ -992,6 +1190,10 @@ func performComplianceAnalysis(ctx context.Context, services *analysisServices,
 	// Simulated compliance analysis
 	// In a real implementation, this would check against compliance frameworks
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return &ComplianceAnalysis{
 		Frameworks: []ComplianceFramework{
 			{
@@ -1036,6 +1238,10 @@ func performOptimizationAnalysis(ctx context.Context, services *analysisServices
 	// Simulated optimization analysis
 	// In a real implementation, this would use Recommender API
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return &OptimizationAnalysis{
 		Overview: OptimizationOverview{
 			TotalOpportunities:  25,
@@ -1172,6 +1378,10 @@ func generateRecommendations(result *AnalysisResult) []Recommendation {
 		}
 	}
 
+	if result.CostAnalysis != nil {
+		recommendations = append(recommendations, budgetRecommendations(result.CostAnalysis.BudgetAnalysis.Alerts)...)
+	}
+
 	// Generate security recommendations
 	if result.SecurityFindings != nil {
 		for _, finding := range result.SecurityFindings.VulnerabilityFindings {
@@ -1209,7 +1419,7 @@ func generateRecommendations(result *AnalysisResult) []Recommendation {
 	return recommendations
 }
 
-func outputAnalysisResults(file *os.File, result *AnalysisResult, format string, verbose bool) {
+func outputAnalysisResults(file io.Writer, result *AnalysisResult, format string, verbose bool) {
 	switch format {
 	case "json":
 		output, _ := json.MarshalIndent(result, "", "  ")
@@ -1218,15 +1428,25 @@ func outputAnalysisResults(file *os.File, result *AnalysisResult, format string,
 		printAnalysisTextResults(file, result, verbose)
 	case "html":
 		printAnalysisHTMLResults(file, result)
+	case "prometheus":
+		writePrometheusMetrics(file, []*AnalysisResult{result})
 	}
 }
 
-func printAnalysisTextResults(file *os.File, result *AnalysisResult, verbose bool) {
+func printAnalysisTextResults(file io.Writer, result *AnalysisResult, verbose bool) {
 	timestamp := result.Timestamp.Format("2006-01-02 15:04:05")
 	fmt.Fprintf(file, "🔍 Analysis Report - %s\n", timestamp)
 	fmt.Fprintf(file, "📍 Project: %s\n", result.ProjectID)
 	fmt.Fprintf(file, "🎯 Scope: %s\n\n", strings.Join(result.AnalysisScope, ", "))
 
+	if len(result.Incomplete) > 0 {
+		reason := "cancelled"
+		if result.TimedOut {
+			reason = "timed out"
+		}
+		fmt.Fprintf(file, "⏱️  Analysis %s; incomplete: %s\n\n", reason, strings.Join(result.Incomplete, ", "))
+	}
+
 	// Overall summary
 	fmt.Fprintf(file, "📊 Overall Summary:\n")
 	fmt.Fprintf(file, "  Resources: %d\n", result.Summary.TotalResources)
@@ -1301,7 +1521,7 @@ func printAnalysisTextResults(file *os.File, result *AnalysisResult, verbose boo
 	}
 }
 
-func printAnalysisHTMLResults(file *os.File, result *AnalysisResult) {
+func printAnalysisHTMLResults(file io.Writer, result *AnalysisResult) {
 	// Simplified HTML output
 	html := `<!DOCTYPE html>
 <html>
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+)
+
+func testAnalysisConfig() AnalysisConfig {
+	config := getDefaultAnalysisConfig("my-project", "us-central1", "all", 24*time.Hour, "standard")
+	config.Analysis.IncludeCompliance = true
+	return config
+}
+
+func TestPerformAnalysisStopsAtDeadline(t *testing.T) {
+	stdout = io.Discard
+	defer func() { stdout = os.Stdout }()
+
+	// The budget webhook hangs until its request is cancelled, so the
+	// deadline passes partway through the analysis
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	config := testAnalysisConfig()
+	config.Budget.Notifications = []notify.Channel{{Type: "webhook", Config: map[string]interface{}{"url": server.URL}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := performAnalysis(ctx, &analysisServices{}, &config, &analysisOptions{})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	assert.NotNil(t, result.CostAnalysis)
+	assert.Nil(t, result.PerformanceData)
+	assert.Nil(t, result.Optimization)
+	assert.True(t, result.TimedOut)
+	assert.Equal(t, []string{"performance", "security", "compliance", "optimization"}, result.Incomplete)
+	assert.NotEmpty(t, result.ResourceInventory)
+}
+
+func TestPerformAnalysisCancelled(t *testing.T) {
+	config := testAnalysisConfig()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := performAnalysis(ctx, &analysisServices{}, &config, &analysisOptions{})
+	require.NoError(t, err)
+	assert.False(t, result.TimedOut)
+	assert.Equal(t, []string{"inventory", "cost", "performance", "security", "compliance", "optimization"}, result.Incomplete)
+	assert.Nil(t, result.CostAnalysis)
+}
+
+func TestPerformAnalysisCompletes(t *testing.T) {
+	config := testAnalysisConfig()
+
+	result, err := performAnalysis(context.Background(), &analysisServices{}, &config, &analysisOptions{})
+	require.NoError(t, err)
+	assert.False(t, result.TimedOut)
+	assert.Empty(t, result.Incomplete)
+	assert.NotNil(t, result.ComplianceReport)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProjectAnalysis is the outcome of analyzing one project of a
+// multi-project run
+type ProjectAnalysis struct {
+	ProjectID string          `json:"project_id"`
+	Result    *AnalysisResult `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// OrgTotals adds up the projects that were analyzed
+type OrgTotals struct {
+	Projects        int            `json:"projects"`
+	Analyzed        int            `json:"analyzed"`
+	Failed          int            `json:"failed"`
+	TotalResources  int            `json:"total_resources"`
+	ResourcesByType map[string]int `json:"resources_by_type"`
+	TotalCost       float64        `json:"total_cost"`
+	IssueCount      map[string]int `json:"issue_count"`
+	// AverageHealthScore weighs every analyzed project equally
+	AverageHealthScore float64 `json:"average_health_score"`
+	Recommendations    int     `json:"recommendations"`
+}
+
+// MultiProjectResult is the combined report of -projects
+type MultiProjectResult struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Projects  []ProjectAnalysis `json:"projects"`
+	Totals    OrgTotals         `json:"totals"`
+}
+
+// projectAnalyzer analyzes a single project
+type projectAnalyzer func(ctx context.Context, projectID string) (*AnalysisResult, error)
+
+// parseProjects splits the -projects list, dropping blanks and duplicates
+func parseProjects(value string) []string {
+	var projects []string
+	seen := make(map[string]bool)
+	for _, project := range strings.Split(value, ",") {
+		project = strings.TrimSpace(project)
+		if project != "" && !seen[project] {
+			seen[project] = true
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// analyzeProjects runs analyze for each project, at most parallel at a
+// time. A project that fails, for instance for lack of permission, is
+// recorded with its error and the others carry on.
+func analyzeProjects(ctx context.Context, projects []string, parallel int, analyze projectAnalyzer) *MultiProjectResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	result := &MultiProjectResult{
+		Timestamp: time.Now(),
+		Projects:  make([]ProjectAnalysis, len(projects)),
+	}
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func(i int, project string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			analysis := ProjectAnalysis{ProjectID: project}
+			res, err := analyze(ctx, project)
+			if err != nil {
+				analysis.Error = err.Error()
+			} else {
+				analysis.Result = res
+			}
+			result.Projects[i] = analysis
+		}(i, project)
+	}
+	wg.Wait()
+
+	result.Totals = aggregateProjects(result.Projects)
+	return result
+}
+
+func aggregateProjects(projects []ProjectAnalysis) OrgTotals {
+	totals := OrgTotals{
+		Projects:        len(projects),
+		ResourcesByType: make(map[string]int),
+		IssueCount:      make(map[string]int),
+	}
+	healthSum := 0.0
+	for _, project := range projects {
+		if project.Result == nil {
+			totals.Failed++
+			continue
+		}
+		totals.Analyzed++
+		summary := project.Result.Summary
+		totals.TotalResources += summary.TotalResources
+		for resourceType, count := range summary.ResourcesByType {
+			totals.ResourcesByType[resourceType] += count
+		}
+		for severity, count := range summary.IssueCount {
+			totals.IssueCount[severity] += count
+		}
+		totals.TotalCost += summary.TotalCost
+		healthSum += summary.OverallHealthScore
+		totals.Recommendations += len(project.Result.Recommendations)
+	}
+	if totals.Analyzed > 0 {
+		totals.AverageHealthScore = healthSum / float64(totals.Analyzed)
+	}
+	return totals
+}
+
+func printMultiProjectText(file io.Writer, result *MultiProjectResult) {
+	fmt.Fprintf(file, "🔍 Multi-Project Analysis Report - %s\n\n", result.Timestamp.Format("2006-01-02 15:04:05"))
+
+	fmt.Fprintf(file, "📋 Projects:\n")
+	for _, project := range result.Projects {
+		if project.Result == nil {
+			fmt.Fprintf(file, "  ❌ %s: %s\n", project.ProjectID, project.Error)
+			continue
+		}
+		summary := project.Result.Summary
+		fmt.Fprintf(file, "  ✅ %s: %d resources, health %.1f%%, $%.2f/month\n",
+			project.ProjectID, summary.TotalResources, summary.OverallHealthScore, summary.TotalCost)
+	}
+	fmt.Fprintln(file)
+
+	totals := result.Totals
+	fmt.Fprintf(file, "📊 Totals (%d of %d projects analyzed):\n", totals.Analyzed, totals.Projects)
+	fmt.Fprintf(file, "  Resources: %d\n", totals.TotalResources)
+	fmt.Fprintf(file, "  Average Health Score: %.1f%%\n", totals.AverageHealthScore)
+	fmt.Fprintf(file, "  Monthly Cost: $%.2f\n", totals.TotalCost)
+	if len(totals.IssueCount) > 0 {
+		severities := make([]string, 0, len(totals.IssueCount))
+		for severity := range totals.IssueCount {
+			severities = append(severities, severity)
+		}
+		sort.Strings(severities)
+		var parts []string
+		for _, severity := range severities {
+			parts = append(parts, fmt.Sprintf("%s %d", severity, totals.IssueCount[severity]))
+		}
+		fmt.Fprintf(file, "  Issues: %s\n", strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(file, "  Recommendations: %d\n", totals.Recommendations)
+}
+
+func outputMultiProjectResults(file io.Writer, result *MultiProjectResult, format string) {
+	switch format {
+	case "json":
+		output, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(file, string(output))
+	case "text":
+		printMultiProjectText(file, result)
+	case "prometheus":
+		var analyzed []*AnalysisResult
+		for _, project := range result.Projects {
+			if project.Result != nil {
+				analyzed = append(analyzed, project.Result)
+			}
+		}
+		writePrometheusMetrics(file, analyzed)
+	}
+}
+
+// multiProjectWebhookPayload is the part of a multi-project analysis
+// exported to webhooks
+func multiProjectWebhookPayload(result *MultiProjectResult) map[string]interface{} {
+	projects := make([]map[string]interface{}, 0, len(result.Projects))
+	for _, project := range result.Projects {
+		entry := map[string]interface{}{"project_id": project.ProjectID}
+		if project.Result != nil {
+			entry["summary"] = project.Result.Summary
+		} else {
+			entry["error"] = project.Error
+		}
+		projects = append(projects, entry)
+	}
+	return map[string]interface{}{
+		"timestamp": result.Timestamp,
+		"projects":  projects,
+		"totals":    result.Totals,
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeProjectResult(projectID string, resources int, cost, health float64) *AnalysisResult {
+	return &AnalysisResult{
+		ProjectID: projectID,
+		Summary: AnalysisSummary{
+			TotalResources:     resources,
+			ResourcesByType:    map[string]int{"compute_instance": resources - 1, "storage_bucket": 1},
+			TotalCost:          cost,
+			OverallHealthScore: health,
+			IssueCount:         map[string]int{"high": 1, "low": resources},
+		},
+		Recommendations: []Recommendation{{ID: projectID + "-rec"}},
+	}
+}
+
+func TestAnalyzeProjectsAggregates(t *testing.T) {
+	fakes := map[string]*AnalysisResult{
+		"proj-a": fakeProjectResult("proj-a", 3, 100, 80),
+		"proj-b": fakeProjectResult("proj-b", 5, 50.5, 60),
+	}
+	analyze := func(ctx context.Context, projectID string) (*AnalysisResult, error) {
+		return fakes[projectID], nil
+	}
+
+	result := analyzeProjects(context.Background(), []string{"proj-a", "proj-b"}, 2, analyze)
+
+	require.Len(t, result.Projects, 2)
+	assert.Equal(t, "proj-a", result.Projects[0].ProjectID)
+	assert.Equal(t, "proj-b", result.Projects[1].ProjectID)
+	assert.Same(t, fakes["proj-b"], result.Projects[1].Result)
+
+	totals := result.Totals
+	assert.Equal(t, 2, totals.Projects)
+	assert.Equal(t, 2, totals.Analyzed)
+	assert.Equal(t, 0, totals.Failed)
+	assert.Equal(t, 8, totals.TotalResources)
+	assert.Equal(t, map[string]int{"compute_instance": 6, "storage_bucket": 2}, totals.ResourcesByType)
+	assert.InDelta(t, 150.5, totals.TotalCost, 0.001)
+	assert.InDelta(t, 70, totals.AverageHealthScore, 0.001)
+	assert.Equal(t, map[string]int{"high": 2, "low": 8}, totals.IssueCount)
+	assert.Equal(t, 2, totals.Recommendations)
+}
+
+func TestAnalyzeProjectsIsolatesFailures(t *testing.T) {
+	var calls atomic.Int32
+	analyze := func(ctx context.Context, projectID string) (*AnalysisResult, error) {
+		calls.Add(1)
+		if projectID == "proj-denied" {
+			return nil, errors.New("permission denied: compute.instances.list")
+		}
+		return fakeProjectResult(projectID, 4, 20, 90), nil
+	}
+
+	result := analyzeProjects(context.Background(), []string{"proj-denied", "proj-ok"}, 1, analyze)
+
+	assert.EqualValues(t, 2, calls.Load())
+	require.Len(t, result.Projects, 2)
+	denied := result.Projects[0]
+	assert.Nil(t, denied.Result)
+	assert.Contains(t, denied.Error, "permission denied")
+	assert.NotNil(t, result.Projects[1].Result)
+	assert.Empty(t, result.Projects[1].Error)
+
+	// Totals only count the project that was analyzed
+	totals := result.Totals
+	assert.Equal(t, 1, totals.Analyzed)
+	assert.Equal(t, 1, totals.Failed)
+	assert.Equal(t, 4, totals.TotalResources)
+	assert.InDelta(t, 90, totals.AverageHealthScore, 0.001)
+
+	var text bytes.Buffer
+	outputMultiProjectResults(&text, result, "text")
+	assert.Contains(t, text.String(), "❌ proj-denied: permission denied")
+	assert.Contains(t, text.String(), "✅ proj-ok: 4 resources")
+	assert.Contains(t, text.String(), "1 of 2 projects analyzed")
+
+	var out bytes.Buffer
+	outputMultiProjectResults(&out, result, "json")
+	var decoded MultiProjectResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, denied.Error, decoded.Projects[0].Error)
+	assert.Equal(t, 1, decoded.Totals.Failed)
+}
+
+func TestAnalyzeProjectsWithPerformAnalysis(t *testing.T) {
+	analyze := func(ctx context.Context, projectID string) (*AnalysisResult, error) {
+		config := testAnalysisConfig()
+		config.ProjectID = projectID
+		config.Analysis.IncludePerformance = false
+		return performAnalysis(ctx, &analysisServices{}, &config, &analysisOptions{Parallel: 1})
+	}
+
+	result := analyzeProjects(context.Background(), []string{"proj-a", "proj-b"}, 2, analyze)
+
+	require.Equal(t, 2, result.Totals.Analyzed)
+	assert.Equal(t, "proj-a", result.Projects[0].Result.ProjectID)
+	assert.Equal(t, "proj-b", result.Projects[1].Result.ProjectID)
+	assert.Equal(t, result.Projects[0].Result.Summary.TotalResources+result.Projects[1].Result.Summary.TotalResources,
+		result.Totals.TotalResources)
+}
+
+func TestParseProjects(t *testing.T) {
+	assert.Equal(t, []string{"proj-a", "proj-b"}, parseProjects(" proj-a, ,proj-b,proj-a"))
+	assert.Empty(t, parseProjects(""))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// reportSection is one part of an analysis written to its own file by
+// -output-dir
+type reportSection struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	File  string `json:"file"`
+	data  interface{}
+}
+
+// reportIndex is index.json of an -output-dir report
+type reportIndex struct {
+	Timestamp     time.Time       `json:"timestamp"`
+	ProjectID     string          `json:"project_id"`
+	AnalysisScope []string        `json:"analysis_scope"`
+	Summary       AnalysisSummary `json:"summary"`
+	TimedOut      bool            `json:"timed_out,omitempty"`
+	Incomplete    []string        `json:"incomplete_analyses,omitempty"`
+	Sections      []reportSection `json:"sections"`
+}
+
+// reportSections lists the sections of result that have data, in report
+// order. Inventory and recommendations are always written, even if empty.
+func reportSections(result *AnalysisResult, ext string) []reportSection {
+	all := []struct {
+		reportSection
+		present bool
+	}{
+		{reportSection{Name: "cost", Title: "Cost Analysis", data: result.CostAnalysis}, result.CostAnalysis != nil},
+		{reportSection{Name: "security", Title: "Security Analysis", data: result.SecurityFindings}, result.SecurityFindings != nil},
+		{reportSection{Name: "performance", Title: "Performance Analysis", data: result.PerformanceData}, result.PerformanceData != nil},
+		{reportSection{Name: "compliance", Title: "Compliance Analysis", data: result.ComplianceReport}, result.ComplianceReport != nil},
+		{reportSection{Name: "optimization", Title: "Optimization Analysis", data: result.Optimization}, result.Optimization != nil},
+		{reportSection{Name: "inventory", Title: "Resource Inventory", data: result.ResourceInventory}, true},
+		{reportSection{Name: "recommendations", Title: "Recommendations", data: result.Recommendations}, true},
+	}
+	var sections []reportSection
+	for _, section := range all {
+		if !section.present {
+			continue
+		}
+		section.File = section.Name + ext
+		sections = append(sections, section.reportSection)
+	}
+	return sections
+}
+
+// writeAnalysisDir writes result to dir as one file per section plus an
+// index referencing them: JSON files and index.json, or linked HTML pages
+// and index.html. Every file is replaced atomically and the index is written
+// last, so a reader following it never sees a partial report.
+func writeAnalysisDir(dir string, result *AnalysisResult, format string) ([]string, error) {
+	var ext string
+	switch format {
+	case "json":
+		ext = ".json"
+	case "html":
+		ext = ".html"
+	default:
+		return nil, fmt.Errorf("-output-dir supports json and html, not %s", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	index := reportIndex{
+		Timestamp:     result.Timestamp,
+		ProjectID:     result.ProjectID,
+		AnalysisScope: result.AnalysisScope,
+		Summary:       result.Summary,
+		TimedOut:      result.TimedOut,
+		Incomplete:    result.Incomplete,
+		Sections:      reportSections(result, ext),
+	}
+
+	var written []string
+	write := func(name string, data []byte) error {
+		path := filepath.Join(dir, name)
+		if err := writeFileAtomic(path, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, path)
+		return nil
+	}
+
+	for _, section := range index.Sections {
+		var data []byte
+		var err error
+		if format == "json" {
+			data, err = json.MarshalIndent(section.data, "", "  ")
+		} else {
+			data, err = renderSectionPage(section)
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to render %s: %w", section.Name, err)
+		}
+		if err := write(section.File, data); err != nil {
+			return written, err
+		}
+	}
+
+	var data []byte
+	var err error
+	if format == "json" {
+		data, err = json.MarshalIndent(index, "", "  ")
+	} else {
+		data, err = renderIndexPage(index)
+	}
+	if err != nil {
+		return written, fmt.Errorf("failed to render index: %w", err)
+	}
+	return written, write("index"+ext, data)
+}
+
+const reportStyle = `body { font-family: Arial, sans-serif; margin: 20px; }
+        .summary { background: #f5f5f5; padding: 15px; border-radius: 5px; }
+        .metric { display: inline-block; margin: 10px; padding: 10px; background: white; border-radius: 3px; }
+        pre { background: #f5f5f5; padding: 15px; border-radius: 5px; overflow-x: auto; }`
+
+var indexPageTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>GCP Analysis Report - {{.ProjectID}}</title>
+    <style>
+        ` + reportStyle + `
+    </style>
+</head>
+<body>
+    <h1>GCP Analysis Report</h1>
+    <p>Project {{.ProjectID}}, {{.Timestamp.Format "2006-01-02 15:04:05"}}</p>
+    <div class="summary">
+        <h2>Summary</h2>
+        <div class="metric">Resources: {{.Summary.TotalResources}}</div>
+        <div class="metric">Health Score: {{printf "%.1f" .Summary.OverallHealthScore}}%</div>
+        <div class="metric">Monthly Cost: ${{printf "%.2f" .Summary.TotalCost}}</div>
+    </div>
+{{- if .Incomplete}}
+    <p>Incomplete: {{range $i, $name := .Incomplete}}{{if $i}}, {{end}}{{$name}}{{end}}</p>
+{{- end}}
+    <h2>Sections</h2>
+    <ul>
+{{- range .Sections}}
+        <li><a href="{{.File}}">{{.Title}}</a></li>
+{{- end}}
+    </ul>
+</body>
+</html>
+`))
+
+var sectionPageTemplate = template.Must(template.New("section").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Title}}</title>
+    <style>
+        ` + reportStyle + `
+    </style>
+</head>
+<body>
+    <p><a href="index.html">&larr; Report index</a></p>
+    <h1>{{.Title}}</h1>
+    <pre>{{.Data}}</pre>
+</body>
+</html>
+`))
+
+func renderIndexPage(index reportIndex) ([]byte, error) {
+	var buf bytes.Buffer
+	err := indexPageTemplate.Execute(&buf, index)
+	return buf.Bytes(), err
+}
+
+func renderSectionPage(section reportSection) ([]byte, error) {
+	data, err := json.MarshalIndent(section.data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = sectionPageTemplate.Execute(&buf, map[string]string{"Title": section.Title, "Data": string(data)})
+	return buf.Bytes(), err
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completedAnalysis(t *testing.T) *AnalysisResult {
+	t.Helper()
+	config := testAnalysisConfig()
+	config.Analysis.IncludePerformance = false
+	result, err := performAnalysis(context.Background(), &analysisServices{}, &config, &analysisOptions{})
+	require.NoError(t, err)
+	return result
+}
+
+func readJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+}
+
+func TestWriteAnalysisDirJSON(t *testing.T) {
+	result := completedAnalysis(t)
+	dir := filepath.Join(t.TempDir(), "report")
+
+	written, err := writeAnalysisDir(dir, result, "json")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "index.json"), written[len(written)-1])
+
+	var index struct {
+		ProjectID string `json:"project_id"`
+		Sections  []struct {
+			Name string `json:"name"`
+			File string `json:"file"`
+		} `json:"sections"`
+	}
+	readJSON(t, filepath.Join(dir, "index.json"), &index)
+	assert.Equal(t, "my-project", index.ProjectID)
+	var files []string
+	for _, section := range index.Sections {
+		assert.Equal(t, section.Name+".json", section.File)
+		files = append(files, section.File)
+	}
+	// Performance wasn't analyzed, so it has no file
+	assert.Equal(t, []string{"cost.json", "security.json", "compliance.json", "optimization.json", "inventory.json", "recommendations.json"}, files)
+
+	var cost CostAnalysis
+	readJSON(t, filepath.Join(dir, "cost.json"), &cost)
+	assert.Equal(t, result.CostAnalysis.CurrentCosts.Total, cost.CurrentCosts.Total)
+
+	var security SecurityAnalysis
+	readJSON(t, filepath.Join(dir, "security.json"), &security)
+	assert.Equal(t, result.SecurityFindings.Overview.SecurityScore, security.Overview.SecurityScore)
+
+	var inventory map[string]ResourceInventory
+	readJSON(t, filepath.Join(dir, "inventory.json"), &inventory)
+	assert.Len(t, inventory, len(result.ResourceInventory))
+
+	var recommendations []Recommendation
+	readJSON(t, filepath.Join(dir, "recommendations.json"), &recommendations)
+	assert.Len(t, recommendations, len(result.Recommendations))
+
+	_, err = os.Stat(filepath.Join(dir, "performance.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, len(files)+1)
+}
+
+func TestWriteAnalysisDirHTML(t *testing.T) {
+	result := completedAnalysis(t)
+	result.ProjectID = "<script>"
+	dir := t.TempDir()
+
+	_, err := writeAnalysisDir(dir, result, "html")
+	require.NoError(t, err)
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	for _, page := range []string{"cost.html", "security.html", "inventory.html", "recommendations.html"} {
+		assert.Contains(t, string(index), `<a href="`+page+`">`)
+		data, err := os.ReadFile(filepath.Join(dir, page))
+		require.NoError(t, err, page)
+		assert.Contains(t, string(data), `<a href="index.html">`)
+	}
+	assert.NotContains(t, string(index), "<script>")
+
+	security, err := os.ReadFile(filepath.Join(dir, "security.html"))
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(security), "security_score"))
+}
+
+func TestWriteAnalysisDirRejectsText(t *testing.T) {
+	_, err := writeAnalysisDir(t.TempDir(), completedAnalysis(t), "text")
+	assert.ErrorContains(t, err, "supports json and html")
+}
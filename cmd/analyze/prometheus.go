@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusMetric is one metric family of the -format prometheus output
+type prometheusMetric struct {
+	name    string
+	help    string
+	samples []prometheusSample
+}
+
+type prometheusSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// analysisMetrics turns analysis summaries into metric families, one
+// sample per project and label set
+func analysisMetrics(results []*AnalysisResult) []prometheusMetric {
+	gauge := func(name, help string, value func(*AnalysisResult) float64) prometheusMetric {
+		metric := prometheusMetric{name: name, help: help}
+		for _, result := range results {
+			metric.samples = append(metric.samples, prometheusSample{
+				labels: resultLabels(result),
+				value:  value(result),
+			})
+		}
+		return metric
+	}
+	breakdown := func(name, help, label string, counts func(AnalysisSummary) map[string]int) prometheusMetric {
+		metric := prometheusMetric{name: name, help: help}
+		for _, result := range results {
+			values := counts(result.Summary)
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				metric.samples = append(metric.samples, prometheusSample{
+					labels: append(resultLabels(result), [2]string{label, key}),
+					value:  float64(values[key]),
+				})
+			}
+		}
+		return metric
+	}
+
+	return []prometheusMetric{
+		gauge("gcp_analysis_overall_health_score", "Overall health score (0-100)",
+			func(r *AnalysisResult) float64 { return r.Summary.OverallHealthScore }),
+		gauge("gcp_analysis_security_score", "Security score (0-100)",
+			func(r *AnalysisResult) float64 { return r.Summary.SecurityScore }),
+		gauge("gcp_analysis_performance_score", "Performance score (0-100)",
+			func(r *AnalysisResult) float64 { return r.Summary.PerformanceScore }),
+		gauge("gcp_analysis_compliance_score", "Compliance score (0-100)",
+			func(r *AnalysisResult) float64 { return r.Summary.ComplianceScore }),
+		gauge("gcp_analysis_optimization_score", "Optimization score (0-100)",
+			func(r *AnalysisResult) float64 { return r.Summary.OptimizationScore }),
+		gauge("gcp_analysis_total_cost", "Total cost of the analyzed resources",
+			func(r *AnalysisResult) float64 { return r.Summary.TotalCost }),
+		gauge("gcp_analysis_resources_total", "Number of analyzed resources",
+			func(r *AnalysisResult) float64 { return float64(r.Summary.TotalResources) }),
+		breakdown("gcp_analysis_resources", "Number of analyzed resources by type", "type",
+			func(s AnalysisSummary) map[string]int { return s.ResourcesByType }),
+		breakdown("gcp_analysis_issues", "Number of issues found by severity", "severity",
+			func(s AnalysisSummary) map[string]int { return s.IssueCount }),
+		gauge("gcp_analysis_timestamp_seconds", "Unix time the analysis ran",
+			func(r *AnalysisResult) float64 { return float64(r.Timestamp.Unix()) }),
+	}
+}
+
+func resultLabels(result *AnalysisResult) [][2]string {
+	return [][2]string{
+		{"project", result.ProjectID},
+		{"scope", strings.Join(result.AnalysisScope, ",")},
+	}
+}
+
+// writePrometheusMetrics writes the summaries in the Prometheus text
+// exposition format, for a node_exporter textfile collector or a
+// pushgateway
+func writePrometheusMetrics(w io.Writer, results []*AnalysisResult) {
+	for _, metric := range analysisMetrics(results) {
+		if len(metric.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", metric.name)
+		for _, sample := range metric.samples {
+			labels := make([]string, len(sample.labels))
+			for i, label := range sample.labels {
+				labels[i] = fmt.Sprintf(`%s="%s"`, label[0], labelValueEscaper.Replace(label[1]))
+			}
+			fmt.Fprintf(w, "%s{%s} %s\n", metric.name, strings.Join(labels, ","),
+				strconv.FormatFloat(sample.value, 'f', -1, 64))
+		}
+	}
+}
+
+// labelValueEscaper escapes label values as the exposition format expects
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
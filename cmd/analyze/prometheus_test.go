@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseExposition maps each sample line's metric and labels to its value,
+// and each metric to its TYPE
+func parseExposition(t *testing.T, text string) (map[string]float64, map[string]string) {
+	t.Helper()
+	samples := make(map[string]float64)
+	types := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			types[fields[2]] = fields[3]
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		require.Positive(t, i, "malformed sample %q", line)
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		require.NoError(t, err, "malformed value in %q", line)
+		samples[line[:i]] = value
+	}
+	return samples, types
+}
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	result := completedAnalysis(t)
+	result.Summary.IssueCount = map[string]int{"high": 2, "low": 5}
+	result.Summary.ResourcesByType = map[string]int{"compute_instance": 3}
+
+	var buf bytes.Buffer
+	outputAnalysisResults(&buf, result, "prometheus", false)
+	samples, types := parseExposition(t, buf.String())
+
+	labels := fmt.Sprintf(`project="my-project",scope="%s"`, strings.Join(result.AnalysisScope, ","))
+	summary := result.Summary
+	want := map[string]float64{
+		"gcp_analysis_overall_health_score{" + labels + "}":              summary.OverallHealthScore,
+		"gcp_analysis_security_score{" + labels + "}":                    summary.SecurityScore,
+		"gcp_analysis_performance_score{" + labels + "}":                 summary.PerformanceScore,
+		"gcp_analysis_compliance_score{" + labels + "}":                  summary.ComplianceScore,
+		"gcp_analysis_optimization_score{" + labels + "}":                summary.OptimizationScore,
+		"gcp_analysis_total_cost{" + labels + "}":                        summary.TotalCost,
+		"gcp_analysis_resources_total{" + labels + "}":                   float64(summary.TotalResources),
+		"gcp_analysis_resources{" + labels + `,type="compute_instance"}`: 3,
+		"gcp_analysis_issues{" + labels + `,severity="high"}`:            2,
+		"gcp_analysis_issues{" + labels + `,severity="low"}`:             5,
+		"gcp_analysis_timestamp_seconds{" + labels + "}":                 float64(result.Timestamp.Unix()),
+	}
+	assert.Equal(t, want, samples)
+	for name, metricType := range types {
+		assert.Equal(t, "gauge", metricType, name)
+	}
+	assert.Len(t, types, 10)
+	assert.Contains(t, buf.String(), "# HELP gcp_analysis_security_score Security score (0-100)\n")
+}
+
+func TestPrometheusLabelEscaping(t *testing.T) {
+	result := &AnalysisResult{
+		ProjectID:     `odd"project\name`,
+		AnalysisScope: []string{"line\nbreak"},
+		Timestamp:     time.Unix(1700000000, 0),
+	}
+
+	var buf bytes.Buffer
+	writePrometheusMetrics(&buf, []*AnalysisResult{result})
+	assert.Contains(t, buf.String(), `gcp_analysis_security_score{project="odd\"project\\name",scope="line\nbreak"} 0`+"\n")
+	assert.Contains(t, buf.String(), "gcp_analysis_timestamp_seconds{")
+	assert.Contains(t, buf.String(), "} 1700000000\n")
+	// Empty breakdowns are left out entirely
+	assert.NotContains(t, buf.String(), "gcp_analysis_issues")
+}
+
+func TestMultiProjectPrometheusMetrics(t *testing.T) {
+	analyze := func(ctx context.Context, projectID string) (*AnalysisResult, error) {
+		if projectID == "proj-denied" {
+			return nil, fmt.Errorf("permission denied")
+		}
+		return fakeProjectResult(projectID, 2, 10, 75), nil
+	}
+	result := analyzeProjects(context.Background(), []string{"proj-a", "proj-denied", "proj-b"}, 2, analyze)
+
+	var buf bytes.Buffer
+	outputMultiProjectResults(&buf, result, "prometheus")
+	samples, _ := parseExposition(t, buf.String())
+
+	assert.Equal(t, 75.0, samples[`gcp_analysis_overall_health_score{project="proj-a",scope=""}`])
+	assert.Equal(t, 75.0, samples[`gcp_analysis_overall_health_score{project="proj-b",scope=""}`])
+	assert.NotContains(t, buf.String(), "proj-denied")
+	assert.Equal(t, 1, strings.Count(buf.String(), "# TYPE gcp_analysis_overall_health_score gauge"))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResourceReference is a link from an inventoried resource to another
+// resource, stored under "references" in ResourceDetails.Configuration
+type ResourceReference struct {
+	// Field is where the reference sits in the configuration, such as
+	// networkInterfaces[0].subnetwork
+	Field string `json:"field"`
+	// Target is the referenced resource as a projects/... path
+	Target string `json:"target"`
+	// TargetID is the inventory ID of the target, empty when dangling
+	TargetID string `json:"target_id,omitempty"`
+	// Dangling is set when the target isn't in the inventory, because it
+	// was deleted or lies outside the analyzed scope
+	Dangling bool `json:"dangling,omitempty"`
+}
+
+// referenceFields are the configuration keys holding links to other
+// resources; selfLink is the resource's own identity, not a reference
+var referenceFields = map[string]bool{
+	"network":           true,
+	"subnetwork":        true,
+	"source":            true,
+	"kmsKeyName":        true,
+	"defaultKmsKeyName": true,
+}
+
+// resolveReferences records in each resource's configuration the resources
+// it references, resolving selfLinks and IDs against the whole inventory.
+// References to resources not in the inventory are kept, marked dangling,
+// and reported as an issue of the inventory holding the resource.
+func resolveReferences(inventory map[string]ResourceInventory) {
+	known := make(map[string]string)
+	for _, scope := range inventory {
+		for _, resource := range scope.Resources {
+			known[resource.ID] = resource.ID
+			if selfLink, ok := resource.Configuration["selfLink"].(string); ok {
+				known[resourcePath(selfLink)] = resource.ID
+			}
+		}
+	}
+
+	for name, scope := range inventory {
+		for i := range scope.Resources {
+			resource := &scope.Resources[i]
+			if resource.Configuration == nil {
+				resource.Configuration = make(map[string]interface{})
+			}
+
+			references := []ResourceReference{}
+			collectReferences("", resource.Configuration, &references)
+			for j := range references {
+				ref := &references[j]
+				if id, ok := known[ref.Target]; ok {
+					ref.TargetID = id
+					continue
+				}
+				ref.Dangling = true
+				scope.Status.Issues = append(scope.Status.Issues,
+					fmt.Sprintf("%s references missing resource %s (%s)", resource.Name, ref.Target, ref.Field))
+			}
+			resource.Configuration["references"] = references
+		}
+		inventory[name] = scope
+	}
+}
+
+// collectReferences walks a configuration value, appending a reference for
+// every string under one of the referenceFields
+func collectReferences(path string, value interface{}, references *[]ResourceReference) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "references" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			if target, ok := v[key].(string); ok {
+				if referenceFields[key] && target != "" {
+					*references = append(*references, ResourceReference{Field: field, Target: resourcePath(target)})
+				}
+				continue
+			}
+			collectReferences(field, v[key], references)
+		}
+	case []interface{}:
+		for i, item := range v {
+			collectReferences(fmt.Sprintf("%s[%d]", path, i), item, references)
+		}
+	case []map[string]interface{}:
+		for i, item := range v {
+			collectReferences(fmt.Sprintf("%s[%d]", path, i), item, references)
+		}
+	}
+}
+
+// resourcePath reduces a selfLink or resource name to its projects/...
+// path, dropping the API host and version and any KMS key version, so the
+// different spellings of one resource compare equal
+func resourcePath(ref string) string {
+	if i := strings.Index(ref, "projects/"); i >= 0 {
+		ref = ref[i:]
+	}
+	if i := strings.Index(ref, "/cryptoKeyVersions/"); i >= 0 {
+		ref = ref[:i]
+	}
+	return strings.TrimSuffix(ref, "/")
+}
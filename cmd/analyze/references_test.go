@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func referenceInventory() map[string]ResourceInventory {
+	const api = "https://www.googleapis.com/compute/v1/"
+	return map[string]ResourceInventory{
+		"compute": {
+			Resources: []ResourceDetails{
+				{
+					ID:   "instance-1",
+					Name: "web-server-1",
+					Configuration: map[string]interface{}{
+						"selfLink": api + "projects/p/zones/us-central1-a/instances/web-server-1",
+						"networkInterfaces": []interface{}{
+							map[string]interface{}{
+								"network":    api + "projects/p/global/networks/vpc",
+								"subnetwork": api + "projects/p/regions/us-central1/subnetworks/web",
+							},
+						},
+						"disks": []interface{}{
+							map[string]interface{}{"source": api + "projects/p/zones/us-central1-a/disks/deleted"},
+						},
+					},
+				},
+			},
+		},
+		"network": {
+			Resources: []ResourceDetails{
+				{
+					ID:   "network-1",
+					Name: "vpc",
+					Configuration: map[string]interface{}{
+						"selfLink": api + "projects/p/global/networks/vpc",
+					},
+				},
+				{
+					ID:   "subnet-1",
+					Name: "web",
+					Configuration: map[string]interface{}{
+						"selfLink": api + "projects/p/regions/us-central1/subnetworks/web",
+						"network":  api + "projects/p/global/networks/vpc",
+					},
+				},
+			},
+		},
+		"storage": {
+			Resources: []ResourceDetails{
+				{
+					ID:   "bucket-1",
+					Name: "data-bucket",
+					Configuration: map[string]interface{}{
+						"encryption": map[string]interface{}{
+							"defaultKmsKeyName": "projects/p/locations/us/keyRings/ring/cryptoKeys/key/cryptoKeyVersions/3",
+						},
+					},
+				},
+			},
+		},
+		"kms": {
+			Resources: []ResourceDetails{
+				{ID: "projects/p/locations/us/keyRings/ring/cryptoKeys/key", Name: "key"},
+			},
+		},
+	}
+}
+
+func references(t *testing.T, resource ResourceDetails) []ResourceReference {
+	t.Helper()
+	refs, ok := resource.Configuration["references"].([]ResourceReference)
+	require.True(t, ok, "references not set on %s", resource.ID)
+	return refs
+}
+
+func TestResolveReferences(t *testing.T) {
+	inventory := referenceInventory()
+	resolveReferences(inventory)
+
+	instance := inventory["compute"].Resources[0]
+	assert.Equal(t, []ResourceReference{
+		{Field: "disks[0].source", Target: "projects/p/zones/us-central1-a/disks/deleted", Dangling: true},
+		{Field: "networkInterfaces[0].network", Target: "projects/p/global/networks/vpc", TargetID: "network-1"},
+		{Field: "networkInterfaces[0].subnetwork", Target: "projects/p/regions/us-central1/subnetworks/web", TargetID: "subnet-1"},
+	}, references(t, instance))
+
+	subnet := inventory["network"].Resources[1]
+	assert.Equal(t, []ResourceReference{
+		{Field: "network", Target: "projects/p/global/networks/vpc", TargetID: "network-1"},
+	}, references(t, subnet))
+
+	bucket := inventory["storage"].Resources[0]
+	assert.Equal(t, []ResourceReference{
+		{Field: "encryption.defaultKmsKeyName", Target: "projects/p/locations/us/keyRings/ring/cryptoKeys/key", TargetID: "projects/p/locations/us/keyRings/ring/cryptoKeys/key"},
+	}, references(t, bucket))
+
+	// Resources without configuration still get an empty list
+	assert.Empty(t, references(t, inventory["kms"].Resources[0]))
+}
+
+func TestResolveReferencesFlagsDangling(t *testing.T) {
+	inventory := referenceInventory()
+	resolveReferences(inventory)
+
+	assert.Equal(t, []string{
+		"web-server-1 references missing resource projects/p/zones/us-central1-a/disks/deleted (disks[0].source)",
+	}, inventory["compute"].Status.Issues)
+	assert.Empty(t, inventory["network"].Status.Issues)
+	assert.Empty(t, inventory["storage"].Status.Issues)
+}
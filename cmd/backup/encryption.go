@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// keyChecker verifies a KMS key can be used before backups rely on it
+type keyChecker interface {
+	CheckKey(ctx context.Context, name string) error
+}
+
+// checkEncryptionKey makes sure the CMEK key of config exists and has an
+// enabled primary version
+func checkEncryptionKey(ctx context.Context, keys keyChecker, encryption EncryptionConfig) error {
+	if !encryption.Enabled || encryption.KeyName == "" {
+		return nil
+	}
+	if !strings.Contains(encryption.KeyName, "/cryptoKeys/") {
+		return fmt.Errorf("encryption: key_name %q is not a crypto key name (projects/*/locations/*/keyRings/*/cryptoKeys/*)", encryption.KeyName)
+	}
+	if keys == nil {
+		return fmt.Errorf("encryption: no KMS client to check key %s", encryption.KeyName)
+	}
+	if err := keys.CheckKey(ctx, encryption.KeyName); err != nil {
+		return fmt.Errorf("encryption: key %s is not usable: %w", encryption.KeyName, err)
+	}
+	return nil
+}
+
+// backupSink writes to the backup bucket with the configured encryption:
+// objects are encrypted with the CMEK key when one is set and, in
+// client-side mode, sealed in AES-256-GCM chunks before they are uploaded
+type backupSink struct {
+	store  objectStore
+	bucket string
+	kmsKey string
+	aead   cipher.AEAD
+}
+
+func newBackupSink(store objectStore, config *BackupConfig) (*backupSink, error) {
+	sink := &backupSink{store: store, bucket: config.Storage.Bucket}
+	encryption := config.Encryption
+	if !encryption.Enabled {
+		return sink, nil
+	}
+	sink.kmsKey = encryption.KeyName
+	if encryption.ClientSide {
+		if encryption.Algorithm != "" && !strings.EqualFold(encryption.Algorithm, "AES256") {
+			return nil, fmt.Errorf("encryption: client-side encryption supports AES256, not %s", encryption.Algorithm)
+		}
+		key, err := readClientKey(encryption.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if sink.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return sink, nil
+}
+
+// readClientKey reads a 32 byte AES key, raw or base64 encoded
+func readClientKey(file string) ([]byte, error) {
+	if file == "" {
+		return nil, fmt.Errorf("encryption: client_side needs client_key_file")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil && len(decoded) == 32 {
+		return decoded, nil
+	}
+	if len(data) == 32 {
+		return data, nil
+	}
+	return nil, fmt.Errorf("encryption: %s does not hold a 32 byte key", file)
+}
+
+// Client-side encrypted objects are sealed in chunks so that neither
+// backups nor restores have to hold a whole object in memory. An object is
+// a version byte and a random nonce prefix, followed by chunks of at most
+// sealChunkSize bytes of plaintext. Each chunk's nonce is the prefix, the
+// chunk's big-endian index and a flag marking the last chunk, so chunks
+// can't be reordered, dropped or the object cut short without failing to
+// open. The object's name is the additional data of every chunk, so a
+// sealed object only opens under the name it was written to.
+const (
+	sealVersion    = 1
+	sealChunkSize  = 64 << 10
+	sealPrefixSize = 7
+	sealHeaderSize = 1 + sealPrefixSize
+	sealMaxIndex   = 1<<32 - 1
+)
+
+// sealWriter encrypts what is written to it into w; Close seals the last
+// chunk and must be called for the object to open
+type sealWriter struct {
+	aead  cipher.AEAD
+	w     io.Writer
+	name  []byte
+	nonce []byte
+	index uint64
+	buf   []byte
+}
+
+func newSealWriter(aead cipher.AEAD, w io.Writer, name string) (*sealWriter, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce[:sealPrefixSize]); err != nil {
+		return nil, err
+	}
+	header := append([]byte{sealVersion}, nonce[:sealPrefixSize]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &sealWriter{aead: aead, w: w, name: []byte(name), nonce: nonce, buf: make([]byte, 0, sealChunkSize)}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, so the last
+		// chunk is never empty unless the whole object is
+		if len(s.buf) == sealChunkSize {
+			if err := s.flush(false); err != nil {
+				return 0, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):sealChunkSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (s *sealWriter) Close() error {
+	return s.flush(true)
+}
+
+func (s *sealWriter) flush(last bool) error {
+	if s.index > sealMaxIndex {
+		return fmt.Errorf("encryption: object is too large to seal")
+	}
+	setChunkNonce(s.nonce, s.index, last)
+	if _, err := s.w.Write(s.aead.Seal(nil, s.nonce, s.buf, s.name)); err != nil {
+		return err
+	}
+	s.index++
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// openReader decrypts an object sealed by sealWriter as it is read
+type openReader struct {
+	aead  cipher.AEAD
+	r     *bufio.Reader
+	name  []byte
+	nonce []byte
+	index uint64
+	chunk []byte
+	plain []byte
+	done  bool
+}
+
+func newOpenReader(aead cipher.AEAD, r io.Reader, name string) (*openReader, error) {
+	header := make([]byte, sealHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("encrypted object is truncated")
+	}
+	if header[0] != sealVersion {
+		return nil, fmt.Errorf("encrypted object has unknown format version %d", header[0])
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, header[1:])
+	return &openReader{
+		aead:  aead,
+		r:     bufio.NewReaderSize(r, sealChunkSize+aead.Overhead()),
+		name:  []byte(name),
+		nonce: nonce,
+		chunk: make([]byte, sealChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.plain) == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+		if err := o.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.plain)
+	o.plain = o.plain[n:]
+	return n, nil
+}
+
+func (o *openReader) next() error {
+	n, err := io.ReadFull(o.r, o.chunk)
+	switch {
+	case err == io.EOF:
+		return fmt.Errorf("encrypted object is truncated")
+	case err == io.ErrUnexpectedEOF:
+		o.done = true
+	case err != nil:
+		return err
+	default:
+		// A full chunk is the last one only if nothing follows it
+		if _, err := o.r.Peek(1); err == io.EOF {
+			o.done = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if o.index > sealMaxIndex {
+		return fmt.Errorf("encrypted object has too many chunks")
+	}
+	setChunkNonce(o.nonce, o.index, o.done)
+	plain, err := o.aead.Open(o.chunk[:0], o.nonce, o.chunk[:n], o.name)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	o.index++
+	o.plain = plain
+	return nil
+}
+
+// setChunkNonce writes the index and last chunk flag after the prefix
+func setChunkNonce(nonce []byte, index uint64, last bool) {
+	binary.BigEndian.PutUint32(nonce[sealPrefixSize:], uint32(index))
+	nonce[len(nonce)-1] = 0
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+// upload streams r to name in the backup bucket, sealing it on the way in
+// client-side mode
+func (s *backupSink) upload(ctx context.Context, name string, r io.Reader) error {
+	if s.aead == nil {
+		return s.store.WriteObject(ctx, s.bucket, name, r, s.kmsKey)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		sealer, err := newSealWriter(s.aead, pw, name)
+		if err == nil {
+			if _, err = io.Copy(sealer, r); err == nil {
+				err = sealer.Close()
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	err := s.store.WriteObject(ctx, s.bucket, name, pr, s.kmsKey)
+	// Unblocks the sealer when the upload stops before reading everything
+	pr.CloseWithError(fmt.Errorf("upload of %s stopped", name))
+	return err
+}
+
+func (s *backupSink) write(ctx context.Context, name string, data []byte) error {
+	return s.upload(ctx, name, bytes.NewReader(data))
+}
+
+func (s *backupSink) read(ctx context.Context, name string) ([]byte, error) {
+	reader, err := s.store.ReadObject(ctx, s.bucket, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	if s.aead == nil {
+		return io.ReadAll(reader)
+	}
+	opener, err := newOpenReader(s.aead, reader, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(opener)
+}
+
+// copyFrom backs up one object. Plain copies stay within Cloud Storage;
+// client-side encryption streams the listed generation through the sealer.
+func (s *backupSink) copyFrom(ctx context.Context, bucket string, object ObjectInfo, name string) error {
+	if s.aead == nil {
+		return s.store.CopyObject(ctx, bucket, object.Name, object.Generation, s.bucket, name, s.kmsKey)
+	}
+	reader, err := s.store.ReadObject(ctx, bucket, object.Name, object.Generation)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return s.upload(ctx, name, reader)
+}
+
+// kmsKeyChecker checks keys with the Cloud KMS API
+type kmsKeyChecker struct {
+	client *kms.KeyManagementClient
+}
+
+func (c *kmsKeyChecker) CheckKey(ctx context.Context, name string) error {
+	key, err := c.client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: name})
+	if err != nil {
+		return err
+	}
+	if key.GetPrimary().GetState() != kmspb.CryptoKeyVersion_ENABLED {
+		return fmt.Errorf("primary version is %s", key.GetPrimary().GetState())
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKMSKey = "projects/my-project/locations/us-central1/keyRings/backups/cryptoKeys/backup-key"
+
+type fakeKeyChecker struct {
+	checked []string
+	err     error
+}
+
+func (f *fakeKeyChecker) CheckKey(_ context.Context, name string) error {
+	f.checked = append(f.checked, name)
+	return f.err
+}
+
+func writeClientKey(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "backup.key")
+	key := bytes.Repeat([]byte{7}, 32)
+	require.NoError(t, os.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+	return file
+}
+
+func TestStorageBackupSetsCMEKKey(t *testing.T) {
+	withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("a"))
+	config, target := storageTestConfig()
+	config.Encryption = EncryptionConfig{Enabled: true, KeyName: testKMSKey}
+
+	_, err := backupStorage(context.Background(), store, config, target, &backupOptions{})
+	require.NoError(t, err)
+
+	uploaded := store.buckets[config.Storage.Bucket]
+	require.Len(t, uploaded, 2)
+	for name, object := range uploaded {
+		assert.Equal(t, testKMSKey, object.kmsKey, name)
+	}
+	// Server-side copies keep the data as it was
+	assert.Equal(t, []byte("a"), uploaded["automated-backups/storage/assets-1772330400/assets-bucket/a.txt"].data)
+}
+
+func TestStorageBackupClientSideEncryption(t *testing.T) {
+	withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("secret contents"))
+	config, target := storageTestConfig()
+	config.Encryption = EncryptionConfig{Enabled: true, KeyName: testKMSKey, ClientSide: true, ClientKeyFile: writeClientKey(t)}
+	ctx := context.Background()
+
+	_, err := backupStorage(ctx, store, config, target, &backupOptions{Incremental: true})
+	require.NoError(t, err)
+
+	uploaded := store.buckets[config.Storage.Bucket]
+	copied := uploaded["automated-backups/storage/assets-1772330400/assets-bucket/a.txt"]
+	assert.NotContains(t, string(copied.data), "secret contents")
+	assert.Equal(t, testKMSKey, copied.kmsKey)
+	manifest := uploaded["automated-backups/storage/assets/assets-bucket.manifest.json"]
+	assert.NotContains(t, string(manifest.data), "a.txt")
+
+	// The sink reads back what it sealed, so later runs see the manifest
+	sink, err := newBackupSink(store, config)
+	require.NoError(t, err)
+	data, err := sink.read(ctx, "automated-backups/storage/assets-1772330400/assets-bucket/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "secret contents", string(data))
+
+	record, err := backupStorage(ctx, store, config, target, &backupOptions{Incremental: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, record.ResourceCount)
+}
+
+func TestNewBackupSinkRejectsBadClientKey(t *testing.T) {
+	config, _ := storageTestConfig()
+	config.Encryption = EncryptionConfig{Enabled: true, ClientSide: true}
+	_, err := newBackupSink(newFakeObjectStore(), config)
+	assert.ErrorContains(t, err, "client_key_file")
+
+	short := filepath.Join(t.TempDir(), "short.key")
+	require.NoError(t, os.WriteFile(short, []byte("too short"), 0600))
+	config.Encryption.ClientKeyFile = short
+	_, err = newBackupSink(newFakeObjectStore(), config)
+	assert.ErrorContains(t, err, "32 byte key")
+}
+
+func TestPerformBackupChecksKMSKey(t *testing.T) {
+	config, _ := storageTestConfig()
+	config.Encryption = EncryptionConfig{Enabled: true, KeyName: testKMSKey}
+	keys := &fakeKeyChecker{err: errors.New("permission denied")}
+
+	_, err := performBackup(context.Background(), &backupServices{Objects: newFakeObjectStore(), Keys: keys}, config, &backupOptions{Parallel: 1})
+	assert.ErrorContains(t, err, "permission denied")
+	assert.Equal(t, []string{testKMSKey}, keys.checked)
+
+	config.Encryption.KeyName = "backup-key"
+	_, err = performBackup(context.Background(), &backupServices{Objects: newFakeObjectStore(), Keys: keys}, config, &backupOptions{Parallel: 1})
+	assert.ErrorContains(t, err, "not a crypto key name")
+}
+
+func testAEAD(t *testing.T) cipher.AEAD {
+	t.Helper()
+	block, err := aes.NewCipher(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead
+}
+
+func sealForTest(t *testing.T, aead cipher.AEAD, name string, data []byte) []byte {
+	t.Helper()
+	var sealed bytes.Buffer
+	sealer, err := newSealWriter(aead, &sealed, name)
+	require.NoError(t, err)
+	_, err = sealer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, sealer.Close())
+	return sealed.Bytes()
+}
+
+func openForTest(aead cipher.AEAD, name string, sealed []byte) ([]byte, error) {
+	opener, err := newOpenReader(aead, bytes.NewReader(sealed), name)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(opener)
+}
+
+func TestSealedObjectsRoundTripInChunks(t *testing.T) {
+	aead := testAEAD(t)
+	for _, size := range []int{0, 1, sealChunkSize, sealChunkSize + 1, 3*sealChunkSize + 17} {
+		data := bytes.Repeat([]byte("x"), size)
+		sealed := sealForTest(t, aead, "backups/a.txt", data)
+		plain, err := openForTest(aead, "backups/a.txt", sealed)
+		require.NoError(t, err, size)
+		assert.Equal(t, data, plain, size)
+	}
+}
+
+func TestSealedObjectsDetectTampering(t *testing.T) {
+	aead := testAEAD(t)
+	data := bytes.Repeat([]byte("x"), 2*sealChunkSize+5)
+	sealed := sealForTest(t, aead, "backups/a.txt", data)
+	chunk := sealChunkSize + aead.Overhead()
+
+	// Opening under another name fails, so sealed objects can't be swapped
+	_, err := openForTest(aead, "backups/b.txt", sealed)
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	// Dropping the last chunk leaves a full chunk that isn't marked last
+	_, err = openForTest(aead, "backups/a.txt", sealed[:sealHeaderSize+2*chunk])
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	// Swapping two chunks breaks their indexes
+	swapped := append([]byte{}, sealed[:sealHeaderSize]...)
+	swapped = append(swapped, sealed[sealHeaderSize+chunk:sealHeaderSize+2*chunk]...)
+	swapped = append(swapped, sealed[sealHeaderSize:sealHeaderSize+chunk]...)
+	swapped = append(swapped, sealed[sealHeaderSize+2*chunk:]...)
+	_, err = openForTest(aead, "backups/a.txt", swapped)
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	_, err = openForTest(aead, "backups/a.txt", sealed[:sealHeaderSize])
+	assert.ErrorContains(t, err, "truncated")
+}
+
+func TestClientSideCopyReadsListedGeneration(t *testing.T) {
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("first"))
+	listed, err := store.StatObject(context.Background(), "assets-bucket", "a.txt")
+	require.NoError(t, err)
+	store.put("assets-bucket", "a.txt", []byte("second"))
+	config, _ := storageTestConfig()
+	config.Encryption = EncryptionConfig{Enabled: true, ClientSide: true, ClientKeyFile: writeClientKey(t)}
+	sink, err := newBackupSink(store, config)
+	require.NoError(t, err)
+
+	// The object changed after it was listed, so the copy must not pick up
+	// the newer generation under the listed one's name
+	err = sink.copyFrom(context.Background(), "assets-bucket", listed, "backups/a.txt")
+	assert.ErrorIs(t, err, errObjectNotExist)
+	assert.NotContains(t, store.buckets[config.Storage.Bucket], "backups/a.txt")
+}
+
+// failingReadStore fails reads partway through the object
+type failingReadStore struct {
+	*fakeObjectStore
+}
+
+func (f failingReadStore) ReadObject(_ context.Context, bucket, name string, generation int64) (io.ReadCloser, error) {
+	return io.NopCloser(io.MultiReader(bytes.NewReader(make([]byte, sealChunkSize+1)), iotest.ErrReader(errors.New("connection reset")))), nil
+}
+
+func TestClientSideCopyAbandonsFailedReads(t *testing.T) {
+	store := failingReadStore{newFakeObjectStore()}
+	config, _ := storageTestConfig()
+	config.Encryption = EncryptionConfig{Enabled: true, ClientSide: true, ClientKeyFile: writeClientKey(t)}
+	sink, err := newBackupSink(store, config)
+	require.NoError(t, err)
+
+	err = sink.copyFrom(context.Background(), "assets-bucket", ObjectInfo{Name: "a.txt", Generation: 1}, "backups/a.txt")
+	assert.ErrorContains(t, err, "connection reset")
+	assert.Empty(t, store.buckets[config.Storage.Bucket])
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupInfo is one backup found in the backup bucket
+type BackupInfo struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	Target  string    `json:"target"`
+	Time    time.Time `json:"time"`
+	Size    int64     `json:"size"`
+	Objects int       `json:"objects"`
+	Status  string    `json:"status"`
+}
+
+// BackupList is the result of -list
+type BackupList struct {
+	Backups []BackupInfo `json:"backups"`
+	Total   int          `json:"total"`
+}
+
+// backupFilter narrows -list; zero values match everything
+type backupFilter struct {
+	Type  string
+	Since time.Time
+}
+
+// parseSince accepts a duration back from now, such as 72h, or an RFC3339
+// time
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -since %q: use a duration like 72h or an RFC3339 time", value)
+	}
+	return t, nil
+}
+
+// listBackups finds the backups under the configured path. Backups live at
+// <path>/<type>/<target>-<unix time>, as a single object or a directory of
+// them; anything else there, such as storage manifests, is not a backup.
+func listBackups(ctx context.Context, store objectStore, config *BackupConfig, filter backupFilter) (*BackupList, error) {
+	prefix := strings.Trim(config.Storage.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	if filter.Type != "" {
+		prefix += filter.Type + "/"
+	}
+	objects, err := store.ListObjects(ctx, config.Storage.Bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gs://%s/%s: %w", config.Storage.Bucket, prefix, err)
+	}
+
+	base := strings.Trim(config.Storage.Path, "/")
+	backups := make(map[string]*BackupInfo)
+	for _, object := range objects {
+		rel := strings.TrimPrefix(strings.TrimPrefix(object.Name, base), "/")
+		parts := strings.SplitN(rel, "/", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		backupType, name := parts[0], parts[1]
+		target, runID := parseBackupID(name)
+		if runID == "" {
+			continue
+		}
+		seconds, _ := strconv.ParseInt(runID, 10, 64)
+		taken := time.Unix(seconds, 0).UTC()
+		if taken.Before(filter.Since) {
+			continue
+		}
+
+		key := backupType + "/" + name
+		info, ok := backups[key]
+		if !ok {
+			info = &BackupInfo{ID: name, Type: backupType, Target: target, Time: taken, Status: "complete"}
+			backups[key] = info
+		}
+		info.Size += object.Size
+		info.Objects++
+	}
+
+	list := &BackupList{Backups: []BackupInfo{}}
+	for _, info := range backups {
+		if info.Type == "storage" {
+			info.Status = storageBackupStatus(ctx, store, config, info)
+		}
+		list.Backups = append(list.Backups, *info)
+	}
+	sort.Slice(list.Backups, func(i, j int) bool {
+		a, b := list.Backups[i], list.Backups[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.After(b.Time)
+		}
+		return a.Type+a.ID < b.Type+b.ID
+	})
+	list.Total = len(list.Backups)
+	return list, nil
+}
+
+// storageBackupStatus tells complete storage backups, whose run made it
+// into a manifest, from ones interrupted before recording it
+func storageBackupStatus(ctx context.Context, store objectStore, config *BackupConfig, info *BackupInfo) string {
+	sink, err := newBackupSink(store, config)
+	if err != nil {
+		return "unknown"
+	}
+	paths := storageBackupPaths{config: config, target: &BackupTarget{Name: info.Target}}
+	manifests, err := store.ListObjects(ctx, config.Storage.Bucket, paths.manifests()+"/")
+	if err != nil {
+		return "unknown"
+	}
+	_, runID := parseBackupID(info.ID)
+	for _, object := range manifests {
+		if !strings.HasSuffix(object.Name, ".manifest.json") {
+			continue
+		}
+		manifest, err := readStorageManifest(ctx, sink, object.Name, "")
+		if err != nil {
+			return "unknown"
+		}
+		for _, run := range manifest.Runs {
+			if run.ID == runID {
+				return "complete"
+			}
+		}
+	}
+	return "incomplete"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listTestStore holds backups of three types taken on 1, 2 and 3 March 2026
+func listTestStore(t *testing.T) (*fakeObjectStore, *BackupConfig) {
+	t.Helper()
+	advance := withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("aaaa"))
+	config, target := storageTestConfig()
+	ctx := context.Background()
+	backupsBucket := config.Storage.Bucket
+
+	// A storage backup on each day, with the third interrupted before its
+	// manifest was written
+	_, err := backupStorage(ctx, store, config, target, &backupOptions{})
+	require.NoError(t, err)
+	advance(24 * time.Hour)
+	_, err = backupStorage(ctx, store, config, target, &backupOptions{})
+	require.NoError(t, err)
+	store.put(backupsBucket, "automated-backups/storage/assets-1772503200/assets-bucket/a.txt", []byte("aaaa"))
+
+	store.put(backupsBucket, "automated-backups/compute/vm-instances-1772330400", make([]byte, 100))
+	store.put(backupsBucket, "automated-backups/compute/vm-instances-1772503200", make([]byte, 150))
+	store.put(backupsBucket, "automated-backups/iam/policies-1772416800/roles.json", make([]byte, 10))
+	store.put(backupsBucket, "automated-backups/iam/policies-1772416800/bindings.json", make([]byte, 20))
+	// Not backups
+	store.put(backupsBucket, "automated-backups/README", []byte("x"))
+	store.put(backupsBucket, "other/compute/vm-instances-1772330400", []byte("x"))
+	return store, config
+}
+
+func backupIDs(list *BackupList) []string {
+	var ids []string
+	for _, backup := range list.Backups {
+		ids = append(ids, backup.Type+"/"+backup.ID)
+	}
+	return ids
+}
+
+func TestListBackups(t *testing.T) {
+	store, config := listTestStore(t)
+
+	list, err := listBackups(context.Background(), store, config, backupFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 6, list.Total)
+	// Newest first
+	assert.Equal(t, []string{
+		"compute/vm-instances-1772503200",
+		"storage/assets-1772503200",
+		"iam/policies-1772416800",
+		"storage/assets-1772416800",
+		"compute/vm-instances-1772330400",
+		"storage/assets-1772330400",
+	}, backupIDs(list))
+
+	iam := list.Backups[2]
+	assert.Equal(t, "policies", iam.Target)
+	assert.Equal(t, time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC), iam.Time)
+	assert.Equal(t, int64(30), iam.Size)
+	assert.Equal(t, 2, iam.Objects)
+	assert.Equal(t, "complete", iam.Status)
+
+	assert.Equal(t, "incomplete", list.Backups[1].Status)
+	assert.Equal(t, "complete", list.Backups[3].Status)
+}
+
+func TestListBackupsFilters(t *testing.T) {
+	store, config := listTestStore(t)
+	ctx := context.Background()
+
+	list, err := listBackups(ctx, store, config, backupFilter{Type: "compute"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"compute/vm-instances-1772503200", "compute/vm-instances-1772330400"}, backupIDs(list))
+
+	since, err := parseSince("2026-03-02T00:00:00Z")
+	require.NoError(t, err)
+	list, err = listBackups(ctx, store, config, backupFilter{Since: since})
+	require.NoError(t, err)
+	assert.Equal(t, 4, list.Total)
+
+	// The clock stands at 2 March 02:00, so 25h reaches back to 1 March 01:00
+	since, err = parseSince("25h")
+	require.NoError(t, err)
+	list, err = listBackups(ctx, store, config, backupFilter{Type: "storage", Since: since})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"storage/assets-1772503200", "storage/assets-1772416800", "storage/assets-1772330400"}, backupIDs(list))
+
+	since, err = parseSince("1h")
+	require.NoError(t, err)
+	list, err = listBackups(ctx, store, config, backupFilter{Type: "storage", Since: since})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"storage/assets-1772503200", "storage/assets-1772416800"}, backupIDs(list))
+
+	_, err = parseSince("last week")
+	assert.Error(t, err)
+}
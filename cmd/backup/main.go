@@ -2,17 +2,27 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
 )
 
+// stdout receives progress output; emoji and colors are stripped from it
+// when color output is disabled
+var stdout io.Writer = os.Stdout
+
 type BackupConfig struct {
 	ProjectID     string             `json:"project_id"`
 	Region        string             `json:"region"`
@@ -63,13 +73,19 @@ type EncryptionConfig struct {
 	Enabled   bool   `json:"enabled"`
 	KeyName   string `json:"key_name"`
 	Algorithm string `json:"algorithm"`
+	// ClientSide encrypts backup data with the key in ClientKeyFile before
+	// it is uploaded, on top of any KeyName encryption
+	ClientSide    bool   `json:"client_side"`
+	ClientKeyFile string `json:"client_key_file"`
 }
 
+// NotificationConfig says where and when the outcome of an operation is
+// reported; channels take the same form as the monitor's alert actions
 type NotificationConfig struct {
-	Enabled   bool     `json:"enabled"`
-	Channels  []string `json:"channels"`
-	OnSuccess bool     `json:"on_success"`
-	OnFailure bool     `json:"on_failure"`
+	Enabled   bool             `json:"enabled"`
+	Channels  []notify.Channel `json:"channels"`
+	OnSuccess bool             `json:"on_success"`
+	OnFailure bool             `json:"on_failure"`
 }
 
 type BackupResult struct {
@@ -109,17 +125,36 @@ func main() {
 		verify       = flag.Bool("verify", false, "Verify existing backups")
 		restore      = flag.String("restore", "", "Restore from backup (backup ID or path)")
 		restoreTime  = flag.String("restore-time", "", "Point-in-time restore (RFC3339 format)")
+		verifyOnly   = flag.Bool("verify-only", false, "With -restore, check the backup can be restored without changing anything")
 		list         = flag.Bool("list", false, "List existing backups")
+		listType     = flag.String("type", "", "With -list, only list backups of this type (compute, storage, iam, secrets, monitoring)")
+		listSince    = flag.String("since", "", "With -list, only list backups taken since this long ago (72h) or this RFC3339 time")
 		cleanup      = flag.Bool("cleanup", false, "Clean up old backups based on retention policy")
+		schedule     = flag.Bool("ensure-schedule", false, "Create snapshot schedule policies for compute targets and attach them to their disks")
 		compress     = flag.Bool("compress", true, "Compress backup data")
+		incremental  = flag.Bool("incremental", false, "Only copy storage objects changed since the last backup")
 		parallel     = flag.Int("parallel", 4, "Number of parallel backup operations")
 		timeout      = flag.Duration("timeout", 2*time.Hour, "Backup operation timeout")
 		verbose      = flag.Bool("verbose", false, "Enable verbose output")
 		format       = flag.String("format", "json", "Output format (json, text)")
 		output       = flag.String("output", "", "Output file (default: stdout)")
+		noColor      = flag.Bool("no-color", false, "Disable colored and emoji output (also honors NO_COLOR)")
+		validateCfg  = flag.Bool("validate-config", false, "Validate the -config file against its schema and exit")
+		configSchema = flag.Bool("config-schema", false, "Print the JSON Schema of the config file and exit")
+		configFormat = flag.String("config-format", "auto", "Config file format (auto, json, yaml); auto uses the file extension")
 	)
 	flag.Parse()
 
+	if *configSchema {
+		configschema.Print(os.Stdout, BackupConfig{})
+		return
+	}
+	if *validateCfg {
+		os.Exit(configschema.ValidateFile(os.Stderr, *configFile, *configFormat, BackupConfig{}))
+	}
+
+	stdout = termcolor.NewWriter(os.Stdout, termcolor.Enabled(*noColor, os.Stdout))
+
 	if *projectID == "" {
 		*projectID = os.Getenv("GCP_PROJECT_ID")
 		if *projectID == "" {
@@ -154,7 +189,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := json.Unmarshal(configData, &backupConfig); err != nil {
+		if err := configschema.DecodeFormat(configData, configschema.DetectFormat(*configFile, *configFormat), &backupConfig); err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing config file: %v\n", err)
 			os.Exit(1)
 		}
@@ -181,53 +216,92 @@ func main() {
 		defer file.Close()
 		outputFile = file
 	}
+	reportOut := termcolor.NewReportWriter(outputFile, *format, termcolor.Enabled(*noColor, outputFile))
 
 	// Execute requested operation
 	var result interface{}
 	var operationErr error
+	operation := "backup"
 
 	switch {
 	case *list:
-		result, operationErr = listBackups(ctx, services, &backupConfig)
+		var since time.Time
+		if since, operationErr = parseSince(*listSince); operationErr == nil {
+			result, operationErr = listBackups(ctx, services.Objects, &backupConfig, backupFilter{Type: *listType, Since: since})
+		}
 	case *verify:
 		result, operationErr = verifyBackups(ctx, services, &backupConfig)
 	case *cleanup:
+		operation = "cleanup"
 		result, operationErr = cleanupBackups(ctx, services, &backupConfig)
+	case *schedule:
+		var scheduler *gcpSnapshotScheduleClient
+		if scheduler, operationErr = newGCPSnapshotScheduleClient(ctx); operationErr == nil {
+			defer scheduler.Close()
+			result, operationErr = ensureSnapshotSchedules(ctx, scheduler, &backupConfig)
+		}
+	case *restore != "" && *verifyOnly:
+		operation = "restore verification"
+		var snapshots *gcpSnapshotScheduleClient
+		if snapshots, operationErr = newGCPSnapshotScheduleClient(ctx); operationErr == nil {
+			defer snapshots.Close()
+			result, operationErr = verifyRestore(ctx, services.Objects, snapshots, &backupConfig, *restore, *restoreTime)
+		}
 	case *restore != "":
+		operation = "restore"
 		result, operationErr = restoreBackup(ctx, services, &backupConfig, *restore, *restoreTime)
 	default:
 		result, operationErr = performBackup(ctx, services, &backupConfig, &backupOptions{
-			Target:     *target,
-			DryRun:     *dryRun,
-			Compress:   *compress,
-			Parallel:   *parallel,
-			Verbose:    *verbose,
+			Target:      *target,
+			DryRun:      *dryRun,
+			Compress:    *compress,
+			Incremental: *incremental,
+			Parallel:    *parallel,
+			Verbose:     *verbose,
 		})
 	}
 
+	// Listing and verifying only report; the rest notify on completion
+	if !*list && !*verify && !*schedule && !*dryRun {
+		notifyCtx, cancelNotify := context.WithTimeout(context.Background(), notifyTimeout)
+		err := notifyOperation(notifyCtx, &backupConfig, operation, result, operationErr)
+		cancelNotify()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Notification failed: %v\n", err)
+		}
+	}
+
 	if operationErr != nil {
 		fmt.Fprintf(os.Stderr, "Operation failed: %v\n", operationErr)
 		os.Exit(1)
 	}
 
 	// Output results
-	outputBackupResults(outputFile, result, *format, *verbose)
+	outputBackupResults(reportOut, result, *format, *verbose)
+
+	if readiness, ok := result.(*RestoreReadiness); ok && !readiness.Ready {
+		os.Exit(1)
+	}
 }
 
 type backupServices struct {
 	Compute    *gcp.ComputeService
 	Storage    *gcp.StorageService
+	Objects    objectStore
+	Keys       keyChecker
 	IAM        *gcp.IAMService
 	Secrets    *gcp.SecretsService
 	Monitoring *gcp.MonitoringService
 }
 
 type backupOptions struct {
-	Target     string
-	DryRun     bool
-	Compress   bool
-	Parallel   int
-	Verbose    bool
+	Target   string
+	DryRun   bool
+	Compress bool
+	// Incremental backs up only storage objects changed since the last run
+	Incremental bool
+	Parallel    int
+	Verbose     bool
 }
 
 func initializeBackupServices(client *gcp.Client) (*backupServices, error) {
@@ -271,9 +345,21 @@ func initializeBackupServices(client *gcp.Client) (*backupServices, error) {
 		return nil, fmt.Errorf("failed to create monitoring service: %v", err)
 	}
 
+	storageClient, err := client.GetStorageClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %v", err)
+	}
+
+	kmsClient, err := client.GetKMSClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS client: %v", err)
+	}
+
 	return &backupServices{
 		Compute:    computeService,
 		Storage:    storageService,
+		Objects:    &gcsObjectStore{client: storageClient},
+		Keys:       &kmsKeyChecker{client: kmsClient},
 		IAM:        iamService,
 		Secrets:    secretsService,
 		Monitoring: monitoringService,
@@ -351,16 +437,18 @@ func performBackup(ctx context.Context, services *backupServices, config *Backup
 	}
 
 	if opts.Verbose {
-		fmt.Printf("🔄 Starting backup operation for project: %s\n", config.ProjectID)
+		fmt.Fprintf(stdout, "🔄 Starting backup operation for project: %s\n", config.ProjectID)
 		if opts.DryRun {
-			fmt.Println("🧪 DRY RUN MODE - No actual backups will be created")
+			fmt.Fprintln(stdout, "🧪 DRY RUN MODE - No actual backups will be created")
 		}
 	}
 
-	var totalSize int64
-	var totalResources int
+	if err := checkEncryptionKey(ctx, services.Keys, config.Encryption); err != nil {
+		return nil, err
+	}
 
-	// Process each backup target
+	// Pick the targets to run, keeping their configured order for the report
+	var targets []BackupTarget
 	for _, target := range config.BackupTargets {
 		if !target.Enabled {
 			continue
@@ -370,38 +458,70 @@ func performBackup(ctx context.Context, services *backupServices, config *Backup
 		if opts.Target != "" && target.Name != opts.Target && target.Type != opts.Target {
 			continue
 		}
+		targets = append(targets, target)
+	}
+
+	// Targets are independent and run up to opts.Parallel at a time; each
+	// target's own steps stay sequential
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	records := make([]BackupRecord, len(targets))
+	errs := make([]error, len(targets))
+	var progress sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+	for i := range targets {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			target := &targets[i]
+			backupRecord, err := backupTarget(ctx, services, config, target, opts)
+			if err != nil {
+				backupRecord = BackupRecord{
+					Target:    target.Name,
+					Type:      target.Type,
+					Status:    "failed",
+					StartTime: time.Now(),
+					EndTime:   time.Now(),
+					Error:     err.Error(),
+				}
+			}
+			records[i], errs[i] = backupRecord, err
+
+			if opts.Verbose {
+				status := "✅"
+				if backupRecord.Status == "failed" {
+					status = "❌"
+				} else if opts.DryRun {
+					status = "🧪"
+				}
+
+				progress.Lock()
+				fmt.Fprintf(stdout, "%s %s.%s: %s (%d resources, %s)\n",
+					status, backupRecord.Type, backupRecord.Target,
+					backupRecord.Status, backupRecord.ResourceCount,
+					formatBytes(backupRecord.Size))
+				progress.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
 
-		backupRecord, err := backupTarget(ctx, services, config, &target, opts)
-		if err != nil {
+	var totalSize int64
+	var totalResources int
+	for i, backupRecord := range records {
+		if errs[i] != nil {
 			result.Success = false
-			result.Errors = append(result.Errors, fmt.Sprintf("Target %s failed: %v", target.Name, err))
-			backupRecord = BackupRecord{
-				Target:    target.Name,
-				Type:      target.Type,
-				Status:    "failed",
-				StartTime: time.Now(),
-				EndTime:   time.Now(),
-				Error:     err.Error(),
-			}
+			result.Errors = append(result.Errors, fmt.Sprintf("Target %s failed: %v", targets[i].Name, errs[i]))
 		}
-
 		result.Backups = append(result.Backups, backupRecord)
 		totalSize += backupRecord.Size
 		totalResources += backupRecord.ResourceCount
-
-		if opts.Verbose {
-			status := "✅"
-			if backupRecord.Status == "failed" {
-				status = "❌"
-			} else if opts.DryRun {
-				status = "🧪"
-			}
-
-			fmt.Printf("%s %s.%s: %s (%d resources, %s)\n",
-				status, backupRecord.Type, backupRecord.Target,
-				backupRecord.Status, backupRecord.ResourceCount,
-				formatBytes(backupRecord.Size))
-		}
 	}
 
 	result.Duration = time.Since(startTime)
@@ -433,7 +553,7 @@ func backupTarget(ctx context.Context, services *backupServices, config *BackupC
 	case "compute":
 		return backupCompute(ctx, services.Compute, config, target, opts)
 	case "storage":
-		return backupStorage(ctx, services.Storage, config, target, opts)
+		return backupStorage(ctx, services.Objects, config, target, opts)
 	case "iam":
 		return backupIAM(ctx, services.IAM, config, target, opts)
 	case "secrets":
@@ -484,7 +604,7 @@ func backupCompute(ctx context.Context, service *gcp.ComputeService, config *Bac
 	return record, nil
 }
 
-func backupStorage(ctx context.Context, service *gcp.StorageService, config *BackupConfig, target *BackupTarget, opts *backupOptions) (BackupRecord, error) {
+func backupStorage(ctx context.Context, store objectStore, config *BackupConfig, target *BackupTarget, opts *backupOptions) (BackupRecord, error) {
 	record := BackupRecord{
 		Target:    target.Name,
 		Type:      "storage",
@@ -493,24 +613,44 @@ func backupStorage(ctx context.Context, service *gcp.StorageService, config *Bac
 		Details:   make(map[string]interface{}),
 	}
 
-	// In a real implementation, this would:
-	// 1. List all storage buckets matching the resources filter
-	// 2. Copy objects to backup bucket with versioning
-	// 3. Export bucket metadata, ACLs, and lifecycle policies
-	// 4. Create backup manifest with object checksums
+	buckets, err := storageTargetBuckets(ctx, store, config, target)
+	if err != nil {
+		return record, err
+	}
+	sink, err := newBackupSink(store, config)
+	if err != nil {
+		return record, err
+	}
+
+	// Incremental runs copy only what changed since the bucket's manifest
+	incremental := opts.Incremental
+	if enabled, ok := target.Config["incremental"].(bool); ok {
+		incremental = incremental || enabled
+	}
+	runID := fmt.Sprintf("%d", now().Unix())
+	paths := storageBackupPaths{config: config, target: target}
+
+	checksum := sha256.New()
+	var outcomes []*bucketBackup
+	for _, bucket := range buckets {
+		outcome, err := backupBucket(ctx, store, sink, paths, bucket, runID, incremental, opts.DryRun)
+		if err != nil {
+			return record, err
+		}
+		outcomes = append(outcomes, outcome)
+		record.ResourceCount += outcome.Copied
+		record.Size += outcome.Bytes
+		checksum.Write(outcome.checksum)
+	}
+	record.Details["incremental"] = incremental
+	record.Details["buckets"] = outcomes
 
 	if opts.DryRun {
 		record.Status = "dry-run"
-		record.ResourceCount = 15 // Simulated count
-		record.Size = 1024 * 1024 * 1024 * 50 // 50GB simulated
 	} else {
-		// Simulated backup operation
-		record.ResourceCount = 15
-		record.Size = 1024 * 1024 * 1024 * 50 // 50GB
-		record.Location = fmt.Sprintf("gs://%s/%s/storage/%s-%d",
-			config.Storage.Bucket, config.Storage.Path,
-			target.Name, time.Now().Unix())
-		record.Checksum = "sha256:fedcba987654321" // Simulated checksum
+		record.Location = fmt.Sprintf("gs://%s/%s",
+			config.Storage.Bucket, paths.object(runID, "", ""))
+		record.Checksum = fmt.Sprintf("sha256:%x", checksum.Sum(nil))
 	}
 
 	record.EndTime = time.Now()
@@ -624,18 +764,6 @@ func backupMonitoring(ctx context.Context, service *gcp.MonitoringService, confi
 	return record, nil
 }
 
-func listBackups(ctx context.Context, services *backupServices, config *BackupConfig) (interface{}, error) {
-	// Implementation would list existing backups from storage
-	return map[string]interface{}{
-		"backups": []string{
-			"compute-vm-instances-1640995200",
-			"storage-buckets-1640995200",
-			"iam-policies-1640995200",
-		},
-		"total": 3,
-	}, nil
-}
-
 func verifyBackups(ctx context.Context, services *backupServices, config *BackupConfig) (interface{}, error) {
 	// Implementation would verify backup integrity
 	return map[string]interface{}{
@@ -697,7 +825,7 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-func outputBackupResults(file *os.File, result interface{}, format string, verbose bool) {
+func outputBackupResults(file io.Writer, result interface{}, format string, verbose bool) {
 	switch format {
 	case "json":
 		output, _ := json.MarshalIndent(result, "", "  ")
@@ -713,7 +841,7 @@ func outputBackupResults(file *os.File, result interface{}, format string, verbo
 	}
 }
 
-func printBackupTextResults(file *os.File, result *BackupResult, verbose bool) {
+func printBackupTextResults(file io.Writer, result *BackupResult, verbose bool) {
 	timestamp := result.Timestamp.Format("2006-01-02 15:04:05")
 	fmt.Fprintf(file, "💾 Backup Report - %s\n", timestamp)
 
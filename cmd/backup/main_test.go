@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrentStore holds every bucket listing until want of them are in
+// flight, or hold has passed, and records the most seen at once
+type concurrentStore struct {
+	*fakeObjectStore
+	want     int
+	hold     time.Duration
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	release  chan struct{}
+}
+
+func newConcurrentStore(want int) *concurrentStore {
+	return &concurrentStore{fakeObjectStore: newFakeObjectStore(), want: want, hold: time.Second, release: make(chan struct{})}
+}
+
+func (s *concurrentStore) ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+		if s.peak == s.want {
+			close(s.release)
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.release:
+	case <-time.After(s.hold):
+	}
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return s.fakeObjectStore.ListObjects(ctx, bucket, prefix)
+}
+
+func parallelTestConfig(store *concurrentStore, buckets int) *BackupConfig {
+	config := getDefaultBackupConfig("my-project", "us-central1", "us-central1-a")
+	config.BackupTargets = nil
+	for i := 0; i < buckets; i++ {
+		bucket := fmt.Sprintf("bucket-%d", i)
+		store.put(bucket, "object.txt", []byte("data"))
+		config.BackupTargets = append(config.BackupTargets, BackupTarget{Type: "storage", Name: bucket, Resources: []string{bucket}, Enabled: true})
+	}
+	return &config
+}
+
+func TestPerformBackupRunsTargetsInParallel(t *testing.T) {
+	store := newConcurrentStore(3)
+	config := parallelTestConfig(store, 3)
+	// Fails straight away without holding up the others
+	config.BackupTargets = append(config.BackupTargets, BackupTarget{Type: "tape", Name: "archive", Enabled: true})
+
+	result, err := performBackup(context.Background(), &backupServices{Objects: store}, config, &backupOptions{Parallel: 3})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, store.peak)
+	assert.False(t, result.Success)
+	assert.Equal(t, []string{"Target archive failed: unsupported backup target type: tape"}, result.Errors)
+	assert.Equal(t, 4, result.Summary["total_targets"])
+	assert.Equal(t, 3, result.Summary["successful"])
+	assert.Equal(t, 1, result.Summary["failed"])
+	assert.Equal(t, 3, result.Summary["total_resources"])
+	assert.Equal(t, int64(12), result.TotalSize)
+
+	// Records keep the configured order whatever order targets finish in
+	var names []string
+	for _, record := range result.Backups {
+		names = append(names, record.Target)
+	}
+	assert.Equal(t, []string{"bucket-0", "bucket-1", "bucket-2", "archive"}, names)
+}
+
+func TestPerformBackupHonorsParallelLimit(t *testing.T) {
+	store := newConcurrentStore(2)
+	store.hold = 50 * time.Millisecond
+	config := parallelTestConfig(store, 3)
+
+	result, err := performBackup(context.Background(), &backupServices{Objects: store}, config, &backupOptions{Parallel: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, store.peak)
+	assert.True(t, result.Success)
+	assert.Equal(t, 3, result.Summary["successful"])
+}
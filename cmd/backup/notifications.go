@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+)
+
+// notifyTimeout bounds sending the completion notification. It gets its own
+// deadline so an operation that ran out of time is still reported.
+const notifyTimeout = 30 * time.Second
+
+// notifyOperation reports how operation went to the configured channels,
+// when notifications are enabled for that outcome. result is what the
+// operation returned, opErr the error it failed with.
+func notifyOperation(ctx context.Context, config *BackupConfig, operation string, result interface{}, opErr error) error {
+	notification := config.Notification
+	if !notification.Enabled || len(notification.Channels) == 0 {
+		return nil
+	}
+
+	msg := operationMessage(config.ProjectID, operation, result, opErr)
+	failed := msg.Severity == "error"
+	if (failed && !notification.OnFailure) || (!failed && !notification.OnSuccess) {
+		return nil
+	}
+	return notify.Send(ctx, notification.Channels, msg)
+}
+
+// operationMessage summarizes an operation's outcome
+func operationMessage(projectID, operation string, result interface{}, opErr error) notify.Message {
+	msg := notify.Message{
+		Severity: "info",
+		Source:   "backup",
+		Fields:   map[string]interface{}{"project": projectID, "operation": operation},
+	}
+
+	switch r := result.(type) {
+	case *BackupResult:
+		if r != nil {
+			msg.Text = fmt.Sprintf("%d of %d targets backed up, %s in %v",
+				countSuccessful(r.Backups), len(r.Backups), formatBytes(r.TotalSize), r.Duration)
+			if len(r.Errors) > 0 {
+				msg.Text += "\n" + strings.Join(r.Errors, "\n")
+			}
+			msg.Fields["successful"] = countSuccessful(r.Backups)
+			msg.Fields["failed"] = countFailed(r.Backups)
+			msg.Fields["total_size"] = r.TotalSize
+			if !r.Success {
+				msg.Severity = "error"
+			}
+		}
+	case *RestoreReadiness:
+		if r != nil {
+			msg.Text = fmt.Sprintf("backup %s is not ready to restore", r.BackupID)
+			if r.Ready {
+				msg.Text = fmt.Sprintf("backup %s is ready to restore (%d objects)", r.BackupID, r.Objects)
+			}
+			for _, check := range r.Checks {
+				if check.Status == checkFail {
+					msg.Text += "\n" + check.Detail
+				}
+			}
+			msg.Fields["backup_id"] = r.BackupID
+			if !r.Ready {
+				msg.Severity = "error"
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range r {
+			msg.Fields[key] = value
+		}
+	}
+
+	if opErr != nil {
+		msg.Severity = "error"
+		msg.Text = opErr.Error()
+	}
+	if msg.Severity == "error" {
+		msg.Title = fmt.Sprintf("%s failed for %s", operationTitle(operation), projectID)
+	} else {
+		msg.Title = fmt.Sprintf("%s succeeded for %s", operationTitle(operation), projectID)
+	}
+	return msg
+}
+
+func operationTitle(operation string) string {
+	if operation == "" {
+		return operation
+	}
+	return strings.ToUpper(operation[:1]) + operation[1:]
+}
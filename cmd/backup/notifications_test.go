@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+)
+
+// notificationServer records the messages posted to its webhook
+func notificationServer(t *testing.T) (*httptest.Server, func() []notify.Message) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []notify.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []notify.Message {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]notify.Message(nil), received...)
+	}
+}
+
+func notificationTestConfig(url string) *BackupConfig {
+	config := getDefaultBackupConfig("my-project", "us-central1", "us-central1-a")
+	config.Notification = NotificationConfig{
+		Enabled:   true,
+		Channels:  []notify.Channel{{Type: "webhook", Config: map[string]interface{}{"url": url}}},
+		OnSuccess: false,
+		OnFailure: true,
+	}
+	return &config
+}
+
+func TestNotifyOperationOnFailure(t *testing.T) {
+	server, received := notificationServer(t)
+	config := notificationTestConfig(server.URL)
+	result := &BackupResult{
+		Success: false,
+		Backups: []BackupRecord{{Target: "buckets", Status: "success", Size: 2048}, {Target: "archive", Status: "failed"}},
+		Errors:  []string{"Target archive failed: unsupported backup target type: tape"},
+	}
+
+	require.NoError(t, notifyOperation(context.Background(), config, "backup", result, nil))
+
+	messages := received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Backup failed for my-project", messages[0].Title)
+	assert.Equal(t, "error", messages[0].Severity)
+	assert.Contains(t, messages[0].Text, "1 of 2 targets backed up")
+	assert.Contains(t, messages[0].Text, "unsupported backup target type: tape")
+	assert.Equal(t, float64(1), messages[0].Fields["failed"])
+}
+
+func TestNotifyOperationError(t *testing.T) {
+	server, received := notificationServer(t)
+	config := notificationTestConfig(server.URL)
+
+	require.NoError(t, notifyOperation(context.Background(), config, "cleanup", nil, errors.New("bucket not found")))
+
+	messages := received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Cleanup failed for my-project", messages[0].Title)
+	assert.Equal(t, "bucket not found", messages[0].Text)
+}
+
+func TestNotifyOperationSkipsSuccessWhenNotWanted(t *testing.T) {
+	server, received := notificationServer(t)
+	config := notificationTestConfig(server.URL)
+	result := &BackupResult{Success: true, Backups: []BackupRecord{{Target: "buckets", Status: "success"}}}
+
+	require.NoError(t, notifyOperation(context.Background(), config, "backup", result, nil))
+	assert.Empty(t, received())
+
+	config.Notification.OnSuccess = true
+	require.NoError(t, notifyOperation(context.Background(), config, "backup", result, nil))
+	messages := received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Backup succeeded for my-project", messages[0].Title)
+	assert.Equal(t, "info", messages[0].Severity)
+}
+
+func TestNotifyOperationDisabled(t *testing.T) {
+	server, received := notificationServer(t)
+	config := notificationTestConfig(server.URL)
+	config.Notification.Enabled = false
+
+	require.NoError(t, notifyOperation(context.Background(), config, "backup", &BackupResult{Success: false}, nil))
+	assert.Empty(t, received())
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+)
+
+// Outcomes of a restore check; failures make a backup not ready, warnings
+// only need the operator's attention
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// RestoreCheck is one thing verified before a restore
+type RestoreCheck struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RestoreReadiness is the verdict of restore -verify-only
+type RestoreReadiness struct {
+	BackupID string         `json:"backup_id"`
+	Target   string         `json:"target"`
+	Time     time.Time      `json:"time,omitempty"`
+	Objects  int            `json:"objects"`
+	Ready    bool           `json:"ready"`
+	Checks   []RestoreCheck `json:"checks"`
+}
+
+func (r *RestoreReadiness) add(check, status, detail string, args ...interface{}) {
+	r.Checks = append(r.Checks, RestoreCheck{Check: check, Status: status, Detail: fmt.Sprintf(detail, args...)})
+	if status == checkFail {
+		r.Ready = false
+	}
+}
+
+// parseBackupID splits a backup ID of the form <target>-<unix time>, as
+// used in backup locations. A bare target name selects the latest run, or
+// the last one before restoreTime. Suffixes too short to be a timestamp
+// stay part of the target name.
+func parseBackupID(backupID string) (target, runID string) {
+	if i := strings.LastIndex(backupID, "-"); i > 0 && len(backupID)-i > 9 {
+		if _, err := strconv.ParseInt(backupID[i+1:], 10, 64); err == nil {
+			return backupID[:i], backupID[i+1:]
+		}
+	}
+	return backupID, ""
+}
+
+// diskSnapshots looks up disks and the snapshots taken of them
+type diskSnapshots interface {
+	GetDisk(ctx context.Context, project, zone, name string) (*computepb.Disk, error)
+	ListDiskSnapshots(ctx context.Context, project, zone, disk string) ([]*computepb.Snapshot, error)
+}
+
+// verifyRestore checks that a backup can be restored without changing
+// anything. For a storage backup its manifests must decrypt and parse,
+// every object copy they reference must still exist with the recorded
+// size, and restoring must not silently replace objects that have changed
+// since. For a compute backup every disk of the target must have a ready
+// snapshot taken by then.
+func verifyRestore(ctx context.Context, store objectStore, snapshots diskSnapshots, config *BackupConfig, backupID, restoreTime string) (*RestoreReadiness, error) {
+	targetName, runID := parseBackupID(backupID)
+	var target *BackupTarget
+	for i := range config.BackupTargets {
+		if config.BackupTargets[i].Name == targetName {
+			target = &config.BackupTargets[i]
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("backup %s: no backup target named %s", backupID, targetName)
+	}
+	if target.Type != "storage" && target.Type != "compute" {
+		return nil, fmt.Errorf("backup %s: verifying %s backups is not supported", backupID, target.Type)
+	}
+
+	at := now()
+	if restoreTime != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, restoreTime); err != nil {
+			return nil, fmt.Errorf("invalid restore time %q: %w", restoreTime, err)
+		}
+	}
+	if target.Type == "compute" {
+		if runID != "" {
+			seconds, _ := strconv.ParseInt(runID, 10, 64)
+			at = time.Unix(seconds, 0).UTC()
+		}
+		return verifyComputeRestore(ctx, snapshots, config, target, backupID, at), nil
+	}
+
+	sink, err := newBackupSink(store, config)
+	if err != nil {
+		return nil, err
+	}
+	readiness := &RestoreReadiness{BackupID: backupID, Target: targetName, Ready: true, Checks: []RestoreCheck{}}
+	paths := storageBackupPaths{config: config, target: target}
+
+	prefix := paths.manifests() + "/"
+	listed, err := store.ListObjects(ctx, config.Storage.Bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifests: %w", err)
+	}
+	var manifests []string
+	for _, object := range listed {
+		if strings.HasSuffix(object.Name, ".manifest.json") {
+			manifests = append(manifests, object.Name)
+		}
+	}
+	if len(manifests) == 0 {
+		readiness.add("manifest", checkFail, "no manifests under gs://%s/%s", config.Storage.Bucket, prefix)
+		return readiness, nil
+	}
+	sort.Strings(manifests)
+
+	for _, name := range manifests {
+		bucket := strings.TrimSuffix(path.Base(name), ".manifest.json")
+		manifest, err := readStorageManifest(ctx, sink, name, bucket)
+		if err != nil {
+			readiness.add("manifest", checkFail, "%v", err)
+			continue
+		}
+		if problem := manifestProblem(manifest, bucket); problem != "" {
+			readiness.add("manifest", checkFail, "%s: %s", name, problem)
+			continue
+		}
+		run, ok := manifestRun(manifest, runID, at)
+		if !ok {
+			readiness.add("manifest", checkFail, "%s has no run matching %s", name, backupID)
+			continue
+		}
+		readiness.Time = run.Time
+		readiness.add("manifest", checkPass, "%s run %s", name, run.ID)
+
+		verifyBucketRestore(ctx, store, sink, readiness, bucket, manifest.Snapshot(run.Time))
+	}
+	return readiness, nil
+}
+
+// verifyComputeRestore checks that every disk of target has a ready
+// snapshot taken at or before at, the latest of which a restore would use,
+// and warns about disks a restore would replace
+func verifyComputeRestore(ctx context.Context, snapshots diskSnapshots, config *BackupConfig, target *BackupTarget, backupID string, at time.Time) *RestoreReadiness {
+	readiness := &RestoreReadiness{BackupID: backupID, Target: target.Name, Time: at, Ready: true, Checks: []RestoreCheck{}}
+	disks := targetDisks(target)
+	if len(disks) == 0 {
+		readiness.add("snapshots", checkFail, "target %s lists no disks", target.Name)
+		return readiness
+	}
+
+	var missing, unusable, ready, existing []string
+	for _, disk := range disks {
+		taken, err := snapshots.ListDiskSnapshots(ctx, config.ProjectID, config.Zone, disk)
+		if err != nil {
+			unusable = append(unusable, fmt.Sprintf("%s (%v)", disk, err))
+			continue
+		}
+		snapshot := latestSnapshot(taken, at)
+		switch {
+		case snapshot == nil:
+			missing = append(missing, disk)
+		case snapshot.GetStatus() != "READY":
+			unusable = append(unusable, fmt.Sprintf("%s (snapshot %s is %s)", disk, snapshot.GetName(), snapshot.GetStatus()))
+		default:
+			readiness.Objects++
+			ready = append(ready, fmt.Sprintf("%s=%s", disk, snapshot.GetName()))
+		}
+
+		_, err = snapshots.GetDisk(ctx, config.ProjectID, config.Zone, disk)
+		switch {
+		case err == nil:
+			existing = append(existing, disk)
+		case !isNotFound(err):
+			readiness.add("collisions", checkFail, "disk %s: failed to get: %v", disk, err)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		readiness.add("snapshots", checkFail, "%d disk(s) have no snapshot taken by %s: %s", len(missing), at.Format(time.RFC3339), strings.Join(missing, ", "))
+	case len(unusable) > 0:
+		readiness.add("snapshots", checkFail, "%d disk snapshot(s) not usable: %s", len(unusable), strings.Join(unusable, ", "))
+	default:
+		readiness.add("snapshots", checkPass, "%d snapshot(s) ready: %s", len(ready), strings.Join(ready, ", "))
+	}
+	if len(existing) > 0 {
+		readiness.add("collisions", checkWarn, "restoring would replace %d existing disk(s): %s", len(existing), strings.Join(existing, ", "))
+	} else {
+		readiness.add("collisions", checkPass, "no disk would be replaced")
+	}
+	return readiness
+}
+
+// latestSnapshot returns the last snapshot created at or before at
+func latestSnapshot(snapshots []*computepb.Snapshot, at time.Time) *computepb.Snapshot {
+	var latest *computepb.Snapshot
+	var latestTime time.Time
+	for _, snapshot := range snapshots {
+		created, err := time.Parse(time.RFC3339, snapshot.GetCreationTimestamp())
+		if err != nil || created.After(at) {
+			continue
+		}
+		if latest == nil || created.After(latestTime) {
+			latest, latestTime = snapshot, created
+		}
+	}
+	return latest
+}
+
+// verifyBucketRestore checks the copies of objects exist and looks for
+// objects in the bucket that restoring them would overwrite
+func verifyBucketRestore(ctx context.Context, store objectStore, sink *backupSink, readiness *RestoreReadiness, bucket string, objects map[string]ManifestObject) {
+	var missing, unreadable []string
+	for _, object := range objects {
+		readiness.Objects++
+		info, err := store.StatObject(ctx, sink.bucket, object.Location)
+		switch {
+		case errors.Is(err, errObjectNotExist):
+			missing = append(missing, object.Name)
+		case err != nil:
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", object.Name, err))
+		case sink.aead == nil && info.Size != object.Size:
+			// Client-side encryption changes the size, so only plain
+			// copies are compared
+			unreadable = append(unreadable, fmt.Sprintf("%s (size %d, expected %d)", object.Name, info.Size, object.Size))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unreadable)
+	switch {
+	case len(missing) > 0:
+		readiness.add("objects", checkFail, "gs://%s: %d backed up object(s) missing: %s", bucket, len(missing), strings.Join(missing, ", "))
+	case len(unreadable) > 0:
+		readiness.add("objects", checkFail, "gs://%s: %d backed up object(s) not usable: %s", bucket, len(unreadable), strings.Join(unreadable, ", "))
+	default:
+		readiness.add("objects", checkPass, "gs://%s: %d object(s) available", bucket, len(objects))
+	}
+
+	current, err := store.ListObjects(ctx, bucket, "")
+	if err != nil {
+		readiness.add("collisions", checkFail, "gs://%s: failed to list: %v", bucket, err)
+		return
+	}
+	var collisions []string
+	for _, object := range current {
+		if backedUp, ok := objects[object.Name]; ok && backedUp.ETag != object.ETag {
+			collisions = append(collisions, object.Name)
+		}
+	}
+	if len(collisions) > 0 {
+		readiness.add("collisions", checkWarn, "gs://%s: restoring would overwrite %d changed object(s): %s", bucket, len(collisions), strings.Join(collisions, ", "))
+	} else {
+		readiness.add("collisions", checkPass, "gs://%s: no changed objects would be overwritten", bucket)
+	}
+}
+
+// manifestProblem describes what is wrong with a manifest, if anything
+func manifestProblem(manifest *StorageManifest, bucket string) string {
+	if manifest.Bucket != bucket {
+		return fmt.Sprintf("records bucket %q", manifest.Bucket)
+	}
+	for i, run := range manifest.Runs {
+		if run.ID == "" || run.Time.IsZero() {
+			return fmt.Sprintf("run %d has no id or time", i)
+		}
+		if i > 0 && run.Time.Before(manifest.Runs[i-1].Time) {
+			return fmt.Sprintf("run %s is out of order", run.ID)
+		}
+		for _, object := range run.Copied {
+			if object.Name == "" || object.Location == "" {
+				return fmt.Sprintf("run %s has an object without a name or location", run.ID)
+			}
+		}
+	}
+	return ""
+}
+
+// manifestRun finds the run with runID or, without one, the last run at or
+// before at
+func manifestRun(manifest *StorageManifest, runID string, at time.Time) (ManifestRun, bool) {
+	var found ManifestRun
+	var ok bool
+	for _, run := range manifest.Runs {
+		if runID != "" && run.ID == runID {
+			return run, true
+		}
+		if runID == "" && !run.Time.After(at) {
+			found, ok = run, true
+		}
+	}
+	return found, ok
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// backedUpStore returns a store holding one backup run of assets-bucket,
+// taken at 1772330400
+func backedUpStore(t *testing.T) (*fakeObjectStore, *BackupConfig) {
+	t.Helper()
+	withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("a"))
+	store.put("assets-bucket", "b.txt", []byte("b"))
+	config, target := storageTestConfig()
+
+	_, err := backupStorage(context.Background(), store, config, target, &backupOptions{})
+	require.NoError(t, err)
+	return store, config
+}
+
+func checkStatuses(readiness *RestoreReadiness) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range readiness.Checks {
+		statuses[check.Check] = check.Status
+	}
+	return statuses
+}
+
+func TestVerifyRestoreReady(t *testing.T) {
+	store, config := backedUpStore(t)
+
+	readiness, err := verifyRestore(context.Background(), store, nil, config, "assets-1772330400", "")
+	require.NoError(t, err)
+	assert.True(t, readiness.Ready, "%+v", readiness.Checks)
+	assert.Equal(t, 2, readiness.Objects)
+	assert.Equal(t, map[string]string{"manifest": checkPass, "objects": checkPass, "collisions": checkPass}, checkStatuses(readiness))
+}
+
+func TestVerifyRestoreMissingCopyIsNotReady(t *testing.T) {
+	store, config := backedUpStore(t)
+	store.remove(config.Storage.Bucket, "automated-backups/storage/assets-1772330400/assets-bucket/b.txt")
+
+	readiness, err := verifyRestore(context.Background(), store, nil, config, "assets-1772330400", "")
+	require.NoError(t, err)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, checkFail, checkStatuses(readiness)["objects"])
+	assert.Contains(t, readiness.Checks[1].Detail, "missing: b.txt")
+}
+
+func TestVerifyRestoreWarnsAboutOverwrites(t *testing.T) {
+	store, config := backedUpStore(t)
+	store.put("assets-bucket", "a.txt", []byte("a, edited since"))
+
+	// A bare target name picks the last run before the restore time
+	readiness, err := verifyRestore(context.Background(), store, nil, config, "assets", "2026-03-02T00:00:00Z")
+	require.NoError(t, err)
+	assert.True(t, readiness.Ready)
+	assert.Equal(t, checkWarn, checkStatuses(readiness)["collisions"])
+	assert.Contains(t, readiness.Checks[2].Detail, "a.txt")
+}
+
+func TestVerifyRestoreCorruptManifest(t *testing.T) {
+	store, config := backedUpStore(t)
+	store.put(config.Storage.Bucket, "automated-backups/storage/assets/assets-bucket.manifest.json", []byte("{not json"))
+
+	readiness, err := verifyRestore(context.Background(), store, nil, config, "assets-1772330400", "")
+	require.NoError(t, err)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, checkFail, checkStatuses(readiness)["manifest"])
+}
+
+func TestVerifyRestoreUnknownBackup(t *testing.T) {
+	store, config := backedUpStore(t)
+
+	readiness, err := verifyRestore(context.Background(), store, nil, config, "assets-1000000000", "")
+	require.NoError(t, err)
+	assert.False(t, readiness.Ready)
+
+	_, err = verifyRestore(context.Background(), store, nil, config, "photos-1772330400", "")
+	assert.ErrorContains(t, err, "no backup target named photos")
+}
+
+// snapshotsFake adds the snapshots taken of each disk to fakeComputeClient
+type snapshotsFake struct {
+	*fakeComputeClient
+	snapshots map[string][]*computepb.Snapshot
+}
+
+func (f *snapshotsFake) ListDiskSnapshots(_ context.Context, project, zone, disk string) ([]*computepb.Snapshot, error) {
+	return f.snapshots[disk], nil
+}
+
+func (f *snapshotsFake) take(disk, name, status string, at time.Time) {
+	f.snapshots[disk] = append(f.snapshots[disk], &computepb.Snapshot{
+		Name:              &name,
+		Status:            &status,
+		CreationTimestamp: proto.String(at.Format(time.RFC3339)),
+	})
+}
+
+// snapshottedDisks returns disks web-1 and web-2, each with a ready
+// snapshot taken at 1772330400, and a config backing them up as target vms
+func snapshottedDisks(t *testing.T) (*snapshotsFake, *BackupConfig) {
+	t.Helper()
+	withClock(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+	fake := &snapshotsFake{fakeComputeClient: newFakeComputeClient(), snapshots: map[string][]*computepb.Snapshot{}}
+	taken := time.Unix(1772330400, 0)
+	fake.take("web-1", "web-1-20260301", "READY", taken)
+	fake.take("web-2", "web-2-20260301", "READY", taken)
+	// Taken after the restore time, so never picked
+	fake.take("web-2", "web-2-20260303", "READY", taken.Add(48*time.Hour))
+
+	config := scheduleTestConfig()
+	config.BackupTargets = []BackupTarget{{Type: "compute", Name: "vms", Resources: []string{"web-1", "web-2"}, Enabled: true}}
+	return fake, config
+}
+
+func TestVerifyComputeRestoreReady(t *testing.T) {
+	fake, config := snapshottedDisks(t)
+
+	readiness, err := verifyRestore(context.Background(), nil, fake, config, "vms", "")
+	require.NoError(t, err)
+	assert.True(t, readiness.Ready, "%+v", readiness.Checks)
+	assert.Equal(t, 2, readiness.Objects)
+	assert.Equal(t, map[string]string{"snapshots": checkPass, "collisions": checkPass}, checkStatuses(readiness))
+	assert.Equal(t, "2 snapshot(s) ready: web-1=web-1-20260301, web-2=web-2-20260301", readiness.Checks[0].Detail)
+
+	// Restoring over a disk that still exists needs the operator's attention
+	fake.disks["web-1"] = &computepb.Disk{Name: proto.String("web-1")}
+	readiness, err = verifyRestore(context.Background(), nil, fake, config, "vms-1772330400", "")
+	require.NoError(t, err)
+	assert.True(t, readiness.Ready)
+	assert.Equal(t, checkWarn, checkStatuses(readiness)["collisions"])
+}
+
+func TestVerifyComputeRestoreDeletedSnapshotIsNotReady(t *testing.T) {
+	fake, config := snapshottedDisks(t)
+	delete(fake.snapshots, "web-1")
+	fake.snapshots["web-2"][0].Status = proto.String("DELETING")
+
+	readiness, err := verifyRestore(context.Background(), nil, fake, config, "vms", "")
+	require.NoError(t, err)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, checkFail, checkStatuses(readiness)["snapshots"])
+	assert.Equal(t, "1 disk(s) have no snapshot taken by 2026-03-02T00:00:00Z: web-1", readiness.Checks[0].Detail)
+
+	fake.take("web-1", "web-1-20260301", "READY", time.Unix(1772330400, 0))
+	readiness, err = verifyRestore(context.Background(), nil, fake, config, "vms", "")
+	require.NoError(t, err)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, "1 disk snapshot(s) not usable: web-2 (snapshot web-2-20260301 is DELETING)", readiness.Checks[0].Detail)
+}
+
+func TestParseBackupID(t *testing.T) {
+	target, run := parseBackupID("vm-instances-1640995200")
+	assert.Equal(t, "vm-instances", target)
+	assert.Equal(t, "1640995200", run)
+
+	target, run = parseBackupID("vm-instances")
+	assert.Equal(t, "vm-instances", target)
+	assert.Empty(t, run)
+
+	target, run = parseBackupID("web-2")
+	assert.Equal(t, "web-2", target)
+	assert.Empty(t, run)
+}
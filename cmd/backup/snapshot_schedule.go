@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// snapshotScheduleClient manages compute resource policies and attaches
+// them to disks
+type snapshotScheduleClient interface {
+	GetResourcePolicy(ctx context.Context, project, region, name string) (*computepb.ResourcePolicy, error)
+	InsertResourcePolicy(ctx context.Context, project, region string, policy *computepb.ResourcePolicy) error
+	GetDisk(ctx context.Context, project, zone, name string) (*computepb.Disk, error)
+	AddDiskResourcePolicy(ctx context.Context, project, zone, disk, policy string) error
+}
+
+// SnapshotScheduleResult reports what EnsureSnapshotSchedule did
+type SnapshotScheduleResult struct {
+	Policy  string `json:"policy"`
+	Created bool   `json:"created"`
+	// AttachedDisks lists every target disk carrying the policy, Newly the
+	// ones attached by this run
+	AttachedDisks []string `json:"attached_disks"`
+	Newly         []string `json:"newly_attached,omitempty"`
+}
+
+// EnsureSnapshotSchedule makes the snapshot schedule policy name exist in
+// the configured region with the schedule and retention of config, and
+// attaches it to disks in the configured zone. Running it again changes
+// nothing; a policy of that name with a different spec is an error, as
+// resource policies can't be updated in place.
+func EnsureSnapshotSchedule(ctx context.Context, client snapshotScheduleClient, config *BackupConfig, name string, disks []string) (*SnapshotScheduleResult, error) {
+	want, err := snapshotSchedulePolicy(config, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SnapshotScheduleResult{Policy: name, AttachedDisks: []string{}}
+	existing, err := client.GetResourcePolicy(ctx, config.ProjectID, config.Region, name)
+	switch {
+	case isNotFound(err):
+		if err := client.InsertResourcePolicy(ctx, config.ProjectID, config.Region, want); err != nil {
+			return nil, fmt.Errorf("failed to create resource policy %s: %w", name, err)
+		}
+		result.Created = true
+	case err != nil:
+		return nil, fmt.Errorf("failed to get resource policy %s: %w", name, err)
+	case !sameSnapshotSchedule(existing.GetSnapshotSchedulePolicy(), want.GetSnapshotSchedulePolicy()):
+		return nil, fmt.Errorf("resource policy %s already exists with a different snapshot schedule", name)
+	}
+
+	for _, diskName := range disks {
+		disk, err := client.GetDisk(ctx, config.ProjectID, config.Zone, diskName)
+		if err != nil {
+			return result, fmt.Errorf("failed to get disk %s: %w", diskName, err)
+		}
+		if !hasResourcePolicy(disk.GetResourcePolicies(), name) {
+			if err := client.AddDiskResourcePolicy(ctx, config.ProjectID, config.Zone, diskName, resourcePolicyURL(config, name)); err != nil {
+				return result, fmt.Errorf("failed to attach %s to disk %s: %w", name, diskName, err)
+			}
+			result.Newly = append(result.Newly, diskName)
+		}
+		result.AttachedDisks = append(result.AttachedDisks, diskName)
+	}
+	return result, nil
+}
+
+// snapshotSchedulePolicy translates the schedule and retention settings of
+// config into a snapshot schedule resource policy
+func snapshotSchedulePolicy(config *BackupConfig, name string) (*computepb.ResourcePolicy, error) {
+	schedule, err := snapshotSchedule(config.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	days := retentionDays(config.Retention)
+	if days < 1 {
+		return nil, fmt.Errorf("retention: at least one of daily, weekly, monthly or yearly must be set")
+	}
+
+	return &computepb.ResourcePolicy{
+		Name:        proto.String(name),
+		Description: proto.String("Snapshot schedule managed by terragrunt-gcp backup"),
+		SnapshotSchedulePolicy: &computepb.ResourcePolicySnapshotSchedulePolicy{
+			Schedule: schedule,
+			RetentionPolicy: &computepb.ResourcePolicySnapshotSchedulePolicyRetentionPolicy{
+				MaxRetentionDays:   proto.Int32(int32(days)),
+				OnSourceDiskDelete: proto.String(computepb.ResourcePolicySnapshotSchedulePolicyRetentionPolicy_KEEP_AUTO_SNAPSHOTS.String()),
+			},
+			SnapshotProperties: &computepb.ResourcePolicySnapshotSchedulePolicySnapshotProperties{
+				StorageLocations: []string{config.Region},
+				Labels:           map[string]string{"managed-by": "terragrunt-gcp-backup"},
+			},
+		},
+	}, nil
+}
+
+// snapshotSchedule converts schedule to a snapshot cycle. Compute only
+// supports hourly, daily and weekly cycles starting on the hour in UTC.
+func snapshotSchedule(schedule ScheduleConfig) (*computepb.ResourcePolicySnapshotSchedulePolicySchedule, error) {
+	start, err := utcStartTime(schedule.Time, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(schedule.Frequency) {
+	case "hourly":
+		hours := int32(schedule.Interval / time.Hour)
+		if hours < 1 {
+			hours = 1
+		}
+		return &computepb.ResourcePolicySnapshotSchedulePolicySchedule{
+			HourlySchedule: &computepb.ResourcePolicyHourlyCycle{HoursInCycle: proto.Int32(hours), StartTime: proto.String(start)},
+		}, nil
+	case "", "daily":
+		return &computepb.ResourcePolicySnapshotSchedulePolicySchedule{
+			DailySchedule: &computepb.ResourcePolicyDailyCycle{DaysInCycle: proto.Int32(1), StartTime: proto.String(start)},
+		}, nil
+	case "weekly":
+		if len(schedule.DaysOfWeek) == 0 {
+			return nil, fmt.Errorf("schedule: weekly frequency needs days_of_week")
+		}
+		weekly := &computepb.ResourcePolicyWeeklyCycle{}
+		for _, day := range schedule.DaysOfWeek {
+			day = strings.ToUpper(day)
+			if !isWeekday(day) {
+				return nil, fmt.Errorf("schedule: unknown day of week %q", day)
+			}
+			weekly.DayOfWeeks = append(weekly.DayOfWeeks, &computepb.ResourcePolicyWeeklyCycleDayOfWeek{Day: proto.String(day), StartTime: proto.String(start)})
+		}
+		return &computepb.ResourcePolicySnapshotSchedulePolicySchedule{WeeklySchedule: weekly}, nil
+	default:
+		return nil, fmt.Errorf("schedule: frequency %q is not supported by snapshot schedules (use hourly, daily or weekly)", schedule.Frequency)
+	}
+}
+
+func isWeekday(day string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.ToUpper(d.String()) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// utcStartTime converts an HH:MM time in timezone to the HH:00 UTC form
+// snapshot schedules take. Schedules run in UTC, so the zone's offset is
+// taken on a fixed date, January 1st 2025: converting at today's offset
+// would give another start time, and so a different schedule, on either
+// side of a daylight saving change.
+func utcStartTime(clock, timezone string) (string, error) {
+	if clock == "" {
+		clock = "00:00"
+	}
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return "", fmt.Errorf("schedule: %w", err)
+		}
+	}
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return "", fmt.Errorf("schedule: time %q is not HH:MM", clock)
+	}
+	start := time.Date(2025, time.January, 1, parsed.Hour(), parsed.Minute(), 0, 0, location).UTC()
+	if start.Minute() != 0 {
+		return "", fmt.Errorf("schedule: time %s %s must fall on the hour in UTC", clock, location)
+	}
+	return start.Format("15:04"), nil
+}
+
+// retentionDays is how long the longest retention tier keeps snapshots;
+// resource policies only support a single maximum age
+func retentionDays(retention RetentionConfig) int {
+	days := retention.Daily
+	for _, tier := range []int{retention.Weekly * 7, retention.Monthly * 31, retention.Yearly * 366} {
+		if tier > days {
+			days = tier
+		}
+	}
+	return days
+}
+
+// sameSnapshotSchedule compares the parts of two snapshot schedules the
+// backup config controls, ignoring fields the API fills in
+func sameSnapshotSchedule(got, want *computepb.ResourcePolicySnapshotSchedulePolicy) bool {
+	if got == nil {
+		return false
+	}
+	if got.GetRetentionPolicy().GetMaxRetentionDays() != want.GetRetentionPolicy().GetMaxRetentionDays() {
+		return false
+	}
+	a, b := got.GetSchedule(), want.GetSchedule()
+	switch {
+	case b.GetHourlySchedule() != nil:
+		return a.GetHourlySchedule().GetHoursInCycle() == b.GetHourlySchedule().GetHoursInCycle() &&
+			a.GetHourlySchedule().GetStartTime() == b.GetHourlySchedule().GetStartTime()
+	case b.GetDailySchedule() != nil:
+		return a.GetDailySchedule().GetDaysInCycle() == b.GetDailySchedule().GetDaysInCycle() &&
+			a.GetDailySchedule().GetStartTime() == b.GetDailySchedule().GetStartTime()
+	default:
+		return weeklyDays(a.GetWeeklySchedule()) == weeklyDays(b.GetWeeklySchedule())
+	}
+}
+
+func weeklyDays(cycle *computepb.ResourcePolicyWeeklyCycle) string {
+	var days []string
+	for _, day := range cycle.GetDayOfWeeks() {
+		days = append(days, day.GetDay()+"@"+day.GetStartTime())
+	}
+	sort.Strings(days)
+	return strings.Join(days, ",")
+}
+
+// hasResourcePolicy reports whether policies, as names or URLs, include name
+func hasResourcePolicy(policies []string, name string) bool {
+	for _, policy := range policies {
+		if policy == name || strings.HasSuffix(policy, "/resourcePolicies/"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+func resourcePolicyURL(config *BackupConfig, name string) string {
+	return fmt.Sprintf("projects/%s/regions/%s/resourcePolicies/%s", config.ProjectID, config.Region, name)
+}
+
+// ensureSnapshotSchedules puts a snapshot schedule named after each enabled
+// compute target on the disks it lists
+func ensureSnapshotSchedules(ctx context.Context, client snapshotScheduleClient, config *BackupConfig) ([]*SnapshotScheduleResult, error) {
+	var results []*SnapshotScheduleResult
+	for _, target := range config.BackupTargets {
+		if !target.Enabled || target.Type != "compute" {
+			continue
+		}
+		result, err := EnsureSnapshotSchedule(ctx, client, config, target.Name+"-snapshots", targetDisks(&target))
+		if err != nil {
+			return results, fmt.Errorf("target %s: %w", target.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// targetDisks returns the disks a compute target names
+func targetDisks(target *BackupTarget) []string {
+	var disks []string
+	for _, resource := range target.Resources {
+		if resource != "*" {
+			disks = append(disks, resource)
+		}
+	}
+	return disks
+}
+
+// gcpSnapshotScheduleClient implements snapshotScheduleClient and
+// diskSnapshots with the compute REST clients
+type gcpSnapshotScheduleClient struct {
+	policies  *compute.ResourcePoliciesClient
+	disks     *compute.DisksClient
+	snapshots *compute.SnapshotsClient
+}
+
+func newGCPSnapshotScheduleClient(ctx context.Context) (*gcpSnapshotScheduleClient, error) {
+	policies, err := compute.NewResourcePoliciesRESTClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource policies client: %w", err)
+	}
+	disks, err := compute.NewDisksRESTClient(ctx)
+	if err != nil {
+		policies.Close()
+		return nil, fmt.Errorf("failed to create disks client: %w", err)
+	}
+	snapshots, err := compute.NewSnapshotsRESTClient(ctx)
+	if err != nil {
+		policies.Close()
+		disks.Close()
+		return nil, fmt.Errorf("failed to create snapshots client: %w", err)
+	}
+	return &gcpSnapshotScheduleClient{policies: policies, disks: disks, snapshots: snapshots}, nil
+}
+
+func (c *gcpSnapshotScheduleClient) Close() error {
+	return errors.Join(c.policies.Close(), c.disks.Close(), c.snapshots.Close())
+}
+
+func (c *gcpSnapshotScheduleClient) GetResourcePolicy(ctx context.Context, project, region, name string) (*computepb.ResourcePolicy, error) {
+	return c.policies.Get(ctx, &computepb.GetResourcePolicyRequest{Project: project, Region: region, ResourcePolicy: name})
+}
+
+func (c *gcpSnapshotScheduleClient) InsertResourcePolicy(ctx context.Context, project, region string, policy *computepb.ResourcePolicy) error {
+	op, err := c.policies.Insert(ctx, &computepb.InsertResourcePolicyRequest{Project: project, Region: region, ResourcePolicyResource: policy})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+func (c *gcpSnapshotScheduleClient) GetDisk(ctx context.Context, project, zone, name string) (*computepb.Disk, error) {
+	return c.disks.Get(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: name})
+}
+
+func (c *gcpSnapshotScheduleClient) AddDiskResourcePolicy(ctx context.Context, project, zone, disk, policy string) error {
+	op, err := c.disks.AddResourcePolicies(ctx, &computepb.AddResourcePoliciesDiskRequest{
+		Project: project,
+		Zone:    zone,
+		Disk:    disk,
+		DisksAddResourcePoliciesRequestResource: &computepb.DisksAddResourcePoliciesRequest{
+			ResourcePolicies: []string{policy},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+func (c *gcpSnapshotScheduleClient) ListDiskSnapshots(ctx context.Context, project, zone, disk string) ([]*computepb.Snapshot, error) {
+	source := fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/zones/%s/disks/%s", project, zone, disk)
+	var snapshots []*computepb.Snapshot
+	it := c.snapshots.List(ctx, &computepb.ListSnapshotsRequest{Project: project, Filter: proto.String(fmt.Sprintf("sourceDisk = %q", source))})
+	for {
+		snapshot, err := it.Next()
+		if err == iterator.Done {
+			return snapshots, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+}
+
+// isNotFound reports whether err is a 404 from a REST or gRPC API
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusNotFound
+	}
+	return status.Code(err) == codes.NotFound
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+// fakeComputeClient keeps resource policies and disks in memory
+type fakeComputeClient struct {
+	policies map[string]*computepb.ResourcePolicy
+	disks    map[string]*computepb.Disk
+	inserted int
+	attached []string
+}
+
+func newFakeComputeClient(disks ...string) *fakeComputeClient {
+	fake := &fakeComputeClient{policies: map[string]*computepb.ResourcePolicy{}, disks: map[string]*computepb.Disk{}}
+	for _, name := range disks {
+		fake.disks[name] = &computepb.Disk{Name: &name}
+	}
+	return fake
+}
+
+func (f *fakeComputeClient) GetResourcePolicy(_ context.Context, project, region, name string) (*computepb.ResourcePolicy, error) {
+	policy, ok := f.policies[name]
+	if !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound}
+	}
+	return policy, nil
+}
+
+func (f *fakeComputeClient) InsertResourcePolicy(_ context.Context, project, region string, policy *computepb.ResourcePolicy) error {
+	f.policies[policy.GetName()] = policy
+	f.inserted++
+	return nil
+}
+
+func (f *fakeComputeClient) GetDisk(_ context.Context, project, zone, name string) (*computepb.Disk, error) {
+	disk, ok := f.disks[name]
+	if !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound}
+	}
+	return disk, nil
+}
+
+func (f *fakeComputeClient) AddDiskResourcePolicy(_ context.Context, project, zone, disk, policy string) error {
+	f.disks[disk].ResourcePolicies = append(f.disks[disk].ResourcePolicies, "https://www.googleapis.com/compute/v1/"+policy)
+	f.attached = append(f.attached, disk+"="+policy)
+	return nil
+}
+
+func scheduleTestConfig() *BackupConfig {
+	config := getDefaultBackupConfig("my-project", "us-central1", "us-central1-a")
+	return &config
+}
+
+func TestEnsureSnapshotScheduleCreatesAndAttaches(t *testing.T) {
+	fake := newFakeComputeClient("web-1", "web-2")
+	config := scheduleTestConfig()
+
+	result, err := EnsureSnapshotSchedule(context.Background(), fake, config, "web-snapshots", []string{"web-1", "web-2"})
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, []string{"web-1", "web-2"}, result.AttachedDisks)
+	assert.Equal(t, []string{"web-1", "web-2"}, result.Newly)
+	assert.Equal(t, []string{
+		"web-1=projects/my-project/regions/us-central1/resourcePolicies/web-snapshots",
+		"web-2=projects/my-project/regions/us-central1/resourcePolicies/web-snapshots",
+	}, fake.attached)
+
+	spec := fake.policies["web-snapshots"].GetSnapshotSchedulePolicy()
+	require.NotNil(t, spec)
+	assert.Equal(t, int32(1), spec.GetSchedule().GetDailySchedule().GetDaysInCycle())
+	assert.Equal(t, "02:00", spec.GetSchedule().GetDailySchedule().GetStartTime())
+	// 3 yearly copies is the longest tier of the default retention
+	assert.Equal(t, int32(3*366), spec.GetRetentionPolicy().GetMaxRetentionDays())
+	assert.Equal(t, "KEEP_AUTO_SNAPSHOTS", spec.GetRetentionPolicy().GetOnSourceDiskDelete())
+	assert.Equal(t, []string{"us-central1"}, spec.GetSnapshotProperties().GetStorageLocations())
+}
+
+func TestEnsureSnapshotScheduleIsIdempotent(t *testing.T) {
+	fake := newFakeComputeClient("web-1", "web-2")
+	config := scheduleTestConfig()
+	ctx := context.Background()
+
+	_, err := EnsureSnapshotSchedule(ctx, fake, config, "web-snapshots", []string{"web-1"})
+	require.NoError(t, err)
+
+	result, err := EnsureSnapshotSchedule(ctx, fake, config, "web-snapshots", []string{"web-1", "web-2"})
+	require.NoError(t, err)
+	assert.False(t, result.Created)
+	assert.Equal(t, 1, fake.inserted)
+	assert.Equal(t, []string{"web-1", "web-2"}, result.AttachedDisks)
+	assert.Equal(t, []string{"web-2"}, result.Newly)
+	assert.Len(t, fake.attached, 2)
+}
+
+func TestEnsureSnapshotScheduleRejectsChangedSpec(t *testing.T) {
+	fake := newFakeComputeClient("web-1")
+	config := scheduleTestConfig()
+	ctx := context.Background()
+
+	_, err := EnsureSnapshotSchedule(ctx, fake, config, "web-snapshots", []string{"web-1"})
+	require.NoError(t, err)
+
+	config.Schedule.Time = "04:00"
+	_, err = EnsureSnapshotSchedule(ctx, fake, config, "web-snapshots", []string{"web-1"})
+	assert.ErrorContains(t, err, "different snapshot schedule")
+}
+
+func TestSnapshotSchedule(t *testing.T) {
+	weekly, err := snapshotSchedule(ScheduleConfig{Frequency: "weekly", Time: "03:00", DaysOfWeek: []string{"monday", "Thursday"}})
+	require.NoError(t, err)
+	require.Len(t, weekly.GetWeeklySchedule().GetDayOfWeeks(), 2)
+	assert.Equal(t, "MONDAY", weekly.GetWeeklySchedule().GetDayOfWeeks()[0].GetDay())
+	assert.Equal(t, "03:00", weekly.GetWeeklySchedule().GetDayOfWeeks()[1].GetStartTime())
+
+	hourly, err := snapshotSchedule(ScheduleConfig{Frequency: "hourly", Interval: 6 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, int32(6), hourly.GetHourlySchedule().GetHoursInCycle())
+	assert.Equal(t, "00:00", hourly.GetHourlySchedule().GetStartTime())
+
+	// The zone's winter offset applies all year, whatever the date today
+	daily, err := snapshotSchedule(ScheduleConfig{Frequency: "daily", Time: "02:00", Timezone: "America/New_York"})
+	require.NoError(t, err)
+	assert.Equal(t, "07:00", daily.GetDailySchedule().GetStartTime())
+
+	_, err = snapshotSchedule(ScheduleConfig{Frequency: "monthly"})
+	assert.Error(t, err)
+	_, err = snapshotSchedule(ScheduleConfig{Frequency: "daily", Time: "02:30"})
+	assert.Error(t, err)
+	_, err = snapshotSchedule(ScheduleConfig{Frequency: "weekly", DaysOfWeek: []string{"funday"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// now is the clock backups are stamped with
+var now = time.Now
+
+// errObjectNotExist is returned by an objectStore for missing objects
+var errObjectNotExist = errors.New("object does not exist")
+
+// ObjectInfo describes one stored object
+type ObjectInfo struct {
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// objectStore is the part of Cloud Storage that backups read and write
+type objectStore interface {
+	ListBuckets(ctx context.Context, project string) ([]string, error)
+	ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error)
+	StatObject(ctx context.Context, bucket, name string) (ObjectInfo, error)
+	// CopyObject copies one generation of an object, encrypting the copy
+	// with kmsKey when it is set
+	CopyObject(ctx context.Context, srcBucket, srcName string, generation int64, dstBucket, dstName, kmsKey string) error
+	// ReadObject opens one generation of an object, or the live one when
+	// generation is 0
+	ReadObject(ctx context.Context, bucket, name string, generation int64) (io.ReadCloser, error)
+	// WriteObject uploads everything read from data. An upload whose data
+	// fails to read is abandoned rather than written short.
+	WriteObject(ctx context.Context, bucket, name string, data io.Reader, kmsKey string) error
+}
+
+// StorageManifest is the cumulative record of the backups of one bucket.
+// Each run lists only what it copied and what had been deleted since the
+// run before, so the bucket at any backup time is rebuilt by replaying the
+// runs up to it.
+type StorageManifest struct {
+	Bucket string        `json:"bucket"`
+	Runs   []ManifestRun `json:"runs"`
+}
+
+// ManifestRun is one backup of a bucket
+type ManifestRun struct {
+	ID          string           `json:"id"`
+	Time        time.Time        `json:"time"`
+	Incremental bool             `json:"incremental"`
+	Copied      []ManifestObject `json:"copied"`
+	Deleted     []string         `json:"deleted,omitempty"`
+}
+
+// ManifestObject is a backed up object generation and where its copy lives
+type ManifestObject struct {
+	ObjectInfo
+	Location string `json:"location"`
+}
+
+// Snapshot returns the objects of the bucket as of the last run at or
+// before at
+func (m *StorageManifest) Snapshot(at time.Time) map[string]ManifestObject {
+	objects := make(map[string]ManifestObject)
+	for _, run := range m.Runs {
+		if run.Time.After(at) {
+			break
+		}
+		for _, name := range run.Deleted {
+			delete(objects, name)
+		}
+		for _, object := range run.Copied {
+			objects[object.Name] = object
+		}
+	}
+	return objects
+}
+
+// storageBackupPaths lays out a target's backups in the backup bucket
+type storageBackupPaths struct {
+	config *BackupConfig
+	target *BackupTarget
+}
+
+// manifests is where the target keeps one manifest per bucket
+func (p storageBackupPaths) manifests() string {
+	return path.Join(p.config.Storage.Path, "storage", p.target.Name)
+}
+
+func (p storageBackupPaths) manifest(bucket string) string {
+	return path.Join(p.manifests(), bucket+".manifest.json")
+}
+
+func (p storageBackupPaths) object(runID, bucket, name string) string {
+	return path.Join(p.config.Storage.Path, "storage", p.target.Name+"-"+runID, bucket, name)
+}
+
+// bucketBackup is the outcome of backing up one bucket
+type bucketBackup struct {
+	Bucket    string `json:"bucket"`
+	Copied    int    `json:"copied"`
+	Unchanged int    `json:"unchanged"`
+	Deleted   int    `json:"deleted"`
+	Bytes     int64  `json:"bytes"`
+	Manifest  string `json:"manifest"`
+	checksum  []byte
+}
+
+// backupBucket copies bucket into the backup bucket and appends the run to
+// its manifest. Incremental runs copy only objects whose generation or etag
+// changed since the manifest's last state; full runs copy everything.
+func backupBucket(ctx context.Context, store objectStore, sink *backupSink, paths storageBackupPaths, bucket, runID string, incremental, dryRun bool) (*bucketBackup, error) {
+	manifest, err := readStorageManifest(ctx, sink, paths.manifest(bucket), bucket)
+	if err != nil {
+		return nil, err
+	}
+	previous := manifest.Snapshot(now())
+
+	objects, err := store.ListObjects(ctx, bucket, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects of %s: %w", bucket, err)
+	}
+
+	run := ManifestRun{ID: runID, Time: now().UTC(), Incremental: incremental, Copied: []ManifestObject{}}
+	outcome := &bucketBackup{Bucket: bucket, Manifest: paths.manifest(bucket)}
+	current := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		current[object.Name] = true
+		if last, ok := previous[object.Name]; incremental && ok && last.Generation == object.Generation && last.ETag == object.ETag {
+			outcome.Unchanged++
+			continue
+		}
+		location := paths.object(runID, bucket, object.Name)
+		if !dryRun {
+			if err := sink.copyFrom(ctx, bucket, object, location); err != nil {
+				return nil, fmt.Errorf("failed to copy gs://%s/%s: %w", bucket, object.Name, err)
+			}
+		}
+		run.Copied = append(run.Copied, ManifestObject{ObjectInfo: object, Location: location})
+		outcome.Copied++
+		outcome.Bytes += object.Size
+	}
+	for name := range previous {
+		if !current[name] {
+			run.Deleted = append(run.Deleted, name)
+		}
+	}
+	sort.Strings(run.Deleted)
+	outcome.Deleted = len(run.Deleted)
+
+	manifest.Runs = append(manifest.Runs, run)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	outcome.checksum = sum[:]
+	if !dryRun {
+		if err := sink.write(ctx, paths.manifest(bucket), data); err != nil {
+			return nil, fmt.Errorf("failed to write manifest for %s: %w", bucket, err)
+		}
+	}
+	return outcome, nil
+}
+
+func readStorageManifest(ctx context.Context, sink *backupSink, name, bucket string) (*StorageManifest, error) {
+	data, err := sink.read(ctx, name)
+	if errors.Is(err, errObjectNotExist) {
+		return &StorageManifest{Bucket: bucket}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", name, err)
+	}
+	var manifest StorageManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest %s is corrupt: %w", name, err)
+	}
+	return &manifest, nil
+}
+
+// storageTargetBuckets returns the buckets a storage target covers, listing
+// the project's buckets, except the backup bucket itself, for "*"
+func storageTargetBuckets(ctx context.Context, store objectStore, config *BackupConfig, target *BackupTarget) ([]string, error) {
+	var buckets []string
+	for _, resource := range target.Resources {
+		if resource != "*" {
+			buckets = append(buckets, resource)
+			continue
+		}
+		all, err := store.ListBuckets(ctx, config.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list buckets: %w", err)
+		}
+		for _, bucket := range all {
+			if bucket != config.Storage.Bucket {
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// gcsObjectStore implements objectStore with the Cloud Storage client
+type gcsObjectStore struct {
+	client *storage.Client
+}
+
+func (s *gcsObjectStore) ListBuckets(ctx context.Context, project string) ([]string, error) {
+	var buckets []string
+	it := s.client.Buckets(ctx, project)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return buckets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, attrs.Name)
+	}
+}
+
+func (s *gcsObjectStore) ListObjects(ctx context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	it := s.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, ObjectInfo{Name: attrs.Name, Generation: attrs.Generation, ETag: attrs.Etag, Size: attrs.Size})
+	}
+}
+
+func (s *gcsObjectStore) StatObject(ctx context.Context, bucket, name string) (ObjectInfo, error) {
+	attrs, err := s.client.Bucket(bucket).Object(name).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return ObjectInfo{}, errObjectNotExist
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Name: attrs.Name, Generation: attrs.Generation, ETag: attrs.Etag, Size: attrs.Size}, nil
+}
+
+func (s *gcsObjectStore) CopyObject(ctx context.Context, srcBucket, srcName string, generation int64, dstBucket, dstName, kmsKey string) error {
+	src := s.client.Bucket(srcBucket).Object(srcName).Generation(generation)
+	copier := s.client.Bucket(dstBucket).Object(dstName).CopierFrom(src)
+	copier.DestinationKMSKeyName = kmsKey
+	_, err := copier.Run(ctx)
+	return err
+}
+
+func (s *gcsObjectStore) ReadObject(ctx context.Context, bucket, name string, generation int64) (io.ReadCloser, error) {
+	object := s.client.Bucket(bucket).Object(name)
+	if generation != 0 {
+		object = object.Generation(generation)
+	}
+	reader, err := object.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, errObjectNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (s *gcsObjectStore) WriteObject(ctx context.Context, bucket, name string, data io.Reader, kmsKey string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	writer := s.client.Bucket(bucket).Object(name).NewWriter(ctx)
+	writer.KMSKeyName = kmsKey
+	if _, err := io.Copy(writer, data); err != nil {
+		// Cancelling before Close abandons the upload, so no partial
+		// object is left behind
+		cancel()
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeObjectStore keeps buckets of objects in memory and records copies
+type fakeObjectStore struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]fakeObject
+	copies  []string
+}
+
+type fakeObject struct {
+	info   ObjectInfo
+	data   []byte
+	kmsKey string
+}
+
+func newFakeObjectStore() *fakeObjectStore {
+	return &fakeObjectStore{buckets: map[string]map[string]fakeObject{}}
+}
+
+// put writes an object, bumping its generation like Cloud Storage does
+func (f *fakeObjectStore) put(bucket, name string, data []byte) {
+	f.putEncrypted(bucket, name, data, "")
+}
+
+func (f *fakeObjectStore) putEncrypted(bucket, name string, data []byte, kmsKey string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.buckets[bucket] == nil {
+		f.buckets[bucket] = map[string]fakeObject{}
+	}
+	generation := f.buckets[bucket][name].info.Generation + 1
+	f.buckets[bucket][name] = fakeObject{
+		info:   ObjectInfo{Name: name, Generation: generation, ETag: fmt.Sprintf("etag-%d", generation), Size: int64(len(data))},
+		data:   data,
+		kmsKey: kmsKey,
+	}
+}
+
+func (f *fakeObjectStore) remove(bucket, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.buckets[bucket], name)
+}
+
+func (f *fakeObjectStore) takeCopies() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copies := f.copies
+	f.copies = nil
+	sort.Strings(copies)
+	return copies
+}
+
+func (f *fakeObjectStore) ListBuckets(_ context.Context, project string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var buckets []string
+	for bucket := range f.buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+	return buckets, nil
+}
+
+func (f *fakeObjectStore) ListObjects(_ context.Context, bucket, prefix string) ([]ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var objects []ObjectInfo
+	for name, object := range f.buckets[bucket] {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, object.info)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+func (f *fakeObjectStore) StatObject(_ context.Context, bucket, name string) (ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.buckets[bucket][name]
+	if !ok {
+		return ObjectInfo{}, errObjectNotExist
+	}
+	return object.info, nil
+}
+
+func (f *fakeObjectStore) CopyObject(_ context.Context, srcBucket, srcName string, generation int64, dstBucket, dstName, kmsKey string) error {
+	f.mu.Lock()
+	src, ok := f.buckets[srcBucket][srcName]
+	f.copies = append(f.copies, srcBucket+"/"+srcName)
+	f.mu.Unlock()
+	if !ok || src.info.Generation != generation {
+		return errObjectNotExist
+	}
+	f.putEncrypted(dstBucket, dstName, src.data, kmsKey)
+	return nil
+}
+
+func (f *fakeObjectStore) ReadObject(_ context.Context, bucket, name string, generation int64) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.buckets[bucket][name]
+	if !ok || (generation != 0 && object.info.Generation != generation) {
+		return nil, errObjectNotExist
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (f *fakeObjectStore) WriteObject(_ context.Context, bucket, name string, data io.Reader, kmsKey string) error {
+	contents, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	f.putEncrypted(bucket, name, contents, kmsKey)
+	return nil
+}
+
+// withClock stops the backup clock at start; the returned func moves it on
+func withClock(t *testing.T, start time.Time) func(time.Duration) {
+	t.Helper()
+	current := start
+	original := now
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = original })
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func storageTestConfig() (*BackupConfig, *BackupTarget) {
+	config := getDefaultBackupConfig("my-project", "us-central1", "us-central1-a")
+	target := &BackupTarget{Type: "storage", Name: "assets", Resources: []string{"assets-bucket"}, Enabled: true}
+	config.BackupTargets = []BackupTarget{*target}
+	return &config, target
+}
+
+func TestIncrementalStorageBackupCopiesOnlyChanges(t *testing.T) {
+	advance := withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("a"))
+	store.put("assets-bucket", "b.txt", []byte("b"))
+	store.put("assets-bucket", "c.txt", []byte("c"))
+	config, target := storageTestConfig()
+	opts := &backupOptions{Incremental: true}
+	ctx := context.Background()
+
+	// The first run has nothing to compare against and copies everything
+	record, err := backupStorage(ctx, store, config, target, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, record.ResourceCount)
+	assert.Equal(t, []string{"assets-bucket/a.txt", "assets-bucket/b.txt", "assets-bucket/c.txt"}, store.takeCopies())
+
+	advance(24 * time.Hour)
+	store.put("assets-bucket", "b.txt", []byte("b, changed"))
+	store.remove("assets-bucket", "c.txt")
+
+	record, err = backupStorage(ctx, store, config, target, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.ResourceCount)
+	assert.Equal(t, int64(len("b, changed")), record.Size)
+	assert.Equal(t, []string{"assets-bucket/b.txt"}, store.takeCopies())
+
+	data := store.buckets[config.Storage.Bucket]["automated-backups/storage/assets/assets-bucket.manifest.json"].data
+	var manifest StorageManifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	require.Len(t, manifest.Runs, 2)
+	assert.Equal(t, []string{"c.txt"}, manifest.Runs[1].Deleted)
+	require.Len(t, manifest.Runs[1].Copied, 1)
+	assert.Equal(t, "automated-backups/storage/assets-1772416800/assets-bucket/b.txt", manifest.Runs[1].Copied[0].Location)
+
+	// Each backup time reconstructs to what the bucket held then
+	first := manifest.Snapshot(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	assert.Len(t, first, 3)
+	assert.Equal(t, "automated-backups/storage/assets-1772330400/assets-bucket/b.txt", first["b.txt"].Location)
+	second := manifest.Snapshot(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	assert.Len(t, second, 2)
+	assert.NotContains(t, second, "c.txt")
+	assert.Equal(t, "automated-backups/storage/assets-1772330400/assets-bucket/a.txt", second["a.txt"].Location)
+	assert.Equal(t, "automated-backups/storage/assets-1772416800/assets-bucket/b.txt", second["b.txt"].Location)
+}
+
+func TestFullStorageBackupCopiesEverything(t *testing.T) {
+	advance := withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("a"))
+	store.put("assets-bucket", "b.txt", []byte("b"))
+	config, target := storageTestConfig()
+	ctx := context.Background()
+
+	_, err := backupStorage(ctx, store, config, target, &backupOptions{})
+	require.NoError(t, err)
+	store.takeCopies()
+	advance(time.Hour)
+
+	record, err := backupStorage(ctx, store, config, target, &backupOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, record.ResourceCount)
+	assert.Len(t, store.takeCopies(), 2)
+	assert.Equal(t, false, record.Details["incremental"])
+}
+
+func TestStorageBackupDryRunWritesNothing(t *testing.T) {
+	withClock(t, time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	store := newFakeObjectStore()
+	store.put("assets-bucket", "a.txt", []byte("a"))
+	config, target := storageTestConfig()
+
+	record, err := backupStorage(context.Background(), store, config, target, &backupOptions{DryRun: true, Incremental: true})
+	require.NoError(t, err)
+	assert.Equal(t, "dry-run", record.Status)
+	assert.Equal(t, 1, record.ResourceCount)
+	assert.Empty(t, store.takeCopies())
+	assert.NotContains(t, store.buckets, config.Storage.Bucket)
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,6 +22,8 @@ import (
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/analysis"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/suppress"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
 )
@@ -30,17 +36,23 @@ var (
 )
 
 type Config struct {
-	Project      string   `mapstructure:"project"`
-	Region       string   `mapstructure:"region"`
-	Zones        []string `mapstructure:"zones"`
-	OutputFormat string   `mapstructure:"output_format"`
-	OutputFile   string   `mapstructure:"output_file"`
-	LogLevel     string   `mapstructure:"log_level"`
-	Credentials  string   `mapstructure:"credentials"`
-	MaxWorkers   int      `mapstructure:"max_workers"`
-	Timeout      int      `mapstructure:"timeout"`
-	Filters      Filters  `mapstructure:"filters"`
-	Export       Export   `mapstructure:"export"`
+	Project         string         `mapstructure:"project"`
+	Region          string         `mapstructure:"region"`
+	Zones           []string       `mapstructure:"zones"`
+	OutputFormat    string         `mapstructure:"output_format"`
+	OutputFile      string         `mapstructure:"output_file"`
+	LogLevel        string         `mapstructure:"log_level"`
+	Credentials     string         `mapstructure:"credentials"`
+	MaxWorkers      int            `mapstructure:"max_workers"`
+	QPS             int            `mapstructure:"qps"`
+	Timeout         int            `mapstructure:"timeout"`
+	// ParallelPerType caps concurrent calls per resource type and TypeLimits
+	// overrides it for individual types
+	ParallelPerType int            `mapstructure:"parallel_per_type"`
+	TypeLimits      map[string]int `mapstructure:"type_limits"`
+	Filters         Filters        `mapstructure:"filters"`
+	Export          Export         `mapstructure:"export"`
+	Table           TableOptions   `mapstructure:"table"`
 }
 
 type Filters struct {
@@ -60,6 +72,10 @@ type Export struct {
 	Compression bool   `mapstructure:"compression"`
 }
 
+// defaultCheckpoint is the resume file discover --resume uses when
+// --checkpoint isn't given
+const defaultCheckpoint = ".cloudrecon-checkpoint.json"
+
 var rootCmd = &cobra.Command{
 	Use:   "cloudrecon",
 	Short: "Cloud infrastructure reconnaissance and analysis tool",
@@ -97,6 +113,14 @@ var securityCmd = &cobra.Command{
 	RunE:  runSecurityAnalysis,
 }
 
+var ageCmd = &cobra.Command{
+	Use:   "age [resource-type]",
+	Short: "Report stale resources by age",
+	Long:  `List resources created long ago and not modified recently, as candidates for review`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runAgeReport,
+}
+
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export discovered resources",
@@ -128,13 +152,18 @@ func init() {
 	rootCmd.PersistentFlags().StringP("project", "p", "", "GCP project ID")
 	rootCmd.PersistentFlags().StringP("region", "r", "us-central1", "Default region")
 	rootCmd.PersistentFlags().StringSliceP("zones", "z", []string{}, "Specific zones to scan")
-	rootCmd.PersistentFlags().StringP("output", "o", "json", "Output format (json, yaml, table)")
+	rootCmd.PersistentFlags().StringP("output", "o", "json", "Output format (json, yaml, table, csv)")
 	rootCmd.PersistentFlags().StringP("output-file", "f", "", "Output file path")
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().StringP("config", "c", "", "Config file path")
 	rootCmd.PersistentFlags().StringP("credentials", "", "", "Path to GCP credentials file")
-	rootCmd.PersistentFlags().IntP("workers", "w", 10, "Number of concurrent workers")
+	rootCmd.PersistentFlags().IntP("workers", "w", 10, "Maximum concurrent API calls across all resource types")
 	rootCmd.PersistentFlags().IntP("timeout", "t", 300, "Operation timeout in seconds")
+	rootCmd.PersistentFlags().Int("qps", 100, "Maximum GCP API requests per second across all workers")
+	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colored output (also honors NO_COLOR)")
+	rootCmd.PersistentFlags().StringSlice("columns", []string{}, "Columns to show with --output table")
+	rootCmd.PersistentFlags().String("sort-by", "", "Sort table rows by column, optionally suffixed with :asc or :desc")
+	rootCmd.PersistentFlags().Bool("no-truncate", false, "Do not ellipsize long table values")
 
 	viper.BindPFlag("project", rootCmd.PersistentFlags().Lookup("project"))
 	viper.BindPFlag("region", rootCmd.PersistentFlags().Lookup("region"))
@@ -145,40 +174,67 @@ func init() {
 	viper.BindPFlag("credentials", rootCmd.PersistentFlags().Lookup("credentials"))
 	viper.BindPFlag("max_workers", rootCmd.PersistentFlags().Lookup("workers"))
 	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("qps", rootCmd.PersistentFlags().Lookup("qps"))
+	viper.BindPFlag("no_color", rootCmd.PersistentFlags().Lookup("no-color"))
+	viper.BindPFlag("table.columns", rootCmd.PersistentFlags().Lookup("columns"))
+	viper.BindPFlag("table.sort_by", rootCmd.PersistentFlags().Lookup("sort-by"))
+	viper.BindPFlag("table.no_truncate", rootCmd.PersistentFlags().Lookup("no-truncate"))
 
 	discoverCmd.Flags().StringSlice("resource-types", []string{}, "Resource types to discover")
 	discoverCmd.Flags().StringToString("labels", map[string]string{}, "Label filters")
 	discoverCmd.Flags().Bool("deep-scan", false, "Perform deep resource scanning")
 	discoverCmd.Flags().Bool("include-deleted", false, "Include recently deleted resources")
+	discoverCmd.Flags().Int("parallel-per-type", 0, "Maximum concurrent API calls per resource type (0 = limited only by --workers)")
+	discoverCmd.Flags().StringToInt("type-limits", map[string]int{}, "Per-type concurrency overrides (e.g. compute.instances=4)")
+	viper.BindPFlag("parallel_per_type", discoverCmd.Flags().Lookup("parallel-per-type"))
+	viper.BindPFlag("type_limits", discoverCmd.Flags().Lookup("type-limits"))
+	discoverCmd.Flags().String("checkpoint", "", "Save partial results to this file while discovering (default "+defaultCheckpoint+" with --resume)")
+	discoverCmd.Flags().Duration("checkpoint-interval", core.DefaultCheckpointInterval, "How often to save the checkpoint")
+	discoverCmd.Flags().Bool("resume", false, "Resume from the checkpoint, re-scanning only unfinished types and zones")
 
 	analyzeCmd.Flags().Bool("detailed", false, "Generate detailed analysis")
 	analyzeCmd.Flags().StringSlice("metrics", []string{}, "Specific metrics to analyze")
 	analyzeCmd.Flags().String("period", "7d", "Analysis period (e.g., 7d, 30d, 3m)")
+	analyzeCmd.Flags().Int("max-resources", 0, "Fail before analyzing when more resources than this are in scope (0 = no limit)")
 
 	costCmd.Flags().String("billing-account", "", "Billing account ID")
 	costCmd.Flags().String("start-date", "", "Start date for cost analysis (YYYY-MM-DD)")
 	costCmd.Flags().String("end-date", "", "End date for cost analysis (YYYY-MM-DD)")
 	costCmd.Flags().Bool("forecast", false, "Include cost forecast")
-	costCmd.Flags().String("group-by", "service", "Group costs by (service, resource, label)")
+	costCmd.Flags().String("group-by", "service", "Group costs by service, region, project, resource or label:<key> (e.g. label:team)")
+	costCmd.Flags().Bool("chargeback", false, "Report billed cost per team from team/cost-center labels, including untagged spend (-o csv to export)")
+	costCmd.Flags().String("team-mapping", "", "Chargeback mapping file of team label keys and aliases")
 
 	securityCmd.Flags().StringSlice("checks", []string{}, "Specific security checks to run")
 	securityCmd.Flags().String("compliance", "", "Compliance framework (cis, pci, hipaa)")
 	securityCmd.Flags().Bool("remediate", false, "Generate remediation scripts")
+	securityCmd.Flags().String("format", "", "Output format (sarif); defaults to --output")
+	securityCmd.Flags().String("min-severity", "", "Only show findings at or above this severity (critical, high, medium, low, info)")
+	securityCmd.Flags().String("fail-on", "", "Exit non-zero when any finding is at or above this severity, shown or not")
+	securityCmd.Flags().String("suppressions", "", "Suppression file of accepted findings, which are listed as suppressed and don't count for --fail-on")
+
+	ageCmd.Flags().String("min-age", "180d", "Only flag resources created longer ago than this (e.g., 90d, 26w, 6m)")
+	ageCmd.Flags().String("max-idle", "90d", "Only flag resources not modified within this period")
+	ageCmd.Flags().String("group-by", "", "Sum up ages by resource type (type)")
 
-	exportCmd.Flags().String("format", "json", "Export format (json, csv, terraform, yaml)")
+	exportCmd.Flags().String("format", "json", "Export format (json, csv, terraform, yaml, infracost)")
 	exportCmd.Flags().String("destination", "", "Export destination (file, gcs, bq)")
 	exportCmd.Flags().String("bucket", "", "GCS bucket name for export")
 	exportCmd.Flags().Bool("compress", false, "Compress exported data")
+	exportCmd.Flags().String("dataset", "", "BigQuery dataset for export (created if missing)")
+	exportCmd.Flags().String("table", "resources", "BigQuery table for export (created if missing)")
 
 	reportCmd.Flags().String("template", "standard", "Report template (standard, executive, technical)")
 	reportCmd.Flags().StringSlice("sections", []string{}, "Report sections to include")
 	reportCmd.Flags().String("format", "html", "Report format (html, pdf, markdown)")
 	reportCmd.Flags().Bool("include-charts", true, "Include charts and visualizations")
+	reportCmd.Flags().String("history-dir", "", "Directory of earlier reports to show trends against; each report is added to it")
 
 	rootCmd.AddCommand(discoverCmd)
 	rootCmd.AddCommand(analyzeCmd)
 	rootCmd.AddCommand(costCmd)
 	rootCmd.AddCommand(securityCmd)
+	rootCmd.AddCommand(ageCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(reportCmd)
 	rootCmd.AddCommand(versionCmd)
@@ -213,9 +269,11 @@ func initConfig() {
 		level = logrus.InfoLevel
 	}
 	logger.SetLevel(level)
+	colorEnabled := termcolor.Enabled(viper.GetBool("no_color"), os.Stderr)
 	logger.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
+		DisableColors:   !colorEnabled,
 	})
 }
 
@@ -241,12 +299,27 @@ func runDiscovery(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create provider: %w", err)
 	}
 
+	checkpoint, _ := cmd.Flags().GetString("checkpoint")
+	checkpointInterval, _ := cmd.Flags().GetDuration("checkpoint-interval")
+	resume, _ := cmd.Flags().GetBool("resume")
+	if resume && checkpoint == "" {
+		checkpoint = defaultCheckpoint
+	}
+
 	discoverer := core.NewDiscoverer(provider, logger, core.DiscoveryOptions{
-		MaxWorkers:    config.MaxWorkers,
-		Timeout:       time.Duration(config.Timeout) * time.Second,
-		ResourceTypes: cmd.Flag("resource-types").Value.String(),
-		DeepScan:      cmd.Flag("deep-scan").Value.String() == "true",
-		Filters:       convertFilters(config.Filters),
+		MaxWorkers:         config.MaxWorkers,
+		Timeout:            time.Duration(config.Timeout) * time.Second,
+		ResourceTypes:      cmd.Flag("resource-types").Value.String(),
+		DeepScan:           cmd.Flag("deep-scan").Value.String() == "true",
+		Filters:            convertFilters(config.Filters),
+		RateLimit:          config.QPS,
+		Progress:           logProgress(5 * time.Second),
+		Zones:              config.Zones,
+		TypeLimits:         config.TypeLimits,
+		DefaultTypeLimit:   config.ParallelPerType,
+		Checkpoint:         checkpoint,
+		CheckpointInterval: checkpointInterval,
+		Resume:             resume,
 	})
 
 	logger.Info("Starting resource discovery...")
@@ -260,6 +333,7 @@ func runDiscovery(cmd *cobra.Command, args []string) error {
 	duration := time.Since(startTime)
 	logger.Infof("Discovery completed in %s", duration)
 	logger.Infof("Found %d resources", len(results.Resources))
+	logTypeTimings(results)
 
 	if err := outputResults(results, config); err != nil {
 		return fmt.Errorf("failed to output results: %w", err)
@@ -296,12 +370,14 @@ func runAnalysis(cmd *cobra.Command, args []string) error {
 	detailed, _ := cmd.Flags().GetBool("detailed")
 	metrics, _ := cmd.Flags().GetStringSlice("metrics")
 	period, _ := cmd.Flags().GetString("period")
+	maxResources, _ := cmd.Flags().GetInt("max-resources")
 
 	options := analysis.AnalysisOptions{
 		ResourceType: resourceType,
 		Detailed:     detailed,
 		Metrics:      metrics,
 		Period:       parsePeriod(period),
+		MaxResources: maxResources,
 	}
 
 	logger.Info("Starting resource analysis...")
@@ -333,6 +409,30 @@ func runCostAnalysis(cmd *cobra.Command, args []string) error {
 
 	costAnalyzer := analysis.NewCostAnalyzer(provider, logger)
 
+	if chargeback, _ := cmd.Flags().GetBool("chargeback"); chargeback {
+		var mapping *analysis.ChargebackMapping
+		if path, _ := cmd.Flags().GetString("team-mapping"); path != "" {
+			if mapping, err = analysis.LoadChargebackMapping(path); err != nil {
+				return err
+			}
+		}
+		// An unset date leaves the period to the last month
+		options := analysis.CostAnalysisOptions{BillingAccount: billingAccount}
+		if startDate != "" {
+			options.StartDate = parseDate(startDate)
+		}
+		if endDate != "" {
+			options.EndDate = parseDate(endDate)
+		}
+
+		logger.Info("Building chargeback report...")
+		report, err := costAnalyzer.Chargeback(ctx, options, mapping)
+		if err != nil {
+			return fmt.Errorf("chargeback failed: %w", err)
+		}
+		return outputResults(report, config)
+	}
+
 	options := analysis.CostAnalysisOptions{
 		BillingAccount: billingAccount,
 		StartDate:      parseDate(startDate),
@@ -350,6 +450,45 @@ func runCostAnalysis(cmd *cobra.Command, args []string) error {
 	return outputResults(results, config)
 }
 
+func runAgeReport(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	provider, err := createProvider(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	resourceType := ""
+	if len(args) > 0 {
+		resourceType = args[0]
+	}
+
+	minAge, _ := cmd.Flags().GetString("min-age")
+	maxIdle, _ := cmd.Flags().GetString("max-idle")
+	groupBy, _ := cmd.Flags().GetString("group-by")
+
+	logger.Info("Listing resources for the age report...")
+	resources, err := provider.ListResources(ctx, resourceType, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	report, err := analysis.BuildAgeReport(resources, analysis.AgeOptions{
+		MinAge:  parsePeriod(minAge),
+		MaxIdle: parsePeriod(maxIdle),
+		GroupBy: groupBy,
+	})
+	if err != nil {
+		return err
+	}
+
+	return outputResults(report, config)
+}
+
 func runSecurityAnalysis(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	config, err := loadConfig()
@@ -365,6 +504,17 @@ func runSecurityAnalysis(cmd *cobra.Command, args []string) error {
 	checks, _ := cmd.Flags().GetStringSlice("checks")
 	compliance, _ := cmd.Flags().GetString("compliance")
 	remediate, _ := cmd.Flags().GetBool("remediate")
+	minSeverity, failOn, err := securitySeverityFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	var suppressions *suppress.List
+	if path, _ := cmd.Flags().GetString("suppressions"); path != "" {
+		if suppressions, err = suppress.Load(path); err != nil {
+			return err
+		}
+	}
 
 	securityAnalyzer := analysis.NewSecurityAnalyzer(provider, logger)
 
@@ -372,6 +522,7 @@ func runSecurityAnalysis(cmd *cobra.Command, args []string) error {
 		Checks:             checks,
 		ComplianceFramework: compliance,
 		GenerateRemediation: remediate,
+		Suppressions:        suppressions,
 	}
 
 	logger.Info("Running security analysis...")
@@ -386,7 +537,51 @@ func runSecurityAnalysis(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return outputResults(results, config)
+	shown := results
+	if minSeverity != "" {
+		shown = results.FilterSeverity(minSeverity)
+	}
+	if err := outputSecurityResults(cmd, shown, config); err != nil {
+		return err
+	}
+	if failOn != "" {
+		return results.CheckFailOn(failOn)
+	}
+	return nil
+}
+
+// securitySeverityFlags reads --min-severity and --fail-on, normalized
+func securitySeverityFlags(cmd *cobra.Command) (minSeverity, failOn string, err error) {
+	if value, _ := cmd.Flags().GetString("min-severity"); value != "" {
+		if minSeverity, err = analysis.ParseSeverity(value); err != nil {
+			return "", "", fmt.Errorf("--min-severity: %w", err)
+		}
+	}
+	if value, _ := cmd.Flags().GetString("fail-on"); value != "" {
+		if failOn, err = analysis.ParseSeverity(value); err != nil {
+			return "", "", fmt.Errorf("--fail-on: %w", err)
+		}
+	}
+	return minSeverity, failOn, nil
+}
+
+func outputSecurityResults(cmd *cobra.Command, results *analysis.SecurityAnalysisResults, config *Config) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" {
+		return outputResults(results, config)
+	}
+	if format != "sarif" {
+		return fmt.Errorf("unsupported security format: %s", format)
+	}
+	output, err := results.MarshalSARIF("cloudrecon", version)
+	if err != nil {
+		return err
+	}
+	if config.OutputFile != "" {
+		return os.WriteFile(config.OutputFile, output, 0644)
+	}
+	fmt.Println(string(output))
+	return nil
 }
 
 func runExport(cmd *cobra.Command, args []string) error {
@@ -400,6 +595,8 @@ func runExport(cmd *cobra.Command, args []string) error {
 	destination, _ := cmd.Flags().GetString("destination")
 	bucket, _ := cmd.Flags().GetString("bucket")
 	compress, _ := cmd.Flags().GetBool("compress")
+	dataset, _ := cmd.Flags().GetString("dataset")
+	table, _ := cmd.Flags().GetString("table")
 
 	provider, err := createProvider(ctx, config)
 	if err != nil {
@@ -409,6 +606,7 @@ func runExport(cmd *cobra.Command, args []string) error {
 	discoverer := core.NewDiscoverer(provider, logger, core.DiscoveryOptions{
 		MaxWorkers: config.MaxWorkers,
 		Timeout:    time.Duration(config.Timeout) * time.Second,
+		RateLimit:  config.QPS,
 	})
 
 	results, err := discoverer.Discover(ctx)
@@ -421,6 +619,9 @@ func runExport(cmd *cobra.Command, args []string) error {
 		Format:      format,
 		Destination: destination,
 		Bucket:      bucket,
+		Project:     config.Project,
+		Dataset:     dataset,
+		Table:       table,
 		Compress:    compress,
 	}
 
@@ -444,6 +645,7 @@ func runReport(cmd *cobra.Command, args []string) error {
 	sections, _ := cmd.Flags().GetStringSlice("sections")
 	format, _ := cmd.Flags().GetString("format")
 	includeCharts, _ := cmd.Flags().GetBool("include-charts")
+	historyDir, _ := cmd.Flags().GetString("history-dir")
 
 	provider, err := createProvider(ctx, config)
 	if err != nil {
@@ -459,6 +661,7 @@ func runReport(cmd *cobra.Command, args []string) error {
 		IncludeCharts: includeCharts,
 		Project:       config.Project,
 		Region:        config.Region,
+		HistoryDir:    historyDir,
 	}
 
 	logger.Info("Generating infrastructure report...")
@@ -499,6 +702,44 @@ func loadConfig() (*Config, error) {
 	return &config, nil
 }
 
+// logProgress returns a progress reporter that logs at most once per interval
+// and always when the last resource type completes
+func logProgress(interval time.Duration) core.ProgressFunc {
+	var mu sync.Mutex
+	var last time.Time
+	return func(p core.DiscoveryProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		done := p.TypesCompleted == p.TypesTotal
+		if !done && time.Since(last) < interval {
+			return
+		}
+		last = time.Now()
+		logger.Infof("Discovery progress: %d resources scanned, %d/%d types complete (current: %s, concurrency: %d, throttled: %d)",
+			p.ResourcesScanned, p.TypesCompleted, p.TypesTotal, p.CurrentType, p.Concurrency, p.Throttled)
+	}
+}
+
+// logTypeTimings logs how long each resource type took, slowest first, in
+// debug (verbose) output to help tune --parallel-per-type and --type-limits
+func logTypeTimings(results *core.DiscoveryResults) {
+	if !logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	types := make([]string, 0, len(results.TypeTimings))
+	for resourceType := range results.TypeTimings {
+		types = append(types, resourceType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return results.TypeTimings[types[i]].Duration > results.TypeTimings[types[j]].Duration
+	})
+	for _, resourceType := range types {
+		timing := results.TypeTimings[resourceType]
+		logger.Debugf("  %-28s %10v  %3d calls  %4d resources", resourceType, timing.Duration.Round(time.Millisecond),
+			timing.Calls, results.Summary.ResourcesByType[resourceType])
+	}
+}
+
 func createProvider(ctx context.Context, config *Config) (providers.Provider, error) {
 	var opts []option.ClientOption
 
@@ -534,6 +775,11 @@ func convertFilters(filters Filters) map[string]interface{} {
 	return result
 }
 
+// csvExporter is implemented by results that can be written as CSV
+type csvExporter interface {
+	WriteCSV(w io.Writer) error
+}
+
 func outputResults(results interface{}, config *Config) error {
 	var output []byte
 	var err error
@@ -544,7 +790,17 @@ func outputResults(results interface{}, config *Config) error {
 	case "yaml":
 		output, err = marshalYAML(results)
 	case "table":
-		return printTable(results)
+		return printTable(results, config.Table)
+	case "csv":
+		exporter, ok := results.(csvExporter)
+		if !ok {
+			return fmt.Errorf("csv output is not supported for %T", results)
+		}
+		var buf bytes.Buffer
+		if err := exporter.WriteCSV(&buf); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		output = buf.Bytes()
 	default:
 		output, err = json.MarshalIndent(results, "", "  ")
 	}
@@ -698,22 +954,8 @@ func marshalYAML(v interface{}) ([]byte, error) {
 	return []byte(fmt.Sprintf("%v", data)), nil
 }
 
-func printTable(results interface{}) error {
-	fmt.Printf("%-20s %-15s %-30s %-15s\n", "Resource", "Type", "Name", "Status")
-	fmt.Println(strings.Repeat("-", 80))
-
-	if dr, ok := results.(*core.DiscoveryResults); ok {
-		for _, resource := range dr.Resources {
-			fmt.Printf("%-20s %-15s %-30s %-15s\n",
-				resource.ID,
-				resource.Type,
-				resource.Name,
-				resource.Status)
-		}
-		fmt.Printf("\nTotal Resources: %d\n", len(dr.Resources))
-	}
-
-	return nil
+func printTable(results interface{}, opts TableOptions) error {
+	return renderTable(os.Stdout, results, opts)
 }
 
 func compressData(data []byte) ([]byte, error) {
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/analysis"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+)
+
+// defaultTableWidth is the widest a cell may be before it is ellipsized
+const defaultTableWidth = 40
+
+// TableOptions controls `--output table`
+type TableOptions struct {
+	Columns    []string `mapstructure:"columns"`
+	SortBy     string   `mapstructure:"sort_by"`
+	NoTruncate bool     `mapstructure:"no_truncate"`
+}
+
+// tableColumn describes one selectable column; numeric columns are right
+// aligned and sort numerically
+type tableColumn struct {
+	Name    string
+	Header  string
+	Numeric bool
+}
+
+// tableData is a result set flattened into named string cells
+type tableData struct {
+	Columns  []tableColumn
+	Defaults []string
+	SortBy   string
+	Rows     []map[string]string
+	Footer   string
+}
+
+// tableFor flattens the result types cloudrecon prints as tables
+func tableFor(results interface{}) (*tableData, error) {
+	switch r := results.(type) {
+	case *core.DiscoveryResults:
+		return discoveryTable(r), nil
+	case *analysis.AnalysisResults:
+		return analysisTable(r), nil
+	case *analysis.CostAnalysisResults:
+		return costTable(r), nil
+	case *analysis.ChargebackReport:
+		return chargebackTable(r), nil
+	case *analysis.AgeReport:
+		return ageTable(r), nil
+	default:
+		return nil, fmt.Errorf("table output is not supported for %T", results)
+	}
+}
+
+func discoveryTable(r *core.DiscoveryResults) *tableData {
+	t := &tableData{
+		Columns: []tableColumn{
+			{Name: "id", Header: "Resource"},
+			{Name: "type", Header: "Type"},
+			{Name: "name", Header: "Name"},
+			{Name: "status", Header: "Status"},
+			{Name: "region", Header: "Region"},
+			{Name: "zone", Header: "Zone"},
+			{Name: "account", Header: "Account"},
+			{Name: "monthly_cost", Header: "Monthly Cost", Numeric: true},
+		},
+		Defaults: []string{"id", "type", "name", "status"},
+		Footer:   fmt.Sprintf("Total Resources: %d", len(r.Resources)),
+	}
+	for _, res := range r.Resources {
+		row := map[string]string{
+			"id":      res.ID,
+			"type":    res.Type,
+			"name":    res.Name,
+			"status":  res.Status,
+			"region":  res.Region,
+			"zone":    res.Zone,
+			"account": res.Account.ID,
+		}
+		if res.Cost != nil {
+			row["monthly_cost"] = formatTableNumber(res.Cost.MonthlyCost)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+func analysisTable(r *analysis.AnalysisResults) *tableData {
+	t := &tableData{
+		Columns: []tableColumn{
+			{Name: "id", Header: "Resource"},
+			{Name: "name", Header: "Name"},
+			{Name: "type", Header: "Type"},
+			{Name: "region", Header: "Region"},
+			{Name: "status", Header: "Status"},
+			{Name: "health", Header: "Health"},
+			{Name: "health_score", Header: "Score", Numeric: true},
+			{Name: "issues", Header: "Issues", Numeric: true},
+			{Name: "current_cost", Header: "Cost", Numeric: true},
+			{Name: "savings", Header: "Savings", Numeric: true},
+		},
+		Defaults: []string{"id", "type", "health", "health_score", "issues"},
+		Footer:   fmt.Sprintf("Analyzed Resources: %d (overall health: %s)", len(r.Resources), r.Summary.OverallHealth),
+	}
+	for _, res := range r.Resources {
+		t.Rows = append(t.Rows, map[string]string{
+			"id":           res.ResourceID,
+			"name":         res.ResourceName,
+			"type":         res.ResourceType,
+			"region":       res.Region,
+			"status":       res.Status,
+			"health":       res.Health,
+			"health_score": strconv.Itoa(res.HealthScore),
+			"issues":       strconv.Itoa(len(res.Issues)),
+			"current_cost": formatTableNumber(res.Cost.CurrentCost),
+			"savings":      formatTableNumber(res.Cost.SavingsPotential),
+		})
+	}
+	return t
+}
+
+func costTable(r *analysis.CostAnalysisResults) *tableData {
+	if len(r.Groups) > 0 {
+		return costGroupTable(r)
+	}
+
+	t := &tableData{
+		Columns: []tableColumn{
+			{Name: "service", Header: "Service"},
+			{Name: "total_cost", Header: "Total Cost", Numeric: true},
+			{Name: "usage_cost", Header: "Usage", Numeric: true},
+			{Name: "request_cost", Header: "Requests", Numeric: true},
+			{Name: "data_transfer_cost", Header: "Data Transfer", Numeric: true},
+			{Name: "trend", Header: "Trend"},
+			{Name: "change_percent", Header: "Change %", Numeric: true},
+		},
+		Defaults: []string{"service", "total_cost", "trend", "change_percent"},
+		// Breakdown is a map, so give the table a stable default order
+		SortBy: "total_cost:desc",
+		Footer: fmt.Sprintf("Total Cost: %s %s", formatTableNumber(r.Summary.TotalCost), r.Summary.Currency),
+	}
+	for name, svc := range r.Breakdown.ByService {
+		if svc.ServiceName != "" {
+			name = svc.ServiceName
+		}
+		t.Rows = append(t.Rows, map[string]string{
+			"service":            name,
+			"total_cost":         formatTableNumber(svc.TotalCost),
+			"usage_cost":         formatTableNumber(svc.UsageCost),
+			"request_cost":       formatTableNumber(svc.RequestCost),
+			"data_transfer_cost": formatTableNumber(svc.DataTransferCost),
+			"trend":              svc.Trend,
+			"change_percent":     formatTableNumber(svc.ChangePercent),
+		})
+	}
+	return t
+}
+
+// costGroupTable shows the --group-by aggregation, most expensive first
+func costGroupTable(r *analysis.CostAnalysisResults) *tableData {
+	t := &tableData{
+		Columns: []tableColumn{
+			{Name: "group", Header: r.Groups[0].Dimension},
+			{Name: "total_cost", Header: "Total Cost", Numeric: true},
+			{Name: "resources", Header: "Resources", Numeric: true},
+			{Name: "percentage", Header: "Share %", Numeric: true},
+		},
+		Defaults: []string{"group", "total_cost", "resources", "percentage"},
+		Footer:   fmt.Sprintf("Total Cost: %s %s", formatTableNumber(r.Summary.TotalCost), r.Summary.Currency),
+	}
+	for _, group := range r.Groups {
+		t.Rows = append(t.Rows, map[string]string{
+			"group":      group.Value,
+			"total_cost": formatTableNumber(group.TotalCost),
+			"resources":  strconv.Itoa(group.ResourceCount),
+			"percentage": formatTableNumber(group.Percentage),
+		})
+	}
+	return t
+}
+
+// chargebackTable shows the cost charged back to each team
+func chargebackTable(r *analysis.ChargebackReport) *tableData {
+	t := &tableData{
+		Columns: []tableColumn{
+			{Name: "team", Header: "Team"},
+			{Name: "cost", Header: "Cost", Numeric: true},
+			{Name: "percentage", Header: "Share %", Numeric: true},
+			{Name: "line_items", Header: "Line Items", Numeric: true},
+		},
+		Defaults: []string{"team", "cost", "percentage", "line_items"},
+		Footer: fmt.Sprintf("Total Cost: %s %s (untagged %s), %s to %s",
+			formatTableNumber(r.TotalCost), r.Currency, formatTableNumber(r.UntaggedCost),
+			r.StartDate.Format("2006-01-02"), r.EndDate.Format("2006-01-02")),
+	}
+	for _, team := range r.Teams {
+		t.Rows = append(t.Rows, map[string]string{
+			"team":       team.Team,
+			"cost":       formatTableNumber(team.Cost),
+			"percentage": formatTableNumber(team.Percentage),
+			"line_items": strconv.Itoa(team.LineItems),
+		})
+	}
+	return t
+}
+
+// ageTable lists stale resources, oldest first, or with --group-by type
+// the stale count of each type
+func ageTable(r *analysis.AgeReport) *tableData {
+	footer := fmt.Sprintf("Stale: %d of %d resources (created over %d days ago, unchanged for %d days)",
+		r.StaleResources, r.TotalResources, r.MinAgeDays, r.MaxIdleDays)
+	if r.UnknownAge > 0 {
+		footer += fmt.Sprintf(", %d without a creation time", r.UnknownAge)
+	}
+
+	if len(r.ByType) > 0 {
+		t := &tableData{
+			Columns: []tableColumn{
+				{Name: "type", Header: "Type"},
+				{Name: "resources", Header: "Resources", Numeric: true},
+				{Name: "stale", Header: "Stale", Numeric: true},
+				{Name: "oldest_age_days", Header: "Oldest (days)", Numeric: true},
+				{Name: "max_idle_days", Header: "Max Idle (days)", Numeric: true},
+			},
+			Defaults: []string{"type", "resources", "stale", "oldest_age_days", "max_idle_days"},
+			Footer:   footer,
+		}
+		for _, group := range r.ByType {
+			t.Rows = append(t.Rows, map[string]string{
+				"type":            group.Type,
+				"resources":       strconv.Itoa(group.Resources),
+				"stale":           strconv.Itoa(group.Stale),
+				"oldest_age_days": strconv.Itoa(group.OldestAgeDays),
+				"max_idle_days":   strconv.Itoa(group.MaxIdleDays),
+			})
+		}
+		return t
+	}
+
+	t := &tableData{
+		Columns: []tableColumn{
+			{Name: "id", Header: "Resource"},
+			{Name: "name", Header: "Name"},
+			{Name: "type", Header: "Type"},
+			{Name: "region", Header: "Region"},
+			{Name: "created", Header: "Created"},
+			{Name: "last_modified", Header: "Last Modified"},
+			{Name: "age_days", Header: "Age (days)", Numeric: true},
+			{Name: "idle_days", Header: "Idle (days)", Numeric: true},
+		},
+		Defaults: []string{"id", "type", "created", "last_modified", "age_days", "idle_days"},
+		Footer:   footer,
+	}
+	for _, resource := range r.Stale {
+		t.Rows = append(t.Rows, map[string]string{
+			"id":            resource.ID,
+			"name":          resource.Name,
+			"type":          resource.Type,
+			"region":        resource.Region,
+			"created":       resource.CreatedAt.Format("2006-01-02"),
+			"last_modified": resource.LastModified.Format("2006-01-02"),
+			"age_days":      strconv.Itoa(resource.AgeDays),
+			"idle_days":     strconv.Itoa(resource.IdleDays),
+		})
+	}
+	return t
+}
+
+// renderTable writes results as an aligned table
+func renderTable(w io.Writer, results interface{}, opts TableOptions) error {
+	t, err := tableFor(results)
+	if err != nil {
+		return err
+	}
+
+	columns, err := t.selectColumns(opts.Columns)
+	if err != nil {
+		return err
+	}
+
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = t.SortBy
+	}
+	if sortBy != "" {
+		if err := t.sortRows(sortBy); err != nil {
+			return err
+		}
+	}
+
+	maxWidth := defaultTableWidth
+	if opts.NoTruncate {
+		maxWidth = 0
+	}
+
+	cells := make([][]string, len(t.Rows))
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col.Header)
+	}
+	for r, row := range t.Rows {
+		cells[r] = make([]string, len(columns))
+		for i, col := range columns {
+			cell := truncateCell(row[col.Name], maxWidth)
+			cells[r][i] = cell
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.Header
+	}
+	writeTableRow(w, columns, widths, headers)
+
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+	fmt.Fprintln(w, strings.Repeat("-", total+2*(len(widths)-1)))
+
+	for _, row := range cells {
+		writeTableRow(w, columns, widths, row)
+	}
+
+	if t.Footer != "" {
+		fmt.Fprintf(w, "\n%s\n", t.Footer)
+	}
+	return nil
+}
+
+// selectColumns resolves --columns against the table, defaulting to the
+// table's default columns
+func (t *tableData) selectColumns(names []string) ([]tableColumn, error) {
+	if len(names) == 0 {
+		names = t.Defaults
+	}
+
+	selected := make([]tableColumn, 0, len(names))
+	for _, name := range names {
+		col, ok := t.column(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(t.columnNames(), ", "))
+		}
+		selected = append(selected, col)
+	}
+	return selected, nil
+}
+
+// sortRows sorts by a column name with an optional ":desc" or ":asc" suffix.
+// Rows that compare equal keep their original order.
+func (t *tableData) sortRows(spec string) error {
+	name, direction, _ := strings.Cut(spec, ":")
+	col, ok := t.column(name)
+	if !ok {
+		return fmt.Errorf("unknown sort column %q (available: %s)", name, strings.Join(t.columnNames(), ", "))
+	}
+
+	var desc bool
+	switch strings.ToLower(direction) {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		return fmt.Errorf("invalid sort direction %q (use asc or desc)", direction)
+	}
+
+	sort.SliceStable(t.Rows, func(i, j int) bool {
+		a, b := t.Rows[i][col.Name], t.Rows[j][col.Name]
+		if desc {
+			a, b = b, a
+		}
+		if col.Numeric {
+			x, errX := strconv.ParseFloat(a, 64)
+			y, errY := strconv.ParseFloat(b, 64)
+			if errX == nil && errY == nil {
+				return x < y
+			}
+		}
+		return a < b
+	})
+	return nil
+}
+
+func (t *tableData) column(name string) (tableColumn, bool) {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return tableColumn{}, false
+}
+
+func (t *tableData) columnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func writeTableRow(w io.Writer, columns []tableColumn, widths []int, cells []string) {
+	parts := make([]string, len(cells))
+	for i, cell := range cells {
+		pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		if columns[i].Numeric {
+			parts[i] = pad + cell
+		} else {
+			parts[i] = cell + pad
+		}
+	}
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(parts, "  "), " "))
+}
+
+// truncateCell ellipsizes values longer than max runes; max <= 0 disables it
+func truncateCell(value string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:max-1]) + "…"
+}
+
+func formatTableNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/analysis"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+)
+
+func tableFixture() *core.DiscoveryResults {
+	return &core.DiscoveryResults{Resources: []core.Resource{
+		{ID: "vm-b", Type: "compute.instances", Name: "web", Status: "RUNNING", Region: "us-central1", Cost: &core.ResourceCost{MonthlyCost: 9.5}},
+		{ID: "bucket-a", Type: "storage.buckets", Name: "logs", Status: "READY", Region: "us", Cost: &core.ResourceCost{MonthlyCost: 120}},
+		{ID: "vm-a", Type: "compute.instances", Name: "batch", Status: "TERMINATED", Region: "us-east1", Cost: &core.ResourceCost{MonthlyCost: 30.25}},
+	}}
+}
+
+func renderLines(t *testing.T, results interface{}, opts TableOptions) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, renderTable(&buf, results, opts))
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+func TestRenderTableColumnsAndSort(t *testing.T) {
+	lines := renderLines(t, tableFixture(), TableOptions{
+		Columns: []string{"name", "region", "monthly_cost"},
+		SortBy:  "monthly_cost:desc",
+	})
+
+	assert.Equal(t, []string{
+		"Name   Region       Monthly Cost",
+		"--------------------------------",
+		"logs   us                 120.00",
+		"batch  us-east1            30.25",
+		"web    us-central1          9.50",
+		"",
+		"Total Resources: 3",
+	}, lines)
+}
+
+func TestRenderTableDefaultsAndStringSort(t *testing.T) {
+	lines := renderLines(t, tableFixture(), TableOptions{SortBy: "id"})
+
+	require.Len(t, lines, 7)
+	assert.Equal(t, "Resource  Type               Name   Status", lines[0])
+	assert.True(t, strings.HasPrefix(lines[2], "bucket-a "))
+	assert.True(t, strings.HasPrefix(lines[3], "vm-a "))
+	assert.True(t, strings.HasPrefix(lines[4], "vm-b "))
+}
+
+func TestRenderTableTruncation(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	results := &core.DiscoveryResults{Resources: []core.Resource{{ID: long, Name: "n"}}}
+
+	lines := renderLines(t, results, TableOptions{Columns: []string{"id"}})
+	assert.Equal(t, strings.Repeat("x", defaultTableWidth-1)+"…", lines[2])
+
+	lines = renderLines(t, results, TableOptions{Columns: []string{"id"}, NoTruncate: true})
+	assert.Equal(t, long, lines[2])
+}
+
+func TestRenderTableCostResults(t *testing.T) {
+	results := &analysis.CostAnalysisResults{
+		Summary: analysis.CostAnalysisSummary{TotalCost: 175, Currency: "USD"},
+		Breakdown: analysis.CostBreakdown{ByService: map[string]analysis.ServiceCost{
+			"Compute Engine": {ServiceName: "Compute Engine", TotalCost: 100, Trend: "up"},
+			"Cloud Storage":  {ServiceName: "Cloud Storage", TotalCost: 25, Trend: "flat"},
+			"BigQuery":       {ServiceName: "BigQuery", TotalCost: 50, Trend: "down"},
+		}},
+	}
+
+	// Cost tables default to the most expensive service first
+	lines := renderLines(t, results, TableOptions{Columns: []string{"service", "total_cost"}})
+	assert.Equal(t, []string{
+		"Service         Total Cost",
+		"--------------------------",
+		"Compute Engine      100.00",
+		"BigQuery             50.00",
+		"Cloud Storage        25.00",
+		"",
+		"Total Cost: 175.00 USD",
+	}, lines)
+}
+
+func TestRenderTableChargeback(t *testing.T) {
+	report := &analysis.ChargebackReport{
+		StartDate:    time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:      time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Currency:     "USD",
+		TotalCost:    400,
+		UntaggedCost: 100,
+		Teams: []analysis.TeamCost{
+			{Team: "payments", Cost: 300, Percentage: 75, LineItems: 4},
+			{Team: "untagged", Cost: 100, Percentage: 25, LineItems: 2},
+		},
+	}
+
+	lines := renderLines(t, report, TableOptions{Columns: []string{"team", "cost"}})
+	assert.Equal(t, []string{
+		"Team        Cost",
+		"----------------",
+		"payments  300.00",
+		"untagged  100.00",
+		"",
+		"Total Cost: 400.00 USD (untagged 100.00), 2026-09-01 to 2026-10-01",
+	}, lines)
+}
+
+func TestRenderTableAgeReport(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	report := &analysis.AgeReport{
+		MinAgeDays: 180, MaxIdleDays: 90, TotalResources: 4, StaleResources: 1, UnknownAge: 1,
+		Stale: []analysis.ResourceAge{
+			{ID: "vm-legacy", Type: "compute.instances", CreatedAt: created, LastModified: created, AgeDays: 944, IdleDays: 944},
+		},
+	}
+
+	lines := renderLines(t, report, TableOptions{Columns: []string{"id", "last_modified", "idle_days"}})
+	assert.Equal(t, []string{
+		"Resource   Last Modified  Idle (days)",
+		"-------------------------------------",
+		"vm-legacy  2024-03-01             944",
+		"",
+		"Stale: 1 of 4 resources (created over 180 days ago, unchanged for 90 days), 1 without a creation time",
+	}, lines)
+
+	// Grouped by type the table lists types instead
+	report.ByType = []analysis.AgeGroup{
+		{Type: "storage.buckets", Resources: 1},
+		{Type: "compute.instances", Resources: 2, Stale: 1, OldestAgeDays: 944, MaxIdleDays: 944},
+	}
+	lines = renderLines(t, report, TableOptions{Columns: []string{"type", "stale"}, SortBy: "stale"})
+	assert.Equal(t, "storage.buckets        0", lines[2])
+	assert.Equal(t, "compute.instances      1", lines[3])
+}
+
+func TestRenderTableAnalysisResults(t *testing.T) {
+	results := &analysis.AnalysisResults{Resources: []analysis.ResourceAnalysis{
+		{ResourceID: "a", Health: "healthy", HealthScore: 95},
+		{ResourceID: "b", Health: "critical", HealthScore: 20},
+	}}
+
+	lines := renderLines(t, results, TableOptions{Columns: []string{"id", "health_score"}, SortBy: "health_score"})
+	assert.Equal(t, "b            20", lines[2])
+	assert.Equal(t, "a            95", lines[3])
+}
+
+func TestRenderTableErrors(t *testing.T) {
+	var buf bytes.Buffer
+	err := renderTable(&buf, tableFixture(), TableOptions{Columns: []string{"owner"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown column "owner"`)
+
+	err = renderTable(&buf, tableFixture(), TableOptions{SortBy: "name:sideways"})
+	require.Error(t, err)
+
+	err = renderTable(&buf, map[string]string{}, TableOptions{})
+	require.Error(t, err)
+}
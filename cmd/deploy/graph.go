@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/depgraph"
+)
+
+// deploymentGraph is the resource dependency graph grouped into the batches
+// performDeployment runs them in
+type deploymentGraph struct {
+	deps    map[string][]string
+	batches [][]string
+	// cycle holds the resources that can't be batched because they are on
+	// or wait for a dependency cycle
+	cycle []string
+}
+
+func newDeploymentGraph(resources []ResourceConfig) *deploymentGraph {
+	g := &deploymentGraph{deps: buildDependencyGraph(resources)}
+	var cycleErr *depgraph.CycleError
+	batches, err := depgraph.Batches(g.deps)
+	if errors.As(err, &cycleErr) {
+		g.cycle = cycleErr.Nodes
+	}
+	g.batches = batches
+	return g
+}
+
+// err reports the cycle, if any, the same way a deployment would
+func (g *deploymentGraph) err() error {
+	if len(g.cycle) == 0 {
+		return nil
+	}
+	return &depgraph.CycleError{Nodes: g.cycle}
+}
+
+// edges lists resource -> dependency pairs, sorted; depends_on entries that
+// aren't resources of the deployment are left out like they are when batching
+func (g *deploymentGraph) edges() [][2]string {
+	var edges [][2]string
+	for node, deps := range g.deps {
+		seen := make(map[string]bool)
+		for _, dep := range deps {
+			if _, ok := g.deps[dep]; !ok || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			edges = append(edges, [2]string{node, dep})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+// renderGraph renders the dependency graph of resources as Graphviz dot or
+// Mermaid, with each batch drawn as a cluster. Resources on a cycle are
+// drawn in red in a cluster of their own, and the graph is returned along
+// with the cycle error.
+func renderGraph(resources []ResourceConfig, format string) (string, error) {
+	g := newDeploymentGraph(resources)
+	switch format {
+	case "dot":
+		return g.dot(), g.err()
+	case "mermaid":
+		return g.mermaid(), g.err()
+	default:
+		return "", fmt.Errorf("unsupported graph format %q (use dot or mermaid)", format)
+	}
+}
+
+func (g *deploymentGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph deployment {\n")
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for i, batch := range g.batches {
+		fmt.Fprintf(&b, "  subgraph cluster_batch_%d {\n", i+1)
+		fmt.Fprintf(&b, "    label=\"batch %d\";\n", i+1)
+		for _, node := range batch {
+			fmt.Fprintf(&b, "    %q;\n", node)
+		}
+		b.WriteString("  }\n")
+	}
+	if len(g.cycle) > 0 {
+		b.WriteString("  subgraph cluster_cycle {\n")
+		b.WriteString("    label=\"cycle\";\n")
+		b.WriteString("    color=red;\n")
+		for _, node := range g.cycle {
+			fmt.Fprintf(&b, "    %q [color=red];\n", node)
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, edge := range g.edges() {
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge[0], edge[1])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *deploymentGraph) mermaid() string {
+	// Resource keys contain dots, so nodes get plain ids and the key as label
+	ids := make(map[string]string)
+	id := func(node string) string {
+		if _, ok := ids[node]; !ok {
+			ids[node] = fmt.Sprintf("r%d", len(ids))
+		}
+		return ids[node]
+	}
+
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for i, batch := range g.batches {
+		fmt.Fprintf(&b, "  subgraph batch_%d[\"batch %d\"]\n", i+1, i+1)
+		for _, node := range batch {
+			fmt.Fprintf(&b, "    %s[\"%s\"]\n", id(node), node)
+		}
+		b.WriteString("  end\n")
+	}
+	if len(g.cycle) > 0 {
+		b.WriteString("  subgraph cycle[\"cycle\"]\n")
+		for _, node := range g.cycle {
+			fmt.Fprintf(&b, "    %s[\"%s\"]:::cycle\n", id(node), node)
+		}
+		b.WriteString("  end\n")
+		b.WriteString("  classDef cycle stroke:#d00,stroke-width:2px\n")
+	}
+
+	for _, edge := range g.edges() {
+		fmt.Fprintf(&b, "  %s --> %s\n", id(edge[0]), id(edge[1]))
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/depgraph"
+)
+
+func graphTestResources() []ResourceConfig {
+	return []ResourceConfig{
+		{Type: "compute", Name: "web", DependsOn: []string{"network.vpc", "storage.assets"}},
+		{Type: "network", Name: "vpc"},
+		{Type: "storage", Name: "assets"},
+		{Type: "network", Name: "subnet", DependsOn: []string{"network.vpc", "external.thing"}},
+		{Type: "dns", Name: "record", DependsOn: []string{"compute.web"}},
+	}
+}
+
+func TestRenderGraphDot(t *testing.T) {
+	output, err := renderGraph(graphTestResources(), "dot")
+	require.NoError(t, err)
+	assert.Equal(t, `digraph deployment {
+  rankdir=TB;
+  node [shape=box];
+  subgraph cluster_batch_1 {
+    label="batch 1";
+    "network.vpc";
+    "storage.assets";
+  }
+  subgraph cluster_batch_2 {
+    label="batch 2";
+    "compute.web";
+    "network.subnet";
+  }
+  subgraph cluster_batch_3 {
+    label="batch 3";
+    "dns.record";
+  }
+  "compute.web" -> "network.vpc";
+  "compute.web" -> "storage.assets";
+  "dns.record" -> "compute.web";
+  "network.subnet" -> "network.vpc";
+}
+`, output)
+}
+
+func TestRenderGraphMermaid(t *testing.T) {
+	output, err := renderGraph(graphTestResources(), "mermaid")
+	require.NoError(t, err)
+	assert.Equal(t, `graph TD
+  subgraph batch_1["batch 1"]
+    r0["network.vpc"]
+    r1["storage.assets"]
+  end
+  subgraph batch_2["batch 2"]
+    r2["compute.web"]
+    r3["network.subnet"]
+  end
+  subgraph batch_3["batch 3"]
+    r4["dns.record"]
+  end
+  r2 --> r0
+  r2 --> r1
+  r4 --> r2
+  r3 --> r0
+`, output)
+}
+
+func TestRenderGraphMarksCycles(t *testing.T) {
+	resources := append(graphTestResources(),
+		ResourceConfig{Type: "iam", Name: "a", DependsOn: []string{"iam.b"}},
+		ResourceConfig{Type: "iam", Name: "b", DependsOn: []string{"iam.a", "network.vpc"}},
+	)
+
+	output, err := renderGraph(resources, "dot")
+	var cycle *depgraph.CycleError
+	require.ErrorAs(t, err, &cycle)
+	assert.Equal(t, []string{"iam.a", "iam.b"}, cycle.Nodes)
+	assert.Contains(t, output, "subgraph cluster_cycle {\n    label=\"cycle\";\n    color=red;\n    \"iam.a\" [color=red];\n    \"iam.b\" [color=red];\n  }\n")
+	assert.Contains(t, output, "\"iam.a\" -> \"iam.b\";")
+	assert.Contains(t, output, "\"iam.b\" -> \"iam.a\";")
+	// The rest of the deployment is still batched
+	assert.Contains(t, output, "label=\"batch 3\";\n    \"dns.record\";")
+
+	output, err = renderGraph(resources, "mermaid")
+	require.Error(t, err)
+	assert.Contains(t, output, "    r5[\"iam.a\"]:::cycle\n    r6[\"iam.b\"]:::cycle\n")
+}
+
+func TestRenderGraphUnknownFormat(t *testing.T) {
+	_, err := renderGraph(graphTestResources(), "svg")
+	assert.ErrorContains(t, err, "unsupported graph format")
+}
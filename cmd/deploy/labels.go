@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+)
+
+// maxLabelLength is the GCP limit on label keys and values
+const maxLabelLength = 63
+
+// labelFields maps the resource types that support labels to the config
+// field holding them. Types not listed (networks, firewalls, IAM) have none.
+var labelFields = map[string]string{
+	"compute_instance": "labels",
+	"compute_disk":     "labels",
+	"storage_bucket":   "labels",
+	"secret":           "labels",
+	"pubsub_topic":     "labels",
+	"gke_cluster":      "resource_labels",
+	"sql_instance":     "user_labels",
+}
+
+// maxLabels is how many labels GCP allows on one resource
+var maxLabels = 64
+
+// labelStore reads and patches the labels of deployed resources
+type labelStore interface {
+	// Labels returns the labels of a deployed resource; ok is false when it
+	// doesn't exist yet
+	Labels(ctx context.Context, resource ResourceConfig) (labels map[string]string, ok bool, err error)
+	PatchLabels(ctx context.Context, resource ResourceConfig, labels map[string]string) error
+}
+
+// LabelChange reports the labels a resource was missing
+type LabelChange struct {
+	Resource string   `json:"resource"`
+	Added    []string `json:"added"`
+	// Patched is set when the labels were applied to an existing resource
+	Patched bool `json:"patched,omitempty"`
+}
+
+// labelReconciler makes every resource carry the deployment's standard
+// labels, keeping the values resources already set
+type labelReconciler struct {
+	labels map[string]string
+	store  labelStore
+}
+
+func newLabelReconciler(labels map[string]string, store labelStore) (*labelReconciler, error) {
+	sanitized := make(map[string]string, len(labels))
+	for key, value := range labels {
+		k, v, err := sanitizeLabel(key, value)
+		if err != nil {
+			return nil, err
+		}
+		sanitized[k] = v
+	}
+	if len(sanitized) > maxLabels {
+		return nil, fmt.Errorf("%d standard labels exceed the limit of %d", len(sanitized), maxLabels)
+	}
+	return &labelReconciler{labels: sanitized, store: store}, nil
+}
+
+// sanitizeLabel applies GCP's label constraints: lowercase letters, digits,
+// underscores and dashes, at most 63 characters, keys starting with a letter
+func sanitizeLabel(key, value string) (string, string, error) {
+	k := sanitizeLabelPart(key)
+	if k == "" || k[0] < 'a' || k[0] > 'z' {
+		return "", "", fmt.Errorf("label key %q must start with a lowercase letter", key)
+	}
+	return k, sanitizeLabelPart(value), nil
+}
+
+func sanitizeLabelPart(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	out := b.String()
+	if len(out) > maxLabelLength {
+		out = out[:maxLabelLength]
+	}
+	return out
+}
+
+// missing returns the standard labels absent from current, sorted by key
+func (r *labelReconciler) missing(current map[string]string) []string {
+	var keys []string
+	for key := range r.labels {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeInto adds the missing standard labels to the resource's config so
+// they are set on create and update, returning the keys it added
+func (r *labelReconciler) mergeInto(resource *ResourceConfig) ([]string, error) {
+	field, ok := labelFields[resource.Type]
+	if !ok {
+		return nil, nil
+	}
+	if resource.Config == nil {
+		resource.Config = make(map[string]interface{})
+	}
+
+	current := make(map[string]string)
+	switch existing := resource.Config[field].(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, value := range existing {
+			current[key] = fmt.Sprint(value)
+		}
+	case map[string]string:
+		for key, value := range existing {
+			current[key] = value
+		}
+	default:
+		return nil, fmt.Errorf("%s.%s: %s must be a map of strings", resource.Type, resource.Name, field)
+	}
+
+	added := r.missing(current)
+	if len(current)+len(added) > maxLabels {
+		return nil, fmt.Errorf("%s.%s: adding %d standard labels exceeds the limit of %d", resource.Type, resource.Name, len(added), maxLabels)
+	}
+	for _, key := range added {
+		current[key] = r.labels[key]
+	}
+
+	merged := make(map[string]interface{}, len(current))
+	for key, value := range current {
+		merged[key] = value
+	}
+	resource.Config[field] = merged
+	return added, nil
+}
+
+// reconcile merges the standard labels into every resource and patches the
+// ones that already exist but lack some. With dryRun nothing is patched.
+func (r *labelReconciler) reconcile(ctx context.Context, resources []ResourceConfig, dryRun bool) ([]LabelChange, error) {
+	var changes []LabelChange
+	var errs []error
+
+	for i := range resources {
+		resource := &resources[i]
+		if _, ok := labelFields[resource.Type]; !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s.%s", resource.Type, resource.Name)
+
+		// Creates and updates carry the labels through the config
+		added, err := r.mergeInto(resource)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		change := LabelChange{Resource: key, Added: added}
+
+		// Resources that already exist get the missing labels patched in
+		if r.store != nil {
+			current, exists, err := r.store.Labels(ctx, *resource)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			if exists {
+				change = LabelChange{Resource: key, Added: r.missing(current)}
+				if len(change.Added) > 0 && !dryRun {
+					patched := make(map[string]string, len(current)+len(change.Added))
+					for k, v := range current {
+						patched[k] = v
+					}
+					for _, k := range change.Added {
+						patched[k] = r.labels[k]
+					}
+					if err := r.store.PatchLabels(ctx, *resource, patched); err != nil {
+						errs = append(errs, fmt.Errorf("%s: failed to patch labels: %w", key, err))
+						continue
+					}
+					change.Patched = true
+				}
+			}
+		}
+
+		if len(change.Added) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// gcpLabelStore reads and patches labels through the GCP APIs for compute
+// instances, storage buckets and secrets
+type gcpLabelStore struct {
+	client  *gcp.Client
+	project string
+	zone    string
+}
+
+func newGCPLabelStore(client *gcp.Client, config *DeploymentConfig) *gcpLabelStore {
+	return &gcpLabelStore{client: client, project: config.ProjectID, zone: config.Zone}
+}
+
+func (s *gcpLabelStore) Labels(ctx context.Context, resource ResourceConfig) (map[string]string, bool, error) {
+	var labels map[string]string
+	var err error
+	switch resource.Type {
+	case "compute_instance":
+		var instance *computepb.Instance
+		if instance, err = s.instance(ctx, resource.Name); err == nil {
+			labels = instance.GetLabels()
+		}
+	case "storage_bucket":
+		var client *storage.Client
+		if client, err = s.client.GetStorageClient(ctx); err != nil {
+			return nil, false, err
+		}
+		var attrs *storage.BucketAttrs
+		attrs, err = client.Bucket(resource.Name).Attrs(ctx)
+		if errors.Is(err, storage.ErrBucketNotExist) {
+			return nil, false, nil
+		}
+		if err == nil {
+			labels = attrs.Labels
+		}
+	case "secret":
+		var secret *secretmanagerpb.Secret
+		if secret, err = s.secret(ctx, resource.Name); err == nil {
+			labels = secret.GetLabels()
+		}
+	default:
+		// Other types are only labelled through their config
+		return nil, false, nil
+	}
+
+	if isNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	return labels, true, nil
+}
+
+func (s *gcpLabelStore) PatchLabels(ctx context.Context, resource ResourceConfig, labels map[string]string) error {
+	switch resource.Type {
+	case "compute_instance":
+		instance, err := s.instance(ctx, resource.Name)
+		if err != nil {
+			return err
+		}
+		client, err := s.client.GetComputeClient(ctx)
+		if err != nil {
+			return err
+		}
+		op, err := client.SetLabels(ctx, &computepb.SetLabelsInstanceRequest{
+			Project:  s.project,
+			Zone:     s.zone,
+			Instance: resource.Name,
+			InstancesSetLabelsRequestResource: &computepb.InstancesSetLabelsRequest{
+				Labels:           labels,
+				LabelFingerprint: instance.LabelFingerprint,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return op.Wait(ctx)
+	case "storage_bucket":
+		client, err := s.client.GetStorageClient(ctx)
+		if err != nil {
+			return err
+		}
+		var update storage.BucketAttrsToUpdate
+		for key, value := range labels {
+			update.SetLabel(key, value)
+		}
+		_, err = client.Bucket(resource.Name).Update(ctx, update)
+		return err
+	case "secret":
+		client, err := s.client.GetSecretManagerClient(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = client.UpdateSecret(ctx, &secretmanagerpb.UpdateSecretRequest{
+			Secret:     &secretmanagerpb.Secret{Name: s.secretName(resource.Name), Labels: labels},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}},
+		})
+		return err
+	default:
+		return fmt.Errorf("patching labels of %s resources is not supported", resource.Type)
+	}
+}
+
+func (s *gcpLabelStore) instance(ctx context.Context, name string) (*computepb.Instance, error) {
+	client, err := s.client.GetComputeClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.Get(ctx, &computepb.GetInstanceRequest{Project: s.project, Zone: s.zone, Instance: name})
+}
+
+func (s *gcpLabelStore) secret(ctx context.Context, name string) (*secretmanagerpb.Secret, error) {
+	client, err := s.client.GetSecretManagerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: s.secretName(name)})
+}
+
+func (s *gcpLabelStore) secretName(name string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", s.project, name)
+}
+
+// isNotFound reports whether err is a 404 from a REST or gRPC API
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusNotFound
+	}
+	return status.Code(err) == codes.NotFound
+}
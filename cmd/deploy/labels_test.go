@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLabelStore holds the labels of deployed resources by type.name
+type fakeLabelStore struct {
+	labels  map[string]map[string]string
+	patched map[string]map[string]string
+}
+
+func (s *fakeLabelStore) Labels(_ context.Context, resource ResourceConfig) (map[string]string, bool, error) {
+	labels, ok := s.labels[resource.Type+"."+resource.Name]
+	return labels, ok, nil
+}
+
+func (s *fakeLabelStore) PatchLabels(_ context.Context, resource ResourceConfig, labels map[string]string) error {
+	if s.patched == nil {
+		s.patched = make(map[string]map[string]string)
+	}
+	s.patched[resource.Type+"."+resource.Name] = labels
+	return nil
+}
+
+var standardLabels = map[string]string{
+	"cost-center": "1234",
+	"Owner":       "Platform Team",
+}
+
+func TestReconcileLabelsMergesOnCreate(t *testing.T) {
+	reconciler, err := newLabelReconciler(standardLabels, &fakeLabelStore{})
+	require.NoError(t, err)
+
+	resources := []ResourceConfig{
+		{Type: "compute_instance", Name: "web", Config: map[string]interface{}{
+			"labels": map[string]interface{}{"owner": "web-team"},
+		}},
+		{Type: "gke_cluster", Name: "main"},
+		{Type: "network", Name: "vpc", Config: map[string]interface{}{}},
+	}
+	changes, err := reconciler.reconcile(context.Background(), resources, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []LabelChange{
+		{Resource: "compute_instance.web", Added: []string{"cost-center"}},
+		{Resource: "gke_cluster.main", Added: []string{"cost-center", "owner"}},
+	}, changes)
+
+	// Values a resource already sets win; values are sanitized for GCP
+	assert.Equal(t, map[string]interface{}{"owner": "web-team", "cost-center": "1234"}, resources[0].Config["labels"])
+	assert.Equal(t, map[string]interface{}{"owner": "platform_team", "cost-center": "1234"}, resources[1].Config["resource_labels"])
+	assert.NotContains(t, resources[2].Config, "labels", "networks don't support labels")
+}
+
+func TestReconcileLabelsPatchesExisting(t *testing.T) {
+	store := &fakeLabelStore{labels: map[string]map[string]string{
+		"storage_bucket.logs":  {"env": "prod"},
+		"storage_bucket.audit": {"cost-center": "1234", "owner": "security"},
+	}}
+	reconciler, err := newLabelReconciler(standardLabels, store)
+	require.NoError(t, err)
+
+	resources := []ResourceConfig{
+		{Type: "storage_bucket", Name: "logs"},
+		{Type: "storage_bucket", Name: "audit"},
+	}
+
+	// A dry run reports but doesn't patch
+	changes, err := reconciler.reconcile(context.Background(), resources, true)
+	require.NoError(t, err)
+	assert.Equal(t, []LabelChange{{Resource: "storage_bucket.logs", Added: []string{"cost-center", "owner"}}}, changes)
+	assert.Empty(t, store.patched)
+
+	changes, err = reconciler.reconcile(context.Background(), resources, false)
+	require.NoError(t, err)
+	assert.Equal(t, []LabelChange{{Resource: "storage_bucket.logs", Added: []string{"cost-center", "owner"}, Patched: true}}, changes)
+	assert.Equal(t, map[string]map[string]string{
+		"storage_bucket.logs": {"env": "prod", "cost-center": "1234", "owner": "platform_team"},
+	}, store.patched)
+}
+
+func TestSanitizeLabel(t *testing.T) {
+	key, value, err := sanitizeLabel("Team.Name", "Data Platform/EU")
+	require.NoError(t, err)
+	assert.Equal(t, "team_name", key)
+	assert.Equal(t, "data_platform_eu", value)
+
+	_, value, err = sanitizeLabel("description", string(make([]byte, 100)))
+	require.NoError(t, err)
+	assert.Len(t, value, maxLabelLength)
+
+	_, _, err = sanitizeLabel("1st", "x")
+	assert.ErrorContains(t, err, "must start with a lowercase letter")
+}
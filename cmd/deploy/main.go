@@ -5,14 +5,28 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/depgraph"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
 )
 
+// stdout receives progress output; emoji and colors are stripped from it
+// when color output is disabled
+var stdout io.Writer = os.Stdout
+
+// jsonOut receives the json result, which is never stripped as its values
+// are data
+var jsonOut io.Writer = os.Stdout
+
 type DeploymentConfig struct {
 	ProjectID     string                 `json:"project_id"`
 	Region        string                 `json:"region"`
@@ -21,6 +35,8 @@ type DeploymentConfig struct {
 	Resources     []ResourceConfig       `json:"resources"`
 	Dependencies  []string              `json:"dependencies,omitempty"`
 	Settings      map[string]interface{} `json:"settings,omitempty"`
+	// Labels are the org-standard labels every labelable resource must carry
+	Labels        map[string]string      `json:"labels,omitempty"`
 }
 
 type ResourceConfig struct {
@@ -36,6 +52,7 @@ type DeploymentResult struct {
 	Errors    []string              `json:"errors,omitempty"`
 	Duration  time.Duration          `json:"duration"`
 	Summary   map[string]interface{} `json:"summary"`
+	Labels    []LabelChange          `json:"labels_added,omitempty"`
 }
 
 type ResourceResult struct {
@@ -46,22 +63,44 @@ type ResourceResult struct {
 	Details   map[string]interface{} `json:"details,omitempty"`
 	Error     string                 `json:"error,omitempty"`
 	Duration  time.Duration          `json:"duration"`
+	// Attempts counts the tries, including retries after transient errors
+	Attempts  int                    `json:"attempts,omitempty"`
 }
 
 func main() {
 	var (
-		configFile  = flag.String("config", "", "Path to deployment configuration file")
-		environment = flag.String("env", "dev", "Deployment environment")
-		dryRun      = flag.Bool("dry-run", false, "Perform dry run without actual deployment")
-		force       = flag.Bool("force", false, "Force deployment even with warnings")
-		parallel    = flag.Int("parallel", 4, "Number of parallel operations")
-		timeout     = flag.Duration("timeout", 30*time.Minute, "Deployment timeout")
-		verbose     = flag.Bool("verbose", false, "Enable verbose output")
-		format      = flag.String("format", "json", "Output format (json, text)")
-		workDir     = flag.String("workdir", ".", "Working directory")
+		configFile   = flag.String("config", "", "Path to deployment configuration file")
+		environment  = flag.String("env", "dev", "Deployment environment")
+		dryRun       = flag.Bool("dry-run", false, "Perform dry run without actual deployment")
+		force        = flag.Bool("force", false, "Force deployment even with warnings")
+		parallel     = flag.Int("parallel", 4, "Number of parallel operations")
+		timeout      = flag.Duration("timeout", 30*time.Minute, "Deployment timeout")
+		verbose      = flag.Bool("verbose", false, "Enable verbose output")
+		format       = flag.String("format", "json", "Output format (json, text)")
+		workDir      = flag.String("workdir", ".", "Working directory")
+		noColor      = flag.Bool("no-color", false, "Disable colored and emoji output (also honors NO_COLOR)")
+		validateCfg  = flag.Bool("validate-config", false, "Validate the -config file against its schema and exit")
+		configSchema = flag.Bool("config-schema", false, "Print the JSON Schema of the config file and exit")
+		configFormat = flag.String("config-format", "auto", "Config file format (auto, json, yaml); auto uses the file extension")
+		graph        = flag.String("graph", "", "Print the resource dependency graph with its batches (dot, mermaid) and exit")
+		maxRetries   = flag.Int("max-retries", 3, "Retries per resource after transient GCP errors")
+		retryDelay   = flag.Duration("retry-delay", 2*time.Second, "Initial backoff between retries, doubled on each retry")
+		watch        = flag.Bool("watch", false, "After deploying, watch the config file and reconcile each change until interrupted")
+		debounce     = flag.Duration("watch-debounce", 2*time.Second, "How long the config must be unchanged before -watch reconciles")
+		minInterval  = flag.Duration("watch-interval", 30*time.Second, "Minimum time between -watch reconciles")
 	)
 	flag.Parse()
 
+	if *configSchema {
+		configschema.Print(os.Stdout, DeploymentConfig{})
+		return
+	}
+	if *validateCfg {
+		os.Exit(configschema.ValidateFile(os.Stderr, *configFile, *configFormat, DeploymentConfig{}))
+	}
+
+	stdout = termcolor.NewWriter(os.Stdout, termcolor.Enabled(*noColor, os.Stdout))
+
 	if *configFile == "" {
 		fmt.Fprintf(os.Stderr, "Error: -config flag is required\n")
 		flag.Usage()
@@ -81,29 +120,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	configData, err := os.ReadFile(configPath)
+	loadConfig := func() (*DeploymentConfig, error) {
+		return loadDeploymentConfig(configPath, *configFormat, *environment)
+	}
+	loaded, err := loadConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
 		os.Exit(1)
 	}
+	deployConfig := *loaded
 
-	var deployConfig DeploymentConfig
-	if err := json.Unmarshal(configData, &deployConfig); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing config file: %v\n", err)
-		os.Exit(1)
+	if *graph != "" {
+		output, err := renderGraph(deployConfig.Resources, *graph)
+		fmt.Fprint(os.Stdout, output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Override environment if specified
-	if *environment != "dev" {
-		deployConfig.Environment = *environment
+	// In watch mode the process runs until SIGINT or SIGTERM, and -timeout
+	// bounds each deployment rather than the whole run
+	baseCtx := context.Background()
+	if *watch {
+		var stop context.CancelFunc
+		baseCtx, stop = signal.NotifyContext(baseCtx, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
 	}
 
 	// Initialize context
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	ctx, cancel := context.WithTimeout(baseCtx, *timeout)
 	defer cancel()
 
 	// Initialize GCP client
-	client, err := gcp.NewClient(ctx, &gcp.ClientConfig{
+	client, err := gcp.NewClient(baseCtx, &gcp.ClientConfig{
 		ProjectID:     deployConfig.ProjectID,
 		Region:        deployConfig.Region,
 		Zone:          deployConfig.Zone,
@@ -118,38 +169,75 @@ func main() {
 	defer client.Close()
 
 	if *verbose {
-		fmt.Printf("🚀 Starting deployment for environment: %s\n", deployConfig.Environment)
-		fmt.Printf("📍 Project: %s, Region: %s, Zone: %s\n",
+		fmt.Fprintf(stdout, "🚀 Starting deployment for environment: %s\n", deployConfig.Environment)
+		fmt.Fprintf(stdout, "📍 Project: %s, Region: %s, Zone: %s\n",
 			deployConfig.ProjectID, deployConfig.Region, deployConfig.Zone)
 		if *dryRun {
-			fmt.Println("🧪 DRY RUN MODE - No actual changes will be made")
+			fmt.Fprintln(stdout, "🧪 DRY RUN MODE - No actual changes will be made")
 		}
 	}
 
+	deploy := func(ctx context.Context, config *DeploymentConfig) *DeploymentResult {
+		startTime := time.Now()
+		result := performDeployment(ctx, client, config, &deploymentOptions{
+			DryRun:   *dryRun,
+			Force:    *force,
+			Parallel: *parallel,
+			Verbose:  *verbose,
+			Labels:   newGCPLabelStore(client, config),
+			Retry:    retryPolicy{MaxRetries: *maxRetries, BaseDelay: *retryDelay, MaxDelay: time.Minute},
+		})
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
 	// Perform deployment
-	startTime := time.Now()
-	result := performDeployment(ctx, client, &deployConfig, &deploymentOptions{
-		DryRun:   *dryRun,
-		Force:    *force,
-		Parallel: *parallel,
-		Verbose:  *verbose,
-	})
-	result.Duration = time.Since(startTime)
+	deployed := cloneResources(deployConfig.Resources)
+	result := deploy(ctx, &deployConfig)
+	failed := notApplied(deployed, result)
+	applied := deployed[:0]
+	for _, resource := range deployed {
+		if !failed[resource.Type+"."+resource.Name] {
+			applied = append(applied, resource)
+		}
+	}
 
 	// Output results
-	switch *format {
-	case "json":
-		output, err := json.MarshalIndent(result, "", "  ")
+	if err := writeResult(result, *format, *verbose); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *watch {
+		r := &reconciler{
+			previous: applied,
+			load:     loadConfig,
+			deploy: func(ctx context.Context, config *DeploymentConfig) *DeploymentResult {
+				ctx, cancel := context.WithTimeout(ctx, *timeout)
+				defer cancel()
+				return deploy(ctx, config)
+			},
+			pruner: simulatedApplier{},
+			dryRun: *dryRun,
+		}
+		fmt.Fprintf(stdout, "👀 Watching %s for changes\n", configPath)
+		err := watchConfig(baseCtx, configPath, watchOptions{Debounce: *debounce, MinInterval: *minInterval}, func(ctx context.Context) {
+			_, result, err := r.reconcile(ctx)
+			if result != nil {
+				if err := writeResult(result, *format, *verbose); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Reconcile error: %v\n", err)
+			}
+		})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println(string(output))
-	case "text":
-		printTextResult(result, *verbose)
-	default:
-		fmt.Fprintf(os.Stderr, "Error: Unsupported format '%s'\n", *format)
-		os.Exit(1)
+		fmt.Fprintln(stdout, "👋 Stopped watching")
+		return
 	}
 
 	// Exit with appropriate code
@@ -158,11 +246,52 @@ func main() {
 	}
 }
 
+// loadDeploymentConfig reads the deployment config at path, applying the
+// -env override
+func loadDeploymentConfig(path, format, environment string) (*DeploymentConfig, error) {
+	configData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var deployConfig DeploymentConfig
+	if err := configschema.DecodeFormat(configData, configschema.DetectFormat(path, format), &deployConfig); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+
+	// Override environment if specified
+	if environment != "dev" {
+		deployConfig.Environment = environment
+	}
+	return &deployConfig, nil
+}
+
+func writeResult(result *DeploymentResult, format string, verbose bool) error {
+	switch format {
+	case "json":
+		output, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting output: %w", err)
+		}
+		fmt.Fprintln(jsonOut, string(output))
+	case "text":
+		printTextResult(result, verbose)
+	default:
+		return fmt.Errorf("unsupported format '%s'", format)
+	}
+	return nil
+}
+
 type deploymentOptions struct {
 	DryRun   bool
 	Force    bool
 	Parallel int
 	Verbose  bool
+	// Labels looks up and patches labels of resources that already exist
+	Labels labelStore
+	// Applier creates the resources; nil simulates the deployment
+	Applier resourceApplier
+	Retry   retryPolicy
 }
 
 func performDeployment(ctx context.Context, client *gcp.Client, config *DeploymentConfig, opts *deploymentOptions) *DeploymentResult {
@@ -172,16 +301,39 @@ func performDeployment(ctx context.Context, client *gcp.Client, config *Deployme
 		Summary:   make(map[string]interface{}),
 	}
 
+	// Make resources carry the standard labels before they are deployed
+	if len(config.Labels) > 0 {
+		changes, err := reconcileLabels(ctx, config, opts)
+		result.Labels = changes
+		if err != nil {
+			result.Success = false
+			result.Errors = append(result.Errors, fmt.Sprintf("Label reconciliation failed: %v", err))
+			if !opts.Force {
+				return result
+			}
+		}
+	}
+
 	// Create service instances
 	services := initializeServices(client)
 
 	// Process resources in dependency order
 	resourceGraph := buildDependencyGraph(config.Resources)
-	executionPlan := topologicalSort(resourceGraph)
+	executionPlan, err := depgraph.Batches(resourceGraph)
+	if err != nil {
+		result.Success = false
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	resources := make(map[string]ResourceConfig, len(config.Resources))
+	for _, resource := range config.Resources {
+		resources[resource.Type+"."+resource.Name] = resource
+	}
 
 	// Execute deployment plan
 	for _, batch := range executionPlan {
-		batchResults := deployBatch(ctx, services, batch, opts)
+		batchResults := deployBatch(ctx, services, batch, resources, opts)
 		result.Resources = append(result.Resources, batchResults...)
 
 		// Check for failures
@@ -204,6 +356,26 @@ func performDeployment(ctx context.Context, client *gcp.Client, config *Deployme
 	return result
 }
 
+// reconcileLabels merges the standard labels into the resources about to be
+// deployed and patches existing ones that lack them
+func reconcileLabels(ctx context.Context, config *DeploymentConfig, opts *deploymentOptions) ([]LabelChange, error) {
+	reconciler, err := newLabelReconciler(config.Labels, opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := reconciler.reconcile(ctx, config.Resources, opts.DryRun)
+	if opts.Verbose {
+		for _, change := range changes {
+			action := "added to config"
+			if change.Patched {
+				action = "patched"
+			}
+			fmt.Fprintf(stdout, "🏷️  %s: %s %s\n", change.Resource, strings.Join(change.Added, ", "), action)
+		}
+	}
+	return changes, err
+}
+
 func initializeServices(client *gcp.Client) map[string]interface{} {
 	services := make(map[string]interface{})
 
@@ -251,60 +423,9 @@ func buildDependencyGraph(resources []ResourceConfig) map[string][]string {
 	return graph
 }
 
-func topologicalSort(graph map[string][]string) [][]string {
-	// Simple topological sort implementation
-	// Returns batches of resources that can be deployed in parallel
-
-	visited := make(map[string]bool)
-	inDegree := make(map[string]int)
-
-	// Calculate in-degrees
-	for node := range graph {
-		if _, exists := inDegree[node]; !exists {
-			inDegree[node] = 0
-		}
-		for _, dep := range graph[node] {
-			inDegree[dep]++
-		}
-	}
-
-	var batches [][]string
-
-	for len(visited) < len(graph) {
-		var currentBatch []string
-
-		// Find nodes with in-degree 0
-		for node, degree := range inDegree {
-			if !visited[node] && degree == 0 {
-				currentBatch = append(currentBatch, node)
-			}
-		}
-
-		if len(currentBatch) == 0 {
-			// Circular dependency detected, break the cycle
-			for node := range graph {
-				if !visited[node] {
-					currentBatch = append(currentBatch, node)
-					break
-				}
-			}
-		}
-
-		// Mark current batch as visited and update in-degrees
-		for _, node := range currentBatch {
-			visited[node] = true
-			for _, dep := range graph[node] {
-				inDegree[dep]--
-			}
-		}
-
-		batches = append(batches, currentBatch)
-	}
-
-	return batches
-}
-
-func deployBatch(ctx context.Context, services map[string]interface{}, batch []string, opts *deploymentOptions) []ResourceResult {
+// deployBatch applies the resources of one batch, retrying transient
+// failures as opts.Retry allows
+func deployBatch(ctx context.Context, services map[string]interface{}, batch []string, resources map[string]ResourceConfig, opts *deploymentOptions) []ResourceResult {
 	results := make([]ResourceResult, 0, len(batch))
 
 	for _, resourceKey := range batch {
@@ -317,10 +438,9 @@ func deployBatch(ctx context.Context, services map[string]interface{}, batch []s
 		startTime := time.Now()
 
 		result := ResourceResult{
-			Type:     resourceType,
-			Name:     resourceName,
-			Status:   "success",
-			Duration: time.Since(startTime),
+			Type:   resourceType,
+			Name:   resourceName,
+			Status: "success",
 		}
 
 		if opts.DryRun {
@@ -330,17 +450,34 @@ func deployBatch(ctx context.Context, services map[string]interface{}, batch []s
 				"type":   resourceType,
 			}
 		} else {
-			// Actual deployment logic would go here
-			// For now, simulate successful deployment
-			result.ID = fmt.Sprintf("%s-%s-%d", resourceType, resourceName, time.Now().Unix())
-			result.Details = map[string]interface{}{
-				"created_at": time.Now().Format(time.RFC3339),
-				"status":     "created",
+			applier := opts.Applier
+			if applier == nil {
+				applier = simulatedApplier{}
+			}
+			attempts, err := opts.Retry.do(ctx, func(ctx context.Context) error {
+				id, err := applier.Apply(ctx, resources[resourceKey])
+				result.ID = id
+				return err
+			})
+			result.Attempts = attempts
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			} else {
+				result.Details = map[string]interface{}{
+					"created_at": time.Now().Format(time.RFC3339),
+					"status":     "created",
+				}
 			}
 		}
+		result.Duration = time.Since(startTime)
 
 		if opts.Verbose {
-			fmt.Printf("✅ %s: %s.%s (%v)\n", result.Status, resourceType, resourceName, result.Duration)
+			icon := "✅"
+			if result.Status == "failed" {
+				icon = "❌"
+			}
+			fmt.Fprintf(stdout, "%s %s: %s.%s (%v)\n", icon, result.Status, resourceType, resourceName, result.Duration)
 		}
 
 		results = append(results, result)
@@ -373,23 +510,30 @@ func generateSummary(resources []ResourceResult) map[string]interface{} {
 
 func printTextResult(result *DeploymentResult, verbose bool) {
 	if result.Success {
-		fmt.Println("✅ Deployment completed successfully")
+		fmt.Fprintln(stdout, "✅ Deployment completed successfully")
 	} else {
-		fmt.Println("❌ Deployment failed")
+		fmt.Fprintln(stdout, "❌ Deployment failed")
 	}
 
-	fmt.Printf("📊 Summary: %d resources processed in %v\n",
+	fmt.Fprintf(stdout, "📊 Summary: %d resources processed in %v\n",
 		len(result.Resources), result.Duration)
 
+	if len(result.Labels) > 0 {
+		fmt.Fprintln(stdout, "\n🏷️  Labels added:")
+		for _, change := range result.Labels {
+			fmt.Fprintf(stdout, "  - %s: %s\n", change.Resource, strings.Join(change.Added, ", "))
+		}
+	}
+
 	if len(result.Errors) > 0 {
-		fmt.Println("\n❌ Errors:")
+		fmt.Fprintln(stdout, "\n❌ Errors:")
 		for _, err := range result.Errors {
-			fmt.Printf("  - %s\n", err)
+			fmt.Fprintf(stdout, "  - %s\n", err)
 		}
 	}
 
 	if verbose {
-		fmt.Println("\n📋 Resource Details:")
+		fmt.Fprintln(stdout, "\n📋 Resource Details:")
 		for _, resource := range result.Resources {
 			status := "✅"
 			if resource.Status == "failed" {
@@ -398,17 +542,17 @@ func printTextResult(result *DeploymentResult, verbose bool) {
 				status = "🧪"
 			}
 
-			fmt.Printf("  %s %s.%s (%v)\n",
+			fmt.Fprintf(stdout, "  %s %s.%s (%v)\n",
 				status, resource.Type, resource.Name, resource.Duration)
 
 			if resource.Error != "" {
-				fmt.Printf("    Error: %s\n", resource.Error)
+				fmt.Fprintf(stdout, "    Error: %s\n", resource.Error)
 			}
 		}
 
-		fmt.Println("\n📈 Summary Details:")
+		fmt.Fprintln(stdout, "\n📈 Summary Details:")
 		summaryJSON, _ := json.MarshalIndent(result.Summary, "  ", "  ")
-		fmt.Printf("  %s\n", string(summaryJSON))
+		fmt.Fprintf(stdout, "  %s\n", string(summaryJSON))
 	}
 }
 
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
+)
+
+func TestWriteResultStripsProgressButNotJSON(t *testing.T) {
+	var progress, report bytes.Buffer
+	originalStdout, originalJSON := stdout, jsonOut
+	t.Cleanup(func() { stdout, jsonOut = originalStdout, originalJSON })
+	stdout, jsonOut = termcolor.NewWriter(&progress, false), &report
+
+	result := &DeploymentResult{Success: true, Resources: []ResourceResult{{Type: "storage", Name: "🚀 launch", Status: "success"}}}
+	require.NoError(t, writeResult(result, "json", false))
+	var decoded DeploymentResult
+	require.NoError(t, json.Unmarshal(report.Bytes(), &decoded))
+	assert.Equal(t, "🚀 launch", decoded.Resources[0].Name)
+
+	require.NoError(t, writeResult(result, "text", false))
+	assert.Contains(t, progress.String(), "Deployment completed successfully")
+	assert.NotContains(t, progress.String(), "✅")
+}
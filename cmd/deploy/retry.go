@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+)
+
+// resourceApplier creates or updates one resource and returns its ID
+type resourceApplier interface {
+	Apply(ctx context.Context, resource ResourceConfig) (string, error)
+}
+
+// simulatedApplier stands in for the per-type deployment logic, which
+// doesn't exist yet, and succeeds for every resource
+type simulatedApplier struct{}
+
+func (simulatedApplier) Apply(_ context.Context, resource ResourceConfig) (string, error) {
+	return fmt.Sprintf("%s-%s-%d", resource.Type, resource.Name, time.Now().Unix()), nil
+}
+
+// retryPolicy bounds the retries of a resource operation that failed with
+// a transient error
+type retryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	// MaxDelay caps the backoff before jitter; zero leaves it uncapped
+	MaxDelay time.Duration
+}
+
+// retrySleep waits d or until ctx is done; tests replace it to skip the wait
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff is the wait before the given retry, counting from zero: the base
+// delay doubled per retry, of which the upper half is random so resources
+// failing together don't retry together
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < retry && delay < math.MaxInt64/2 && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryable reports whether err is transient. A *gcp.Error in its chain
+// decides by its Retryable flag; any other error is retried when the error
+// catalog classifies it as transient or gcp.IsRetryable matches its message.
+func retryable(err error) bool {
+	var gcpErr *gcp.Error
+	if errors.As(err, &gcpErr) {
+		return gcpErr.Retryable
+	}
+	return gcp.AsError(err).Retryable || gcp.IsRetryable(err)
+}
+
+// do runs op until it succeeds, fails with an error retryable doesn't
+// consider transient, or runs out of retries, and returns how many attempts
+// it made
+func (p retryPolicy) do(ctx context.Context, op func(context.Context) error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil || attempt > p.MaxRetries || !retryable(err) {
+			return attempt, err
+		}
+		if ctx.Err() != nil {
+			return attempt, err
+		}
+		if sleepErr := retrySleep(ctx, p.backoff(attempt-1)); sleepErr != nil {
+			return attempt, fmt.Errorf("%w (retry abandoned: %v)", err, sleepErr)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+)
+
+// flakyApplier fails each resource with the queued errors before it succeeds
+type flakyApplier struct {
+	errs  map[string][]error
+	calls map[string]int
+}
+
+func (a *flakyApplier) Apply(_ context.Context, resource ResourceConfig) (string, error) {
+	key := resource.Type + "." + resource.Name
+	if a.calls == nil {
+		a.calls = make(map[string]int)
+	}
+	a.calls[key]++
+	if errs := a.errs[key]; len(errs) > 0 {
+		a.errs[key] = errs[1:]
+		return "", errs[0]
+	}
+	return key + "-id", nil
+}
+
+// recordSleeps skips backoff waits and records them
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var sleeps []time.Duration
+	original := retrySleep
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { retrySleep = original })
+	return &sleeps
+}
+
+func deployOne(ctx context.Context, applier resourceApplier, policy retryPolicy) ResourceResult {
+	resources := map[string]ResourceConfig{"compute_instance.web": {Type: "compute_instance", Name: "web"}}
+	results := deployBatch(ctx, nil, []string{"compute_instance.web"}, resources, &deploymentOptions{Applier: applier, Retry: policy})
+	return results[0]
+}
+
+func TestDeployBatchRetriesTransientErrors(t *testing.T) {
+	sleeps := recordSleeps(t)
+	applier := &flakyApplier{errs: map[string][]error{"compute_instance.web": {
+		status.Error(codes.Unavailable, "backend unavailable"),
+		errors.New("googleapi: Error 429: Rate Limit Exceeded"),
+	}}}
+
+	result := deployOne(context.Background(), applier, retryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Minute})
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, "compute_instance.web-id", result.ID)
+	assert.Equal(t, 3, result.Attempts)
+	require.Len(t, *sleeps, 2)
+	// Exponential with the upper half jittered
+	assert.GreaterOrEqual(t, (*sleeps)[0], 500*time.Millisecond)
+	assert.LessOrEqual(t, (*sleeps)[0], time.Second)
+	assert.GreaterOrEqual(t, (*sleeps)[1], time.Second)
+	assert.LessOrEqual(t, (*sleeps)[1], 2*time.Second)
+}
+
+func TestDeployBatchDoesNotRetryPermanentErrors(t *testing.T) {
+	sleeps := recordSleeps(t)
+	applier := &flakyApplier{errs: map[string][]error{"compute_instance.web": {
+		status.Error(codes.InvalidArgument, "machine type n9-standard-1 does not exist"),
+	}}}
+
+	result := deployOne(context.Background(), applier, retryPolicy{MaxRetries: 3, BaseDelay: time.Second})
+	assert.Equal(t, "failed", result.Status)
+	assert.Contains(t, result.Error, "does not exist")
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, 1, applier.calls["compute_instance.web"])
+	assert.Empty(t, *sleeps)
+}
+
+func TestDeployBatchTrustsCatalogErrors(t *testing.T) {
+	recordSleeps(t)
+	// The message reads as transient, but the catalog says it isn't
+	permanent := fmt.Errorf("apply: %w", &gcp.Error{Code: "FAILED_PRECONDITION", Message: "service unavailable until the API is enabled"})
+	applier := &flakyApplier{errs: map[string][]error{"compute_instance.web": {permanent}}}
+	result := deployOne(context.Background(), applier, retryPolicy{MaxRetries: 3, BaseDelay: time.Second})
+	assert.Equal(t, "failed", result.Status)
+	assert.Equal(t, 1, result.Attempts)
+
+	// The message doesn't, but the catalog marks it retryable
+	busy := &gcp.Error{Code: "UNAVAILABLE", Message: "backend busy", Retryable: true}
+	applier = &flakyApplier{errs: map[string][]error{"compute_instance.web": {busy}}}
+	result = deployOne(context.Background(), applier, retryPolicy{MaxRetries: 3, BaseDelay: time.Second})
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, 2, result.Attempts)
+}
+
+func TestDeployBatchFailsOnceRetriesAreExhausted(t *testing.T) {
+	recordSleeps(t)
+	unavailable := status.Error(codes.Unavailable, "backend unavailable")
+	applier := &flakyApplier{errs: map[string][]error{"compute_instance.web": {
+		unavailable, unavailable, unavailable, unavailable, unavailable,
+	}}}
+
+	result := deployOne(context.Background(), applier, retryPolicy{MaxRetries: 2, BaseDelay: time.Second})
+	assert.Equal(t, "failed", result.Status)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, 3, applier.calls["compute_instance.web"])
+}
+
+func TestDeployBatchStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	unavailable := status.Error(codes.Unavailable, "backend unavailable")
+	applier := &flakyApplier{errs: map[string][]error{"compute_instance.web": {unavailable, unavailable}}}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	result := deployOne(ctx, applier, retryPolicy{MaxRetries: 5, BaseDelay: time.Hour})
+	assert.Equal(t, "failed", result.Status)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Contains(t, result.Error, "retry abandoned: context canceled")
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	policy := retryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for retry := 0; retry < 70; retry++ {
+		delay := policy.backoff(retry)
+		assert.LessOrEqual(t, delay, 10*time.Second, "retry %d", retry)
+		assert.Positive(t, delay, "retry %d", retry)
+	}
+	assert.Zero(t, retryPolicy{}.backoff(3))
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/depgraph"
+)
+
+// watchOptions paces -watch: a change is acted on once the config has been
+// quiet for Debounce, and never sooner than MinInterval after the last run
+type watchOptions struct {
+	Debounce    time.Duration
+	MinInterval time.Duration
+}
+
+// watchConfig calls onChange after path changes, until ctx is done. Saves
+// arriving in bursts, as editors and sync tools write them, coalesce into a
+// single call. The directory is watched rather than the file, since the
+// file is often replaced instead of written to.
+func watchConfig(ctx context.Context, path string, opts watchOptions, onChange func(context.Context)) error {
+	path = filepath.Clean(path)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	pending := false
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != path || event.Op == fsnotify.Chmod {
+				continue
+			}
+			pending = true
+			timer.Reset(opts.Debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(stdout, "⚠️  Config watch error: %v\n", err)
+		case <-timer.C:
+			if !pending {
+				continue
+			}
+			if wait := opts.MinInterval - time.Since(last); wait > 0 {
+				timer.Reset(wait)
+				continue
+			}
+			pending = false
+			onChange(ctx)
+			last = time.Now()
+		}
+	}
+}
+
+// configDiff lists the resources, by type.name, a reconcile creates,
+// updates and prunes
+type configDiff struct {
+	Created []string `json:"created,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Pruned  []string `json:"pruned,omitempty"`
+}
+
+func (d configDiff) empty() bool {
+	return len(d.Created) == 0 && len(d.Updated) == 0 && len(d.Pruned) == 0
+}
+
+func (d configDiff) String() string {
+	var lines []string
+	for _, key := range d.Created {
+		lines = append(lines, "  + "+key)
+	}
+	for _, key := range d.Updated {
+		lines = append(lines, "  ~ "+key)
+	}
+	for _, key := range d.Pruned {
+		lines = append(lines, "  - "+key)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func diffResources(previous, current []ResourceConfig) configDiff {
+	before := resourcesByKey(previous)
+	after := resourcesByKey(current)
+	var diff configDiff
+	for key, resource := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			diff.Created = append(diff.Created, key)
+		case !reflect.DeepEqual(old, resource):
+			diff.Updated = append(diff.Updated, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			diff.Pruned = append(diff.Pruned, key)
+		}
+	}
+	sort.Strings(diff.Created)
+	sort.Strings(diff.Updated)
+	sort.Strings(diff.Pruned)
+	return diff
+}
+
+func resourcesByKey(resources []ResourceConfig) map[string]ResourceConfig {
+	byKey := make(map[string]ResourceConfig, len(resources))
+	for _, resource := range resources {
+		byKey[resource.Type+"."+resource.Name] = resource
+	}
+	return byKey
+}
+
+// cloneResources deep-copies resources so label reconciliation, which edits
+// resource configs in place, doesn't show up as a change on the next diff
+func cloneResources(resources []ResourceConfig) []ResourceConfig {
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return resources
+	}
+	var clone []ResourceConfig
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return resources
+	}
+	return clone
+}
+
+// resourcePruner deletes resources removed from the config
+type resourcePruner interface {
+	Delete(ctx context.Context, resource ResourceConfig) error
+}
+
+func (simulatedApplier) Delete(context.Context, ResourceConfig) error {
+	return nil
+}
+
+// reconciler brings the deployment in line with the config each time
+// -watch sees it change
+type reconciler struct {
+	// previous holds the resources as last loaded
+	previous []ResourceConfig
+	load     func() (*DeploymentConfig, error)
+	deploy   func(context.Context, *DeploymentConfig) *DeploymentResult
+	pruner   resourcePruner
+	dryRun   bool
+}
+
+// reconcile deploys the created and updated resources and deletes the
+// pruned ones, dependents first. A config that fails to load is skipped so
+// a half-finished edit doesn't tear anything down. Only the resources that
+// were applied or deleted move on to their new config, so the next change
+// retries the ones that failed.
+func (r *reconciler) reconcile(ctx context.Context) (configDiff, *DeploymentResult, error) {
+	config, err := r.load()
+	if err != nil {
+		return configDiff{}, nil, fmt.Errorf("config not reloaded: %w", err)
+	}
+	diff := diffResources(r.previous, config.Resources)
+	if diff.empty() {
+		return diff, nil, nil
+	}
+	fmt.Fprintf(stdout, "🔄 Config changed:\n%s\n", diff)
+
+	previous := resourcesByKey(r.previous)
+	current := cloneResources(config.Resources)
+	reconciled := resourcesByKey(r.previous)
+
+	var result *DeploymentResult
+	changed := make(map[string]bool)
+	for _, key := range append(diff.Created, diff.Updated...) {
+		changed[key] = true
+	}
+	if len(changed) > 0 {
+		apply := *config
+		apply.Resources = nil
+		for _, resource := range config.Resources {
+			if changed[resource.Type+"."+resource.Name] {
+				apply.Resources = append(apply.Resources, resource)
+			}
+		}
+		result = r.deploy(ctx, &apply)
+
+		failed := notApplied(apply.Resources, result)
+		for _, resource := range current {
+			key := resource.Type + "." + resource.Name
+			if changed[key] && !failed[key] {
+				reconciled[key] = resource
+			}
+		}
+	}
+
+	var pruneErrs []error
+	for _, key := range pruneOrder(previous, diff.Pruned) {
+		if r.dryRun {
+			fmt.Fprintf(stdout, "🧪 would delete %s\n", key)
+			delete(reconciled, key)
+			continue
+		}
+		if err := r.pruner.Delete(ctx, previous[key]); err != nil {
+			pruneErrs = append(pruneErrs, fmt.Errorf("failed to delete %s: %w", key, err))
+			continue
+		}
+		delete(reconciled, key)
+		fmt.Fprintf(stdout, "🗑️  deleted %s\n", key)
+	}
+
+	// Keep the order of the config, then of the resources not deleted
+	var next []ResourceConfig
+	for _, resource := range append(current, r.previous...) {
+		key := resource.Type + "." + resource.Name
+		if resource, ok := reconciled[key]; ok {
+			next = append(next, resource)
+			delete(reconciled, key)
+		}
+	}
+	r.previous = next
+	return diff, result, errors.Join(pruneErrs...)
+}
+
+// notApplied returns the keys of the resources a deployment didn't apply:
+// those that failed and, once it stopped on a failure, those it never got to
+func notApplied(resources []ResourceConfig, result *DeploymentResult) map[string]bool {
+	status := make(map[string]string, len(result.Resources))
+	for _, res := range result.Resources {
+		status[res.Type+"."+res.Name] = res.Status
+	}
+	failed := make(map[string]bool)
+	for _, resource := range resources {
+		key := resource.Type + "." + resource.Name
+		s, ok := status[key]
+		if s == "failed" || (!ok && !result.Success) {
+			failed[key] = true
+		}
+	}
+	return failed
+}
+
+// pruneOrder orders the pruned resources so dependents are deleted before
+// what they depend on
+func pruneOrder(previous map[string]ResourceConfig, pruned []string) []string {
+	graph := make(map[string][]string, len(pruned))
+	for _, key := range pruned {
+		graph[key] = previous[key].DependsOn
+	}
+	batches, err := depgraph.Batches(graph)
+	if err != nil {
+		return pruned
+	}
+	var order []string
+	for i := len(batches) - 1; i >= 0; i-- {
+		order = append(order, batches[i]...)
+	}
+	return order
+}
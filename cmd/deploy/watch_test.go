@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startWatch watches a config file in a temp dir and counts the changes
+// reported
+func startWatch(t *testing.T, opts watchOptions) (string, *atomic.Int32) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "deploy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"resources": []}`), 0o644))
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- watchConfig(ctx, path, opts, func(context.Context) { calls.Add(1) })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	// Give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+	return path, &calls
+}
+
+func TestWatchConfigDebouncesEdits(t *testing.T) {
+	path, calls := startWatch(t, watchOptions{Debounce: 100 * time.Millisecond})
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(path, []byte(`{"resources": [{"type": "storage_bucket", "name": "a"}]}`), 0o644))
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"resources": []}`), 0o644))
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatchConfigIgnoresOtherFiles(t *testing.T) {
+	path, calls := startWatch(t, watchOptions{Debounce: 20 * time.Millisecond})
+
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "notes.txt"), []byte("x"), 0o644))
+	time.Sleep(150 * time.Millisecond)
+	assert.Zero(t, calls.Load())
+}
+
+func TestWatchConfigWaitsForMinInterval(t *testing.T) {
+	path, calls := startWatch(t, watchOptions{Debounce: 20 * time.Millisecond, MinInterval: 400 * time.Millisecond})
+	start := time.Now()
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"resources": [{"type": "storage_bucket", "name": "a"}]}`), 0o644))
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	// The watch started less than MinInterval before the edit
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+}
+
+// recordingPruner records the resources it deletes
+type recordingPruner struct {
+	deleted []string
+	err     error
+}
+
+func (p *recordingPruner) Delete(_ context.Context, resource ResourceConfig) error {
+	p.deleted = append(p.deleted, resource.Type+"."+resource.Name)
+	return p.err
+}
+
+func TestReconcileAppliesDiff(t *testing.T) {
+	previous := []ResourceConfig{
+		{Type: "network", Name: "vpc"},
+		{Type: "compute", Name: "web", Config: map[string]interface{}{"machine_type": "e2-small"}, DependsOn: []string{"network.vpc"}},
+		{Type: "dns", Name: "record", DependsOn: []string{"compute.web"}},
+		{Type: "storage", Name: "logs"},
+	}
+	current := &DeploymentConfig{ProjectID: "p", Resources: []ResourceConfig{
+		{Type: "storage", Name: "logs"},
+		{Type: "storage", Name: "assets"},
+		{Type: "network", Name: "vpc", Config: map[string]interface{}{"mtu": float64(1500)}},
+	}}
+
+	var deployed []string
+	pruner := &recordingPruner{}
+	r := &reconciler{
+		previous: previous,
+		load:     func() (*DeploymentConfig, error) { return current, nil },
+		deploy: func(_ context.Context, config *DeploymentConfig) *DeploymentResult {
+			for _, resource := range config.Resources {
+				deployed = append(deployed, resource.Type+"."+resource.Name)
+			}
+			return &DeploymentResult{Success: true}
+		},
+		pruner: pruner,
+	}
+
+	diff, result, err := r.reconcile(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, configDiff{
+		Created: []string{"storage.assets"},
+		Updated: []string{"network.vpc"},
+		Pruned:  []string{"compute.web", "dns.record"},
+	}, diff)
+	assert.Equal(t, []string{"storage.assets", "network.vpc"}, deployed)
+	// Dependents go first
+	assert.Equal(t, []string{"dns.record", "compute.web"}, pruner.deleted)
+	assert.Equal(t, "  + storage.assets\n  ~ network.vpc\n  - compute.web\n  - dns.record", diff.String())
+
+	// Nothing changed since
+	deployed = nil
+	diff, result, err = r.reconcile(context.Background())
+	require.NoError(t, err)
+	assert.True(t, diff.empty())
+	assert.Nil(t, result)
+	assert.Empty(t, deployed)
+}
+
+func TestReconcileKeepsStateWhenConfigDoesNotLoad(t *testing.T) {
+	previous := []ResourceConfig{{Type: "storage", Name: "logs"}}
+	pruner := &recordingPruner{}
+	r := &reconciler{
+		previous: previous,
+		load:     func() (*DeploymentConfig, error) { return nil, errors.New("unexpected end of JSON input") },
+		pruner:   pruner,
+	}
+
+	_, result, err := r.reconcile(context.Background())
+	assert.ErrorContains(t, err, "config not reloaded")
+	assert.Nil(t, result)
+	assert.Empty(t, pruner.deleted)
+	assert.Equal(t, previous, r.previous)
+}
+
+func TestReconcileDryRunDoesNotPrune(t *testing.T) {
+	pruner := &recordingPruner{err: errors.New("should not be called")}
+	r := &reconciler{
+		previous: []ResourceConfig{{Type: "storage", Name: "logs"}},
+		load:     func() (*DeploymentConfig, error) { return &DeploymentConfig{}, nil },
+		pruner:   pruner,
+		dryRun:   true,
+	}
+
+	diff, _, err := r.reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"storage.logs"}, diff.Pruned)
+	assert.Empty(t, pruner.deleted)
+}
+
+func TestReconcileRetriesResourcesThatFailed(t *testing.T) {
+	previous := []ResourceConfig{{Type: "storage", Name: "logs"}, {Type: "storage", Name: "old"}}
+	current := &DeploymentConfig{ProjectID: "p", Resources: []ResourceConfig{
+		{Type: "storage", Name: "logs", Config: map[string]interface{}{"class": "COLDLINE"}},
+		{Type: "storage", Name: "assets"},
+		{Type: "storage", Name: "media"},
+	}}
+
+	var deployed []string
+	pruner := &recordingPruner{err: errors.New("bucket not empty")}
+	r := &reconciler{
+		previous: previous,
+		load:     func() (*DeploymentConfig, error) { return current, nil },
+		deploy: func(_ context.Context, config *DeploymentConfig) *DeploymentResult {
+			result := &DeploymentResult{Success: true}
+			for _, resource := range config.Resources {
+				deployed = append(deployed, resource.Type+"."+resource.Name)
+				status := "success"
+				if resource.Name == "logs" || resource.Name == "assets" {
+					status = "failed"
+					result.Success = false
+				}
+				result.Resources = append(result.Resources, ResourceResult{Type: resource.Type, Name: resource.Name, Status: status})
+			}
+			return result
+		},
+		pruner: pruner,
+	}
+
+	_, _, err := r.reconcile(context.Background())
+	assert.ErrorContains(t, err, "failed to delete storage.old")
+	assert.Equal(t, []ResourceConfig{{Type: "storage", Name: "logs"}, {Type: "storage", Name: "media"}, {Type: "storage", Name: "old"}}, r.previous)
+
+	// The next reconcile retries what failed, though the config is unchanged
+	deployed, pruner.err = nil, nil
+	diff, _, err := r.reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, configDiff{
+		Created: []string{"storage.assets"},
+		Updated: []string{"storage.logs"},
+		Pruned:  []string{"storage.old"},
+	}, diff)
+	assert.Equal(t, []string{"storage.logs", "storage.assets"}, deployed)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// thresholdState is whether one resource metric is over its threshold, and
+// since when it has been back under the exit threshold
+type thresholdState struct {
+	breached   bool
+	clearSince time.Time
+}
+
+// thresholdTracker remembers threshold breaches across monitoring ticks so a
+// metric hovering around its threshold doesn't flip its resource between
+// healthy and unhealthy every tick
+type thresholdTracker struct {
+	mu     sync.Mutex
+	states map[string]*thresholdState
+}
+
+func newThresholdTracker() *thresholdTracker {
+	return &thresholdTracker{states: make(map[string]*thresholdState)}
+}
+
+// breached reports whether value puts the metric named by key over its
+// threshold. A metric enters the breached state above threshold, and leaves
+// it only after staying at or below exit for recovery. An exit of 0 means the
+// threshold itself. Without a tracker, or with neither an exit threshold nor
+// a recovery period, it is a plain comparison.
+func (t *thresholdTracker) breached(key string, value, threshold, exit float64, recovery time.Duration, now time.Time) bool {
+	if exit == 0 || exit > threshold {
+		exit = threshold
+	}
+	if t == nil || (exit == threshold && recovery <= 0) {
+		return value > threshold
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[key]
+	if !ok {
+		state = &thresholdState{}
+		t.states[key] = state
+	}
+
+	if value > threshold {
+		state.breached = true
+		state.clearSince = time.Time{}
+		return true
+	}
+	if !state.breached {
+		return false
+	}
+	if value > exit {
+		state.clearSince = time.Time{}
+		return true
+	}
+	if state.clearSince.IsZero() {
+		state.clearSince = now
+	}
+	if now.Sub(state.clearSince) >= recovery {
+		state.breached = false
+		state.clearSince = time.Time{}
+		return false
+	}
+	return true
+}
+
+// checkThreshold marks status unhealthy when the metric is breaching the
+// resource's threshold for it
+func checkThreshold(tracker *thresholdTracker, resource *ResourceMonitor, status *ResourceStatus, metric string, value float64, now time.Time) {
+	threshold, exists := resource.Thresholds[metric]
+	if !exists {
+		return
+	}
+	exit := resource.ExitThresholds[metric]
+	key := fmt.Sprintf("%s.%s/%s", resource.Type, resource.Name, metric)
+	if !tracker.breached(key, value, threshold, exit, resource.RecoveryPeriod, now) {
+		return
+	}
+
+	status.Status = "unhealthy"
+	if value > threshold {
+		status.Issues = append(status.Issues, fmt.Sprintf("Metric %s (%f) exceeds threshold (%f)", metric, value, threshold))
+	} else {
+		status.Issues = append(status.Issues, fmt.Sprintf("Metric %s (%f) is recovering from exceeding threshold (%f)", metric, value, threshold))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// oscillate returns the statuses of a metric alternating just above and just
+// below a threshold of 80, one tick a minute
+func oscillate(tracker *thresholdTracker, resource *ResourceMonitor, ticks int) []string {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var statuses []string
+	for i := 0; i < ticks; i++ {
+		value := 79.0
+		if i%2 == 0 {
+			value = 81.0
+		}
+		status := ResourceStatus{Status: "healthy"}
+		checkThreshold(tracker, resource, &status, "cpu", value, start.Add(time.Duration(i)*time.Minute))
+		statuses = append(statuses, status.Status)
+	}
+	return statuses
+}
+
+func TestCheckThresholdWithoutHysteresisThrashes(t *testing.T) {
+	resource := &ResourceMonitor{Type: "compute", Name: "vm", Thresholds: map[string]float64{"cpu": 80}}
+
+	statuses := oscillate(newThresholdTracker(), resource, 6)
+
+	assert.Equal(t, []string{"unhealthy", "healthy", "unhealthy", "healthy", "unhealthy", "healthy"}, statuses)
+}
+
+func TestCheckThresholdExitThresholdHoldsBreach(t *testing.T) {
+	resource := &ResourceMonitor{
+		Type:           "compute",
+		Name:           "vm",
+		Thresholds:     map[string]float64{"cpu": 80},
+		ExitThresholds: map[string]float64{"cpu": 70},
+	}
+
+	statuses := oscillate(newThresholdTracker(), resource, 6)
+
+	assert.Equal(t, []string{"unhealthy", "unhealthy", "unhealthy", "unhealthy", "unhealthy", "unhealthy"}, statuses)
+}
+
+func TestCheckThresholdRecoveryPeriod(t *testing.T) {
+	resource := &ResourceMonitor{
+		Type:           "compute",
+		Name:           "vm",
+		Thresholds:     map[string]float64{"cpu": 80},
+		RecoveryPeriod: 2 * time.Minute,
+	}
+	tracker := newThresholdTracker()
+
+	assert.Equal(t, []string{"unhealthy", "unhealthy", "unhealthy", "unhealthy", "unhealthy"}, oscillate(tracker, resource, 5))
+
+	// Staying under the threshold for the recovery period ends the breach
+	start := time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+	var statuses []string
+	for i := 0; i < 4; i++ {
+		status := ResourceStatus{Status: "healthy"}
+		checkThreshold(tracker, resource, &status, "cpu", 60, start.Add(time.Duration(i)*time.Minute))
+		statuses = append(statuses, status.Status)
+	}
+	assert.Equal(t, []string{"unhealthy", "unhealthy", "healthy", "healthy"}, statuses)
+}
+
+func TestThresholdTrackerKeysAreIndependent(t *testing.T) {
+	tracker := newThresholdTracker()
+	now := time.Now()
+
+	assert.True(t, tracker.breached("a/cpu", 90, 80, 70, 0, now))
+	assert.False(t, tracker.breached("b/cpu", 75, 80, 70, 0, now))
+	assert.True(t, tracker.breached("a/cpu", 75, 80, 70, 0, now))
+	assert.False(t, tracker.breached("a/cpu", 65, 80, 70, 0, now))
+}
@@ -5,15 +5,24 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
 )
 
+// stdout receives progress output; emoji and colors are stripped from it
+// when color output is disabled
+var stdout io.Writer = os.Stdout
+
 type MonitorConfig struct {
 	ProjectID       string              `json:"project_id"`
 	Region          string              `json:"region"`
@@ -30,6 +39,12 @@ type ResourceMonitor struct {
 	Thresholds map[string]float64     `json:"thresholds"`
 	Labels     map[string]string      `json:"labels"`
 	Interval   time.Duration          `json:"interval"`
+	// ExitThresholds are the values a metric must drop to, at or below, for
+	// a breach of its threshold to end; they default to the threshold
+	ExitThresholds map[string]float64 `json:"exit_thresholds,omitempty"`
+	// RecoveryPeriod is how long a metric must stay at or below its exit
+	// threshold before its resource is healthy again
+	RecoveryPeriod time.Duration `json:"recovery_period,omitempty"`
 }
 
 type MetricConfig struct {
@@ -38,6 +53,9 @@ type MetricConfig struct {
 	Filter      string            `json:"filter"`
 	Aggregation string            `json:"aggregation"`
 	Labels      map[string]string `json:"labels"`
+	// Unit is the metric's unit, such as "By"; when set it must match the
+	// unit of the queried series
+	Unit string `json:"unit,omitempty"`
 }
 
 type AlertConfig struct {
@@ -55,10 +73,8 @@ type AlertCondition struct {
 	Duration   time.Duration `json:"duration"`
 }
 
-type AlertAction struct {
-	Type   string                 `json:"type"`
-	Config map[string]interface{} `json:"config"`
-}
+// AlertAction is a notification channel an alert is delivered to
+type AlertAction = notify.Channel
 
 type DashboardConfig struct {
 	Name    string                 `json:"name"`
@@ -79,6 +95,10 @@ type MonitorSettings struct {
 	LogLevel        string        `json:"log_level"`
 	WebPort         int           `json:"web_port"`
 	EnableWebUI     bool          `json:"enable_web_ui"`
+	// Parallelism bounds how many resources are monitored at once
+	Parallelism int `json:"parallelism,omitempty"`
+	// Webhook receives every monitoring result that is output
+	Webhook *notify.Webhook `json:"webhook,omitempty"`
 }
 
 type MonitoringResult struct {
@@ -142,9 +162,26 @@ func main() {
 		webPort      = flag.Int("web-port", 8080, "Web UI port")
 		alertsOnly   = flag.Bool("alerts-only", false, "Show only active alerts")
 		filter       = flag.String("filter", "", "Filter resources by type or name")
+		noColor      = flag.Bool("no-color", false, "Disable colored and emoji output (also honors NO_COLOR)")
+		webhookURL   = flag.String("webhook-url", "", "POST monitoring results to this webhook (signed with $"+notify.WebhookSecretEnv+")")
+		webhookTmpl  = flag.String("webhook-template", "", "Template file rendering the webhook payload")
+		parallel     = flag.Int("parallel", 0, "Resources monitored at once (default from config, or 8)")
+		validateCfg  = flag.Bool("validate-config", false, "Validate the -config file against its schema and exit")
+		configSchema = flag.Bool("config-schema", false, "Print the JSON Schema of the config file and exit")
+		configFormat = flag.String("config-format", "auto", "Config file format (auto, json, yaml); auto uses the file extension")
 	)
 	flag.Parse()
 
+	if *configSchema {
+		configschema.Print(os.Stdout, MonitorConfig{})
+		return
+	}
+	if *validateCfg {
+		os.Exit(configschema.ValidateFile(os.Stderr, *configFile, *configFormat, MonitorConfig{}))
+	}
+
+	stdout = termcolor.NewWriter(os.Stdout, termcolor.Enabled(*noColor, os.Stdout))
+
 	if *projectID == "" {
 		*projectID = os.Getenv("GCP_PROJECT_ID")
 		if *projectID == "" {
@@ -162,7 +199,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := json.Unmarshal(configData, &monitorConfig); err != nil {
+		if err := configschema.DecodeFormat(configData, configschema.DetectFormat(*configFile, *configFormat), &monitorConfig); err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing config file: %v\n", err)
 			os.Exit(1)
 		}
@@ -170,6 +207,10 @@ func main() {
 		// Use default configuration
 		monitorConfig = getDefaultConfig(*projectID, *region)
 	}
+	if err := validateMetrics(&monitorConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in monitoring configuration: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Override settings from command line
 	if *interval != 30*time.Second {
@@ -179,6 +220,17 @@ func main() {
 		monitorConfig.Settings.EnableWebUI = true
 		monitorConfig.Settings.WebPort = *webPort
 	}
+	if *parallel > 0 {
+		monitorConfig.Settings.Parallelism = *parallel
+	}
+	if *webhookURL != "" {
+		webhook, err := notify.NewWebhook(*webhookURL, *webhookTmpl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		monitorConfig.Settings.Webhook = webhook
+	}
 
 	// Initialize GCP client
 	ctx := context.Background()
@@ -214,6 +266,7 @@ func main() {
 		defer file.Close()
 		outputFile = file
 	}
+	reportOut := termcolor.NewReportWriter(outputFile, *format, termcolor.Enabled(*noColor, outputFile))
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -223,7 +276,7 @@ func main() {
 	if monitorConfig.Settings.EnableWebUI {
 		go startWebUI(monitorConfig.Settings.WebPort, &monitorConfig)
 		if !*quiet {
-			fmt.Printf("🌐 Web UI started on http://localhost:%d\n", monitorConfig.Settings.WebPort)
+			fmt.Fprintf(stdout, "🌐 Web UI started on http://localhost:%d\n", monitorConfig.Settings.WebPort)
 		}
 	}
 
@@ -232,10 +285,20 @@ func main() {
 	defer ticker.Stop()
 
 	startTime := time.Now()
+	thresholds := newThresholdTracker()
+
+	// Webhook exports run in the background; let them finish before exiting
+	var exports sync.WaitGroup
+	defer exports.Wait()
 
 	for {
 		// Perform monitoring check
-		result, err := performMonitoring(ctx, client, monitoringService, &monitorConfig, *filter)
+		tickStart := time.Now()
+		result, err := performMonitoring(ctx, client, monitoringService, thresholds, &monitorConfig, *filter)
+		if elapsed := time.Since(tickStart); elapsed > monitorConfig.Settings.RefreshInterval && !*quiet {
+			fmt.Fprintf(os.Stderr, "Warning: monitoring took %v, longer than the %v interval; consider raising -parallel or -interval\n",
+				elapsed.Round(time.Millisecond), monitorConfig.Settings.RefreshInterval)
+		}
 		if err != nil {
 			if !*quiet {
 				fmt.Fprintf(os.Stderr, "Monitoring error: %v\n", err)
@@ -243,7 +306,10 @@ func main() {
 		} else {
 			// Output results
 			if !*alertsOnly || len(result.Alerts) > 0 {
-				outputResults(outputFile, result, *format, *verbose, *quiet)
+				outputResults(reportOut, result, *format, *verbose, *quiet)
+				if webhook := monitorConfig.Settings.Webhook; webhook != nil {
+					exportResult(ctx, &exports, webhook, result, os.Stderr)
+				}
 			}
 		}
 
@@ -254,7 +320,7 @@ func main() {
 
 		if *duration > 0 && time.Since(startTime) >= *duration {
 			if !*quiet {
-				fmt.Println("Monitoring duration completed")
+				fmt.Fprintln(stdout, "Monitoring duration completed")
 			}
 			break
 		}
@@ -265,13 +331,31 @@ func main() {
 			continue
 		case sig := <-sigChan:
 			if !*quiet {
-				fmt.Printf("\nReceived signal %v, shutting down gracefully...\n", sig)
+				fmt.Fprintf(stdout, "\nReceived signal %v, shutting down gracefully...\n", sig)
 			}
 			return
 		}
 	}
 }
 
+// webhookExportTimeout bounds a webhook export, retries included
+var webhookExportTimeout = 30 * time.Second
+
+// exportResult posts result to webhook in the background, so a slow endpoint
+// doesn't hold up the next tick, and reports a failed export to errOut.
+// exports tracks the exports still in flight.
+func exportResult(ctx context.Context, exports *sync.WaitGroup, webhook *notify.Webhook, result *MonitoringResult, errOut io.Writer) {
+	exports.Add(1)
+	go func() {
+		defer exports.Done()
+		exportCtx, cancel := context.WithTimeout(ctx, webhookExportTimeout)
+		defer cancel()
+		if err := webhook.Export(exportCtx, result); err != nil {
+			fmt.Fprintf(errOut, "Webhook export failed: %v\n", err)
+		}
+	}()
+}
+
 func getDefaultConfig(projectID, region string) MonitorConfig {
 	return MonitorConfig{
 		ProjectID: projectID,
@@ -308,11 +392,28 @@ func getDefaultConfig(projectID, region string) MonitorConfig {
 			LogLevel:        "info",
 			WebPort:         8080,
 			EnableWebUI:     false,
+			Parallelism:     defaultParallelism,
 		},
 	}
 }
 
-func performMonitoring(ctx context.Context, client *gcp.Client, monitoringService *gcp.MonitoringService, config *MonitorConfig, filter string) (*MonitoringResult, error) {
+// defaultParallelism is how many resources are monitored at once when the
+// config doesn't say
+const defaultParallelism = 8
+
+// resourceCheck monitors one resource
+type resourceCheck func(ctx context.Context, resource *ResourceMonitor) (ResourceStatus, error)
+
+func performMonitoring(ctx context.Context, client *gcp.Client, monitoringService *gcp.MonitoringService, thresholds *thresholdTracker, config *MonitorConfig, filter string) (*MonitoringResult, error) {
+	return monitorResources(ctx, config, filter, func(ctx context.Context, resource *ResourceMonitor) (ResourceStatus, error) {
+		return monitorResource(ctx, monitoringService, thresholds, resource)
+	}), nil
+}
+
+// monitorResources runs check over the resources matching filter on a
+// bounded worker pool and aggregates the statuses in config order, so the
+// result doesn't depend on which check finishes first
+func monitorResources(ctx context.Context, config *MonitorConfig, filter string, check resourceCheck) *MonitoringResult {
 	result := &MonitoringResult{
 		Timestamp: time.Now(),
 		Resources: make(map[string]ResourceStatus),
@@ -320,30 +421,55 @@ func performMonitoring(ctx context.Context, client *gcp.Client, monitoringServic
 		Health:    OverallHealth{},
 	}
 
-	healthyCount := 0
-	totalResources := 0
-	resourceTypes := make(map[string]int)
-	criticalAlerts := 0
-
-	// Monitor each resource
-	for _, resource := range config.Resources {
+	var resources []*ResourceMonitor
+	for i := range config.Resources {
+		resource := &config.Resources[i]
 		// Apply filter if specified
 		if filter != "" && !strings.Contains(resource.Type, filter) && !strings.Contains(resource.Name, filter) {
 			continue
 		}
+		resources = append(resources, resource)
+	}
 
-		totalResources++
-		resourceTypes[resource.Type]++
-
-		status, err := monitorResource(ctx, monitoringService, &resource)
-		if err != nil {
-			status = ResourceStatus{
-				Status:      "error",
-				LastUpdated: time.Now(),
-				Issues:      []string{err.Error()},
-				Details:     make(map[string]interface{}),
+	workers := config.Settings.Parallelism
+	if workers <= 0 {
+		workers = defaultParallelism
+	}
+	statuses := make([]ResourceStatus, len(resources))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(resources); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				status, err := check(ctx, resources[i])
+				if err != nil {
+					status = ResourceStatus{
+						Status:      "error",
+						LastUpdated: time.Now(),
+						Issues:      []string{err.Error()},
+						Details:     make(map[string]interface{}),
+					}
+				}
+				statuses[i] = status
 			}
-		}
+		}()
+	}
+	for i := range resources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	healthyCount := 0
+	totalResources := len(resources)
+	resourceTypes := make(map[string]int)
+	criticalAlerts := 0
+
+	for i, resource := range resources {
+		status := statuses[i]
+		resourceTypes[resource.Type]++
 
 		resourceKey := fmt.Sprintf("%s.%s", resource.Type, resource.Name)
 		result.Resources[resourceKey] = status
@@ -354,7 +480,7 @@ func performMonitoring(ctx context.Context, client *gcp.Client, monitoringServic
 		}
 
 		// Check for alerts
-		alerts := checkResourceAlerts(&resource, &status, config.Alerts)
+		alerts := checkResourceAlerts(resource, &status, config.Alerts)
 		for _, alert := range alerts {
 			if alert.Level == "critical" {
 				criticalAlerts++
@@ -394,10 +520,10 @@ func performMonitoring(ctx context.Context, client *gcp.Client, monitoringServic
 		MetricsSummary:  make(map[string]float64),
 	}
 
-	return result, nil
+	return result
 }
 
-func monitorResource(ctx context.Context, service *gcp.MonitoringService, resource *ResourceMonitor) (ResourceStatus, error) {
+func monitorResource(ctx context.Context, service *gcp.MonitoringService, thresholds *thresholdTracker, resource *ResourceMonitor) (ResourceStatus, error) {
 	status := ResourceStatus{
 		Status:      "healthy",
 		Metrics:     make(map[string]float64),
@@ -422,19 +548,32 @@ func monitorResource(ctx context.Context, service *gcp.MonitoringService, resour
 			continue
 		}
 
-		// Extract metric value (simplified)
-		if len(result.TimeSeries) > 0 && len(result.TimeSeries[0].Points) > 0 {
-			value := result.TimeSeries[0].Points[0].Value.DoubleValue
-			status.Metrics[metric.Name] = value
+		if len(result) == 0 {
+			continue
+		}
+		series := result[0]
+		if metric.Unit != "" && series.GetUnit() != "" && series.GetUnit() != metric.Unit {
+			status.Issues = append(status.Issues, fmt.Sprintf("Metric %s has unit %q, but %q is configured", metric.Name, series.GetUnit(), metric.Unit))
+			continue
+		}
 
-			// Check thresholds
-			if threshold, exists := resource.Thresholds[metric.Name]; exists {
-				if value > threshold {
-					status.Status = "unhealthy"
-					status.Issues = append(status.Issues, fmt.Sprintf("Metric %s (%f) exceeds threshold (%f)", metric.Name, value, threshold))
-				}
+		// Gauges report their latest value, counters their per-second rate
+		value, err := metricValue(metric, seriesPoints(series))
+		if err != nil {
+			status.Issues = append(status.Issues, fmt.Sprintf("Failed to evaluate metric %s: %v", metric.Name, err))
+			continue
+		}
+		status.Metrics[metric.Name] = value
+		if isRateMetric(metric) {
+			unit := metric.Unit
+			if unit == "" {
+				unit = series.GetUnit()
 			}
+			status.Details[metric.Name+"_unit"] = rateUnit(unit)
 		}
+
+		// Check thresholds
+		checkThreshold(thresholds, resource, &status, metric.Name, value, time.Now())
 	}
 
 	return status, nil
@@ -494,7 +633,7 @@ func checkResourceAlerts(resource *ResourceMonitor, status *ResourceStatus, aler
 	return alerts
 }
 
-func outputResults(file *os.File, result *MonitoringResult, format string, verbose, quiet bool) {
+func outputResults(file io.Writer, result *MonitoringResult, format string, verbose, quiet bool) {
 	switch format {
 	case "json":
 		output, _ := json.MarshalIndent(result, "", "  ")
@@ -506,7 +645,7 @@ func outputResults(file *os.File, result *MonitoringResult, format string, verbo
 	}
 }
 
-func printTextResults(file *os.File, result *MonitoringResult, verbose, quiet bool) {
+func printTextResults(file io.Writer, result *MonitoringResult, verbose, quiet bool) {
 	if quiet && len(result.Alerts) == 0 {
 		return
 	}
@@ -563,7 +702,7 @@ func printTextResults(file *os.File, result *MonitoringResult, verbose, quiet bo
 	fmt.Fprintln(file)
 }
 
-func printTableResults(file *os.File, result *MonitoringResult, verbose bool) {
+func printTableResults(file io.Writer, result *MonitoringResult, verbose bool) {
 	// Simple table output implementation
 	fmt.Fprintf(file, "%-20s %-10s %-15s %-50s\n", "Resource", "Status", "Alerts", "Issues")
 	fmt.Fprintf(file, "%s\n", strings.Repeat("-", 95))
@@ -593,7 +732,7 @@ func startWebUI(port int, config *MonitorConfig) {
 	// Placeholder for web UI implementation
 	// In a real implementation, this would start an HTTP server
 	// serving a dashboard with real-time monitoring data
-	fmt.Printf("Web UI would start on port %d\n", port)
+	fmt.Fprintf(stdout, "Web UI would start on port %d\n", port)
 }
 
 func getLogLevel(verbose, quiet bool) string {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+)
+
+// Metric kinds, matching Cloud Monitoring's MetricKind. Gauges are
+// thresholded on their latest value; delta and cumulative counters on their
+// per-second rate.
+const (
+	metricGauge      = "gauge"
+	metricDelta      = "delta"
+	metricCumulative = "cumulative"
+)
+
+// metricPoint is one sample of a metric. Delta points cover Start to End;
+// cumulative points count from Start, the counter's reset time.
+type metricPoint struct {
+	Start time.Time
+	End   time.Time
+	Value float64
+}
+
+// metricKind normalizes a MetricConfig type, defaulting to a gauge
+func metricKind(metric MetricConfig) string {
+	kind := strings.ToLower(metric.Type)
+	if kind == "" {
+		return metricGauge
+	}
+	return kind
+}
+
+// isRateMetric reports whether the metric is thresholded on its rate
+func isRateMetric(metric MetricConfig) bool {
+	kind := metricKind(metric)
+	return kind == metricDelta || kind == metricCumulative
+}
+
+// rateUnit is the unit of a rate metric's computed value
+func rateUnit(unit string) string {
+	if unit == "" || unit == "1" {
+		return "1/s"
+	}
+	return unit + "/s"
+}
+
+// validateMetrics checks the metric kinds and units of a monitoring config
+func validateMetrics(config *MonitorConfig) error {
+	for _, resource := range config.Resources {
+		for _, metric := range resource.Metrics {
+			switch metricKind(metric) {
+			case metricGauge, metricDelta, metricCumulative:
+			default:
+				return fmt.Errorf("resource %s.%s: metric %s has unknown type %q (want gauge, delta or cumulative)",
+					resource.Type, resource.Name, metric.Name, metric.Type)
+			}
+			if isRateMetric(metric) && strings.HasSuffix(metric.Unit, "/s") {
+				return fmt.Errorf("resource %s.%s: metric %s is a %s counter, so its unit %q must not already be a rate",
+					resource.Type, resource.Name, metric.Name, metricKind(metric), metric.Unit)
+			}
+		}
+	}
+	return nil
+}
+
+// metricValue returns the value to threshold for points: the latest value of
+// a gauge, or the per-second rate of a counter over its latest interval
+func metricValue(metric MetricConfig, points []metricPoint) (float64, error) {
+	if len(points) == 0 {
+		return 0, fmt.Errorf("no points")
+	}
+	sorted := append([]metricPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].End.Before(sorted[j].End) })
+	latest := sorted[len(sorted)-1]
+
+	switch metricKind(metric) {
+	case metricDelta:
+		seconds := latest.End.Sub(latest.Start).Seconds()
+		if seconds <= 0 && len(sorted) > 1 {
+			seconds = latest.End.Sub(sorted[len(sorted)-2].End).Seconds()
+		}
+		if seconds <= 0 {
+			return 0, fmt.Errorf("delta point has no interval")
+		}
+		return latest.Value / seconds, nil
+	case metricCumulative:
+		if len(sorted) < 2 {
+			return 0, fmt.Errorf("a rate needs two cumulative points")
+		}
+		previous := sorted[len(sorted)-2]
+		seconds := latest.End.Sub(previous.End).Seconds()
+		if seconds <= 0 {
+			return 0, fmt.Errorf("cumulative points share an end time")
+		}
+		delta := latest.Value - previous.Value
+		if delta < 0 || latest.Start.After(previous.End) {
+			// The counter reset between the points, so it counted up from
+			// zero since its start time
+			since := latest.End.Sub(latest.Start).Seconds()
+			if latest.Start.IsZero() || since <= 0 {
+				return 0, fmt.Errorf("counter reset without a start time")
+			}
+			return latest.Value / since, nil
+		}
+		return delta / seconds, nil
+	default:
+		return latest.Value, nil
+	}
+}
+
+// seriesPoints converts a Cloud Monitoring time series to metric points
+func seriesPoints(series *monitoringpb.TimeSeries) []metricPoint {
+	points := make([]metricPoint, 0, len(series.GetPoints()))
+	for _, point := range series.GetPoints() {
+		p := metricPoint{
+			Start: point.GetInterval().GetStartTime().AsTime(),
+			End:   point.GetInterval().GetEndTime().AsTime(),
+		}
+		if point.GetInterval().GetStartTime() == nil {
+			p.Start = time.Time{}
+		}
+		switch value := point.GetValue().GetValue().(type) {
+		case *monitoringpb.TypedValue_DoubleValue:
+			p.Value = value.DoubleValue
+		case *monitoringpb.TypedValue_Int64Value:
+			p.Value = float64(value.Int64Value)
+		default:
+			continue
+		}
+		points = append(points, p)
+	}
+	return points
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var counterStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// cumulativeSeries is a request counter sampled every minute, newest first as
+// Cloud Monitoring returns it
+func cumulativeSeries(values ...int64) *monitoringpb.TimeSeries {
+	series := &monitoringpb.TimeSeries{Unit: "{request}"}
+	for i := len(values) - 1; i >= 0; i-- {
+		series.Points = append(series.Points, &monitoringpb.Point{
+			Interval: &monitoringpb.TimeInterval{
+				StartTime: timestamppb.New(counterStart),
+				EndTime:   timestamppb.New(counterStart.Add(time.Duration(i+1) * time.Minute)),
+			},
+			Value: &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: values[i]}},
+		})
+	}
+	return series
+}
+
+func TestMetricValueCumulativeRate(t *testing.T) {
+	metric := MetricConfig{Name: "requests", Type: "CUMULATIVE"}
+
+	// 600 requests in the last minute
+	rate, err := metricValue(metric, seriesPoints(cumulativeSeries(1200, 1800, 2400)))
+	require.NoError(t, err)
+	assert.InDelta(t, 10.0, rate, 1e-9)
+
+	rate, err = metricValue(metric, seriesPoints(cumulativeSeries(0, 60, 3060)))
+	require.NoError(t, err)
+	assert.InDelta(t, 50.0, rate, 1e-9)
+
+	_, err = metricValue(metric, seriesPoints(cumulativeSeries(100)))
+	assert.Error(t, err)
+}
+
+func TestMetricValueCumulativeReset(t *testing.T) {
+	metric := MetricConfig{Name: "requests", Type: "cumulative"}
+	reset := counterStart.Add(150 * time.Second)
+	points := []metricPoint{
+		{Start: counterStart, End: counterStart.Add(2 * time.Minute), Value: 5000},
+		{Start: reset, End: reset.Add(30 * time.Second), Value: 90},
+	}
+
+	rate, err := metricValue(metric, points)
+	require.NoError(t, err)
+	assert.InDelta(t, 3.0, rate, 1e-9)
+}
+
+func TestMetricValueDeltaAndGauge(t *testing.T) {
+	points := []metricPoint{
+		{Start: counterStart, End: counterStart.Add(time.Minute), Value: 120},
+		{Start: counterStart.Add(time.Minute), End: counterStart.Add(2 * time.Minute), Value: 300},
+	}
+
+	rate, err := metricValue(MetricConfig{Type: "delta"}, points)
+	require.NoError(t, err)
+	assert.InDelta(t, 5.0, rate, 1e-9)
+
+	value, err := metricValue(MetricConfig{Type: "gauge"}, points)
+	require.NoError(t, err)
+	assert.Equal(t, 300.0, value)
+}
+
+func TestValidateMetrics(t *testing.T) {
+	config := &MonitorConfig{Resources: []ResourceMonitor{{
+		Type:    "lb",
+		Name:    "frontend",
+		Metrics: []MetricConfig{{Name: "bytes", Type: "cumulative", Unit: "By"}, {Name: "cpu"}},
+	}}}
+	require.NoError(t, validateMetrics(config))
+	assert.Equal(t, "By/s", rateUnit("By"))
+	assert.Equal(t, "1/s", rateUnit(""))
+
+	config.Resources[0].Metrics[0].Unit = "By/s"
+	assert.ErrorContains(t, validateMetrics(config), "must not already be a rate")
+
+	config.Resources[0].Metrics[0] = MetricConfig{Name: "bytes", Type: "distribution"}
+	assert.ErrorContains(t, validateMetrics(config), "unknown type")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/notify"
+)
+
+func manyResources(n int) *MonitorConfig {
+	config := &MonitorConfig{
+		Alerts: []AlertConfig{{
+			Name:       "high-cpu",
+			Enabled:    true,
+			Conditions: []AlertCondition{{Metric: "cpu", Comparison: "greater_than", Threshold: 50}},
+		}},
+		Settings: MonitorSettings{Parallelism: 4},
+	}
+	for i := 0; i < n; i++ {
+		config.Resources = append(config.Resources, ResourceMonitor{Type: "compute", Name: fmt.Sprintf("vm-%03d", i)})
+	}
+	return config
+}
+
+// fakeCheck reports every third resource unhealthy and over the alert
+// threshold, fails every tenth, and finishes in random order
+func fakeCheck(inFlight, peak *int32) resourceCheck {
+	return func(_ context.Context, resource *ResourceMonitor) (ResourceStatus, error) {
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+
+		var i int
+		fmt.Sscanf(resource.Name, "vm-%d", &i)
+		if i%10 == 0 {
+			return ResourceStatus{}, errors.New("metrics unavailable")
+		}
+		status := ResourceStatus{Status: "healthy", Metrics: map[string]float64{"cpu": 10}}
+		if i%3 == 0 {
+			status.Status = "unhealthy"
+			status.Metrics["cpu"] = 90
+		}
+		return status, nil
+	}
+}
+
+func TestMonitorResourcesParallelIsComplete(t *testing.T) {
+	config := manyResources(200)
+	var inFlight, peak int32
+
+	result := monitorResources(context.Background(), config, "", fakeCheck(&inFlight, &peak))
+
+	require.Len(t, result.Resources, 200)
+	assert.LessOrEqual(t, peak, int32(4))
+	assert.Greater(t, peak, int32(1))
+
+	// 0, 10, ... 190 fail; of the rest, multiples of 3 are unhealthy
+	failed, unhealthy := 0, 0
+	for i := 0; i < 200; i++ {
+		switch {
+		case i%10 == 0:
+			failed++
+		case i%3 == 0:
+			unhealthy++
+		}
+	}
+	assert.Equal(t, 200, result.Summary.TotalResources)
+	assert.Equal(t, 200-failed-unhealthy, result.Summary.HealthyCount)
+	assert.Equal(t, "error", result.Resources["compute.vm-010"].Status)
+	assert.Equal(t, "unhealthy", result.Resources["compute.vm-003"].Status)
+	require.Len(t, result.Alerts, unhealthy)
+	assert.Equal(t, unhealthy, result.Summary.CriticalAlerts)
+}
+
+func TestMonitorResourcesOrderIndependent(t *testing.T) {
+	var inFlight, peak int32
+	first := monitorResources(context.Background(), manyResources(100), "", fakeCheck(&inFlight, &peak))
+	second := monitorResources(context.Background(), manyResources(100), "", fakeCheck(&inFlight, &peak))
+
+	assert.Equal(t, first.Summary, second.Summary)
+	require.Len(t, second.Alerts, len(first.Alerts))
+	for i := range first.Alerts {
+		assert.Equal(t, first.Alerts[i].Resource, second.Alerts[i].Resource)
+	}
+	assert.Equal(t, "compute.vm-003", first.Alerts[0].Resource)
+}
+
+func TestMonitorResourcesFilter(t *testing.T) {
+	config := manyResources(20)
+	var inFlight, peak int32
+
+	result := monitorResources(context.Background(), config, "vm-01", fakeCheck(&inFlight, &peak))
+
+	assert.Len(t, result.Resources, 10)
+	assert.Equal(t, 10, result.Summary.TotalResources)
+}
+
+func TestExportResultDoesNotBlockOnASlowWebhook(t *testing.T) {
+	original := webhookExportTimeout
+	webhookExportTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { webhookExportTimeout = original })
+
+	// The endpoint doesn't answer until the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var exports sync.WaitGroup
+	var errOut bytes.Buffer
+	start := time.Now()
+	exportResult(context.Background(), &exports, &notify.Webhook{URL: server.URL}, &MonitoringResult{}, &errOut)
+	assert.Less(t, time.Since(start), webhookExportTimeout, "export blocked the tick")
+
+	exports.Wait()
+	assert.Less(t, time.Since(start), 5*time.Second, "export outlived its timeout")
+	assert.Contains(t, errOut.String(), "Webhook export failed")
+	assert.Contains(t, errOut.String(), context.DeadlineExceeded.Error())
+}
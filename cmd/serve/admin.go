@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// authTokenEnv overrides the bearer token in the config, keeping it out of
+// config files
+const authTokenEnv = "SERVE_AUTH_TOKEN"
+
+// RuntimeMetrics is the Go runtime state served on the admin listener
+type RuntimeMetrics struct {
+	Goroutines   int           `json:"goroutines"`
+	GOMAXPROCS   int           `json:"gomaxprocs"`
+	HeapAlloc    uint64        `json:"heap_alloc_bytes"`
+	HeapInuse    uint64        `json:"heap_inuse_bytes"`
+	HeapObjects  uint64        `json:"heap_objects"`
+	Sys          uint64        `json:"sys_bytes"`
+	NumGC        uint32        `json:"num_gc"`
+	LastGCPause  time.Duration `json:"last_gc_pause"`
+	TotalGCPause time.Duration `json:"total_gc_pause"`
+}
+
+// authMiddleware requires a bearer token matching the configured one when
+// auth is enabled. An empty token rejects every request rather than
+// allowing them all.
+func (s *APIServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.EnableAuth {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if method := strings.ToLower(s.config.AuthMethod); method != "" && method != "bearer" {
+			s.writeError(w, http.StatusNotImplemented, "Unsupported auth method: "+s.config.AuthMethod)
+			return
+		}
+
+		token := os.Getenv(authTokenEnv)
+		if token == "" {
+			token = s.config.AuthToken
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="terragrunt-gcp-api"`)
+			s.writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminHandler serves pprof and Go runtime metrics behind authMiddleware. It
+// is only mounted on the admin listener, never on the public API mux.
+func (s *APIServer) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/runtime", s.handleRuntimeMetrics)
+	return s.authMiddleware(mux)
+}
+
+func (s *APIServer) handleRuntimeMetrics(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	metrics := RuntimeMetrics{
+		Goroutines:   runtime.NumGoroutine(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		TotalGCPause: time.Duration(mem.PauseTotalNs),
+	}
+	if mem.NumGC > 0 {
+		metrics.LastGCPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+	s.writeJSON(w, http.StatusOK, metrics)
+}
+
+// newAdminServer returns the admin listener's server on addr, or nil when
+// profiling is disabled. It has no write timeout since CPU profiles and
+// traces stream for as long as asked. Without auth the endpoints are open
+// to anyone who can reach them, so addr must then be a loopback address.
+func (s *APIServer) newAdminServer(enabled bool, addr string) (*http.Server, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if !s.config.EnableAuth && !isLoopbackAddr(addr) {
+		return nil, fmt.Errorf("admin address %s is not a loopback address; enable auth to serve pprof on it", addr)
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s.adminHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}, nil
+}
+
+// isLoopbackAddr reports whether the host of addr only listens on the
+// loopback interface; an empty host listens on all of them
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
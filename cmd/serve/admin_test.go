@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIServer(config *ServerConfig) *APIServer {
+	return &APIServer{
+		config:    config,
+		services:  &ServiceContainer{},
+		startTime: time.Now(),
+		metrics: &ServerMetrics{
+			RequestCount: make(map[string]int64),
+			ErrorCount:   make(map[string]int64),
+		},
+		requests: &inFlight{},
+	}
+}
+
+func get(t *testing.T, url, token string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestPprofOnlyOnAdminListener(t *testing.T) {
+	s := newTestAPIServer(&ServerConfig{EnableHealth: true, HealthPath: "/health"})
+
+	mux := http.NewServeMux()
+	s.setupRoutes(mux)
+	public := httptest.NewServer(mux)
+	defer public.Close()
+
+	adminServer, err := s.newAdminServer(false, "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.Nil(t, adminServer, "admin server created with profiling disabled")
+	adminServer, err = s.newAdminServer(true, "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NotNil(t, adminServer)
+	admin := httptest.NewServer(adminServer.Handler)
+	defer admin.Close()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/runtime"} {
+		assert.Equal(t, http.StatusNotFound, get(t, public.URL+path, "").StatusCode, "public %s", path)
+		assert.Equal(t, http.StatusOK, get(t, admin.URL+path, "").StatusCode, "admin %s", path)
+	}
+
+	var body struct {
+		Data RuntimeMetrics `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(get(t, admin.URL+"/debug/runtime", "").Body).Decode(&body))
+	assert.Greater(t, body.Data.Goroutines, 0)
+	assert.Greater(t, body.Data.HeapAlloc, uint64(0))
+}
+
+func TestAdminRequiresAuthWhenEnabled(t *testing.T) {
+	s := newTestAPIServer(&ServerConfig{EnableAuth: true, AuthMethod: "bearer", AuthToken: "s3cret"})
+	adminServer, err := s.newAdminServer(true, "127.0.0.1:0")
+	require.NoError(t, err)
+	admin := httptest.NewServer(adminServer.Handler)
+	defer admin.Close()
+
+	resp := get(t, admin.URL+"/debug/pprof/", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, get(t, admin.URL+"/debug/pprof/", "wrong").StatusCode)
+	assert.Equal(t, http.StatusOK, get(t, admin.URL+"/debug/pprof/", "s3cret").StatusCode)
+
+	t.Setenv(authTokenEnv, "rotated")
+	assert.Equal(t, http.StatusUnauthorized, get(t, admin.URL+"/debug/pprof/", "s3cret").StatusCode)
+	assert.Equal(t, http.StatusOK, get(t, admin.URL+"/debug/runtime", "rotated").StatusCode)
+
+	s.config.AuthToken = ""
+	t.Setenv(authTokenEnv, "")
+	assert.Equal(t, http.StatusUnauthorized, get(t, admin.URL+"/debug/runtime", "").StatusCode)
+}
+
+func TestAdminRefusesNonLoopbackAddressWithoutAuth(t *testing.T) {
+	s := newTestAPIServer(&ServerConfig{})
+	for _, addr := range []string{":6060", "0.0.0.0:6060", "10.0.0.5:6060", "[::]:6060"} {
+		_, err := s.newAdminServer(true, addr)
+		assert.ErrorContains(t, err, "not a loopback address", addr)
+	}
+	for _, addr := range []string{"127.0.0.1:6060", "localhost:6060", "[::1]:6060"} {
+		_, err := s.newAdminServer(true, addr)
+		assert.NoError(t, err, addr)
+	}
+
+	s = newTestAPIServer(&ServerConfig{EnableAuth: true, AuthToken: "s3cret"})
+	adminServer, err := s.newAdminServer(true, "0.0.0.0:6060")
+	require.NoError(t, err)
+	assert.NotNil(t, adminServer)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/depgraph"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+)
+
+// maxBatchOperations bounds the operations of one batch request
+const maxBatchOperations = 100
+
+// batchActions are the actions a batch operation can take
+var batchActions = map[string]bool{"create": true, "update": true, "delete": true}
+
+// BatchRequest is the body of POST /api/v1/batch
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+	// DryRun validates and orders the operations without executing them
+	DryRun bool `json:"dry_run"`
+}
+
+// BatchOperation is one resource change in a batch
+type BatchOperation struct {
+	// ID names the operation for depends_on; it defaults to type.name
+	ID        string                 `json:"id,omitempty"`
+	Type      string                 `json:"type"`
+	Name      string                 `json:"name"`
+	Action    string                 `json:"action"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+	DependsOn []string               `json:"depends_on,omitempty"`
+}
+
+// BatchOperationResult is the outcome of one operation: succeeded, failed,
+// skipped because a dependency failed, or dry-run. Failures carry the error's
+// catalog code.
+type BatchOperationResult struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Name     string                 `json:"name"`
+	Action   string                 `json:"action"`
+	Status   string                 `json:"status"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Code     string                 `json:"code,omitempty"`
+	Duration time.Duration          `json:"duration"`
+}
+
+// BatchResponse reports every operation of a batch in execution order
+type BatchResponse struct {
+	Success bool                   `json:"success"`
+	DryRun  bool                   `json:"dry_run"`
+	Results []BatchOperationResult `json:"results"`
+	Summary map[string]int         `json:"summary"`
+}
+
+// operationRunner executes one batch operation
+type operationRunner func(ctx context.Context, op BatchOperation) (map[string]interface{}, error)
+
+// handleBatch runs the operations of a batch in dependency order. Operations
+// in the same dependency batch are independent, so one failing only skips
+// the operations that depend on it.
+func (s *APIServer) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req BatchRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid batch request: %v", err))
+		return
+	}
+
+	plan, err := planBatch(req.Operations)
+	if err != nil {
+		s.writeServiceError(w, gcp.NewValidationError("operations", err.Error()))
+		return
+	}
+
+	run := s.runOperation
+	if s.operationRunner != nil {
+		run = s.operationRunner
+	}
+	response := executeBatch(r.Context(), req, plan, run)
+
+	status := http.StatusOK
+	if !response.Success {
+		status = http.StatusMultiStatus
+	}
+	s.writeJSON(w, status, response)
+}
+
+// planBatch validates the operations, defaults their IDs and orders them
+func planBatch(ops []BatchOperation) ([][]string, error) {
+	if len(ops) == 0 {
+		return nil, errors.New("batch has no operations")
+	}
+	if len(ops) > maxBatchOperations {
+		return nil, fmt.Errorf("batch has %d operations, more than the limit of %d", len(ops), maxBatchOperations)
+	}
+
+	graph := make(map[string][]string, len(ops))
+	for i := range ops {
+		op := &ops[i]
+		if op.Type == "" || op.Name == "" {
+			return nil, fmt.Errorf("operation %d: type and name are required", i)
+		}
+		if !batchActions[op.Action] {
+			return nil, fmt.Errorf("operation %d: unsupported action %q (expected create, update or delete)", i, op.Action)
+		}
+		if op.ID == "" {
+			op.ID = op.Type + "." + op.Name
+		}
+		if _, exists := graph[op.ID]; exists {
+			return nil, fmt.Errorf("operation %d: duplicate id %q", i, op.ID)
+		}
+		graph[op.ID] = op.DependsOn
+	}
+	for _, op := range ops {
+		for _, dep := range op.DependsOn {
+			if _, ok := graph[dep]; !ok {
+				return nil, fmt.Errorf("operation %q depends on unknown operation %q", op.ID, dep)
+			}
+		}
+	}
+
+	return depgraph.Batches(graph)
+}
+
+// executeBatch runs planned operations batch by batch
+func executeBatch(ctx context.Context, req BatchRequest, plan [][]string, run operationRunner) *BatchResponse {
+	byID := make(map[string]BatchOperation, len(req.Operations))
+	for _, op := range req.Operations {
+		byID[op.ID] = op
+	}
+
+	response := &BatchResponse{Success: true, DryRun: req.DryRun, Summary: make(map[string]int)}
+	failed := make(map[string]bool)
+	for _, batch := range plan {
+		for _, id := range batch {
+			op := byID[id]
+			result := BatchOperationResult{ID: op.ID, Type: op.Type, Name: op.Name, Action: op.Action}
+
+			blocked := ""
+			for _, dep := range op.DependsOn {
+				if failed[dep] {
+					blocked = dep
+					break
+				}
+			}
+
+			start := time.Now()
+			switch {
+			case blocked != "":
+				result.Status = "skipped"
+				result.Error = fmt.Sprintf("dependency %s did not succeed", blocked)
+			case ctx.Err() != nil:
+				result.Status = "skipped"
+				result.Error = ctx.Err().Error()
+			case req.DryRun:
+				result.Status = "dry-run"
+				result.Details = map[string]interface{}{"would": op.Action}
+			default:
+				details, err := run(ctx, op)
+				result.Details = details
+				result.Status = "succeeded"
+				if err != nil {
+					result.Status = "failed"
+					result.Error = err.Error()
+					result.Code = gcp.AsError(err).Code
+				}
+			}
+			result.Duration = time.Since(start)
+
+			if result.Status == "failed" || result.Status == "skipped" {
+				failed[op.ID] = true
+				response.Success = false
+			}
+			response.Summary[result.Status]++
+			response.Results = append(response.Results, result)
+		}
+	}
+	return response
+}
+
+// runOperation executes an operation against the resource types the API
+// serves
+func (s *APIServer) runOperation(ctx context.Context, op BatchOperation) (map[string]interface{}, error) {
+	var available bool
+	switch op.Type {
+	case "compute.instance":
+		available = s.services.Compute != nil
+	case "storage.bucket":
+		available = s.services.Storage != nil
+	case "network.network", "network.subnet", "network.firewall":
+		available = s.services.Network != nil
+	case "iam.service_account":
+		available = s.services.IAM != nil
+	case "secrets.secret":
+		available = s.services.Secrets != nil
+	default:
+		return nil, gcp.NewValidationError("type", fmt.Sprintf("unsupported resource type %q", op.Type))
+	}
+	if !available {
+		return nil, gcp.WrapError(nil, gcp.ErrorCodeUnavailable, fmt.Sprintf("service for %s is not available", op.Type))
+	}
+
+	return map[string]interface{}{
+		"id":         fmt.Sprintf("%s-%s", op.Type, op.Name),
+		"action":     op.Action,
+		"updated_at": time.Now().Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func postBatch(t *testing.T, server *APIServer, body string) (*http.Response, BatchResponse) {
+	mux := http.NewServeMux()
+	server.setupRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/api/v1/batch", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data BatchResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&envelope))
+	return resp, envelope.Data
+}
+
+const batchBody = `{"operations": [
+	{"type": "compute.instance", "name": "web", "action": "create", "depends_on": ["network.network.vpc"]},
+	{"type": "network.network", "name": "vpc", "action": "create"},
+	{"type": "storage.bucket", "name": "logs", "action": "create"},
+	{"type": "secrets.secret", "name": "token", "action": "create", "depends_on": ["storage.bucket.logs"]}
+]}`
+
+func TestBatchRunsDependenciesFirstAndReportsPartialFailure(t *testing.T) {
+	server := newTestAPIServer(&ServerConfig{})
+	var mu sync.Mutex
+	var order []string
+	server.operationRunner = func(ctx context.Context, op BatchOperation) (map[string]interface{}, error) {
+		mu.Lock()
+		order = append(order, op.ID)
+		mu.Unlock()
+		if op.ID == "storage.bucket.logs" {
+			return nil, &googleapi.Error{Code: http.StatusTooManyRequests, Message: "slow down"}
+		}
+		return map[string]interface{}{"id": op.ID}, nil
+	}
+
+	resp, batch := postBatch(t, server, batchBody)
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.False(t, batch.Success)
+	assert.Equal(t, []string{"network.network.vpc", "storage.bucket.logs", "compute.instance.web"}, order)
+
+	require.Len(t, batch.Results, 4)
+	statuses := make(map[string]string)
+	for _, result := range batch.Results {
+		statuses[result.ID] = result.Status
+		if result.ID == "storage.bucket.logs" {
+			assert.Equal(t, "RATE_LIMITED", result.Code)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"network.network.vpc":  "succeeded",
+		"storage.bucket.logs":  "failed",
+		"compute.instance.web": "succeeded",
+		"secrets.secret.token": "skipped",
+	}, statuses)
+	assert.Equal(t, map[string]int{"succeeded": 2, "failed": 1, "skipped": 1}, batch.Summary)
+}
+
+func TestBatchDryRunDoesNotExecute(t *testing.T) {
+	server := newTestAPIServer(&ServerConfig{})
+	server.operationRunner = func(ctx context.Context, op BatchOperation) (map[string]interface{}, error) {
+		t.Errorf("dry run executed %s", op.ID)
+		return nil, nil
+	}
+
+	body := strings.Replace(batchBody, `{"operations"`, `{"dry_run": true, "operations"`, 1)
+	resp, batch := postBatch(t, server, body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, batch.DryRun)
+	assert.Equal(t, map[string]int{"dry-run": 4}, batch.Summary)
+}
+
+func TestBatchRejectsInvalidPlans(t *testing.T) {
+	server := newTestAPIServer(&ServerConfig{})
+	for name, body := range map[string]string{
+		"empty":      `{"operations": []}`,
+		"action":     `{"operations": [{"type": "storage.bucket", "name": "a", "action": "explode"}]}`,
+		"unknown":    `{"operations": [{"type": "storage.bucket", "name": "a", "action": "create", "depends_on": ["b"]}]}`,
+		"duplicate":  `{"operations": [{"id": "a", "type": "storage.bucket", "name": "a", "action": "create"}, {"id": "a", "type": "storage.bucket", "name": "b", "action": "create"}]}`,
+		"cycle":      `{"operations": [{"id": "a", "type": "storage.bucket", "name": "a", "action": "create", "depends_on": ["b"]}, {"id": "b", "type": "storage.bucket", "name": "b", "action": "create", "depends_on": ["a"]}]}`,
+		"unknownKey": `{"operations": [], "dryrun": true}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, _ := postBatch(t, server, body)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// shutdownTimeout is how long in-flight requests get to finish on shutdown
+	shutdownTimeout = 30 * time.Second
+	// shutdownGrace is how long requests still running after shutdownTimeout
+	// get to return once their contexts are cancelled
+	shutdownGrace = 5 * time.Second
+)
+
+// inFlight counts the requests being served so shutdown can wait for them
+// and report how many drained and how many were cut off
+type inFlight struct {
+	active int64
+}
+
+func (f *inFlight) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&f.active, 1)
+		defer atomic.AddInt64(&f.active, -1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *inFlight) count() int64 {
+	return atomic.LoadInt64(&f.active)
+}
+
+// drainServer shuts server down, giving in-flight requests timeout to finish.
+// Request contexts derive from the server's base context, so requests still
+// running after that, such as streams, are told to stop through cancelBase
+// and get grace to return before their connections are closed.
+func drainServer(server *http.Server, requests *inFlight, cancelBase context.CancelFunc, timeout, grace time.Duration) (drained, cutOff int64) {
+	pending := requests.count()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err == nil {
+		cancelBase()
+		return pending, 0
+	}
+
+	cutOff = requests.count()
+	cancelBase()
+	deadline := time.Now().Add(grace)
+	for requests.count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.Close()
+
+	if drained = pending - cutOff; drained < 0 {
+		drained = 0
+	}
+	return drained, cutOff
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves handler behind the in-flight counter the way main does
+func startServer(t *testing.T, handler http.HandlerFunc) (*http.Server, *inFlight, context.CancelFunc, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	requests := &inFlight{}
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	server := &http.Server{
+		Handler:     requests.middleware(handler),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	go server.Serve(listener)
+	return server, requests, cancelBase, "http://" + listener.Addr().String()
+}
+
+// waitInFlight waits until n requests are being served
+func waitInFlight(t *testing.T, requests *inFlight, n int64) {
+	require.Eventually(t, func() bool { return requests.count() == n }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestDrainServerLetsSlowRequestFinish(t *testing.T) {
+	server, requests, cancelBase, url := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{body: string(body), err: err}
+	}()
+	waitInFlight(t, requests, 1)
+
+	drained, cutOff := drainServer(server, requests, cancelBase, 5*time.Second, time.Second)
+
+	assert.Equal(t, int64(1), drained)
+	assert.Equal(t, int64(0), cutOff)
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+}
+
+func TestDrainServerCancelsRequestsPastTimeout(t *testing.T) {
+	cancelled := make(chan error, 1)
+	server, requests, cancelBase, url := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// A stream that only stops when its context does
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		cancelled <- r.Context().Err()
+	})
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}()
+	waitInFlight(t, requests, 1)
+
+	start := time.Now()
+	drained, cutOff := drainServer(server, requests, cancelBase, 100*time.Millisecond, 2*time.Second)
+
+	assert.Equal(t, int64(0), drained)
+	assert.Equal(t, int64(1), cutOff)
+	assert.Less(t, time.Since(start), time.Second)
+	select {
+	case err := <-cancelled:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("handler never saw its context cancelled")
+	}
+	assert.Equal(t, int64(0), requests.count())
+}
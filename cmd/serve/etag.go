@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+)
+
+// Kinds of resource served with optimistic concurrency
+const (
+	instanceResource = "instance"
+	bucketResource   = "bucket"
+	secretResource   = "secret"
+)
+
+// errStaleVersion is returned by a resourceBackend when the resource
+// changed after the version an update was based on
+var errStaleVersion = errors.New("resource has changed")
+
+// servedResource is a resource as the API serves it
+type servedResource struct {
+	Data   map[string]interface{}
+	Labels map[string]string
+	// Version is the resource's own version token, which the ETag carries:
+	// an instance's label fingerprint, a bucket's metageneration or a
+	// secret's etag
+	Version string
+}
+
+// resourceBackend reads resources and updates their labels. SetLabels
+// applies only while the resource is still at version, failing with
+// errStaleVersion otherwise, so a write that passed the If-Match check
+// can't overwrite one that landed in between.
+type resourceBackend interface {
+	Get(ctx context.Context, kind, name string) (*servedResource, error)
+	SetLabels(ctx context.Context, kind, name string, labels map[string]string, version string) (*servedResource, error)
+}
+
+// fingerprint hashes the resource's contents; it is the ETag of resources
+// served without a backend
+func fingerprint(data map[string]interface{}) string {
+	encoded, _ := json.Marshal(data)
+	sum := sha256.Sum256(encoded)
+	return base64.StdEncoding.EncodeToString(sum[:8])
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header value
+// matches etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// handleVersioned serves GET, PUT and PATCH for one resource. GET returns
+// the resource with its ETag, and 304 while If-None-Match still matches.
+// Updates change the labels: they need the ETag back in If-Match (428
+// without it) and fail with 412 once the resource has changed. PUT
+// replaces the labels and PATCH merges into them, a null removing a label.
+// Without a backend the resource is served from fallback and updates get
+// 501.
+func (s *APIServer) handleVersioned(w http.ResponseWriter, r *http.Request, kind, name string, fallback map[string]interface{}) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPut, http.MethodPatch:
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH")
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		resource := &servedResource{Data: fallback, Version: fingerprint(fallback)}
+		if s.resources != nil {
+			var err error
+			if resource, err = s.resources.Get(r.Context(), kind, name); err != nil {
+				s.writeServiceError(w, err)
+				return
+			}
+		}
+		etag := strconv.Quote(resource.Version)
+		w.Header().Set("ETag", etag)
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, resource.Data)
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		s.writeError(w, http.StatusPreconditionRequired, "If-Match header is required for updates")
+		return
+	}
+	var update struct {
+		Labels map[string]*string `json:"labels"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: only labels can be updated: %v", err))
+		return
+	}
+	if s.resources == nil {
+		s.writeError(w, http.StatusNotImplemented, "Updating this resource is not supported: no backend is configured")
+		return
+	}
+
+	current, err := s.resources.Get(r.Context(), kind, name)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	etag := strconv.Quote(current.Version)
+	if !etagMatches(ifMatch, etag) {
+		w.Header().Set("ETag", etag)
+		s.writeError(w, http.StatusPreconditionFailed, "Resource has changed; fetch it again and retry with the new ETag")
+		return
+	}
+
+	labels := make(map[string]string)
+	if r.Method == http.MethodPatch {
+		for key, value := range current.Labels {
+			labels[key] = value
+		}
+	}
+	for key, value := range update.Labels {
+		if value == nil {
+			delete(labels, key)
+			continue
+		}
+		labels[key] = *value
+	}
+
+	updated, err := s.resources.SetLabels(r.Context(), kind, name, labels, current.Version)
+	if errors.Is(err, errStaleVersion) {
+		s.writeError(w, http.StatusPreconditionFailed, "Resource has changed; fetch it again and retry with the new ETag")
+		return
+	}
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", strconv.Quote(updated.Version))
+	s.writeJSON(w, http.StatusOK, updated.Data)
+}
+
+// gcpResources serves instances, buckets and secrets from the GCP services
+type gcpResources struct {
+	services *ServiceContainer
+	project  string
+	zone     string
+}
+
+func (g *gcpResources) Get(ctx context.Context, kind, name string) (*servedResource, error) {
+	switch kind {
+	case instanceResource:
+		instance, err := g.services.Compute.GetInstance(ctx, g.zone, name)
+		if err != nil {
+			return nil, err
+		}
+		return &servedResource{
+			Data: map[string]interface{}{
+				"id":          strconv.FormatUint(instance.GetId(), 10),
+				"name":        instance.GetName(),
+				"status":      instance.GetStatus(),
+				"zone":        g.zone,
+				"labels":      instance.GetLabels(),
+				"fingerprint": instance.GetLabelFingerprint(),
+			},
+			Labels:  instance.GetLabels(),
+			Version: instance.GetLabelFingerprint(),
+		}, nil
+	case bucketResource:
+		bucket, err := g.services.Storage.GetBucket(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return &servedResource{
+			Data: map[string]interface{}{
+				"name":           bucket.Name,
+				"location":       bucket.Location,
+				"class":          bucket.StorageClass,
+				"labels":         bucket.Labels,
+				"metageneration": bucket.MetaGeneration,
+			},
+			Labels:  bucket.Labels,
+			Version: strconv.FormatInt(bucket.MetaGeneration, 10),
+		}, nil
+	case secretResource:
+		secret, err := g.services.Secrets.GetSecret(ctx, g.secretName(name))
+		if err != nil {
+			return nil, err
+		}
+		return &servedResource{
+			Data: map[string]interface{}{
+				"name":   name,
+				"labels": secret.GetLabels(),
+				"etag":   secret.GetEtag(),
+			},
+			Labels:  secret.GetLabels(),
+			Version: secret.GetEtag(),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown resource kind %q", kind)
+}
+
+func (g *gcpResources) SetLabels(ctx context.Context, kind, name string, labels map[string]string, version string) (*servedResource, error) {
+	var err error
+	switch kind {
+	case instanceResource:
+		err = g.services.Compute.SetInstanceLabels(ctx, g.zone, name, labels, version)
+	case bucketResource:
+		metageneration, parseErr := strconv.ParseInt(version, 10, 64)
+		if parseErr != nil {
+			return nil, errStaleVersion
+		}
+		_, err = g.services.Storage.UpdateBucketLabels(ctx, name, labels, metageneration)
+	case secretResource:
+		_, err = g.services.Secrets.UpdateSecretLabels(ctx, g.secretName(name), labels, version)
+	default:
+		return nil, fmt.Errorf("unknown resource kind %q", kind)
+	}
+	if err != nil {
+		// Compute and Cloud Storage answer a stale fingerprint or
+		// metageneration with 412, Secret Manager a stale etag with
+		// FAILED_PRECONDITION or ABORTED
+		switch gcp.ErrorCode(gcp.AsError(err).Code) {
+		case gcp.ErrorCodePreconditionFailed, gcp.ErrorCodeFailedPrecondition, gcp.ErrorCodeAborted:
+			return nil, errStaleVersion
+		}
+		return nil, err
+	}
+	return g.Get(ctx, kind, name)
+}
+
+func (g *gcpResources) secretName(name string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", g.project, name)
+}
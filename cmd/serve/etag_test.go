@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResources keeps labelled resources with a generation as their version
+type fakeResources struct {
+	mu         sync.Mutex
+	labels     map[string]map[string]string
+	generation map[string]int
+	// beforeSet runs ahead of each SetLabels, to race it with other writes
+	beforeSet func()
+}
+
+func newFakeResources() *fakeResources {
+	return &fakeResources{labels: map[string]map[string]string{}, generation: map[string]int{}}
+}
+
+func (f *fakeResources) Get(_ context.Context, kind, name string) (*servedResource, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.view(kind + "/" + name), nil
+}
+
+func (f *fakeResources) SetLabels(_ context.Context, kind, name string, labels map[string]string, version string) (*servedResource, error) {
+	if f.beforeSet != nil {
+		f.beforeSet()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := kind + "/" + name
+	if strconv.Itoa(f.generation[key]) != version {
+		return nil, errStaleVersion
+	}
+	f.labels[key] = labels
+	f.generation[key]++
+	return f.view(key), nil
+}
+
+func (f *fakeResources) view(key string) *servedResource {
+	labels := map[string]string{}
+	for k, v := range f.labels[key] {
+		labels[k] = v
+	}
+	return &servedResource{
+		Data:    map[string]interface{}{"name": key, "labels": labels},
+		Labels:  labels,
+		Version: strconv.Itoa(f.generation[key]),
+	}
+}
+
+func requestInstance(t *testing.T, server *APIServer, method string, headers map[string]string, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, "/api/v1/compute/instances/web-1", strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	server.handleComputeInstance(rec, req, "web-1")
+
+	var envelope struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if rec.Code != http.StatusNotModified {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&envelope))
+	}
+	return rec, envelope.Data
+}
+
+func newVersionedTestServer() (*APIServer, *fakeResources) {
+	server := newTestAPIServer(&ServerConfig{Zone: "us-central1-a"})
+	resources := newFakeResources()
+	server.resources = resources
+	return server, resources
+}
+
+func TestUpdateWithMatchingETag(t *testing.T) {
+	server, _ := newVersionedTestServer()
+
+	rec, _ := requestInstance(t, server, http.MethodGet, nil, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, `"0"`, etag)
+
+	rec, instance := requestInstance(t, server, http.MethodPatch, map[string]string{"If-Match": etag}, `{"labels": {"env": "prod", "team": "web"}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "team": "web"}, instance["labels"])
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	// PATCH merges, with null removing a label; PUT replaces
+	rec, instance = requestInstance(t, server, http.MethodPatch, map[string]string{"If-Match": `"1"`}, `{"labels": {"team": null, "tier": "1"}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "tier": "1"}, instance["labels"])
+	rec, instance = requestInstance(t, server, http.MethodPut, map[string]string{"If-Match": `W/"2"`}, `{"labels": {"owner": "a"}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]interface{}{"owner": "a"}, instance["labels"])
+
+	rec, _ = requestInstance(t, server, http.MethodGet, map[string]string{"If-None-Match": `"3"`}, "")
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestUpdateWithStaleETag(t *testing.T) {
+	server, resources := newVersionedTestServer()
+
+	rec, _ := requestInstance(t, server, http.MethodPut, map[string]string{"If-Match": `"0"`}, `{"labels": {"owner": "a"}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec, _ = requestInstance(t, server, http.MethodPut, map[string]string{"If-Match": `"0"`}, `{"labels": {"owner": "b"}}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	// A write landing between the If-Match check and the update also fails
+	resources.beforeSet = func() {
+		resources.beforeSet = nil
+		resources.SetLabels(context.Background(), instanceResource, "web-1", map[string]string{"owner": "c"}, "1")
+	}
+	rec, _ = requestInstance(t, server, http.MethodPut, map[string]string{"If-Match": `"1"`}, `{"labels": {"owner": "b"}}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	_, instance := requestInstance(t, server, http.MethodGet, nil, "")
+	assert.Equal(t, map[string]interface{}{"owner": "c"}, instance["labels"])
+}
+
+func TestUpdateRequiresIfMatch(t *testing.T) {
+	server, resources := newVersionedTestServer()
+
+	rec, _ := requestInstance(t, server, http.MethodPatch, nil, `{"labels": {"env": "prod"}}`)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	assert.Empty(t, resources.labels)
+
+	rec, _ = requestInstance(t, server, http.MethodPatch, map[string]string{"If-Match": "*"}, `{"zone": "elsewhere"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUpdatesNeedABackend(t *testing.T) {
+	server := newTestAPIServer(&ServerConfig{Zone: "us-central1-a"})
+
+	rec, instance := requestInstance(t, server, http.MethodGet, nil, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "us-central1-a", instance["zone"])
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rec, _ = requestInstance(t, server, http.MethodPatch, map[string]string{"If-Match": etag}, `{"labels": {"env": "prod"}}`)
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	rec, _ = requestInstance(t, server, http.MethodDelete, nil, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
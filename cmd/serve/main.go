@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,7 +15,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/analysis"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
 )
 
 type ServerConfig struct {
@@ -26,6 +31,8 @@ type ServerConfig struct {
 	EnableCORS      bool              `json:"enable_cors"`
 	EnableAuth      bool              `json:"enable_auth"`
 	AuthMethod      string            `json:"auth_method"`
+	// AuthToken is the bearer token auth requires; SERVE_AUTH_TOKEN overrides it
+	AuthToken       string            `json:"auth_token,omitempty"`
 	CertFile        string            `json:"cert_file"`
 	KeyFile         string            `json:"key_file"`
 	EnableMetrics   bool              `json:"enable_metrics"`
@@ -38,6 +45,7 @@ type ServerConfig struct {
 	RateLimit       RateLimitConfig   `json:"rate_limit"`
 	Services        ServicesConfig    `json:"services"`
 	Security        SecurityConfig    `json:"security"`
+	TLS             TLSSettings       `json:"tls"`
 }
 
 type RateLimitConfig struct {
@@ -68,12 +76,21 @@ type SecurityConfig struct {
 type APIResponse struct {
 	Success   bool        `json:"success"`
 	Data      interface{} `json:"data,omitempty"`
-	Error     string      `json:"error,omitempty"`
+	Error     *APIError   `json:"error,omitempty"`
 	Message   string      `json:"message,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 	RequestID string      `json:"request_id,omitempty"`
 }
 
+// APIError describes a failed request. Code comes from the gcp error catalog
+// (NOT_FOUND, RATE_LIMITED, VALIDATION_FAILED, ...) and is stable for clients
+// to branch on.
+type APIError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details []gcp.ErrorDetail `json:"details,omitempty"`
+}
+
 type HealthResponse struct {
 	Status    string                 `json:"status"`
 	Version   string                 `json:"version"`
@@ -97,6 +114,15 @@ type APIServer struct {
 	server       *http.Server
 	startTime    time.Time
 	metrics      *ServerMetrics
+	requests     *inFlight
+	// operationRunner replaces runOperation for batch operations in tests
+	operationRunner operationRunner
+	// analyzer backs /api/v1/utils/recommendations; nil when unavailable
+	analyzer        recommendationAnalyzer
+	recommendations recommendationCache
+	// resources backs instance, bucket and secret reads and label updates;
+	// without one, reads are served from placeholders and updates get 501
+	resources resourceBackend
 }
 
 type ServiceContainer struct {
@@ -118,23 +144,36 @@ type ServerMetrics struct {
 
 func main() {
 	var (
-		configFile = flag.String("config", "", "Path to server configuration file")
-		port       = flag.Int("port", 8080, "Server port")
-		host       = flag.String("host", "0.0.0.0", "Server host")
-		projectID  = flag.String("project", "", "GCP Project ID")
-		region     = flag.String("region", "us-central1", "GCP Region")
-		zone       = flag.String("zone", "us-central1-a", "GCP Zone")
-		cors       = flag.Bool("cors", true, "Enable CORS")
-		tls        = flag.Bool("tls", false, "Enable TLS")
-		certFile   = flag.String("cert", "", "TLS certificate file")
-		keyFile    = flag.String("key", "", "TLS private key file")
-		verbose    = flag.Bool("verbose", false, "Enable verbose logging")
-		metrics    = flag.Bool("metrics", true, "Enable metrics endpoint")
-		health     = flag.Bool("health", true, "Enable health endpoint")
-		swagger    = flag.Bool("swagger", true, "Enable Swagger documentation")
+		configFile   = flag.String("config", "", "Path to server configuration file")
+		port         = flag.Int("port", 8080, "Server port")
+		host         = flag.String("host", "0.0.0.0", "Server host")
+		projectID    = flag.String("project", "", "GCP Project ID")
+		region       = flag.String("region", "us-central1", "GCP Region")
+		zone         = flag.String("zone", "us-central1-a", "GCP Zone")
+		cors         = flag.Bool("cors", true, "Enable CORS")
+		tls          = flag.Bool("tls", false, "Enable TLS")
+		certFile     = flag.String("cert", "", "TLS certificate file")
+		keyFile      = flag.String("key", "", "TLS private key file")
+		verbose      = flag.Bool("verbose", false, "Enable verbose logging")
+		metrics      = flag.Bool("metrics", true, "Enable metrics endpoint")
+		health       = flag.Bool("health", true, "Enable health endpoint")
+		swagger      = flag.Bool("swagger", true, "Enable Swagger documentation")
+		validateCfg  = flag.Bool("validate-config", false, "Validate the -config file against its schema and exit")
+		configSchema = flag.Bool("config-schema", false, "Print the JSON Schema of the config file and exit")
+		configFormat = flag.String("config-format", "auto", "Config file format (auto, json, yaml); auto uses the file extension")
+		pprofFlag    = flag.Bool("pprof", false, "Serve pprof and runtime metrics on the admin listener")
+		adminAddr    = flag.String("admin-addr", "127.0.0.1:6060", "Admin listener address for -pprof; must be loopback unless auth is enabled")
 	)
 	flag.Parse()
 
+	if *configSchema {
+		configschema.Print(os.Stdout, ServerConfig{})
+		return
+	}
+	if *validateCfg {
+		os.Exit(configschema.ValidateFile(os.Stderr, *configFile, *configFormat, ServerConfig{}))
+	}
+
 	if *projectID == "" {
 		*projectID = os.Getenv("GCP_PROJECT_ID")
 		if *projectID == "" {
@@ -150,7 +189,7 @@ func main() {
 			log.Fatalf("Error reading config file: %v", err)
 		}
 
-		if err := json.Unmarshal(configData, &serverConfig); err != nil {
+		if err := configschema.DecodeFormat(configData, configschema.DetectFormat(*configFile, *configFormat), &serverConfig); err != nil {
 			log.Fatalf("Error parsing config file: %v", err)
 		}
 	} else {
@@ -197,6 +236,14 @@ func main() {
 		log.Fatalf("Error initializing services: %v", err)
 	}
 
+	// Recommendations come from analyzing the project's resources
+	var analyzer recommendationAnalyzer
+	if provider, err := providers.NewGCPProvider(ctx, serverConfig.ProjectID, serverConfig.Region); err != nil {
+		log.Printf("Recommendations disabled: failed to create analysis provider: %v", err)
+	} else {
+		analyzer = analysis.NewAnalyzer(provider, logrus.StandardLogger())
+	}
+
 	// Create API server
 	apiServer := &APIServer{
 		config:    &serverConfig,
@@ -207,21 +254,55 @@ func main() {
 			RequestCount: make(map[string]int64),
 			ErrorCount:   make(map[string]int64),
 		},
+		requests:  &inFlight{},
+		analyzer:  analyzer,
+		resources: &gcpResources{services: services, project: serverConfig.ProjectID, zone: serverConfig.Zone},
 	}
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	apiServer.setupRoutes(mux)
 
+	// Request contexts derive from baseCtx so shutdown can stop requests
+	// that don't finish in time
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", serverConfig.Host, serverConfig.Port),
-		Handler:      apiServer.corsMiddleware(apiServer.loggingMiddleware(apiServer.metricsMiddleware(mux))),
+		Handler:      apiServer.requests.middleware(apiServer.corsMiddleware(apiServer.loggingMiddleware(apiServer.metricsMiddleware(mux)))),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
 	apiServer.server = server
 
+	if *tls && serverConfig.CertFile != "" && serverConfig.KeyFile != "" {
+		reloader, err := newCertReloader(serverConfig.CertFile, serverConfig.KeyFile)
+		if err != nil {
+			log.Fatalf("Error loading TLS certificate: %v", err)
+		}
+		defer reloader.Close()
+		server.TLSConfig, err = serverTLSConfig(serverConfig.TLS, reloader)
+		if err != nil {
+			log.Fatalf("Error configuring TLS: %v", err)
+		}
+	}
+
+	// Profiling stays off the public listener
+	adminServer, err := apiServer.newAdminServer(*pprofFlag, *adminAddr)
+	if err != nil {
+		log.Fatalf("Error configuring the admin listener: %v", err)
+	}
+	if adminServer != nil {
+		go func() {
+			log.Printf("🩺 Admin endpoints (pprof, runtime metrics): http://%s/debug/", *adminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Admin server failed: %v", err)
+			}
+		}()
+	}
+
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -242,9 +323,9 @@ func main() {
 		}
 
 		var err error
-		if *tls && serverConfig.CertFile != "" && serverConfig.KeyFile != "" {
+		if server.TLSConfig != nil {
 			log.Printf("🔒 Starting HTTPS server")
-			err = server.ListenAndServeTLS(serverConfig.CertFile, serverConfig.KeyFile)
+			err = server.ListenAndServeTLS("", "")
 		} else {
 			log.Printf("🌐 Starting HTTP server")
 			err = server.ListenAndServe()
@@ -260,11 +341,14 @@ func main() {
 	log.Println("🛑 Shutting down server gracefully...")
 
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+	if adminServer != nil {
+		adminServer.Close()
+	}
+	drained, cutOff := drainServer(server, apiServer.requests, cancelBase, shutdownTimeout, shutdownGrace)
+	if cutOff > 0 {
+		log.Printf("Drained %d in-flight requests; cut off %d still running after %v", drained, cutOff, shutdownTimeout)
+	} else {
+		log.Printf("Drained %d in-flight requests", drained)
 	}
 
 	// Close GCP client
@@ -413,6 +497,7 @@ func (s *APIServer) setupRoutes(mux *http.ServeMux) {
 
 	// API endpoints
 	mux.HandleFunc("/api/v1/", s.handleAPIRequest)
+	mux.HandleFunc("/api/v1/batch", s.handleBatch)
 
 	// Service-specific endpoints
 	if s.config.Services.Compute {
@@ -567,6 +652,11 @@ func (s *APIServer) handleSwagger(w http.ResponseWriter, r *http.Request) {
         <div class="path">/api/v1/utils/*</div>
         <p>Utility functions</p>
     </div>
+    <div class="endpoint">
+        <div class="method">PUT|PATCH</div>
+        <div class="path">/api/v1/{compute/instances,storage/buckets,secrets/secrets}/{name}</div>
+        <p>Update a resource's labels; send the ETag from GET in If-Match (428 without it, 412 if it is stale)</p>
+    </div>
 </body>
 </html>`
 
@@ -590,6 +680,7 @@ func (s *APIServer) handleAPIRequest(w http.ResponseWriter, r *http.Request) {
 			"/api/v1/secrets/",
 			"/api/v1/monitoring/",
 			"/api/v1/utils/",
+			"/api/v1/batch",
 		},
 	})
 }
@@ -763,7 +854,7 @@ func (s *APIServer) handleComputeInstances(w http.ResponseWriter, r *http.Reques
 }
 
 func (s *APIServer) handleComputeInstance(w http.ResponseWriter, r *http.Request, instanceID string) {
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+	s.handleVersioned(w, r, instanceResource, instanceID, map[string]interface{}{
 		"id":     instanceID,
 		"name":   "web-server-1",
 		"status": "running",
@@ -784,7 +875,7 @@ func (s *APIServer) handleStorageBuckets(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *APIServer) handleStorageBucket(w http.ResponseWriter, r *http.Request, bucketName string) {
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+	s.handleVersioned(w, r, bucketResource, bucketName, map[string]interface{}{
 		"name":     bucketName,
 		"location": s.config.Region,
 		"class":    "STANDARD",
@@ -870,7 +961,7 @@ func (s *APIServer) handleSecrets(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *APIServer) handleSecret(w http.ResponseWriter, r *http.Request, secretName string) {
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+	s.handleVersioned(w, r, secretResource, secretName, map[string]interface{}{
 		"name": secretName,
 		"versions": []map[string]interface{}{
 			{
@@ -928,25 +1019,14 @@ func (s *APIServer) handleProjectInfo(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *APIServer) handleRecommendations(w http.ResponseWriter, r *http.Request) {
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"recommendations": []map[string]interface{}{
-			{
-				"type":        "cost",
-				"description": "Rightsize compute instances",
-				"savings":     125.50,
-			},
-		},
-	})
-}
-
 // Middleware functions
 func (s *APIServer) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if s.config.EnableCORS {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, If-Match, If-None-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusOK)
@@ -1016,12 +1096,29 @@ func (s *APIServer) writeJSON(w http.ResponseWriter, status int, data interface{
 }
 
 func (s *APIServer) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeAPIError(w, status, &APIError{
+		Code:    string(gcp.CodeForStatus(status)),
+		Message: message,
+	})
+}
+
+// writeServiceError answers with the catalog code and status of err
+func (s *APIServer) writeServiceError(w http.ResponseWriter, err error) {
+	gcpErr := gcp.AsError(err)
+	s.writeAPIError(w, gcpErr.HTTPStatus(), &APIError{
+		Code:    gcpErr.Code,
+		Message: gcpErr.Error(),
+		Details: gcpErr.Details,
+	})
+}
+
+func (s *APIServer) writeAPIError(w http.ResponseWriter, status int, apiErr *APIError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
 	response := APIResponse{
 		Success:   false,
-		Error:     message,
+		Error:     apiErr,
 		Timestamp: time.Now(),
 	}
 
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func decodeAPIError(t *testing.T, rec *httptest.ResponseRecorder) *APIError {
+	var response APIResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.False(t, response.Success)
+	require.NotNil(t, response.Error)
+	return response.Error
+}
+
+func TestWriteServiceErrorMapsToCatalog(t *testing.T) {
+	server := newTestAPIServer(&ServerConfig{})
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("failed to get bucket: %w", &googleapi.Error{Code: 404, Message: "no such bucket"}), http.StatusNotFound, "NOT_FOUND"},
+		{&googleapi.Error{Code: 429, Message: "too many requests"}, http.StatusTooManyRequests, "RATE_LIMITED"},
+		{&googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, http.StatusTooManyRequests, "QUOTA_EXCEEDED"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.writeServiceError(rec, tt.err)
+
+		assert.Equal(t, tt.status, rec.Code)
+		apiErr := decodeAPIError(t, rec)
+		assert.Equal(t, tt.code, apiErr.Code)
+		assert.NotEmpty(t, apiErr.Message)
+	}
+}
+
+func TestWriteErrorIncludesCode(t *testing.T) {
+	server := newTestAPIServer(&ServerConfig{})
+	rec := httptest.NewRecorder()
+	server.writeError(rec, http.StatusNotFound, "Endpoint not found")
+
+	apiErr := decodeAPIError(t, rec)
+	assert.Equal(t, "NOT_FOUND", apiErr.Code)
+	assert.Equal(t, "Endpoint not found", apiErr.Message)
+}
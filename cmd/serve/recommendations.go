@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/analysis"
+)
+
+const (
+	defaultRecommendationPageSize = 20
+	maxRecommendationPageSize     = 100
+	// recommendationsTTL is how long one analysis serves every page and
+	// filter of the recommendations for its resource type
+	recommendationsTTL = 10 * time.Minute
+)
+
+// recommendationAnalyzer analyzes the project's resources;
+// *analysis.Analyzer satisfies it and tests substitute a fake
+type recommendationAnalyzer interface {
+	Analyze(ctx context.Context, options analysis.AnalysisOptions) (*analysis.AnalysisResults, error)
+}
+
+// recommendationCache keeps the recommendations of an analysis per resource
+// type, as an analysis takes minutes. Concurrent requests for a type share
+// one analysis; a failed one isn't kept.
+type recommendationCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedRecommendations
+	// ttl overrides recommendationsTTL in tests
+	ttl time.Duration
+}
+
+type cachedRecommendations struct {
+	done            chan struct{}
+	recommendations []Recommendation
+	err             error
+	expires         time.Time
+}
+
+// get returns the recommendations for resourceType, analyzing again once
+// the cached ones expire. The analysis outlives a request that gives up
+// waiting for it, so the next one can still use it.
+func (c *recommendationCache) get(ctx context.Context, analyzer recommendationAnalyzer, resourceType string) ([]Recommendation, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[resourceType]
+	// An entry still being analyzed has no expiry yet
+	if !ok || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		if c.entries == nil {
+			c.entries = make(map[string]*cachedRecommendations)
+		}
+		entry = &cachedRecommendations{done: make(chan struct{})}
+		c.entries[resourceType] = entry
+		go c.analyze(context.WithoutCancel(ctx), analyzer, resourceType, entry)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.recommendations, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *recommendationCache) analyze(ctx context.Context, analyzer recommendationAnalyzer, resourceType string, entry *cachedRecommendations) {
+	results, err := analyzer.Analyze(ctx, analysis.AnalysisOptions{ResourceType: resourceType})
+
+	ttl := c.ttl
+	if ttl == 0 {
+		ttl = recommendationsTTL
+	}
+	c.mu.Lock()
+	if err != nil {
+		entry.err = err
+		if c.entries[resourceType] == entry {
+			delete(c.entries, resourceType)
+		}
+	} else {
+		entry.recommendations = collectRecommendations(results)
+		entry.expires = time.Now().Add(ttl)
+	}
+	c.mu.Unlock()
+	close(entry.done)
+}
+
+// Recommendation is one entry of /api/v1/utils/recommendations
+type Recommendation struct {
+	ID          string   `json:"id"`
+	Category    string   `json:"category"`
+	Priority    string   `json:"priority"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Resources   []string `json:"resources,omitempty"`
+	// MonthlyCost is the resource's current estimated monthly cost
+	MonthlyCost float64 `json:"monthly_cost,omitempty"`
+	Savings     float64 `json:"savings"`
+}
+
+// RecommendationsPage is one page of recommendations, sorted by savings
+type RecommendationsPage struct {
+	Recommendations []Recommendation `json:"recommendations"`
+	Total           int              `json:"total"`
+	TotalSavings    float64          `json:"total_savings"`
+	Page            int              `json:"page"`
+	PageSize        int              `json:"page_size"`
+	NextPage        int              `json:"next_page,omitempty"`
+}
+
+// recommendationCategories maps analysis categories and issue types to the
+// categories clients filter by
+var recommendationCategories = map[string]string{
+	"COST":         "cost",
+	"OPTIMIZATION": "cost",
+	"PERFORMANCE":  "performance",
+	"RELIABILITY":  "performance",
+	"SECURITY":     "security",
+}
+
+// recommendationQuery holds the parsed query parameters
+type recommendationQuery struct {
+	category     string
+	minSavings   float64
+	resourceType string
+	page         int
+	pageSize     int
+}
+
+func (s *APIServer) handleRecommendations(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRecommendationQuery(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.analyzer == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Recommendations are not available: no analysis backend is configured")
+		return
+	}
+
+	recommendations, err := s.recommendations.get(r.Context(), s.analyzer, query.resourceType)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, pageRecommendations(filterRecommendations(recommendations, query), query))
+}
+
+func parseRecommendationQuery(r *http.Request) (recommendationQuery, error) {
+	values := r.URL.Query()
+	query := recommendationQuery{
+		category:     strings.ToLower(values.Get("category")),
+		resourceType: values.Get("resource_type"),
+		page:         1,
+		pageSize:     defaultRecommendationPageSize,
+	}
+
+	switch query.category {
+	case "", "cost", "security", "performance":
+	default:
+		return query, fmt.Errorf("category must be cost, security or performance, got %q", query.category)
+	}
+
+	if raw := values.Get("min_savings"); raw != "" {
+		minSavings, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minSavings < 0 {
+			return query, fmt.Errorf("min_savings must be a non-negative number, got %q", raw)
+		}
+		query.minSavings = minSavings
+	}
+	if raw := values.Get("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return query, fmt.Errorf("page must be a positive integer, got %q", raw)
+		}
+		query.page = page
+	}
+	if raw := values.Get("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > maxRecommendationPageSize {
+			return query, fmt.Errorf("page_size must be between 1 and %d, got %q", maxRecommendationPageSize, raw)
+		}
+		query.pageSize = size
+	}
+	return query, nil
+}
+
+// collectRecommendations gathers the project-wide recommendations of an
+// analysis, the cost recommendations of each resource and the performance
+// and security issues found on them. Cost issues are left out as they
+// repeat the resource's cost recommendations.
+func collectRecommendations(results *analysis.AnalysisResults) []Recommendation {
+	var recommendations []Recommendation
+	for _, rec := range results.Recommendations {
+		recommendations = append(recommendations, Recommendation{
+			ID:          rec.ID,
+			Category:    recommendationCategory(rec.Category),
+			Priority:    strings.ToLower(rec.Priority),
+			Title:       rec.Title,
+			Description: rec.Description,
+			Resources:   rec.Resources,
+			Savings:     rec.EstimatedSavings,
+		})
+	}
+
+	for _, resource := range results.Resources {
+		name := resource.ResourceName
+		if name == "" {
+			name = resource.ResourceID
+		}
+		for _, rec := range resource.Cost.Recommendations {
+			recommendations = append(recommendations, Recommendation{
+				ID:          fmt.Sprintf("%s-%s", strings.ToLower(rec.Type), resource.ResourceID),
+				Category:    "cost",
+				Priority:    strings.ToLower(rec.Priority),
+				Title:       fmt.Sprintf("%s %s", strings.ToLower(rec.Type), name),
+				Description: rec.Description,
+				Resources:   []string{resource.ResourceID},
+				MonthlyCost: resource.Cost.CurrentCost,
+				Savings:     rec.Savings,
+			})
+		}
+		for _, issue := range resource.Issues {
+			if issue.Type == "COST" {
+				continue
+			}
+			recommendations = append(recommendations, Recommendation{
+				ID:          issue.ID,
+				Category:    recommendationCategory(issue.Type),
+				Priority:    strings.ToLower(issue.Severity),
+				Title:       issue.Description,
+				Description: issue.Resolution,
+				Resources:   []string{resource.ResourceID},
+				MonthlyCost: resource.Cost.CurrentCost,
+			})
+		}
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Savings != recommendations[j].Savings {
+			return recommendations[i].Savings > recommendations[j].Savings
+		}
+		return recommendations[i].ID < recommendations[j].ID
+	})
+	return recommendations
+}
+
+func recommendationCategory(category string) string {
+	if mapped, ok := recommendationCategories[strings.ToUpper(category)]; ok {
+		return mapped
+	}
+	return strings.ToLower(category)
+}
+
+func filterRecommendations(recommendations []Recommendation, query recommendationQuery) []Recommendation {
+	filtered := []Recommendation{}
+	for _, rec := range recommendations {
+		if query.category != "" && rec.Category != query.category {
+			continue
+		}
+		if rec.Savings < query.minSavings {
+			continue
+		}
+		filtered = append(filtered, rec)
+	}
+	return filtered
+}
+
+func pageRecommendations(recommendations []Recommendation, query recommendationQuery) RecommendationsPage {
+	page := RecommendationsPage{
+		Recommendations: []Recommendation{},
+		Total:           len(recommendations),
+		Page:            query.page,
+		PageSize:        query.pageSize,
+	}
+	for _, rec := range recommendations {
+		page.TotalSavings += rec.Savings
+	}
+
+	start := (query.page - 1) * query.pageSize
+	if start >= len(recommendations) {
+		return page
+	}
+	end := start + query.pageSize
+	if end < len(recommendations) {
+		page.NextPage = query.page + 1
+	} else {
+		end = len(recommendations)
+	}
+	page.Recommendations = recommendations[start:end]
+	return page
+}
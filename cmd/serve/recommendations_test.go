@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/analysis"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
+)
+
+// fakeAnalyzer returns canned results and records the options it got
+type fakeAnalyzer struct {
+	mu      sync.Mutex
+	results *analysis.AnalysisResults
+	err     error
+	options []analysis.AnalysisOptions
+	// release, when set, holds every analysis until it is closed
+	release chan struct{}
+}
+
+func (f *fakeAnalyzer) Analyze(ctx context.Context, options analysis.AnalysisOptions) (*analysis.AnalysisResults, error) {
+	f.mu.Lock()
+	f.options = append(f.options, options)
+	f.mu.Unlock()
+	if f.release != nil {
+		<-f.release
+	}
+	return f.results, f.err
+}
+
+func (f *fakeAnalyzer) analyses() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.options)
+}
+
+func sampleAnalysis() *analysis.AnalysisResults {
+	return &analysis.AnalysisResults{
+		Recommendations: []analysis.AnalysisRecommendation{
+			{ID: "cost-opt-001", Category: "COST", Priority: "HIGH", Title: "Significant Cost Optimization Opportunity", EstimatedSavings: 1450},
+			{ID: "scale-001", Category: "PERFORMANCE", Priority: "HIGH", Title: "Resources Require Scaling"},
+		},
+		Resources: []analysis.ResourceAnalysis{
+			{
+				ResourceID:   "projects/acme/zones/us-central1-a/instances/web-1",
+				ResourceName: "web-1",
+				Cost: analysis.CostAnalysisDetail{
+					CurrentCost: 240,
+					Recommendations: []analysis.CostRecommendation{
+						{Type: "RIGHTSIZING", Description: "Resource is underutilized. Consider downsizing.", Savings: 96, Priority: "HIGH"},
+					},
+				},
+				Issues: []analysis.AnalysisIssue{
+					{ID: "cost-opt-web-1", Type: "COST", Severity: "MEDIUM"},
+				},
+			},
+			{
+				ResourceID:   "projects/acme/zones/us-central1-a/instances/batch-1",
+				ResourceName: "batch-1",
+				Cost: analysis.CostAnalysisDetail{
+					CurrentCost: 50,
+					Recommendations: []analysis.CostRecommendation{
+						{Type: "RIGHTSIZING", Description: "Resource is underutilized. Consider downsizing.", Savings: 20, Priority: "HIGH"},
+					},
+				},
+				Issues: []analysis.AnalysisIssue{
+					{ID: "cpu-high-batch-1", Type: "PERFORMANCE", Severity: "HIGH", Description: "CPU utilization is high: 93.00%", Resolution: "Consider scaling up or optimizing workload"},
+				},
+			},
+		},
+	}
+}
+
+// newRecommendationsServer returns a server with the utils API, which
+// serves recommendations, enabled
+func newRecommendationsServer(analyzer recommendationAnalyzer) *APIServer {
+	server := newTestAPIServer(&ServerConfig{Services: ServicesConfig{Utils: true}})
+	server.services.Utils = &gcp.UtilsService{}
+	server.analyzer = analyzer
+	return server
+}
+
+func getRecommendations(t *testing.T, server *APIServer, query string) (int, RecommendationsPage) {
+	t.Helper()
+	mux := http.NewServeMux()
+	server.setupRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/utils/recommendations"+query, nil))
+
+	var envelope struct {
+		Data RecommendationsPage `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&envelope))
+	return rec.Code, envelope.Data
+}
+
+func recommendationIDs(page RecommendationsPage) []string {
+	ids := []string{}
+	for _, rec := range page.Recommendations {
+		ids = append(ids, rec.ID)
+	}
+	return ids
+}
+
+func TestRecommendationsReflectAnalysis(t *testing.T) {
+	analyzer := &fakeAnalyzer{results: sampleAnalysis()}
+	server := newRecommendationsServer(analyzer)
+
+	status, page := getRecommendations(t, server, "?resource_type=compute.instances")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "compute.instances", analyzer.options[0].ResourceType)
+
+	assert.Equal(t, []string{
+		"cost-opt-001",
+		"rightsizing-projects/acme/zones/us-central1-a/instances/web-1",
+		"rightsizing-projects/acme/zones/us-central1-a/instances/batch-1",
+		"cpu-high-batch-1",
+		"scale-001",
+	}, recommendationIDs(page))
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, 1566.0, page.TotalSavings)
+
+	web := page.Recommendations[1]
+	assert.Equal(t, "cost", web.Category)
+	assert.Equal(t, 240.0, web.MonthlyCost)
+	assert.Equal(t, 96.0, web.Savings)
+	assert.Equal(t, "rightsizing web-1", web.Title)
+	assert.Equal(t, "performance", page.Recommendations[3].Category)
+}
+
+func TestRecommendationsFiltersAndPages(t *testing.T) {
+	server := newRecommendationsServer(&fakeAnalyzer{results: sampleAnalysis()})
+
+	_, page := getRecommendations(t, server, "?category=cost&min_savings=50")
+	assert.Equal(t, []string{"cost-opt-001", "rightsizing-projects/acme/zones/us-central1-a/instances/web-1"}, recommendationIDs(page))
+	assert.Equal(t, 1546.0, page.TotalSavings)
+
+	_, page = getRecommendations(t, server, "?category=performance")
+	assert.Equal(t, []string{"cpu-high-batch-1", "scale-001"}, recommendationIDs(page))
+
+	_, page = getRecommendations(t, server, "?category=security")
+	assert.Empty(t, page.Recommendations)
+
+	_, page = getRecommendations(t, server, "?page_size=2&page=2")
+	assert.Equal(t, []string{"rightsizing-projects/acme/zones/us-central1-a/instances/batch-1", "cpu-high-batch-1"}, recommendationIDs(page))
+	assert.Equal(t, 3, page.NextPage)
+	assert.Equal(t, 5, page.Total)
+
+	_, page = getRecommendations(t, server, "?page_size=2&page=3")
+	assert.Equal(t, []string{"scale-001"}, recommendationIDs(page))
+	assert.Zero(t, page.NextPage)
+
+	_, page = getRecommendations(t, server, "?page=9")
+	assert.Empty(t, page.Recommendations)
+}
+
+func TestRecommendationsShareOneAnalysisPerResourceType(t *testing.T) {
+	analyzer := &fakeAnalyzer{results: sampleAnalysis(), release: make(chan struct{})}
+	server := newRecommendationsServer(analyzer)
+
+	// Requests arriving while the analysis runs wait for it
+	var wg sync.WaitGroup
+	for page := 1; page <= 3; page++ {
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			status, _ := getRecommendations(t, server, fmt.Sprintf("?page_size=2&page=%d", page))
+			assert.Equal(t, http.StatusOK, status)
+		}(page)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(analyzer.release)
+	wg.Wait()
+
+	_, page := getRecommendations(t, server, "?category=cost")
+	assert.Len(t, page.Recommendations, 3)
+	assert.Equal(t, 1, analyzer.analyses())
+
+	getRecommendations(t, server, "?resource_type=compute.instances")
+	assert.Equal(t, 2, analyzer.analyses())
+
+	// Expired analyses are redone
+	server.recommendations.ttl = time.Millisecond
+	getRecommendations(t, server, "?resource_type=storage.buckets")
+	time.Sleep(5 * time.Millisecond)
+	getRecommendations(t, server, "?resource_type=storage.buckets")
+	assert.Equal(t, 4, analyzer.analyses())
+}
+
+func TestRecommendationsRejectBadQueries(t *testing.T) {
+	server := newRecommendationsServer(&fakeAnalyzer{results: sampleAnalysis()})
+
+	for _, query := range []string{"?category=latency", "?min_savings=-1", "?min_savings=lots", "?page=0", "?page_size=500"} {
+		status, _ := getRecommendations(t, server, query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
+
+func TestRecommendationsWithoutOrFailingBackend(t *testing.T) {
+	server := newRecommendationsServer(nil)
+	status, _ := getRecommendations(t, server, "")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	failing := &fakeAnalyzer{err: errors.New("quota exceeded")}
+	server.analyzer = failing
+	status, _ = getRecommendations(t, server, "")
+	assert.GreaterOrEqual(t, status, 500)
+
+	// A failed analysis is retried by the next request
+	getRecommendations(t, server, "")
+	assert.Equal(t, 2, failing.analyses())
+}
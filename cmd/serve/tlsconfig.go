@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TLSSettings configures HTTPS beyond the certificate and key files
+type TLSSettings struct {
+	// MinVersion is "1.2", the default, or "1.3"
+	MinVersion string `json:"min_version,omitempty"`
+	// ClientCAFile turns on mTLS: client certificates must chain to a CA in
+	// this PEM bundle
+	ClientCAFile string `json:"client_ca_file,omitempty"`
+	// ClientCertOptional verifies client certificates that are presented
+	// without requiring one
+	ClientCertOptional bool `json:"client_cert_optional,omitempty"`
+}
+
+// secureCipherSuites are the TLS 1.2 suites served: ECDHE key exchange with
+// AEAD ciphers only. TLS 1.3 suites aren't configurable and are all secure.
+var secureCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// certReloader serves the certificate in certFile and keyFile, reloading it
+// when either changes so certificates rotate without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+	watcher  *fsnotify.Watcher
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newCertReloader loads the certificate and starts watching its files. The
+// directories are watched rather than the files, since rotation usually
+// replaces files instead of writing to them.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: filepath.Clean(certFile), keyFile: filepath.Clean(keyFile)}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch TLS certificate: %w", err)
+	}
+	for _, dir := range uniqueDirs(r.certFile, r.keyFile) {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	r.watcher = watcher
+	go r.watch()
+	return r, nil
+}
+
+func uniqueDirs(paths ...string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if dir := filepath.Dir(path); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// reload replaces the served certificate, keeping the old one if the files
+// don't hold a valid pair
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) watch() {
+	for {
+		select {
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			// A half-written pair fails to load; the write completing it
+			// triggers another reload
+			if err := r.reload(); err == nil {
+				log.Printf("🔒 Reloaded TLS certificate after %s", event)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("TLS certificate watch error: %v", err)
+		}
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) Close() error {
+	return r.watcher.Close()
+}
+
+// serverTLSConfig returns the server's TLS config: TLS 1.2 or newer with
+// secureCipherSuites, certificates from reloader, and client certificate
+// verification when settings configure a client CA
+func serverTLSConfig(settings TLSSettings, reloader *certReloader) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		CipherSuites:   secureCipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch settings.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS min_version %q (expected 1.2 or 1.3)", settings.MinVersion)
+	}
+
+	if settings.ClientCAFile != "" {
+		data, err := os.ReadFile(settings.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", settings.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if settings.ClientCertOptional {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate with its key, signed by parent or self-signed
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, serial int64, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) pem(t *testing.T) (certPEM, keyPEM []byte) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	certPEM, keyPEM := c.pem(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+// writeCert writes c over the pair at certFile and keyFile the way rotation
+// tools do: to temporary files renamed into place
+func writeCert(t *testing.T, c *testCert, certFile, keyFile string) {
+	certPEM, keyPEM := c.pem(t)
+	for path, data := range map[string][]byte{keyFile: keyPEM, certFile: certPEM} {
+		tmp := path + ".tmp"
+		require.NoError(t, os.WriteFile(tmp, data, 0600))
+		require.NoError(t, os.Rename(tmp, path))
+	}
+}
+
+// serveTLS completes handshakes with config until the test ends
+func serveTLS(t *testing.T, config *tls.Config) string {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func servedSerial(addr string) (int64, error) {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func startReloader(t *testing.T, cert *testCert) (*certReloader, string, string) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, cert, certFile, keyFile)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	t.Cleanup(func() { reloader.Close() })
+	return reloader, certFile, keyFile
+}
+
+func TestCertReloaderPicksUpRotatedCert(t *testing.T) {
+	reloader, certFile, keyFile := startReloader(t, newTestCert(t, 1, nil, false))
+	config, err := serverTLSConfig(TLSSettings{}, reloader)
+	require.NoError(t, err)
+	addr := serveTLS(t, config)
+
+	serial, err := servedSerial(addr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), serial)
+
+	writeCert(t, newTestCert(t, 2, nil, false), certFile, keyFile)
+
+	assert.Eventually(t, func() bool {
+		serial, err := servedSerial(addr)
+		return err == nil && serial == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A broken pair keeps the last good certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0600))
+	time.Sleep(100 * time.Millisecond)
+	serial, err = servedSerial(addr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), serial)
+}
+
+func TestServerTLSConfigRejectsOldTLS(t *testing.T) {
+	reloader, _, _ := startReloader(t, newTestCert(t, 1, nil, false))
+	config, err := serverTLSConfig(TLSSettings{}, reloader)
+	require.NoError(t, err)
+	addr := serveTLS(t, config)
+
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11} {
+		_, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, MinVersion: version, MaxVersion: version})
+		assert.Error(t, err, "TLS version %x was accepted", version)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+	require.NoError(t, err)
+	assert.Contains(t, secureCipherSuites, conn.ConnectionState().CipherSuite)
+	conn.Close()
+
+	_, err = serverTLSConfig(TLSSettings{MinVersion: "1.0"}, reloader)
+	assert.Error(t, err)
+}
+
+func TestServerTLSConfigMutualTLS(t *testing.T) {
+	ca := newTestCert(t, 10, nil, true)
+	caPEM, _ := ca.pem(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0600))
+
+	reloader, _, _ := startReloader(t, newTestCert(t, 1, nil, false))
+	config, err := serverTLSConfig(TLSSettings{ClientCAFile: caFile}, reloader)
+	require.NoError(t, err)
+	addr := serveTLS(t, config)
+
+	// TLS 1.2 so a rejected client certificate fails the client's handshake
+	client := &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12}
+	_, err = tls.Dial("tcp", addr, client)
+	assert.Error(t, err, "handshake without a client certificate succeeded")
+
+	stranger := newTestCert(t, 11, nil, false)
+	client.Certificates = []tls.Certificate{stranger.tlsCertificate(t)}
+	_, err = tls.Dial("tcp", addr, client)
+	assert.Error(t, err, "handshake with an untrusted client certificate succeeded")
+
+	trusted := newTestCert(t, 12, ca, false)
+	client.Certificates = []tls.Certificate{trusted.tlsCertificate(t)}
+	conn, err := tls.Dial("tcp", addr, client)
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// applyDependenciesPrompt is where the go-ahead to apply dependencies first
+// is read from; tests replace it
+var applyDependenciesPrompt io.Reader = os.Stdin
+
+// unappliedDependencies returns the modules the module at ctx.WorkingDir
+// depends on, directly or through other dependencies, that have no state
+// yet, in the order they have to be applied
+func unappliedDependencies(ctx *ExecutionContext) ([]string, error) {
+	graph := make(map[string][]string)
+	pending := []string{ctx.WorkingDir}
+	for len(pending) > 0 {
+		module := pending[0]
+		pending = pending[1:]
+		if _, ok := graph[module]; ok {
+			continue
+		}
+		deps, err := moduleDependencies(ctx, module)
+		if err != nil {
+			return nil, err
+		}
+		graph[module] = deps
+		pending = append(pending, deps...)
+	}
+
+	order, err := topologicalSort(graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to order dependencies: %w", err)
+	}
+
+	var unapplied []string
+	for _, module := range order {
+		if module == ctx.WorkingDir {
+			continue
+		}
+		hasState, err := moduleHasState(ctx, module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check state of %s: %w", module, err)
+		}
+		if !hasState {
+			unapplied = append(unapplied, module)
+		}
+	}
+	return unapplied, nil
+}
+
+// applyDependencies applies the unapplied dependencies of the module at
+// ctx.WorkingDir one at a time, stopping at the first failure. Unless
+// autoApprove, the list is confirmed first; a non-interactive run can't
+// confirm, so it fails naming the modules instead.
+func applyDependencies(ctx *ExecutionContext, autoApprove bool) error {
+	unapplied, err := unappliedDependencies(ctx)
+	if err != nil {
+		return err
+	}
+	if len(unapplied) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(unapplied))
+	for i, module := range unapplied {
+		names[i] = relativeModulePath(ctx.WorkingDir, module)
+	}
+	logger.Infof("Dependencies without state will be applied first: %s", strings.Join(names, ", "))
+
+	if !autoApprove && ctx.Config.NonInteractive {
+		return fmt.Errorf("%d dependencies have not been applied (%s); pass --auto-approve to apply them non-interactively", len(unapplied), strings.Join(names, ", "))
+	}
+	if !autoApprove {
+		fmt.Fprintf(os.Stderr, "Apply %d dependencies before %s? [y/N]: ", len(unapplied), filepath.Base(ctx.WorkingDir))
+		answer, _ := bufio.NewReader(applyDependenciesPrompt).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		default:
+			return fmt.Errorf("apply cancelled: %d dependencies have not been applied", len(unapplied))
+		}
+	}
+
+	for i, module := range unapplied {
+		logger.Infof("Applying dependency %s", names[i])
+		moduleCtx, err := newModuleContext(ctx, module)
+		if err != nil {
+			return fmt.Errorf("dependency %s: %w", names[i], err)
+		}
+		if err := prepareTerraformDir(moduleCtx); err != nil {
+			return fmt.Errorf("dependency %s: %w", names[i], err)
+		}
+		if err := executeTerraform(moduleCtx, "apply", "-auto-approve"); err != nil {
+			return fmt.Errorf("failed to apply dependency %s: %w", names[i], err)
+		}
+	}
+	return nil
+}
+
+// relativeModulePath returns module relative to from for log messages
+func relativeModulePath(from, module string) string {
+	if rel, err := filepath.Rel(from, module); err == nil {
+		return rel
+	}
+	return module
+}
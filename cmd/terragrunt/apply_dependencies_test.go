@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const appliedState = `{"version": 4, "resources": [{"type": "google_compute_network", "name": "main"}]}`
+
+// writeApplyDependenciesTree creates app depending on subnet and db, with
+// subnet depending on vpc. Only db has been applied.
+func writeApplyDependenciesTree(t *testing.T) (*ExecutionContext, string) {
+	t.Helper()
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", "")
+	newDependencyModule(t, root, "subnet", "dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n")
+	newDependencyModule(t, root, "db", "")
+	require.NoError(t, os.WriteFile(filepath.Join(root, "db", "terraform.tfstate"), []byte(appliedState), 0644))
+	newDependencyModule(t, root, "app", "dependency \"subnet\" {\n  config_path = \"../subnet\"\n}\n\ndependency \"db\" {\n  config_path = \"../db\"\n}\n")
+
+	config := defaultTerragruntConfig()
+	ctx := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "app"), Environment: map[string]string{}, shared: &runState{}}
+	return ctx, root
+}
+
+// applyLoggingTerraform writes a terraform stand-in that logs the module it
+// ran in and leaves state behind like a real apply
+func applyLoggingTerraform(t *testing.T) (string, string) {
+	t.Helper()
+	log := filepath.Join(t.TempDir(), "apply.log")
+	terraform := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\nbasename \"$PWD\" >> " + log + "\necho '" + appliedState + "' > terraform.tfstate\n"
+	require.NoError(t, os.WriteFile(terraform, []byte(script), 0755))
+	return terraform, log
+}
+
+func TestUnappliedDependencies(t *testing.T) {
+	ctx, root := writeApplyDependenciesTree(t)
+
+	unapplied, err := unappliedDependencies(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "vpc"), filepath.Join(root, "subnet")}, unapplied)
+}
+
+func TestApplyDependenciesAppliesUpstreamFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	ctx, _ := writeApplyDependenciesTree(t)
+	terraform, log := applyLoggingTerraform(t)
+	ctx.Config.TerraformPath = terraform
+
+	original := applyDependenciesPrompt
+	applyDependenciesPrompt = strings.NewReader("y\n")
+	t.Cleanup(func() { applyDependenciesPrompt = original })
+
+	require.NoError(t, applyDependencies(ctx, false))
+	require.NoError(t, executeTerraform(ctx, "apply", "-auto-approve"))
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vpc", "subnet", "app"}, strings.Fields(string(data)))
+
+	// Once applied, nothing is left to bring up
+	unapplied, err := unappliedDependencies(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, unapplied)
+}
+
+func TestApplyDependenciesDeclined(t *testing.T) {
+	ctx, _ := writeApplyDependenciesTree(t)
+	terraform, log := applyLoggingTerraform(t)
+	ctx.Config.TerraformPath = terraform
+
+	original := applyDependenciesPrompt
+	applyDependenciesPrompt = strings.NewReader("n\n")
+	t.Cleanup(func() { applyDependenciesPrompt = original })
+
+	err := applyDependencies(ctx, false)
+	assert.EqualError(t, err, "apply cancelled: 2 dependencies have not been applied")
+	_, err = os.Stat(log)
+	assert.True(t, os.IsNotExist(err), "nothing should be applied")
+}
+
+func TestApplyDependenciesNonInteractiveNeedsAutoApprove(t *testing.T) {
+	ctx, _ := writeApplyDependenciesTree(t)
+	terraform, log := applyLoggingTerraform(t)
+	ctx.Config.TerraformPath = terraform
+	ctx.Config.NonInteractive = true
+
+	err := applyDependencies(ctx, false)
+	assert.EqualError(t, err, "2 dependencies have not been applied (../vpc, ../subnet); pass --auto-approve to apply them non-interactively")
+	_, err = os.Stat(log)
+	assert.True(t, os.IsNotExist(err), "nothing should be applied")
+}
+
+func TestApplyDependenciesStopsAtFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	ctx, _ := writeApplyDependenciesTree(t)
+	terraform := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(terraform, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	ctx.Config.TerraformPath = terraform
+	ctx.Config.RetryAttempts = 0
+
+	err := applyDependencies(ctx, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply dependency ../vpc")
+}
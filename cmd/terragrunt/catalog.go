@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// catalogTemplateSuffix marks the files of a catalog template that are
+// rendered; the suffix is dropped from the file written
+const catalogTemplateSuffix = ".tmpl"
+
+// catalogTemplatesDir is the conventional directory holding templates inside
+// a catalog; catalogs without it expose their top-level directories instead
+const catalogTemplatesDir = "templates"
+
+// templateVars are substituted into catalog templates
+type templateVars struct {
+	Name    string
+	Project string
+}
+
+// catalogCacheDir returns the local cache directory for a catalog URL
+func catalogCacheDir(ctx *ExecutionContext, catalogURL string) string {
+	sum := sha256.Sum256([]byte(catalogURL))
+	return filepath.Join(downloadDir(ctx), "catalog", hex.EncodeToString(sum[:])[:16])
+}
+
+// downloadDir is where remote configurations are downloaded, ~/.terragrunt
+// unless --terragrunt-download-dir says otherwise
+func downloadDir(ctx *ExecutionContext) string {
+	if ctx.Config.DownloadDir != "" {
+		if dir, err := filepath.Abs(ctx.Config.DownloadDir); err == nil {
+			return dir
+		}
+		return ctx.Config.DownloadDir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".terragrunt")
+}
+
+// fetchCatalog downloads (or refreshes) a catalog into the local cache and
+// returns its directory. Supported sources are git repositories and gs:// paths.
+func fetchCatalog(ctx *ExecutionContext, catalogURL string) (string, error) {
+	dir := catalogCacheDir(ctx, catalogURL)
+
+	if strings.HasPrefix(catalogURL, "gs://") {
+		if err := fetchGCSCatalog(context.Background(), catalogURL, dir); err != nil {
+			return "", fmt.Errorf("failed to fetch catalog %s: %w", catalogURL, err)
+		}
+		return dir, nil
+	}
+
+	if err := fetchGitCatalog(strings.TrimPrefix(catalogURL, "git::"), dir); err != nil {
+		return "", fmt.Errorf("failed to fetch catalog %s: %w", catalogURL, err)
+	}
+	return dir, nil
+}
+
+func fetchGitCatalog(repoURL, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		logger.Infof("Updating cached catalog in %s", dir)
+		cmd := exec.Command("git", "-C", dir, "pull", "--ff-only", "--quiet")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			// A stale cache is still usable offline
+			logger.Warnf("Failed to update catalog cache, using cached copy: %s", strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+
+	logger.Infof("Cloning catalog %s", repoURL)
+	cmd := exec.Command("git", "clone", "--depth", "1", "--quiet", repoURL, dir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func fetchGCSCatalog(ctx context.Context, catalogURL, dir string) error {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(catalogURL, "gs://"), "/")
+	if bucket == "" {
+		return fmt.Errorf("invalid GCS catalog path: %s", catalogURL)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	it := client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(attrs.Name, "/") {
+			continue
+		}
+
+		rel := strings.TrimPrefix(attrs.Name, prefix)
+		dest := filepath.Join(dir, filepath.FromSlash(rel))
+		if !strings.HasPrefix(dest, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("illegal object path: %s", attrs.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		reader, err := client.Bucket(bucket).Object(attrs.Name).NewReader(ctx)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// catalogRoot returns the directory that holds the catalog's templates
+func catalogRoot(dir string) string {
+	if info, err := os.Stat(filepath.Join(dir, catalogTemplatesDir)); err == nil && info.IsDir() {
+		return filepath.Join(dir, catalogTemplatesDir)
+	}
+	return dir
+}
+
+// listCatalogTemplates returns the sorted template names available in a catalog
+func listCatalogTemplates(dir string) ([]string, error) {
+	entries, err := os.ReadDir(catalogRoot(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			templates = append(templates, entry.Name())
+		}
+	}
+	sort.Strings(templates)
+	return templates, nil
+}
+
+// renderCatalogTemplate copies a catalog template into dest. Files ending in
+// .tmpl are rendered with text/template so {{ .Name }} and {{ .Project }} are
+// substituted, and written without the suffix; the rest are copied as they
+// are, since terraform files and binaries may hold {{ of their own.
+func renderCatalogTemplate(catalogDir, templateName, dest string, vars templateVars) error {
+	if templateName == "" || templateName == "." || strings.Contains(templateName, "..") || strings.ContainsAny(templateName, `/\`) {
+		return fmt.Errorf("invalid template name %q", templateName)
+	}
+	src := filepath.Join(catalogRoot(catalogDir), templateName)
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		available, _ := listCatalogTemplates(catalogDir)
+		return fmt.Errorf("template %q not found in catalog (available: %s)", templateName, strings.Join(available, ", "))
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+
+		if !strings.HasSuffix(rel, catalogTemplateSuffix) {
+			return copyFile(path, target)
+		}
+		target = strings.TrimSuffix(target, catalogTemplateSuffix)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		tmpl, err := template.New(rel).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse template file %s: %w", rel, err)
+		}
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, vars); err != nil {
+			return fmt.Errorf("failed to render template file %s: %w", rel, err)
+		}
+
+		return os.WriteFile(target, rendered.Bytes(), info.Mode().Perm())
+	})
+}
+
+// scaffoldFromCatalog materializes a module from a remote catalog template
+func scaffoldFromCatalog(ctx *ExecutionContext, catalogURL, templateName, path string, vars templateVars, list bool) error {
+	catalogDir, err := fetchCatalog(ctx, catalogURL)
+	if err != nil {
+		return err
+	}
+
+	if list {
+		templates, err := listCatalogTemplates(catalogDir)
+		if err != nil {
+			return fmt.Errorf("failed to list catalog templates: %w", err)
+		}
+		for _, name := range templates {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create module directory: %w", err)
+	}
+
+	if err := renderCatalogTemplate(catalogDir, templateName, path, vars); err != nil {
+		return err
+	}
+
+	logger.Infof("Module scaffolded from %s (template %s) at %s", catalogURL, templateName, path)
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitCatalog creates a local git repository holding two templates
+func newGitCatalog(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	files := map[string]string{
+		"templates/gce/main.tf.tmpl":        "resource \"google_compute_instance\" \"{{ .Name }}\" {\n  project = \"{{ .Project }}\"\n}\n",
+		"templates/gce/terragrunt.hcl.tmpl": "inputs = {\n  name = \"{{ .Name }}\"\n}\n",
+		"templates/gce/startup.sh":          "echo \"{{ .not_a_template_var }}\"\n",
+		"templates/gcs/main.tf.tmpl":        "resource \"google_storage_bucket\" \"{{ .Name }}\" {}\n",
+		"templates/gcs/modules/README.tmpl": "nested file for {{ .Name }}\n",
+		"README.md":                         "catalog readme\n",
+	}
+	for rel, content := range files {
+		path := filepath.Join(repo, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "--quiet", "-m", "catalog"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	return repo
+}
+
+func TestScaffoldFromGitCatalog(t *testing.T) {
+	catalog := newGitCatalog(t)
+	ctx := &ExecutionContext{Config: &TerragruntConfig{DownloadDir: t.TempDir()}, Logger: logger}
+
+	catalogDir, err := fetchCatalog(ctx, catalog)
+	require.NoError(t, err)
+	assert.Equal(t, catalogCacheDir(ctx, catalog), catalogDir)
+
+	templates, err := listCatalogTemplates(catalogDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gce", "gcs"}, templates)
+
+	dest := filepath.Join(t.TempDir(), "web")
+	vars := templateVars{Name: "web", Project: "acme-prod"}
+	require.NoError(t, scaffoldFromCatalog(ctx, catalog, "gce", dest, vars, false))
+
+	mainTF, err := os.ReadFile(filepath.Join(dest, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "resource \"google_compute_instance\" \"web\" {\n  project = \"acme-prod\"\n}\n", string(mainTF))
+
+	hcl, err := os.ReadFile(filepath.Join(dest, "terragrunt.hcl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(hcl), `name = "web"`)
+
+	// Files without the .tmpl suffix are copied untouched
+	script, err := os.ReadFile(filepath.Join(dest, "startup.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo \"{{ .not_a_template_var }}\"\n", string(script))
+	assert.NoFileExists(t, filepath.Join(dest, "main.tf.tmpl"))
+
+	// Fetching again reuses the cache
+	again, err := fetchCatalog(ctx, catalog)
+	require.NoError(t, err)
+	assert.Equal(t, catalogDir, again)
+}
+
+func TestScaffoldFromCatalogNestedAndUnknownTemplate(t *testing.T) {
+	catalog := newGitCatalog(t)
+	ctx := &ExecutionContext{Config: &TerragruntConfig{DownloadDir: t.TempDir()}, Logger: logger}
+
+	dest := filepath.Join(t.TempDir(), "bucket")
+	require.NoError(t, scaffoldFromCatalog(ctx, catalog, "gcs", dest, templateVars{Name: "logs"}, false))
+
+	nested, err := os.ReadFile(filepath.Join(dest, "modules", "README"))
+	require.NoError(t, err)
+	assert.Equal(t, "nested file for logs\n", string(nested))
+
+	err = scaffoldFromCatalog(ctx, catalog, "gke", filepath.Join(t.TempDir(), "x"), templateVars{Name: "x"}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gce, gcs")
+}
+
+func TestScaffoldFromCatalogRejectsTemplatePaths(t *testing.T) {
+	catalog := newGitCatalog(t)
+	ctx := &ExecutionContext{Config: &TerragruntConfig{DownloadDir: t.TempDir()}, Logger: logger}
+
+	for _, name := range []string{"..", "../templates/gce", "gce/../gcs", "gce/sub", `gce\sub`, ""} {
+		err := scaffoldFromCatalog(ctx, catalog, name, filepath.Join(t.TempDir(), "x"), templateVars{Name: "x"}, false)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "invalid template name", name)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove .terraform directories, generated files and caches",
+	Long: `Remove the local artifacts of every module under the working directory:
+.terraform and .terragrunt-cache directories, files terragrunt generated, input
+snapshots and cached outputs. Source .tf and .hcl files are never removed.`,
+	RunE: runClean,
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	lockFiles, _ := cmd.Flags().GetBool("lock-file")
+
+	removed, err := cleanModules(ctx, lockFiles, dryRun)
+	if dryRun {
+		for _, path := range removed {
+			fmt.Println(path)
+		}
+		logger.Infof("Would remove %d path(s)", len(removed))
+	} else {
+		logger.Infof("Removed %d path(s)", len(removed))
+	}
+	return err
+}
+
+// cleanModules removes the artifacts of every module findModules returns,
+// or only lists them when dryRun is set
+func cleanModules(ctx *ExecutionContext, lockFiles, dryRun bool) ([]string, error) {
+	modules, err := findModules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find modules: %w", err)
+	}
+
+	targets, err := cleanTargets(ctx, modules, lockFiles)
+	if err != nil || dryRun {
+		return targets, err
+	}
+
+	for i, path := range targets {
+		if err := os.RemoveAll(path); err != nil {
+			return targets[:i], fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		logger.Debugf("Removed %s", path)
+	}
+	return targets, nil
+}
+
+// cleanTargets returns the existing artifacts of modules, sorted
+func cleanTargets(ctx *ExecutionContext, modules []string, lockFiles bool) ([]string, error) {
+	var targets []string
+	addIfExists := func(path string) {
+		if _, err := os.Lstat(path); err == nil {
+			targets = append(targets, path)
+		}
+	}
+
+	for _, module := range modules {
+		addIfExists(filepath.Join(module, ".terraform"))
+		addIfExists(filepath.Join(module, terragruntCacheDir))
+		if lockFiles {
+			addIfExists(filepath.Join(module, ".terraform.lock.hcl"))
+		}
+		addIfExists(filepath.Join(module, inputsSnapshotFile))
+
+		// Generated files are only ever known through the manifest
+		generated, err := readGeneratedManifest(module)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", module, err)
+		}
+		for _, path := range generated {
+			path = filepath.Clean(path)
+			if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(os.PathSeparator)) {
+				logger.Warnf("Not removing %s listed in %s: outside the module", path, filepath.Join(module, generatedManifestFile))
+				continue
+			}
+			addIfExists(filepath.Join(module, path))
+		}
+		addIfExists(filepath.Join(module, generatedManifestFile))
+
+		if ctx.Config.Cache.Dir != "" {
+			addIfExists(filepath.Join(ctx.Config.Cache.Dir, fmt.Sprintf("%s-outputs.json", module)))
+		}
+	}
+
+	sort.Strings(targets)
+	return targets, nil
+}
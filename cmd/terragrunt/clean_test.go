@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCleanTree creates modules app and vendor/lib with terraform and
+// terragrunt artifacts next to their sources
+func writeCleanTree(t *testing.T) (*ExecutionContext, string) {
+	t.Helper()
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	for _, module := range []string{"app", filepath.Join("vendor", "lib")} {
+		dir := filepath.Join(root, module)
+		files := map[string]string{
+			"terragrunt.hcl":            "",
+			"main.tf":                   "",
+			"backend.tf":                "# generated",
+			".terraform.lock.hcl":       "",
+			".terraform/modules/m.json": "{}",
+			inputsSnapshotFile:          "{}",
+			generatedManifestFile:       `{"files": ["backend.tf", "../main.tf", "missing.tf"]}`,
+		}
+		for name, contents := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+		}
+
+		cached := filepath.Join(cacheDir, dir+"-outputs.json")
+		require.NoError(t, os.MkdirAll(filepath.Dir(cached), 0755))
+		require.NoError(t, os.WriteFile(cached, []byte("{}"), 0644))
+	}
+
+	// A source file the manifests point at from outside their module
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.tf"), nil, 0644))
+
+	config := defaultTerragruntConfig()
+	config.Cache.Dir = cacheDir
+	config.ExcludeDirs = []string{"vendor"}
+	return &ExecutionContext{Config: config, WorkingDir: root}, root
+}
+
+func TestCleanModulesDryRun(t *testing.T) {
+	ctx, root := writeCleanTree(t)
+	app := filepath.Join(root, "app")
+
+	targets, err := cleanModules(ctx, false, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(ctx.Config.Cache.Dir, app+"-outputs.json"),
+		filepath.Join(app, ".terraform"),
+		filepath.Join(app, ".terragrunt-generated.json"),
+		filepath.Join(app, inputsSnapshotFile),
+		filepath.Join(app, "backend.tf"),
+	}, targets)
+
+	for _, path := range targets {
+		_, err := os.Lstat(path)
+		assert.NoError(t, err, "dry run removed %s", path)
+	}
+}
+
+func TestCleanModulesRemovesArtifactsOnly(t *testing.T) {
+	ctx, root := writeCleanTree(t)
+	app := filepath.Join(root, "app")
+	lib := filepath.Join(root, "vendor", "lib")
+
+	targets, err := cleanModules(ctx, true, false)
+	require.NoError(t, err)
+	assert.Contains(t, targets, filepath.Join(app, ".terraform.lock.hcl"))
+
+	for _, path := range targets {
+		assert.NoFileExists(t, path)
+		assert.NoDirExists(t, path)
+	}
+
+	// Sources, and everything in excluded modules, stay
+	assert.FileExists(t, filepath.Join(app, "terragrunt.hcl"))
+	assert.FileExists(t, filepath.Join(app, "main.tf"))
+	assert.FileExists(t, filepath.Join(root, "main.tf"), "manifest entries outside the module are ignored")
+	assert.DirExists(t, filepath.Join(lib, ".terraform"))
+	assert.FileExists(t, filepath.Join(lib, "backend.tf"))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// runnerCommand is an external command for a commandRunner to run
+type runnerCommand struct {
+	Path string
+	Args []string
+	Dir  string
+	// Env replaces the environment when set
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// commandRunner finds and runs external commands. Failures that ran to
+// completion carry their exit code through an ExitCode() int method, as
+// *exec.ExitError does.
+type commandRunner interface {
+	LookPath(file string) (string, error)
+	Run(ctx context.Context, command runnerCommand) error
+}
+
+// terraformRunner runs terraform; tests replace it with a fake that records
+// the arguments and returns canned output
+var terraformRunner commandRunner = execRunner{}
+
+// execRunner runs commands as processes
+type execRunner struct{}
+
+func (execRunner) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+func (execRunner) Run(ctx context.Context, command runnerCommand) error {
+	cmd := exec.CommandContext(ctx, command.Path, command.Args...)
+	// Let terraform release state locks when the run is interrupted
+	cmd.Cancel = func() error { return interruptProcess(cmd.Process) }
+	cmd.WaitDelay = 30 * time.Second
+	cmd.Dir = command.Dir
+	cmd.Env = command.Env
+	cmd.Stdin = command.Stdin
+	cmd.Stdout = command.Stdout
+	cmd.Stderr = command.Stderr
+	return cmd.Run()
+}
+
+// interruptProcess asks process to stop with one SIGINT, unless Ctrl-C at
+// the terminal already delivered it: terraform takes a second SIGINT as a
+// request to abort at once, without releasing the state lock
+func interruptProcess(process *os.Process) error {
+	if interruptedAtTerminal.Load() {
+		return nil
+	}
+	return process.Signal(os.Interrupt)
+}
+
+// runnerOutput runs command with runner and returns its standard output
+func runnerOutput(ctx context.Context, runner commandRunner, command runnerCommand) ([]byte, error) {
+	var stdout bytes.Buffer
+	command.Stdout = &stdout
+	err := runner.Run(ctx, command)
+	return stdout.Bytes(), err
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExitError is a failed run with an exit code, like *exec.ExitError
+type fakeExitError int
+
+func (e fakeExitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e fakeExitError) ExitCode() int { return int(e) }
+
+// fakeRunner records the commands it is asked to run and answers them with
+// canned output, keyed by terraform subcommand
+type fakeRunner struct {
+	mu       sync.Mutex
+	calls    []runnerCommand
+	stdout   map[string]string
+	exitCode map[string]int
+}
+
+func (f *fakeRunner) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+func (f *fakeRunner) Run(_ context.Context, command runnerCommand) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, command)
+	f.mu.Unlock()
+
+	subcommand := ""
+	if len(command.Args) > 0 {
+		subcommand = command.Args[0]
+	}
+	if out, ok := f.stdout[subcommand]; ok && command.Stdout != nil {
+		io.WriteString(command.Stdout, out)
+	}
+	if code := f.exitCode[subcommand]; code != 0 {
+		return fakeExitError(code)
+	}
+	return nil
+}
+
+// argv returns the arguments of every command run so far
+func (f *fakeRunner) argv() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var argv [][]string
+	for _, call := range f.calls {
+		argv = append(argv, call.Args)
+	}
+	return argv
+}
+
+// useFakeRunner swaps terraformRunner for a fake until the test ends
+func useFakeRunner(t *testing.T) *fakeRunner {
+	t.Helper()
+	fake := &fakeRunner{stdout: map[string]string{}, exitCode: map[string]int{}}
+	original := terraformRunner
+	terraformRunner = fake
+	t.Cleanup(func() { terraformRunner = original })
+	return fake
+}
+
+// newModuleCommand returns command run against a fresh, empty module
+func newModuleCommand(t *testing.T, command *cobra.Command, run func(*cobra.Command, []string) error) (*cobra.Command, string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), nil, 0644))
+
+	// The command's flags are shared with the real command, so put them
+	// back to their defaults afterwards
+	resetFlags(command.Flags())
+	t.Cleanup(func() { resetFlags(command.Flags()) })
+	cmd := &cobra.Command{Use: command.Use, RunE: run}
+	cmd.Flags().AddFlagSet(command.Flags())
+	// Global flags are persistent on the root command, where the command's
+	// own flags shadow them
+	global := pflag.NewFlagSet("global", pflag.ContinueOnError)
+	registerGlobalFlags(global)
+	global.VisitAll(func(flag *pflag.Flag) {
+		if cmd.Flags().Lookup(flag.Name) != nil {
+			return
+		}
+		if flag.Shorthand != "" && cmd.Flags().ShorthandLookup(flag.Shorthand) != nil {
+			flag.Shorthand = ""
+		}
+		cmd.Flags().AddFlag(flag)
+	})
+	require.NoError(t, cmd.Flags().Set("terragrunt-working-dir", dir))
+	return cmd, dir
+}
+
+func resetFlags(flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
+
+func TestPlanArgvWithFakeRunner(t *testing.T) {
+	withPartialParseCache(t)
+	fake := useFakeRunner(t)
+	cmd, dir := newModuleCommand(t, planCmd, runPlan)
+	require.NoError(t, cmd.Flags().Set("out", "tfplan"))
+	require.NoError(t, cmd.Flags().Set("target", "google_compute_network.main"))
+	require.NoError(t, cmd.Flags().Set("var", "region=europe-west1"))
+	require.NoError(t, cmd.Flags().Set("refresh-only", "true"))
+
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	assert.Equal(t, [][]string{
+		{"init", "-input=false"},
+		{"plan", "-out=tfplan", "-refresh-only", "-target=google_compute_network.main", "-var=region=europe-west1"},
+	}, fake.argv())
+	for _, call := range fake.calls {
+		assert.Equal(t, "terraform", call.Path)
+		assert.Equal(t, dir, call.Dir)
+	}
+}
+
+func TestApplyArgvWithFakeRunner(t *testing.T) {
+	withPartialParseCache(t)
+	fake := useFakeRunner(t)
+	fake.stdout["output"] = `{"network_id": {"value": "projects/acme/global/networks/main"}}`
+	cmd, _ := newModuleCommand(t, applyCmd, runApply)
+	require.NoError(t, cmd.Flags().Set("terragrunt-no-auto-init", "true"))
+	require.NoError(t, cmd.Flags().Set("auto-approve", "true"))
+	require.NoError(t, cmd.Flags().Set("replace", "google_compute_instance.web"))
+	require.NoError(t, cmd.Flags().Set("var-file", "prod.tfvars"))
+
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	assert.Equal(t, [][]string{
+		{"apply", "-auto-approve", "-parallelism=10", "-replace=google_compute_instance.web", "-var-file=prod.tfvars"},
+		{"output", "-json"},
+	}, fake.argv())
+}
+
+func TestApplyFailureWithFakeRunner(t *testing.T) {
+	withPartialParseCache(t)
+	fake := useFakeRunner(t)
+	fake.exitCode["apply"] = 1
+	cmd, _ := newModuleCommand(t, applyCmd, runApply)
+	require.NoError(t, cmd.Flags().Set("terragrunt-no-auto-init", "true"))
+	require.NoError(t, cmd.Flags().Set("terragrunt-non-interactive", "true"))
+
+	err := cmd.RunE(cmd, []string{"saved.tfplan"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "terraform apply failed: exit status 1")
+	assert.Equal(t, [][]string{{"apply", "-auto-approve", "-parallelism=10", "saved.tfplan"}}, fake.argv(), "outputs are not saved after a failed apply")
+}
+
+func TestTerraformVersionWithFakeRunner(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.stdout["version"] = `{"terraform_version": "1.7.5", "platform": "linux_amd64"}`
+
+	assert.Equal(t, "1.7.5", getTerraformVersion())
+	assert.Equal(t, [][]string{{"version", "-json"}}, fake.argv())
+}
+
+// lockedBuffer is a bytes.Buffer a running process can write to while the
+// test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestInterruptProcessSignalsOnlyOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh and POSIX signals")
+	}
+	t.Cleanup(func() { interruptedAtTerminal.Store(false) })
+	run := func() (string, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var stdout lockedBuffer
+		done := make(chan error, 1)
+		go func() {
+			done <- execRunner{}.Run(ctx, runnerCommand{
+				Path:   "sh",
+				Args:   []string{"-c", `trap 'echo interrupted; exit 3' INT; echo started; while :; do sleep 0.05; done`},
+				Stdout: &stdout,
+			})
+		}()
+		require.Eventually(t, func() bool { return strings.Contains(stdout.String(), "started") }, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		select {
+		case err := <-done:
+			return stdout.String(), err
+		case <-time.After(2 * time.Second):
+			return stdout.String(), nil
+		}
+	}
+
+	// A cancelled run gets one SIGINT to stop on
+	out, err := run()
+	assert.Contains(t, out, "interrupted")
+	assert.Error(t, err)
+
+	// Ctrl-C at a terminal has reached terraform already, so it is left
+	// to finish on its own
+	interruptedAtTerminal.Store(true)
+	cmd := exec.Command("sleep", "5")
+	require.NoError(t, cmd.Start())
+	defer cmd.Process.Kill()
+	require.NoError(t, interruptProcess(cmd.Process))
+	exited := make(chan struct{})
+	go func() { cmd.Wait(); close(exited) }()
+	select {
+	case <-exited:
+		t.Fatal("the process was signalled")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
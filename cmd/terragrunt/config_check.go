@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// supportedBackends are the backend types terragrunt can configure
+var supportedBackends = []string{"gcs"}
+
+// withConfigCheck runs the --terragrunt-check validation pass instead of
+// the command when the flag is set
+func withConfigCheck(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if check, _ := cmd.Flags().GetBool("terragrunt-check"); check {
+			return runConfigCheck(cmd)
+		}
+		return run(cmd, args)
+	}
+}
+
+// addConfigCheck wraps the RunE of cmd and its subcommands with withConfigCheck
+func addConfigCheck(cmd *cobra.Command) {
+	if cmd.RunE != nil {
+		cmd.RunE = withConfigCheck(cmd.RunE)
+	}
+	for _, sub := range cmd.Commands() {
+		addConfigCheck(sub)
+	}
+}
+
+func runConfigCheck(cmd *cobra.Command) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+	if err := checkConfiguration(ctx); err != nil {
+		return err
+	}
+	logger.Info("Configuration check passed")
+	return nil
+}
+
+// checkConfiguration validates the resolved config without running
+// terraform and reports every problem found in one error
+func checkConfiguration(ctx *ExecutionContext) error {
+	problems := configProblems(ctx)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("configuration check found %d problem(s):\n  - %s", len(problems), strings.Join(problems, "\n  - "))
+}
+
+func configProblems(ctx *ExecutionContext) []string {
+	config := ctx.Config
+	var problems []string
+
+	if config.TerraformPath == "" {
+		problems = append(problems, "terraform_path must not be empty")
+	}
+	if config.Parallelism < 1 {
+		problems = append(problems, fmt.Sprintf("parallelism must be at least 1, got %d", config.Parallelism))
+	}
+
+	if config.Backend.Type != "" {
+		if !isSupportedBackend(config.Backend.Type) {
+			problems = append(problems, fmt.Sprintf("backend: unknown type %q (supported: %s)", config.Backend.Type, strings.Join(supportedBackends, ", ")))
+		} else if config.Backend.Type == "gcs" && config.Backend.Bucket == "" {
+			problems = append(problems, "backend: gcs backend requires a bucket")
+		}
+	}
+	if config.OnlyWithState && config.OnlyWithoutState {
+		problems = append(problems, "only_with_state and only_without_state are mutually exclusive")
+	}
+	if config.FailFast && config.ContinueOnError {
+		problems = append(problems, "fail_fast and continue_on_error are mutually exclusive")
+	}
+	if config.RemoteState.Backend != "" && !isSupportedBackend(config.RemoteState.Backend) {
+		problems = append(problems, fmt.Sprintf("remote_state: unknown backend %q (supported: %s)", config.RemoteState.Backend, strings.Join(supportedBackends, ", ")))
+	}
+
+	for _, dep := range config.Dependencies {
+		for _, problem := range validateMockOutputs(dep) {
+			problems = append(problems, fmt.Sprintf("dependency %q: mock outputs: %s", dep.Name, problem))
+		}
+		if dep.ConfigPath == "" && dep.Path == "" {
+			problems = append(problems, fmt.Sprintf("dependency %q: config_path is required", dep.Name))
+			continue
+		}
+		dir := dependencyDir(ctx, dep)
+		if _, err := os.Stat(dir); err != nil {
+			problems = append(problems, fmt.Sprintf("dependency %q: %s does not exist", dep.Name, dir))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "terragrunt.hcl")); err != nil {
+			problems = append(problems, fmt.Sprintf("dependency %q: no terragrunt.hcl in %s", dep.Name, dir))
+		}
+	}
+
+	if _, err := desiredGeneratedFiles(config); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := generatedIfExists(config); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	hooks := map[string][]HookConfig{
+		"before_hook": config.Hooks.BeforeHooks,
+		"after_hook":  config.Hooks.AfterHooks,
+		"error_hook":  config.Hooks.ErrorHooks,
+	}
+	for _, kind := range []string{"before_hook", "after_hook", "error_hook"} {
+		for _, hook := range hooks[kind] {
+			if len(hook.Execute) == 0 {
+				problems = append(problems, fmt.Sprintf("%s %q: execute is required", kind, hook.Name))
+			}
+			if hook.WorkingDir != "" {
+				if _, err := os.Stat(hook.WorkingDir); err != nil {
+					problems = append(problems, fmt.Sprintf("%s %q: working_dir %s does not exist", kind, hook.Name, hook.WorkingDir))
+				}
+			}
+		}
+	}
+
+	if config.LabelPolicy.Enabled {
+		keys := make([]string, 0, len(config.LabelPolicy.Patterns))
+		for key := range config.LabelPolicy.Patterns {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, err := config.LabelPolicy.valuePattern(key); err != nil {
+				problems = append(problems, "label_policy: "+err.Error())
+			}
+		}
+	}
+
+	return problems
+}
+
+func isSupportedBackend(backend string) bool {
+	for _, supported := range supportedBackends {
+		if backend == supported {
+			return true
+		}
+	}
+	return false
+}
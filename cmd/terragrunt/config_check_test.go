@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkContext loads hcl as the terragrunt.hcl of root/app
+func checkContext(t *testing.T, root, hcl string) *ExecutionContext {
+	t.Helper()
+	dir := filepath.Join(root, "app")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(hcl), 0644))
+
+	config := defaultTerragruntConfig()
+	require.NoError(t, loadConfigFile(path, "auto", config))
+	return &ExecutionContext{Config: config, WorkingDir: dir}
+}
+
+func TestCheckConfigurationValid(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "vpc"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "vpc", "terragrunt.hcl"), nil, 0644))
+
+	ctx := checkContext(t, root, `
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+`)
+	assert.NoError(t, checkConfiguration(ctx))
+}
+
+func TestCheckConfigurationReportsProblems(t *testing.T) {
+	root := t.TempDir()
+	ctx := checkContext(t, root, `
+backend {
+  type = "s3"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+
+generate "provider" {
+  contents = "provider \"google\" {}"
+}
+`)
+
+	err := checkConfiguration(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "configuration check found 3 problem(s)")
+	assert.Contains(t, err.Error(), `backend: unknown type "s3" (supported: gcs)`)
+	assert.Contains(t, err.Error(), `dependency "vpc": `+filepath.Join(root, "vpc")+" does not exist")
+	assert.Contains(t, err.Error(), `generate block "provider" has no path`)
+}
+
+func TestWithConfigCheckSkipsCommand(t *testing.T) {
+	ran := false
+	cmd := &cobra.Command{Use: "plan", RunE: func(*cobra.Command, []string) error {
+		ran = true
+		return nil
+	}}
+	registerGlobalFlags(cmd.Flags())
+	addConfigCheck(cmd)
+
+	require.NoError(t, cmd.Flags().Set("terragrunt-working-dir", t.TempDir()))
+	require.NoError(t, cmd.Flags().Set("terragrunt-check", "true"))
+	require.NoError(t, cmd.RunE(cmd, nil))
+	assert.False(t, ran)
+
+	require.NoError(t, cmd.Flags().Set("terragrunt-check", "false"))
+	require.NoError(t, cmd.RunE(cmd, nil))
+	assert.True(t, ran)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Configuration is resolved through a single precedence chain:
+//
+//	defaults < config file < environment (TERRAGRUNT_*) < command-line flags
+//
+// Each layer only overrides the settings it explicitly provides, so an unset
+// flag never clobbers a value from the environment or config file.
+
+type settingKind int
+
+const (
+	settingString settingKind = iota
+	settingBool
+	settingInt
+	settingFloat
+	settingStringSlice
+)
+
+// configSetting maps one TerragruntConfig field to its env var and flag
+type configSetting struct {
+	Key     string // config/env key, read from TERRAGRUNT_<KEY>
+	Flag    string // command-line flag name
+	Kind    settingKind
+	Negated bool // the flag expresses the inverse of Key (e.g. --terragrunt-no-auto-init)
+	Apply   func(config *TerragruntConfig, value interface{})
+}
+
+var configSettings = []configSetting{
+	{
+		Key: "working_dir", Flag: "terragrunt-working-dir", Kind: settingString,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.WorkingDir = v.(string) },
+	},
+	{
+		Key: "non_interactive", Flag: "terragrunt-non-interactive", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.NonInteractive = v.(bool) },
+	},
+	{
+		Key: "log_level", Flag: "terragrunt-log-level", Kind: settingString,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.LogLevel = v.(string) },
+	},
+	{
+		Key: "iam_role", Flag: "terragrunt-iam-role", Kind: settingString,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.IamRole = v.(string) },
+	},
+	{
+		Key: "auto_init", Flag: "terragrunt-no-auto-init", Kind: settingBool, Negated: true,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.AutoInit = v.(bool) },
+	},
+	{
+		Key: "auto_retry", Flag: "terragrunt-no-auto-retry", Kind: settingBool, Negated: true,
+		Apply: func(c *TerragruntConfig, v interface{}) {
+			if !v.(bool) {
+				c.RetryAttempts = 0
+			}
+		},
+	},
+	{
+		Key: "parallelism", Flag: "terragrunt-parallelism", Kind: settingInt,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.Parallelism = v.(int) },
+	},
+	{
+		Key: "include_dirs", Flag: "terragrunt-include-dir", Kind: settingStringSlice,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.IncludeDirs = v.([]string) },
+	},
+	{
+		Key: "exclude_dirs", Flag: "terragrunt-exclude-dir", Kind: settingStringSlice,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.ExcludeDirs = v.([]string) },
+	},
+	{
+		Key: "download_dir", Flag: "terragrunt-download-dir", Kind: settingString,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.DownloadDir = v.(string) },
+	},
+	{
+		Key: "fetch_dependency_output_from_state", Flag: "terragrunt-fetch-dependency-output-from-state", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.FetchDependencyOutputFromState = v.(bool) },
+	},
+	{
+		Key: "read_output_from_backend", Flag: "terragrunt-read-output-from-backend", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.ReadOutputFromBackend = v.(bool) },
+	},
+	{
+		Key: "use_partial_parse_config_cache", Flag: "terragrunt-use-partial-parse-config-cache", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.UsePartialParseConfigCache = v.(bool) },
+	},
+	{
+		Key: "include_external_dependencies", Flag: "terragrunt-include-external-dependencies", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.IncludeExternalDependencies = v.(bool) },
+	},
+	{
+		Key: "ignore_external_dependencies", Flag: "terragrunt-ignore-external-dependencies", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.IgnoreExternalDependencies = v.(bool) },
+	},
+	{
+		Key: "diff", Flag: "terragrunt-diff", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.Diff = v.(bool) },
+	},
+	{
+		Key: "json_log", Flag: "terragrunt-json-log", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.JSONLog = v.(bool) },
+	},
+	{
+		Key: "detect_drift", Flag: "terragrunt-detect-drift", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.DetectDrift = v.(bool) },
+	},
+	{
+		Key: "accept_drift", Flag: "terragrunt-accept-drift", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.AcceptDrift = v.(bool) },
+	},
+	{
+		Key: "only_with_state", Flag: "terragrunt-only-with-state", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.OnlyWithState = v.(bool) },
+	},
+	{
+		Key: "only_without_state", Flag: "terragrunt-only-without-state", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.OnlyWithoutState = v.(bool) },
+	},
+	{
+		Key: "source", Flag: "terragrunt-source", Kind: settingString,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.SourceOverride = v.(string) },
+	},
+	{
+		Key: "source_update", Flag: "terragrunt-source-update", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.SourceUpdate = v.(bool) },
+	},
+	{
+		Key: "isolate_working_dir", Flag: "terragrunt-isolate-working-dir", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.IsolateWorkingDir = v.(bool) },
+	},
+	{
+		Key: "queue_include_dirs", Flag: "terragrunt-queue-include-dir", Kind: settingStringSlice,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.QueueIncludeDirs = v.([]string) },
+	},
+	{
+		Key: "apply_dependencies", Flag: "terragrunt-apply-dependencies", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.ApplyDependencies = v.(bool) },
+	},
+	{
+		Key: "fail_fast", Flag: "terragrunt-fail-fast", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.FailFast = v.(bool) },
+	},
+	{
+		Key: "continue_on_error", Flag: "terragrunt-continue-on-error", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.ContinueOnError = v.(bool) },
+	},
+	{
+		Key: "max_dependency_depth", Flag: "terragrunt-max-dependency-depth", Kind: settingInt,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.MaxDependencyDepth = v.(int) },
+	},
+	{
+		Key: "dependency_fan_warning", Flag: "terragrunt-dependency-fan-warning", Kind: settingInt,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.DependencyFanWarning = v.(int) },
+	},
+	{
+		Key: "retry_budget", Flag: "terragrunt-retry-budget", Kind: settingInt,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.RetryBudget = v.(int) },
+	},
+	{
+		Key: "max_exec_rate", Flag: "terragrunt-max-exec-rate", Kind: settingFloat,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.MaxExecRate = v.(float64) },
+	},
+	{
+		Key: "debug", Flag: "terragrunt-debug", Kind: settingBool,
+		Apply: func(c *TerragruntConfig, v interface{}) { c.Debug = v.(bool) },
+	},
+}
+
+// defaultTerragruntConfig returns the lowest-precedence configuration layer
+func defaultTerragruntConfig() *TerragruntConfig {
+	return &TerragruntConfig{
+		TerraformPath: "terraform",
+		WorkingDir:    ".",
+		AutoInit:      true,
+		Parallelism:   10,
+		RetryAttempts: 3,
+		RetryDelay:    2 * time.Second,
+		LogLevel:      "info",
+		Variables:     make(map[string]interface{}),
+		Environment:   make(map[string]string),
+
+		UsePartialParseConfigCache: true,
+		DependencyFanWarning:       defaultDependencyFanWarning,
+	}
+}
+
+// resolveConfig builds the effective configuration from defaults, the config
+// file (if any), the environment and the flags that were explicitly set
+func resolveConfig(flags *pflag.FlagSet, configFile string, lookupEnv func(string) (string, bool)) (*TerragruntConfig, error) {
+	config := defaultTerragruntConfig()
+
+	if configFile != "" {
+		format := "auto"
+		if flags != nil && flags.Lookup("config-format") != nil {
+			format, _ = flags.GetString("config-format")
+		}
+		if err := loadConfigFile(configFile, format, config); err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+		if config.Variables == nil {
+			config.Variables = make(map[string]interface{})
+		}
+		if config.Environment == nil {
+			config.Environment = make(map[string]string)
+		}
+	}
+
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	for _, setting := range configSettings {
+		envName := "TERRAGRUNT_" + strings.ToUpper(setting.Key)
+		raw, ok := lookupEnv(envName)
+		if !ok || raw == "" {
+			continue
+		}
+		value, err := parseSettingValue(setting.Kind, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", envName, err)
+		}
+		setting.Apply(config, value)
+	}
+
+	if flags != nil {
+		for _, setting := range configSettings {
+			if !flags.Changed(setting.Flag) {
+				continue
+			}
+			value, err := flagSettingValue(flags, setting)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for --%s: %w", setting.Flag, err)
+			}
+			setting.Apply(config, value)
+		}
+	}
+
+	return config, nil
+}
+
+// flagSettingValue reads a flag's typed value, inverting negated booleans
+func flagSettingValue(flags *pflag.FlagSet, setting configSetting) (interface{}, error) {
+	switch setting.Kind {
+	case settingBool:
+		v, err := flags.GetBool(setting.Flag)
+		if err != nil {
+			return nil, err
+		}
+		if setting.Negated {
+			return !v, nil
+		}
+		return v, nil
+	case settingInt:
+		return flags.GetInt(setting.Flag)
+	case settingFloat:
+		return flags.GetFloat64(setting.Flag)
+	case settingStringSlice:
+		return flags.GetStringSlice(setting.Flag)
+	default:
+		return flags.GetString(setting.Flag)
+	}
+}
+
+// parseSettingValue converts an environment variable string to the setting's type
+func parseSettingValue(kind settingKind, raw string) (interface{}, error) {
+	switch kind {
+	case settingBool:
+		return strconv.ParseBool(raw)
+	case settingInt:
+		return strconv.Atoi(raw)
+	case settingFloat:
+		return strconv.ParseFloat(raw, 64)
+	case settingStringSlice:
+		var values []string
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+		return values, nil
+	default:
+		return raw, nil
+	}
+}
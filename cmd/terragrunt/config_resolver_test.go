@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	flags := pflag.NewFlagSet("terragrunt", pflag.ContinueOnError)
+	registerGlobalFlags(flags)
+	require.NoError(t, flags.Parse(args))
+	return flags
+}
+
+func envMap(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, values map[string]interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(values)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "terragrunt.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestResolveConfigDefaults(t *testing.T) {
+	config, err := resolveConfig(newTestFlags(t), "", envMap(nil))
+	require.NoError(t, err)
+
+	assert.True(t, config.AutoInit)
+	assert.Equal(t, 10, config.Parallelism)
+	assert.Equal(t, 3, config.RetryAttempts)
+	assert.Equal(t, ".", config.WorkingDir)
+	assert.NotNil(t, config.Variables)
+	assert.NotNil(t, config.Environment)
+}
+
+func TestResolveConfigPrecedence(t *testing.T) {
+	configFile := writeConfigFile(t, map[string]interface{}{
+		"parallelism":  2,
+		"log_level":    "warn",
+		"download_dir": "/from/file",
+		"iam_role":     "file-role",
+	})
+	env := envMap(map[string]string{
+		"TERRAGRUNT_PARALLELISM":  "4",
+		"TERRAGRUNT_DOWNLOAD_DIR": "/from/env",
+	})
+
+	config, err := resolveConfig(newTestFlags(t, "--terragrunt-parallelism=8"), configFile, env)
+	require.NoError(t, err)
+
+	assert.Equal(t, 8, config.Parallelism, "flag should win over env and file")
+	assert.Equal(t, "/from/env", config.DownloadDir, "env should win over file")
+	assert.Equal(t, "warn", config.LogLevel, "file should win over defaults")
+	assert.Equal(t, "file-role", config.IamRole)
+}
+
+func TestResolveConfigUnsetFlagDoesNotOverride(t *testing.T) {
+	env := envMap(map[string]string{"TERRAGRUNT_PARALLELISM": "4"})
+
+	// The flag default (10) must not clobber the env value
+	config, err := resolveConfig(newTestFlags(t), "", env)
+	require.NoError(t, err)
+	assert.Equal(t, 4, config.Parallelism)
+}
+
+func TestResolveConfigNoAutoInitDisablesAutoInit(t *testing.T) {
+	config, err := resolveConfig(newTestFlags(t, "--terragrunt-no-auto-init"), "", envMap(nil))
+	require.NoError(t, err)
+	assert.False(t, config.AutoInit)
+
+	// The positive env key must not be inverted
+	config, err = resolveConfig(newTestFlags(t), "", envMap(map[string]string{"TERRAGRUNT_AUTO_INIT": "true"}))
+	require.NoError(t, err)
+	assert.True(t, config.AutoInit)
+
+	config, err = resolveConfig(newTestFlags(t), "", envMap(map[string]string{"TERRAGRUNT_AUTO_INIT": "false"}))
+	require.NoError(t, err)
+	assert.False(t, config.AutoInit)
+
+	// The flag wins over a config file that enables auto-init
+	configFile := writeConfigFile(t, map[string]interface{}{"auto_init": true})
+	config, err = resolveConfig(newTestFlags(t, "--terragrunt-no-auto-init"), configFile, envMap(nil))
+	require.NoError(t, err)
+	assert.False(t, config.AutoInit)
+}
+
+func TestResolveConfigNoAutoRetry(t *testing.T) {
+	config, err := resolveConfig(newTestFlags(t, "--terragrunt-no-auto-retry"), "", envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, 0, config.RetryAttempts)
+}
+
+func TestResolveConfigSlicesAndInvalidEnv(t *testing.T) {
+	env := envMap(map[string]string{"TERRAGRUNT_EXCLUDE_DIRS": "a, b"})
+	config, err := resolveConfig(newTestFlags(t, "--terragrunt-include-dir=x,y"), "", env)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, config.IncludeDirs)
+	assert.Equal(t, []string{"a", "b"}, config.ExcludeDirs)
+
+	_, err = resolveConfig(newTestFlags(t), "", envMap(map[string]string{"TERRAGRUNT_PARALLELISM": "many"}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// requiredCopySuffixes are the files terraform and terragrunt read, which
+// are copied whatever include_in_copy and exclude_from_copy say
+var requiredCopySuffixes = []string{".tf", ".tf.json", ".tfvars", ".tfvars.json", ".hcl"}
+
+// copyFilter decides what is copied from a module source into its cache.
+// Patterns are slash-separated globs relative to the source; ** matches any
+// number of directories and a pattern without a slash matches the name at
+// any depth. When include patterns are set, other files are only copied if
+// they match one. Files with a requiredCopySuffixes suffix are copied even
+// when excluded or not included, except inside an excluded directory: that
+// is skipped with everything in it.
+type copyFilter struct {
+	include []string
+	exclude []string
+}
+
+func newCopyFilter(include, exclude []string) (*copyFilter, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid copy pattern %q: %w", pattern, err)
+		}
+	}
+	return &copyFilter{include: include, exclude: exclude}, nil
+}
+
+// copies reports whether the file or directory at rel is copied
+func (f *copyFilter) copies(rel string, dir bool) bool {
+	if f == nil {
+		return true
+	}
+	for _, pattern := range f.exclude {
+		if matchCopyPattern(pattern, rel) {
+			return !dir && requiredCopyFile(rel)
+		}
+	}
+	if dir || len(f.include) == 0 || requiredCopyFile(rel) {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchCopyPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func requiredCopyFile(rel string) bool {
+	for _, suffix := range requiredCopySuffixes {
+		if strings.HasSuffix(rel, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCopyPattern matches a slash-separated path against a glob
+func matchCopyPattern(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSuffix(pattern, "/"), "./")
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchCopyPattern(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide.md", true},
+		{"docs", "docs", true},
+		{"docs/*.md", "docs/guide.md", true},
+		{"docs/*.md", "docs/deep/guide.md", false},
+		{"docs/**/*.md", "docs/deep/guide.md", true},
+		{"docs/**", "docs/deep/guide.md", true},
+		{"./test/", "test", true},
+		{"test", "modules/test", true},
+		{"test/fixtures", "modules/test/fixtures", false},
+		{"*.md", "README.txt", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchCopyPattern(tt.pattern, tt.path), "%s against %s", tt.pattern, tt.path)
+	}
+}
+
+func TestNewCopyFilterRejectsBadPatterns(t *testing.T) {
+	_, err := newCopyFilter(nil, []string{"[docs"})
+	assert.Error(t, err)
+}
+
+func TestPrepareTerraformDirCopyFilters(t *testing.T) {
+	root, module := writeCacheTree(t, "../../modules/vpc")
+	files := map[string]string{
+		"modules/vpc/README.md":            "",
+		"modules/vpc/docs/diagram.png":     "",
+		"modules/vpc/test/vpc_test.go":     "",
+		"modules/vpc/test/fixture.tf":      "",
+		"modules/vpc/scripts/bootstrap.sh": "",
+		"modules/vpc/templates/startup.sh": "",
+		"modules/vpc/.git/HEAD":            "",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+	hcl := `terraform {
+  source            = "../../modules/vpc"
+  exclude_from_copy = ["*.md", "docs", "test/*.go", "*.tf"]
+  include_in_copy   = ["templates/**"]
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(module, "terragrunt.hcl"), []byte(hcl), 0644))
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: module}
+	require.NoError(t, prepareTerraformDir(ctx))
+
+	// Terraform and terragrunt files are copied even when excluded, other
+	// files only when they match include_in_copy
+	assert.ElementsMatch(t, []string{
+		"main.tf",
+		"sub/inner.tf",
+		"test/fixture.tf",
+		"templates/startup.sh",
+		"terragrunt.hcl",
+		"terraform.tfvars",
+	}, listFiles(t, ctx.terraformDir()))
+}
+
+func TestSyncLockFileCanBeDisabled(t *testing.T) {
+	_, module := writeCacheTree(t, "../../modules/vpc")
+	hcl := "terraform {\n  source                   = \"../../modules/vpc\"\n  copy_terraform_lock_file = false\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(module, "terragrunt.hcl"), []byte(hcl), 0644))
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: module}
+	require.NoError(t, prepareTerraformDir(ctx))
+	require.NoError(t, os.WriteFile(filepath.Join(ctx.terraformDir(), ".terraform.lock.hcl"), []byte("# lock"), 0644))
+
+	require.NoError(t, syncLockFile(ctx))
+	assert.NoFileExists(t, filepath.Join(module, ".terraform.lock.hcl"))
+}
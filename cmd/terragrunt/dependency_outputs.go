@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+)
+
+// terraformOutput is one entry of `terraform output -json`, which shares its
+// shape with the "outputs" section of a v4 state file
+type terraformOutput struct {
+	Value     interface{}     `json:"value"`
+	Type      json.RawMessage `json:"type,omitempty"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
+// stateFile is the subset of the terraform state format needed for outputs
+type stateFile struct {
+	Version int                        `json:"version"`
+	Outputs map[string]terraformOutput `json:"outputs"`
+}
+
+// errStateGenerationNotFound is returned for a pinned generation that the
+// state object doesn't have (any more)
+var errStateGenerationNotFound = errors.New("state generation not found")
+
+// readStateObject fetches a state object from the gcs backend of config,
+// generation 0 being the latest, as the backend's service account and with
+// its customer-supplied key; tests replace it
+var readStateObject = func(ctx context.Context, config *TerragruntConfig, object string, generation int64) ([]byte, error) {
+	opts, err := impersonationOptions(ctx, gcsImpersonation(config))
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	handle := client.Bucket(config.Backend.Bucket).Object(object)
+	if generation > 0 {
+		handle = handle.Generation(generation)
+	}
+	if config.Backend.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(config.Backend.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption_key: %w", err)
+		}
+		handle = handle.Key(key)
+	}
+	reader, err := handle.NewReader(ctx)
+	if generation > 0 && errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %d", errStateGenerationNotFound, generation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// runTerraformOutput runs `terraform output -json` for the module in
+// module, where it last ran; tests replace it
+var runTerraformOutput = func(ctx *ExecutionContext, module string) ([]byte, error) {
+	moduleCtx := *ctx
+	moduleCtx.WorkingDir = module
+	moduleCtx.TerraformDir = cachedTerraformDir(ctx, module)
+	return queryTerraform(&moduleCtx, "output", "-json")
+}
+
+// stateOutputCache holds outputs parsed from remote state for the rest of the
+// run, keyed by gs:// URL, so modules sharing a dependency read it once
+type stateOutputCache struct {
+	mu      sync.Mutex
+	outputs map[string]map[string]terraformOutput
+	// modules maps module directories to the key of their latest state, for
+	// dropping it once the module is applied
+	modules map[string]string
+}
+
+var dependencyStateCache = &stateOutputCache{outputs: make(map[string]map[string]terraformOutput)}
+
+func (c *stateOutputCache) get(key string) (map[string]terraformOutput, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	outputs, ok := c.outputs[key]
+	return outputs, ok
+}
+
+// put caches the outputs of key, read for module unless module is empty
+func (c *stateOutputCache) put(module, key string, outputs map[string]terraformOutput) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outputs[key] = outputs
+	if module == "" {
+		return
+	}
+	if c.modules == nil {
+		c.modules = make(map[string]string)
+	}
+	c.modules[filepath.Clean(module)] = key
+}
+
+// invalidate drops the latest outputs of module, whose state has changed
+func (c *stateOutputCache) invalidate(module string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	module = filepath.Clean(module)
+	if key, ok := c.modules[module]; ok {
+		delete(c.outputs, key)
+		delete(c.modules, module)
+	}
+}
+
+func loadDependencyOutputs(ctx *ExecutionContext) error {
+	for _, dep := range ctx.Config.Dependencies {
+		if !dep.Enabled || dep.SkipOutputs {
+			continue
+		}
+
+		if dep.MockOutputs != nil {
+			if problems := validateMockOutputs(dep); len(problems) > 0 {
+				return fmt.Errorf("mock outputs of dependency %s don't match mock_outputs_schema: %s", dep.Name, strings.Join(problems, "; "))
+			}
+			// Compare the mocks with the real outputs, if the dependency
+			// has any yet, before standing in for them
+			if outputs, err := fetchDependencyOutputs(ctx, dep); err != nil {
+				logger.Debugf("Not checking the mock outputs of dependency %s for drift: %v", dep.Name, err)
+			} else if len(outputs) > 0 {
+				if drift := outputDrift(dep, outputs); len(drift) > 0 {
+					logger.Warnf("Outputs of dependency %s have drifted from its mocks: %s", dep.Name, strings.Join(drift, "; "))
+				}
+			}
+			// Use mock outputs
+			for key, value := range dep.MockOutputs {
+				ctx.Dependencies[fmt.Sprintf("%s.%s", dep.Name, key)] = value
+			}
+			continue
+		}
+
+		outputs, err := fetchDependencyOutputs(ctx, dep)
+		if err != nil {
+			return fmt.Errorf("failed to read outputs of dependency %s: %w", dep.Name, err)
+		}
+		if drift := outputDrift(dep, outputs); len(drift) > 0 {
+			logger.Warnf("Outputs of dependency %s have drifted from its mocks: %s", dep.Name, strings.Join(drift, "; "))
+		}
+		for key, output := range outputs {
+			if output.Sensitive {
+				registerSecret(output.Value)
+			}
+			ctx.Dependencies[fmt.Sprintf("%s.%s", dep.Name, key)] = output.Value
+		}
+	}
+	return nil
+}
+
+// dependencyDir resolves the module directory a dependency points at
+func dependencyDir(ctx *ExecutionContext, dep DependencyConfig) string {
+	dir := dep.ConfigPath
+	if dir == "" {
+		dir = dep.Path
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(ctx.WorkingDir, dir)
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// fetchDependencyOutputs reads a dependency's outputs, straight from its
+// remote state when enabled and falling back to `terraform output`. Outputs
+// pinned to a state generation can only come from that generation.
+func fetchDependencyOutputs(ctx *ExecutionContext, dep DependencyConfig) (map[string]terraformOutput, error) {
+	dir := dependencyDir(ctx, dep)
+
+	if dep.StateGeneration < 0 {
+		return nil, fmt.Errorf("invalid state_generation %d", dep.StateGeneration)
+	}
+	if dep.StateGeneration > 0 {
+		return outputsFromRemoteState(dir, dep.StateGeneration)
+	}
+
+	if ctx.Config.FetchDependencyOutputFromState || ctx.Config.ReadOutputFromBackend {
+		outputs, err := outputsFromRemoteState(dir, 0)
+		if err == nil {
+			return outputs, nil
+		}
+		logger.Debugf("Falling back to terraform output for %s: %v", dep.Name, err)
+	}
+
+	if outputs, ok := dependencyStateCache.get(terraformOutputKey(dir)); ok {
+		return outputs, nil
+	}
+	return readTerraformOutputs(ctx, dir)
+}
+
+// readTerraformOutputs runs `terraform output` for the module in dir, where
+// it last ran
+func readTerraformOutputs(ctx *ExecutionContext, dir string) (map[string]terraformOutput, error) {
+	data, err := runTerraformOutput(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs map[string]terraformOutput
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform output: %w", err)
+	}
+	return outputs, nil
+}
+
+// terraformOutputKey is the stateOutputCache key of outputs read with
+// `terraform output` from the module in dir
+func terraformOutputKey(dir string) string {
+	return "terraform output " + filepath.Clean(dir)
+}
+
+// outputsFromRemoteState reads the dependency's GCS backend settings from its
+// terragrunt.hcl and parses outputs out of the default workspace state, at
+// generation or, for 0, the latest
+func outputsFromRemoteState(dir string, generation int64) (map[string]terraformOutput, error) {
+	config := defaultTerragruntConfig()
+	if err := loadConfigFile(filepath.Join(dir, "terragrunt.hcl"), "auto", config); err != nil {
+		return nil, err
+	}
+	if config.Backend.Type != "gcs" || config.Backend.Bucket == "" {
+		return nil, fmt.Errorf("dependency does not use a gcs backend")
+	}
+
+	object := path.Join(config.Backend.Prefix, "default.tfstate")
+	key := fmt.Sprintf("gs://%s/%s", config.Backend.Bucket, object)
+	if generation > 0 {
+		key += fmt.Sprintf("#%d", generation)
+	}
+	if outputs, ok := dependencyStateCache.get(key); ok {
+		return outputs, nil
+	}
+
+	data, err := readStateObject(context.Background(), config, object, generation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	outputs, err := parseStateOutputs(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	if generation > 0 {
+		// A pinned generation never changes
+		dependencyStateCache.put("", key, outputs)
+	} else {
+		dependencyStateCache.put(dir, key, outputs)
+	}
+	return outputs, nil
+}
+
+// prefetchDependencyOutputs reads the state of every module in graph that
+// others depend on, in parallel, so the modules of a run-all find their
+// dependency outputs cached instead of reading them one by one. Modules
+// whose state can't be read are left to be read on demand.
+func prefetchDependencyOutputs(ctx *ExecutionContext, graph map[string][]string) {
+	seen := make(map[string]bool)
+	var modules []string
+	for _, node := range sortedGraphNodes(graph) {
+		for _, dep := range graph[node] {
+			if !seen[dep] {
+				seen[dep] = true
+				modules = append(modules, dep)
+			}
+		}
+	}
+	if len(modules) == 0 {
+		return
+	}
+
+	parallelism := ctx.Config.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	fetched := 0
+	semaphore := make(chan struct{}, parallelism)
+	for _, module := range modules {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(module string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if _, err := outputsFromRemoteState(module, 0); err != nil {
+				logger.Debugf("Not prefetching outputs of %s: %v", module, err)
+				return
+			}
+			mu.Lock()
+			fetched++
+			mu.Unlock()
+		}(module)
+	}
+	wg.Wait()
+	logger.Infof("Prefetched outputs of %d/%d dependency modules from their backend", fetched, len(modules))
+}
+
+// loadModuleDependencyOutputs loads the outputs of the dependencies of the
+// module ctx runs in, as declared in its own terragrunt.hcl
+func loadModuleDependencyOutputs(ctx *ExecutionContext) error {
+	moduleConfig := defaultTerragruntConfig()
+	if err := loadModuleConfig(ctx, filepath.Join(ctx.WorkingDir, "terragrunt.hcl"), moduleConfig); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", ctx.WorkingDir, err)
+	}
+	config := *ctx.Config
+	config.Dependencies = moduleConfig.Dependencies
+	ctx.Config = &config
+	return loadDependencyOutputs(ctx)
+}
+
+// parseStateOutputs extracts outputs from a state file, rejecting formats
+// other than v4 so callers can fall back to terraform itself
+func parseStateOutputs(data []byte) (map[string]terraformOutput, error) {
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version %d", state.Version)
+	}
+	if state.Outputs == nil {
+		state.Outputs = make(map[string]terraformOutput)
+	}
+	return state.Outputs, nil
+}
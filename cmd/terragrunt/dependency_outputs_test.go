@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleGCSState = `{
+  "version": 4,
+  "terraform_version": "1.6.0",
+  "serial": 12,
+  "lineage": "3b1c2f0e-1111-2222-3333-444455556666",
+  "outputs": {
+    "network_id": {
+      "value": "projects/acme/global/networks/main",
+      "type": "string"
+    },
+    "subnets": {
+      "value": ["10.0.0.0/24", "10.0.1.0/24"],
+      "type": ["list", "string"]
+    },
+    "db_password": {
+      "value": "hunter2",
+      "type": "string",
+      "sensitive": true
+    }
+  },
+  "resources": []
+}`
+
+// stubDependencyIO replaces the GCS reader and terraform runner for a test
+func stubDependencyIO(t *testing.T, objects map[string]string, outputJSON string) *[]string {
+	t.Helper()
+	var reads []string
+	var mu sync.Mutex
+
+	originalRead, originalRun, originalCache := readStateObject, runTerraformOutput, dependencyStateCache
+	readStateObject = func(_ context.Context, config *TerragruntConfig, object string, generation int64) ([]byte, error) {
+		key := "gs://" + config.Backend.Bucket + "/" + object
+		if generation > 0 {
+			key += fmt.Sprintf("#%d", generation)
+		}
+		mu.Lock()
+		reads = append(reads, key)
+		mu.Unlock()
+		data, ok := objects[key]
+		if !ok && generation > 0 {
+			return nil, fmt.Errorf("%w: %d", errStateGenerationNotFound, generation)
+		}
+		if !ok {
+			return nil, errors.New("object not found")
+		}
+		return []byte(data), nil
+	}
+	runTerraformOutput = func(*ExecutionContext, string) ([]byte, error) {
+		reads = append(reads, "terraform output")
+		return []byte(outputJSON), nil
+	}
+	dependencyStateCache = &stateOutputCache{outputs: make(map[string]map[string]terraformOutput)}
+
+	t.Cleanup(func() {
+		readStateObject, runTerraformOutput, dependencyStateCache = originalRead, originalRun, originalCache
+	})
+	return &reads
+}
+
+func newDependencyModule(t *testing.T, root, name, config string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(config), 0644))
+}
+
+func TestParseStateOutputs(t *testing.T) {
+	outputs, err := parseStateOutputs([]byte(sampleGCSState))
+	require.NoError(t, err)
+
+	assert.Equal(t, "projects/acme/global/networks/main", outputs["network_id"].Value)
+	assert.Equal(t, []interface{}{"10.0.0.0/24", "10.0.1.0/24"}, outputs["subnets"].Value)
+	assert.True(t, outputs["db_password"].Sensitive)
+
+	_, err = parseStateOutputs([]byte(`{"version": 3, "modules": []}`))
+	assert.Error(t, err)
+}
+
+func TestLoadDependencyOutputsFromState(t *testing.T) {
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", `
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+  prefix = "prod/vpc"
+}
+`)
+	reads := stubDependencyIO(t, map[string]string{"gs://tf-state/prod/vpc/default.tfstate": sampleGCSState}, "")
+
+	config := defaultTerragruntConfig()
+	config.FetchDependencyOutputFromState = true
+	config.Dependencies = []DependencyConfig{{Name: "vpc", ConfigPath: "../vpc", Enabled: true}}
+
+	app := filepath.Join(root, "app")
+	ctx := &ExecutionContext{Config: config, WorkingDir: app, Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+
+	assert.Equal(t, "projects/acme/global/networks/main", ctx.Dependencies["vpc.network_id"])
+	assert.Equal(t, []interface{}{"10.0.0.0/24", "10.0.1.0/24"}, ctx.Dependencies["vpc.subnets"])
+
+	// The parsed state is cached for the rest of the run
+	other := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "web"), Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(other))
+	assert.Equal(t, []string{"gs://tf-state/prod/vpc/default.tfstate"}, *reads)
+}
+
+func TestLoadDependencyOutputsFallsBackToTerraform(t *testing.T) {
+	root := t.TempDir()
+	newDependencyModule(t, root, "legacy", `
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+  prefix = "legacy"
+}
+`)
+	newDependencyModule(t, root, "local", `terraform_path = "terraform"`)
+
+	reads := stubDependencyIO(t,
+		map[string]string{"gs://tf-state/legacy/default.tfstate": `{"version": 3, "modules": []}`},
+		`{"id": {"value": "from-terraform", "type": "string"}}`,
+	)
+
+	config := defaultTerragruntConfig()
+	config.FetchDependencyOutputFromState = true
+	config.Dependencies = []DependencyConfig{
+		{Name: "legacy", ConfigPath: filepath.Join(root, "legacy"), Enabled: true},
+		{Name: "local", ConfigPath: filepath.Join(root, "local"), Enabled: true},
+	}
+
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+
+	assert.Equal(t, "from-terraform", ctx.Dependencies["legacy.id"])
+	assert.Equal(t, "from-terraform", ctx.Dependencies["local.id"])
+	assert.Equal(t, []string{"gs://tf-state/legacy/default.tfstate", "terraform output", "terraform output"}, *reads)
+}
+
+func TestLoadDependencyOutputsWithoutStateFastPath(t *testing.T) {
+	root := t.TempDir()
+	reads := stubDependencyIO(t, nil, `{"id": {"value": "x"}}`)
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{
+		{Name: "db", ConfigPath: root, Enabled: true},
+		{Name: "mocked", Enabled: true, MockOutputs: map[string]interface{}{"id": "mock"}},
+	}
+
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+
+	assert.Equal(t, "x", ctx.Dependencies["db.id"])
+	assert.Equal(t, "mock", ctx.Dependencies["mocked.id"])
+	// The mocked dependency's real outputs are read only to check for drift
+	assert.Equal(t, []string{"terraform output", "terraform output"}, *reads)
+}
+
+func TestLoadDependencyOutputsFromPinnedGeneration(t *testing.T) {
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", `
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+  prefix = "prod/vpc"
+}
+`)
+	promoted := `{"version": 4, "outputs": {"network_id": {"value": "projects/acme/global/networks/old", "type": "string"}}}`
+	reads := stubDependencyIO(t, map[string]string{
+		"gs://tf-state/prod/vpc/default.tfstate":                  sampleGCSState,
+		"gs://tf-state/prod/vpc/default.tfstate#1700000000000001": promoted,
+	}, `{"network_id": {"value": "from-terraform"}}`)
+
+	load := func(dep DependencyConfig) (*ExecutionContext, error) {
+		config := defaultTerragruntConfig()
+		config.Dependencies = []DependencyConfig{dep}
+		ctx := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "app"), Dependencies: make(map[string]interface{})}
+		return ctx, loadDependencyOutputs(ctx)
+	}
+
+	// The pinned generation is read from state even without
+	// FetchDependencyOutputFromState
+	pinned, err := load(DependencyConfig{Name: "vpc", ConfigPath: "../vpc", Enabled: true, StateGeneration: 1700000000000001})
+	require.NoError(t, err)
+	assert.Equal(t, "projects/acme/global/networks/old", pinned.Dependencies["vpc.network_id"])
+	assert.NotContains(t, pinned.Dependencies, "vpc.subnets")
+
+	latest, err := load(DependencyConfig{Name: "vpc", ConfigPath: "../vpc", Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, "from-terraform", latest.Dependencies["vpc.network_id"])
+
+	assert.Equal(t, []string{"gs://tf-state/prod/vpc/default.tfstate#1700000000000001", "terraform output"}, *reads)
+}
+
+func TestLoadDependencyOutputsRejectsMissingGeneration(t *testing.T) {
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", `
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+  prefix = "prod/vpc"
+}
+`)
+	reads := stubDependencyIO(t, map[string]string{"gs://tf-state/prod/vpc/default.tfstate": sampleGCSState}, `{}`)
+
+	config := defaultTerragruntConfig()
+	config.FetchDependencyOutputFromState = true
+	config.Dependencies = []DependencyConfig{{Name: "vpc", ConfigPath: "../vpc", Enabled: true, StateGeneration: 42}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "app"), Dependencies: make(map[string]interface{})}
+
+	err := loadDependencyOutputs(ctx)
+	require.ErrorIs(t, err, errStateGenerationNotFound)
+	assert.Contains(t, err.Error(), "gs://tf-state/prod/vpc/default.tfstate#42")
+	// A missing generation doesn't silently fall back to terraform output
+	assert.Equal(t, []string{"gs://tf-state/prod/vpc/default.tfstate#42"}, *reads)
+	assert.Empty(t, ctx.Dependencies)
+}
+
+// writePrefetchTree makes modules vpc and db with gcs backends, legacy
+// without one, and returns their dependency graph
+func writePrefetchTree(t *testing.T) (string, map[string][]string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"vpc", "db"} {
+		newDependencyModule(t, root, name, fmt.Sprintf(`
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+  prefix = "prod/%s"
+}
+`, name))
+	}
+	newDependencyModule(t, root, "legacy", "")
+	newDependencyModule(t, root, "app", `
+dependency "vpc" {
+  config_path = "../vpc"
+  enabled     = true
+}
+dependency "db" {
+  config_path = "../db"
+  enabled     = true
+}
+`)
+
+	module := func(name string) string { return filepath.Join(root, name) }
+	return root, map[string][]string{
+		module("app"):    {module("vpc"), module("db")},
+		module("web"):    {module("vpc"), module("legacy")},
+		module("db"):     {module("vpc")},
+		module("vpc"):    {},
+		module("legacy"): {},
+	}
+}
+
+func TestPrefetchDependencyOutputsReadsEachStateOnce(t *testing.T) {
+	root, graph := writePrefetchTree(t)
+	reads := stubDependencyIO(t, map[string]string{
+		"gs://tf-state/prod/vpc/default.tfstate": sampleGCSState,
+		"gs://tf-state/prod/db/default.tfstate":  `{"version": 4, "outputs": {"address": {"value": "10.1.0.3"}}}`,
+	}, `{}`)
+
+	config := defaultTerragruntConfig()
+	config.ReadOutputFromBackend = true
+	config.Parallelism = 4
+	prefetchDependencyOutputs(&ExecutionContext{Config: config, WorkingDir: root}, graph)
+
+	prefetched := append([]string(nil), *reads...)
+	sort.Strings(prefetched)
+	assert.Equal(t, []string{"gs://tf-state/prod/db/default.tfstate", "gs://tf-state/prod/vpc/default.tfstate"}, prefetched)
+
+	// Every module then finds its dependency outputs cached
+	app := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "app"), Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadModuleDependencyOutputs(app))
+	assert.Equal(t, "10.1.0.3", app.Dependencies["db.address"])
+	assert.Equal(t, "projects/acme/global/networks/main", app.Dependencies["vpc.network_id"])
+	assert.Len(t, *reads, 2)
+	assert.Empty(t, config.Dependencies, "the module's dependencies don't leak into the shared config")
+}
+
+func TestApplyInvalidatesPrefetchedOutputs(t *testing.T) {
+	root, graph := writePrefetchTree(t)
+	reads := stubDependencyIO(t, map[string]string{
+		"gs://tf-state/prod/vpc/default.tfstate": sampleGCSState,
+		"gs://tf-state/prod/db/default.tfstate":  `{"version": 4, "outputs": {}}`,
+	}, `{}`)
+	useFakeRunner(t)
+
+	config := defaultTerragruntConfig()
+	config.ReadOutputFromBackend = true
+	prefetchDependencyOutputs(&ExecutionContext{Config: config, WorkingDir: root}, graph)
+	require.Len(t, *reads, 2)
+
+	vpc := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "vpc"), Environment: map[string]string{}}
+	require.NoError(t, executeTerraform(vpc, "apply", "-auto-approve"))
+
+	app := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "app"), Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadModuleDependencyOutputs(app))
+	assert.Equal(t, "gs://tf-state/prod/vpc/default.tfstate", (*reads)[2], "the applied module's state is read again")
+	assert.Len(t, *reads, 3, "db wasn't applied and stays cached")
+}
+
+func TestReadStateUsesBackendImpersonationAndKey(t *testing.T) {
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", `
+backend {
+  type                        = "gcs"
+  bucket                      = "tf-state"
+  prefix                      = "prod/vpc"
+  impersonate_service_account = "state@acme.iam.gserviceaccount.com"
+  encryption_key              = "c2VjcmV0a2V5"
+}
+`)
+	stubDependencyIO(t, nil, "")
+	var seen *TerragruntConfig
+	readStateObject = func(_ context.Context, config *TerragruntConfig, _ string, _ int64) ([]byte, error) {
+		seen = config
+		return []byte(sampleGCSState), nil
+	}
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{{Name: "vpc", ConfigPath: "../vpc", Enabled: true, StateGeneration: 7}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: filepath.Join(root, "app"), Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+
+	require.NotNil(t, seen)
+	assert.Equal(t, "state@acme.iam.gserviceaccount.com", gcsImpersonation(seen))
+	assert.Equal(t, "c2VjcmV0a2V5", seen.Backend.EncryptionKey)
+}
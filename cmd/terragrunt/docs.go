@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/spf13/cobra"
+)
+
+// Generated documentation lives between these markers in README.md; anything
+// outside them is hand-written and left untouched
+const (
+	docsBeginMarker = "<!-- BEGIN_TF_DOCS -->"
+	docsEndMarker   = "<!-- END_TF_DOCS -->"
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate module README tables",
+	Long:  `Regenerate the inputs/outputs tables in a module's README.md from its variables and outputs`,
+	RunE:  runDocs,
+}
+
+func runDocs(cmd *cobra.Command, args []string) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	path, _ := cmd.Flags().GetString("path")
+	check, _ := cmd.Flags().GetBool("check")
+
+	if path == "" {
+		path = ctx.WorkingDir
+	}
+
+	if check {
+		current, updated, err := moduleReadme(path)
+		if err != nil {
+			return err
+		}
+		if current != updated {
+			return fmt.Errorf("README.md in %s is out of date, run 'terragrunt docs'", path)
+		}
+		logger.Infof("README.md in %s is up to date", path)
+		return nil
+	}
+
+	changed, err := writeModuleDocs(path)
+	if err != nil {
+		return err
+	}
+	if changed {
+		logger.Infof("Updated README.md in %s", path)
+	} else {
+		logger.Infof("README.md in %s is up to date", path)
+	}
+	return nil
+}
+
+// writeModuleDocs regenerates the README tables for the module in dir and
+// reports whether the file changed
+func writeModuleDocs(dir string) (bool, error) {
+	current, updated, err := moduleReadme(dir)
+	if err != nil {
+		return false, err
+	}
+	if current == updated {
+		return false, nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(updated), 0644); err != nil {
+		return false, fmt.Errorf("failed to write README.md: %w", err)
+	}
+	return true, nil
+}
+
+// moduleReadme returns the current README.md content of dir and the content
+// it should have with freshly generated tables
+func moduleReadme(dir string) (string, string, error) {
+	module, diags := tfconfig.LoadModule(dir)
+	if diags.HasErrors() {
+		return "", "", fmt.Errorf("failed to parse module %s: %w", dir, diags.Err())
+	}
+
+	var current string
+	base := fmt.Sprintf("# %s Module\n", filepath.Base(dir))
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	switch {
+	case err == nil:
+		current = string(data)
+		base = current
+	case !os.IsNotExist(err):
+		return "", "", fmt.Errorf("failed to read README.md: %w", err)
+	}
+
+	updated, err := replaceDocsSection(base, renderModuleDocs(module))
+	if err != nil {
+		return "", "", err
+	}
+	return current, updated, nil
+}
+
+// replaceDocsSection swaps the content between the docs markers, appending a
+// marked section when the README doesn't have one yet
+func replaceDocsSection(readme, docs string) (string, error) {
+	section := docsBeginMarker + "\n" + docs + docsEndMarker
+
+	begin := strings.Index(readme, docsBeginMarker)
+	end := strings.Index(readme, docsEndMarker)
+
+	switch {
+	case begin == -1 && end == -1:
+		return strings.TrimRight(readme, "\n") + "\n\n" + section + "\n", nil
+	case begin == -1 || end == -1 || end < begin:
+		return "", fmt.Errorf("README.md has unbalanced %s/%s markers", docsBeginMarker, docsEndMarker)
+	}
+
+	return readme[:begin] + section + readme[end+len(docsEndMarker):], nil
+}
+
+// renderModuleDocs renders the inputs and outputs tables in declaration order
+func renderModuleDocs(module *tfconfig.Module) string {
+	variables := make([]*tfconfig.Variable, 0, len(module.Variables))
+	for _, v := range module.Variables {
+		variables = append(variables, v)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return sourcePosLess(variables[i].Pos, variables[j].Pos)
+	})
+
+	outputs := make([]*tfconfig.Output, 0, len(module.Outputs))
+	for _, o := range module.Outputs {
+		outputs = append(outputs, o)
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		return sourcePosLess(outputs[i].Pos, outputs[j].Pos)
+	})
+
+	var b strings.Builder
+
+	b.WriteString("## Inputs\n\n")
+	if len(variables) == 0 {
+		b.WriteString("No inputs.\n")
+	} else {
+		b.WriteString("| Name | Description | Type | Default | Required |\n")
+		b.WriteString("|------|-------------|------|---------|:--------:|\n")
+		for _, v := range variables {
+			varType := v.Type
+			if varType == "" {
+				varType = "any"
+			}
+			required := "no"
+			if v.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				v.Name, docsCell(v.Description), docsCell(varType), docsCell(formatDocsDefault(v)), required)
+		}
+	}
+
+	b.WriteString("\n## Outputs\n\n")
+	if len(outputs) == 0 {
+		b.WriteString("No outputs.\n")
+	} else {
+		b.WriteString("| Name | Description |\n")
+		b.WriteString("|------|-------------|\n")
+		for _, o := range outputs {
+			fmt.Fprintf(&b, "| %s | %s |\n", o.Name, docsCell(o.Description))
+		}
+	}
+
+	return b.String()
+}
+
+func sourcePosLess(a, b tfconfig.SourcePos) bool {
+	if a.Filename != b.Filename {
+		return a.Filename < b.Filename
+	}
+	return a.Line < b.Line
+}
+
+// formatDocsDefault renders a variable default; strings are shown bare and
+// everything else as compact JSON
+func formatDocsDefault(v *tfconfig.Variable) string {
+	if v.Required {
+		return "-"
+	}
+	switch value := v.Default.(type) {
+	case nil:
+		return "null"
+	case string:
+		return value
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(encoded)
+	}
+}
+
+// docsCell escapes text for use in a markdown table cell
+func docsCell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const docsTestVariables = `variable "name" {
+  description = "Name of the bucket"
+  type        = string
+}
+
+variable "location" {
+  description = "Bucket location"
+  type        = string
+  default     = "US"
+}
+
+variable "versioning" {
+  description = "Enable object versioning"
+  type        = bool
+  default     = true
+}
+
+variable "retention_days" {
+  type    = number
+  default = 30
+}
+
+variable "labels" {
+  description = "Labels | applied to the bucket"
+  type        = map(string)
+  default     = {}
+}
+
+variable "lifecycle_rules" {
+  description = "Lifecycle rules"
+  type = list(object({
+    action = string
+    age    = number
+  }))
+  default = []
+}
+`
+
+const docsTestOutputs = `output "url" {
+  description = "The gs:// URL of the bucket"
+  value       = "gs://example"
+}
+
+output "self_link" {
+  value = "link"
+}
+`
+
+func writeDocsModule(t *testing.T, readme string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(docsTestVariables), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "outputs.tf"), []byte(docsTestOutputs), 0644))
+	if readme != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(readme), 0644))
+	}
+	return dir
+}
+
+func TestWriteModuleDocsTables(t *testing.T) {
+	dir := writeDocsModule(t, "")
+
+	changed, err := writeModuleDocs(dir)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	readme, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+
+	expected := docsBeginMarker + `
+## Inputs
+
+| Name | Description | Type | Default | Required |
+|------|-------------|------|---------|:--------:|
+| name | Name of the bucket | string | - | yes |
+| location | Bucket location | string | US | no |
+| versioning | Enable object versioning | bool | true | no |
+| retention_days |  | number | 30 | no |
+| labels | Labels \| applied to the bucket | map(string) | {} | no |
+| lifecycle_rules | Lifecycle rules | list(object({<br>    action = string<br>    age    = number<br>  })) | [] | no |
+
+## Outputs
+
+| Name | Description |
+|------|-------------|
+| url | The gs:// URL of the bucket |
+| self_link |  |
+` + docsEndMarker + "\n"
+
+	assert.Equal(t, "# "+filepath.Base(dir)+" Module\n\n"+expected, string(readme))
+
+	// A second run is a no-op
+	changed, err = writeModuleDocs(dir)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestWriteModuleDocsPreservesHandWrittenSections(t *testing.T) {
+	readme := "# Bucket\n\nHand-written intro.\n\n" +
+		docsBeginMarker + "\nstale tables\n" + docsEndMarker +
+		"\n\n## Notes\n\nKeep me.\n"
+	dir := writeDocsModule(t, readme)
+
+	_, err := writeModuleDocs(dir)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	content := string(data)
+
+	assert.Contains(t, content, "# Bucket\n\nHand-written intro.\n\n"+docsBeginMarker+"\n## Inputs")
+	assert.Contains(t, content, docsEndMarker+"\n\n## Notes\n\nKeep me.\n")
+	assert.NotContains(t, content, "stale tables")
+	assert.Contains(t, content, "| versioning | Enable object versioning | bool | true | no |")
+}
+
+func TestWriteModuleDocsUnbalancedMarkers(t *testing.T) {
+	dir := writeDocsModule(t, "# Bucket\n\n"+docsBeginMarker+"\n")
+
+	_, err := writeModuleDocs(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unbalanced")
+}
+
+func TestScaffoldReadmeMatchesVariables(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(generateVariablesTF("default", "web")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "outputs.tf"), []byte(generateOutputsTF("default", "web")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(generateREADME("web")), 0644))
+
+	_, err := writeModuleDocs(dir)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "| zone | The zone for the instance | string | - | yes |")
+	assert.Contains(t, string(data), "| tags | Network tags for the instance | list(string) | [] | no |")
+	assert.Contains(t, string(data), "| instance_network_ip | The internal IP of the instance |")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// driftPrompt is where drift acknowledgement is read from; tests replace it
+var driftPrompt io.Reader = os.Stdin
+
+// refreshOnlyPlan runs a refresh-only plan in the module and returns it as
+// terraform show -json output; tests replace it
+var refreshOnlyPlan = func(ctx *ExecutionContext, varArgs []string) ([]byte, error) {
+	dir, cleanup, err := makeTempDir("terragrunt-drift-")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	planFile := filepath.Join(dir, "drift.tfplan")
+
+	args := append([]string{"plan", "-refresh-only", "-input=false", "-out=" + planFile}, varArgs...)
+	if err := executeTerraform(ctx, args...); err != nil {
+		return nil, fmt.Errorf("refresh-only plan failed: %w", err)
+	}
+
+	return queryTerraform(ctx, "show", "-json", planFile)
+}
+
+// driftedResource is a resource whose real state no longer matches the
+// terraform state
+type driftedResource struct {
+	Address string
+	Actions []string
+}
+
+// parseResourceDrift reads the resource_drift of a plan in show -json form
+func parseResourceDrift(planJSON []byte) ([]driftedResource, error) {
+	var plan struct {
+		ResourceDrift []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_drift"`
+	}
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse refresh-only plan: %w", err)
+	}
+
+	drift := make([]driftedResource, 0, len(plan.ResourceDrift))
+	for _, resource := range plan.ResourceDrift {
+		drift = append(drift, driftedResource{Address: resource.Address, Actions: resource.Change.Actions})
+	}
+	return drift, nil
+}
+
+// driftSummary lists drifted resources one per line
+func driftSummary(drift []driftedResource) string {
+	var b strings.Builder
+	for _, resource := range drift {
+		fmt.Fprintf(&b, "  %s (%s)\n", resource.Address, strings.Join(resource.Actions, ", "))
+	}
+	return b.String()
+}
+
+// checkDrift runs a refresh-only plan and, if resources changed outside
+// terraform, requires acknowledgement before the apply goes ahead:
+// --terragrunt-accept-drift, or answering the prompt when interactive
+func checkDrift(ctx *ExecutionContext, varArgs []string) error {
+	logger.Info("Checking for drift with a refresh-only plan")
+	planJSON, err := refreshOnlyPlan(ctx, varArgs)
+	if err != nil {
+		return err
+	}
+	drift, err := parseResourceDrift(planJSON)
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		logger.Info("No drift detected")
+		return nil
+	}
+
+	summary := driftSummary(drift)
+	logger.Warnf("Drift detected in %d resource(s) changed outside terraform:\n%s", len(drift), summary)
+
+	if ctx.Config.AcceptDrift {
+		logger.Warn("Applying anyway (--terragrunt-accept-drift)")
+		return nil
+	}
+	if ctx.Config.NonInteractive {
+		return fmt.Errorf("drift detected in %d resource(s); rerun with --terragrunt-accept-drift to apply anyway:\n%s", len(drift), summary)
+	}
+
+	fmt.Fprint(os.Stderr, "Apply anyway and bundle these changes into the apply? [y/N]: ")
+	answer, _ := bufio.NewReader(driftPrompt).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("apply cancelled: drift in %d resource(s) was not acknowledged", len(drift))
+	}
+}
+
+// terraformVarArgs returns the -var and -var-file arguments of args
+func terraformVarArgs(args []string) []string {
+	var vars []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-var=") || strings.HasPrefix(arg, "-var-file=") {
+			vars = append(vars, arg)
+		}
+	}
+	return vars
+}
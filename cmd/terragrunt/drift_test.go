@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDrift makes refreshOnlyPlan return plan and prompts read answer
+func stubDrift(t *testing.T, plan []byte, answer string) *int {
+	t.Helper()
+	originalPlan, originalPrompt := refreshOnlyPlan, driftPrompt
+	t.Cleanup(func() { refreshOnlyPlan, driftPrompt = originalPlan, originalPrompt })
+
+	runs := 0
+	refreshOnlyPlan = func(*ExecutionContext, []string) ([]byte, error) {
+		runs++
+		return plan, nil
+	}
+	driftPrompt = strings.NewReader(answer)
+	return &runs
+}
+
+func driftFixture(t *testing.T) []byte {
+	t.Helper()
+	plan, err := os.ReadFile(filepath.Join("testdata", "drift_plan.json"))
+	require.NoError(t, err)
+	return plan
+}
+
+func TestParseResourceDrift(t *testing.T) {
+	drift, err := parseResourceDrift(driftFixture(t))
+	require.NoError(t, err)
+	assert.Equal(t, []driftedResource{
+		{Address: "google_compute_firewall.allow_ssh", Actions: []string{"update"}},
+		{Address: "google_storage_bucket.scratch", Actions: []string{"delete"}},
+	}, drift)
+	assert.Equal(t, "  google_compute_firewall.allow_ssh (update)\n  google_storage_bucket.scratch (delete)\n", driftSummary(drift))
+}
+
+func TestCheckDriftNonInteractive(t *testing.T) {
+	stubDrift(t, driftFixture(t), "")
+	config := defaultTerragruntConfig()
+	config.NonInteractive = true
+	ctx := &ExecutionContext{Config: config}
+
+	err := checkDrift(ctx, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "drift detected in 2 resource(s); rerun with --terragrunt-accept-drift")
+	assert.Contains(t, err.Error(), "google_storage_bucket.scratch (delete)")
+
+	config.AcceptDrift = true
+	assert.NoError(t, checkDrift(ctx, nil))
+}
+
+func TestCheckDriftPrompt(t *testing.T) {
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig()}
+
+	stubDrift(t, driftFixture(t), "y\n")
+	assert.NoError(t, checkDrift(ctx, nil))
+
+	stubDrift(t, driftFixture(t), "\n")
+	assert.ErrorContains(t, checkDrift(ctx, nil), "apply cancelled: drift in 2 resource(s) was not acknowledged")
+}
+
+func TestCheckDriftNoDrift(t *testing.T) {
+	runs := stubDrift(t, []byte(`{"format_version":"1.2","resource_changes":[]}`), "")
+	config := defaultTerragruntConfig()
+	config.NonInteractive = true
+
+	assert.NoError(t, checkDrift(&ExecutionContext{Config: config}, nil))
+	assert.Equal(t, 1, *runs)
+}
+
+func TestTerraformVarArgs(t *testing.T) {
+	args := []string{"apply", "-auto-approve", "-var=region=europe-west1", "-parallelism=5", "-var-file=prod.tfvars"}
+	assert.Equal(t, []string{"-var=region=europe-west1", "-var-file=prod.tfvars"}, terraformVarArgs(args))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isExternalModule reports whether dir lies outside root
+func isExternalModule(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return true
+	}
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// externalDependencies returns the dependencies in graph that live outside
+// root and aren't part of the graph themselves
+func externalDependencies(root string, graph map[string][]string) []string {
+	seen := make(map[string]bool)
+	var external []string
+	for _, deps := range graph {
+		for _, dep := range deps {
+			if _, ok := graph[dep]; ok || seen[dep] || !isExternalModule(root, dep) {
+				continue
+			}
+			seen[dep] = true
+			external = append(external, dep)
+		}
+	}
+	sort.Strings(external)
+	return external
+}
+
+// resolveExternalDependencies handles dependencies outside the working
+// directory. With include_external_dependencies they, and anything they
+// depend on, are added to graph so run-all executes them. Otherwise they stay
+// out of the run and only their outputs are read, up front so the run fails
+// before anything executes if they can't be, and cached for the dependents
+// to load them from; ignore_external_dependencies skips even that.
+func resolveExternalDependencies(ctx *ExecutionContext, graph map[string][]string) (map[string][]string, error) {
+	external := externalDependencies(ctx.WorkingDir, graph)
+	if len(external) == 0 {
+		return graph, nil
+	}
+
+	if ctx.Config.IncludeExternalDependencies {
+		for len(external) > 0 {
+			for _, module := range external {
+				logger.Infof("Including external dependency: %s", module)
+				deps, err := moduleDependencies(ctx, module)
+				if err != nil {
+					return nil, err
+				}
+				graph[module] = deps
+			}
+			external = externalDependencies(ctx.WorkingDir, graph)
+		}
+		return graph, nil
+	}
+
+	if ctx.Config.IgnoreExternalDependencies {
+		for _, module := range external {
+			logger.Debugf("Ignoring external dependency: %s", module)
+		}
+		return graph, nil
+	}
+
+	for _, module := range external {
+		logger.Warnf("External dependency %s is not part of this run; reading its outputs only (use --terragrunt-include-external-dependencies to run it)", module)
+		outputs, err := readTerraformOutputs(ctx, module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read outputs of external dependency %s: %w", module, err)
+		}
+		// The module is not applied in this run, so its outputs hold
+		dependencyStateCache.put("", terraformOutputKey(module), outputs)
+	}
+	return graph, nil
+}
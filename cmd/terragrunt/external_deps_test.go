@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeExternalTree lays out live/app depending on shared/vpc, which sits
+// outside the live working directory and itself depends on shared/org
+func writeExternalTree(t *testing.T) (string, *ExecutionContext) {
+	t.Helper()
+	root := t.TempDir()
+	modules := map[string]string{
+		"live/app":   `dependency "vpc" { config_path = "../../shared/vpc" }`,
+		"shared/vpc": `dependency "org" { config_path = "../org" }`,
+		"shared/org": ``,
+	}
+	for name, hcl := range modules {
+		dir := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(hcl), 0644))
+	}
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: filepath.Join(root, "live")}
+	return root, ctx
+}
+
+func externalRunOrder(t *testing.T, ctx *ExecutionContext) []string {
+	t.Helper()
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	graph, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	graph, err = resolveExternalDependencies(ctx, graph)
+	require.NoError(t, err)
+	order, err := topologicalSort(graph)
+	require.NoError(t, err)
+	return order
+}
+
+func TestExternalDependenciesIncludedWithFlag(t *testing.T) {
+	root, ctx := writeExternalTree(t)
+	calls := stubModuleOutputs(t, root, nil)
+	ctx.Config.IncludeExternalDependencies = true
+
+	order := externalRunOrder(t, ctx)
+	assert.Equal(t, []string{
+		filepath.Join(root, "shared/org"),
+		filepath.Join(root, "shared/vpc"),
+		filepath.Join(root, "live/app"),
+	}, order)
+	assert.Empty(t, *calls)
+}
+
+func TestExternalDependenciesReadOnlyByDefault(t *testing.T) {
+	root, ctx := writeExternalTree(t)
+	calls := stubModuleOutputs(t, root, map[string]string{
+		"shared/vpc": `{"network_id": {"value": "net-1", "type": "string"}}`,
+	})
+
+	order := externalRunOrder(t, ctx)
+	assert.Equal(t, []string{filepath.Join(root, "live/app")}, order)
+	assert.Equal(t, []string{"shared/vpc"}, *calls)
+
+	// The dependents load the outputs read up front
+	app := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: filepath.Join(root, "live/app"), Dependencies: map[string]interface{}{}}
+	app.Config.Dependencies = []DependencyConfig{{Name: "vpc", ConfigPath: "../../shared/vpc", Enabled: true}}
+	require.NoError(t, loadDependencyOutputs(app))
+	assert.Equal(t, "net-1", app.Dependencies["vpc.network_id"])
+	assert.Equal(t, []string{"shared/vpc"}, *calls)
+
+	// Unreadable outputs fail the run before anything executes
+	stubModuleOutputs(t, root, nil)
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	graph, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	_, err = resolveExternalDependencies(ctx, graph)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "external dependency")
+
+	// Ignoring external dependencies skips reading them at all
+	ctx.Config.IgnoreExternalDependencies = true
+	calls = stubModuleOutputs(t, root, nil)
+	assert.Equal(t, []string{filepath.Join(root, "live/app")}, externalRunOrder(t, ctx))
+	assert.Empty(t, *calls)
+}
+
+func TestIsExternalModule(t *testing.T) {
+	assert.False(t, isExternalModule("/repo/live", "/repo/live/app"))
+	assert.False(t, isExternalModule("/repo/live", "/repo/live/..app"))
+	assert.True(t, isExternalModule("/repo/live", "/repo/shared/vpc"))
+	assert.True(t, isExternalModule("/repo/live", "/repo"))
+}
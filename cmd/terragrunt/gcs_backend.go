@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// gcsStatePermissions are what terraform needs on the state bucket
+var gcsStatePermissions = []string{"storage.objects.get", "storage.objects.create", "storage.objects.list"}
+
+// gcsImpersonation returns the service account state access runs as; the
+// backend setting wins over the gcp one
+func gcsImpersonation(config *TerragruntConfig) string {
+	if config.Backend.ImpersonateServiceAccount != "" {
+		return config.Backend.ImpersonateServiceAccount
+	}
+	return config.GCP.ImpersonateServiceAccount
+}
+
+// gcsEncryptionKeyEnvVar is where the gcs backend reads a customer-supplied
+// encryption key from. Unlike -backend-config arguments, the environment
+// isn't visible to other users through ps or /proc.
+const gcsEncryptionKeyEnvVar = "GOOGLE_ENCRYPTION_KEY"
+
+// gcsBackendConfigArgs returns the -backend-config arguments terraform init
+// needs for the gcs backend, including impersonation and KMS encryption. A
+// customer-supplied key goes through gcsBackendEnv instead.
+func gcsBackendConfigArgs(config *TerragruntConfig) ([]string, error) {
+	backend := config.Backend
+	if backend.EncryptionKey != "" && backend.KMSEncryptionKey != "" {
+		return nil, fmt.Errorf("gcs backend: set encryption_key or kms_encryption_key, not both")
+	}
+
+	args := []string{
+		fmt.Sprintf("-backend-config=bucket=%s", backend.Bucket),
+		fmt.Sprintf("-backend-config=prefix=%s", backend.Prefix),
+	}
+	if sa := gcsImpersonation(config); sa != "" {
+		args = append(args, fmt.Sprintf("-backend-config=impersonate_service_account=%s", sa))
+	}
+	if backend.KMSEncryptionKey != "" {
+		args = append(args, fmt.Sprintf("-backend-config=kms_encryption_key=%s", backend.KMSEncryptionKey))
+	}
+	return args, nil
+}
+
+// gcsBackendEnv returns the environment terraform needs for the gcs backend
+// on every command that touches state
+func gcsBackendEnv(config *TerragruntConfig) map[string]string {
+	if config.Backend.Type != "gcs" || config.Backend.EncryptionKey == "" {
+		return nil
+	}
+	return map[string]string{gcsEncryptionKeyEnvVar: config.Backend.EncryptionKey}
+}
+
+// validateGCSBackend checks the encryption settings and that state access
+// will work before terraform init tries it
+func validateGCSBackend(ctx context.Context, config *TerragruntConfig) error {
+	backend := config.Backend
+	if backend.Bucket == "" {
+		return fmt.Errorf("gcs backend requires a bucket")
+	}
+	if backend.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(backend.EncryptionKey)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("gcs backend: encryption_key must be a base64-encoded 32 byte AES-256 key")
+		}
+	}
+	if backend.KMSEncryptionKey != "" && !strings.Contains(backend.KMSEncryptionKey, "/cryptoKeys/") {
+		return fmt.Errorf("gcs backend: kms_encryption_key %q is not a crypto key name (projects/*/locations/*/keyRings/*/cryptoKeys/*)", backend.KMSEncryptionKey)
+	}
+	return checkGCSBackendAccess(ctx, backend.Bucket, gcsImpersonation(config), backend.KMSEncryptionKey)
+}
+
+// checkGCSBackendAccess verifies, as serviceAccount when set, that the state
+// bucket grants the permissions terraform needs and that kmsKey exists;
+// tests replace it
+var checkGCSBackendAccess = func(ctx context.Context, bucket, serviceAccount, kmsKey string) error {
+	opts, err := impersonationOptions(ctx, serviceAccount)
+	if err != nil {
+		return err
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	granted, err := client.Bucket(bucket).IAM().TestPermissions(ctx, gcsStatePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to check access to state bucket %s: %w", bucket, err)
+	}
+	if missing := missingPermissions(gcsStatePermissions, granted); len(missing) > 0 {
+		who := "current credentials"
+		if serviceAccount != "" {
+			who = serviceAccount
+		}
+		return fmt.Errorf("%s lack %s on state bucket %s", who, strings.Join(missing, ", "), bucket)
+	}
+
+	if kmsKey == "" {
+		return nil
+	}
+	kmsClient, err := kms.NewKeyManagementClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create KMS client: %w", err)
+	}
+	defer kmsClient.Close()
+	if _, err := kmsClient.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: kmsKey}); err != nil {
+		return fmt.Errorf("kms_encryption_key %s is not accessible: %w", kmsKey, err)
+	}
+	return nil
+}
+
+// impersonationOptions returns the client options acting as serviceAccount,
+// or none to use the current credentials
+func impersonationOptions(ctx context.Context, serviceAccount string) ([]option.ClientOption, error) {
+	if serviceAccount == "" {
+		return nil, nil
+	}
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: serviceAccount,
+		Scopes:          []string{"https://www.googleapis.com/auth/cloud-platform"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s: %w", serviceAccount, err)
+	}
+	return []option.ClientOption{option.WithTokenSource(ts)}, nil
+}
+
+func missingPermissions(required, granted []string) []string {
+	have := make(map[string]bool, len(granted))
+	for _, permission := range granted {
+		have[permission] = true
+	}
+	var missing []string
+	for _, permission := range required {
+		if !have[permission] {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKMSKey = "projects/demo/locations/europe-west1/keyRings/state/cryptoKeys/terraform"
+
+func gcsConfig() *TerragruntConfig {
+	config := defaultTerragruntConfig()
+	config.Backend.Type = "gcs"
+	config.Backend.Bucket = "demo-tf-state"
+	config.Backend.Prefix = "network"
+	return config
+}
+
+func TestGCSBackendConfigArgs(t *testing.T) {
+	config := gcsConfig()
+	config.GCP.ImpersonateServiceAccount = "terraform@demo.iam.gserviceaccount.com"
+	config.Backend.KMSEncryptionKey = testKMSKey
+
+	args, err := gcsBackendConfigArgs(config)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"-backend-config=bucket=demo-tf-state",
+		"-backend-config=prefix=network",
+		"-backend-config=impersonate_service_account=terraform@demo.iam.gserviceaccount.com",
+		"-backend-config=kms_encryption_key=" + testKMSKey,
+	}, args)
+
+	// The backend's own service account and a customer-supplied key
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	config.Backend.ImpersonateServiceAccount = "state@demo.iam.gserviceaccount.com"
+	config.Backend.KMSEncryptionKey = ""
+	config.Backend.EncryptionKey = key
+	args, err = gcsBackendConfigArgs(config)
+	require.NoError(t, err)
+	assert.Contains(t, args, "-backend-config=impersonate_service_account=state@demo.iam.gserviceaccount.com")
+	assert.NotContains(t, strings.Join(args, " "), key, "the key stays off the command line")
+	assert.Equal(t, map[string]string{"GOOGLE_ENCRYPTION_KEY": key}, gcsBackendEnv(config))
+
+	config.Backend.KMSEncryptionKey = testKMSKey
+	_, err = gcsBackendConfigArgs(config)
+	assert.ErrorContains(t, err, "not both")
+}
+
+func TestTerraformGetsEncryptionKeyThroughEnvironment(t *testing.T) {
+	fake := useFakeRunner(t)
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	config := gcsConfig()
+	config.Backend.EncryptionKey = key
+	ctx := &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Environment: map[string]string{}}
+
+	require.NoError(t, executeTerraform(ctx, "plan"))
+
+	require.Len(t, fake.calls, 1)
+	assert.NotContains(t, strings.Join(fake.calls[0].Args, " "), key)
+	assert.Contains(t, fake.calls[0].Env, "GOOGLE_ENCRYPTION_KEY="+key)
+}
+
+func TestValidateGCSBackend(t *testing.T) {
+	original := checkGCSBackendAccess
+	t.Cleanup(func() { checkGCSBackendAccess = original })
+	var checked []string
+	checkGCSBackendAccess = func(_ context.Context, bucket, serviceAccount, kmsKey string) error {
+		checked = append(checked, strings.Join([]string{bucket, serviceAccount, kmsKey}, "|"))
+		if serviceAccount == "nobody@demo.iam.gserviceaccount.com" {
+			return errors.New("nobody@demo.iam.gserviceaccount.com lack storage.objects.create on state bucket demo-tf-state")
+		}
+		return nil
+	}
+
+	config := gcsConfig()
+	config.GCP.ImpersonateServiceAccount = "terraform@demo.iam.gserviceaccount.com"
+	config.Backend.KMSEncryptionKey = testKMSKey
+	require.NoError(t, validateGCSBackend(context.Background(), config))
+	assert.Equal(t, []string{"demo-tf-state|terraform@demo.iam.gserviceaccount.com|" + testKMSKey}, checked)
+
+	config.GCP.ImpersonateServiceAccount = "nobody@demo.iam.gserviceaccount.com"
+	assert.ErrorContains(t, validateGCSBackend(context.Background(), config), "lack storage.objects.create")
+
+	// Malformed keys fail before any API call
+	checked = nil
+	config = gcsConfig()
+	config.Backend.KMSEncryptionKey = "terraform-key"
+	assert.ErrorContains(t, validateGCSBackend(context.Background(), config), "is not a crypto key name")
+	config = gcsConfig()
+	config.Backend.EncryptionKey = "c2hvcnQ="
+	assert.ErrorContains(t, validateGCSBackend(context.Background(), config), "32 byte AES-256 key")
+	assert.Empty(t, checked)
+}
+
+func TestMissingPermissions(t *testing.T) {
+	assert.Equal(t, []string{"storage.objects.create"},
+		missingPermissions(gcsStatePermissions, []string{"storage.objects.list", "storage.objects.get"}))
+	assert.Empty(t, missingPermissions(gcsStatePermissions, gcsStatePermissions))
+}
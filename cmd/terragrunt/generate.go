@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write the generated files of every module",
+	Long: `Render the generate and remote_state blocks of every module under the
+working directory into the module, removing files earlier configs generated.
+With --check nothing is written: the command prints a diff of every generated
+file that differs from the one on disk and fails if there are any, so CI can
+catch hand-edited or stale committed files.`,
+	RunE: runGenerate,
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+	check, _ := cmd.Flags().GetBool("check")
+
+	modules, err := generatedModules(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !check {
+		for _, moduleCtx := range modules {
+			if err := generateFiles(moduleCtx); err != nil {
+				return fmt.Errorf("module %s: %w", moduleCtx.WorkingDir, err)
+			}
+		}
+		logger.Infof("Generated files in %d module(s)", len(modules))
+		return nil
+	}
+
+	drifted, err := checkGeneratedFiles(ctx, modules)
+	if err != nil {
+		return err
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("generated files differ in %d module(s), run 'terragrunt generate'", len(drifted))
+	}
+	logger.Infof("Generated files in %d module(s) are up to date", len(modules))
+	return nil
+}
+
+// generatedModules returns a context, with the module's own config, for
+// every module under the working directory that generates into its own
+// directory. Modules with a terraform source generate into their cache,
+// which is never committed, so they are left out.
+func generatedModules(ctx *ExecutionContext) ([]*ExecutionContext, error) {
+	modules, err := findModules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find modules: %w", err)
+	}
+
+	var contexts []*ExecutionContext
+	for _, module := range modules {
+		config := defaultTerragruntConfig()
+		if err := loadModuleConfig(ctx, filepath.Join(module, "terragrunt.hcl"), config); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", module, err)
+		}
+		if config.Terraform.Source != "" || ctx.Config.IsolateWorkingDir {
+			logger.Debugf("Skipping %s: it generates into %s", module, terragruntCacheDir)
+			continue
+		}
+		contexts = append(contexts, &ExecutionContext{Config: config, WorkingDir: module, Logger: ctx.Logger})
+	}
+	return contexts, nil
+}
+
+// checkGeneratedFiles prints the diff of each module whose generated files
+// don't match its config and returns those modules
+func checkGeneratedFiles(ctx *ExecutionContext, modules []*ExecutionContext) ([]string, error) {
+	var drifted []string
+	for _, moduleCtx := range modules {
+		diff, err := generatedFilesDiff(moduleCtx)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", moduleCtx.WorkingDir, err)
+		}
+		if diff == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(ctx.WorkingDir, moduleCtx.WorkingDir)
+		if err != nil {
+			rel = moduleCtx.WorkingDir
+		}
+		drifted = append(drifted, moduleCtx.WorkingDir)
+		fmt.Fprintf(diffOutput, "# %s\n%s", filepath.ToSlash(rel), diff)
+	}
+	return drifted, nil
+}
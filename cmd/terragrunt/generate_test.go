@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const generateProviderHCL = `generate "provider" {
+  path     = "provider.tf"
+  contents = "provider \"google\" {}\n"
+}
+`
+
+// writeGenerateTree creates modules app and db generating provider.tf,
+// committed along with the manifest, with app's copy matching and db's
+// hand-edited, and module vpc using a terraform source
+func writeGenerateTree(t *testing.T) *ExecutionContext {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"app/terragrunt.hcl":  generateProviderHCL,
+		"app/provider.tf":     "provider \"google\" {}\n",
+		"db/terragrunt.hcl":   generateProviderHCL,
+		"db/provider.tf":      "provider \"google\" {\n  project = \"edited\"\n}\n",
+		"vpc/terragrunt.hcl":  "terraform {\n  source = \"../modules/vpc\"\n}\n" + generateProviderHCL,
+		"modules/vpc/main.tf": "",
+
+		"app/" + generatedManifestFile: `{"files": ["provider.tf"]}`,
+		"db/" + generatedManifestFile:  `{"files": ["provider.tf"]}`,
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+	return &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root, Logger: logger}
+}
+
+func captureDiffOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	original := diffOutput
+	diffOutput = &out
+	t.Cleanup(func() { diffOutput = original })
+	return &out
+}
+
+func TestCheckGeneratedFiles(t *testing.T) {
+	ctx := writeGenerateTree(t)
+	out := captureDiffOutput(t)
+
+	modules, err := generatedModules(ctx)
+	require.NoError(t, err)
+	var dirs []string
+	for _, moduleCtx := range modules {
+		dirs = append(dirs, moduleCtx.WorkingDir)
+	}
+	assert.ElementsMatch(t, []string{filepath.Join(ctx.WorkingDir, "app"), filepath.Join(ctx.WorkingDir, "db")}, dirs)
+
+	drifted, err := checkGeneratedFiles(ctx, modules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(ctx.WorkingDir, "db")}, drifted)
+	assert.Contains(t, out.String(), "# db\n--- a/provider.tf\n+++ b/provider.tf\n")
+	assert.Contains(t, out.String(), `-  project = "edited"`)
+	assert.NotContains(t, out.String(), "# app")
+
+	// Nothing is written while checking
+	edited, err := os.ReadFile(filepath.Join(ctx.WorkingDir, "db", "provider.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(edited), "edited")
+}
+
+func TestCheckGeneratedFilesPassesOnceGenerated(t *testing.T) {
+	ctx := writeGenerateTree(t)
+	out := captureDiffOutput(t)
+
+	modules, err := generatedModules(ctx)
+	require.NoError(t, err)
+	for _, moduleCtx := range modules {
+		require.NoError(t, generateFiles(moduleCtx))
+	}
+
+	drifted, err := checkGeneratedFiles(ctx, modules)
+	require.NoError(t, err)
+	assert.Empty(t, drifted)
+	assert.Empty(t, out.String())
+}
+
+func TestCheckGeneratedFilesReportsMissingFiles(t *testing.T) {
+	ctx := writeGenerateTree(t)
+	captureDiffOutput(t)
+	require.NoError(t, os.Remove(filepath.Join(ctx.WorkingDir, "app", "provider.tf")))
+
+	modules, err := generatedModules(ctx)
+	require.NoError(t, err)
+	drifted, err := checkGeneratedFiles(ctx, modules)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(ctx.WorkingDir, "app"), filepath.Join(ctx.WorkingDir, "db")}, drifted)
+}
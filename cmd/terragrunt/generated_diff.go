@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// inputsSnapshotFile is where the inputs snapshot used to be kept, next to
+// the module's sources; clean still removes it
+const inputsSnapshotFile = ".terragrunt-inputs.json"
+
+// diffOutput is where --terragrunt-diff writes; tests replace it
+var diffOutput io.Writer = os.Stdout
+
+// unifiedDiff returns a unified diff of before and after, or "" when equal
+func unifiedDiff(path, before, after string) (string, error) {
+	if before == after {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(before),
+		B:        diffLines(after),
+		FromFile: "a/" + filepath.ToSlash(path),
+		ToFile:   "b/" + filepath.ToSlash(path),
+		Context:  3,
+	})
+}
+
+// diffLines splits text into newline-terminated lines
+func diffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+// generatedFilesDiff compares the generated files on disk with what the
+// current config would generate, including stale files that would be removed
+func generatedFilesDiff(ctx *ExecutionContext) (string, error) {
+	desired, err := desiredGeneratedFiles(ctx.Config)
+	if err != nil {
+		return "", err
+	}
+	previous, err := readGeneratedManifest(ctx.terraformDir())
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(desired))
+	for path := range desired {
+		paths = append(paths, path)
+	}
+	for _, path := range previous {
+		if _, ok := desired[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var out strings.Builder
+	for _, path := range paths {
+		current, err := os.ReadFile(filepath.Join(ctx.terraformDir(), path))
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		diff, err := unifiedDiff(path, string(current), desired[path])
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(diff)
+	}
+	return out.String(), nil
+}
+
+// inputsDiff compares the current inputs with the snapshot saved by the
+// previous diff run
+func inputsDiff(ctx *ExecutionContext) (string, error) {
+	current, err := inputsSnapshot(ctx.Config)
+	if err != nil {
+		return "", err
+	}
+	previous, err := os.ReadFile(inputsSnapshotPath(ctx))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read previous inputs: %w", err)
+	}
+	return unifiedDiff("inputs.json", string(previous), current)
+}
+
+// saveInputsSnapshot records the current inputs for the next diff
+func saveInputsSnapshot(ctx *ExecutionContext) error {
+	current, err := inputsSnapshot(ctx.Config)
+	if err != nil {
+		return err
+	}
+	path := inputsSnapshotPath(ctx)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return os.WriteFile(path, []byte(current), 0600)
+}
+
+// inputsSnapshotPath is where the inputs of the last plan run with
+// --terragrunt-diff are kept, in the module cache next to its tfvars
+func inputsSnapshotPath(ctx *ExecutionContext) string {
+	return strings.TrimSuffix(inputsTfvarsPath(ctx), ".tfvars.json") + ".snapshot.json"
+}
+
+// inputsSnapshot encodes the inputs of config with their secrets redacted,
+// so neither the snapshot nor the diff shows them
+func inputsSnapshot(config *TerragruntConfig) (string, error) {
+	inputs := make(map[string]interface{}, len(config.Variables))
+	for name, value := range config.Variables {
+		if sensitiveInput(config, name) {
+			value = redactedValue
+		}
+		inputs[name] = value
+	}
+	// encoding/json sorts map keys, so equal inputs give equal snapshots
+	data, err := json.MarshalIndent(inputs, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode inputs: %w", err)
+	}
+	return redactSecrets(string(data)) + "\n", nil
+}
+
+// printDiff writes diff, or a note that there is nothing to show
+func printDiff(what, diff string) {
+	if diff == "" {
+		fmt.Fprintf(diffOutput, "No changes to %s.\n", what)
+		return
+	}
+	fmt.Fprint(diffOutput, diff)
+}
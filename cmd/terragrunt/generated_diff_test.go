@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedFilesDiffShowsChanges(t *testing.T) {
+	ctx := newGenerateContext(t,
+		GenerateConfig{Name: "provider", Path: "provider.tf", Contents: "provider \"google\" {\n  region = \"us-central1\"\n}\n"},
+	)
+	require.NoError(t, generateFiles(ctx))
+
+	ctx.Config.Generate[0].Contents = "provider \"google\" {\n  region = \"europe-west1\"\n}\n"
+	diff, err := generatedFilesDiff(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `--- a/provider.tf
++++ b/provider.tf
+@@ -1,3 +1,3 @@
+ provider "google" {
+-  region = "us-central1"
++  region = "europe-west1"
+ }
+`, diff)
+
+	// Nothing is written while diffing
+	data, err := os.ReadFile(filepath.Join(ctx.WorkingDir, "provider.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "us-central1")
+}
+
+func TestGeneratedFilesDiffEmptyWhenIdentical(t *testing.T) {
+	ctx := newGenerateContext(t,
+		GenerateConfig{Name: "provider", Path: "provider.tf", Contents: "provider \"google\" {}\n"},
+		GenerateConfig{Name: "versions", Path: "versions.tf", Contents: "terraform {}\n"},
+	)
+	require.NoError(t, generateFiles(ctx))
+
+	diff, err := generatedFilesDiff(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	// Dropping a generate block shows the file being removed
+	ctx.Config.Generate = ctx.Config.Generate[:1]
+	diff, err = generatedFilesDiff(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "--- a/versions.tf\n+++ b/versions.tf\n@@ -1 +0,0 @@\n-terraform {}\n", diff)
+}
+
+func TestInputsDiffAgainstPreviousRun(t *testing.T) {
+	ctx := newGenerateContext(t)
+	ctx.Config.Variables = map[string]interface{}{"region": "us-central1", "machine_type": "e2-small"}
+	require.NoError(t, saveInputsSnapshot(ctx))
+
+	diff, err := inputsDiff(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	ctx.Config.Variables["machine_type"] = "e2-medium"
+	diff, err = inputsDiff(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-  \"machine_type\": \"e2-small\",\n+  \"machine_type\": \"e2-medium\",\n")
+}
+
+func TestInputsSnapshotRedactsSecretsAndStaysOutOfTheSources(t *testing.T) {
+	ctx := newGenerateContext(t)
+	ctx.Config.Variables = map[string]interface{}{"region": "us-central1", "db_password": "hunter2-correct-horse"}
+	require.NoError(t, saveInputsSnapshot(ctx))
+
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, inputsSnapshotFile))
+	path := inputsSnapshotPath(ctx)
+	require.True(t, strings.HasPrefix(path, filepath.Join(ctx.WorkingDir, terragruntCacheDir)+string(os.PathSeparator)), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2-correct-horse")
+	assert.Contains(t, string(data), `"db_password": "(redacted)"`)
+
+	ctx.Config.Variables["db_password"] = "another-secret-value"
+	diff, err := inputsDiff(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
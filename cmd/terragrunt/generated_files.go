@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// generatedManifestFile records, relative to the working directory, every file
+// terragrunt generated there. Only files listed in it are ever removed, so
+// user-authored files with the same names are never touched.
+const generatedManifestFile = ".terragrunt-generated.json"
+
+type generatedManifest struct {
+	Files []string `json:"files"`
+}
+
+// legacyBackendPattern matches the backend.tf terragrunt generated before it
+// kept a manifest, which is taken over as generated on the first run that
+// finds no manifest
+var legacyBackendPattern = regexp.MustCompile(`\Aterraform \{\n  backend "[^"\n]*" \{\n    bucket = "[^"\n]*"\n    prefix = "[^"\n]*"\n  \}\n\}\n\z`)
+
+// backendFilePath is where the remote_state block generates the backend
+func backendFilePath(config *TerragruntConfig) string {
+	if p, ok := config.RemoteState.Generate["path"].(string); ok && p != "" {
+		return filepath.Clean(p)
+	}
+	return "backend.tf"
+}
+
+// desiredGeneratedFiles returns the files the current config generates,
+// keyed by path relative to the working directory
+func desiredGeneratedFiles(config *TerragruntConfig) (map[string]string, error) {
+	files := make(map[string]string)
+
+	if config.RemoteState.Generate != nil {
+		files[backendFilePath(config)] = generateBackendTF(config)
+	}
+
+	for _, gen := range config.Generate {
+		if gen.Path == "" {
+			return nil, fmt.Errorf("generate block %q has no path", gen.Name)
+		}
+		path := filepath.Clean(gen.Path)
+		if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(os.PathSeparator)) {
+			return nil, fmt.Errorf("generate block %q writes outside the working directory: %s", gen.Name, gen.Path)
+		}
+		files[path] = gen.Contents
+	}
+
+	return files, nil
+}
+
+// generatedIfExists returns the if_exists setting of each generated file,
+// keyed like desiredGeneratedFiles
+func generatedIfExists(config *TerragruntConfig) (map[string]string, error) {
+	settings := make(map[string]string)
+	add := func(name, path, ifExists string) error {
+		switch ifExists {
+		case "", "error", "overwrite", "skip":
+		default:
+			return fmt.Errorf("generate block %q has unsupported if_exists %q (expected error, overwrite or skip)", name, ifExists)
+		}
+		settings[filepath.Clean(path)] = ifExists
+		return nil
+	}
+
+	if config.RemoteState.Generate != nil {
+		ifExists, _ := config.RemoteState.Generate["if_exists"].(string)
+		if err := add("remote_state", backendFilePath(config), ifExists); err != nil {
+			return nil, err
+		}
+	}
+	for _, gen := range config.Generate {
+		if err := add(gen.Name, gen.Path, gen.IfExists); err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+func generateFiles(ctx *ExecutionContext) error {
+	desired, err := desiredGeneratedFiles(ctx.Config)
+	if err != nil {
+		return err
+	}
+	ifExists, err := generatedIfExists(ctx.Config)
+	if err != nil {
+		return err
+	}
+
+	previous, err := readGeneratedManifest(ctx.terraformDir())
+	if err != nil {
+		return err
+	}
+
+	// Remove files generated by an earlier config that the current one no longer produces
+	for _, path := range previous {
+		if _, ok := desired[path]; ok {
+			continue
+		}
+		if err := removeGeneratedFile(ctx.terraformDir(), path); err != nil {
+			return err
+		}
+		logger.Infof("Removed stale generated file %s", path)
+	}
+
+	generated := make(map[string]bool, len(previous))
+	for _, path := range previous {
+		generated[path] = true
+	}
+	if _, err := os.Lstat(filepath.Join(ctx.terraformDir(), generatedManifestFile)); os.IsNotExist(err) && ctx.Config.RemoteState.Generate != nil {
+		path := backendFilePath(ctx.Config)
+		if data, err := os.ReadFile(filepath.Join(ctx.terraformDir(), path)); err == nil && legacyBackendPattern.Match(data) {
+			logger.Debugf("Taking over %s, generated before terragrunt recorded the files it generates", path)
+			generated[path] = true
+		}
+	}
+
+	paths := make([]string, 0, len(desired))
+	for path, contents := range desired {
+		target := filepath.Join(ctx.terraformDir(), path)
+		// A file terragrunt didn't generate is only replaced when asked to
+		if _, err := os.Lstat(target); err == nil && !generated[path] {
+			switch ifExists[path] {
+			case "overwrite":
+				logger.Warnf("Overwriting %s, which terragrunt didn't generate", path)
+			case "skip":
+				logger.Infof("Not generating %s, which already exists", path)
+				continue
+			default:
+				return fmt.Errorf("refusing to generate %s over a file terragrunt didn't generate; set if_exists to overwrite or skip", path)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", path, err)
+		}
+		if err := os.WriteFile(target, []byte(contents), 0644); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+
+	return writeGeneratedManifest(ctx.terraformDir(), paths)
+}
+
+// cleanModuleGeneratedFiles removes the files generated for the module, in
+// its directory and in its cache copy
+func cleanModuleGeneratedFiles(ctx *ExecutionContext) error {
+	if err := cleanGeneratedFiles(ctx.WorkingDir); err != nil {
+		return err
+	}
+	if dir := cachedTerraformDir(ctx, ctx.WorkingDir); dir != ctx.WorkingDir {
+		return cleanGeneratedFiles(dir)
+	}
+	return nil
+}
+
+// cleanGeneratedFiles removes every file recorded as generated in dir
+func cleanGeneratedFiles(dir string) error {
+	previous, err := readGeneratedManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range previous {
+		if err := removeGeneratedFile(dir, path); err != nil {
+			return err
+		}
+		logger.Debugf("Removed generated file %s", path)
+	}
+	return writeGeneratedManifest(dir, nil)
+}
+
+func removeGeneratedFile(dir, path string) error {
+	if err := os.Remove(filepath.Join(dir, path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove generated file %s: %w", path, err)
+	}
+	return nil
+}
+
+func readGeneratedManifest(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, generatedManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated files manifest: %w", err)
+	}
+
+	var manifest generatedManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse generated files manifest: %w", err)
+	}
+	return manifest.Files, nil
+}
+
+// writeGeneratedManifest records paths as generated, removing the manifest
+// when nothing is generated
+func writeGeneratedManifest(dir string, paths []string) error {
+	manifestPath := filepath.Join(dir, generatedManifestFile)
+	if len(paths) == 0 {
+		if err := os.Remove(manifestPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove generated files manifest: %w", err)
+		}
+		return nil
+	}
+
+	sort.Strings(paths)
+	data, err := json.MarshalIndent(generatedManifest{Files: paths}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write generated files manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGenerateContext(t *testing.T, generate ...GenerateConfig) *ExecutionContext {
+	t.Helper()
+	config := defaultTerragruntConfig()
+	config.Generate = generate
+	return &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Logger: logger}
+}
+
+func TestGenerateFilesRemovesOrphans(t *testing.T) {
+	ctx := newGenerateContext(t,
+		GenerateConfig{Name: "provider", Path: "provider.tf", Contents: "provider \"google\" {}\n"},
+		GenerateConfig{Name: "versions", Path: "versions.tf", Contents: "terraform {}\n"},
+	)
+	userFile := filepath.Join(ctx.WorkingDir, "main.tf")
+	require.NoError(t, os.WriteFile(userFile, []byte("# user authored\n"), 0644))
+
+	require.NoError(t, generateFiles(ctx))
+	assert.FileExists(t, filepath.Join(ctx.WorkingDir, "provider.tf"))
+	assert.FileExists(t, filepath.Join(ctx.WorkingDir, "versions.tf"))
+
+	// Rename the provider block's output file and drop versions.tf
+	ctx.Config.Generate = []GenerateConfig{
+		{Name: "provider", Path: "provider_google.tf", Contents: "provider \"google\" {}\n"},
+	}
+	require.NoError(t, generateFiles(ctx))
+
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, "provider.tf"))
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, "versions.tf"))
+	assert.FileExists(t, filepath.Join(ctx.WorkingDir, "provider_google.tf"))
+	assert.FileExists(t, userFile)
+
+	manifest, err := readGeneratedManifest(ctx.WorkingDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"provider_google.tf"}, manifest)
+}
+
+func TestGenerateFilesLeavesUnrecordedFiles(t *testing.T) {
+	ctx := newGenerateContext(t)
+
+	// A hand-written backend.tf was never generated, so it must survive
+	backend := filepath.Join(ctx.WorkingDir, "backend.tf")
+	require.NoError(t, os.WriteFile(backend, []byte("terraform {}\n"), 0644))
+
+	require.NoError(t, generateFiles(ctx))
+	assert.FileExists(t, backend)
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, generatedManifestFile))
+
+	require.NoError(t, cleanGeneratedFiles(ctx.WorkingDir))
+	assert.FileExists(t, backend)
+}
+
+func TestGenerateFilesRefusesToOverwriteUnrecordedFiles(t *testing.T) {
+	ctx := newGenerateContext(t, GenerateConfig{Name: "provider", Path: "provider.tf", Contents: "provider \"google\" {}\n"})
+	provider := filepath.Join(ctx.WorkingDir, "provider.tf")
+	require.NoError(t, os.WriteFile(provider, []byte("# user authored\n"), 0644))
+
+	err := generateFiles(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to generate provider.tf")
+	data, _ := os.ReadFile(provider)
+	assert.Equal(t, "# user authored\n", string(data))
+
+	ctx.Config.Generate[0].IfExists = "skip"
+	require.NoError(t, generateFiles(ctx))
+	data, _ = os.ReadFile(provider)
+	assert.Equal(t, "# user authored\n", string(data))
+	require.NoError(t, cleanGeneratedFiles(ctx.WorkingDir))
+	assert.FileExists(t, provider, "a skipped file isn't terragrunt's to clean")
+
+	ctx.Config.Generate[0].IfExists = "overwrite"
+	require.NoError(t, generateFiles(ctx))
+	data, _ = os.ReadFile(provider)
+	assert.Equal(t, "provider \"google\" {}\n", string(data))
+
+	// Once generated, the file is terragrunt's to rewrite
+	ctx.Config.Generate[0].IfExists = ""
+	ctx.Config.Generate[0].Contents = "provider \"google-beta\" {}\n"
+	require.NoError(t, generateFiles(ctx))
+	data, _ = os.ReadFile(provider)
+	assert.Equal(t, "provider \"google-beta\" {}\n", string(data))
+
+	ctx.Config.Generate[0].IfExists = "replace"
+	assert.ErrorContains(t, generateFiles(ctx), `unsupported if_exists "replace"`)
+}
+
+func TestGenerateFilesTakesOverBackendFromBeforeTheManifest(t *testing.T) {
+	ctx := newGenerateContext(t)
+	ctx.Config.Backend.Type, ctx.Config.Backend.Bucket, ctx.Config.Backend.Prefix = "gcs", "tf-state", "app"
+	ctx.Config.RemoteState.Generate = map[string]interface{}{"path": "backend.tf"}
+	backend := filepath.Join(ctx.WorkingDir, "backend.tf")
+
+	// Generated by an earlier release for another bucket, with no manifest
+	legacy := "terraform {\n  backend \"gcs\" {\n    bucket = \"old-state\"\n    prefix = \"app\"\n  }\n}\n"
+	require.NoError(t, os.WriteFile(backend, []byte(legacy), 0644))
+	require.NoError(t, generateFiles(ctx))
+	data, err := os.ReadFile(backend)
+	require.NoError(t, err)
+	assert.Equal(t, generateBackendTF(ctx.Config), string(data))
+	generated, err := readGeneratedManifest(ctx.WorkingDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backend.tf"}, generated)
+
+	// An edited backend.tf is the user's
+	require.NoError(t, os.Remove(filepath.Join(ctx.WorkingDir, generatedManifestFile)))
+	require.NoError(t, os.WriteFile(backend, []byte(legacy+"# pinned\n"), 0644))
+	assert.ErrorContains(t, generateFiles(ctx), "refusing to generate backend.tf")
+}
+
+func TestCleanGeneratedFiles(t *testing.T) {
+	ctx := newGenerateContext(t, GenerateConfig{Name: "provider", Path: "nested/provider.tf", Contents: "x"})
+	ctx.Config.RemoteState.Generate = map[string]interface{}{"path": "backend.tf"}
+	ctx.Config.Backend = BackendConfig{Type: "gcs", Bucket: "state", Prefix: "app"}
+
+	require.NoError(t, generateFiles(ctx))
+	assert.FileExists(t, filepath.Join(ctx.WorkingDir, "backend.tf"))
+	assert.FileExists(t, filepath.Join(ctx.WorkingDir, "nested", "provider.tf"))
+
+	require.NoError(t, cleanGeneratedFiles(ctx.WorkingDir))
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, "backend.tf"))
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, "nested", "provider.tf"))
+	assert.NoFileExists(t, filepath.Join(ctx.WorkingDir, generatedManifestFile))
+}
+
+func TestGenerateFilesRejectsEscapingPaths(t *testing.T) {
+	ctx := newGenerateContext(t, GenerateConfig{Name: "evil", Path: "../outside.tf"})
+	err := generateFiles(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside the working directory")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// graphSchemaVersion is bumped whenever the JSON graph document changes
+// incompatibly
+const graphSchemaVersion = 1
+
+// graphDocument is the stable `graph-dependencies --format json` output
+type graphDocument struct {
+	SchemaVersion int         `json:"schema_version"`
+	Root          string      `json:"root"`
+	Nodes         []graphNode `json:"nodes"`
+	Edges         []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID           string `json:"id"`
+	Path         string `json:"path"`
+	RelativePath string `json:"relative_path"`
+}
+
+// graphEdge points from a module to a module it depends on
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// buildGraphDocument converts the dependency map into a deterministically
+// ordered document; node IDs are slash-separated paths relative to root
+func buildGraphDocument(root string, graph map[string][]string) graphDocument {
+	doc := graphDocument{
+		SchemaVersion: graphSchemaVersion,
+		Root:          filepath.ToSlash(root),
+		Nodes:         []graphNode{},
+		Edges:         []graphEdge{},
+	}
+
+	id := func(path string) string {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		return filepath.ToSlash(rel)
+	}
+
+	seen := make(map[string]bool)
+	addNode := func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		rel := id(path)
+		doc.Nodes = append(doc.Nodes, graphNode{ID: rel, Path: filepath.ToSlash(path), RelativePath: rel})
+	}
+
+	for _, node := range sortedGraphNodes(graph) {
+		addNode(node)
+		for _, dep := range sortedDeps(graph[node]) {
+			addNode(dep)
+			doc.Edges = append(doc.Edges, graphEdge{From: id(node), To: id(dep)})
+		}
+	}
+
+	sort.Slice(doc.Nodes, func(i, j int) bool { return doc.Nodes[i].ID < doc.Nodes[j].ID })
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		if doc.Edges[i].From != doc.Edges[j].From {
+			return doc.Edges[i].From < doc.Edges[j].From
+		}
+		return doc.Edges[i].To < doc.Edges[j].To
+	})
+	return doc
+}
+
+// marshalGraphJSON renders the versioned graph document
+func marshalGraphJSON(root string, graph map[string][]string) ([]byte, error) {
+	return json.MarshalIndent(buildGraphDocument(root, graph), "", "  ")
+}
+
+func sortedGraphNodes(graph map[string][]string) []string {
+	nodes := make([]string, 0, len(graph))
+	for node := range graph {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func sortedDeps(deps []string) []string {
+	sorted := append([]string(nil), deps...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func sampleGraph() map[string][]string {
+	return map[string][]string{
+		"/infra/prod/app":     {"/infra/prod/vpc", "/infra/prod/db"},
+		"/infra/prod/db":      {"/infra/prod/vpc"},
+		"/infra/prod/vpc":     {},
+		"/infra/prod/monitor": {"/infra/prod/app", "/infra/shared/logging"},
+	}
+}
+
+func TestGraphJSONGolden(t *testing.T) {
+	got, err := marshalGraphJSON("/infra", sampleGraph())
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "graph.golden.json")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(golden, got, 0644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	// Map iteration order must not leak into the output
+	for i := 0; i < 20; i++ {
+		again, err := marshalGraphJSON("/infra", sampleGraph())
+		require.NoError(t, err)
+		require.Equal(t, string(got), string(again))
+	}
+}
+
+func TestDotAndMermaidGraphsAreSorted(t *testing.T) {
+	dot := generateDotGraph(sampleGraph())
+	assert.Equal(t, `digraph dependencies {
+  rankdir=TB;
+  node [shape=box];
+  "app" -> "db";
+  "app" -> "vpc";
+  "db" -> "vpc";
+  "monitor" -> "app";
+  "monitor" -> "logging";
+}
+`, dot)
+
+	mermaid := generateMermaidGraph(sampleGraph())
+	assert.Equal(t, "graph TD\n  app --> db\n  app --> vpc\n  db --> vpc\n  monitor --> app\n  monitor --> logging\n", mermaid)
+}
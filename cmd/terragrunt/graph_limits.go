@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultDependencyFanWarning is the fan-in or fan-out above which a module
+// is warned about
+const defaultDependencyFanWarning = 20
+
+// longestDependencyChain returns the longest chain of dependencies starting
+// at a module of graph, the module first. Modules on a cycle end the chain
+// where the cycle closes.
+func longestDependencyChain(graph map[string][]string) []string {
+	chains := make(map[string][]string)
+	visiting := make(map[string]bool)
+
+	var chain func(string) []string
+	chain = func(node string) []string {
+		if c, ok := chains[node]; ok {
+			return c
+		}
+		if visiting[node] {
+			return nil
+		}
+		visiting[node] = true
+
+		var longest []string
+		for _, dep := range sortedDeps(graph[node]) {
+			if c := chain(dep); len(c) > len(longest) {
+				longest = c
+			}
+		}
+
+		visiting[node] = false
+		chains[node] = append([]string{node}, longest...)
+		return chains[node]
+	}
+
+	var longest []string
+	for _, node := range sortedGraphNodes(graph) {
+		if c := chain(node); len(c) > len(longest) {
+			longest = c
+		}
+	}
+	return longest
+}
+
+// checkDependencyDepth fails when a chain of dependencies is more than
+// maxDepth modules below the module it starts at; 0 means no limit
+func checkDependencyDepth(graph map[string][]string, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+	chain := longestDependencyChain(graph)
+	if depth := len(chain) - 1; depth > maxDepth {
+		return fmt.Errorf("dependency depth %d exceeds --terragrunt-max-dependency-depth %d: %s",
+			depth, maxDepth, strings.Join(chain, " → "))
+	}
+	return nil
+}
+
+// dependencyFanWarnings describes the modules that depend on, or are
+// depended on by, more than threshold modules; 0 turns the warnings off
+func dependencyFanWarnings(graph map[string][]string, threshold int) []string {
+	if threshold <= 0 {
+		return nil
+	}
+
+	fanIn := make(map[string]int)
+	var warnings []string
+	for _, node := range sortedGraphNodes(graph) {
+		deps := graph[node]
+		if len(deps) > threshold {
+			warnings = append(warnings, fmt.Sprintf("%s depends on %d modules (more than %d)", node, len(deps), threshold))
+		}
+		for _, dep := range deps {
+			fanIn[dep]++
+		}
+	}
+
+	depended := make([]string, 0, len(fanIn))
+	for dep, count := range fanIn {
+		if count > threshold {
+			depended = append(depended, dep)
+		}
+	}
+	sort.Strings(depended)
+	for _, dep := range depended {
+		warnings = append(warnings, fmt.Sprintf("%d modules depend on %s (more than %d)", fanIn[dep], dep, threshold))
+	}
+	return warnings
+}
+
+// checkGraphLimits warns about modules with a large fan-in or fan-out and
+// enforces the dependency depth limit, guarding against graphs grown far
+// beyond what was intended
+func checkGraphLimits(ctx *ExecutionContext, graph map[string][]string) error {
+	for _, warning := range dependencyFanWarnings(graph, ctx.Config.DependencyFanWarning) {
+		logger.Warnf("Large dependency fan: %s", warning)
+	}
+	return checkDependencyDepth(graph, ctx.Config.MaxDependencyDepth)
+}
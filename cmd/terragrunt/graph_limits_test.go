@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainGraph makes modules m0 → m1 → ... → m<n-1>, each depending on the next
+func chainGraph(n int) map[string][]string {
+	graph := make(map[string][]string)
+	for i := 0; i < n; i++ {
+		module := fmt.Sprintf("m%d", i)
+		graph[module] = nil
+		if i+1 < n {
+			graph[module] = []string{fmt.Sprintf("m%d", i+1)}
+		}
+	}
+	return graph
+}
+
+func TestCheckDependencyDepth(t *testing.T) {
+	// m0 has 4 modules below it
+	graph := chainGraph(5)
+	graph["side"] = []string{"m3"}
+
+	assert.NoError(t, checkDependencyDepth(graph, 4))
+	assert.NoError(t, checkDependencyDepth(graph, 0), "0 means no limit")
+
+	err := checkDependencyDepth(graph, 3)
+	require.Error(t, err)
+	assert.Equal(t, "dependency depth 4 exceeds --terragrunt-max-dependency-depth 3: m0 → m1 → m2 → m3 → m4", err.Error())
+}
+
+func TestLongestDependencyChainStopsAtCycles(t *testing.T) {
+	graph := map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}
+	assert.Equal(t, []string{"a", "b", "c"}, longestDependencyChain(graph))
+}
+
+func TestDependencyFanWarnings(t *testing.T) {
+	graph := map[string][]string{
+		"app":  {"vpc", "dns", "db", "iam"},
+		"api":  {"vpc", "iam"},
+		"jobs": {"vpc", "iam"},
+		"web":  {"vpc"},
+	}
+
+	assert.Equal(t, []string{
+		"app depends on 4 modules (more than 3)",
+		"4 modules depend on vpc (more than 3)",
+	}, dependencyFanWarnings(graph, 3))
+	assert.Empty(t, dependencyFanWarnings(graph, 4), "a fan at the threshold doesn't warn")
+	assert.Empty(t, dependencyFanWarnings(graph, 0), "0 turns warnings off")
+}
+
+func TestCheckGraphLimitsLogsFanWarnings(t *testing.T) {
+	originalLogger := logger
+	var out bytes.Buffer
+	logger = logrus.New()
+	logger.SetOutput(&out)
+	t.Cleanup(func() { logger = originalLogger })
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig()}
+	ctx.Config.DependencyFanWarning = 1
+	ctx.Config.MaxDependencyDepth = 1
+
+	err := checkGraphLimits(ctx, map[string][]string{"app": {"vpc", "db"}, "db": {"vpc"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "app → db → vpc")
+	assert.Contains(t, out.String(), "Large dependency fan: app depends on 2 modules (more than 1)")
+	assert.Contains(t, out.String(), "level=warning")
+
+	// The defaults only warn about very wide graphs and don't limit depth
+	ctx.Config = defaultTerragruntConfig()
+	out.Reset()
+	assert.NoError(t, checkGraphLimits(ctx, chainGraph(50)))
+	assert.Empty(t, out.String())
+}
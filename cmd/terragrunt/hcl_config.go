@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// configStdin is where `--terragrunt-config -` reads from; tests replace it
+var configStdin io.Reader = os.Stdin
+
+// hclBlockAliases maps terragrunt.hcl block/attribute names to the keys of
+// TerragruntConfig where the two differ
+var hclBlockAliases = map[string]string{
+	"dependency": "dependencies",
+	"inputs":     "variables",
+}
+
+// detectConfigFormat returns the explicit format hint, or sniffs the content:
+// JSON documents start with '{', anything else is treated as HCL
+func detectConfigFormat(data []byte, hint string) (string, error) {
+	switch strings.ToLower(hint) {
+	case "json", "hcl":
+		return strings.ToLower(hint), nil
+	case "", "auto":
+	default:
+		return "", fmt.Errorf("unsupported config format %q (expected auto, hcl or json)", hint)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return "json", nil
+	}
+	return "hcl", nil
+}
+
+// decodeConfig unmarshals HCL or JSON config data into config
+func decodeConfig(data []byte, filename, format string, config *TerragruntConfig) error {
+	if format == "json" {
+		return json.Unmarshal(data, config)
+	}
+
+	values, err := decodeHCLConfig(data, filename)
+	if err != nil {
+		return err
+	}
+	return decodeValues(values, config)
+}
+
+// decodeValues fills out, a config struct, from a decoded HCL map
+func decodeValues(values map[string]interface{}, out interface{}) error {
+	// Round-trip through JSON so HCL and JSON share the struct tags
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to convert HCL config: %w", err)
+	}
+	return json.Unmarshal(encoded, out)
+}
+
+// decodeHCLConfig parses HCL into a generic map. Attributes that need an
+// evaluation context (functions, locals) are skipped since they can't be
+// resolved without running terragrunt's full evaluator. Dependency outputs
+// are only known once the dependencies are read: inputs referencing them are
+// left for configInputs to evaluate then, and any other attribute
+// referencing them is an error.
+func decodeHCLConfig(data []byte, filename string) (map[string]interface{}, error) {
+	file, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected HCL body type in %s", filename)
+	}
+	return hclBodyToMap(body, true)
+}
+
+func hclBodyToMap(body *hclsyntax.Body, topLevel bool) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for name, attr := range body.Attributes {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			if referencesDependency(attr.Expr) {
+				if topLevel && name == "inputs" {
+					logger.Debugf("Evaluating inputs once the dependency outputs are read")
+					continue
+				}
+				return nil, fmt.Errorf("%s: attribute %q references dependency outputs, which only inputs can use", attr.SrcRange, name)
+			}
+			logger.Debugf("Skipping attribute %q: %s", name, diags.Error())
+			continue
+		}
+
+		encoded, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+		if err != nil {
+			logger.Debugf("Skipping attribute %q: %v", name, err)
+			continue
+		}
+
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			logger.Debugf("Skipping attribute %q: %v", name, err)
+			continue
+		}
+		result[hclKey(name)] = decoded
+	}
+
+	for _, block := range body.Blocks {
+		key := hclKey(block.Type)
+		values, err := hclBodyToMap(block.Body, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// Labeled blocks (dependency "vpc" {}) collect into a list keyed by name
+		if len(block.Labels) > 0 {
+			values["name"] = block.Labels[0]
+			list, _ := result[key].([]interface{})
+			result[key] = append(list, values)
+			continue
+		}
+
+		switch existing := result[key].(type) {
+		case nil:
+			result[key] = values
+		case []interface{}:
+			result[key] = append(existing, values)
+		default:
+			result[key] = []interface{}{existing, values}
+		}
+	}
+
+	return result, nil
+}
+
+func hclKey(name string) string {
+	if alias, ok := hclBlockAliases[name]; ok {
+		return alias
+	}
+	return name
+}
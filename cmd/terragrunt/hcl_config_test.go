@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withStdin(t *testing.T, content string) {
+	t.Helper()
+	original := configStdin
+	configStdin = strings.NewReader(content)
+	t.Cleanup(func() { configStdin = original })
+}
+
+func TestLoadConfigFromStdinHCL(t *testing.T) {
+	withStdin(t, `
+terraform_path = "/usr/local/bin/terraform"
+parallelism    = 4
+auto_init      = false
+
+gcp {
+  project = "my-project"
+  region  = "europe-west1"
+}
+
+backend {
+  type   = "gcs"
+  bucket = "tf-state"
+  prefix = "dev/network"
+}
+
+dependency "vpc" {
+  config_path      = "../vpc"
+  enabled          = true
+  state_generation = 1700000000000001
+  mock_outputs = {
+    network_id = "mock-network"
+  }
+}
+
+inputs = {
+  zone = "europe-west1-b"
+}
+
+locals {
+  computed = find_in_parent_folders()
+}
+`)
+
+	config := defaultTerragruntConfig()
+	require.NoError(t, loadConfigFile("-", "auto", config))
+
+	assert.Equal(t, "/usr/local/bin/terraform", config.TerraformPath)
+	assert.Equal(t, 4, config.Parallelism)
+	assert.False(t, config.AutoInit)
+	assert.Equal(t, "my-project", config.GCP.Project)
+	assert.Equal(t, "europe-west1", config.GCP.Region)
+	assert.Equal(t, "gcs", config.Backend.Type)
+	assert.Equal(t, "tf-state", config.Backend.Bucket)
+	require.Len(t, config.Dependencies, 1)
+	assert.Equal(t, "vpc", config.Dependencies[0].Name)
+	assert.Equal(t, "../vpc", config.Dependencies[0].ConfigPath)
+	assert.True(t, config.Dependencies[0].Enabled)
+	assert.Equal(t, int64(1700000000000001), config.Dependencies[0].StateGeneration)
+	assert.Equal(t, "mock-network", config.Dependencies[0].MockOutputs["network_id"])
+	assert.Equal(t, "europe-west1-b", config.Variables["zone"])
+}
+
+func TestLoadConfigFromStdinJSON(t *testing.T) {
+	withStdin(t, `{
+  "parallelism": 6,
+  "gcp": {"project": "json-project"},
+  "dependencies": [{"name": "db", "config_path": "../db"}]
+}`)
+
+	config := defaultTerragruntConfig()
+	require.NoError(t, loadConfigFile("-", "", config))
+
+	assert.Equal(t, 6, config.Parallelism)
+	assert.Equal(t, "json-project", config.GCP.Project)
+	require.Len(t, config.Dependencies, 1)
+	assert.Equal(t, "db", config.Dependencies[0].Name)
+}
+
+func TestLoadConfigFromStdinFormatHint(t *testing.T) {
+	withStdin(t, `parallelism = 2`)
+
+	config := defaultTerragruntConfig()
+	assert.Error(t, loadConfigFile("-", "json", config), "HCL content with a json hint should fail")
+
+	withStdin(t, `parallelism = 2`)
+	require.NoError(t, loadConfigFile("-", "hcl", config))
+	assert.Equal(t, 2, config.Parallelism)
+
+	withStdin(t, `parallelism = 2`)
+	assert.Error(t, loadConfigFile("-", "yaml", config))
+}
+
+func TestResolveConfigFromStdin(t *testing.T) {
+	withStdin(t, `{"parallelism": 3, "log_level": "debug"}`)
+
+	config, err := resolveConfig(newTestFlags(t, "--terragrunt-parallelism=5"), "-", envMap(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, config.Parallelism, "flags still win over stdin config")
+	assert.Equal(t, "debug", config.LogLevel)
+}
+
+func TestLoadConfigDependencyReferences(t *testing.T) {
+	// Inputs wait for the dependency outputs
+	withStdin(t, `
+parallelism = 2
+
+inputs = {
+  network = dependency.vpc.outputs.network_id
+}
+`)
+	config := defaultTerragruntConfig()
+	require.NoError(t, loadConfigFile("-", "hcl", config))
+	assert.Equal(t, 2, config.Parallelism)
+	assert.Empty(t, config.Variables)
+
+	// Anywhere else they would be lost
+	withStdin(t, `
+backend {
+  type   = "gcs"
+  bucket = dependency.state.outputs.bucket
+}
+`)
+	err := loadConfigFile("-", "hcl", defaultTerragruntConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `attribute "bucket" references dependency outputs`)
+}
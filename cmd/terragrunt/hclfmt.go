@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// hclfmtOutput is where `hclfmt --format json` writes its report; tests
+// replace it
+var hclfmtOutput io.Writer = os.Stdout
+
+// hclfmtResult is one file before and after formatting
+type hclfmtResult struct {
+	Original  []byte
+	Formatted []byte
+}
+
+func (r *hclfmtResult) changed() bool {
+	return !bytes.Equal(r.Original, r.Formatted)
+}
+
+// formatHCLFile formats the file at path, writing it back when write is set
+// and this isn't a check. Files that don't parse are reported as errors
+// rather than formatted.
+func formatHCLFile(path string, check, write bool) (*hclfmtResult, error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, diags := hclsyntax.ParseConfig(original, path, hcl.InitialPos); diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse: %s", diags.Error())
+	}
+
+	result := &hclfmtResult{Original: original, Formatted: hclwrite.Format(original)}
+	if result.changed() && write && !check {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, result.Formatted, info.Mode().Perm()); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// hclfmtReport is the `hclfmt --format json` output
+type hclfmtReport struct {
+	NeedsFormat bool               `json:"needs_format"`
+	Files       []hclfmtFileReport `json:"files"`
+}
+
+// hclfmtFileReport lists the lines of the file as it was read that
+// formatting changes, for annotating them in review tools
+type hclfmtFileReport struct {
+	Path        string      `json:"path"`
+	NeedsFormat bool        `json:"needs_format"`
+	Changes     []lineRange `json:"changes"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// lineRange is an inclusive, 1-based range of lines
+type lineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// add records the result of formatting path, named relative to root
+func (r *hclfmtReport) add(root, path string, result *hclfmtResult, err error) {
+	name := path
+	if rel, relErr := filepath.Rel(root, path); relErr == nil {
+		name = rel
+	}
+
+	file := hclfmtFileReport{Path: filepath.ToSlash(name), Changes: []lineRange{}}
+	if err != nil {
+		file.Error = err.Error()
+	} else if result.changed() {
+		file.NeedsFormat = true
+		file.Changes = changedLines(string(result.Original), string(result.Formatted))
+		r.NeedsFormat = true
+	}
+	r.Files = append(r.Files, file)
+}
+
+// changedLines returns the ranges of lines in before that differ in after.
+// Lines only inserted by formatting are attributed to the line they follow.
+func changedLines(before, after string) []lineRange {
+	a, b := diffLines(before), diffLines(after)
+	ranges := []lineRange{}
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		start, end := op.I1+1, op.I2
+		if op.Tag == 'i' {
+			start, end = op.I1, op.I1
+			if start < 1 {
+				start, end = 1, 1
+			}
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End >= start-1 {
+			if end > ranges[n-1].End {
+				ranges[n-1].End = end
+			}
+			continue
+		}
+		ranges = append(ranges, lineRange{Start: start, End: end})
+	}
+	return ranges
+}
+
+func writeHCLFmtReport(w io.Writer, report hclfmtReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
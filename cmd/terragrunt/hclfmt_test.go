@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const misformattedHCL = `terraform {
+source="../modules/vpc"
+}
+
+inputs = {
+  name = "vpc"
+  region = "europe-west1"
+}
+
+locals {
+  env = "dev"
+}
+`
+
+// newHCLFmtCommand returns an hclfmt command run against dir, with its
+// report captured
+func newHCLFmtCommand(t *testing.T, dir string) (*cobra.Command, *bytes.Buffer) {
+	t.Helper()
+	cmd := &cobra.Command{Use: "hclfmt", RunE: runHCLFormat}
+	registerGlobalFlags(cmd.Flags())
+	cmd.Flags().Bool("check", false, "")
+	cmd.Flags().Bool("diff", false, "")
+	cmd.Flags().Bool("write", true, "")
+	cmd.Flags().String("format", "text", "")
+	require.NoError(t, cmd.Flags().Set("terragrunt-working-dir", dir))
+
+	var out bytes.Buffer
+	original := hclfmtOutput
+	hclfmtOutput = &out
+	t.Cleanup(func() { hclfmtOutput = original })
+	return cmd, &out
+}
+
+func TestChangedLines(t *testing.T) {
+	formatted := `terraform {
+  source = "../modules/vpc"
+}
+
+inputs = {
+  name   = "vpc"
+  region = "europe-west1"
+}
+
+locals {
+  env = "dev"
+}
+`
+	assert.Equal(t, []lineRange{{Start: 2, End: 2}, {Start: 6, End: 6}}, changedLines(misformattedHCL, formatted))
+	assert.Empty(t, changedLines(formatted, formatted))
+}
+
+func TestHCLFmtJSONReportCheck(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app", "terragrunt.hcl"), []byte(misformattedHCL), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.hcl"), []byte("locals {\n  x = 1\n}\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform", "modules"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform", "modules", "main.tf"), []byte("a=1\n"), 0644))
+
+	cmd, out := newHCLFmtCommand(t, dir)
+	require.NoError(t, cmd.Flags().Set("check", "true"))
+	require.NoError(t, cmd.Flags().Set("format", "json"))
+
+	err := cmd.RunE(cmd, nil)
+	require.Error(t, err, "check still fails when files need formatting")
+
+	var report hclfmtReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.True(t, report.NeedsFormat)
+	assert.Equal(t, []hclfmtFileReport{
+		{Path: "app/terragrunt.hcl", NeedsFormat: true, Changes: []lineRange{{Start: 2, End: 2}, {Start: 6, End: 6}}},
+		{Path: "root.hcl", Changes: []lineRange{}},
+	}, report.Files)
+
+	data, err := os.ReadFile(filepath.Join(dir, "app", "terragrunt.hcl"))
+	require.NoError(t, err)
+	assert.Equal(t, misformattedHCL, string(data), "check must not rewrite files")
+}
+
+func TestHCLFmtWritesAndPasses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(misformattedHCL), 0644))
+
+	cmd, out := newHCLFmtCommand(t, dir)
+	require.NoError(t, cmd.Flags().Set("format", "json"))
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	var report hclfmtReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Files, 1)
+	assert.True(t, report.Files[0].NeedsFormat)
+
+	out.Reset()
+	require.NoError(t, cmd.Flags().Set("check", "true"))
+	require.NoError(t, cmd.RunE(cmd, nil), "formatted files pass the check")
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.False(t, report.NeedsFormat)
+}
+
+func TestHCLFmtReportsParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.hcl"), []byte("inputs = {\n"), 0644))
+
+	cmd, out := newHCLFmtCommand(t, dir)
+	require.NoError(t, cmd.Flags().Set("format", "json"))
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	var report hclfmtReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Files, 1)
+	assert.Contains(t, report.Files[0].Error, "failed to parse")
+	assert.False(t, report.Files[0].NeedsFormat)
+}
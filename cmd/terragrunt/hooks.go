@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// defaultHookParallelism bounds concurrent hooks when max_parallel is unset
+const defaultHookParallelism = 4
+
+// hookStdout and hookStderr receive hook output; tests replace them
+var (
+	hookStdout io.Writer = os.Stdout
+	hookStderr io.Writer = os.Stderr
+)
+
+// runHooks runs the hooks of a group that apply to command. Hooks run one
+// after another unless the group's settings set parallel, in which case they
+// run concurrently and every failure is reported.
+func runHooks(ctx *ExecutionContext, hooks []HookConfig, settings HookGroupConfig, command string) error {
+	var matching []HookConfig
+	for _, hook := range hooks {
+		if hookApplies(hook, command) {
+			matching = append(matching, hook)
+		}
+	}
+
+	if !settings.Parallel || len(matching) < 2 {
+		for _, hook := range matching {
+			if err := runHook(ctx, hook, hookStdout, hookStderr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return runHooksParallel(ctx, matching, settings.MaxParallel)
+}
+
+func runHooksParallel(ctx *ExecutionContext, hooks []HookConfig, limit int) error {
+	if limit <= 0 {
+		limit = defaultHookParallelism
+	}
+
+	// Output is shared, so each hook writes whole prefixed lines under one lock
+	var outputMu sync.Mutex
+	sem := make(chan struct{}, limit)
+	errs := make([]error, len(hooks))
+
+	var wg sync.WaitGroup
+	for i, hook := range hooks {
+		wg.Add(1)
+		go func(i int, hook HookConfig) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			stdout := newHookLineWriter(hookStdout, &outputMu, hook.Name)
+			stderr := newHookLineWriter(hookStderr, &outputMu, hook.Name)
+			errs[i] = runHook(ctx, hook, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+		}(i, hook)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func hookApplies(hook HookConfig, command string) bool {
+	for _, cmd := range hook.Commands {
+		if cmd == command || cmd == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// runHook executes a single hook's commands in order
+func runHook(ctx *ExecutionContext, hook HookConfig, stdout, stderr io.Writer) error {
+	logger.Infof("Running hook: %s", hook.Name)
+
+	for _, execute := range hook.Execute {
+		parts := strings.Fields(execute)
+		if len(parts) == 0 {
+			continue
+		}
+
+		cmd := exec.Command(parts[0], parts[1:]...)
+		cmd.Dir = hook.WorkingDir
+		if cmd.Dir == "" {
+			cmd.Dir = ctx.terraformDir()
+		}
+		cmd.Env = envToSlice(ctx.Environment)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		if err := cmd.Run(); err != nil {
+			if !hook.RunOnError {
+				return fmt.Errorf("hook %s failed: %w", hook.Name, err)
+			}
+			logger.Warnf("Hook %s failed but continuing: %v", hook.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// hookLineWriter buffers a hook's output and emits it one complete line at a
+// time, prefixed with the hook name, so concurrent hooks don't interleave
+// mid-line
+type hookLineWriter struct {
+	out    io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newHookLineWriter(out io.Writer, mu *sync.Mutex, name string) *hookLineWriter {
+	return &hookLineWriter{out: out, mu: mu, prefix: "[" + name + "] "}
+}
+
+func (w *hookLineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.buf.Next(idx + 1)
+		if err := w.emit(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line
+func (w *hookLineWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := append(w.buf.Bytes(), '\n')
+	w.buf.Reset()
+	return w.emit(line)
+}
+
+func (w *hookLineWriter) emit(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := io.WriteString(w.out, w.prefix+string(line))
+	return err
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHookContext(t *testing.T, parallel bool) (*ExecutionContext, *bytes.Buffer) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var out bytes.Buffer
+	originalStdout, originalStderr := hookStdout, hookStderr
+	hookStdout, hookStderr = &out, &out
+	t.Cleanup(func() { hookStdout, hookStderr = originalStdout, originalStderr })
+
+	config := defaultTerragruntConfig()
+	config.Hooks.Before.Parallel = parallel
+	return &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}, &out
+}
+
+// writeHookScript writes a script into the working dir and returns the
+// hook command that runs it
+func writeHookScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0755))
+	return "sh " + name
+}
+
+// rendezvous touches its own marker and waits for the peer's, which only
+// succeeds if both hooks are running at the same time
+func rendezvous(self, peer string) string {
+	return strings.Join([]string{
+		"touch " + self + ".started",
+		"i=0",
+		"while [ ! -f " + peer + ".started ]; do",
+		"  i=$((i+1))",
+		"  if [ $i -gt 100 ]; then echo \"" + self + " timed out\"; exit 1; fi",
+		"  sleep 0.05",
+		"done",
+		"echo \"" + self + " saw " + peer + "\"",
+	}, "\n") + "\n"
+}
+
+func TestRunHooksParallelRunsConcurrently(t *testing.T) {
+	ctx, out := newHookContext(t, true)
+	dir := ctx.WorkingDir
+
+	ctx.Config.Hooks.BeforeHooks = []HookConfig{
+		{Name: "lint", Commands: []string{"plan"}, Execute: []string{writeHookScript(t, dir, "lint.sh", rendezvous("lint", "fmt"))}},
+		{Name: "fmt", Commands: []string{"plan"}, Execute: []string{writeHookScript(t, dir, "fmt.sh", rendezvous("fmt", "lint"))}},
+		{Name: "skipped", Commands: []string{"apply"}, Execute: []string{"false"}},
+	}
+
+	require.NoError(t, runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "plan"))
+
+	output := out.String()
+	assert.Contains(t, output, "[lint] lint saw fmt\n")
+	assert.Contains(t, output, "[fmt] fmt saw lint\n")
+}
+
+func TestRunHooksParallelAggregatesFailures(t *testing.T) {
+	ctx, out := newHookContext(t, true)
+	dir := ctx.WorkingDir
+
+	ctx.Config.Hooks.BeforeHooks = []HookConfig{
+		{Name: "policy", Commands: []string{"all"}, Execute: []string{writeHookScript(t, dir, "policy.sh", "echo denied\nexit 3\n")}},
+		{Name: "tflint", Commands: []string{"all"}, Execute: []string{writeHookScript(t, dir, "tflint.sh", "sleep 0.2\necho clean > tflint.done\necho done\n")}},
+		{Name: "checkov", Commands: []string{"all"}, Execute: []string{"false"}},
+	}
+
+	err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "plan")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hook policy failed")
+	assert.Contains(t, err.Error(), "hook checkov failed")
+	assert.NotContains(t, err.Error(), "tflint")
+
+	// The failing hooks did not stop tflint from finishing
+	assert.FileExists(t, filepath.Join(dir, "tflint.done"))
+	assert.Contains(t, out.String(), "[policy] denied\n")
+	assert.Contains(t, out.String(), "[tflint] done\n")
+}
+
+func TestRunHooksSequentialByDefault(t *testing.T) {
+	ctx, out := newHookContext(t, false)
+	dir := ctx.WorkingDir
+
+	ctx.Config.Hooks.BeforeHooks = []HookConfig{
+		{Name: "first", Commands: []string{"plan"}, Execute: []string{writeHookScript(t, dir, "first.sh", "echo one > order\n")}},
+		{Name: "fail", Commands: []string{"plan"}, Execute: []string{"false"}},
+		{Name: "never", Commands: []string{"plan"}, Execute: []string{writeHookScript(t, dir, "never.sh", "echo two >> order\n")}},
+	}
+
+	err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "plan")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hook fail failed")
+
+	order, readErr := os.ReadFile(filepath.Join(dir, "order"))
+	require.NoError(t, readErr)
+	assert.Equal(t, "one\n", string(order), "hooks after a failure must not run")
+	assert.Empty(t, out.String())
+}
+
+func TestRunHooksParallelIsSetPerGroup(t *testing.T) {
+	ctx, _ := newHookContext(t, true)
+	dir := ctx.WorkingDir
+
+	// Only the before hooks are parallel, so the after hooks stop at the
+	// first failure
+	ctx.Config.Hooks.AfterHooks = []HookConfig{
+		{Name: "fail", Commands: []string{"apply"}, Execute: []string{"false"}},
+		{Name: "never", Commands: []string{"apply"}, Execute: []string{writeHookScript(t, dir, "never.sh", "echo ran > never\n")}},
+	}
+	err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, ctx.Config.Hooks.After, "apply")
+	require.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "never"))
+
+	ctx.Config.Hooks.After.Parallel = true
+	err = runHooks(ctx, ctx.Config.Hooks.AfterHooks, ctx.Config.Hooks.After, "apply")
+	require.Error(t, err)
+	assert.FileExists(t, filepath.Join(dir, "never"))
+}
+
+func TestHooksGroupSettingsDecodeFromHCL(t *testing.T) {
+	config := defaultTerragruntConfig()
+	require.NoError(t, decodeConfig([]byte(`
+hooks {
+  before {
+    parallel     = true
+    max_parallel = 2
+  }
+}
+`), "terragrunt.hcl", "hcl", config))
+	assert.Equal(t, HookGroupConfig{Parallel: true, MaxParallel: 2}, config.Hooks.Before)
+	assert.Equal(t, HookGroupConfig{}, config.Hooks.After)
+}
+
+func TestHookLineWriterBuffersPartialLines(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := newHookLineWriter(&out, &mu, "lint")
+
+	_, _ = w.Write([]byte("par"))
+	assert.Empty(t, out.String())
+	_, _ = w.Write([]byte("tial\nnext"))
+	assert.Equal(t, "[lint] partial\n", out.String())
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[lint] partial\n[lint] next\n", out.String())
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// infoOutput is where `terragrunt info` prints; tests replace it
+var infoOutput io.Writer = os.Stdout
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show what terragrunt resolved for the module",
+	Long: `Print the resolved working directory, config file, download directory,
+include chain, backend configuration, generated files and dependencies of the
+current module without running terraform`,
+	RunE: runInfo,
+}
+
+// moduleInfo is the `terragrunt info` document
+type moduleInfo struct {
+	WorkingDir   string           `json:"working_dir"`
+	ConfigFile   string           `json:"config_file"`
+	DownloadDir  string           `json:"download_dir"`
+	IncludeChain []string         `json:"include_chain"`
+	Backend      backendInfo      `json:"backend"`
+	Generate     []string         `json:"generate"`
+	Dependencies []dependencyInfo `json:"dependencies"`
+}
+
+type backendInfo struct {
+	Type   string `json:"type"`
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// InitArgs are the -backend-config arguments init passes
+	InitArgs []string `json:"init_args,omitempty"`
+	// Env is the environment terraform gets for the backend, with
+	// customer-supplied encryption keys redacted
+	Env []string `json:"env,omitempty"`
+}
+
+type dependencyInfo struct {
+	Name        string `json:"name"`
+	Dir         string `json:"dir"`
+	Enabled     bool   `json:"enabled"`
+	SkipOutputs bool   `json:"skip_outputs"`
+	Mocked      bool   `json:"mocked"`
+	// StateGeneration is the state generation outputs are pinned to
+	StateGeneration int64 `json:"state_generation,omitempty"`
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	info, err := collectModuleInfo(ctx, viper.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	return writeModuleInfo(infoOutput, info, format)
+}
+
+// collectModuleInfo gathers what terragrunt resolved for the module from the
+// config alone; nothing is downloaded and terraform is not run
+func collectModuleInfo(ctx *ExecutionContext, configFile string) (*moduleInfo, error) {
+	config := ctx.Config
+	info := &moduleInfo{
+		WorkingDir:   ctx.WorkingDir,
+		DownloadDir:  moduleCacheRoot(ctx),
+		IncludeChain: []string{},
+		Generate:     []string{},
+		Dependencies: []dependencyInfo{},
+	}
+
+	if configFile != "" && configFile != "-" {
+		path, err := filepath.Abs(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config file: %w", err)
+		}
+		info.ConfigFile = path
+		if info.IncludeChain, err = includeChain(path); err != nil {
+			return nil, err
+		}
+	} else if configFile == "-" {
+		info.ConfigFile = "<stdin>"
+	}
+
+	info.Backend = backendInfo{Type: config.Backend.Type}
+	if config.Backend.Type == "gcs" {
+		info.Backend.Bucket = config.Backend.Bucket
+		info.Backend.Prefix = config.Backend.Prefix
+		args, err := gcsBackendConfigArgs(config)
+		if err != nil {
+			return nil, err
+		}
+		info.Backend.InitArgs = args
+		for key := range gcsBackendEnv(config) {
+			info.Backend.Env = append(info.Backend.Env, key+"=(redacted)")
+		}
+	}
+
+	generated, err := desiredGeneratedFiles(config)
+	if err != nil {
+		return nil, err
+	}
+	for path := range generated {
+		info.Generate = append(info.Generate, filepath.ToSlash(path))
+	}
+	sort.Strings(info.Generate)
+
+	for _, dep := range config.Dependencies {
+		info.Dependencies = append(info.Dependencies, dependencyInfo{
+			Name:        dep.Name,
+			Dir:         dependencyDir(ctx, dep),
+			Enabled:     dep.Enabled,
+			SkipOutputs: dep.SkipOutputs,
+			Mocked:      dep.MockOutputs != nil,
+
+			StateGeneration: dep.StateGeneration,
+		})
+	}
+
+	return info, nil
+}
+
+// includeChain follows the include blocks of an HCL config file, returning
+// every file it pulls in, nearest first. Paths written as
+// find_in_parent_folders(...) are resolved the way terragrunt would.
+func includeChain(configFile string) ([]string, error) {
+	chain := []string{}
+	seen := map[string]bool{configFile: true}
+
+	for current := configFile; ; {
+		if filepath.Ext(current) == ".json" {
+			return chain, nil
+		}
+		data, err := os.ReadFile(current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", current, err)
+		}
+		file, diags := hclsyntax.ParseConfig(data, current, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %s", current, diags.Error())
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			return chain, nil
+		}
+
+		next := ""
+		for _, block := range body.Blocks {
+			if block.Type != "include" {
+				continue
+			}
+			path, err := includePath(block, filepath.Dir(current))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", current, err)
+			}
+			if path != "" {
+				next = path
+				break
+			}
+		}
+		if next == "" {
+			return chain, nil
+		}
+		if seen[next] {
+			return nil, fmt.Errorf("include cycle: %s includes %s", current, next)
+		}
+		seen[next] = true
+		chain = append(chain, next)
+		current = next
+	}
+}
+
+// includePath resolves the path attribute of an include block relative to
+// the including file's directory
+func includePath(block *hclsyntax.Block, dir string) (string, error) {
+	attr, ok := block.Body.Attributes["path"]
+	if !ok {
+		return "", nil
+	}
+
+	if call, ok := attr.Expr.(*hclsyntax.FunctionCallExpr); ok && call.Name == "find_in_parent_folders" {
+		name := "terragrunt.hcl"
+		if len(call.Args) > 0 {
+			value, diags := call.Args[0].Value(nil)
+			if diags.HasErrors() || value.Type() != cty.String {
+				return "", fmt.Errorf("include %s: find_in_parent_folders needs a literal file name", blockLabel(block))
+			}
+			name = value.AsString()
+		}
+		return findInParentFolders(dir, name)
+	}
+
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || value.Type() != cty.String {
+		return "", fmt.Errorf("include %s: path must be a string or find_in_parent_folders()", blockLabel(block))
+	}
+	path := value.AsString()
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// findInParentFolders returns the nearest file called name in a directory
+// above dir
+func findInParentFolders(dir, name string) (string, error) {
+	for parent := filepath.Dir(dir); ; parent = filepath.Dir(parent) {
+		candidate := filepath.Join(parent, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		if parent == filepath.Dir(parent) {
+			return "", fmt.Errorf("no %s found in the parent folders of %s", name, dir)
+		}
+	}
+}
+
+func blockLabel(block *hclsyntax.Block) string {
+	if len(block.Labels) > 0 {
+		return fmt.Sprintf("%q", block.Labels[0])
+	}
+	return "block"
+}
+
+func writeModuleInfo(w io.Writer, info *moduleInfo, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	case "", "text":
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	orNone := func(value string) string {
+		if value == "" {
+			return "(none)"
+		}
+		return value
+	}
+	fmt.Fprintf(tw, "Working dir:\t%s\n", info.WorkingDir)
+	fmt.Fprintf(tw, "Config file:\t%s\n", orNone(info.ConfigFile))
+	fmt.Fprintf(tw, "Download dir:\t%s\n", info.DownloadDir)
+	fmt.Fprintf(tw, "Includes:\t%s\n", orNone(strings.Join(info.IncludeChain, " -> ")))
+	fmt.Fprintf(tw, "Backend:\t%s\n", orNone(info.Backend.Type))
+	if info.Backend.Bucket != "" {
+		fmt.Fprintf(tw, "  bucket:\t%s\n", info.Backend.Bucket)
+		fmt.Fprintf(tw, "  prefix:\t%s\n", info.Backend.Prefix)
+	}
+	fmt.Fprintf(tw, "Generate:\t%s\n", orNone(strings.Join(info.Generate, ", ")))
+	if len(info.Dependencies) == 0 {
+		fmt.Fprintf(tw, "Dependencies:\t(none)\n")
+	} else {
+		fmt.Fprintf(tw, "Dependencies:\t\n")
+		for _, dep := range info.Dependencies {
+			var notes []string
+			if !dep.Enabled {
+				notes = append(notes, "disabled")
+			}
+			if dep.SkipOutputs {
+				notes = append(notes, "skip outputs")
+			}
+			if dep.Mocked {
+				notes = append(notes, "mocked")
+			}
+			if dep.StateGeneration > 0 {
+				notes = append(notes, fmt.Sprintf("state generation %d", dep.StateGeneration))
+			}
+			line := fmt.Sprintf("  %s:\t%s", dep.Name, dep.Dir)
+			if len(notes) > 0 {
+				line += " (" + strings.Join(notes, ", ") + ")"
+			}
+			fmt.Fprintln(tw, line)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeInfoTree creates live/app, which includes live/env.hcl through
+// find_in_parent_folders, which in turn includes root.hcl
+func writeInfoTree(t *testing.T) (root, configFile string) {
+	t.Helper()
+	root = t.TempDir()
+	files := map[string]string{
+		"root.hcl": `backend {
+  type = "gcs"
+}
+`,
+		"live/env.hcl": `include "root" {
+  path = "../root.hcl"
+}
+`,
+		"live/vpc/terragrunt.hcl": "",
+		"live/app/terragrunt.hcl": `include "env" {
+  path = find_in_parent_folders("env.hcl")
+}
+
+download_dir = "../.cache"
+
+backend {
+  type           = "gcs"
+  bucket         = "tf-state"
+  prefix         = "live/app"
+  encryption_key = "c2VjcmV0"
+}
+
+generate "provider" {
+  path     = "provider.tf"
+  contents = "provider \"google\" {}"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+  enabled     = true
+  mock_outputs = {
+    network_id = "mock"
+  }
+}
+`,
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+	return root, filepath.Join(root, "live", "app", "terragrunt.hcl")
+}
+
+func newInfoContext(t *testing.T, configFile string) *ExecutionContext {
+	t.Helper()
+	config, err := resolveConfig(nil, configFile, func(string) (string, bool) { return "", false })
+	require.NoError(t, err)
+	dir := filepath.Dir(configFile)
+	t.Chdir(dir)
+	return &ExecutionContext{Config: config, WorkingDir: dir}
+}
+
+func TestCollectModuleInfo(t *testing.T) {
+	root, configFile := writeInfoTree(t)
+	ctx := newInfoContext(t, configFile)
+
+	info, err := collectModuleInfo(ctx, configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(root, "live", "app"), info.WorkingDir)
+	assert.Equal(t, configFile, info.ConfigFile)
+	assert.Equal(t, filepath.Join(root, "live", ".cache"), info.DownloadDir)
+	assert.Equal(t, []string{filepath.Join(root, "live", "env.hcl"), filepath.Join(root, "root.hcl")}, info.IncludeChain)
+
+	assert.Equal(t, "gcs", info.Backend.Type)
+	assert.Equal(t, "tf-state", info.Backend.Bucket)
+	assert.Equal(t, "live/app", info.Backend.Prefix)
+	assert.NotContains(t, strings.Join(info.Backend.InitArgs, " "), "encryption_key")
+	assert.Equal(t, []string{"GOOGLE_ENCRYPTION_KEY=(redacted)"}, info.Backend.Env)
+
+	assert.Equal(t, []string{"provider.tf"}, info.Generate)
+	require.Len(t, info.Dependencies, 1)
+	assert.Equal(t, dependencyInfo{Name: "vpc", Dir: filepath.Join(root, "live", "vpc"), Enabled: true, Mocked: true}, info.Dependencies[0])
+}
+
+func TestWriteModuleInfoFormats(t *testing.T) {
+	root, configFile := writeInfoTree(t)
+	info, err := collectModuleInfo(newInfoContext(t, configFile), configFile)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, writeModuleInfo(&out, info, "json"))
+	var decoded moduleInfo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, *info, decoded)
+
+	out.Reset()
+	require.NoError(t, writeModuleInfo(&out, info, "text"))
+	text := out.String()
+	assert.Contains(t, text, "Config file:   "+configFile)
+	assert.Contains(t, text, filepath.Join(root, "live", "env.hcl")+" -> "+filepath.Join(root, "root.hcl"))
+	assert.Contains(t, text, "  bucket:      tf-state")
+	assert.Contains(t, text, "Generate:      provider.tf")
+	assert.Contains(t, text, "  vpc:         "+filepath.Join(root, "live", "vpc")+" (mocked)")
+
+	assert.Error(t, writeModuleInfo(&out, info, "yaml"))
+}
+
+func TestIncludeChainErrors(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.hcl")
+	b := filepath.Join(dir, "b.hcl")
+	require.NoError(t, os.WriteFile(a, []byte(`include { path = "b.hcl" }`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte(`include { path = "a.hcl" }`+"\n"), 0644))
+
+	_, err := includeChain(a)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle")
+
+	missing := filepath.Join(dir, "missing.hcl")
+	require.NoError(t, os.WriteFile(missing, []byte(`include { path = find_in_parent_folders("nowhere.hcl") }`+"\n"), 0644))
+	_, err = includeChain(missing)
+	assert.Error(t, err)
+
+	chain, err := includeChain(filepath.Join(dir, "none.json"))
+	require.NoError(t, err)
+	assert.Empty(t, chain)
+}
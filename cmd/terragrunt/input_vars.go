@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/viper"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// writeInputsTfvars writes the inputs to a tfvars file in the module's
+// cache, and returns the -var-file argument passing it to terraform and a
+// function removing it again. Going through a file keeps complex values
+// intact and command lines short. Given ahead of explicit -var and -var-file
+// arguments, the inputs override the module's own tfvars files but not
+// those arguments; and living in the cache, a file left behind by a crash
+// never ends up in the source tree.
+func writeInputsTfvars(ctx *ExecutionContext) ([]string, func(), error) {
+	noop := func() {}
+	inputs, err := moduleInputs(ctx)
+	if err != nil {
+		return nil, noop, err
+	}
+	if len(inputs) == 0 {
+		return nil, noop, nil
+	}
+
+	data, err := json.MarshalIndent(inputs, "", "  ")
+	if err != nil {
+		return nil, noop, fmt.Errorf("failed to encode inputs: %w", err)
+	}
+	path := inputsTfvarsPath(ctx)
+	if ctx.DryRun {
+		logger.Infof("DRY RUN: would pass the inputs through %s", path)
+		return nil, noop, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, noop, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return nil, noop, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	logger.Debugf("Passing the inputs to terraform through %s: %s", path, redactSecrets(string(data)))
+
+	return []string{"-var-file=" + path}, func() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("Failed to remove %s: %v", path, err)
+		}
+	}, nil
+}
+
+// inputsTfvarsPath is where the inputs of the module are written for
+// terraform, keyed by module as the cache may be a shared download dir
+func inputsTfvarsPath(ctx *ExecutionContext) string {
+	sum := sha256.Sum256([]byte(ctx.WorkingDir))
+	return filepath.Join(moduleCacheRoot(ctx), "inputs", hex.EncodeToString(sum[:])[:16]+".tfvars.json")
+}
+
+// moduleInputs returns the inputs of the module: those decoded with the
+// config, plus the ones in its HCL config file that reference dependency
+// outputs (dependency.vpc.outputs.network_id), which can only be evaluated
+// once loadDependencyOutputs has run. Sensitive inputs are registered as
+// secrets, including those evaluated from dependency outputs.
+func moduleInputs(ctx *ExecutionContext) (map[string]interface{}, error) {
+	inputs, err := configInputs(ctx, moduleConfigPath(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range inputs {
+		if sensitiveInput(ctx.Config, name) {
+			registerSecret(value)
+		}
+	}
+	return inputs, nil
+}
+
+// moduleConfigPath returns the HCL config of the module ctx runs in: its own
+// terragrunt.hcl, or for a module without one the config file in use
+func moduleConfigPath(ctx *ExecutionContext) string {
+	path := filepath.Join(ctx.WorkingDir, "terragrunt.hcl")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return viper.ConfigFileUsed()
+}
+
+// configInputs merges the inputs decoded with the config and those of
+// configPath that reference dependency outputs
+func configInputs(ctx *ExecutionContext, configPath string) (map[string]interface{}, error) {
+	inputs := make(map[string]interface{}, len(ctx.Config.Variables))
+	for name, value := range ctx.Config.Variables {
+		inputs[name] = value
+	}
+	if configPath == "" || configPath == "-" || strings.HasSuffix(configPath, ".json") {
+		return inputs, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if format, err := detectConfigFormat(data, ""); err != nil || format != "hcl" {
+		return inputs, nil
+	}
+	file, diags := hclsyntax.ParseConfig(data, configPath, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+	attr, ok := file.Body.(*hclsyntax.Body).Attributes["inputs"]
+	if !ok {
+		return inputs, nil
+	}
+	object, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return inputs, nil
+	}
+
+	evalCtx, err := dependencyEvalContext(ctx.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range object.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		if diags.HasErrors() || key.Type() != cty.String {
+			continue
+		}
+		name := key.AsString()
+		if _, decoded := ctx.Config.Variables[name]; decoded {
+			continue
+		}
+
+		value, diags := item.ValueExpr.Value(evalCtx)
+		if diags.HasErrors() {
+			if referencesDependency(item.ValueExpr) {
+				return nil, fmt.Errorf("failed to evaluate input %q: %s", name, diags.Error())
+			}
+			logger.Debugf("Skipping input %q: %s", name, diags.Error())
+			continue
+		}
+		encoded, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode input %q: %w", name, err)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to encode input %q: %w", name, err)
+		}
+		inputs[name] = decoded
+	}
+	return inputs, nil
+}
+
+// moduleInputsConfig is the part of a module's terragrunt.hcl naming its
+// inputs
+type moduleInputsConfig struct {
+	Variables       map[string]interface{} `json:"variables"`
+	SensitiveInputs []string               `json:"sensitive_inputs"`
+}
+
+// withModuleInputs returns config with the inputs of the module's own
+// terragrunt.hcl in place of those of the config the run started from, and
+// with its sensitive_inputs added, so each module of a run-all passes its
+// own inputs
+func withModuleInputs(ctx *ExecutionContext, config *TerragruntConfig, moduleDir string) (*TerragruntConfig, error) {
+	path := filepath.Join(moduleDir, "terragrunt.hcl")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return config, nil
+	}
+
+	values, err := readModuleValues(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var module moduleInputsConfig
+	if err := decodeValues(values, &module); err != nil {
+		return nil, fmt.Errorf("failed to decode inputs in %s: %w", path, err)
+	}
+
+	moduleConfig := *config
+	moduleConfig.Variables = module.Variables
+	moduleConfig.SensitiveInputs = append(append([]string(nil), config.SensitiveInputs...), module.SensitiveInputs...)
+	return &moduleConfig, nil
+}
+
+// dependencyEvalContext exposes loaded dependency outputs, keyed
+// "<dependency>.<output>", as dependency.<dependency>.outputs.<output>
+func dependencyEvalContext(outputs map[string]interface{}) (*hcl.EvalContext, error) {
+	byDependency := make(map[string]map[string]cty.Value)
+	for key, value := range outputs {
+		dep, output, ok := strings.Cut(key, ".")
+		if !ok {
+			continue
+		}
+		converted, err := toCtyValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert output %s of dependency %s: %w", output, dep, err)
+		}
+		if byDependency[dep] == nil {
+			byDependency[dep] = make(map[string]cty.Value)
+		}
+		byDependency[dep][output] = converted
+	}
+
+	deps := make(map[string]cty.Value, len(byDependency))
+	for dep, values := range byDependency {
+		deps[dep] = cty.ObjectVal(map[string]cty.Value{"outputs": cty.ObjectVal(values)})
+	}
+	return &hcl.EvalContext{Variables: map[string]cty.Value{"dependency": cty.ObjectVal(deps)}}, nil
+}
+
+// referencesDependency reports whether expr reads any dependency output
+func referencesDependency(expr hclsyntax.Expression) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() == "dependency" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tfvarsRunner records the path and contents of the generated tfvars file
+// each command is given
+type tfvarsRunner struct {
+	*fakeRunner
+	files  []string
+	tfvars []string
+}
+
+func (r *tfvarsRunner) Run(ctx context.Context, command runnerCommand) error {
+	for _, arg := range command.Args {
+		path, ok := strings.CutPrefix(arg, "-var-file=")
+		if !ok || !strings.Contains(path, terragruntCacheDir) {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			r.files = append(r.files, path)
+			r.tfvars = append(r.tfvars, string(data))
+		}
+	}
+	return r.fakeRunner.Run(ctx, command)
+}
+
+// useTfvarsRunner swaps terraformRunner for a tfvarsRunner until the test
+// ends
+func useTfvarsRunner(t *testing.T) *tfvarsRunner {
+	t.Helper()
+	runner := &tfvarsRunner{fakeRunner: useFakeRunner(t)}
+	terraformRunner = runner
+	return runner
+}
+
+// withConfigFile makes path the config file commands load
+func withConfigFile(t *testing.T, path string) {
+	t.Helper()
+	viper.SetConfigFile(path)
+	t.Cleanup(viper.Reset)
+}
+
+const complexInputsConfig = `
+inputs = {
+  zone     = "europe-west1-b"
+  name     = "web server's name"
+  subnets  = ["10.0.0.0/24", "10.0.1.0/24"]
+  labels   = { env = "dev", "cost-center" = "cc-1" }
+  replicas = 2
+  public   = false
+  peer     = null
+}
+`
+
+func TestPlanPassesInputsThroughTfvarsFile(t *testing.T) {
+	withPartialParseCache(t)
+	runner := useTfvarsRunner(t)
+	cmd, dir := newModuleCommand(t, planCmd, runPlan)
+	config := filepath.Join(dir, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(config, []byte(complexInputsConfig), 0644))
+	withConfigFile(t, config)
+	require.NoError(t, cmd.Flags().Set("terragrunt-no-auto-init", "true"))
+	require.NoError(t, cmd.Flags().Set("var", "zone=us-central1-a"))
+	require.NoError(t, cmd.Flags().Set("var-file", "prod.tfvars"))
+
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	require.Len(t, runner.tfvars, 1)
+	assert.Equal(t, [][]string{{"plan", "-var-file=" + runner.files[0], "-var=zone=us-central1-a", "-var-file=prod.tfvars"}}, runner.argv(),
+		"explicit variables come after the inputs, so they take precedence")
+	assert.Equal(t, filepath.Join(dir, terragruntCacheDir), filepath.Dir(filepath.Dir(runner.files[0])),
+		"the inputs are written to the module's cache, not its source")
+	assert.JSONEq(t, `{
+  "zone": "europe-west1-b",
+  "name": "web server's name",
+  "subnets": ["10.0.0.0/24", "10.0.1.0/24"],
+  "labels": {"env": "dev", "cost-center": "cc-1"},
+  "replicas": 2,
+  "public": false,
+  "peer": null
+}`, runner.tfvars[0])
+	assert.NoFileExists(t, runner.files[0])
+}
+
+func TestApplyRemovesTfvarsFileWhenTerraformFails(t *testing.T) {
+	withPartialParseCache(t)
+	runner := useTfvarsRunner(t)
+	runner.exitCode["apply"] = 1
+	cmd, dir := newModuleCommand(t, applyCmd, runApply)
+	config := filepath.Join(dir, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(config, []byte(complexInputsConfig), 0644))
+	withConfigFile(t, config)
+	require.NoError(t, cmd.Flags().Set("terragrunt-no-auto-init", "true"))
+	require.NoError(t, cmd.Flags().Set("auto-approve", "true"))
+
+	require.Error(t, cmd.RunE(cmd, nil))
+	require.Len(t, runner.tfvars, 1)
+	assert.NoFileExists(t, runner.files[0])
+
+	// A saved plan carries its variables, and terraform refuses new ones
+	runner.tfvars = nil
+	require.Error(t, cmd.RunE(cmd, []string{"saved.tfplan"}))
+	assert.Empty(t, runner.tfvars)
+}
+
+func TestWriteInputsTfvarsRedactsDebugLog(t *testing.T) {
+	out := useSecretLogger(t)
+	config := defaultTerragruntConfig()
+	config.SensitiveInputs = []string{"db_password"}
+	config.Variables = map[string]interface{}{"db_password": "s3cr3t-from-secret-manager", "region": "us-central1"}
+	registerConfigSecrets(nil, config, nil)
+	dir := t.TempDir()
+	ctx := &ExecutionContext{Config: config, WorkingDir: dir}
+
+	args, remove, err := writeInputsTfvars(ctx)
+	require.NoError(t, err)
+	path := inputsTfvarsPath(ctx)
+	assert.Equal(t, []string{"-var-file=" + path}, args)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "s3cr3t-from-secret-manager", "terraform gets the real value")
+	assert.NotContains(t, out.String(), "s3cr3t-from-secret-manager")
+	assert.Contains(t, out.String(), "us-central1")
+
+	remove()
+	assert.NoFileExists(t, path)
+
+	// Without inputs there is nothing to write
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, terragruntCacheDir)))
+	ctx.Config.Variables = nil
+	args, remove, err = writeInputsTfvars(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, args)
+	remove()
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestPlanPassesDependencyOutputsAsInputs(t *testing.T) {
+	withPartialParseCache(t)
+	runner := useTfvarsRunner(t)
+	cmd, dir := newModuleCommand(t, planCmd, runPlan)
+	config := filepath.Join(dir, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(config, []byte(`
+dependency "vpc" {
+  config_path = "../vpc"
+  enabled     = true
+  mock_outputs = {
+    network_id = "mock-network"
+    subnets    = ["10.0.0.0/24"]
+    routing    = { mode = "GLOBAL", mtu = 1460, peer = null }
+  }
+}
+
+inputs = {
+  zone    = "europe-west1-b"
+  network = dependency.vpc.outputs.network_id
+  subnets = dependency.vpc.outputs.subnets
+  routing = dependency.vpc.outputs.routing
+}
+`), 0644))
+	withConfigFile(t, config)
+	require.NoError(t, cmd.Flags().Set("terragrunt-no-auto-init", "true"))
+
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	require.Len(t, runner.tfvars, 1)
+	assert.JSONEq(t, `{
+  "zone": "europe-west1-b",
+  "network": "mock-network",
+  "subnets": ["10.0.0.0/24"],
+  "routing": {"mode": "GLOBAL", "mtu": 1460, "peer": null}
+}`, runner.tfvars[0])
+}
+
+func TestModuleInputsFailsOnUnknownDependencyOutput(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(config, []byte(`
+inputs = {
+  network = dependency.vpc.outputs.network_id
+  zone    = "europe-west1-b"
+}
+`), 0644))
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: dir, Dependencies: map[string]interface{}{"vpc.name": "default"}}
+
+	_, err := moduleInputs(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `input "network"`)
+}
+
+func TestModuleInputsReadTheModulesOwnConfig(t *testing.T) {
+	useSecretLogger(t)
+	root := t.TempDir()
+	parent := filepath.Join(root, "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(parent, []byte("inputs = {\n  network = dependency.other.outputs.id\n}\n"), 0644))
+	withConfigFile(t, parent)
+	newDependencyModule(t, root, "app", `
+inputs = {
+  db_password = dependency.db.outputs.password
+  db_host     = dependency.db.outputs.host
+}
+`)
+	ctx := &ExecutionContext{
+		Config:       defaultTerragruntConfig(),
+		WorkingDir:   filepath.Join(root, "app"),
+		Dependencies: map[string]interface{}{"db.password": "hunter2-from-outputs", "db.host": "10.0.0.5"},
+	}
+
+	inputs, err := moduleInputs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"db_password": "hunter2-from-outputs", "db_host": "10.0.0.5"}, inputs)
+
+	// The sensitive input is masked from then on, the other one is not
+	assert.Equal(t, "password=(redacted) host=10.0.0.5", redactSecrets("password=hunter2-from-outputs host=10.0.0.5"))
+}
+
+func TestRunModulesPassesEachModuleItsInputs(t *testing.T) {
+	withPartialParseCache(t)
+	runner := useTfvarsRunner(t)
+	stubDependencyIO(t, nil, `{"network_id": {"value": "projects/acme/global/networks/main"}}`)
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", "inputs = {\n  cidr = \"10.0.0.0/16\"\n}\n")
+	newDependencyModule(t, root, "app", `
+dependency "vpc" {
+  config_path = "../vpc"
+  enabled     = true
+}
+
+inputs = {
+  network = dependency.vpc.outputs.network_id
+  zone    = "europe-west1-b"
+}
+`)
+
+	config := defaultTerragruntConfig()
+	config.AutoInit = false
+	config.Parallelism = 1
+	config.Variables = map[string]interface{}{"root_only": "not for modules"}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Environment: map[string]string{}, Dependencies: map[string]interface{}{}, shared: &runState{}}
+
+	modules := []string{filepath.Join(root, "vpc"), filepath.Join(root, "app")}
+	runModules(ctx, modules, "plan", map[string][]string{modules[1]: {modules[0]}})
+	require.Empty(t, ctx.Errors())
+
+	require.Len(t, runner.tfvars, 2)
+	assert.JSONEq(t, `{"cidr": "10.0.0.0/16"}`, runner.tfvars[0])
+	assert.JSONEq(t, `{"network": "projects/acme/global/networks/main", "zone": "europe-west1-b"}`, runner.tfvars[1])
+	for _, file := range runner.files {
+		assert.NoFileExists(t, file)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/sirupsen/logrus"
+)
+
+// jsonStreamCommands are the terraform commands whose -json output is a
+// stream of log lines
+var jsonStreamCommands = map[string]bool{
+	"plan":    true,
+	"apply":   true,
+	"destroy": true,
+	"refresh": true,
+}
+
+// minJSONStreamVersion is the first terraform release with -json on plan
+// and apply
+var minJSONStreamVersion = goversion.Must(goversion.NewVersion("0.15.3"))
+
+// terraformJSONArgs adds -json to args when the command and the terraform
+// binary support streaming it. Apply and destroy only accept -json when they
+// won't prompt, so they need -auto-approve or a saved plan.
+func terraformJSONArgs(terraformPath string, args []string) ([]string, bool) {
+	if len(args) == 0 || !jsonStreamCommands[args[0]] {
+		return nil, false
+	}
+
+	prompts := args[0] == "apply" || args[0] == "destroy"
+	for _, arg := range args[1:] {
+		if arg == "-json" {
+			return nil, false
+		}
+		if arg == "-auto-approve" || (args[0] == "apply" && !strings.HasPrefix(arg, "-")) {
+			prompts = false
+		}
+	}
+	if prompts {
+		return nil, false
+	}
+
+	raw, err := terraformVersionOf(terraformPath)
+	if err != nil {
+		return nil, false
+	}
+	version, err := goversion.NewVersion(raw)
+	if err != nil || version.LessThan(minJSONStreamVersion) {
+		return nil, false
+	}
+
+	return append([]string{args[0], "-json"}, args[1:]...), true
+}
+
+// terraformJSONMessage is one line of terraform's machine-readable UI output
+type terraformJSONMessage struct {
+	Level   string `json:"@level"`
+	Message string `json:"@message"`
+	Module  string `json:"@module"`
+	Type    string `json:"type"`
+	Hook    *struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+		Action         string  `json:"action"`
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+	} `json:"hook"`
+	Changes *struct {
+		Add       int    `json:"add"`
+		Change    int    `json:"change"`
+		Remove    int    `json:"remove"`
+		Operation string `json:"operation"`
+	} `json:"changes"`
+	Diagnostic *struct {
+		Severity string `json:"severity"`
+		Summary  string `json:"summary"`
+		Detail   string `json:"detail"`
+	} `json:"diagnostic"`
+}
+
+// applyProgressEvent reports a resource starting, progressing, finishing or
+// failing during apply
+type applyProgressEvent struct {
+	Type      string
+	Resource  string
+	Action    string
+	Elapsed   float64
+	Completed int
+	Total     int
+}
+
+// terraformJSONStream is the stdout of a terraform command run with -json.
+// It re-emits each message as a structured log entry, tracks apply progress
+// and passes lines that aren't JSON through to out unchanged.
+type terraformJSONStream struct {
+	entry *logrus.Entry
+	out   io.Writer
+	// onProgress, when set, receives every apply progress event
+	onProgress func(applyProgressEvent)
+
+	buf       []byte
+	total     int
+	completed int
+}
+
+func newTerraformJSONStream(entry *logrus.Entry, out io.Writer) *terraformJSONStream {
+	return &terraformJSONStream{entry: entry, out: out}
+}
+
+func (s *terraformJSONStream) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			break
+		}
+		s.handleLine(s.buf[:i])
+		s.buf = s.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush handles a trailing line without a newline
+func (s *terraformJSONStream) Flush() {
+	if len(s.buf) > 0 {
+		s.handleLine(s.buf)
+		s.buf = nil
+	}
+}
+
+func (s *terraformJSONStream) handleLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+
+	var msg terraformJSONMessage
+	if err := json.Unmarshal(line, &msg); err != nil || msg.Type == "" {
+		fmt.Fprintf(s.out, "%s\n", line)
+		return
+	}
+	s.handleMessage(msg)
+}
+
+func (s *terraformJSONStream) handleMessage(msg terraformJSONMessage) {
+	fields := logrus.Fields{"tf_type": msg.Type}
+	message := msg.Message
+
+	switch {
+	case msg.Type == "change_summary" && msg.Changes != nil:
+		fields["add"] = msg.Changes.Add
+		fields["change"] = msg.Changes.Change
+		fields["remove"] = msg.Changes.Remove
+		if msg.Changes.Operation == "plan" {
+			s.total = msg.Changes.Add + msg.Changes.Change + msg.Changes.Remove
+		}
+	case msg.Type == "diagnostic" && msg.Diagnostic != nil:
+		fields["severity"] = msg.Diagnostic.Severity
+		if msg.Diagnostic.Detail != "" {
+			fields["detail"] = msg.Diagnostic.Detail
+		}
+	case msg.Hook != nil:
+		fields["resource"] = msg.Hook.Resource.Addr
+		fields["action"] = msg.Hook.Action
+		if event, ok := s.progress(msg); ok {
+			if event.Type == "apply_complete" || event.Type == "apply_errored" {
+				message = fmt.Sprintf("[%d/%d] %s", event.Completed, event.Total, message)
+			}
+			if s.onProgress != nil {
+				s.onProgress(event)
+			}
+		}
+	}
+
+	s.entry.WithFields(fields).Log(terraformLogLevel(msg.Level), message)
+}
+
+// progress turns an apply hook message into a progress event
+func (s *terraformJSONStream) progress(msg terraformJSONMessage) (applyProgressEvent, bool) {
+	switch msg.Type {
+	case "apply_complete", "apply_errored":
+		s.completed++
+		if s.completed > s.total {
+			s.total = s.completed
+		}
+	case "apply_start", "apply_progress":
+	default:
+		return applyProgressEvent{}, false
+	}
+	return applyProgressEvent{
+		Type:      msg.Type,
+		Resource:  msg.Hook.Resource.Addr,
+		Action:    msg.Hook.Action,
+		Elapsed:   msg.Hook.ElapsedSeconds,
+		Completed: s.completed,
+		Total:     s.total,
+	}, true
+}
+
+// terraformLogLevel maps terraform's @level to a logrus level
+func terraformLogLevel(level string) logrus.Level {
+	switch level {
+	case "error":
+		return logrus.ErrorLevel
+	case "warn":
+		return logrus.WarnLevel
+	case "debug":
+		return logrus.DebugLevel
+	case "trace":
+		return logrus.TraceLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformJSONStreamApplyProgress(t *testing.T) {
+	captured, err := os.ReadFile(filepath.Join("testdata", "apply.jsonl"))
+	require.NoError(t, err)
+
+	log, hook := test.NewNullLogger()
+	var out bytes.Buffer
+	stream := newTerraformJSONStream(logrus.NewEntry(log), &out)
+	var events []applyProgressEvent
+	stream.onProgress = func(event applyProgressEvent) { events = append(events, event) }
+
+	// Feed the capture in uneven chunks the way a pipe would deliver it
+	for len(captured) > 0 {
+		n := min(97, len(captured))
+		_, err := stream.Write(captured[:n])
+		require.NoError(t, err)
+		captured = captured[n:]
+	}
+	stream.Flush()
+
+	assert.Equal(t, []applyProgressEvent{
+		{Type: "apply_start", Resource: "google_compute_network.main", Action: "update", Total: 2},
+		{Type: "apply_start", Resource: "google_storage_bucket.logs", Action: "create", Total: 2},
+		{Type: "apply_complete", Resource: "google_storage_bucket.logs", Action: "create", Elapsed: 2, Completed: 1, Total: 2},
+		{Type: "apply_progress", Resource: "google_compute_network.main", Action: "update", Elapsed: 10, Completed: 1, Total: 2},
+		{Type: "apply_complete", Resource: "google_compute_network.main", Action: "update", Elapsed: 12, Completed: 2, Total: 2},
+	}, events)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 11)
+	assert.Empty(t, out.String())
+
+	complete := entries[6]
+	assert.Equal(t, "[1/2] google_storage_bucket.logs: Creation complete after 2s [id=demo-logs]", complete.Message)
+	assert.Equal(t, "apply_complete", complete.Data["tf_type"])
+	assert.Equal(t, "google_storage_bucket.logs", complete.Data["resource"])
+	assert.Equal(t, "create", complete.Data["action"])
+
+	summary := entries[9]
+	assert.Equal(t, "Apply complete! Resources: 1 added, 1 changed, 0 destroyed.", summary.Message)
+	assert.Equal(t, 1, summary.Data["add"])
+	assert.Equal(t, 1, summary.Data["change"])
+}
+
+func TestTerraformJSONStreamPassthrough(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	var out bytes.Buffer
+	stream := newTerraformJSONStream(logrus.NewEntry(log), &out)
+
+	_, err := stream.Write([]byte("Initializing plugins...\n" +
+		`{"@level":"error","@message":"Error: Invalid reference","type":"diagnostic","diagnostic":{"severity":"error","summary":"Invalid reference","detail":"A reference must be a resource"}}` + "\n" +
+		`{"not":"a terraform message"}` + "\ntrailing"))
+	require.NoError(t, err)
+	stream.Flush()
+
+	assert.Equal(t, "Initializing plugins...\n{\"not\":\"a terraform message\"}\ntrailing\n", out.String())
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "Error: Invalid reference", entry.Message)
+	assert.Equal(t, "A reference must be a resource", entry.Data["detail"])
+}
+
+func TestTerraformJSONArgs(t *testing.T) {
+	original := terraformVersionOf
+	t.Cleanup(func() { terraformVersionOf = original })
+	version := "1.6.6"
+	terraformVersionOf = func(string) (string, error) { return version, nil }
+
+	args, ok := terraformJSONArgs("terraform", []string{"apply", "-auto-approve", "-parallelism=5"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"apply", "-json", "-auto-approve", "-parallelism=5"}, args)
+
+	args, ok = terraformJSONArgs("terraform", []string{"apply", "tfplan"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"apply", "-json", "tfplan"}, args)
+
+	_, ok = terraformJSONArgs("terraform", []string{"plan", "-out=tfplan"})
+	assert.True(t, ok)
+
+	// Interactive applies, unsupported commands and explicit -json pass through
+	for _, unsupported := range [][]string{
+		{"apply"},
+		{"destroy", "-var=a=b"},
+		{"output", "-json"},
+		{"init"},
+		{"plan", "-json"},
+	} {
+		_, ok := terraformJSONArgs("terraform", unsupported)
+		assert.False(t, ok, "%v", unsupported)
+	}
+
+	version = "0.14.11"
+	_, ok = terraformJSONArgs("terraform", []string{"plan"})
+	assert.False(t, ok)
+
+	terraformVersionOf = func(string) (string, error) { return "", errors.New("not found") }
+	_, ok = terraformJSONArgs("terraform", []string{"plan"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// defaultRequiredLabels apply when the label policy is enabled without an
+// explicit required list
+var defaultRequiredLabels = []string{"env", "owner", "cost-center"}
+
+// defaultLabelValuePattern is GCP's constraint on label values
+const defaultLabelValuePattern = `^[a-z0-9_-]{1,63}$`
+
+// labeledResourceTypes are the resource types that accept a top-level labels argument
+var labeledResourceTypes = map[string]bool{
+	"google_compute_instance":         true,
+	"google_compute_disk":             true,
+	"google_compute_image":            true,
+	"google_compute_snapshot":         true,
+	"google_storage_bucket":           true,
+	"google_container_cluster":        true,
+	"google_bigquery_dataset":         true,
+	"google_bigquery_table":           true,
+	"google_pubsub_topic":             true,
+	"google_pubsub_subscription":      true,
+	"google_cloudfunctions_function":  true,
+	"google_cloudfunctions2_function": true,
+	"google_kms_crypto_key":           true,
+	"google_redis_instance":           true,
+	"google_secret_manager_secret":    true,
+}
+
+// LabelPolicyConfig lists the labels every resource must carry
+type LabelPolicyConfig struct {
+	Enabled  bool              `json:"enabled" mapstructure:"enabled"`
+	Required []string          `json:"required" mapstructure:"required"`
+	Patterns map[string]string `json:"patterns" mapstructure:"patterns"`
+}
+
+func (p LabelPolicyConfig) requiredLabels() []string {
+	if len(p.Required) > 0 {
+		return p.Required
+	}
+	return defaultRequiredLabels
+}
+
+// valuePattern returns the regexp a label's value must match
+func (p LabelPolicyConfig) valuePattern(key string) (*regexp.Regexp, error) {
+	pattern := defaultLabelValuePattern
+	if custom, ok := p.Patterns[key]; ok && custom != "" {
+		pattern = custom
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for label %s: %w", key, err)
+	}
+	return re, nil
+}
+
+// labelViolation describes a resource that breaks the label policy
+type labelViolation struct {
+	Resource  string
+	Pos       string
+	Missing   []string
+	Malformed []string
+}
+
+func (v labelViolation) String() string {
+	var problems []string
+	if len(v.Missing) > 0 {
+		problems = append(problems, "missing labels "+strings.Join(v.Missing, ", "))
+	}
+	if len(v.Malformed) > 0 {
+		problems = append(problems, "malformed labels "+strings.Join(v.Malformed, ", "))
+	}
+	return fmt.Sprintf("%s (%s): %s", v.Resource, v.Pos, strings.Join(problems, "; "))
+}
+
+// checkModuleLabels statically checks the labels of every labelable resource
+// in the module at dir. Labels are evaluated against variable defaults, the
+// module's terragrunt.hcl inputs and overrides; resources whose labels can't
+// be evaluated statically are skipped with a warning.
+func checkModuleLabels(dir string, policy LabelPolicyConfig, overrides map[string]interface{}) ([]labelViolation, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var bodies []*hclsyntax.Body
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, diags := hclsyntax.ParseConfig(data, file, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %s", file, diags.Error())
+		}
+		bodies = append(bodies, parsed.Body.(*hclsyntax.Body))
+	}
+
+	vars := moduleVariableDefaults(bodies)
+	for name, value := range readModuleInputs(dir) {
+		vars[name] = value
+	}
+	for name, value := range overrides {
+		if converted, err := toCtyValue(value); err == nil {
+			vars[name] = converted
+		}
+	}
+
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(vars)},
+		Functions: map[string]function.Function{"merge": stdlib.MergeFunc},
+	}
+
+	var violations []labelViolation
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "resource" || len(block.Labels) != 2 || !labeledResourceTypes[block.Labels[0]] {
+				continue
+			}
+
+			address := block.Labels[0] + "." + block.Labels[1]
+			pos := fmt.Sprintf("%s:%d", filepath.Base(block.DefRange().Filename), block.DefRange().Start.Line)
+
+			labels := map[string]string{}
+			if attr, ok := block.Body.Attributes["labels"]; ok {
+				value, diags := attr.Expr.Value(evalCtx)
+				if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() || !value.CanIterateElements() {
+					logger.Warnf("Skipping label check for %s: labels can't be evaluated statically", address)
+					continue
+				}
+				for it := value.ElementIterator(); it.Next(); {
+					key, val := it.Element()
+					if val.Type() == cty.String && !val.IsNull() {
+						labels[key.AsString()] = val.AsString()
+					}
+				}
+			}
+
+			violation := labelViolation{Resource: address, Pos: pos}
+			for _, key := range policy.requiredLabels() {
+				value, ok := labels[key]
+				if !ok || value == "" {
+					violation.Missing = append(violation.Missing, key)
+					continue
+				}
+				re, err := policy.valuePattern(key)
+				if err != nil {
+					return nil, err
+				}
+				if !re.MatchString(value) {
+					violation.Malformed = append(violation.Malformed, fmt.Sprintf("%s=%q", key, value))
+				}
+			}
+			if len(violation.Missing) > 0 || len(violation.Malformed) > 0 {
+				violations = append(violations, violation)
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// moduleVariableDefaults evaluates the literal defaults of variable blocks
+func moduleVariableDefaults(bodies []*hclsyntax.Body) map[string]cty.Value {
+	vars := make(map[string]cty.Value)
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 {
+				continue
+			}
+			attr, ok := block.Body.Attributes["default"]
+			if !ok {
+				continue
+			}
+			if value, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+				vars[block.Labels[0]] = value
+			}
+		}
+	}
+	return vars
+}
+
+// readModuleInputs evaluates the inputs of dir/terragrunt.hcl with its
+// literal locals in scope. Inputs that reference anything else are skipped.
+func readModuleInputs(dir string) map[string]cty.Value {
+	inputs := make(map[string]cty.Value)
+
+	data, err := os.ReadFile(filepath.Join(dir, "terragrunt.hcl"))
+	if err != nil {
+		return inputs
+	}
+	file, diags := hclsyntax.ParseConfig(data, "terragrunt.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return inputs
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	locals := make(map[string]cty.Value)
+	for _, block := range body.Blocks {
+		if block.Type != "locals" {
+			continue
+		}
+		for name, attr := range block.Body.Attributes {
+			if value, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+				locals[name] = value
+			}
+		}
+	}
+	evalCtx := &hcl.EvalContext{Variables: map[string]cty.Value{"local": cty.ObjectVal(locals)}}
+
+	attr, ok := body.Attributes["inputs"]
+	if !ok {
+		return inputs
+	}
+	object, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return inputs
+	}
+	for _, item := range object.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		if diags.HasErrors() || key.Type() != cty.String {
+			continue
+		}
+		value, diags := item.ValueExpr.Value(evalCtx)
+		if diags.HasErrors() {
+			continue
+		}
+		inputs[key.AsString()] = value
+	}
+	return inputs
+}
+
+func toCtyValue(value interface{}) (cty.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ty, err := ctyjson.ImpliedType(data)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(data, ty)
+}
+
+// validateLabels fails when any resource in the module breaks the label policy
+func validateLabels(ctx *ExecutionContext) error {
+	violations, err := checkModuleLabels(ctx.terraformDir(), ctx.Config.LabelPolicy, ctx.Config.Variables)
+	if err != nil {
+		return fmt.Errorf("label check failed: %w", err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		logger.Errorf("Label policy violation: %s", v)
+		lines = append(lines, v.String())
+	}
+	return fmt.Errorf("%d resource(s) violate the label policy:\n  %s", len(violations), strings.Join(lines, "\n  "))
+}
+
+// scaffoldLabelLines renders the label entries scaffold injects into the
+// generated inputs for required labels the template doesn't already set.
+// Labels scaffold can't fill in come commented out, starting with "# ", for
+// the user to set: an empty value would fail the policy.
+func scaffoldLabelLines(policy LabelPolicyConfig, existing []string) []string {
+	if !policy.Enabled {
+		return nil
+	}
+
+	present := make(map[string]bool, len(existing))
+	for _, key := range existing {
+		present[key] = true
+	}
+
+	var lines []string
+	for _, key := range policy.requiredLabels() {
+		if present[key] {
+			continue
+		}
+		name := key
+		if !hclsyntax.ValidIdentifier(key) || strings.Contains(key, "-") {
+			name = fmt.Sprintf("%q", key)
+		}
+		if key == "env" || key == "environment" {
+			lines = append(lines, fmt.Sprintf("%s = local.environment", name))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf(`# %s = ""`, name))
+	}
+	return lines
+}
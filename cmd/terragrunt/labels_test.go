@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScaffoldedModule(t *testing.T, policy LabelPolicyConfig) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf":        generateMainTF("default", "web"),
+		"variables.tf":   generateVariablesTF("default", "web"),
+		"terragrunt.hcl": generateTerragruntHCL("default", "web", policy),
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestScaffoldInjectsRequiredLabels(t *testing.T) {
+	policy := LabelPolicyConfig{Enabled: true}
+	hcl := generateTerragruntHCL("default", "web", policy)
+
+	assert.Contains(t, hcl, "    env           = local.environment\n")
+	assert.Contains(t, hcl, "    managed_by    = \"terragrunt\"\n")
+	// Labels without a value are left for the user, commented out so no
+	// empty label is applied
+	assert.Contains(t, hcl, "    # owner         = \"\"\n")
+	assert.Contains(t, hcl, "    # \"cost-center\" = \"\"\n")
+	assert.NotContains(t, hcl, "= \"\" #")
+
+	_, err := decodeHCLConfig([]byte(hcl), "terragrunt.hcl")
+	require.NoError(t, err, "generated terragrunt.hcl must stay valid HCL")
+
+	// Without a policy the template is unchanged
+	plain := generateTerragruntHCL("default", "web", LabelPolicyConfig{})
+	assert.NotContains(t, plain, "cost-center")
+	assert.Contains(t, plain, "    environment = local.environment\n")
+}
+
+func TestValidateCatchesMissingCostCenter(t *testing.T) {
+	dir := writeScaffoldedModule(t, LabelPolicyConfig{Enabled: true})
+
+	// Fill in owner but leave cost-center unset
+	hclPath := filepath.Join(dir, "terragrunt.hcl")
+	data, err := os.ReadFile(hclPath)
+	require.NoError(t, err)
+	updated := strings.Replace(string(data), `# owner         = ""`, `owner           = "platform"`, 1)
+	require.NoError(t, os.WriteFile(hclPath, []byte(updated), 0644))
+
+	violations, err := checkModuleLabels(dir, LabelPolicyConfig{Enabled: true}, nil)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "google_compute_instance.example", violations[0].Resource)
+	assert.Equal(t, []string{"cost-center"}, violations[0].Missing)
+	assert.Empty(t, violations[0].Malformed)
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: dir}
+	ctx.Config.LabelPolicy = LabelPolicyConfig{Enabled: true}
+	err = validateLabels(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "google_compute_instance.example (main.tf:3): missing labels cost-center")
+
+	// Supplying the label through inputs overrides satisfies the policy
+	ctx.Config.Variables["labels"] = map[string]interface{}{"env": "dev", "owner": "platform", "cost-center": "cc-1234"}
+	assert.NoError(t, validateLabels(ctx))
+}
+
+func TestCheckModuleLabelsPatterns(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+locals {
+  ignored = true
+}
+
+resource "google_storage_bucket" "logs" {
+  name = "logs"
+  labels = merge(var.base_labels, {
+    owner         = "Platform Team"
+    "cost-center" = "1234"
+  })
+}
+
+resource "google_storage_bucket" "unlabeled" {
+  name = "unlabeled"
+}
+
+resource "google_compute_network" "vpc" {
+  name = "vpc"
+}
+
+variable "base_labels" {
+  type    = map(string)
+  default = { env = "prod" }
+}
+`), 0644))
+
+	policy := LabelPolicyConfig{
+		Enabled:  true,
+		Patterns: map[string]string{"cost-center": `^cc-[0-9]+$`},
+	}
+	violations, err := checkModuleLabels(dir, policy, nil)
+	require.NoError(t, err)
+	require.Len(t, violations, 2)
+
+	assert.Equal(t, "google_storage_bucket.logs", violations[0].Resource)
+	assert.Empty(t, violations[0].Missing)
+	assert.Equal(t, []string{`owner="Platform Team"`, `cost-center="1234"`}, violations[0].Malformed)
+
+	assert.Equal(t, "google_storage_bucket.unlabeled", violations[1].Resource)
+	assert.Equal(t, []string{"env", "owner", "cost-center"}, violations[1].Missing)
+}
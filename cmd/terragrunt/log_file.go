@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// logFileOptions configures the --terragrunt-log-file sink
+type logFileOptions struct {
+	Path string
+	// MaxSizeMB is the size at which the file is rotated
+	MaxSizeMB int
+	// MaxBackups is how many rotated files to keep (0 keeps all)
+	MaxBackups int
+	// Format is text or json, independent of the console format
+	Format string
+}
+
+// logFileSink is the open log file, closed when the command finishes
+var logFileSink io.Closer
+
+// fileLogHook tees every log entry to a writer with its own formatter
+type fileLogHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
+
+func (h *fileLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fileLogHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.writer.Write(line)
+	return err
+}
+
+// addLogFile makes log write to a size-rotated file in addition to its
+// console output
+func addLogFile(log *logrus.Logger, opts logFileOptions) (io.Closer, error) {
+	var formatter logrus.Formatter
+	switch opts.Format {
+	case "", "text":
+		formatter = &logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+			DisableColors:   true,
+		}
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+	default:
+		return nil, fmt.Errorf("unsupported log file format %q (expected text or json)", opts.Format)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   opts.Path,
+		MaxSize:    opts.MaxSizeMB,
+		MaxBackups: opts.MaxBackups,
+	}
+	log.AddHook(&fileLogHook{writer: file, formatter: formatter})
+	return file, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileLogger(t *testing.T, opts logFileOptions) (*logrus.Logger, *bytes.Buffer) {
+	t.Helper()
+	var console bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&console)
+
+	sink, err := addLogFile(log, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { sink.Close() })
+	return log, &console
+}
+
+func TestLogFileReceivesLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "terragrunt.log")
+	log, console := newFileLogger(t, logFileOptions{Path: path, MaxSizeMB: 1, Format: "json"})
+
+	log.WithField("module", "vpc").Info("Applying Terraform configuration")
+	log.Debug("not logged at info level")
+
+	assert.Contains(t, console.String(), "Applying Terraform configuration")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "Applying Terraform configuration", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "vpc", entry["module"])
+}
+
+func TestLogFileRotatesAtMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "terragrunt.log")
+	log, _ := newFileLogger(t, logFileOptions{Path: path, MaxSizeMB: 1, MaxBackups: 2})
+
+	// Roughly 1.5MB of text, enough to rotate a 1MB file once
+	line := strings.Repeat("x", 1000)
+	for i := 0; i < 1500; i++ {
+		log.Info(line)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "terragrunt-*.log"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	backup, err := os.Stat(files[0])
+	require.NoError(t, err)
+	assert.LessOrEqual(t, backup.Size(), int64(1024*1024))
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(current), "\x1b[", "file sink must not be colored")
+	assert.Contains(t, string(current), "level=info")
+}
+
+func TestLogFileRejectsUnknownFormat(t *testing.T) {
+	_, err := addLogFile(logrus.New(), logFileOptions{Path: filepath.Join(t.TempDir(), "x.log"), Format: "xml"})
+	assert.ErrorContains(t, err, `unsupported log file format "xml"`)
+}
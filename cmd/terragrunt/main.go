@@ -2,24 +2,26 @@ package main
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
+	goversion "github.com/hashicorp/go-version"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
 )
 
 var (
@@ -54,6 +56,48 @@ type TerragruntConfig struct {
 	RemoteState     RemoteStateConfig      `json:"remote_state" mapstructure:"remote_state"`
 	TerraformBinary TerraformBinaryConfig  `json:"terraform_binary" mapstructure:"terraform_binary"`
 	ErrorHandling   ErrorHandlingConfig    `json:"error_handling" mapstructure:"error_handling"`
+	Generate        []GenerateConfig       `json:"generate" mapstructure:"generate"`
+	Terraform       TerraformConfig        `json:"terraform" mapstructure:"terraform"`
+
+	FetchDependencyOutputFromState bool                       `json:"fetch_dependency_output_from_state" mapstructure:"fetch_dependency_output_from_state"`
+	ReadOutputFromBackend          bool                       `json:"read_output_from_backend" mapstructure:"read_output_from_backend"`
+	LabelPolicy                    LabelPolicyConfig          `json:"label_policy" mapstructure:"label_policy"`
+	Scaffold                       ScaffoldConfig             `json:"scaffold" mapstructure:"scaffold"`
+	UsePartialParseConfigCache     bool                       `json:"use_partial_parse_config_cache" mapstructure:"use_partial_parse_config_cache"`
+	IncludeExternalDependencies    bool                       `json:"include_external_dependencies" mapstructure:"include_external_dependencies"`
+	IgnoreExternalDependencies     bool                       `json:"ignore_external_dependencies" mapstructure:"ignore_external_dependencies"`
+	ProviderVerification           ProviderVerificationConfig `json:"provider_verification" mapstructure:"provider_verification"`
+	ProviderPatch                  ProviderPatchConfig        `json:"provider_patch" mapstructure:"provider_patch"`
+	Diff                           bool                       `json:"diff" mapstructure:"diff"`
+	JSONLog                        bool                       `json:"json_log" mapstructure:"json_log"`
+	DetectDrift                    bool                       `json:"detect_drift" mapstructure:"detect_drift"`
+	AcceptDrift                    bool                       `json:"accept_drift" mapstructure:"accept_drift"`
+	OnlyWithState                  bool                       `json:"only_with_state" mapstructure:"only_with_state"`
+	OnlyWithoutState               bool                       `json:"only_without_state" mapstructure:"only_without_state"`
+	SourceOverride                 string                     `json:"source_override" mapstructure:"source_override"`
+	SourceUpdate                   bool                       `json:"source_update" mapstructure:"source_update"`
+	IsolateWorkingDir              bool                       `json:"isolate_working_dir" mapstructure:"isolate_working_dir"`
+	QueueIncludeDirs               []string                   `json:"queue_include_dirs" mapstructure:"queue_include_dirs"`
+	ApplyDependencies              bool                       `json:"apply_dependencies" mapstructure:"apply_dependencies"`
+	// FailFast stops a run-all at the first failed module; ContinueOnError,
+	// the default, runs every module and reports the failures at the end
+	FailFast        bool `json:"fail_fast" mapstructure:"fail_fast"`
+	ContinueOnError bool `json:"continue_on_error" mapstructure:"continue_on_error"`
+	// MaxDependencyDepth fails run-all and graph-dependencies when a chain
+	// of dependencies is deeper (0 = no limit); DependencyFanWarning warns
+	// about modules with more dependencies or dependents than it
+	MaxDependencyDepth   int `json:"max_dependency_depth" mapstructure:"max_dependency_depth"`
+	DependencyFanWarning int `json:"dependency_fan_warning" mapstructure:"dependency_fan_warning"`
+	// RetryBudget caps the retries of all terraform commands of a run
+	// together and MaxExecRate the terraform executions per second across
+	// modules, so a run-all failing everywhere can't hammer the APIs behind
+	// it (0 = no limit)
+	RetryBudget int     `json:"retry_budget" mapstructure:"retry_budget"`
+	MaxExecRate float64 `json:"max_exec_rate" mapstructure:"max_exec_rate"`
+	// SensitiveInputs names inputs holding secrets, such as values read
+	// from Secret Manager, that are masked in logs and rendered output
+	SensitiveInputs []string `json:"sensitive_inputs" mapstructure:"sensitive_inputs"`
+	Debug           bool     `json:"debug" mapstructure:"debug"`
 }
 
 type GCPConfig struct {
@@ -68,13 +112,15 @@ type GCPConfig struct {
 }
 
 type BackendConfig struct {
-	Type          string                 `json:"type" mapstructure:"type"`
-	Bucket        string                 `json:"bucket" mapstructure:"bucket"`
-	Prefix        string                 `json:"prefix" mapstructure:"prefix"`
-	EncryptionKey string                 `json:"encryption_key" mapstructure:"encryption_key"`
-	DynamoDBTable string                 `json:"dynamodb_table" mapstructure:"dynamodb_table"`
-	StateFileID   string                 `json:"state_file_id" mapstructure:"state_file_id"`
-	CustomConfig  map[string]interface{} `json:"custom_config" mapstructure:"custom_config"`
+	Type                      string                 `json:"type" mapstructure:"type"`
+	Bucket                    string                 `json:"bucket" mapstructure:"bucket"`
+	Prefix                    string                 `json:"prefix" mapstructure:"prefix"`
+	EncryptionKey             string                 `json:"encryption_key" mapstructure:"encryption_key"`
+	KMSEncryptionKey          string                 `json:"kms_encryption_key" mapstructure:"kms_encryption_key"`
+	ImpersonateServiceAccount string                 `json:"impersonate_service_account" mapstructure:"impersonate_service_account"`
+	DynamoDBTable             string                 `json:"dynamodb_table" mapstructure:"dynamodb_table"`
+	StateFileID               string                 `json:"state_file_id" mapstructure:"state_file_id"`
+	CustomConfig              map[string]interface{} `json:"custom_config" mapstructure:"custom_config"`
 }
 
 type DependencyConfig struct {
@@ -83,13 +129,42 @@ type DependencyConfig struct {
 	ConfigPath  string                 `json:"config_path" mapstructure:"config_path"`
 	SkipOutputs bool                   `json:"skip_outputs" mapstructure:"skip_outputs"`
 	MockOutputs map[string]interface{} `json:"mock_outputs" mapstructure:"mock_outputs"`
-	Enabled     bool                   `json:"enabled" mapstructure:"enabled"`
+	// MockOutputsSchema declares the type of each output ("string",
+	// "list(string)", ...) that mocks must match and real outputs are
+	// compared against
+	MockOutputsSchema map[string]string `json:"mock_outputs_schema" mapstructure:"mock_outputs_schema"`
+	Enabled           bool              `json:"enabled" mapstructure:"enabled"`
+	// StateGeneration pins outputs to that GCS object generation of the
+	// dependency's state, as in promotion workflows; 0 reads the latest
+	StateGeneration int64 `json:"state_generation" mapstructure:"state_generation"`
+}
+
+// GenerateConfig is a file written into the working directory before
+// terraform runs (generate "provider" { path = ..., contents = ... })
+type GenerateConfig struct {
+	Name     string `json:"name" mapstructure:"name"`
+	Path     string `json:"path" mapstructure:"path"`
+	Contents string `json:"contents" mapstructure:"contents"`
+	// IfExists says what to do when a file terragrunt didn't generate is
+	// already at Path: "error" (the default), "overwrite" or "skip"
+	IfExists string `json:"if_exists" mapstructure:"if_exists"`
 }
 
 type HooksConfig struct {
 	BeforeHooks []HookConfig `json:"before_hooks" mapstructure:"before_hooks"`
 	AfterHooks  []HookConfig `json:"after_hooks" mapstructure:"after_hooks"`
 	ErrorHooks  []HookConfig `json:"error_hooks" mapstructure:"error_hooks"`
+	// Before, After and Error set how the hooks of each group run
+	Before HookGroupConfig `json:"before" mapstructure:"before"`
+	After  HookGroupConfig `json:"after" mapstructure:"after"`
+	Error  HookGroupConfig `json:"error" mapstructure:"error"`
+}
+
+// HookGroupConfig lets the hooks of a group run concurrently, at most
+// MaxParallel at a time
+type HookGroupConfig struct {
+	Parallel    bool `json:"parallel" mapstructure:"parallel"`
+	MaxParallel int  `json:"max_parallel" mapstructure:"max_parallel"`
 }
 
 type HookConfig struct {
@@ -106,6 +181,7 @@ type CacheConfig struct {
 	MaxSize        int64         `json:"max_size" mapstructure:"max_size"`
 	TTL            time.Duration `json:"ttl" mapstructure:"ttl"`
 	CleanupOnStart bool          `json:"cleanup_on_start" mapstructure:"cleanup_on_start"`
+	PluginCacheDir string        `json:"plugin_cache_dir" mapstructure:"plugin_cache_dir"`
 }
 
 type RemoteStateConfig struct {
@@ -135,6 +211,7 @@ type ErrorHandlingConfig struct {
 type ExecutionContext struct {
 	Config          *TerragruntConfig
 	WorkingDir      string
+	TerraformDir    string
 	Command         string
 	Args            []string
 	Environment     map[string]string
@@ -148,8 +225,7 @@ type ExecutionContext struct {
 	Hooks           []HookConfig
 	StartTime       time.Time
 	Logger          *logrus.Logger
-	mutex           sync.Mutex
-	errors          []error
+	shared          *runState
 }
 
 var rootCmd = &cobra.Command{
@@ -160,6 +236,16 @@ var rootCmd = &cobra.Command{
 - Managing remote state
 - Managing dependencies between modules
 - Keeping your Terraform code DRY`,
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if clean, _ := cmd.Flags().GetBool("terragrunt-clean"); !clean {
+			return nil
+		}
+		ctx, err := createExecutionContext(cmd)
+		if err != nil {
+			return err
+		}
+		return cleanModuleGeneratedFiles(ctx)
+	},
 }
 
 var initCmd = &cobra.Command{
@@ -207,7 +293,13 @@ var outputCmd = &cobra.Command{
 var runAllCmd = &cobra.Command{
 	Use:   "run-all",
 	Short: "Run Terraform command against multiple modules",
-	Long:  `Execute Terraform commands across multiple modules in dependency order`,
+	Long: `Execute Terraform commands across multiple modules in dependency order.
+
+By default a failed module does not stop the run: every other module still
+runs and the failures are reported at the end, though later queue groups are
+not started. --terragrunt-continue-on-error runs the later queue groups too.
+With --terragrunt-fail-fast the first failure interrupts the modules that are
+running and skips the ones still waiting.`,
 }
 
 var planAllCmd = &cobra.Command{
@@ -224,7 +316,7 @@ var applyAllCmd = &cobra.Command{
 
 var destroyAllCmd = &cobra.Command{
 	Use:   "destroy",
-	Short: "Run terraform destroy across all modules",
+	Short: "Run terraform destroy across all modules, dependents first",
 	RunE:  runDestroyAll,
 }
 
@@ -251,9 +343,11 @@ var renderJsonCmd = &cobra.Command{
 
 var awsProviderPatchCmd = &cobra.Command{
 	Use:   "aws-provider-patch",
-	Short: "Patch AWS provider",
-	Long:  `Update AWS provider to handle authentication and region configuration`,
-	RunE:  runAWSProviderPatch,
+	Short: "Patch provider blocks in downloaded modules",
+	Long: `Override attributes of the aws or google provider blocks in the modules
+terraform downloaded to .terraform/modules, for settings such as region or
+assume_role that the modules don't expose as variables. Run after init.`,
+	RunE: runAWSProviderPatch,
 }
 
 var scaffoldCmd = &cobra.Command{
@@ -285,35 +379,7 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
-	rootCmd.PersistentFlags().StringP("terragrunt-config", "c", "", "Path to the Terragrunt config file")
-	rootCmd.PersistentFlags().StringP("terragrunt-working-dir", "w", "", "Working directory for Terragrunt")
-	rootCmd.PersistentFlags().BoolP("terragrunt-non-interactive", "n", false, "Run in non-interactive mode")
-	rootCmd.PersistentFlags().BoolP("terragrunt-debug", "d", false, "Enable debug logging")
-	rootCmd.PersistentFlags().StringP("terragrunt-log-level", "l", "info", "Set log level")
-	rootCmd.PersistentFlags().StringP("terragrunt-iam-role", "", "", "IAM role to assume")
-	rootCmd.PersistentFlags().BoolP("terragrunt-no-auto-init", "", false, "Disable automatic terraform init")
-	rootCmd.PersistentFlags().BoolP("terragrunt-no-auto-retry", "", false, "Disable automatic retry on errors")
-	rootCmd.PersistentFlags().IntP("terragrunt-parallelism", "p", 10, "Limit number of parallel executions")
-	rootCmd.PersistentFlags().StringSliceP("terragrunt-include-dir", "", []string{}, "Include directories")
-	rootCmd.PersistentFlags().StringSliceP("terragrunt-exclude-dir", "", []string{}, "Exclude directories")
-	rootCmd.PersistentFlags().StringP("terragrunt-download-dir", "", "", "Directory for downloading remote configurations")
-	rootCmd.PersistentFlags().BoolP("terragrunt-source-update", "", false, "Update module source")
-	rootCmd.PersistentFlags().BoolP("terragrunt-ignore-dependency-errors", "", false, "Ignore dependency errors")
-	rootCmd.PersistentFlags().BoolP("terragrunt-ignore-dependency-order", "", false, "Ignore dependency order")
-	rootCmd.PersistentFlags().BoolP("terragrunt-ignore-external-dependencies", "", false, "Ignore external dependencies")
-	rootCmd.PersistentFlags().BoolP("terragrunt-include-external-dependencies", "", false, "Include external dependencies")
-	rootCmd.PersistentFlags().BoolP("terragrunt-fail-on-state-bucket-creation", "", false, "Fail if state bucket needs to be created")
-	rootCmd.PersistentFlags().BoolP("terragrunt-disable-bucket-update", "", false, "Disable state bucket updates")
-	rootCmd.PersistentFlags().StringP("terragrunt-json-out", "", "", "Output JSON to specified file")
-	rootCmd.PersistentFlags().BoolP("terragrunt-check", "", false, "Check configuration without running")
-	rootCmd.PersistentFlags().BoolP("terragrunt-diff", "", false, "Show diff of changes")
-	rootCmd.PersistentFlags().BoolP("terragrunt-hclfmt-file", "", false, "Format specific HCL file")
-	rootCmd.PersistentFlags().StringP("terragrunt-source", "", "", "Override module source")
-	rootCmd.PersistentFlags().StringP("terragrunt-source-map", "", "", "Map module sources")
-	rootCmd.PersistentFlags().BoolP("terragrunt-fetch", "", false, "Fetch remote configurations")
-	rootCmd.PersistentFlags().StringSliceP("terragrunt-module-groups", "", []string{}, "Module groups to include")
-	rootCmd.PersistentFlags().BoolP("terragrunt-strict-include", "", false, "Use strict include mode")
-	rootCmd.PersistentFlags().BoolP("terragrunt-use-partial-parse-config-cache", "", true, "Use configuration cache")
+	registerGlobalFlags(rootCmd.PersistentFlags())
 
 	// Bind flags to viper
 	viper.BindPFlag("config_file", rootCmd.PersistentFlags().Lookup("terragrunt-config"))
@@ -321,11 +387,15 @@ func init() {
 	viper.BindPFlag("non_interactive", rootCmd.PersistentFlags().Lookup("terragrunt-non-interactive"))
 	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("terragrunt-log-level"))
 	viper.BindPFlag("iam_role", rootCmd.PersistentFlags().Lookup("terragrunt-iam-role"))
-	viper.BindPFlag("auto_init", rootCmd.PersistentFlags().Lookup("terragrunt-no-auto-init"))
 	viper.BindPFlag("parallelism", rootCmd.PersistentFlags().Lookup("terragrunt-parallelism"))
 	viper.BindPFlag("include_dirs", rootCmd.PersistentFlags().Lookup("terragrunt-include-dir"))
 	viper.BindPFlag("exclude_dirs", rootCmd.PersistentFlags().Lookup("terragrunt-exclude-dir"))
 	viper.BindPFlag("download_dir", rootCmd.PersistentFlags().Lookup("terragrunt-download-dir"))
+	viper.BindPFlag("no_color", rootCmd.PersistentFlags().Lookup("no-color"))
+	viper.BindPFlag("log_file", rootCmd.PersistentFlags().Lookup("terragrunt-log-file"))
+	viper.BindPFlag("log_file_max_size", rootCmd.PersistentFlags().Lookup("terragrunt-log-file-max-size"))
+	viper.BindPFlag("log_file_max_backups", rootCmd.PersistentFlags().Lookup("terragrunt-log-file-max-backups"))
+	viper.BindPFlag("log_file_format", rootCmd.PersistentFlags().Lookup("terragrunt-log-file-format"))
 
 	// Command-specific flags
 	initCmd.Flags().BoolP("upgrade", "u", false, "Upgrade modules and plugins")
@@ -339,6 +409,8 @@ func init() {
 	planCmd.Flags().StringSlice("replace", []string{}, "Resources to replace")
 	planCmd.Flags().StringSliceP("var", "", []string{}, "Set variable value")
 	planCmd.Flags().StringP("var-file", "", "", "Variable file")
+	planCmd.Flags().StringSlice("policy-dir", []string{}, "Rego policy directory to check the plan against with conftest (builtin selects the bundled examples)")
+	planCmd.Flags().String("policy-suppressions", "", "Suppression file of accepted policy denials, which are logged but don't fail the plan")
 
 	applyCmd.Flags().BoolP("auto-approve", "a", false, "Skip interactive approval")
 	applyCmd.Flags().StringP("backup", "", "", "Path to backup state file")
@@ -364,16 +436,33 @@ func init() {
 	scaffoldCmd.Flags().StringP("path", "", "", "Path to create module")
 	scaffoldCmd.Flags().Bool("with-examples", false, "Include example configurations")
 	scaffoldCmd.Flags().Bool("with-tests", false, "Include test configurations")
+	scaffoldCmd.Flags().String("from", "", "Catalog to scaffold from (git URL or gs://bucket/path)")
+	scaffoldCmd.Flags().String("project", "", "GCP project substituted into the .tmpl files of catalog templates")
+	scaffoldCmd.Flags().Bool("list", false, "List the templates available in the --from catalog")
+
+	awsProviderPatchCmd.Flags().StringSlice("terragrunt-override-attr", []string{}, "Provider attribute to set as KEY=VALUE (nested blocks as block.attr)")
+	awsProviderPatchCmd.Flags().String("provider", "", "Provider to patch (defaults to provider_patch.provider or aws)")
 
 	hclfmtCmd.Flags().Bool("check", false, "Check if files are formatted")
 	hclfmtCmd.Flags().Bool("diff", false, "Show formatting diff")
 	hclfmtCmd.Flags().Bool("write", true, "Write formatted files")
+	hclfmtCmd.Flags().StringP("format", "f", "text", "Output format (text, json); json prints a per-file report of the lines that change")
+
+	docsCmd.Flags().String("path", "", "Module directory (defaults to the working directory)")
+	docsCmd.Flags().Bool("check", false, "Fail if README.md is out of date instead of writing it")
 
 	graphDependenciesCmd.Flags().StringP("output", "o", "", "Output file path")
 	graphDependenciesCmd.Flags().StringP("format", "f", "dot", "Output format (dot, json, mermaid)")
 
+	cleanCmd.Flags().Bool("dry-run", false, "List what would be removed without removing it")
+	cleanCmd.Flags().Bool("lock-file", false, "Also remove .terraform.lock.hcl files")
+
+	infoCmd.Flags().StringP("format", "f", "text", "Output format (text, json)")
+
+	generateCmd.Flags().Bool("check", false, "Fail if generated files on disk differ from the config instead of writing them")
+
 	// Add run-all subcommands
-	runAllCmd.AddCommand(planAllCmd, applyAllCmd, destroyAllCmd)
+	runAllCmd.AddCommand(planAllCmd, applyAllCmd, destroyAllCmd, outputAllCmd)
 
 	// Build command tree
 	rootCmd.AddCommand(
@@ -389,8 +478,71 @@ func init() {
 		renderJsonCmd,
 		awsProviderPatchCmd,
 		scaffoldCmd,
+		docsCmd,
+		cleanCmd,
+		infoCmd,
+		generateCmd,
 		versionCmd,
 	)
+
+	// --terragrunt-check validates config in place of any command
+	addConfigCheck(rootCmd)
+}
+
+// registerGlobalFlags declares the flags shared by every terragrunt command
+func registerGlobalFlags(flags *pflag.FlagSet) {
+	flags.StringP("terragrunt-config", "c", "", "Path to the Terragrunt config file (- reads from stdin)")
+	flags.StringP("terragrunt-working-dir", "w", "", "Working directory for Terragrunt")
+	flags.BoolP("terragrunt-non-interactive", "n", false, "Run in non-interactive mode")
+	flags.BoolP("terragrunt-debug", "d", false, "Enable debug logging and write the inputs passed to terraform to "+debugTfvarsFile)
+	flags.StringP("terragrunt-log-level", "l", "info", "Set log level")
+	flags.String("terragrunt-log-file", "", "Also write logs to this file, rotating it by size")
+	flags.Int("terragrunt-log-file-max-size", 100, "Size in megabytes at which the log file is rotated")
+	flags.Int("terragrunt-log-file-max-backups", 5, "Number of rotated log files to keep")
+	flags.String("terragrunt-log-file-format", "text", "Format of the log file (text, json)")
+	flags.StringP("terragrunt-iam-role", "", "", "IAM role to assume")
+	flags.BoolP("terragrunt-no-auto-init", "", false, "Disable automatic terraform init")
+	flags.BoolP("terragrunt-no-auto-retry", "", false, "Disable automatic retry on errors")
+	flags.IntP("terragrunt-parallelism", "p", 10, "Limit number of parallel executions")
+	flags.StringSliceP("terragrunt-include-dir", "", []string{}, "Include directories")
+	flags.StringSliceP("terragrunt-exclude-dir", "", []string{}, "Exclude directories")
+	flags.StringP("terragrunt-download-dir", "", "", "Directory for downloading remote configurations")
+	flags.BoolP("terragrunt-source-update", "", false, "Discard the module's .terragrunt-cache copy and fetch its source again")
+	flags.BoolP("terragrunt-ignore-dependency-errors", "", false, "Ignore dependency errors")
+	flags.BoolP("terragrunt-ignore-dependency-order", "", false, "Ignore dependency order")
+	flags.BoolP("terragrunt-ignore-external-dependencies", "", false, "Skip dependencies outside the working directory entirely")
+	flags.BoolP("terragrunt-include-external-dependencies", "", false, "Run dependencies outside the working directory as part of run-all")
+	flags.BoolP("terragrunt-fail-on-state-bucket-creation", "", false, "Fail if state bucket needs to be created")
+	flags.BoolP("terragrunt-disable-bucket-update", "", false, "Disable state bucket updates")
+	flags.StringP("terragrunt-json-out", "", "", "Output JSON to specified file")
+	flags.BoolP("terragrunt-check", "", false, "Validate the configuration without running terraform")
+	flags.BoolP("terragrunt-diff", "", false, "Show a diff of generated files instead of writing them (init) and of inputs since the last diff (plan)")
+	flags.Bool("terragrunt-detect-drift", false, "Run a refresh-only plan before apply and require acknowledgement of drift")
+	flags.Bool("terragrunt-accept-drift", false, "Apply even when --terragrunt-detect-drift finds drift")
+	flags.Bool("terragrunt-only-with-state", false, "Only run-all modules whose state exists and has resources")
+	flags.Bool("terragrunt-only-without-state", false, "Only run-all modules that have no state or an empty one")
+	flags.Bool("terragrunt-json-log", false, "Run terraform with -json where supported and log its messages and apply progress as structured entries")
+	flags.BoolP("terragrunt-hclfmt-file", "", false, "Format specific HCL file")
+	flags.StringP("terragrunt-source", "", "", "Run this local path in place of the terraform source (keeping each module's //subdir)")
+	flags.StringSlice("terragrunt-queue-include-dir", []string{}, "Run only modules under these directories or globs, one group after another in the order given")
+	flags.Bool("terragrunt-apply-dependencies", false, "Before apply, apply the module's dependencies that have no state yet, in dependency order")
+	flags.Bool("terragrunt-fail-fast", false, "Stop run-all at the first failed module, interrupting running modules and skipping queued ones")
+	flags.Bool("terragrunt-continue-on-error", false, "Run every module in run-all even when some fail, later queue groups included, and report the failures at the end")
+	flags.Int("terragrunt-max-dependency-depth", 0, "Fail when a chain of dependencies is deeper than this (0 = no limit)")
+	flags.Int("terragrunt-dependency-fan-warning", defaultDependencyFanWarning, "Warn about modules with more dependencies or dependents than this (0 = off)")
+	flags.Int("terragrunt-retry-budget", 0, "Retries allowed across all modules of a run (0 = no limit)")
+	flags.Float64("terragrunt-max-exec-rate", 0, "Terraform executions per second allowed across all modules, retries included (0 = no limit)")
+	flags.Bool("terragrunt-isolate-working-dir", false, "Copy modules without a terraform source into .terragrunt-cache too, so terraform never writes to the source tree")
+	flags.StringP("terragrunt-source-map", "", "", "Map module sources")
+	flags.BoolP("terragrunt-fetch", "", false, "Fetch remote configurations")
+	flags.StringSliceP("terragrunt-module-groups", "", []string{}, "Module groups to include")
+	flags.BoolP("terragrunt-strict-include", "", false, "Use strict include mode")
+	flags.BoolP("terragrunt-use-partial-parse-config-cache", "", true, "Cache partially parsed module configs, keyed by file mtime and content hash")
+	flags.Bool("terragrunt-fetch-dependency-output-from-state", false, "Read dependency outputs directly from their GCS state instead of running terraform output")
+	flags.Bool("terragrunt-read-output-from-backend", false, "Read dependency outputs from their GCS state, prefetching them all at the start of a run-all")
+	flags.Bool("terragrunt-clean", false, "Remove all generated files once the command finishes")
+	flags.Bool("no-color", false, "Disable colored output (also honors NO_COLOR)")
+	flags.String("config-format", "auto", "Format of the config file (auto, hcl, json); use with --terragrunt-config - to read stdin")
 }
 
 func initConfig() {
@@ -417,6 +569,9 @@ func initConfig() {
 		logger.Infof("Using config file: %s", viper.ConfigFileUsed())
 	}
 
+	// Scrub secrets before any hook writes the entry out
+	logger.AddHook(&redactHook{registry: secrets})
+
 	// Setup logging
 	logLevel := viper.GetString("log_level")
 	level, err := logrus.ParseLevel(logLevel)
@@ -425,14 +580,30 @@ func initConfig() {
 	}
 	logger.SetLevel(level)
 
-	// Set formatter
+	// Set formatter, only forcing colors when the log sink is a terminal
+	colorEnabled := termcolor.Enabled(viper.GetBool("no_color"), os.Stderr)
 	logger.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
-		DisableColors:   false,
-		ForceColors:     true,
+		DisableColors:   !colorEnabled,
+		ForceColors:     colorEnabled,
 	})
 
+	// Tee logs to a rotated file
+	if path := viper.GetString("log_file"); path != "" {
+		sink, err := addLogFile(logger, logFileOptions{
+			Path:       path,
+			MaxSizeMB:  viper.GetInt("log_file_max_size"),
+			MaxBackups: viper.GetInt("log_file_max_backups"),
+			Format:     viper.GetString("log_file_format"),
+		})
+		if err != nil {
+			logger.Warnf("Not writing log file: %v", err)
+		} else {
+			logFileSink = sink
+		}
+	}
+
 	// Add debug handler
 	if viper.GetBool("debug") || level == logrus.DebugLevel {
 		logger.SetReportCaller(true)
@@ -440,33 +611,10 @@ func initConfig() {
 }
 
 func createExecutionContext(cmd *cobra.Command) (*ExecutionContext, error) {
-	config := &TerragruntConfig{
-		TerraformPath:  "terraform",
-		WorkingDir:     ".",
-		AutoInit:       !viper.GetBool("auto_init"),
-		NonInteractive: viper.GetBool("non_interactive"),
-		Parallelism:    viper.GetInt("parallelism"),
-		RetryAttempts:  3,
-		RetryDelay:     2 * time.Second,
-		LogLevel:       viper.GetString("log_level"),
-		DownloadDir:    viper.GetString("download_dir"),
-		IamRole:        viper.GetString("iam_role"),
-		IncludeDirs:    viper.GetStringSlice("include_dirs"),
-		ExcludeDirs:    viper.GetStringSlice("exclude_dirs"),
-		Variables:      make(map[string]interface{}),
-		Environment:    make(map[string]string),
-	}
-
-	// Load configuration from file if exists
-	if viper.ConfigFileUsed() != "" {
-		if err := loadConfigFile(viper.ConfigFileUsed(), config); err != nil {
-			return nil, fmt.Errorf("failed to load config file: %w", err)
-		}
-	}
-
-	// Override with command-line flags
-	if workingDir, _ := cmd.Flags().GetString("terragrunt-working-dir"); workingDir != "" {
-		config.WorkingDir = workingDir
+	// Resolve defaults < config file < env < flags
+	config, err := resolveConfig(cmd.Flags(), viper.ConfigFileUsed(), os.LookupEnv)
+	if err != nil {
+		return nil, err
 	}
 
 	// Resolve working directory
@@ -486,8 +634,9 @@ func createExecutionContext(cmd *cobra.Command) (*ExecutionContext, error) {
 		Dependencies: make(map[string]interface{}),
 		Outputs:      make(map[string]interface{}),
 		State:        make(map[string]interface{}),
-		errors:       []error{},
+		shared:       &runState{},
 	}
+	registerConfigSecrets(cmd, config, ctx.Environment)
 
 	// Check for dry-run mode
 	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
@@ -507,11 +656,14 @@ func runInit(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
 
 	logger.Info("Initializing Terraform configuration")
 
 	// Run before hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, "init"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "init"); err != nil {
 		logger.Warnf("Before hook failed: %v", err)
 	}
 
@@ -525,6 +677,16 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to download dependencies: %w", err)
 	}
 
+	// With --terragrunt-diff only show what generation would change
+	if ctx.Config.Diff {
+		diff, err := generatedFilesDiff(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to diff generated files: %w", err)
+		}
+		printDiff("generated files", diff)
+		return nil
+	}
+
 	// Generate files if needed
 	if err := generateFiles(ctx); err != nil {
 		return fmt.Errorf("failed to generate files: %w", err)
@@ -545,20 +707,33 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Add backend config
-	if ctx.Config.Backend.Type != "" {
-		tfArgs = append(tfArgs, fmt.Sprintf("-backend-config=bucket=%s", ctx.Config.Backend.Bucket))
-		tfArgs = append(tfArgs, fmt.Sprintf("-backend-config=prefix=%s", ctx.Config.Backend.Prefix))
+	if ctx.Config.Backend.Type == "gcs" {
+		backendArgs, err := gcsBackendConfigArgs(ctx.Config)
+		if err != nil {
+			return err
+		}
+		tfArgs = append(tfArgs, backendArgs...)
 	}
 
 	// Execute terraform init
 	if err := executeTerraform(ctx, tfArgs...); err != nil {
 		// Run error hooks
-		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, "init")
+		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, ctx.Config.Hooks.Error, "init")
 		return fmt.Errorf("terraform init failed: %w", err)
 	}
 
+	// Keep the module's lock file current when init ran in the cache
+	if err := syncLockFile(ctx); err != nil {
+		logger.Warnf("Failed to copy the lock file back to %s: %v", ctx.WorkingDir, err)
+	}
+
+	// Check the providers terraform locked against the expected hashes
+	if err := verifyProviderHashes(ctx); err != nil {
+		return err
+	}
+
 	// Run after hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, "init"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, ctx.Config.Hooks.After, "init"); err != nil {
 		logger.Warnf("After hook failed: %v", err)
 	}
 
@@ -571,6 +746,9 @@ func runPlan(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
 
 	logger.Info("Generating Terraform plan")
 
@@ -582,7 +760,7 @@ func runPlan(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run before hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, "plan"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "plan"); err != nil {
 		logger.Warnf("Before hook failed: %v", err)
 	}
 
@@ -595,7 +773,18 @@ func runPlan(cmd *cobra.Command, args []string) error {
 	tfArgs := []string{"plan"}
 
 	// Add plan-specific flags
-	if out, _ := cmd.Flags().GetString("out"); out != "" {
+	out, _ := cmd.Flags().GetString("out")
+	policyDirs, _ := cmd.Flags().GetStringSlice("policy-dir")
+	if out == "" && len(policyDirs) > 0 {
+		// The policy gate needs a saved plan to render as JSON
+		planDir, removePlanDir, err := makeTempDir("terragrunt-plan-")
+		if err != nil {
+			return fmt.Errorf("failed to create plan directory: %w", err)
+		}
+		defer removePlanDir()
+		out = filepath.Join(planDir, "tfplan")
+	}
+	if out != "" {
 		tfArgs = append(tfArgs, fmt.Sprintf("-out=%s", out))
 	}
 	if destroy, _ := cmd.Flags().GetBool("destroy"); destroy {
@@ -619,6 +808,15 @@ func runPlan(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Pass the terragrunt variables ahead of explicit ones, which take
+	// precedence over them
+	inputArgs, removeTfvars, err := writeInputsTfvars(ctx)
+	if err != nil {
+		return err
+	}
+	defer removeTfvars()
+	tfArgs = append(tfArgs, inputArgs...)
+
 	// Add variables
 	if vars, _ := cmd.Flags().GetStringSlice("var"); len(vars) > 0 {
 		for _, v := range vars {
@@ -631,20 +829,42 @@ func runPlan(cmd *cobra.Command, args []string) error {
 		tfArgs = append(tfArgs, fmt.Sprintf("-var-file=%s", varFile))
 	}
 
-	// Add terragrunt variables
-	for key, value := range ctx.Config.Variables {
-		tfArgs = append(tfArgs, fmt.Sprintf("-var=%s=%v", key, value))
+	// Show how the inputs changed since the last diff run
+	if ctx.Config.Diff {
+		diff, err := inputsDiff(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to diff inputs: %w", err)
+		}
+		printDiff("inputs", diff)
 	}
 
 	// Execute terraform plan
 	if err := executeTerraform(ctx, tfArgs...); err != nil {
 		// Run error hooks
-		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, "plan")
+		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, ctx.Config.Hooks.Error, "plan")
 		return fmt.Errorf("terraform plan failed: %w", err)
 	}
 
+	if ctx.Config.Diff && !ctx.DryRun {
+		if err := saveInputsSnapshot(ctx); err != nil {
+			logger.Warnf("Failed to save inputs for the next diff: %v", err)
+		}
+	}
+
+	// Gate the plan on policies
+	if len(policyDirs) > 0 && !ctx.DryRun {
+		suppressions, err := loadPolicySuppressions(cmd)
+		if err == nil {
+			err = checkPlanPolicies(ctx, out, policyDirs, suppressions)
+		}
+		if err != nil {
+			runHooks(ctx, ctx.Config.Hooks.ErrorHooks, ctx.Config.Hooks.Error, "plan")
+			return err
+		}
+	}
+
 	// Run after hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, "plan"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, ctx.Config.Hooks.After, "plan"); err != nil {
 		logger.Warnf("After hook failed: %v", err)
 	}
 
@@ -657,6 +877,17 @@ func runApply(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
+
+	// Bring up dependencies that were never applied, so their outputs exist
+	if ctx.Config.ApplyDependencies && len(args) == 0 {
+		autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+		if err := applyDependencies(ctx, autoApprove); err != nil {
+			return err
+		}
+	}
 
 	logger.Info("Applying Terraform configuration")
 
@@ -668,7 +899,7 @@ func runApply(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run before hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, "apply"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "apply"); err != nil {
 		logger.Warnf("Before hook failed: %v", err)
 	}
 
@@ -714,6 +945,17 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// A plan file carries its variables; otherwise pass the terragrunt
+	// variables ahead of explicit ones, which take precedence over them
+	if len(args) == 0 {
+		inputArgs, removeTfvars, err := writeInputsTfvars(ctx)
+		if err != nil {
+			return err
+		}
+		defer removeTfvars()
+		tfArgs = append(tfArgs, inputArgs...)
+	}
+
 	// Add variables
 	if vars, _ := cmd.Flags().GetStringSlice("var"); len(vars) > 0 {
 		for _, v := range vars {
@@ -726,20 +968,23 @@ func runApply(cmd *cobra.Command, args []string) error {
 		tfArgs = append(tfArgs, fmt.Sprintf("-var-file=%s", varFile))
 	}
 
-	// Add terragrunt variables
-	for key, value := range ctx.Config.Variables {
-		tfArgs = append(tfArgs, fmt.Sprintf("-var=%s=%v", key, value))
-	}
-
 	// Check if we have a plan file
 	if len(args) > 0 {
 		tfArgs = append(tfArgs, args[0])
 	}
 
+	// Look for out-of-band changes before applying over them; a saved plan
+	// already fails if the state moved on
+	if ctx.Config.DetectDrift && len(args) == 0 && !ctx.DryRun {
+		if err := checkDrift(ctx, terraformVarArgs(tfArgs)); err != nil {
+			return err
+		}
+	}
+
 	// Execute terraform apply
 	if err := executeTerraform(ctx, tfArgs...); err != nil {
 		// Run error hooks
-		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, "apply")
+		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, ctx.Config.Hooks.Error, "apply")
 		return fmt.Errorf("terraform apply failed: %w", err)
 	}
 
@@ -749,7 +994,7 @@ func runApply(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run after hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, "apply"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, ctx.Config.Hooks.After, "apply"); err != nil {
 		logger.Warnf("After hook failed: %v", err)
 	}
 
@@ -762,6 +1007,9 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
 
 	logger.Info("Destroying Terraform-managed infrastructure")
 
@@ -773,7 +1021,7 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run before hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, "destroy"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.BeforeHooks, ctx.Config.Hooks.Before, "destroy"); err != nil {
 		logger.Warnf("Before hook failed: %v", err)
 	}
 
@@ -797,6 +1045,15 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Pass the terragrunt variables ahead of explicit ones, which take
+	// precedence over them
+	inputArgs, removeTfvars, err := writeInputsTfvars(ctx)
+	if err != nil {
+		return err
+	}
+	defer removeTfvars()
+	tfArgs = append(tfArgs, inputArgs...)
+
 	// Add variables
 	if vars, _ := cmd.Flags().GetStringSlice("var"); len(vars) > 0 {
 		for _, v := range vars {
@@ -809,15 +1066,10 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 		tfArgs = append(tfArgs, fmt.Sprintf("-var-file=%s", varFile))
 	}
 
-	// Add terragrunt variables
-	for key, value := range ctx.Config.Variables {
-		tfArgs = append(tfArgs, fmt.Sprintf("-var=%s=%v", key, value))
-	}
-
 	// Execute terraform destroy
 	if err := executeTerraform(ctx, tfArgs...); err != nil {
 		// Run error hooks
-		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, "destroy")
+		runHooks(ctx, ctx.Config.Hooks.ErrorHooks, ctx.Config.Hooks.Error, "destroy")
 		return fmt.Errorf("terraform destroy failed: %w", err)
 	}
 
@@ -826,8 +1078,13 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 		logger.Warnf("Failed to cleanup outputs: %v", err)
 	}
 
+	// Generated files describe infrastructure that no longer exists
+	if err := cleanGeneratedFiles(ctx.terraformDir()); err != nil {
+		logger.Warnf("Failed to remove generated files: %v", err)
+	}
+
 	// Run after hooks
-	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, "destroy"); err != nil {
+	if err := runHooks(ctx, ctx.Config.Hooks.AfterHooks, ctx.Config.Hooks.After, "destroy"); err != nil {
 		logger.Warnf("After hook failed: %v", err)
 	}
 
@@ -840,6 +1097,9 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
 
 	logger.Info("Validating Terraform configuration")
 
@@ -855,6 +1115,13 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("terraform validate failed: %w", err)
 	}
 
+	// Check required labels
+	if ctx.Config.LabelPolicy.Enabled {
+		if err := validateLabels(ctx); err != nil {
+			return err
+		}
+	}
+
 	logger.Info("Terraform configuration is valid")
 	return nil
 }
@@ -864,6 +1131,9 @@ func runOutput(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
 
 	// Build terraform output command
 	tfArgs := []string{"output"}
@@ -913,6 +1183,9 @@ func runAllCommand(cmd *cobra.Command, args []string, command string) error {
 	if err != nil {
 		return err
 	}
+	if ctx.Config.FailFast && ctx.Config.ContinueOnError {
+		return fmt.Errorf("--terragrunt-fail-fast and --terragrunt-continue-on-error are mutually exclusive")
+	}
 
 	logger.Infof("Running %s on all modules", command)
 
@@ -922,6 +1195,25 @@ func runAllCommand(cmd *cobra.Command, args []string, command string) error {
 		return fmt.Errorf("failed to find modules: %w", err)
 	}
 
+	// Narrow to modules with or without state when asked
+	modules, err = filterModulesByState(ctx, modules)
+	if err != nil {
+		return err
+	}
+
+	// Narrow to the queued directories when a queue is given
+	var queue [][]string
+	if len(ctx.Config.QueueIncludeDirs) > 0 {
+		queue, err = queueGroups(ctx, modules)
+		if err != nil {
+			return err
+		}
+		modules = nil
+		for _, group := range queue {
+			modules = append(modules, group...)
+		}
+	}
+
 	logger.Infof("Found %d modules", len(modules))
 
 	// Build dependency graph
@@ -930,67 +1222,186 @@ func runAllCommand(cmd *cobra.Command, args []string, command string) error {
 		return fmt.Errorf("failed to build dependency graph: %w", err)
 	}
 
+	// Pull in or read from modules outside the working directory
+	graph, err = resolveExternalDependencies(ctx, graph)
+	if err != nil {
+		return err
+	}
+
 	// Get execution order
 	executionOrder, err := topologicalSort(graph)
 	if err != nil {
 		return fmt.Errorf("failed to determine execution order: %w", err)
 	}
+	if err := checkGraphLimits(ctx, graph); err != nil {
+		return err
+	}
+	if ctx.Config.ReadOutputFromBackend {
+		prefetchDependencyOutputs(ctx, graph)
+	}
+
+	// Queued groups run one after another, each in dependency order
+	groups := [][]string{executionOrder}
+	if queue != nil {
+		groups = queuedExecutionOrder(graph, executionOrder, queue)
+	}
+	waitFor := graph
+	if command == "destroy" {
+		groups, waitFor = destroyOrder(groups, graph)
+	}
+	for i, group := range groups {
+		runModules(ctx, group, command, waitFor)
+		if ctx.runContext().Err() != nil {
+			for _, later := range groups[i+1:] {
+				for _, mod := range later {
+					ctx.recordSkipped(mod)
+				}
+			}
+			break
+		}
+		if failed := len(ctx.Errors()); failed > 0 && i < len(groups)-1 && !ctx.Config.ContinueOnError {
+			logger.Warnf("Not running the %d remaining queue group(s) after %d module(s) failed", len(groups)-1-i, failed)
+			break
+		}
+	}
+
+	// Collect errors
+	errors := ctx.Errors()
+	skipped := ctx.Skipped()
+
+	if len(skipped) > 0 {
+		logger.Warnf("Run stopped early; %d module(s) did not finish: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	if len(errors) > 0 || len(skipped) > 0 {
+		for _, err := range errors {
+			logger.Error(err)
+		}
+		if len(skipped) > 0 {
+			return fmt.Errorf("%d modules failed, %d skipped", len(errors), len(skipped))
+		}
+		return fmt.Errorf("%d modules failed", len(errors))
+	}
+
+	logger.Infof("Successfully ran %s on all modules", command)
+	return nil
+}
 
-	// Execute command on each module
+// runModules runs command on modules, started in order with up to
+// parallelism at a time, and waits for all of them; failures are recorded
+// in the run state of ctx. A module runs only once the modules it depends
+// on in graph that are among modules have succeeded, so modules must come
+// in dependency order; one whose dependency failed fails without running.
+// With fail-fast the first failure cancels the run, and the modules it
+// stops are recorded as skipped, as are the modules not yet started when
+// the run is interrupted
+func runModules(ctx *ExecutionContext, modules []string, command string, graph map[string][]string) {
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, ctx.Config.Parallelism)
-	errorChan := make(chan error, len(executionOrder))
 
-	for _, module := range executionOrder {
+	runs := make(map[string]*moduleRun, len(modules))
+	for _, module := range modules {
+		runs[module] = &moduleRun{finished: make(chan struct{})}
+	}
+
+	if ctx.Config.FailFast {
+		ctx.shared.mu.Lock()
+		if ctx.shared.stop == nil {
+			ctx.shared.stop, ctx.shared.stopRun = context.WithCancel(runCtx)
+		}
+		ctx.shared.mu.Unlock()
+	}
+	done := ctx.runContext().Done()
+
+	for i, module := range modules {
+		// Take the slot before starting so modules start in order
+		select {
+		case semaphore <- struct{}{}:
+		case <-done:
+		}
+		// Whether interrupted or stopped by fail-fast, start nothing more
+		if ctx.runContext().Err() != nil {
+			for _, mod := range modules[i:] {
+				ctx.recordSkipped(mod)
+			}
+			break
+		}
 		wg.Add(1)
 		go func(mod string) {
 			defer wg.Done()
-			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
+			defer close(runs[mod].finished)
+
+			for _, dep := range graph[mod] {
+				depRun, ok := runs[dep]
+				if !ok {
+					continue
+				}
+				select {
+				case <-depRun.finished:
+				case <-done:
+					ctx.recordSkipped(mod)
+					return
+				}
+				if !depRun.succeeded {
+					ctx.recordModuleFailure(mod, fmt.Errorf("not run because dependency %s failed", dep))
+					return
+				}
+			}
 
 			logger.Infof("Running %s on module: %s", command, mod)
 
-			// Change to module directory
-			moduleCtx := *ctx
-			moduleCtx.WorkingDir = mod
+			// Run in the module directory with its own maps and environment
+			moduleCtx, err := newModuleContext(ctx, mod)
+			if err != nil {
+				ctx.recordModuleFailure(mod, err)
+				return
+			}
+			if err := prepareTerraformDir(moduleCtx); err != nil {
+				ctx.recordModuleFailure(mod, err)
+				return
+			}
+			// Pass the module its inputs, read from its own config with
+			// the outputs of its dependencies
+			if err := loadModuleDependencyOutputs(moduleCtx); err != nil {
+				ctx.recordModuleFailure(mod, fmt.Errorf("failed to load dependency outputs: %w", err))
+				return
+			}
+			inputArgs, removeTfvars, err := writeInputsTfvars(moduleCtx)
+			if err != nil {
+				ctx.recordModuleFailure(mod, err)
+				return
+			}
+			defer removeTfvars()
 
 			// Execute command
-			var err error
 			switch command {
 			case "plan":
-				err = executeTerraform(&moduleCtx, "plan")
+				err = executeTerraform(moduleCtx, append([]string{"plan"}, inputArgs...)...)
 			case "apply":
-				err = executeTerraform(&moduleCtx, "apply", "-auto-approve")
+				err = executeTerraform(moduleCtx, append([]string{"apply", "-auto-approve"}, inputArgs...)...)
 			case "destroy":
-				err = executeTerraform(&moduleCtx, "destroy", "-auto-approve")
+				err = executeTerraform(moduleCtx, append([]string{"destroy", "-auto-approve"}, inputArgs...)...)
 			default:
 				err = fmt.Errorf("unsupported command: %s", command)
 			}
 
 			if err != nil {
-				errorChan <- fmt.Errorf("module %s: %w", mod, err)
+				moduleCtx.recordModuleFailure(mod, err)
+				return
 			}
+			runs[mod].succeeded = true
 		}(module)
 	}
 
 	wg.Wait()
-	close(errorChan)
-
-	// Collect errors
-	var errors []error
-	for err := range errorChan {
-		errors = append(errors, err)
-	}
-
-	if len(errors) > 0 {
-		for _, err := range errors {
-			logger.Error(err)
-		}
-		return fmt.Errorf("%d modules failed", len(errors))
-	}
+}
 
-	logger.Infof("Successfully ran %s on all modules", command)
-	return nil
+// moduleRun tracks one module of runModules. finished is closed once the
+// module is done, and succeeded is set before that, so it can be read once
+// finished is closed
+type moduleRun struct {
+	finished  chan struct{}
+	succeeded bool
 }
 
 func runHCLFormat(cmd *cobra.Command, args []string) error {
@@ -1004,6 +1415,10 @@ func runHCLFormat(cmd *cobra.Command, args []string) error {
 	check, _ := cmd.Flags().GetBool("check")
 	diff, _ := cmd.Flags().GetBool("diff")
 	write, _ := cmd.Flags().GetBool("write")
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format: %s", format)
+	}
 
 	// Find all HCL files
 	files, err := findHCLFiles(ctx.WorkingDir)
@@ -1014,29 +1429,40 @@ func runHCLFormat(cmd *cobra.Command, args []string) error {
 	logger.Infof("Found %d HCL files", len(files))
 
 	// Format each file
-	needsFormatting := false
+	report := hclfmtReport{Files: []hclfmtFileReport{}}
 	for _, file := range files {
-		formatted, changed, err := formatHCLFile(file, check, diff, write)
+		result, err := formatHCLFile(file, check, write)
 		if err != nil {
 			logger.Errorf("Failed to format %s: %v", file, err)
+			report.add(ctx.WorkingDir, file, nil, err)
 			continue
 		}
+		report.add(ctx.WorkingDir, file, result, nil)
 
-		if changed {
-			needsFormatting = true
+		if result.changed() {
 			if check {
 				logger.Warnf("File needs formatting: %s", file)
 			} else if write {
 				logger.Infof("Formatted: %s", file)
 			}
 
-			if diff {
-				fmt.Println(formatted)
+			if diff && format == "text" {
+				text, err := unifiedDiff(file, string(result.Original), string(result.Formatted))
+				if err != nil {
+					return err
+				}
+				fmt.Println(text)
 			}
 		}
 	}
 
-	if check && needsFormatting {
+	if format == "json" {
+		if err := writeHCLFmtReport(hclfmtOutput, report); err != nil {
+			return err
+		}
+	}
+
+	if check && report.NeedsFormat {
 		return fmt.Errorf("HCL files need formatting")
 	}
 
@@ -1064,6 +1490,13 @@ func runGraphDependencies(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to build dependency graph: %w", err)
 	}
 
+	// A cycle or an over-deep graph is reported after the graph is written,
+	// which helps to find it
+	_, graphErr := topologicalSort(graph)
+	if graphErr == nil {
+		graphErr = checkGraphLimits(ctx, graph)
+	}
+
 	// Get output format
 	format, _ := cmd.Flags().GetString("format")
 	output, _ := cmd.Flags().GetString("output")
@@ -1074,7 +1507,7 @@ func runGraphDependencies(cmd *cobra.Command, args []string) error {
 	case "dot":
 		result = generateDotGraph(graph)
 	case "json":
-		data, err := json.MarshalIndent(graph, "", "  ")
+		data, err := marshalGraphJSON(ctx.WorkingDir, graph)
 		if err != nil {
 			return fmt.Errorf("failed to marshal graph: %w", err)
 		}
@@ -1095,7 +1528,7 @@ func runGraphDependencies(cmd *cobra.Command, args []string) error {
 		fmt.Println(result)
 	}
 
-	return nil
+	return graphErr
 }
 
 func runRenderJSON(cmd *cobra.Command, args []string) error {
@@ -1112,13 +1545,7 @@ func runRenderJSON(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to marshal configuration: %w", err)
 	}
 
-	fmt.Println(string(data))
-	return nil
-}
-
-func runAWSProviderPatch(cmd *cobra.Command, args []string) error {
-	// This would patch AWS provider configuration
-	logger.Info("AWS provider patch not implemented in GCP-focused version")
+	fmt.Println(redactSecrets(string(data)))
 	return nil
 }
 
@@ -1133,6 +1560,13 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 	path, _ := cmd.Flags().GetString("path")
 	withExamples, _ := cmd.Flags().GetBool("with-examples")
 	withTests, _ := cmd.Flags().GetBool("with-tests")
+	from, _ := cmd.Flags().GetString("from")
+	project, _ := cmd.Flags().GetString("project")
+	list, _ := cmd.Flags().GetBool("list")
+
+	if from != "" && list {
+		return scaffoldFromCatalog(ctx, from, "", "", templateVars{}, true)
+	}
 
 	if name == "" {
 		return fmt.Errorf("module name is required")
@@ -1142,6 +1576,14 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 		path = filepath.Join(ctx.WorkingDir, name)
 	}
 
+	// Materialize from a remote catalog instead of the embedded templates
+	if from != "" {
+		if project == "" {
+			project = ctx.Config.GCP.Project
+		}
+		return scaffoldFromCatalog(ctx, from, template, path, templateVars{Name: name, Project: project}, false)
+	}
+
 	logger.Infof("Scaffolding new module: %s", name)
 
 	// Create module directory
@@ -1167,8 +1609,14 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to write outputs.tf: %w", err)
 	}
 
+	// Generate versions.tf
+	versionsTF := generateVersionsTF(ctx.Config.Scaffold.versionsFor(template))
+	if err := os.WriteFile(filepath.Join(path, "versions.tf"), []byte(versionsTF), 0644); err != nil {
+		return fmt.Errorf("failed to write versions.tf: %w", err)
+	}
+
 	// Generate terragrunt.hcl
-	terragruntHCL := generateTerragruntHCL(template, name)
+	terragruntHCL := generateTerragruntHCL(template, name, ctx.Config.LabelPolicy)
 	if err := os.WriteFile(filepath.Join(path, "terragrunt.hcl"), []byte(terragruntHCL), 0644); err != nil {
 		return fmt.Errorf("failed to write terragrunt.hcl: %w", err)
 	}
@@ -1178,6 +1626,9 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 	if err := os.WriteFile(filepath.Join(path, "README.md"), []byte(readme), 0644); err != nil {
 		return fmt.Errorf("failed to write README.md: %w", err)
 	}
+	if _, err := writeModuleDocs(path); err != nil {
+		return fmt.Errorf("failed to generate module docs: %w", err)
+	}
 
 	// Generate examples if requested
 	if withExamples {
@@ -1190,6 +1641,9 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 		if err := os.WriteFile(filepath.Join(examplesDir, "main.tf"), []byte(exampleTF), 0644); err != nil {
 			return fmt.Errorf("failed to write example: %w", err)
 		}
+		if err := os.WriteFile(filepath.Join(examplesDir, "versions.tf"), []byte(versionsTF), 0644); err != nil {
+			return fmt.Errorf("failed to write example versions.tf: %w", err)
+		}
 	}
 
 	// Generate tests if requested
@@ -1199,7 +1653,7 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to create tests directory: %w", err)
 		}
 
-		testGo := generateTestGo(name)
+		testGo := generateTestGo()
 		if err := os.WriteFile(filepath.Join(testsDir, "main_test.go"), []byte(testGo), 0644); err != nil {
 			return fmt.Errorf("failed to write test: %w", err)
 		}
@@ -1211,7 +1665,51 @@ func runScaffold(cmd *cobra.Command, args []string) error {
 
 // Helper functions
 
+// TerraformResult describes a finished terraform run, the last attempt when
+// it was retried
+type TerraformResult struct {
+	Args     []string
+	ExitCode int
+	Duration time.Duration
+	Attempts int
+	// Stdout and Stderr are only kept when the run was captured
+	Stdout string
+	Stderr string
+}
+
+// terraformRunOptions adjusts how runTerraform runs terraform
+type terraformRunOptions struct {
+	// Capture keeps terraform's output in the result; it is streamed to
+	// the console all the same
+	Capture bool
+	// Query runs a command that only reads, like show or output: its output
+	// is kept in the result instead of streamed, and it runs in dry runs too
+	Query bool
+}
+
+// executeTerraform runs terraform in the module, streaming its output
 func executeTerraform(ctx *ExecutionContext, args ...string) error {
+	_, err := runTerraform(ctx, terraformRunOptions{}, args...)
+	return err
+}
+
+// queryTerraform runs a read-only terraform command in the module and
+// returns its output
+func queryTerraform(ctx *ExecutionContext, args ...string) ([]byte, error) {
+	result, err := runTerraform(ctx, terraformRunOptions{Query: true}, args...)
+	if err != nil {
+		if result != nil && result.Stderr != "" {
+			return nil, fmt.Errorf("terraform %s failed: %w: %s", args[0], err, strings.TrimSpace(result.Stderr))
+		}
+		return nil, fmt.Errorf("terraform %s failed: %w", args[0], err)
+	}
+	return []byte(result.Stdout), nil
+}
+
+// runTerraform runs terraform in the module with retries and returns the
+// result of the last attempt. The result is nil only when terraform could
+// not be started at all; a failing run returns both its result and an error.
+func runTerraform(ctx *ExecutionContext, opts terraformRunOptions, args ...string) (*TerraformResult, error) {
 	// Find terraform binary
 	terraformPath := ctx.Config.TerraformPath
 	if terraformPath == "" {
@@ -1219,122 +1717,193 @@ func executeTerraform(ctx *ExecutionContext, args ...string) error {
 	}
 
 	// Check if terraform exists
-	if _, err := exec.LookPath(terraformPath); err != nil {
+	if _, err := terraformRunner.LookPath(terraformPath); err != nil {
 		// Try to download terraform if configured
 		if ctx.Config.TerraformBinary.AutoDownload {
-			if err := downloadTerraform(ctx); err != nil {
-				return fmt.Errorf("failed to download terraform: %w", err)
+			installed, err := installTerraformOnce(ctx, "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to download terraform: %w", err)
 			}
+			terraformPath = installed
+		} else {
+			return nil, fmt.Errorf("terraform not found: %w", err)
+		}
+	}
+
+	// Make sure the binary satisfies the required version constraints
+	terraformPath, err := ensureTerraformVersion(ctx, terraformPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Share downloaded providers between modules
+	env, cacheDir, err := pluginCacheEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range gcsBackendEnv(ctx.Config) {
+		env[key] = value
+	}
+	unlock := lockPluginCache(cacheDir, ctx.terraformDir(), args)
+	defer unlock()
+
+	if ctx.Config.Debug && !opts.Query {
+		if err := writeDebugTfvars(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Stream terraform's machine-readable output where it supports it
+	var stdout io.Writer = os.Stdout
+	if ctx.Config.JSONLog && !opts.Query {
+		if jsonArgs, ok := terraformJSONArgs(terraformPath, args); ok {
+			args = jsonArgs
+			stream := newTerraformJSONStream(logger.WithField("module", ctx.WorkingDir), os.Stdout)
+			defer stream.Flush()
+			stdout = stream
 		} else {
-			return fmt.Errorf("terraform not found: %w", err)
+			logger.Debugf("terraform %s does not support -json here; passing output through", args[0])
 		}
 	}
 
-	// Build command
-	cmd := exec.CommandContext(context.Background(), terraformPath, args...)
-	cmd.Dir = ctx.WorkingDir
-	cmd.Env = envToSlice(ctx.Environment)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	// Cached outputs of the module are stale once it is applied or
+	// destroyed, even partway
+	if len(args) > 0 && (args[0] == "apply" || args[0] == "destroy") && !ctx.DryRun {
+		defer dependencyStateCache.invalidate(ctx.WorkingDir)
+	}
 
 	// Execute with retry logic
+	limits := ctx.limits()
+	result := &TerraformResult{Args: args}
 	var lastErr error
 	for attempt := 0; attempt <= ctx.Config.RetryAttempts; attempt++ {
 		if attempt > 0 {
+			if !limits.takeRetry() {
+				return result, fmt.Errorf("terraform command failed with the retry budget of %d used up: %w", ctx.Config.RetryBudget, lastErr)
+			}
 			logger.Infof("Retrying terraform command (attempt %d/%d)", attempt, ctx.Config.RetryAttempts)
 			time.Sleep(ctx.Config.RetryDelay * time.Duration(attempt))
 		}
 
-		if ctx.DryRun {
+		if ctx.DryRun && !opts.Query {
 			logger.Infof("DRY RUN: would execute: %s %s", terraformPath, strings.Join(args, " "))
-			return nil
+			return result, nil
+		}
+
+		if err := limits.wait(ctx.runContext()); err != nil {
+			if lastErr != nil {
+				return result, lastErr
+			}
+			return result, err
+		}
+
+		logger.Debugf("Executing %s %s in %s", terraformPath, strings.Join(args, " "), ctx.terraformDir())
+		command := runnerCommand{
+			Path:   terraformPath,
+			Args:   args,
+			Dir:    ctx.terraformDir(),
+			Env:    envToSlice(env),
+			Stdin:  os.Stdin,
+			Stdout: stdout,
+		}
+		var captured, stderr bytes.Buffer
+		switch {
+		case opts.Query:
+			command.Stdin = nil
+			command.Stdout = &captured
+			command.Stderr = &stderr
+		case opts.Capture:
+			command.Stdout = io.MultiWriter(stdout, &captured)
+			command.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		default:
+			command.Stderr = io.MultiWriter(os.Stderr, &stderr)
 		}
 
-		err := cmd.Run()
+		start := time.Now()
+		err := terraformRunner.Run(ctx.runContext(), command)
+		result.Attempts = attempt + 1
+		result.Duration = time.Since(start)
+		result.ExitCode = exitCode(err)
+		if opts.Capture || opts.Query {
+			result.Stdout = captured.String()
+			result.Stderr = stderr.String()
+		}
 		if err == nil {
-			return nil
+			return result, nil
 		}
 
 		lastErr = err
+		if ctx.runContext().Err() != nil {
+			return result, err
+		}
 
-		// Check if error is retryable
-		if !isRetryableError(err, ctx.Config.ErrorHandling.RetryableErrors) {
-			return err
+		// Check if error is retryable, matching terraform's error output too
+		if !isRetryableError(fmt.Errorf("%w\n%s", err, stderr.String()), ctx.Config.ErrorHandling.RetryableErrors) {
+			return result, err
 		}
 	}
 
-	return fmt.Errorf("terraform command failed after %d attempts: %w", ctx.Config.RetryAttempts, lastErr)
+	return result, fmt.Errorf("terraform command failed after %d attempts: %w", ctx.Config.RetryAttempts, lastErr)
+}
+
+// exitCode returns the exit code of a finished command, or -1 when it
+// didn't run to completion
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
 
 func autoInit(ctx *ExecutionContext) error {
 	// Check if .terraform directory exists
-	terraformDir := filepath.Join(ctx.WorkingDir, ".terraform")
+	terraformDir := filepath.Join(ctx.terraformDir(), ".terraform")
 	if _, err := os.Stat(terraformDir); os.IsNotExist(err) {
 		logger.Info("Running terraform init (auto-init)")
-		return executeTerraform(ctx, "init", "-input=false")
-	}
-	return nil
-}
-
-func runHooks(ctx *ExecutionContext, hooks []HookConfig, command string) error {
-	for _, hook := range hooks {
-		// Check if hook should run for this command
-		shouldRun := false
-		for _, cmd := range hook.Commands {
-			if cmd == command || cmd == "all" {
-				shouldRun = true
-				break
-			}
-		}
-
-		if !shouldRun {
-			continue
+		if err := executeTerraform(ctx, "init", "-input=false"); err != nil {
+			return err
 		}
-
-		logger.Infof("Running hook: %s", hook.Name)
-
-		for _, execute := range hook.Execute {
-			parts := strings.Fields(execute)
-			if len(parts) == 0 {
-				continue
-			}
-
-			cmd := exec.Command(parts[0], parts[1:]...)
-			cmd.Dir = hook.WorkingDir
-			if cmd.Dir == "" {
-				cmd.Dir = ctx.WorkingDir
-			}
-			cmd.Env = envToSlice(ctx.Environment)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-
-			if err := cmd.Run(); err != nil {
-				if !hook.RunOnError {
-					return fmt.Errorf("hook %s failed: %w", hook.Name, err)
-				}
-				logger.Warnf("Hook %s failed but continuing: %v", hook.Name, err)
-			}
+		if err := syncLockFile(ctx); err != nil {
+			logger.Warnf("Failed to copy the lock file back to %s: %v", ctx.WorkingDir, err)
 		}
+		return verifyProviderHashes(ctx)
 	}
-
 	return nil
 }
 
-func loadConfigFile(path string, config *TerragruntConfig) error {
-	data, err := os.ReadFile(path)
+func loadConfigFile(path, format string, config *TerragruntConfig) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		// Read generated config piped in on stdin
+		data, err = io.ReadAll(configStdin)
+		path = "<stdin>"
+	} else {
+		data, err = os.ReadFile(path)
+	}
 	if err != nil {
 		return err
 	}
 
-	// Parse HCL or JSON based on extension
-	if strings.HasSuffix(path, ".json") {
-		return json.Unmarshal(data, config)
+	// Prefer the explicit hint, then the file extension, then content sniffing
+	if format == "" || format == "auto" {
+		switch {
+		case strings.HasSuffix(path, ".json"):
+			format = "json"
+		case strings.HasSuffix(path, ".hcl"):
+			format = "hcl"
+		}
+	}
+	format, err = detectConfigFormat(data, format)
+	if err != nil {
+		return err
 	}
 
-	// For HCL parsing, we would use hcl package
-	// This is simplified for now
-	return nil
+	return decodeConfig(data, path, format, config)
 }
 
 func initializeBackend(ctx *ExecutionContext) error {
@@ -1344,10 +1913,11 @@ func initializeBackend(ctx *ExecutionContext) error {
 
 	switch ctx.Config.Backend.Type {
 	case "gcs":
-		// TODO: Implement bucket creation check
-		// For now, assume bucket exists or will be created by Terraform
 		ctx.Logger.Infof("Using GCS backend bucket: %s", ctx.Config.Backend.Bucket)
-		return nil
+		if ctx.DryRun {
+			return nil
+		}
+		return validateGCSBackend(runCtx, ctx.Config)
 	default:
 		return fmt.Errorf("unsupported backend type: %s", ctx.Config.Backend.Type)
 	}
@@ -1367,43 +1937,13 @@ func downloadDependencies(ctx *ExecutionContext) error {
 	return nil
 }
 
-func generateFiles(ctx *ExecutionContext) error {
-	// Generate backend.tf if needed
-	if ctx.Config.RemoteState.Generate != nil {
-		backendTF := generateBackendTF(ctx.Config)
-		if err := os.WriteFile(filepath.Join(ctx.WorkingDir, "backend.tf"), []byte(backendTF), 0644); err != nil {
-			return fmt.Errorf("failed to generate backend.tf: %w", err)
-		}
-	}
-	return nil
-}
-
-func loadDependencyOutputs(ctx *ExecutionContext) error {
-	for _, dep := range ctx.Config.Dependencies {
-		if !dep.Enabled || dep.SkipOutputs {
-			continue
-		}
-
-		if dep.MockOutputs != nil {
-			// Use mock outputs
-			for key, value := range dep.MockOutputs {
-				ctx.Dependencies[fmt.Sprintf("%s.%s", dep.Name, key)] = value
-			}
-			continue
-		}
-
-		// Load real outputs from dependency
-		// This would execute terraform output -json in the dependency directory
-		// For now, this is a stub
-	}
-	return nil
-}
-
 func saveOutputs(ctx *ExecutionContext) error {
 	// Execute terraform output -json
-	cmd := exec.Command(ctx.Config.TerraformPath, "output", "-json")
-	cmd.Dir = ctx.WorkingDir
-	output, err := cmd.Output()
+	output, err := runnerOutput(context.Background(), terraformRunner, runnerCommand{
+		Path: ctx.Config.TerraformPath,
+		Args: []string{"output", "-json"},
+		Dir:  ctx.terraformDir(),
+	})
 	if err != nil {
 		return err
 	}
@@ -1437,94 +1977,127 @@ func cleanupOutputs(ctx *ExecutionContext) error {
 	return nil
 }
 
-func findModules(ctx *ExecutionContext) ([]string, error) {
-	var modules []string
+func buildDependencyGraph(ctx *ExecutionContext, modules []string) (map[string][]string, error) {
+	graph := make(map[string][]string)
 
-	err := filepath.Walk(ctx.WorkingDir, func(path string, info os.FileInfo, err error) error {
+	for _, module := range modules {
+		deps, err := moduleDependencies(ctx, module)
 		if err != nil {
-			return err
-		}
-
-		// Skip if in exclude list
-		for _, exclude := range ctx.Config.ExcludeDirs {
-			if strings.Contains(path, exclude) {
-				return filepath.SkipDir
-			}
+			return nil, err
 		}
+		graph[module] = deps
+	}
 
-		// Check if terragrunt.hcl exists
-		if info.Name() == "terragrunt.hcl" {
-			dir := filepath.Dir(path)
+	return graph, nil
+}
 
-			// Check if in include list (if specified)
-			if len(ctx.Config.IncludeDirs) > 0 {
-				included := false
-				for _, include := range ctx.Config.IncludeDirs {
-					if strings.Contains(dir, include) {
-						included = true
-						break
-					}
-				}
-				if !included {
-					return nil
-				}
-			}
+// moduleDependencies reads a module's dependency blocks and returns the
+// directories of the modules it depends on
+func moduleDependencies(ctx *ExecutionContext, module string) ([]string, error) {
+	config := defaultTerragruntConfig()
+	if err := loadModuleConfig(ctx, filepath.Join(module, "terragrunt.hcl"), config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", module, err)
+	}
 
-			modules = append(modules, dir)
+	moduleCtx := &ExecutionContext{WorkingDir: module}
+	seen := make(map[string]bool)
+	deps := []string{}
+	for _, dep := range config.Dependencies {
+		dir := dependencyDir(moduleCtx, dep)
+		if dir == module || seen[dir] {
+			continue
 		}
-
-		return nil
-	})
-
-	return modules, err
+		seen[dir] = true
+		deps = append(deps, dir)
+	}
+	return deps, nil
 }
 
-func buildDependencyGraph(ctx *ExecutionContext, modules []string) (map[string][]string, error) {
-	graph := make(map[string][]string)
-
-	for _, module := range modules {
-		// Parse terragrunt.hcl to find dependencies
-		// This is simplified - real implementation would parse HCL
-		graph[module] = []string{}
-	}
+// dependencyCycleError names a cycle of modules, each depending on the
+// next, ending where it started
+type dependencyCycleError struct {
+	Path []string
+}
 
-	return graph, nil
+func (e *dependencyCycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " → ")
 }
 
+// topologicalSort orders the modules of graph so that every module comes
+// after the modules it depends on. Dependencies outside the graph are
+// followed for ordering but not returned. A graph with a cycle has no such
+// order and fails with a *dependencyCycleError.
 func topologicalSort(graph map[string][]string) ([]string, error) {
-	// Simplified topological sort
+	const (
+		visiting = 1
+		done     = 2
+	)
+
 	var result []string
-	visited := make(map[string]bool)
+	state := make(map[string]int)
+	// stack is the path of modules being visited, for naming a cycle
+	var stack []string
 
 	var visit func(string) error
 	visit = func(node string) error {
-		if visited[node] {
+		switch state[node] {
+		case visiting:
+			for i, n := range stack {
+				if n == node {
+					path := append(append([]string(nil), stack[i:]...), node)
+					return &dependencyCycleError{Path: path}
+				}
+			}
+		case done:
 			return nil
 		}
-		visited[node] = true
+		state[node] = visiting
+		stack = append(stack, node)
 
-		for _, dep := range graph[node] {
+		for _, dep := range sortedDeps(graph[node]) {
 			if err := visit(dep); err != nil {
 				return err
 			}
 		}
 
-		result = append(result, node)
+		stack = stack[:len(stack)-1]
+		state[node] = done
+		if _, ok := graph[node]; ok {
+			result = append(result, node)
+		}
 		return nil
 	}
 
-	for node := range graph {
+	for _, node := range sortedGraphNodes(graph) {
 		if err := visit(node); err != nil {
 			return nil, err
 		}
 	}
 
-	// Reverse for correct execution order
-	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
-		result[i], result[j] = result[j], result[i]
+	return result, nil
+}
+
+// destroyOrder turns the groups and graph of an apply around for destroy:
+// the groups run last to first, each in reverse, and a module waits for the
+// modules depending on it instead of those it depends on, so nothing is
+// destroyed while something still uses it
+func destroyOrder(groups [][]string, graph map[string][]string) ([][]string, map[string][]string) {
+	reversed := make([][]string, len(groups))
+	for i, group := range groups {
+		order := make([]string, len(group))
+		for j, module := range group {
+			order[len(group)-1-j] = module
+		}
+		reversed[len(groups)-1-i] = order
 	}
 
-	return result, nil
+	dependents := make(map[string][]string, len(graph))
+	for module, deps := range graph {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], module)
+		}
+	}
+	return reversed, dependents
 }
 
 func findHCLFiles(dir string) ([]string, error) {
@@ -1535,6 +2108,11 @@ func findHCLFiles(dir string) ([]string, error) {
 			return err
 		}
 
+		// Downloaded modules aren't ours to reformat
+		if info.IsDir() && (info.Name() == ".terraform" || info.Name() == ".terragrunt-cache") {
+			return filepath.SkipDir
+		}
+
 		if strings.HasSuffix(path, ".hcl") || strings.HasSuffix(path, ".tf") {
 			files = append(files, path)
 		}
@@ -1545,21 +2123,15 @@ func findHCLFiles(dir string) ([]string, error) {
 	return files, err
 }
 
-func formatHCLFile(path string, check, diff, write bool) (string, bool, error) {
-	// This would use hcl formatter
-	// For now, return stub
-	return "", false, nil
-}
-
 func generateDotGraph(graph map[string][]string) string {
 	var result strings.Builder
 	result.WriteString("digraph dependencies {\n")
 	result.WriteString("  rankdir=TB;\n")
 	result.WriteString("  node [shape=box];\n")
 
-	for node, deps := range graph {
+	for _, node := range sortedGraphNodes(graph) {
 		nodeName := filepath.Base(node)
-		for _, dep := range deps {
+		for _, dep := range sortedDeps(graph[node]) {
 			depName := filepath.Base(dep)
 			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\";\n", nodeName, depName))
 		}
@@ -1573,9 +2145,9 @@ func generateMermaidGraph(graph map[string][]string) string {
 	var result strings.Builder
 	result.WriteString("graph TD\n")
 
-	for node, deps := range graph {
+	for _, node := range sortedGraphNodes(graph) {
 		nodeName := filepath.Base(node)
-		for _, dep := range deps {
+		for _, dep := range sortedDeps(graph[node]) {
 			depName := filepath.Base(dep)
 			result.WriteString(fmt.Sprintf("  %s --> %s\n", nodeName, depName))
 		}
@@ -1584,13 +2156,20 @@ func generateMermaidGraph(graph map[string][]string) string {
 	return result.String()
 }
 
-func downloadTerraform(ctx *ExecutionContext) error {
-	ctx.Logger.Info("Downloading Terraform binary")
+// terraformReleasesURL is where terraform releases are downloaded from;
+// tests replace it
+var terraformReleasesURL = "https://releases.hashicorp.com/terraform"
 
+// downloadTerraform installs the given terraform version, unless installed
+// already, and returns the path of the binary. An empty version uses the
+// configured version or the latest.
+func downloadTerraform(ctx *ExecutionContext, version string) (string, error) {
 	// Determine required version from config or use latest
-	version := "latest"
-	if ctx.Config != nil && ctx.Config.TerraformBinary.Version != "" {
-		version = ctx.Config.TerraformBinary.Version
+	if version == "" {
+		version = "latest"
+		if ctx.Config != nil && ctx.Config.TerraformBinary.Version != "" {
+			version = ctx.Config.TerraformBinary.Version
+		}
 	}
 
 	// Detect OS and architecture
@@ -1607,75 +2186,102 @@ func downloadTerraform(ctx *ExecutionContext) error {
 		arch = "386"
 	}
 
+	// A constraint such as ">= 1.5, < 2.0" resolves to the newest matching release
+	if version != "latest" {
+		if _, err := goversion.NewVersion(version); err != nil {
+			constraints, err := goversion.NewConstraint(version)
+			if err != nil {
+				return "", fmt.Errorf("invalid terraform version %q: %w", version, err)
+			}
+			if version, err = matchingTerraformRelease(constraints, version); err != nil {
+				return "", err
+			}
+		}
+	}
+
 	// If version is "latest", fetch it from HashiCorp releases API
 	if version == "latest" {
 		latestVersion, err := getLatestTerraformVersion()
 		if err != nil {
-			return fmt.Errorf("failed to get latest terraform version: %w", err)
+			return "", fmt.Errorf("failed to get latest terraform version: %w", err)
 		}
 		version = latestVersion
 	}
 
+	// Each version has its own install directory, so one installed before
+	// is used as is
+	installDir := filepath.Join(os.Getenv("HOME"), ".terragrunt", "terraform", version)
+	binaryName := "terraform"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	dstBinary := filepath.Join(installDir, binaryName)
+	if info, err := os.Stat(dstBinary); err == nil && info.Mode().IsRegular() {
+		ctx.Logger.Infof("Using Terraform %s installed at %s", version, dstBinary)
+		addToPath(ctx, installDir)
+		return dstBinary, nil
+	}
+
 	// Construct download URL
 	filename := fmt.Sprintf("terraform_%s_%s_%s.zip", version, goos, arch)
-	downloadURL := fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/%s", version, filename)
+	downloadURL := fmt.Sprintf("%s/%s/%s", terraformReleasesURL, version, filename)
 
 	ctx.Logger.Infof("Downloading Terraform %s for %s/%s", version, goos, arch)
 
 	// Create temporary directory for download
-	tmpDir, err := os.MkdirTemp("", "terraform-download-*")
+	tmpDir, removeTmpDir, err := makeTempDir("terraform-download-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
-	defer os.RemoveAll(tmpDir)
+	defer removeTmpDir()
 
 	// Download the zip file
 	zipPath := filepath.Join(tmpDir, filename)
 	if err := downloadFile(downloadURL, zipPath); err != nil {
-		return fmt.Errorf("failed to download terraform: %w", err)
+		return "", fmt.Errorf("failed to download terraform: %w", err)
 	}
 
 	ctx.Logger.Info("Extracting Terraform binary")
 
 	// Extract the binary
 	if err := extractZip(zipPath, tmpDir); err != nil {
-		return fmt.Errorf("failed to extract terraform: %w", err)
+		return "", fmt.Errorf("failed to extract terraform: %w", err)
 	}
 
-	// Determine installation directory
-	installDir := filepath.Join(os.Getenv("HOME"), ".terragrunt", "terraform", version)
+	// Move the binary into place under a temporary name, then rename it
+	// over the final path, so a concurrent run never sees half a binary
 	if err := os.MkdirAll(installDir, 0755); err != nil {
-		return fmt.Errorf("failed to create install directory: %w", err)
+		return "", fmt.Errorf("failed to create install directory: %w", err)
 	}
-
-	// Move binary to installation directory
-	srcBinary := filepath.Join(tmpDir, "terraform")
-	if runtime.GOOS == "windows" {
-		srcBinary += ".exe"
+	staged, err := os.CreateTemp(installDir, ".terraform-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to install terraform: %w", err)
 	}
-
-	dstBinary := filepath.Join(installDir, filepath.Base(srcBinary))
-	if err := os.Rename(srcBinary, dstBinary); err != nil {
-		// Try copying if rename fails (cross-device link)
-		if err := copyFile(srcBinary, dstBinary); err != nil {
-			return fmt.Errorf("failed to install terraform: %w", err)
-		}
+	staged.Close()
+	defer os.Remove(staged.Name())
+	if err := copyFile(filepath.Join(tmpDir, binaryName), staged.Name()); err != nil {
+		return "", fmt.Errorf("failed to install terraform: %w", err)
 	}
 
 	// Make binary executable
-	if err := os.Chmod(dstBinary, 0755); err != nil {
-		return fmt.Errorf("failed to make terraform executable: %w", err)
+	if err := os.Chmod(staged.Name(), 0755); err != nil {
+		return "", fmt.Errorf("failed to make terraform executable: %w", err)
+	}
+	if err := os.Rename(staged.Name(), dstBinary); err != nil {
+		return "", fmt.Errorf("failed to install terraform: %w", err)
 	}
 
 	ctx.Logger.Infof("Terraform %s installed successfully to %s", version, dstBinary)
+	addToPath(ctx, installDir)
+	return dstBinary, nil
+}
 
-	// Update PATH in context if needed
+// addToPath puts dir first on the PATH terraform is run with
+func addToPath(ctx *ExecutionContext, dir string) {
 	if ctx.Environment == nil {
 		ctx.Environment = make(map[string]string)
 	}
-	ctx.Environment["PATH"] = fmt.Sprintf("%s%c%s", installDir, os.PathListSeparator, os.Getenv("PATH"))
-
-	return nil
+	ctx.Environment["PATH"] = fmt.Sprintf("%s%c%s", dir, os.PathListSeparator, os.Getenv("PATH"))
 }
 
 // getLatestTerraformVersion fetches the latest Terraform version from HashiCorp's API
@@ -1702,7 +2308,11 @@ func getLatestTerraformVersion() (string, error) {
 
 // downloadFile downloads a file from URL to destination
 func downloadFile(url string, dest string) error {
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download: %w", err)
 	}
@@ -1806,20 +2416,11 @@ func copyFile(src string, dst string) error {
 }
 
 func getTerraformVersion() string {
-	cmd := exec.Command("terraform", "version", "-json")
-	output, err := cmd.Output()
+	version, err := terraformVersionOf("terraform")
 	if err != nil {
 		return ""
 	}
-
-	var version struct {
-		TerraformVersion string `json:"terraform_version"`
-	}
-	if err := json.Unmarshal(output, &version); err != nil {
-		return ""
-	}
-
-	return version.TerraformVersion
+	return version
 }
 
 func isRetryableError(err error, patterns []string) bool {
@@ -1960,7 +2561,41 @@ output "instance_network_ip" {
 `)
 }
 
-func generateTerragruntHCL(template, name string) string {
+func generateTerragruntHCL(template, name string, policy LabelPolicyConfig) string {
+	labels := [][2]string{
+		{"environment", "local.environment"},
+		{"project", "local.project_id"},
+		{"managed_by", `"terragrunt"`},
+	}
+	existing := []string{"environment", "project", "managed_by"}
+	var todo [][2]string
+	for _, line := range scaffoldLabelLines(policy, existing) {
+		commented := strings.HasPrefix(line, "# ")
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, "# "), " = ")
+		if commented {
+			todo = append(todo, [2]string{key, value})
+		} else {
+			labels = append(labels, [2]string{key, value})
+		}
+	}
+
+	width := 0
+	for _, label := range append(labels, todo...) {
+		if len(label[0]) > width {
+			width = len(label[0])
+		}
+	}
+	var labelLines strings.Builder
+	for _, label := range labels {
+		fmt.Fprintf(&labelLines, "    %-*s = %s\n", width, label[0], label[1])
+	}
+	if len(todo) > 0 {
+		labelLines.WriteString("    # TODO: set the labels the label policy requires and uncomment them\n")
+		for _, label := range todo {
+			fmt.Fprintf(&labelLines, "    # %-*s = %s\n", width, label[0], label[1])
+		}
+	}
+
 	return `include "root" {
   path = find_in_parent_folders()
 }
@@ -1979,14 +2614,13 @@ inputs = {
   ]
 
   labels = {
-    environment = local.environment
-    project     = local.project_id
-    managed_by  = "terragrunt"
-  }
+` + labelLines.String() + `  }
 }
 `
 }
 
+// generateREADME returns the hand-written part of a scaffolded README; the
+// inputs/outputs tables are filled in by writeModuleDocs
 func generateREADME(name string) string {
 	return fmt.Sprintf(`# %s Module
 
@@ -1995,27 +2629,9 @@ This module manages [describe what this module does].
 ## Usage
 
 %s%s%s
-
-## Inputs
-
-| Name | Description | Type | Default | Required |
-|------|-------------|------|---------|:--------:|
-| machine_type | The machine type for the instance | string | e2-micro | no |
-| zone | The zone for the instance | string | - | yes |
-| boot_image | The boot disk image | string | debian-cloud/debian-11 | no |
-| network | The network to attach the instance to | string | default | no |
-| tags | Network tags for the instance | list(string) | [] | no |
-| labels | Labels for the instance | map(string) | {} | no |
-
-## Outputs
-
-| Name | Description |
-|------|-------------|
-| instance_id | The ID of the instance |
-| instance_name | The name of the instance |
-| instance_self_link | The self link of the instance |
-| instance_network_ip | The internal IP of the instance |
-`, name, "```hcl\n", "module \""+name+"\" {\n  source = \"./"+name+"\"\n  \n  zone = \"us-central1-a\"\n}\n", "```\n")
+%s
+%s
+`, name, "```hcl\n", "module \""+name+"\" {\n  source = \"./"+name+"\"\n  \n  zone = \"us-central1-a\"\n}\n", "```\n", docsBeginMarker, docsEndMarker)
 }
 
 func generateExampleTF(name string) string {
@@ -2038,8 +2654,8 @@ output "example_instance_id" {
 `, name, name)
 }
 
-func generateTestGo(name string) string {
-	return fmt.Sprintf(`package test
+func generateTestGo() string {
+	return `package test
 
 import (
 	"testing"
@@ -2066,25 +2682,21 @@ func TestTerraformModule(t *testing.T) {
 	instanceID := terraform.Output(t, terraformOptions, "example_instance_id")
 	assert.NotEmpty(t, instanceID)
 }
-`, name)
-}
-
-func handleSignals() {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-sigChan
-		logger.Info("Received interrupt signal, cleaning up...")
-		os.Exit(1)
-	}()
+`
 }
 
 func main() {
 	handleSignals()
 
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	tempDirs.removeAll()
+	if err != nil {
 		logger.Error(err)
+	}
+	if logFileSink != nil {
+		logFileSink.Close()
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// outputKinds maps terraform type names to the kind of value they hold.
+// Collections compare by shape only, so list(string) and tuple both are lists.
+var outputKinds = map[string]string{
+	"string": "string",
+	"number": "number",
+	"bool":   "bool",
+	"list":   "list",
+	"set":    "list",
+	"tuple":  "list",
+	"map":    "map",
+	"object": "map",
+	"any":    "any",
+}
+
+// schemaKind reduces a declared type such as "list(string)" to its kind
+func schemaKind(declared string) (string, bool) {
+	name := strings.TrimSpace(declared)
+	if i := strings.Index(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	kind, ok := outputKinds[strings.ToLower(strings.TrimSpace(name))]
+	return kind, ok
+}
+
+// valueKind returns the kind of a decoded output or mock value
+func valueKind(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "map"
+	}
+	if _, ok := value.(json.Number); ok {
+		return "number"
+	}
+	return "unknown"
+}
+
+// outputKind prefers the type terraform recorded for an output, which is a
+// name ("string") or a [name, element] pair, over the kind of its value
+func outputKind(output terraformOutput) string {
+	var typ interface{}
+	if len(output.Type) > 0 && json.Unmarshal(output.Type, &typ) == nil {
+		if pair, ok := typ.([]interface{}); ok && len(pair) > 0 {
+			typ = pair[0]
+		}
+		if name, ok := typ.(string); ok {
+			if kind, ok := schemaKind(name); ok {
+				return kind
+			}
+		}
+	}
+	return valueKind(output.Value)
+}
+
+// compareOutputs lists where outputs, given by kind, differ from the schema:
+// missing outputs, undeclared ones and kind mismatches
+func compareOutputs(schema map[string]string, kinds map[string]string) []string {
+	var problems []string
+
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want, ok := schemaKind(schema[name])
+		if !ok {
+			problems = append(problems, fmt.Sprintf("output %q has unknown type %q", name, schema[name]))
+			continue
+		}
+		got, present := kinds[name]
+		switch {
+		case !present:
+			problems = append(problems, fmt.Sprintf("output %q is missing", name))
+		case want != "any" && got != want:
+			problems = append(problems, fmt.Sprintf("output %q is %s, expected %s", name, got, schema[name]))
+		}
+	}
+
+	var extra []string
+	for name := range kinds {
+		if _, ok := schema[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		problems = append(problems, fmt.Sprintf("output %q is not declared", name))
+	}
+	return problems
+}
+
+// validateMockOutputs checks a dependency's mock outputs against its
+// mock_outputs_schema. Dependencies without a schema always pass.
+func validateMockOutputs(dep DependencyConfig) []string {
+	if len(dep.MockOutputsSchema) == 0 {
+		return nil
+	}
+	kinds := make(map[string]string, len(dep.MockOutputs))
+	for name, value := range dep.MockOutputs {
+		kinds[name] = valueKind(value)
+	}
+	return compareOutputs(dep.MockOutputsSchema, kinds)
+}
+
+// outputDrift lists how a dependency's real outputs differ from what its
+// mocks declare, so mocks that have fallen behind the module are noticed.
+// Each mocked output is expected with the type mock_outputs_schema gives it,
+// or else the kind of its mock value; without mocks the schema is the
+// expectation. Real outputs nothing declares are not drift.
+func outputDrift(dep DependencyConfig, outputs map[string]terraformOutput) []string {
+	expected := dep.MockOutputsSchema
+	if len(dep.MockOutputs) > 0 {
+		expected = make(map[string]string, len(dep.MockOutputs))
+		for name, value := range dep.MockOutputs {
+			if declared, ok := dep.MockOutputsSchema[name]; ok {
+				expected[name] = declared
+				continue
+			}
+			kind := valueKind(value)
+			if _, known := outputKinds[kind]; !known {
+				kind = "any"
+			}
+			expected[name] = kind
+		}
+	}
+	if len(expected) == 0 {
+		return nil
+	}
+
+	kinds := make(map[string]string, len(expected))
+	for name, output := range outputs {
+		if _, ok := expected[name]; ok {
+			kinds[name] = outputKind(output)
+		}
+	}
+	return compareOutputs(expected, kinds)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var networkSchema = map[string]string{
+	"network_id": "string",
+	"subnets":    "list(string)",
+	"mtu":        "number",
+}
+
+func TestValidateMockOutputsMatchingSchema(t *testing.T) {
+	dep := DependencyConfig{
+		Name:              "vpc",
+		Enabled:           true,
+		MockOutputsSchema: networkSchema,
+		MockOutputs: map[string]interface{}{
+			"network_id": "mock-network",
+			"subnets":    []interface{}{"10.0.0.0/24"},
+			"mtu":        float64(1460),
+		},
+	}
+	assert.Empty(t, validateMockOutputs(dep))
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{dep}
+	ctx := &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+	assert.Equal(t, "mock-network", ctx.Dependencies["vpc.network_id"])
+}
+
+func TestValidateMockOutputsMismatchingSchema(t *testing.T) {
+	dep := DependencyConfig{
+		Name:              "vpc",
+		Enabled:           true,
+		MockOutputsSchema: networkSchema,
+		MockOutputs: map[string]interface{}{
+			"network_id": "mock-network",
+			"subnets":    "10.0.0.0/24",
+			"region":     "europe-west1",
+		},
+	}
+	assert.Equal(t, []string{
+		`output "mtu" is missing`,
+		`output "subnets" is string, expected list(string)`,
+		`output "region" is not declared`,
+	}, validateMockOutputs(dep))
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{dep}
+	ctx := &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Dependencies: make(map[string]interface{})}
+	err := loadDependencyOutputs(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mock outputs of dependency vpc don't match mock_outputs_schema")
+	assert.Empty(t, ctx.Dependencies)
+}
+
+func TestValidateMockOutputsUnknownType(t *testing.T) {
+	dep := DependencyConfig{
+		Name:              "vpc",
+		MockOutputsSchema: map[string]string{"id": "strng"},
+		MockOutputs:       map[string]interface{}{"id": "x"},
+	}
+	assert.Equal(t, []string{`output "id" has unknown type "strng"`}, validateMockOutputs(dep))
+	assert.Empty(t, validateMockOutputs(DependencyConfig{MockOutputs: map[string]interface{}{"id": 1}}))
+}
+
+func TestRealOutputsDriftFromMockSchema(t *testing.T) {
+	root := t.TempDir()
+	stubDependencyIO(t, nil, `{
+  "network_id": {"value": "projects/acme/global/networks/main", "type": "string"},
+  "subnets": {"value": {"a": "10.0.0.0/24"}, "type": ["map", "string"]}
+}`)
+
+	original := logger
+	var hook *test.Hook
+	logger, hook = test.NewNullLogger()
+	t.Cleanup(func() { logger = original })
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{
+		{Name: "vpc", ConfigPath: root, Enabled: true, MockOutputsSchema: networkSchema},
+	}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+	assert.Equal(t, "projects/acme/global/networks/main", ctx.Dependencies["vpc.network_id"])
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Contains(t, entry.Message, `output "mtu" is missing`)
+	assert.Contains(t, entry.Message, `output "subnets" is map, expected list(string)`)
+}
+
+func TestConfigCheckReportsMockSchemaProblems(t *testing.T) {
+	root := t.TempDir()
+	newDependencyModule(t, root, "vpc", "")
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{{
+		Name:              "vpc",
+		ConfigPath:        "vpc",
+		Enabled:           true,
+		MockOutputsSchema: map[string]string{"network_id": "string"},
+		MockOutputs:       map[string]interface{}{"network_id": true},
+	}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	assert.Contains(t, configProblems(ctx), `dependency "vpc": mock outputs: output "network_id" is bool, expected string`)
+}
+
+func TestRealOutputsDriftFromMocks(t *testing.T) {
+	root := t.TempDir()
+	stubDependencyIO(t, nil, `{
+  "network_id": {"value": 42, "type": "number"},
+  "subnets": {"value": ["10.0.0.0/24"], "type": ["list", "string"]},
+  "region": {"value": "europe-west1", "type": "string"}
+}`)
+
+	original := logger
+	var hook *test.Hook
+	logger, hook = test.NewNullLogger()
+	t.Cleanup(func() { logger = original })
+
+	config := defaultTerragruntConfig()
+	config.Dependencies = []DependencyConfig{{
+		Name:       "vpc",
+		ConfigPath: root,
+		Enabled:    true,
+		MockOutputs: map[string]interface{}{
+			"network_id": "mock-network",
+			"subnets":    []interface{}{"10.0.0.0/24"},
+			"mtu":        float64(1460),
+		},
+	}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Dependencies: make(map[string]interface{})}
+	require.NoError(t, loadDependencyOutputs(ctx))
+	assert.Equal(t, "mock-network", ctx.Dependencies["vpc.network_id"])
+
+	// Only the mocked outputs are compared; region is not mocked
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, `Outputs of dependency vpc have drifted from its mocks: output "mtu" is missing; output "network_id" is number, expected string`, entry.Message)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// runState is shared by the contexts of one run so module goroutines can
+// report into a single place
+type runState struct {
+	mu      sync.Mutex
+	errors  []error
+	skipped []string
+	// stop, when set, is cancelled by the first failure of a fail-fast run
+	// to stop the modules still running or waiting
+	stop    context.Context
+	stopRun context.CancelFunc
+	// limits caps retries and terraform executions across the run
+	limits *runLimits
+	// binaries holds the terraform binaries checked and installed
+	binaries terraformBinaries
+}
+
+// newModuleContext returns a context for running in moduleDir. Maps and
+// slices are copied so modules can modify theirs concurrently; Logger and the
+// run state stay shared. Config is copied with the module's own inputs, and
+// its error handling when the module overrides that.
+func newModuleContext(ctx *ExecutionContext, moduleDir string) (*ExecutionContext, error) {
+	env, err := moduleEnvironment(ctx, moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := moduleRetryConfig(ctx, moduleDir)
+	if err != nil {
+		return nil, err
+	}
+	config, err = withModuleInputs(ctx, config, moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := ctx.shared
+	if shared == nil {
+		return nil, fmt.Errorf("execution context for %s has no run state", moduleDir)
+	}
+
+	return &ExecutionContext{
+		Config:          config,
+		WorkingDir:      moduleDir,
+		Command:         ctx.Command,
+		Args:            append([]string(nil), ctx.Args...),
+		Environment:     env,
+		DryRun:          ctx.DryRun,
+		Force:           ctx.Force,
+		TargetModules:   append([]string(nil), ctx.TargetModules...),
+		ExcludedModules: append([]string(nil), ctx.ExcludedModules...),
+		Dependencies:    copyValues(ctx.Dependencies),
+		Outputs:         copyValues(ctx.Outputs),
+		State:           copyValues(ctx.State),
+		Hooks:           append([]HookConfig(nil), ctx.Hooks...),
+		StartTime:       ctx.StartTime,
+		Logger:          ctx.Logger,
+		shared:          shared,
+	}, nil
+}
+
+// recordError adds err to the errors of the run
+func (ctx *ExecutionContext) recordError(err error) {
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	ctx.shared.errors = append(ctx.shared.errors, err)
+}
+
+// recordModuleFailure records err as the failure of module. In a fail-fast
+// run the first failure stops the run, and modules failing after that are
+// taken to have been interrupted by it and recorded as skipped instead
+func (ctx *ExecutionContext) recordModuleFailure(module string, err error) {
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	if ctx.shared.stop != nil {
+		if ctx.shared.stop.Err() != nil {
+			ctx.shared.skipped = append(ctx.shared.skipped, module)
+			return
+		}
+		ctx.shared.stopRun()
+	}
+	ctx.shared.errors = append(ctx.shared.errors, fmt.Errorf("module %s: %w", module, err))
+}
+
+// recordSkipped notes a module that was not run, or was stopped, because
+// the run failed fast
+func (ctx *ExecutionContext) recordSkipped(module string) {
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	ctx.shared.skipped = append(ctx.shared.skipped, module)
+}
+
+// Skipped returns the modules a fail-fast run did not finish
+func (ctx *ExecutionContext) Skipped() []string {
+	if ctx.shared == nil {
+		return nil
+	}
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	return append([]string(nil), ctx.shared.skipped...)
+}
+
+// runContext returns the context terraform runs under: the fail-fast
+// context of the run when there is one, otherwise the interrupt context
+func (ctx *ExecutionContext) runContext() context.Context {
+	if ctx.shared == nil {
+		return runCtx
+	}
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	if ctx.shared.stop != nil {
+		return ctx.shared.stop
+	}
+	return runCtx
+}
+
+// Errors returns the errors recorded so far by any context of the run
+func (ctx *ExecutionContext) Errors() []error {
+	if ctx.shared == nil {
+		return nil
+	}
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	return append([]error(nil), ctx.shared.errors...)
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
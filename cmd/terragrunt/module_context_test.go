@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewModuleContextIsolatesModules(t *testing.T) {
+	withPartialParseCache(t)
+	root := t.TempDir()
+	const modules = 64
+	for i := 0; i < modules; i++ {
+		writeModuleHCL(t, filepath.Join(root, fmt.Sprintf("m%d", i)),
+			fmt.Sprintf("environment {\n  GOOGLE_PROJECT = \"proj-%d\"\n}\n", i))
+	}
+
+	ctx := &ExecutionContext{
+		Config:       defaultTerragruntConfig(),
+		WorkingDir:   root,
+		Environment:  map[string]string{"GOOGLE_PROJECT": "proj-global"},
+		Dependencies: map[string]interface{}{"shared": "parent"},
+		Outputs:      make(map[string]interface{}),
+		State:        make(map[string]interface{}),
+		shared:       &runState{},
+	}
+
+	var wg sync.WaitGroup
+	contexts := make([]*ExecutionContext, modules)
+	for i := 0; i < modules; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			moduleCtx, err := newModuleContext(ctx, filepath.Join(root, fmt.Sprintf("m%d", i)))
+			if err != nil {
+				ctx.recordError(err)
+				return
+			}
+
+			// Mutate every map the module owns, as dependency loading does
+			name := fmt.Sprintf("m%d", i)
+			moduleCtx.Dependencies["shared"] = name
+			moduleCtx.Dependencies[name] = i
+			moduleCtx.Outputs[name] = i
+			moduleCtx.State[name] = i
+			moduleCtx.Environment["MODULE"] = name
+			moduleCtx.recordError(errors.New(name))
+			contexts[i] = moduleCtx
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, ctx.Errors(), modules)
+	for i, moduleCtx := range contexts {
+		require.NotNil(t, moduleCtx)
+		name := fmt.Sprintf("m%d", i)
+		assert.Equal(t, filepath.Join(root, name), moduleCtx.WorkingDir)
+		assert.Equal(t, map[string]interface{}{"shared": name, name: i}, moduleCtx.Dependencies)
+		assert.Equal(t, fmt.Sprintf("proj-%d", i), moduleCtx.Environment["GOOGLE_PROJECT"])
+		assert.Equal(t, name, moduleCtx.Environment["MODULE"])
+	}
+
+	assert.Equal(t, map[string]interface{}{"shared": "parent"}, ctx.Dependencies)
+	assert.Empty(t, ctx.Outputs)
+	assert.Empty(t, ctx.State)
+	assert.Equal(t, map[string]string{"GOOGLE_PROJECT": "proj-global"}, ctx.Environment)
+}
+
+func TestNewModuleContextRequiresRunState(t *testing.T) {
+	_, err := newModuleContext(&ExecutionContext{Config: defaultTerragruntConfig()}, t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// readModuleDir lists a directory during module discovery; tests replace it
+var readModuleDir = os.ReadDir
+
+// findModules returns the sorted directories under WorkingDir that hold a
+// terragrunt.hcl. Directories are read concurrently, at most Parallelism at
+// a time, and excluded directories are never descended into.
+func findModules(ctx *ExecutionContext) ([]string, error) {
+	workers := ctx.Config.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	w := &moduleWalker{ctx: ctx, sem: make(chan struct{}, workers)}
+
+	if w.excluded(ctx.WorkingDir) {
+		return nil, nil
+	}
+	if _, err := os.Lstat(ctx.WorkingDir); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.walk(ctx.WorkingDir)
+	w.wg.Wait()
+
+	if w.err != nil {
+		return nil, w.err
+	}
+	sort.Strings(w.modules)
+	return w.modules, nil
+}
+
+// moduleWalker is the shared state of one findModules traversal
+type moduleWalker struct {
+	ctx *ExecutionContext
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu      sync.Mutex
+	modules []string
+	err     error
+}
+
+func (w *moduleWalker) walk(dir string) {
+	defer w.wg.Done()
+	if w.failed() {
+		return
+	}
+
+	w.sem <- struct{}{}
+	entries, err := readModuleDir(dir)
+	<-w.sem
+	if err != nil {
+		w.fail(err)
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if w.excluded(path) {
+			continue
+		}
+		// Cached module copies carry their own terragrunt.hcl
+		if entry.IsDir() && entry.Name() == terragruntCacheDir {
+			continue
+		}
+		if entry.IsDir() {
+			w.wg.Add(1)
+			go w.walk(path)
+			continue
+		}
+		if entry.Name() == "terragrunt.hcl" && w.included(dir) {
+			w.mu.Lock()
+			w.modules = append(w.modules, dir)
+			w.mu.Unlock()
+		}
+	}
+}
+
+// excluded reports whether path matches one of the exclude dirs
+func (w *moduleWalker) excluded(path string) bool {
+	for _, exclude := range w.ctx.Config.ExcludeDirs {
+		if strings.Contains(path, exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether dir matches the include dirs, if any are set
+func (w *moduleWalker) included(dir string) bool {
+	if len(w.ctx.Config.IncludeDirs) == 0 {
+		return true
+	}
+	for _, include := range w.ctx.Config.IncludeDirs {
+		if strings.Contains(dir, include) {
+			return true
+		}
+	}
+	return false
+}
+
+// fail records the first error and stops further descent
+func (w *moduleWalker) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *moduleWalker) failed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err != nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModuleTree creates regions x services modules under root, each with
+// a nested non-module directory, and returns their directories sorted
+func writeModuleTree(tb testing.TB, root string, regions, services int) []string {
+	tb.Helper()
+	var modules []string
+	for r := 0; r < regions; r++ {
+		for s := 0; s < services; s++ {
+			dir := filepath.Join(root, fmt.Sprintf("region-%02d", r), fmt.Sprintf("service-%02d", s))
+			require.NoError(tb, os.MkdirAll(filepath.Join(dir, "files"), 0755))
+			require.NoError(tb, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), nil, 0644))
+			require.NoError(tb, os.WriteFile(filepath.Join(dir, "files", "main.tf"), nil, 0644))
+			modules = append(modules, dir)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+func TestFindModulesLargeTree(t *testing.T) {
+	root := t.TempDir()
+	expected := writeModuleTree(t, root, 12, 25)
+
+	// An excluded subtree full of modules, as in a vendored checkout
+	vendored := filepath.Join(root, "vendor")
+	writeModuleTree(t, vendored, 3, 10)
+
+	original := readModuleDir
+	t.Cleanup(func() { readModuleDir = original })
+	var mu sync.Mutex
+	var read []string
+	readModuleDir = func(dir string) ([]os.DirEntry, error) {
+		mu.Lock()
+		read = append(read, dir)
+		mu.Unlock()
+		return original(dir)
+	}
+
+	config := defaultTerragruntConfig()
+	config.Parallelism = 4
+	config.ExcludeDirs = []string{"vendor"}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	for i := 0; i < 3; i++ {
+		read = nil
+		modules, err := findModules(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, modules)
+
+		for _, dir := range read {
+			assert.False(t, strings.HasPrefix(dir, vendored), "walked excluded dir %s", dir)
+		}
+		// root + 12 regions + 300 services + 300 files dirs
+		assert.Len(t, read, 1+12+300+300)
+	}
+}
+
+func TestFindModulesIncludeDirs(t *testing.T) {
+	root := t.TempDir()
+	writeModuleTree(t, root, 3, 2)
+
+	config := defaultTerragruntConfig()
+	config.IncludeDirs = []string{"region-01"}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "region-01", "service-00"),
+		filepath.Join(root, "region-01", "service-01"),
+	}, modules)
+}
+
+func TestFindModulesMissingDir(t *testing.T) {
+	config := defaultTerragruntConfig()
+	ctx := &ExecutionContext{Config: config, WorkingDir: filepath.Join(t.TempDir(), "missing")}
+
+	_, err := findModules(ctx)
+	assert.Error(t, err)
+}
+
+func BenchmarkFindModules(b *testing.B) {
+	root := b.TempDir()
+	writeModuleTree(b, root, 20, 50)
+
+	config := defaultTerragruntConfig()
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := findModules(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
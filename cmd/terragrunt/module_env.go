@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// moduleEnvFile holds per-module settings that are layered over the global
+// config during run-all
+const moduleEnvFile = "module.hcl"
+
+// moduleHCL is the subset of module.hcl read by run-all
+type moduleHCL struct {
+	Environment map[string]string `json:"environment"`
+}
+
+// moduleEnvironment returns a fresh copy of the run's environment with the
+// module's module.hcl environment block merged over it. The result is never
+// shared between modules, so each terraform invocation can own it.
+func moduleEnvironment(ctx *ExecutionContext, moduleDir string) (map[string]string, error) {
+	env := make(map[string]string, len(ctx.Environment))
+	for key, value := range ctx.Environment {
+		env[key] = value
+	}
+
+	path := filepath.Join(moduleDir, moduleEnvFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return env, nil
+	}
+
+	values, err := readModuleValues(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var module moduleHCL
+	if err := decodeValues(values, &module); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	for key, value := range module.Environment {
+		env[key] = value
+	}
+	return env, nil
+}
+
+// readModuleValues parses a module's HCL file, through the partial parse
+// cache when it is enabled
+func readModuleValues(ctx *ExecutionContext, path string) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	var err error
+	if ctx.Config != nil && ctx.Config.UsePartialParseConfigCache {
+		values, err = partialParseConfigCache.load(path)
+	} else {
+		var data []byte
+		if data, err = os.ReadFile(path); err == nil {
+			values, err = decodeHCLConfig(data, path)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModuleHCL(t *testing.T, dir, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, moduleEnvFile), []byte(content), 0644))
+}
+
+func TestModuleEnvironmentMergesModuleHCL(t *testing.T) {
+	withPartialParseCache(t)
+	root := t.TempDir()
+	writeModuleHCL(t, filepath.Join(root, "block"), `
+environment {
+  GOOGLE_PROJECT = "proj-block"
+  TF_LOG         = "DEBUG"
+}
+`)
+	writeModuleHCL(t, filepath.Join(root, "attribute"), `environment = { GOOGLE_PROJECT = "proj-attribute" }`)
+
+	ctx := &ExecutionContext{
+		Config:      defaultTerragruntConfig(),
+		Environment: map[string]string{"GOOGLE_PROJECT": "proj-global", "GOOGLE_REGION": "europe-west1"},
+	}
+
+	env, err := moduleEnvironment(ctx, filepath.Join(root, "block"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"GOOGLE_PROJECT": "proj-block",
+		"GOOGLE_REGION":  "europe-west1",
+		"TF_LOG":         "DEBUG",
+	}, env)
+
+	env, err = moduleEnvironment(ctx, filepath.Join(root, "attribute"))
+	require.NoError(t, err)
+	assert.Equal(t, "proj-attribute", env["GOOGLE_PROJECT"])
+
+	// Modules without module.hcl get a copy of the global environment
+	env, err = moduleEnvironment(ctx, root)
+	require.NoError(t, err)
+	assert.Equal(t, ctx.Environment, env)
+	env["GOOGLE_PROJECT"] = "changed"
+	assert.Equal(t, "proj-global", ctx.Environment["GOOGLE_PROJECT"])
+}
+
+func TestModuleEnvironmentIsolatedUnderConcurrency(t *testing.T) {
+	withPartialParseCache(t)
+	root := t.TempDir()
+	const modules = 32
+	for i := 0; i < modules; i++ {
+		writeModuleHCL(t, filepath.Join(root, fmt.Sprintf("m%d", i)),
+			fmt.Sprintf("environment {\n  GOOGLE_PROJECT = \"proj-%d\"\n}\n", i))
+	}
+
+	ctx := &ExecutionContext{
+		Config:      defaultTerragruntConfig(),
+		Environment: map[string]string{"GOOGLE_PROJECT": "proj-global"},
+	}
+
+	var wg sync.WaitGroup
+	results := make([]map[string]string, modules)
+	errs := make([]error, modules)
+	for i := 0; i < modules; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			env, err := moduleEnvironment(ctx, filepath.Join(root, fmt.Sprintf("m%d", i)))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			// Each module writes to its own map
+			env["MODULE_INDEX"] = fmt.Sprint(i)
+			results[i] = env
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < modules; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("proj-%d", i), results[i]["GOOGLE_PROJECT"])
+		assert.Equal(t, fmt.Sprint(i), results[i]["MODULE_INDEX"])
+	}
+	assert.Equal(t, map[string]string{"GOOGLE_PROJECT": "proj-global"}, ctx.Environment)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// moduleErrorHandling is the error_handling block of a module's
+// terragrunt.hcl. Unset fields fall back to the global settings.
+type moduleErrorHandling struct {
+	ErrorHandling *struct {
+		MaxRetries      *int        `json:"max_retries"`
+		RetryDelay      interface{} `json:"retry_delay"`
+		RetryableErrors []string    `json:"retryable_errors"`
+	} `json:"error_handling"`
+}
+
+// moduleRetryConfig returns the config to run moduleDir with: ctx.Config
+// itself, or a copy with the retry settings of the module's error_handling
+// block merged over the global ones
+func moduleRetryConfig(ctx *ExecutionContext, moduleDir string) (*TerragruntConfig, error) {
+	path := filepath.Join(moduleDir, "terragrunt.hcl")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ctx.Config, nil
+	}
+
+	values, err := readModuleValues(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var module moduleErrorHandling
+	if err := decodeValues(values, &module); err != nil {
+		return nil, fmt.Errorf("failed to decode error_handling in %s: %w", path, err)
+	}
+	override := module.ErrorHandling
+	if override == nil {
+		return ctx.Config, nil
+	}
+
+	config := *ctx.Config
+	if override.MaxRetries != nil {
+		if *override.MaxRetries < 0 {
+			return nil, fmt.Errorf("%s: max_retries must not be negative", path)
+		}
+		config.RetryAttempts = *override.MaxRetries
+		config.ErrorHandling.MaxRetries = *override.MaxRetries
+	}
+	if override.RetryDelay != nil {
+		delay, err := parseRetryDelay(override.RetryDelay)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		config.RetryDelay = delay
+		config.ErrorHandling.RetryDelay = delay
+	}
+	if override.RetryableErrors != nil {
+		config.ErrorHandling.RetryableErrors = override.RetryableErrors
+	}
+	return &config, nil
+}
+
+// UnmarshalJSON lets retry_delay be written as a duration string such as
+// "30s" in terragrunt.hcl as well as in nanoseconds
+func (c *ErrorHandlingConfig) UnmarshalJSON(data []byte) error {
+	type plain ErrorHandlingConfig
+	raw := struct {
+		*plain
+		RetryDelay interface{} `json:"retry_delay"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.RetryDelay != nil {
+		delay, err := parseRetryDelay(raw.RetryDelay)
+		if err != nil {
+			return err
+		}
+		c.RetryDelay = delay
+	}
+	return nil
+}
+
+// parseRetryDelay accepts a duration string or a number of nanoseconds
+func parseRetryDelay(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case string:
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retry_delay %q: %w", v, err)
+		}
+		return delay, nil
+	case float64:
+		return time.Duration(v), nil
+	default:
+		return 0, fmt.Errorf("invalid retry_delay %v (expected a duration such as \"30s\")", value)
+	}
+}
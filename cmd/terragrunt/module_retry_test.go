@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyTerraform writes a terraform stand-in that records each run in
+// attempts.log and fails with a retryable error
+func flakyTerraform(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	path := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\necho run >> attempts.log\necho 'Error: googleapi: Error 503: backend unavailable' >&2\nexit 1\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+// writeTerragruntHCL writes content as the terragrunt.hcl of dir
+func writeTerragruntHCL(t *testing.T, dir, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(content), 0644))
+}
+
+func attempts(t *testing.T, dir string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "attempts.log"))
+	require.NoError(t, err)
+	return strings.Count(string(data), "run\n")
+}
+
+func TestModuleErrorHandlingOverridesRetries(t *testing.T) {
+	withPartialParseCache(t)
+	root := t.TempDir()
+	flaky := filepath.Join(root, "flaky")
+	steady := filepath.Join(root, "steady")
+	writeTerragruntHCL(t, flaky, `
+error_handling {
+  max_retries      = 4
+  retry_delay      = "1ms"
+  retryable_errors = ["Error 503"]
+}
+`)
+	writeTerragruntHCL(t, steady, "")
+
+	config := defaultTerragruntConfig()
+	config.TerraformPath = flakyTerraform(t)
+	config.Cache.Dir = t.TempDir()
+	config.RetryAttempts = 1
+	config.RetryDelay = time.Millisecond
+	config.ErrorHandling.RetryableErrors = []string{"googleapi: Error 503"}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Environment: map[string]string{}, shared: &runState{}}
+
+	for _, dir := range []string{flaky, steady} {
+		moduleCtx, err := newModuleContext(ctx, dir)
+		require.NoError(t, err)
+		err = executeTerraform(moduleCtx, "plan")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "terraform command failed after")
+	}
+
+	assert.Equal(t, 5, attempts(t, flaky))
+	assert.Equal(t, 2, attempts(t, steady))
+	assert.Equal(t, 1, config.RetryAttempts, "global config must not change")
+}
+
+func TestModuleRetryConfigMergesGlobalDefaults(t *testing.T) {
+	withPartialParseCache(t)
+	dir := t.TempDir()
+	writeTerragruntHCL(t, dir, "error_handling {\n  retry_delay = \"10s\"\n}\n")
+
+	config := defaultTerragruntConfig()
+	config.ErrorHandling.RetryableErrors = []string{"timeout"}
+	ctx := &ExecutionContext{Config: config}
+
+	merged, err := moduleRetryConfig(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, merged.RetryDelay)
+	assert.Equal(t, config.RetryAttempts, merged.RetryAttempts)
+	assert.Equal(t, []string{"timeout"}, merged.ErrorHandling.RetryableErrors)
+
+	// Modules without an override keep the shared config
+	other := t.TempDir()
+	writeTerragruntHCL(t, other, "")
+	same, err := moduleRetryConfig(ctx, other)
+	require.NoError(t, err)
+	assert.Same(t, config, same)
+
+	writeTerragruntHCL(t, dir, "error_handling {\n  retry_delay = \"soon\"\n}\n")
+	_, err = moduleRetryConfig(&ExecutionContext{Config: defaultTerragruntConfig()}, dir)
+	assert.ErrorContains(t, err, `invalid retry_delay "soon"`)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var outputAllCmd = &cobra.Command{
+	Use:   "output [name]",
+	Short: "Collect terraform outputs across all modules",
+	Long: `Run terraform output -json in every module in dependency order and print
+the outputs as one JSON document keyed by module path. With a name, print only
+that output's value from each module that defines it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOutputAll,
+}
+
+func runOutputAll(cmd *cobra.Command, args []string) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	modules, err := findModules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find modules: %w", err)
+	}
+	modules, err = filterModulesByState(ctx, modules)
+	if err != nil {
+		return err
+	}
+
+	graph, err := buildDependencyGraph(ctx, modules)
+	if err != nil {
+		return fmt.Errorf("failed to build dependency graph: %w", err)
+	}
+
+	order, err := topologicalSort(graph)
+	if err != nil {
+		return fmt.Errorf("failed to determine execution order: %w", err)
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	collected, err := collectModuleOutputs(ctx, order, name)
+	if collected != nil {
+		data, marshalErr := json.MarshalIndent(collected, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal outputs: %w", marshalErr)
+		}
+		fmt.Println(string(data))
+	}
+	return err
+}
+
+// collectModuleOutputs reads the outputs of each module in order. The result
+// is keyed by module path relative to the working directory; each entry holds
+// the module's full `terraform output -json` document, or only the value of
+// name when one is given. Modules without that output are left out. Modules
+// that fail are reported together after the rest have been collected.
+func collectModuleOutputs(ctx *ExecutionContext, modules []string, name string) (map[string]interface{}, error) {
+	collected := make(map[string]interface{})
+	var errs []error
+	for _, module := range modules {
+		key, err := filepath.Rel(ctx.WorkingDir, module)
+		if err != nil {
+			key = module
+		}
+		key = filepath.ToSlash(key)
+
+		logger.Debugf("Reading outputs of module: %s", key)
+		data, err := runTerraformOutput(ctx, module)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("module %s: %w", key, err))
+			continue
+		}
+
+		var outputs map[string]terraformOutput
+		if err := json.Unmarshal(data, &outputs); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: failed to parse terraform output: %w", key, err))
+			continue
+		}
+
+		if name == "" {
+			collected[key] = outputs
+			continue
+		}
+		if output, ok := outputs[name]; ok {
+			collected[key] = output.Value
+		}
+	}
+
+	if len(errs) > 0 {
+		return collected, fmt.Errorf("%d modules failed: %w", len(errs), errors.Join(errs...))
+	}
+	return collected, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeOutputTree lays out modules with dependency blocks and returns the root
+func writeOutputTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	modules := map[string]string{
+		"network":      ``,
+		"db":           `dependency "network" { config_path = "../network" }`,
+		"app":          "dependency \"db\" { config_path = \"../db\" }\ndependency \"network\" { config_path = \"../network\" }",
+		"sandbox/temp": ``,
+	}
+	for name, hcl := range modules {
+		dir := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(hcl), 0644))
+	}
+	return root
+}
+
+func stubModuleOutputs(t *testing.T, root string, outputs map[string]string) *[]string {
+	t.Helper()
+	var order []string
+	original, originalCache := runTerraformOutput, dependencyStateCache
+	dependencyStateCache = &stateOutputCache{outputs: make(map[string]map[string]terraformOutput)}
+	runTerraformOutput = func(_ *ExecutionContext, dir string) ([]byte, error) {
+		rel, _ := filepath.Rel(root, dir)
+		order = append(order, filepath.ToSlash(rel))
+		data, ok := outputs[filepath.ToSlash(rel)]
+		if !ok {
+			return nil, errors.New("no state")
+		}
+		return []byte(data), nil
+	}
+	t.Cleanup(func() { runTerraformOutput, dependencyStateCache = original, originalCache })
+	return &order
+}
+
+func outputTreeOrder(t *testing.T, ctx *ExecutionContext) []string {
+	t.Helper()
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	graph, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	order, err := topologicalSort(graph)
+	require.NoError(t, err)
+	return order
+}
+
+func TestRunAllOutputAggregatesInDependencyOrder(t *testing.T) {
+	root := writeOutputTree(t)
+	calls := stubModuleOutputs(t, root, map[string]string{
+		"network": `{"network_id": {"value": "net-1", "type": "string"}}`,
+		"db":      `{"connection": {"value": "10.0.0.5", "type": "string"}, "password": {"value": "s3cret", "type": "string", "sensitive": true}}`,
+		"app":     `{"url": {"value": "https://app.example.com", "type": "string"}, "network_id": {"value": "net-1", "type": "string"}}`,
+	})
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root}
+	ctx.Config.ExcludeDirs = []string{"sandbox"}
+
+	collected, err := collectModuleOutputs(ctx, outputTreeOrder(t, ctx), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"network", "db", "app"}, *calls)
+
+	data, err := json.Marshal(collected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"network": {"network_id": {"value": "net-1", "type": "string"}},
+		"db": {
+			"connection": {"value": "10.0.0.5", "type": "string"},
+			"password": {"value": "s3cret", "type": "string", "sensitive": true}
+		},
+		"app": {
+			"url": {"value": "https://app.example.com", "type": "string"},
+			"network_id": {"value": "net-1", "type": "string"}
+		}
+	}`, string(data))
+}
+
+func TestRunAllOutputSelectsSingleName(t *testing.T) {
+	root := writeOutputTree(t)
+	stubModuleOutputs(t, root, map[string]string{
+		"network":      `{"network_id": {"value": "net-1", "type": "string"}}`,
+		"db":           `{"connection": {"value": "10.0.0.5", "type": "string"}}`,
+		"app":          `{"network_id": {"value": "net-1", "type": "string"}}`,
+		"sandbox/temp": `{"network_id": {"value": "net-sandbox", "type": "string"}}`,
+	})
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root}
+	collected, err := collectModuleOutputs(ctx, outputTreeOrder(t, ctx), "network_id")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"network":      "net-1",
+		"app":          "net-1",
+		"sandbox/temp": "net-sandbox",
+	}, collected)
+
+	// Include filters narrow the modules that are read
+	ctx.Config.IncludeDirs = []string{"sandbox"}
+	collected, err = collectModuleOutputs(ctx, outputTreeOrder(t, ctx), "network_id")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sandbox/temp": "net-sandbox"}, collected)
+}
+
+func TestRunAllOutputReportsFailedModules(t *testing.T) {
+	root := writeOutputTree(t)
+	stubModuleOutputs(t, root, map[string]string{
+		"network": `{"network_id": {"value": "net-1", "type": "string"}}`,
+	})
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root}
+	ctx.Config.ExcludeDirs = []string{"sandbox"}
+	collected, err := collectModuleOutputs(ctx, outputTreeOrder(t, ctx), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 modules failed")
+	assert.Contains(t, err.Error(), "module db: no state")
+	assert.Contains(t, collected, "network")
+}
+
+func TestTopologicalSortDetectsCycles(t *testing.T) {
+	_, err := topologicalSort(map[string][]string{"a": {"b"}, "b": {"a"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle")
+}
+
+func TestTopologicalSortNamesCyclePath(t *testing.T) {
+	// app, db and dns form the cycle; network hangs off it without being
+	// part of it
+	graph := map[string][]string{
+		"app":     {"db"},
+		"db":      {"network", "dns"},
+		"dns":     {"app"},
+		"network": nil,
+	}
+
+	order, err := topologicalSort(graph)
+	assert.Nil(t, order)
+	var cycle *dependencyCycleError
+	require.ErrorAs(t, err, &cycle)
+	assert.Equal(t, []string{"app", "db", "dns", "app"}, cycle.Path)
+	assert.Equal(t, "dependency cycle: app → db → dns → app", err.Error())
+
+	// Entered from a module outside it, the path still starts and ends at
+	// the same module
+	graph = map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"d"},
+		"d": {"b"},
+	}
+	_, err = topologicalSort(graph)
+	require.ErrorAs(t, err, &cycle)
+	assert.Equal(t, []string{"b", "c", "d", "b"}, cycle.Path)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// partialParseCache holds module configs that have been parsed but not
+// evaluated, so run-all dependency discovery reads each file once. Entries
+// are keyed by path and validated against the file's mtime and size; when
+// those change the file is re-hashed, and parses are shared by content hash
+// so identical files are only parsed once.
+type partialParseCache struct {
+	mu       sync.Mutex
+	files    map[string]partialParseFile
+	parsed   map[[sha256.Size]byte]map[string]interface{}
+	hits     int
+	misses   int
+	parseHCL func(data []byte, filename string) (map[string]interface{}, error)
+}
+
+// partialParseFile records the file state a cached parse was taken from
+type partialParseFile struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+var partialParseConfigCache = newPartialParseCache()
+
+func newPartialParseCache() *partialParseCache {
+	return &partialParseCache{
+		files:    make(map[string]partialParseFile),
+		parsed:   make(map[[sha256.Size]byte]map[string]interface{}),
+		parseHCL: decodeHCLConfig,
+	}
+}
+
+// load returns the decoded HCL of path, parsing it only when no parse of the
+// same content is cached. The returned map is shared and must not be modified.
+func (c *partialParseCache) load(path string) (map[string]interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if file, ok := c.files[path]; ok && file.modTime.Equal(info.ModTime()) && file.size == info.Size() {
+		if values, ok := c.parsed[file.hash]; ok {
+			c.hits++
+			return values, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	c.files[path] = partialParseFile{modTime: info.ModTime(), size: info.Size(), hash: hash}
+
+	if values, ok := c.parsed[hash]; ok {
+		c.hits++
+		return values, nil
+	}
+
+	c.misses++
+	values, err := c.parseHCL(data, path)
+	if err != nil {
+		delete(c.files, path)
+		return nil, err
+	}
+	c.parsed[hash] = values
+	return values, nil
+}
+
+// stats returns the number of cache hits and misses so far
+func (c *partialParseCache) stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// loadModuleConfig reads a module's terragrunt.hcl into config, going through
+// the partial parse cache unless it has been disabled
+func loadModuleConfig(ctx *ExecutionContext, path string, config *TerragruntConfig) error {
+	if ctx.Config == nil || !ctx.Config.UsePartialParseConfigCache {
+		return loadConfigFile(path, "hcl", config)
+	}
+
+	values, err := partialParseConfigCache.load(path)
+	if err != nil {
+		return err
+	}
+	if err := decodeValues(values, config); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
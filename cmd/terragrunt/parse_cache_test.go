@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withPartialParseCache(t *testing.T) *partialParseCache {
+	t.Helper()
+	original := partialParseConfigCache
+	partialParseConfigCache = newPartialParseCache()
+	t.Cleanup(func() { partialParseConfigCache = original })
+	return partialParseConfigCache
+}
+
+func TestPartialParseCacheHitsUnchangedFiles(t *testing.T) {
+	cache := newPartialParseCache()
+	path := filepath.Join(t.TempDir(), "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(`dependency "vpc" { config_path = "../vpc" }`), 0644))
+
+	first, err := cache.load(path)
+	require.NoError(t, err)
+	second, err := cache.load(path)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	hits, misses := cache.stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 1, misses)
+}
+
+func TestPartialParseCacheInvalidatesModifiedFiles(t *testing.T) {
+	cache := newPartialParseCache()
+	path := filepath.Join(t.TempDir(), "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(`dependency "vpc" { config_path = "../vpc" }`), 0644))
+
+	_, err := cache.load(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`dependency "db" { config_path = "../db" }`), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	values, err := cache.load(path)
+	require.NoError(t, err)
+	deps := values["dependencies"].([]interface{})
+	require.Len(t, deps, 1)
+	assert.Equal(t, "db", deps[0].(map[string]interface{})["name"])
+
+	hits, misses := cache.stats()
+	assert.Equal(t, 0, hits)
+	assert.Equal(t, 2, misses)
+
+	// Touching a file without changing it re-hashes but reuses the parse
+	touched := later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, touched, touched))
+	_, err = cache.load(path)
+	require.NoError(t, err)
+	hits, misses = cache.stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 2, misses)
+}
+
+func TestPartialParseCacheSharesIdenticalContent(t *testing.T) {
+	cache := newPartialParseCache()
+	root := t.TempDir()
+	content := []byte(`inputs = { env = "dev" }`)
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name, "terragrunt.hcl"), content, 0644))
+	}
+
+	_, err := cache.load(filepath.Join(root, "a", "terragrunt.hcl"))
+	require.NoError(t, err)
+	_, err = cache.load(filepath.Join(root, "b", "terragrunt.hcl"))
+	require.NoError(t, err)
+
+	hits, misses := cache.stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 1, misses)
+}
+
+func TestBuildDependencyGraphReusesPartialParseCache(t *testing.T) {
+	cache := withPartialParseCache(t)
+	root := writeOutputTree(t)
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root}
+
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	first, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	hits, misses := cache.stats()
+
+	second, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	hitsAfter, missesAfter := cache.stats()
+	assert.Equal(t, hits+len(modules), hitsAfter)
+	assert.Equal(t, misses, missesAfter)
+
+	// With the cache disabled every module is parsed directly
+	ctx.Config.UsePartialParseConfigCache = false
+	third, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	assert.Equal(t, first, third)
+	hitsDisabled, _ := cache.stats()
+	assert.Equal(t, hitsAfter, hitsDisabled)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// pluginCacheEnvVar points terraform at a provider cache shared by all modules
+const pluginCacheEnvVar = "TF_PLUGIN_CACHE_DIR"
+
+// pluginCacheLocks serializes inits that may write to a cache directory.
+// Terraform doesn't lock the plugin cache, so two inits downloading the same
+// provider at once can leave a corrupt binary behind.
+var pluginCacheLocks sync.Map
+
+// pluginCacheDir returns the provider cache directory for ctx: an explicit
+// TF_PLUGIN_CACHE_DIR, cache.plugin_cache_dir, a "plugins" directory under
+// cache.dir, or ~/.terragrunt/plugin-cache
+func pluginCacheDir(ctx *ExecutionContext) string {
+	if dir := ctx.Environment[pluginCacheEnvVar]; dir != "" {
+		return dir
+	}
+	if ctx.Config.Cache.PluginCacheDir != "" {
+		return ctx.Config.Cache.PluginCacheDir
+	}
+	if ctx.Config.Cache.Dir != "" {
+		return filepath.Join(ctx.Config.Cache.Dir, "plugins")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".terragrunt", "plugin-cache")
+}
+
+// pluginCacheEnv creates the provider cache directory and returns a copy of
+// the context environment with TF_PLUGIN_CACHE_DIR set to it
+func pluginCacheEnv(ctx *ExecutionContext) (map[string]string, string, error) {
+	dir, err := filepath.Abs(pluginCacheDir(ctx))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve plugin cache dir: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create plugin cache dir: %w", err)
+	}
+
+	env := make(map[string]string, len(ctx.Environment)+1)
+	for key, value := range ctx.Environment {
+		env[key] = value
+	}
+	env[pluginCacheEnvVar] = dir
+	return env, dir, nil
+}
+
+// lockPluginCache holds the cache directory's lock for an init whose
+// providers aren't all cached yet, and returns the function releasing it.
+// Inits that only link already cached providers run in parallel.
+func lockPluginCache(cacheDir, moduleDir string, args []string) func() {
+	if len(args) == 0 || args[0] != "init" || pluginCacheWarm(cacheDir, moduleDir) {
+		return func() {}
+	}
+
+	value, _ := pluginCacheLocks.LoadOrStore(cacheDir, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// pluginCacheWarm reports whether every provider pinned in the module's
+// .terraform.lock.hcl is already in the cache. Modules without a lock file
+// are treated as cold since their providers are unknown.
+func pluginCacheWarm(cacheDir, moduleDir string) bool {
+	lockPath := filepath.Join(moduleDir, ".terraform.lock.hcl")
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+
+	values, err := decodeHCLConfig(data, lockPath)
+	if err != nil {
+		return false
+	}
+
+	providers, _ := values["provider"].([]interface{})
+	if len(providers) == 0 {
+		return false
+	}
+
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	for _, p := range providers {
+		provider, _ := p.(map[string]interface{})
+		address, _ := provider["name"].(string)
+		version, _ := provider["version"].(string)
+		if address == "" || version == "" {
+			return false
+		}
+
+		parts := append(strings.Split(address, "/"), version, platform)
+		if _, err := os.Stat(filepath.Join(append([]string{cacheDir}, parts...)...)); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTerraform writes a terraform stand-in that records TF_PLUGIN_CACHE_DIR
+func fakeTerraform(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	path := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\necho \"$TF_PLUGIN_CACHE_DIR\" > plugin-cache.out\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestExecuteTerraformSetsPluginCacheDir(t *testing.T) {
+	config := defaultTerragruntConfig()
+	config.TerraformPath = fakeTerraform(t)
+	config.Cache.Dir = filepath.Join(t.TempDir(), "cache")
+	ctx := &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Environment: map[string]string{}, Logger: logger}
+
+	require.NoError(t, executeTerraform(ctx, "init"))
+
+	want := filepath.Join(config.Cache.Dir, "plugins")
+	assert.DirExists(t, want)
+	out, err := os.ReadFile(filepath.Join(ctx.WorkingDir, "plugin-cache.out"))
+	require.NoError(t, err)
+	assert.Equal(t, want, strings.TrimSpace(string(out)))
+	assert.NotContains(t, ctx.Environment, pluginCacheEnvVar)
+
+	// A dedicated directory takes precedence over cache.dir
+	config.Cache.PluginCacheDir = filepath.Join(t.TempDir(), "providers")
+	require.NoError(t, executeTerraform(ctx, "init"))
+	assert.DirExists(t, config.Cache.PluginCacheDir)
+	out, err = os.ReadFile(filepath.Join(ctx.WorkingDir, "plugin-cache.out"))
+	require.NoError(t, err)
+	assert.Equal(t, config.Cache.PluginCacheDir, strings.TrimSpace(string(out)))
+}
+
+func TestPluginCacheWarm(t *testing.T) {
+	cacheDir := t.TempDir()
+	moduleDir := t.TempDir()
+	assert.False(t, pluginCacheWarm(cacheDir, moduleDir), "no lock file")
+
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir, ".terraform.lock.hcl"), []byte(`
+provider "registry.terraform.io/hashicorp/google" {
+  version     = "5.10.0"
+  constraints = "~> 5.0"
+  hashes      = ["h1:abc"]
+}
+`), 0644))
+	assert.False(t, pluginCacheWarm(cacheDir, moduleDir), "provider not cached yet")
+
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "registry.terraform.io", "hashicorp", "google", "5.10.0", platform), 0755))
+	assert.True(t, pluginCacheWarm(cacheDir, moduleDir))
+}
@@ -0,0 +1,28 @@
+package terraform.guardrails.allowed_regions
+
+import rego.v1
+
+allowed_regions := ["us-central1", "us-east1", "europe-west1"]
+
+# Deny regional resources outside the allowed regions
+deny contains msg if {
+    resource := input.resource_changes[_]
+    region := resource.change.after.region
+    is_string(region)
+    not region in allowed_regions
+    msg := sprintf("allowed_regions: %s is in region %s (allowed: %s)", [resource.address, region, concat(", ", allowed_regions)])
+}
+
+# Zones must belong to an allowed region
+deny contains msg if {
+    resource := input.resource_changes[_]
+    zone := resource.change.after.zone
+    is_string(zone)
+    not zone_allowed(zone)
+    msg := sprintf("allowed_regions: %s is in zone %s (allowed regions: %s)", [resource.address, zone, concat(", ", allowed_regions)])
+}
+
+zone_allowed(zone) if {
+    region := allowed_regions[_]
+    startswith(zone, sprintf("%s-", [region]))
+}
@@ -0,0 +1,28 @@
+package terraform.guardrails.public_buckets
+
+import rego.v1
+
+# Deny granting bucket access to everyone
+deny contains msg if {
+    resource := input.resource_changes[_]
+    resource.type == "google_storage_bucket_iam_member"
+    member := resource.change.after.member
+    member in ["allUsers", "allAuthenticatedUsers"]
+    msg := sprintf("public_buckets: %s grants access to %s", [resource.address, member])
+}
+
+deny contains msg if {
+    resource := input.resource_changes[_]
+    resource.type == "google_storage_bucket_iam_binding"
+    member := resource.change.after.members[_]
+    member in ["allUsers", "allAuthenticatedUsers"]
+    msg := sprintf("public_buckets: %s grants access to %s", [resource.address, member])
+}
+
+# Require uniform bucket-level access so object ACLs can't make data public
+warn contains msg if {
+    resource := input.resource_changes[_]
+    resource.type == "google_storage_bucket"
+    not resource.change.after.uniform_bucket_level_access
+    msg := sprintf("public_buckets: %s should enable uniform_bucket_level_access", [resource.address])
+}
@@ -0,0 +1,24 @@
+package terraform.guardrails.required_labels
+
+import rego.v1
+
+required_labels := ["env", "owner", "cost-center"]
+
+labeled_types := {
+    "google_compute_instance",
+    "google_storage_bucket",
+    "google_container_cluster",
+    "google_sql_database_instance",
+    "google_bigquery_dataset",
+}
+
+# Deny labelable resources missing any of the required labels
+deny contains msg if {
+    resource := input.resource_changes[_]
+    resource.type in labeled_types
+    not "delete" in resource.change.actions
+    labels := object.get(resource.change.after, "labels", {})
+    missing := [label | label := required_labels[_]; not labels[label]]
+    count(missing) > 0
+    msg := sprintf("required_labels: %s is missing labels %s", [resource.address, concat(", ", missing)])
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/suppress"
+)
+
+// builtinPolicyDir is the --policy-dir value that selects the bundled
+// example policies (no public buckets, required labels, allowed regions)
+const builtinPolicyDir = "builtin"
+
+//go:embed policies/*.rego
+var builtinPolicies embed.FS
+
+// policyResult is one entry of `conftest test --output json`
+type policyResult struct {
+	Filename  string          `json:"filename"`
+	Namespace string          `json:"namespace"`
+	Successes int             `json:"successes"`
+	Warnings  []policyMessage `json:"warnings"`
+	Failures  []policyMessage `json:"failures"`
+}
+
+type policyMessage struct {
+	Msg      string                 `json:"msg"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// policyViolation is a single deny or warn produced by a policy
+type policyViolation struct {
+	Namespace string
+	Message   string
+	// ID and Resource come from the rule's metadata; without a resource
+	// the address in messages like "rule: address ..." is used
+	ID       string
+	Resource string
+}
+
+func newPolicyViolation(namespace string, message policyMessage) policyViolation {
+	v := policyViolation{Namespace: namespace, Message: message.Msg}
+	v.ID, _ = message.Metadata["id"].(string)
+	v.Resource, _ = message.Metadata["resource"].(string)
+	if v.Resource == "" {
+		if _, rest, ok := strings.Cut(message.Msg, ": "); ok {
+			v.Resource, _, _ = strings.Cut(rest, " ")
+		}
+	}
+	return v
+}
+
+// suppressed finds the suppression accepting a denial. Rules are policy
+// namespaces, written in full or as their last part such as public_buckets.
+func (v policyViolation) suppressed(suppressions *suppress.List, at time.Time) (suppress.Suppression, bool) {
+	if s, ok := suppressions.Match(v.ID, v.Resource, v.Namespace, at); ok {
+		return s, true
+	}
+	short := v.Namespace[strings.LastIndex(v.Namespace, ".")+1:]
+	return suppressions.Match(v.ID, v.Resource, short, at)
+}
+
+// runConftest evaluates planJSON against the policy directories; tests replace it
+var runConftest = func(planJSON string, policyDirs []string) ([]byte, error) {
+	args := []string{"test", "--all-namespaces", "--no-color", "--output", "json"}
+	for _, dir := range policyDirs {
+		args = append(args, "--policy", dir)
+	}
+	args = append(args, planJSON)
+
+	cmd := exec.Command("conftest", args...)
+	output, err := cmd.Output()
+	// conftest exits non-zero when a policy denies, but still prints results
+	if err != nil && len(output) == 0 {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("conftest failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("conftest failed: %w", err)
+	}
+	return output, nil
+}
+
+// showPlanJSON renders a saved plan as JSON; tests replace it
+var showPlanJSON = func(ctx *ExecutionContext, planFile string) ([]byte, error) {
+	return queryTerraform(ctx, "show", "-json", planFile)
+}
+
+// checkPlanPolicies converts a saved plan to JSON and gates it on the
+// policies, failing when any deny rule matches that suppressions don't accept
+func checkPlanPolicies(ctx *ExecutionContext, planFile string, policyDirs []string, suppressions *suppress.List) error {
+	planJSON, err := showPlanJSON(ctx, planFile)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, removeTmpDir, err := makeTempDir("terragrunt-policy-")
+	if err != nil {
+		return err
+	}
+	defer removeTmpDir()
+
+	planPath := filepath.Join(tmpDir, "plan.json")
+	if err := os.WriteFile(planPath, planJSON, 0600); err != nil {
+		return err
+	}
+
+	dirs, err := resolvePolicyDirs(policyDirs, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	denials, warnings, err := evaluatePolicies(planPath, dirs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, s := range suppressions.Expired(now) {
+		logger.Warnf("Policy suppression %s has expired", s)
+	}
+	for _, v := range warnings {
+		logger.Warnf("Policy warning [%s]: %s", v.Namespace, v.Message)
+	}
+	var rules []string
+	suppressed := 0
+	for _, v := range denials {
+		if s, ok := v.suppressed(suppressions, now); ok {
+			logger.Warnf("Policy denied [%s]: %s (suppressed: %s)", v.Namespace, v.Message, s.Justification)
+			suppressed++
+			continue
+		}
+		logger.Errorf("Policy denied [%s]: %s", v.Namespace, v.Message)
+		rules = append(rules, fmt.Sprintf("[%s] %s", v.Namespace, v.Message))
+	}
+
+	if len(rules) > 0 {
+		return fmt.Errorf("plan violates %d policy rule(s):\n  %s", len(rules), strings.Join(rules, "\n  "))
+	}
+
+	logger.Infof("Plan passed policy checks (%d warning(s), %d suppressed)", len(warnings), suppressed)
+	return nil
+}
+
+// loadPolicySuppressions reads the --policy-suppressions file, if any
+func loadPolicySuppressions(cmd *cobra.Command) (*suppress.List, error) {
+	path, _ := cmd.Flags().GetString("policy-suppressions")
+	if path == "" {
+		return nil, nil
+	}
+	return suppress.Load(path)
+}
+
+// evaluatePolicies runs conftest and splits its results by severity
+func evaluatePolicies(planPath string, policyDirs []string) ([]policyViolation, []policyViolation, error) {
+	output, err := runConftest(planPath, policyDirs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []policyResult
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse conftest output: %w", err)
+	}
+
+	var denials, warnings []policyViolation
+	for _, result := range results {
+		for _, failure := range result.Failures {
+			denials = append(denials, newPolicyViolation(result.Namespace, failure))
+		}
+		for _, warning := range result.Warnings {
+			warnings = append(warnings, newPolicyViolation(result.Namespace, warning))
+		}
+	}
+	return denials, warnings, nil
+}
+
+// resolvePolicyDirs expands the builtin keyword by extracting the bundled
+// policies into tmpDir
+func resolvePolicyDirs(policyDirs []string, tmpDir string) ([]string, error) {
+	var dirs []string
+	for _, dir := range policyDirs {
+		if dir != builtinPolicyDir {
+			dirs = append(dirs, dir)
+			continue
+		}
+
+		target := filepath.Join(tmpDir, "builtin")
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return nil, err
+		}
+		entries, err := fs.ReadDir(builtinPolicies, "policies")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			data, err := builtinPolicies.ReadFile("policies/" + entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(filepath.Join(target, entry.Name()), data, 0644); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, target)
+	}
+	return dirs, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/suppress"
+)
+
+const unlabeledBucketPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "google_storage_bucket.logs",
+      "type": "google_storage_bucket",
+      "change": {
+        "actions": ["create"],
+        "after": {
+          "name": "acme-logs",
+          "location": "US",
+          "uniform_bucket_level_access": true,
+          "labels": {"env": "prod", "owner": "platform"}
+        }
+      }
+    }
+  ]
+}`
+
+func stubPlanJSON(t *testing.T, plan string) {
+	t.Helper()
+	original := showPlanJSON
+	showPlanJSON = func(*ExecutionContext, string) ([]byte, error) { return []byte(plan), nil }
+	t.Cleanup(func() { showPlanJSON = original })
+}
+
+func TestPlanPolicyGateFailsOnLabelDenial(t *testing.T) {
+	stubPlanJSON(t, unlabeledBucketPlan)
+
+	original := runConftest
+	t.Cleanup(func() { runConftest = original })
+
+	var gotPlan []byte
+	var gotDirs []string
+	var builtinExtracted bool
+	runConftest = func(planJSON string, policyDirs []string) ([]byte, error) {
+		gotPlan, _ = os.ReadFile(planJSON)
+		gotDirs = policyDirs
+		_, err := os.Stat(filepath.Join(policyDirs[0], "required_labels.rego"))
+		builtinExtracted = err == nil
+		return json.Marshal([]policyResult{
+			{
+				Filename:  planJSON,
+				Namespace: "terraform.guardrails.required_labels",
+				Failures:  []policyMessage{{Msg: "required_labels: google_storage_bucket.logs is missing labels cost-center"}},
+			},
+			{
+				Filename:  planJSON,
+				Namespace: "terraform.guardrails.public_buckets",
+				Successes: 1,
+				Warnings:  []policyMessage{{Msg: "public_buckets: example warning"}},
+			},
+		})
+	}
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: t.TempDir()}
+	err := checkPlanPolicies(ctx, "tfplan", []string{builtinPolicyDir, "/org/policies"}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 policy rule(s)")
+	assert.Contains(t, err.Error(), "[terraform.guardrails.required_labels] required_labels: google_storage_bucket.logs is missing labels cost-center")
+	assert.NotContains(t, err.Error(), "example warning", "warnings don't fail the gate")
+
+	assert.JSONEq(t, unlabeledBucketPlan, string(gotPlan))
+	require.Len(t, gotDirs, 2)
+	assert.Equal(t, "/org/policies", gotDirs[1])
+	assert.True(t, builtinExtracted, "builtin policies are extracted for conftest")
+}
+
+func TestPlanPolicyGatePassesWithWarningsOnly(t *testing.T) {
+	stubPlanJSON(t, unlabeledBucketPlan)
+
+	original := runConftest
+	t.Cleanup(func() { runConftest = original })
+	runConftest = func(string, []string) ([]byte, error) {
+		return []byte(`[{"filename":"plan.json","namespace":"terraform.cost","successes":3,"warnings":[{"msg":"consider committed use"}],"failures":[]}]`), nil
+	}
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: t.TempDir()}
+	assert.NoError(t, checkPlanPolicies(ctx, "tfplan", []string{"/org/policies"}, nil))
+}
+
+func TestBuiltinPoliciesWithConftest(t *testing.T) {
+	if _, err := exec.LookPath("conftest"); err != nil {
+		t.Skip("conftest not available")
+	}
+	stubPlanJSON(t, unlabeledBucketPlan)
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: t.TempDir()}
+	err := checkPlanPolicies(ctx, "tfplan", []string{builtinPolicyDir}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing labels cost-center")
+}
+
+func TestBuiltinPoliciesParse(t *testing.T) {
+	dirs, err := resolvePolicyDirs([]string{builtinPolicyDir}, t.TempDir())
+	require.NoError(t, err)
+
+	// Rego v1, the default of OPA 1.0 and conftest 0.56, drops deny[msg] {
+	entries, err := os.ReadDir(dirs[0])
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dirs[0], entry.Name()))
+		require.NoError(t, err)
+		assert.NotRegexp(t, `(?m)^(deny|warn)\[`, string(data), "%s uses pre-1.0 rule syntax", entry.Name())
+	}
+
+	opa, err := exec.LookPath("opa")
+	if err != nil {
+		t.Skip("opa not available")
+	}
+	output, err := exec.Command(opa, "check", "--strict", dirs[0]).CombinedOutput()
+	assert.NoError(t, err, "opa check failed:\n%s", output)
+}
+
+func stubConftestDenials(t *testing.T, results []policyResult) {
+	t.Helper()
+	original := runConftest
+	t.Cleanup(func() { runConftest = original })
+	runConftest = func(string, []string) ([]byte, error) {
+		return json.Marshal(results)
+	}
+}
+
+func TestPlanPolicyGateHonorsSuppressions(t *testing.T) {
+	stubPlanJSON(t, unlabeledBucketPlan)
+	stubConftestDenials(t, []policyResult{{
+		Namespace: "terraform.guardrails.public_buckets",
+		Failures: []policyMessage{
+			{Msg: "public_buckets: google_storage_bucket_iam_member.docs grants access to allUsers"},
+			{Msg: "bucket is public", Metadata: map[string]interface{}{"id": "PB-7", "resource": "google_storage_bucket_iam_member.site"}},
+		},
+	}})
+
+	suppressions, err := suppress.Parse([]byte(`{"suppressions": [
+		{"rule": "public_buckets", "resource": "google_storage_bucket_iam_member.docs", "expires": "2999-12-31", "justification": "public docs site"},
+		{"id": "PB-7", "justification": "static site"}
+	]}`), "json")
+	require.NoError(t, err)
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: t.TempDir()}
+	assert.NoError(t, checkPlanPolicies(ctx, "tfplan", []string{"/org/policies"}, suppressions))
+}
+
+func TestPlanPolicyGateFailsOnExpiredSuppression(t *testing.T) {
+	stubPlanJSON(t, unlabeledBucketPlan)
+	stubConftestDenials(t, []policyResult{{
+		Namespace: "terraform.guardrails.public_buckets",
+		Failures:  []policyMessage{{Msg: "public_buckets: google_storage_bucket_iam_member.docs grants access to allUsers"}},
+	}})
+
+	suppressions, err := suppress.Parse([]byte(`{"suppressions": [
+		{"rule": "terraform.guardrails.public_buckets", "resource": "google_storage_bucket_iam_member.*", "expires": "2020-01-01", "justification": "temporary"}
+	]}`), "json")
+	require.NoError(t, err)
+
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: t.TempDir()}
+	err = checkPlanPolicies(ctx, "tfplan", []string{"/org/policies"}, suppressions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 policy rule(s)")
+	assert.Contains(t, err.Error(), "google_storage_bucket_iam_member.docs grants access to allUsers")
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultProviderHost is the registry host of providers given as namespace/type
+const defaultProviderHost = "registry.terraform.io"
+
+// registryTimeout bounds each checksum lookup against a provider registry
+const registryTimeout = 30 * time.Second
+
+// ProviderVerificationConfig pins the hashes that .terraform.lock.hcl must
+// record for each provider. Hashes use terraform's h1: (package contents)
+// and zh: (release zip sha256) schemes.
+type ProviderVerificationConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// Hashes maps a provider address (hashicorp/google or
+	// registry.terraform.io/hashicorp/google) to its expected hashes
+	Hashes map[string][]string `json:"hashes" mapstructure:"hashes"`
+	// Registry checks providers without pinned hashes against the checksum
+	// the registry publishes for this platform
+	Registry bool `json:"registry" mapstructure:"registry"`
+}
+
+// lockedProvider is a provider block of .terraform.lock.hcl
+type lockedProvider struct {
+	Address string   `json:"name"`
+	Version string   `json:"version"`
+	Hashes  []string `json:"hashes"`
+}
+
+// registryProviderHashes returns the zh: hash the registry publishes for a
+// provider release on this platform; tests replace it
+var registryProviderHashes = func(ctx context.Context, address, version string) ([]string, error) {
+	parts := strings.Split(address, "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid provider address %q", address)
+	}
+	url := fmt.Sprintf("https://%s/v1/providers/%s/%s/%s/download/%s/%s",
+		parts[0], parts[1], parts[2], version, runtime.GOOS, runtime.GOARCH)
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned status %d for %s %s", resp.StatusCode, address, version)
+	}
+
+	var download struct {
+		Shasum string `json:"shasum"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&download); err != nil {
+		return nil, fmt.Errorf("failed to parse registry response: %w", err)
+	}
+	return []string{"zh:" + download.Shasum}, nil
+}
+
+// normalizeProviderAddress expands namespace/type to the default registry host
+func normalizeProviderAddress(address string) string {
+	if strings.Count(address, "/") == 1 {
+		return defaultProviderHost + "/" + address
+	}
+	return address
+}
+
+// validateProviderHash checks that hash is a well-formed h1: or zh: hash
+func validateProviderHash(hash string) error {
+	scheme, value, ok := strings.Cut(hash, ":")
+	if !ok {
+		return fmt.Errorf("hash %q has no scheme", hash)
+	}
+	switch scheme {
+	case "h1":
+		if decoded, err := base64.StdEncoding.DecodeString(value); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("h1 hash %q is not a base64 sha256 digest", hash)
+		}
+	case "zh":
+		if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("zh hash %q is not a hex sha256 digest", hash)
+		}
+	default:
+		return fmt.Errorf("unsupported hash scheme %q in %q (expected h1 or zh)", scheme, hash)
+	}
+	return nil
+}
+
+// readLockedProviders parses the provider blocks of a lock file
+func readLockedProviders(path string) ([]lockedProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := decodeHCLConfig(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock struct {
+		Providers []lockedProvider `json:"provider"`
+	}
+	if err := decodeValues(values, &lock); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return lock.Providers, nil
+}
+
+// verifyProviderHashes compares the hashes recorded in the module's
+// .terraform.lock.hcl with the expected ones. Pinned hashes are strict: every
+// locked hash of a pinned scheme must be pinned. Registry checksums only need
+// to appear among the locked hashes since they cover a single platform.
+// Without a lock file there is nothing to verify, which fails.
+func verifyProviderHashes(ctx *ExecutionContext) error {
+	verification := ctx.Config.ProviderVerification
+	if !verification.Enabled || ctx.DryRun {
+		return nil
+	}
+
+	lockPath := filepath.Join(ctx.terraformDir(), ".terraform.lock.hcl")
+	providers, err := readLockedProviders(lockPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("provider hash verification failed: %s does not exist", lockPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read provider lock file: %w", err)
+	}
+
+	pinned := make(map[string][]string)
+	for address, hashes := range verification.Hashes {
+		for _, hash := range hashes {
+			if err := validateProviderHash(hash); err != nil {
+				return fmt.Errorf("provider_verification for %s: %w", address, err)
+			}
+		}
+		pinned[normalizeProviderAddress(address)] = hashes
+	}
+
+	var errs []error
+	for _, provider := range providers {
+		address := normalizeProviderAddress(provider.Address)
+		for _, hash := range provider.Hashes {
+			if err := validateProviderHash(hash); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", address, err))
+			}
+		}
+
+		if expected, ok := pinned[address]; ok {
+			if err := checkPinnedHashes(provider.Hashes, expected); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", address, provider.Version, err))
+			}
+			continue
+		}
+
+		if !verification.Registry {
+			logger.Debugf("No expected hashes for provider %s", address)
+			continue
+		}
+		expected, err := registryProviderHashes(ctx.runContext(), address, provider.Version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", address, provider.Version, err))
+			continue
+		}
+		if !containsAny(provider.Hashes, expected) {
+			errs = append(errs, fmt.Errorf("%s %s: lock file hashes do not include the registry checksum %s",
+				address, provider.Version, strings.Join(expected, ", ")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("provider hash verification failed: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// checkPinnedHashes requires locked to share a hash with expected and to hold
+// no hash of a pinned scheme that isn't pinned
+func checkPinnedHashes(locked, expected []string) error {
+	allowed := make(map[string]bool)
+	schemes := make(map[string]bool)
+	for _, hash := range expected {
+		allowed[hash] = true
+		scheme, _, _ := strings.Cut(hash, ":")
+		schemes[scheme] = true
+	}
+
+	var unexpected []string
+	for _, hash := range locked {
+		scheme, _, _ := strings.Cut(hash, ":")
+		if schemes[scheme] && !allowed[hash] {
+			unexpected = append(unexpected, hash)
+		}
+	}
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+		return fmt.Errorf("lock file has unexpected hashes %s", strings.Join(unexpected, ", "))
+	}
+	if !containsAny(locked, expected) {
+		return fmt.Errorf("lock file hashes match none of the expected hashes")
+	}
+	return nil
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
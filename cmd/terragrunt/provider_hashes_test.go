@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testH1(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return "h1:" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func testZH(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return "zh:" + hex.EncodeToString(sum[:])
+}
+
+func lockFileContext(t *testing.T, hashes ...string) *ExecutionContext {
+	t.Helper()
+	dir := t.TempDir()
+	quoted := ""
+	for _, hash := range hashes {
+		quoted += fmt.Sprintf("    %q,\n", hash)
+	}
+	lock := fmt.Sprintf(`
+provider "registry.terraform.io/hashicorp/google" {
+  version     = "5.10.0"
+  constraints = "~> 5.0"
+  hashes = [
+%s  ]
+}
+`, quoted)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(lock), 0644))
+
+	config := defaultTerragruntConfig()
+	config.ProviderVerification.Enabled = true
+	return &ExecutionContext{Config: config, WorkingDir: dir}
+}
+
+func TestVerifyProviderHashesMatching(t *testing.T) {
+	ctx := lockFileContext(t, testH1("linux"), testZH("linux"), testZH("darwin"))
+	ctx.Config.ProviderVerification.Hashes = map[string][]string{
+		"hashicorp/google": {testH1("linux"), testZH("linux"), testZH("darwin"), testZH("windows")},
+	}
+	assert.NoError(t, verifyProviderHashes(ctx))
+
+	// Pinning only one scheme leaves the other unchecked
+	ctx.Config.ProviderVerification.Hashes = map[string][]string{
+		"registry.terraform.io/hashicorp/google": {testH1("linux")},
+	}
+	assert.NoError(t, verifyProviderHashes(ctx))
+}
+
+func TestVerifyProviderHashesMismatching(t *testing.T) {
+	ctx := lockFileContext(t, testH1("tampered"), testZH("linux"))
+	ctx.Config.ProviderVerification.Hashes = map[string][]string{
+		"hashicorp/google": {testH1("linux"), testZH("linux")},
+	}
+	err := verifyProviderHashes(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "registry.terraform.io/hashicorp/google 5.10.0: lock file has unexpected hashes "+testH1("tampered"))
+
+	ctx = lockFileContext(t, testZH("tampered"))
+	ctx.Config.ProviderVerification.Hashes = map[string][]string{
+		"hashicorp/google": {testH1("linux")},
+	}
+	err = verifyProviderHashes(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "match none of the expected hashes")
+}
+
+func TestVerifyProviderHashesAgainstRegistry(t *testing.T) {
+	original := registryProviderHashes
+	t.Cleanup(func() { registryProviderHashes = original })
+	var queried []string
+	registryProviderHashes = func(_ context.Context, address, version string) ([]string, error) {
+		queried = append(queried, address+"@"+version)
+		return []string{testZH("linux")}, nil
+	}
+
+	ctx := lockFileContext(t, testH1("linux"), testZH("linux"), testZH("darwin"))
+	ctx.Config.ProviderVerification.Registry = true
+	require.NoError(t, verifyProviderHashes(ctx))
+	assert.Equal(t, []string{"registry.terraform.io/hashicorp/google@5.10.0"}, queried)
+
+	ctx = lockFileContext(t, testH1("linux"), testZH("darwin"))
+	ctx.Config.ProviderVerification.Registry = true
+	err := verifyProviderHashes(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "do not include the registry checksum")
+}
+
+func TestVerifyProviderHashesWithoutLockFile(t *testing.T) {
+	config := defaultTerragruntConfig()
+	config.ProviderVerification.Enabled = true
+	ctx := &ExecutionContext{Config: config, WorkingDir: t.TempDir()}
+
+	err := verifyProviderHashes(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ".terraform.lock.hcl does not exist")
+
+	// A dry run never runs init, so there is no lock file to look at
+	ctx.DryRun = true
+	assert.NoError(t, verifyProviderHashes(ctx))
+}
+
+func TestRegistryProviderHashesHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := registryProviderHashes(ctx, "registry.terraform.io/hashicorp/google", "5.10.0")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestValidateProviderHash(t *testing.T) {
+	assert.NoError(t, validateProviderHash(testH1("x")))
+	assert.NoError(t, validateProviderHash(testZH("x")))
+	assert.Error(t, validateProviderHash("h1:not-base64"))
+	assert.Error(t, validateProviderHash("zh:abc"))
+	assert.Error(t, validateProviderHash("md5:abc"))
+	assert.Error(t, validateProviderHash("abc"))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ProviderPatchConfig sets the provider attributes aws-provider-patch writes
+// into the modules terraform downloaded. Keys may name a nested block
+// attribute, e.g. assume_role.role_arn.
+type ProviderPatchConfig struct {
+	Provider   string            `json:"provider" mapstructure:"provider"`
+	Attributes map[string]string `json:"attributes" mapstructure:"attributes"`
+}
+
+func runAWSProviderPatch(cmd *cobra.Command, args []string) error {
+	ctx, err := createExecutionContext(cmd)
+	if err != nil {
+		return err
+	}
+	if err := prepareTerraformDir(ctx); err != nil {
+		return err
+	}
+
+	overrides, _ := cmd.Flags().GetStringSlice("terragrunt-override-attr")
+	provider, _ := cmd.Flags().GetString("provider")
+
+	provider, attributes, err := providerPatchAttributes(ctx.Config, provider, overrides)
+	if err != nil {
+		return err
+	}
+
+	patched, err := patchModuleProviders(ctx.terraformDir(), provider, attributes)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range patched {
+		logger.Infof("Patched %s provider in %s", provider, file)
+	}
+	if len(patched) == 0 {
+		logger.Infof("No %s provider blocks found in downloaded modules", provider)
+	}
+	return nil
+}
+
+// providerPatchAttributes resolves which provider to patch and with what.
+// --terragrunt-override-attr KEY=VALUE flags win over provider_patch config;
+// the google providers fall back to the project and region of the gcp block.
+func providerPatchAttributes(config *TerragruntConfig, provider string, overrides []string) (string, map[string]string, error) {
+	if provider == "" {
+		provider = config.ProviderPatch.Provider
+	}
+	if provider == "" {
+		provider = "aws"
+	}
+
+	attributes := make(map[string]string)
+	for key, value := range config.ProviderPatch.Attributes {
+		attributes[key] = value
+	}
+	for _, override := range overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || key == "" {
+			return "", nil, fmt.Errorf("invalid override %q (expected KEY=VALUE)", override)
+		}
+		attributes[key] = value
+	}
+
+	if len(attributes) == 0 && strings.HasPrefix(provider, "google") {
+		if config.GCP.Project != "" {
+			attributes["project"] = config.GCP.Project
+		}
+		if config.GCP.Region != "" {
+			attributes["region"] = config.GCP.Region
+		}
+	}
+
+	if len(attributes) == 0 {
+		return "", nil, fmt.Errorf("no attributes to patch; pass --terragrunt-override-attr KEY=VALUE or set provider_patch.attributes")
+	}
+	return provider, attributes, nil
+}
+
+// patchModuleProviders rewrites the named provider blocks in every .tf file
+// under .terraform/modules, returning the files that changed
+func patchModuleProviders(workingDir, provider string, attributes map[string]string) ([]string, error) {
+	modulesDir := filepath.Join(workingDir, ".terraform", "modules")
+	if _, err := os.Stat(modulesDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not exist; run terragrunt init first", modulesDir)
+	}
+
+	var patched []string
+	err := filepath.Walk(modulesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".tf") {
+			return nil
+		}
+
+		changed, err := patchProviderFile(path, provider, attributes)
+		if err != nil {
+			return err
+		}
+		if changed {
+			patched = append(patched, path)
+		}
+		return nil
+	})
+	return patched, err
+}
+
+// patchProviderFile sets attributes on each provider block of the given type
+// in path, writing the file back only when something changed
+func patchProviderFile(path, provider string, attributes map[string]string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	file, diags := hclwrite.ParseConfig(data, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return false, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	// Plain attributes first so new nested blocks end up after them
+	sort.Slice(keys, func(i, j int) bool {
+		di, dj := strings.Count(keys[i], "."), strings.Count(keys[j], ".")
+		if di != dj {
+			return di < dj
+		}
+		return keys[i] < keys[j]
+	})
+
+	found := false
+	for _, block := range file.Body().Blocks() {
+		if block.Type() != "provider" || len(block.Labels()) == 0 || block.Labels()[0] != provider {
+			continue
+		}
+		found = true
+		for _, key := range keys {
+			setNestedAttribute(block.Body(), strings.Split(key, "."), patchValue(attributes[key]))
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	updated := hclwrite.Format(file.Bytes())
+	if string(updated) == string(data) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, updated, 0644)
+}
+
+// setNestedAttribute sets path[len-1] inside the nested blocks named by the
+// rest of path, creating blocks that don't exist yet
+func setNestedAttribute(body *hclwrite.Body, path []string, value cty.Value) {
+	for _, name := range path[:len(path)-1] {
+		block := body.FirstMatchingBlock(name, nil)
+		if block == nil {
+			block = body.AppendNewBlock(name, nil)
+		}
+		body = block.Body()
+	}
+	body.SetAttributeValue(path[len(path)-1], value)
+}
+
+// patchValue interprets an override as an HCL literal (true, 3, ["a"]) and
+// falls back to a plain string for anything else, such as europe-west1.
+// Digits with a leading zero, like a billing account or project number
+// 012345678901, stay strings: as numbers they would lose the zero.
+func patchValue(raw string) cty.Value {
+	if hasLeadingZero(raw) {
+		return cty.StringVal(raw)
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(raw), "override", hcl.InitialPos)
+	if !diags.HasErrors() {
+		if value, diags := expr.Value(nil); !diags.HasErrors() && value.IsWhollyKnown() {
+			return value
+		}
+	}
+	return cty.StringVal(raw)
+}
+
+// hasLeadingZero reports whether raw starts with a zero followed by a digit
+func hasLeadingZero(raw string) bool {
+	digits := strings.TrimPrefix(strings.TrimSpace(raw), "-")
+	return len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9'
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func writeDownloadedModule(t *testing.T, content string) (string, string) {
+	t.Helper()
+	workingDir := t.TempDir()
+	dir := filepath.Join(workingDir, ".terraform", "modules", "vpc")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, "providers.tf")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return workingDir, path
+}
+
+func TestPatchModuleProvidersGoogle(t *testing.T) {
+	workingDir, path := writeDownloadedModule(t, `provider "google" {
+  project = "module-default"
+  region  = "us-central1"
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+`)
+
+	config := defaultTerragruntConfig()
+	config.GCP.Project = "my-project"
+	config.GCP.Region = "europe-west1"
+	provider, attributes, err := providerPatchAttributes(config, "google", nil)
+	require.NoError(t, err)
+
+	patched, err := patchModuleProviders(workingDir, provider, attributes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, patched)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `provider "google" {
+  project = "my-project"
+  region  = "europe-west1"
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+`, string(data))
+
+	// Patching again is a no-op
+	patched, err = patchModuleProviders(workingDir, provider, attributes)
+	require.NoError(t, err)
+	assert.Empty(t, patched)
+}
+
+func TestPatchModuleProvidersNestedAttributes(t *testing.T) {
+	workingDir, path := writeDownloadedModule(t, `provider "aws" {
+  region = "us-east-1"
+}
+`)
+
+	config := defaultTerragruntConfig()
+	config.ProviderPatch.Attributes = map[string]string{"region": "eu-west-1"}
+	provider, attributes, err := providerPatchAttributes(config, "", []string{
+		"assume_role.role_arn=arn:aws:iam::123456789012:role/deploy",
+		"skip_metadata_api_check=true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "aws", provider)
+
+	_, err = patchModuleProviders(workingDir, provider, attributes)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `provider "aws" {
+  region                  = "eu-west-1"
+  skip_metadata_api_check = true
+  assume_role {
+    role_arn = "arn:aws:iam::123456789012:role/deploy"
+  }
+}
+`, string(data))
+}
+
+func TestProviderPatchAttributesErrors(t *testing.T) {
+	config := defaultTerragruntConfig()
+	_, _, err := providerPatchAttributes(config, "aws", nil)
+	assert.Error(t, err)
+
+	_, _, err = providerPatchAttributes(config, "aws", []string{"region"})
+	assert.Error(t, err)
+
+	_, err = patchModuleProviders(t.TempDir(), "aws", map[string]string{"region": "x"})
+	assert.ErrorContains(t, err, "run terragrunt init first")
+}
+
+func TestPatchValueKeepsLeadingZeros(t *testing.T) {
+	assert.Equal(t, cty.StringVal("012345678901"), patchValue("012345678901"))
+	assert.Equal(t, cty.StringVal("-01"), patchValue("-01"))
+	assert.True(t, cty.NumberIntVal(123456789012).RawEquals(patchValue("123456789012")))
+	assert.True(t, cty.NumberIntVal(0).RawEquals(patchValue("0")))
+	assert.True(t, cty.NumberFloatVal(0.5).RawEquals(patchValue("0.5")))
+	assert.Equal(t, cty.StringVal("europe-west1"), patchValue("europe-west1"))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// queueGroups resolves --terragrunt-queue-include-dir into groups of
+// modules, one per entry and in the order given. Entries are directories
+// or globs relative to the working directory; a group holds the modules
+// under any directory the entry names, leaving out those an earlier group
+// already took. Entries naming nothing that exists are an error.
+func queueGroups(ctx *ExecutionContext, modules []string) ([][]string, error) {
+	queued := make(map[string]bool)
+	groups := make([][]string, 0, len(ctx.Config.QueueIncludeDirs))
+	for _, entry := range ctx.Config.QueueIncludeDirs {
+		dirs, err := queueEntryDirs(ctx.WorkingDir, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		var group []string
+		for _, module := range modules {
+			if queued[module] || !underAnyDir(module, dirs) {
+				continue
+			}
+			queued[module] = true
+			group = append(group, module)
+		}
+		if len(group) == 0 {
+			logger.Warnf("Queue entry %s has no modules left to run", entry)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// queueEntryDirs returns the directories a queue entry names
+func queueEntryDirs(workingDir, entry string) ([]string, error) {
+	path := entry
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+
+	if !strings.ContainsAny(entry, "*?[") {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("queue dir %s does not exist", entry)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("queue dir %s is not a directory", entry)
+		}
+		return []string{filepath.Clean(path)}, nil
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue glob %s: %w", entry, err)
+	}
+	var dirs []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			dirs = append(dirs, match)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("queue glob %s matches no directories", entry)
+	}
+	return dirs, nil
+}
+
+func underAnyDir(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// queuedExecutionOrder splits order, a dependency order of graph, into the
+// queue groups. Modules the queue doesn't name, such as pulled-in external
+// dependencies, run with the first group. Dependencies on a later group
+// can't be honored and are only warned about.
+func queuedExecutionOrder(graph map[string][]string, order []string, queue [][]string) [][]string {
+	groupOf := make(map[string]int)
+	for i, group := range queue {
+		for _, module := range group {
+			groupOf[module] = i
+		}
+	}
+
+	groups := make([][]string, len(queue))
+	for _, module := range order {
+		i := groupOf[module]
+		groups[i] = append(groups[i], module)
+		for _, dep := range graph[module] {
+			if j, ok := groupOf[dep]; ok && j > i {
+				logger.Warnf("%s depends on %s, which is queued after it", module, dep)
+			}
+		}
+	}
+	return groups
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeQueueTree creates network/{vpc,subnet}, apps/{api,web} and
+// tools/ci, with subnet depending on vpc and web on api
+func writeQueueTree(t *testing.T) *ExecutionContext {
+	t.Helper()
+	root := t.TempDir()
+	newDependencyModule(t, root, "network/vpc", "")
+	newDependencyModule(t, root, "network/subnet", "dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n")
+	newDependencyModule(t, root, "apps/web", "dependency \"api\" {\n  config_path = \"../api\"\n}\n")
+	newDependencyModule(t, root, "apps/api", "")
+	newDependencyModule(t, root, "tools/ci", "")
+
+	config := defaultTerragruntConfig()
+	config.Parallelism = 1
+	return &ExecutionContext{Config: config, WorkingDir: root, Environment: map[string]string{}, shared: &runState{}}
+}
+
+// queuedOrder resolves the queue of ctx into groups of module paths
+// relative to the working directory
+func queuedOrder(t *testing.T, ctx *ExecutionContext) [][]string {
+	t.Helper()
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	queue, err := queueGroups(ctx, modules)
+	require.NoError(t, err)
+	var queued []string
+	for _, group := range queue {
+		queued = append(queued, group...)
+	}
+	graph, err := buildDependencyGraph(ctx, queued)
+	require.NoError(t, err)
+	order, err := topologicalSort(graph)
+	require.NoError(t, err)
+
+	groups := queuedExecutionOrder(graph, order, queue)
+	relative := make([][]string, len(groups))
+	for i, group := range groups {
+		for _, module := range group {
+			rel, err := filepath.Rel(ctx.WorkingDir, module)
+			require.NoError(t, err)
+			relative[i] = append(relative[i], filepath.ToSlash(rel))
+		}
+	}
+	return relative
+}
+
+func TestQueueGroupsFollowQueueOrder(t *testing.T) {
+	ctx := writeQueueTree(t)
+	ctx.Config.QueueIncludeDirs = []string{"network", "apps/*"}
+
+	assert.Equal(t, [][]string{
+		{"network/vpc", "network/subnet"},
+		{"apps/api", "apps/web"},
+	}, queuedOrder(t, ctx))
+
+	// Reversing the queue reverses the groups but not the order inside them
+	ctx.Config.QueueIncludeDirs = []string{"apps", "network"}
+	assert.Equal(t, [][]string{
+		{"apps/api", "apps/web"},
+		{"network/vpc", "network/subnet"},
+	}, queuedOrder(t, ctx))
+
+	// A module goes to the first entry naming it
+	ctx.Config.QueueIncludeDirs = []string{"apps/web", "apps"}
+	assert.Equal(t, [][]string{{"apps/web"}, {"apps/api"}}, queuedOrder(t, ctx))
+}
+
+func TestQueueGroupsRejectUnknownDirs(t *testing.T) {
+	ctx := writeQueueTree(t)
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+
+	ctx.Config.QueueIncludeDirs = []string{"network", "databases"}
+	_, err = queueGroups(ctx, modules)
+	assert.EqualError(t, err, "queue dir databases does not exist")
+
+	ctx.Config.QueueIncludeDirs = []string{"apps/*/nothing*"}
+	_, err = queueGroups(ctx, modules)
+	assert.EqualError(t, err, "queue glob apps/*/nothing* matches no directories")
+}
+
+func TestRunModulesRunsQueueGroupsInOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	ctx := writeQueueTree(t)
+	ctx.Config.QueueIncludeDirs = []string{"apps", "network"}
+
+	log := filepath.Join(t.TempDir(), "order.log")
+	terraform := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\necho \"$(basename \"$(dirname \"$PWD\")\")/$(basename \"$PWD\")\" >> " + log + "\n"
+	require.NoError(t, os.WriteFile(terraform, []byte(script), 0755))
+	ctx.Config.TerraformPath = terraform
+
+	groups := queuedOrder(t, ctx)
+	for _, group := range groups {
+		var modules []string
+		for _, module := range group {
+			modules = append(modules, filepath.Join(ctx.WorkingDir, module))
+		}
+		runModules(ctx, modules, "plan", nil)
+	}
+	require.Empty(t, ctx.Errors())
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps/api", "apps/web", "network/vpc", "network/subnet"}, strings.Fields(string(data)))
+}
+
+// eventRunner records when each module's command starts and ends, taking
+// a while over each so that unordered modules would overlap, and fails
+// the modules in fail
+type eventRunner struct {
+	mu     sync.Mutex
+	events []string
+	fail   map[string]bool
+}
+
+func (r *eventRunner) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+func (r *eventRunner) Run(_ context.Context, cmd runnerCommand) error {
+	module := filepath.Base(filepath.Dir(cmd.Dir)) + "/" + filepath.Base(cmd.Dir)
+	r.record("start " + module)
+	time.Sleep(20 * time.Millisecond)
+	r.record("end " + module)
+	if r.fail[module] {
+		return fmt.Errorf("plan failed")
+	}
+	return nil
+}
+
+func (r *eventRunner) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRunner) index(event string) int {
+	for i, recorded := range r.events {
+		if recorded == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRunModulesWaitsForDependenciesInParallel(t *testing.T) {
+	withPartialParseCache(t)
+	ctx := writeQueueTree(t)
+	ctx.Config.Parallelism = 5
+	ctx.Config.AutoInit = false
+	runner := &eventRunner{fail: map[string]bool{"apps/api": true}}
+	original := terraformRunner
+	terraformRunner = runner
+	t.Cleanup(func() { terraformRunner = original })
+
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	graph, err := buildDependencyGraph(ctx, modules)
+	require.NoError(t, err)
+	order, err := topologicalSort(graph)
+	require.NoError(t, err)
+	runModules(ctx, order, "plan", graph)
+
+	// Independent modules overlap, but subnet waits for vpc to finish
+	assert.Less(t, runner.index("start tools/ci"), runner.index("end network/vpc"))
+	assert.Less(t, runner.index("end network/vpc"), runner.index("start network/subnet"))
+
+	// web is not run after api failed
+	assert.Equal(t, -1, runner.index("start apps/web"))
+	errs := ctx.Errors()
+	require.Len(t, errs, 2)
+	assert.ElementsMatch(t, []string{
+		"module " + filepath.Join(ctx.WorkingDir, "apps/api") + ": plan failed",
+		"module " + filepath.Join(ctx.WorkingDir, "apps/web") + ": not run because dependency " + filepath.Join(ctx.WorkingDir, "apps/api") + " failed",
+	}, []string{errs[0].Error(), errs[1].Error()})
+}
+
+func TestDestroyRunsInReverseOrder(t *testing.T) {
+	withPartialParseCache(t)
+	ctx := writeQueueTree(t)
+	ctx.Config.QueueIncludeDirs = []string{"network", "apps"}
+	ctx.Config.Parallelism = 5
+	ctx.Config.AutoInit = false
+	runner := &eventRunner{}
+	original := terraformRunner
+	terraformRunner = runner
+	t.Cleanup(func() { terraformRunner = original })
+
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	queue, err := queueGroups(ctx, modules)
+	require.NoError(t, err)
+	var queued []string
+	for _, group := range queue {
+		queued = append(queued, group...)
+	}
+	graph, err := buildDependencyGraph(ctx, queued)
+	require.NoError(t, err)
+	order, err := topologicalSort(graph)
+	require.NoError(t, err)
+
+	groups, dependents := destroyOrder(queuedExecutionOrder(graph, order, queue), graph)
+	var relGroups [][]string
+	for _, group := range groups {
+		var names []string
+		for _, module := range group {
+			rel, err := filepath.Rel(ctx.WorkingDir, module)
+			require.NoError(t, err)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		relGroups = append(relGroups, names)
+	}
+	assert.Equal(t, [][]string{{"apps/web", "apps/api"}, {"network/subnet", "network/vpc"}}, relGroups)
+
+	for _, group := range groups {
+		runModules(ctx, group, "destroy", dependents)
+	}
+	require.Empty(t, ctx.Errors())
+
+	// Dependents are destroyed before what they depend on, a group at a time
+	assert.Less(t, runner.index("end apps/web"), runner.index("start apps/api"))
+	assert.Less(t, runner.index("end apps/api"), runner.index("start network/subnet"))
+	assert.Less(t, runner.index("end network/subnet"), runner.index("start network/vpc"))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// redactedValue replaces secrets wherever they would be shown
+const redactedValue = "(redacted)"
+
+// minSecretLength keeps short values like "1" or "true" from being masked
+// in every line they happen to appear in
+const minSecretLength = 4
+
+// debugTfvarsFile holds the inputs passed to terraform when running with
+// --terragrunt-debug
+const debugTfvarsFile = "terragrunt-debug.tfvars.json"
+
+// sensitiveNamePattern matches input and environment variable names that
+// hold secrets even when not listed in sensitive_inputs
+var sensitiveNamePattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|private_key|api_key|credentials)`)
+
+// secretRegistry holds the values that must never reach the logs
+type secretRegistry struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// secrets is the registry the log hook and rendered output are scrubbed with
+var secrets = &secretRegistry{}
+
+// register adds value, and every string inside it when it is a list or an
+// object, to the registry
+func (r *secretRegistry) register(value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		if len(v) < minSecretLength {
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.values == nil {
+			r.values = make(map[string]bool)
+		}
+		if !r.values[v] {
+			r.values[v] = true
+			r.replacer = nil
+		}
+	case []interface{}:
+		for _, item := range v {
+			r.register(item)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			r.register(item)
+		}
+	default:
+		r.register(fmt.Sprint(v))
+	}
+}
+
+// redact replaces every registered secret in text, as is and as it appears
+// inside a JSON string
+func (r *secretRegistry) redact(text string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	empty := len(r.values) == 0
+	r.mu.RUnlock()
+	if empty {
+		return text
+	}
+
+	if replacer == nil {
+		r.mu.Lock()
+		if r.replacer == nil {
+			r.replacer = r.newReplacer()
+		}
+		replacer = r.replacer
+		r.mu.Unlock()
+	}
+	return replacer.Replace(text)
+}
+
+// newReplacer must be called with the lock held. Longer secrets go first so
+// one containing another is masked whole.
+func (r *secretRegistry) newReplacer() *strings.Replacer {
+	forms := make(map[string]bool)
+	for value := range r.values {
+		forms[value] = true
+		quoted, _ := json.Marshal(value)
+		forms[string(quoted[1:len(quoted)-1])] = true
+	}
+	sorted := make([]string, 0, len(forms))
+	for form := range forms {
+		sorted = append(sorted, form)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, form := range sorted {
+		pairs = append(pairs, form, redactedValue)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// registerSecret marks value as a secret for the rest of the run
+func registerSecret(value interface{}) {
+	secrets.register(value)
+}
+
+// redactSecrets masks every registered secret in text
+func redactSecrets(text string) string {
+	return secrets.redact(text)
+}
+
+// redactHook scrubs registered secrets from the message and string fields
+// of every entry. It has to be added before hooks that write entries out.
+type redactHook struct {
+	registry *secretRegistry
+}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.registry.redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = h.registry.redact(v)
+		case error:
+			entry.Data[key] = h.registry.redact(v.Error())
+		}
+	}
+	return nil
+}
+
+// sensitiveInput reports whether the input or environment variable name
+// holds a secret
+func sensitiveInput(config *TerragruntConfig, name string) bool {
+	for _, sensitive := range config.SensitiveInputs {
+		if strings.EqualFold(sensitive, name) {
+			return true
+		}
+	}
+	return sensitiveNamePattern.MatchString(name)
+}
+
+// registerConfigSecrets registers the secrets of config, its environment
+// and the -var values of cmd
+func registerConfigSecrets(cmd *cobra.Command, config *TerragruntConfig, env map[string]string) {
+	for name, value := range config.Variables {
+		if sensitiveInput(config, name) {
+			registerSecret(value)
+		}
+	}
+	for name, value := range env {
+		if sensitiveInput(config, name) {
+			registerSecret(value)
+		}
+	}
+	registerSecret(config.Backend.EncryptionKey)
+
+	if cmd == nil || cmd.Flags().Lookup("var") == nil {
+		return
+	}
+	vars, _ := cmd.Flags().GetStringSlice("var")
+	for _, v := range vars {
+		if name, value, ok := strings.Cut(v, "="); ok && sensitiveInput(config, name) {
+			registerSecret(value)
+		}
+	}
+}
+
+// writeDebugTfvars writes the inputs terraform runs with, secrets masked,
+// into the terraform directory for debugging
+func writeDebugTfvars(ctx *ExecutionContext) error {
+	data, err := json.MarshalIndent(ctx.Config.Variables, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode inputs: %w", err)
+	}
+	path := filepath.Join(ctx.terraformDir(), debugTfvarsFile)
+	if err := os.WriteFile(path, []byte(redactSecrets(string(data))+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", debugTfvarsFile, err)
+	}
+	logger.Debugf("Wrote the inputs passed to terraform to %s", path)
+	return nil
+}
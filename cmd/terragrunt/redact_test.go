@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useSecretLogger gives the test its own registry and a debug logger
+// scrubbing it, returning what the logger writes
+func useSecretLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	originalSecrets, originalLogger := secrets, logger
+	secrets = &secretRegistry{}
+
+	var out bytes.Buffer
+	logger = logrus.New()
+	logger.SetOutput(&out)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(&redactHook{registry: secrets})
+	t.Cleanup(func() { secrets, logger = originalSecrets, originalLogger })
+	return &out
+}
+
+func TestRedactHookMasksMessagesAndFields(t *testing.T) {
+	out := useSecretLogger(t)
+	registerSecret("hunter2-password")
+	registerSecret("abc")
+
+	logger.WithField("error", errors.New("auth failed for hunter2-password")).
+		Infof("Executing terraform plan -var=db_password=hunter2-password -var=name=abc")
+
+	assert.NotContains(t, out.String(), "hunter2-password")
+	assert.Contains(t, out.String(), "-var=db_password=(redacted)")
+	assert.Contains(t, out.String(), "auth failed for (redacted)")
+	assert.Contains(t, out.String(), "-var=name=abc", "values shorter than minSecretLength aren't secrets")
+}
+
+func TestRedactSecretsMasksLongestFirstAndJSONForms(t *testing.T) {
+	useSecretLogger(t)
+	registerSecret("token")
+	registerSecret("token-with-suffix")
+	registerSecret(map[string]interface{}{"key": `quo"ted`, "list": []interface{}{12345}})
+
+	assert.Equal(t, "a (redacted) b", redactSecrets("a token-with-suffix b"))
+	assert.Equal(t, `{"key": "(redacted)"}`, redactSecrets(`{"key": "quo\"ted"}`))
+	assert.Equal(t, "port (redacted)", redactSecrets("port 12345"))
+}
+
+func TestRegisterConfigSecrets(t *testing.T) {
+	useSecretLogger(t)
+	config := defaultTerragruntConfig()
+	config.SensitiveInputs = []string{"db_url"}
+	config.Variables = map[string]interface{}{
+		"db_url":      "postgres://admin@db",
+		"api_token":   "tok-123456",
+		"region":      "us-central1",
+		"environment": "production",
+	}
+	config.Backend.EncryptionKey = "c2VjcmV0a2V5"
+	registerConfigSecrets(nil, config, map[string]string{"GITHUB_TOKEN": "ghp_abcdef", "HOME": "/home/ci"})
+
+	line := redactSecrets("postgres://admin@db tok-123456 us-central1 production c2VjcmV0a2V5 ghp_abcdef /home/ci")
+	assert.Equal(t, "(redacted) (redacted) us-central1 production (redacted) (redacted) /home/ci", line)
+}
+
+func TestDebugRunMasksSecretsInCommandLineAndTfvars(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	out := useSecretLogger(t)
+
+	dir := t.TempDir()
+	terraform := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(terraform, []byte("#!/bin/sh\nexit 0\n"), 0755))
+
+	config := defaultTerragruntConfig()
+	config.TerraformPath = terraform
+	config.Debug = true
+	config.SensitiveInputs = []string{"db_password"}
+	config.Variables = map[string]interface{}{"db_password": "s3cr3t-from-secret-manager", "region": "us-central1"}
+	registerConfigSecrets(nil, config, nil)
+	ctx := &ExecutionContext{Config: config, WorkingDir: dir, Environment: map[string]string{}}
+
+	require.NoError(t, executeTerraform(ctx, "plan", "-var=db_password=s3cr3t-from-secret-manager", "-var=region=us-central1"))
+
+	assert.NotContains(t, out.String(), "s3cr3t-from-secret-manager")
+	assert.Contains(t, out.String(), "-var=db_password=(redacted) -var=region=us-central1")
+
+	tfvars, err := os.ReadFile(filepath.Join(dir, debugTfvarsFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(tfvars), "s3cr3t-from-secret-manager")
+	assert.JSONEq(t, `{"db_password": "(redacted)", "region": "us-central1"}`, string(tfvars))
+}
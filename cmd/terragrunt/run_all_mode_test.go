@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// moduleRunner fails terraform in some modules and holds it in others until
+// the run is cancelled, recording the modules it was started in
+type moduleRunner struct {
+	mu      sync.Mutex
+	started []string
+	fail    map[string]bool
+	hold    map[string]bool
+}
+
+func (r *moduleRunner) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+func (r *moduleRunner) Run(ctx context.Context, command runnerCommand) error {
+	module := filepath.Base(command.Dir)
+	r.mu.Lock()
+	r.started = append(r.started, module)
+	r.mu.Unlock()
+
+	switch {
+	case r.fail[module]:
+		return fakeExitError(1)
+	case r.hold[module]:
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (r *moduleRunner) modules() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	modules := append([]string(nil), r.started...)
+	sort.Strings(modules)
+	return modules
+}
+
+// runAllModeContext creates modules a to d and a runner in which a fails
+// and b runs until it is interrupted
+func runAllModeContext(t *testing.T) (*ExecutionContext, []string, *moduleRunner) {
+	t.Helper()
+	root := t.TempDir()
+	var modules []string
+	for _, name := range []string{"a", "b", "c", "d"} {
+		newDependencyModule(t, root, name, "")
+		modules = append(modules, filepath.Join(root, name))
+	}
+
+	runner := &moduleRunner{fail: map[string]bool{"a": true}, hold: map[string]bool{"b": true}}
+	original := terraformRunner
+	terraformRunner = runner
+	t.Cleanup(func() { terraformRunner = original })
+
+	config := defaultTerragruntConfig()
+	config.AutoInit = false
+	config.RetryAttempts = 0
+	config.Parallelism = 2
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Environment: map[string]string{}, shared: &runState{}}
+	return ctx, modules, runner
+}
+
+func TestRunModulesFailFastCancelsPendingModules(t *testing.T) {
+	ctx, modules, runner := runAllModeContext(t)
+	ctx.Config.FailFast = true
+
+	runModules(ctx, modules, "plan", nil)
+
+	errs := ctx.Errors()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), modules[0])
+
+	// b was interrupted, c and d never started
+	skipped := ctx.Skipped()
+	sort.Strings(skipped)
+	assert.Equal(t, modules[1:], skipped)
+	assert.NotContains(t, runner.modules(), "c")
+	assert.NotContains(t, runner.modules(), "d")
+	assert.Error(t, ctx.runContext().Err())
+}
+
+func TestRunModulesContinueOnErrorRunsAllModules(t *testing.T) {
+	ctx, modules, runner := runAllModeContext(t)
+	ctx.Config.ContinueOnError = true
+	// Nothing cancels the run, so b must not wait for it
+	runner.hold = nil
+
+	runModules(ctx, modules, "plan", nil)
+
+	errs := ctx.Errors()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), modules[0])
+	assert.Empty(t, ctx.Skipped())
+	assert.Equal(t, []string{"a", "b", "c", "d"}, runner.modules())
+	assert.NoError(t, ctx.runContext().Err())
+}
+
+func TestRunModulesStopsStartingModulesWhenInterrupted(t *testing.T) {
+	withRunContext(t)
+	ctx, modules, runner := runAllModeContext(t)
+	// a and b take both slots until the run is interrupted
+	runner.fail = nil
+	runner.hold = map[string]bool{"a": true, "b": true}
+
+	finished := make(chan struct{})
+	go func() {
+		runModules(ctx, modules, "plan", nil)
+		close(finished)
+	}()
+	require.Eventually(t, func() bool { return len(runner.modules()) == 2 }, 5*time.Second, time.Millisecond)
+	cancelRun()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run-all hung after the interrupt")
+	}
+	assert.Equal(t, []string{"a", "b"}, runner.modules())
+	skipped := ctx.Skipped()
+	sort.Strings(skipped)
+	assert.Equal(t, modules[2:], skipped)
+}
+
+func TestFailFastAndContinueOnErrorAreExclusive(t *testing.T) {
+	config := defaultTerragruntConfig()
+	config.FailFast = true
+	config.ContinueOnError = true
+	ctx := &ExecutionContext{Config: config, WorkingDir: t.TempDir()}
+
+	assert.Contains(t, configProblems(ctx), "fail_fast and continue_on_error are mutually exclusive")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// runLimits is shared by the modules of a run: a budget of retries and a
+// limiter on terraform executions. The limiter hands out its slots in the
+// order they are asked for, so a module retrying in a loop queues behind the
+// others instead of starving them.
+type runLimits struct {
+	limiter *rate.Limiter
+
+	mu      sync.Mutex
+	budget  int
+	retries int
+}
+
+// newRunLimits returns the limits configured in config; values of 0 or less
+// mean no limit
+func newRunLimits(config *TerragruntConfig) *runLimits {
+	limits := &runLimits{budget: config.RetryBudget}
+	if config.MaxExecRate > 0 {
+		limits.limiter = rate.NewLimiter(rate.Limit(config.MaxExecRate), 1)
+	}
+	return limits
+}
+
+// wait blocks until the run may start another terraform execution
+func (l *runLimits) wait(ctx context.Context) error {
+	if l == nil || l.limiter == nil {
+		return nil
+	}
+	return l.limiter.Wait(ctx)
+}
+
+// takeRetry reports whether the budget allows one more retry, counting it
+func (l *runLimits) takeRetry() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.budget > 0 && l.retries >= l.budget {
+		return false
+	}
+	l.retries++
+	return true
+}
+
+// limits returns the limits of the run, set up from ctx's config the first
+// time they are needed
+func (ctx *ExecutionContext) limits() *runLimits {
+	if ctx.shared == nil {
+		return nil
+	}
+	ctx.shared.mu.Lock()
+	defer ctx.shared.mu.Unlock()
+	if ctx.shared.limits == nil {
+		ctx.shared.limits = newRunLimits(ctx.Config)
+	}
+	return ctx.shared.limits
+}
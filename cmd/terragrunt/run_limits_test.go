@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timingRunner fails every command with a retryable error and records when
+// each one started
+type timingRunner struct {
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+func (r *timingRunner) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+func (r *timingRunner) Run(_ context.Context, _ runnerCommand) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.starts = append(r.starts, time.Now())
+	return fmt.Errorf("Error 503: backend unavailable")
+}
+
+// flakyRunAll sets up a run of n modules that all fail transiently
+func flakyRunAll(t *testing.T, n int) (*ExecutionContext, []string, *timingRunner) {
+	t.Helper()
+	withPartialParseCache(t)
+	root := t.TempDir()
+	var modules []string
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("m%d", i)
+		newDependencyModule(t, root, name, "")
+		modules = append(modules, filepath.Join(root, name))
+	}
+
+	runner := &timingRunner{}
+	original := terraformRunner
+	terraformRunner = runner
+	t.Cleanup(func() { terraformRunner = original })
+
+	config := defaultTerragruntConfig()
+	config.AutoInit = false
+	config.Parallelism = n
+	config.RetryAttempts = 3
+	config.RetryDelay = 0
+	config.ErrorHandling.RetryableErrors = []string{"Error 503"}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root, Environment: map[string]string{}, shared: &runState{}}
+	return ctx, modules, runner
+}
+
+func TestRetryBudgetCapsRetriesAcrossModules(t *testing.T) {
+	ctx, modules, runner := flakyRunAll(t, 10)
+	ctx.Config.RetryBudget = 5
+
+	runModules(ctx, modules, "plan", nil)
+
+	// Every module runs once; only 5 retries are shared out between them
+	assert.Len(t, runner.starts, 15)
+	errs := ctx.Errors()
+	require.Len(t, errs, 10)
+	budgetErrs := 0
+	for _, err := range errs {
+		assert.Contains(t, err.Error(), "Error 503")
+		if strings.Contains(err.Error(), "retry budget of 5 used up") {
+			budgetErrs++
+		}
+	}
+	assert.Positive(t, budgetErrs)
+
+	// Without a budget each module retries on its own
+	ctx, modules, runner = flakyRunAll(t, 10)
+	runModules(ctx, modules, "plan", nil)
+	assert.Len(t, runner.starts, 40)
+}
+
+func TestMaxExecRateLimitsExecutionsAcrossModules(t *testing.T) {
+	ctx, modules, runner := flakyRunAll(t, 8)
+	ctx.Config.RetryAttempts = 1
+	ctx.Config.MaxExecRate = 40
+
+	runModules(ctx, modules, "plan", nil)
+
+	require.Len(t, runner.starts, 16)
+	// Whatever the module, no window of executions runs faster than the rate
+	// allows: the first is free, each later one waits 25ms
+	interval := time.Second / 40
+	slack := 5 * time.Millisecond
+	for i := 1; i < len(runner.starts); i++ {
+		assert.GreaterOrEqual(t, runner.starts[i].Sub(runner.starts[0]), time.Duration(i)*interval-slack,
+			"execution %d started too early", i)
+	}
+}
+
+func TestRunLimitsWithoutLimits(t *testing.T) {
+	limits := newRunLimits(defaultTerragruntConfig())
+	for i := 0; i < 100; i++ {
+		assert.True(t, limits.takeRetry())
+	}
+	assert.NoError(t, limits.wait(context.Background()))
+
+	// Contexts outside a run have no limits at all
+	var none *runLimits
+	assert.True(t, none.takeRetry())
+	assert.NoError(t, none.wait(context.Background()))
+	assert.Nil(t, (&ExecutionContext{Config: defaultTerragruntConfig()}).limits())
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Versions pinned in scaffolded modules unless the config says otherwise
+const (
+	defaultScaffoldTerraformVersion = ">= 1.5.0"
+	defaultScaffoldProviderVersion  = "~> 5.0"
+)
+
+// ScaffoldVersions are the terraform and provider version constraints
+// written to a scaffolded module's versions.tf
+type ScaffoldVersions struct {
+	Terraform  string `json:"terraform" mapstructure:"terraform"`
+	Google     string `json:"google" mapstructure:"google"`
+	GoogleBeta string `json:"google_beta" mapstructure:"google_beta"`
+}
+
+// ScaffoldConfig configures scaffold; Templates overrides the versions of
+// individual templates, field by field
+type ScaffoldConfig struct {
+	Versions  ScaffoldVersions            `json:"versions" mapstructure:"versions"`
+	Templates map[string]ScaffoldVersions `json:"templates" mapstructure:"templates"`
+}
+
+// versionsFor returns the versions to pin for a template
+func (c ScaffoldConfig) versionsFor(template string) ScaffoldVersions {
+	versions := ScaffoldVersions{
+		Terraform:  defaultScaffoldTerraformVersion,
+		Google:     defaultScaffoldProviderVersion,
+		GoogleBeta: defaultScaffoldProviderVersion,
+	}
+	for _, override := range []ScaffoldVersions{c.Versions, c.Templates[template]} {
+		if override.Terraform != "" {
+			versions.Terraform = override.Terraform
+		}
+		if override.Google != "" {
+			versions.Google = override.Google
+		}
+		if override.GoogleBeta != "" {
+			versions.GoogleBeta = override.GoogleBeta
+		}
+	}
+	return versions
+}
+
+func generateVersionsTF(versions ScaffoldVersions) string {
+	return fmt.Sprintf(`terraform {
+  required_version = %q
+
+  required_providers {
+    google = {
+      source  = "hashicorp/google"
+      version = %q
+    }
+    google-beta = {
+      source  = "hashicorp/google-beta"
+      version = %q
+    }
+  }
+}
+`, versions.Terraform, versions.Google, versions.GoogleBeta)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseVersionsTF returns the required_version and the source and version
+// of each required provider in a versions.tf
+func parseVersionsTF(t *testing.T, path string) (string, map[string][2]string) {
+	t.Helper()
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	content, diags := file.Body.Content(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}}})
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, content.Blocks, 1)
+
+	terraform, diags := content.Blocks[0].Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "required_version", Required: true}},
+		Blocks:     []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+	})
+	require.False(t, diags.HasErrors(), diags.Error())
+	requiredVersion, diags := terraform.Attributes["required_version"].Expr.Value(nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, terraform.Blocks, 1)
+
+	attrs, diags := terraform.Blocks[0].Body.JustAttributes()
+	require.False(t, diags.HasErrors(), diags.Error())
+	providers := make(map[string][2]string)
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		require.False(t, diags.HasErrors(), diags.Error())
+		providers[name] = [2]string{value.GetAttr("source").AsString(), value.GetAttr("version").AsString()}
+	}
+	return requiredVersion.AsString(), providers
+}
+
+func TestScaffoldWritesVersionsTF(t *testing.T) {
+	dir := t.TempDir()
+	cmd := &cobra.Command{Use: "scaffold", RunE: runScaffold}
+	registerGlobalFlags(cmd.Flags())
+	cmd.Flags().AddFlagSet(scaffoldCmd.Flags())
+	require.NoError(t, cmd.Flags().Set("terragrunt-working-dir", dir))
+	require.NoError(t, cmd.Flags().Set("name", "web"))
+	require.NoError(t, cmd.Flags().Set("with-examples", "true"))
+	require.NoError(t, cmd.RunE(cmd, nil))
+
+	for _, path := range []string{
+		filepath.Join(dir, "web", "versions.tf"),
+		filepath.Join(dir, "web", "examples", "versions.tf"),
+	} {
+		requiredVersion, providers := parseVersionsTF(t, path)
+		assert.Equal(t, ">= 1.5.0", requiredVersion, path)
+		assert.Equal(t, map[string][2]string{
+			"google":      {"hashicorp/google", "~> 5.0"},
+			"google-beta": {"hashicorp/google-beta", "~> 5.0"},
+		}, providers, path)
+	}
+}
+
+func TestScaffoldVersionsPerTemplate(t *testing.T) {
+	config := ScaffoldConfig{
+		Versions: ScaffoldVersions{Terraform: ">= 1.6.0", Google: "~> 6.0"},
+		Templates: map[string]ScaffoldVersions{
+			"gke": {GoogleBeta: "= 6.12.0"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "versions.tf")
+	require.NoError(t, os.WriteFile(path, []byte(generateVersionsTF(config.versionsFor("gke"))), 0644))
+	requiredVersion, providers := parseVersionsTF(t, path)
+	assert.Equal(t, ">= 1.6.0", requiredVersion)
+	assert.Equal(t, "~> 6.0", providers["google"][1])
+	assert.Equal(t, "= 6.12.0", providers["google-beta"][1])
+
+	// Other templates only get the shared overrides
+	assert.Equal(t, ScaffoldVersions{Terraform: ">= 1.6.0", Google: "~> 6.0", GoogleBeta: "~> 5.0"}, config.versionsFor("default"))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/termcolor"
+)
+
+// runCtx is cancelled when terragrunt is interrupted so downloads and
+// terraform runs stop and deferred cleanup gets to run
+var runCtx, cancelRun = context.WithCancel(context.Background())
+
+// interruptedAtTerminal is set when the run was interrupted with Ctrl-C at
+// a terminal, which sends SIGINT to the whole foreground process group, so
+// terraform has already received it
+var interruptedAtTerminal atomic.Bool
+
+// tempDirRegistry tracks temporary directories so an interrupt can remove
+// them even when the code that created them is still blocked
+type tempDirRegistry struct {
+	mu   sync.Mutex
+	dirs map[string]struct{}
+}
+
+var tempDirs = &tempDirRegistry{dirs: make(map[string]struct{})}
+
+func (r *tempDirRegistry) add(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirs[dir] = struct{}{}
+}
+
+// remove deletes dir and stops tracking it
+func (r *tempDirRegistry) remove(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.dirs, dir)
+	os.RemoveAll(dir)
+}
+
+// removeAll deletes every tracked directory
+func (r *tempDirRegistry) removeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for dir := range r.dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warnf("Failed to remove %s: %v", dir, err)
+		}
+		delete(r.dirs, dir)
+	}
+}
+
+// makeTempDir creates a temporary directory that is removed by the returned
+// function or on interrupt, whichever comes first
+func makeTempDir(pattern string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	tempDirs.add(dir)
+	return dir, func() { tempDirs.remove(dir) }, nil
+}
+
+// handleInterrupt cancels the run and removes temporary directories
+func handleInterrupt() {
+	cancelRun()
+	tempDirs.removeAll()
+}
+
+// handleSignals cancels the run on the first interrupt and lets the command
+// unwind; a second interrupt exits immediately after cleaning up
+func handleSignals() {
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		if sig := <-sigChan; sig == os.Interrupt && termcolor.IsTerminal(os.Stdin) {
+			interruptedAtTerminal.Store(true)
+		}
+		logger.Info("Received interrupt signal, cleaning up...")
+		handleInterrupt()
+
+		<-sigChan
+		logger.Warn("Received second interrupt signal, exiting")
+		tempDirs.removeAll()
+		os.Exit(1)
+	}()
+}
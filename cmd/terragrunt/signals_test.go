@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withRunContext(t *testing.T) {
+	t.Helper()
+	originalCtx, originalCancel := runCtx, cancelRun
+	runCtx, cancelRun = context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancelRun()
+		runCtx, cancelRun = originalCtx, originalCancel
+	})
+}
+
+func trackedTempDirs() []string {
+	tempDirs.mu.Lock()
+	defer tempDirs.mu.Unlock()
+	var dirs []string
+	for dir := range tempDirs.dirs {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+func TestInterruptDuringDownloadRemovesTempDir(t *testing.T) {
+	withRunContext(t)
+
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("PK partial archive"))
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	originalURL := terraformReleasesURL
+	terraformReleasesURL = server.URL
+	t.Cleanup(func() { terraformReleasesURL = originalURL })
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), Logger: logger}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := downloadTerraform(ctx, "1.5.7")
+		done <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("download never started")
+	}
+
+	dirs := trackedTempDirs()
+	require.Len(t, dirs, 1)
+	assert.DirExists(t, dirs[0])
+
+	handleInterrupt()
+	assert.NoDirExists(t, dirs[0])
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("download did not stop after interrupt")
+	}
+	assert.NoDirExists(t, dirs[0])
+	assert.Empty(t, trackedTempDirs())
+}
+
+func TestMakeTempDirCleanup(t *testing.T) {
+	dir, remove, err := makeTempDir("terragrunt-test-")
+	require.NoError(t, err)
+	assert.Contains(t, trackedTempDirs(), dir)
+
+	remove()
+	assert.NoDirExists(t, dir)
+	assert.NotContains(t, trackedTempDirs(), dir)
+}
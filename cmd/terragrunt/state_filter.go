@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"cloud.google.com/go/storage"
+)
+
+// smallStateSize bounds the state objects read to count their resources. An
+// empty state is a couple of hundred bytes, so anything larger is assumed
+// to hold resources without downloading it.
+const smallStateSize = 4096
+
+// stateObjectSize returns the size of a state object in GCS, or
+// storage.ErrObjectNotExist; tests replace it
+var stateObjectSize = func(ctx context.Context, bucket, object string) (int64, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	defer client.Close()
+
+	attrs, err := client.Bucket(bucket).Object(object).Attrs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return attrs.Size, nil
+}
+
+// filterModulesByState applies --terragrunt-only-with-state and
+// --terragrunt-only-without-state to modules, keeping their order. Modules
+// are checked concurrently, at most Parallelism at a time.
+func filterModulesByState(ctx *ExecutionContext, modules []string) ([]string, error) {
+	config := ctx.Config
+	if config.OnlyWithState && config.OnlyWithoutState {
+		return nil, fmt.Errorf("--terragrunt-only-with-state and --terragrunt-only-without-state are mutually exclusive")
+	}
+	if !config.OnlyWithState && !config.OnlyWithoutState {
+		return modules, nil
+	}
+
+	workers := config.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	hasState := make([]bool, len(modules))
+	errs := make([]error, len(modules))
+
+	var wg sync.WaitGroup
+	for i, module := range modules {
+		wg.Add(1)
+		go func(i int, module string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			hasState[i], errs[i] = moduleHasState(ctx, module)
+		}(i, module)
+	}
+	wg.Wait()
+
+	filtered := []string{}
+	for i, module := range modules {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to check state of %s: %w", module, errs[i])
+		}
+		if hasState[i] == config.OnlyWithState {
+			filtered = append(filtered, module)
+		} else {
+			logger.Debugf("Skipping %s: has state = %t", module, hasState[i])
+		}
+	}
+	return filtered, nil
+}
+
+// moduleHasState reports whether a module's state exists and tracks at least
+// one resource. The state lives in the module's gcs backend when it has
+// one and otherwise in terraform.tfstate where terraform last ran for it,
+// which is its cache copy when it has one.
+func moduleHasState(ctx *ExecutionContext, module string) (bool, error) {
+	config := defaultTerragruntConfig()
+	if err := loadModuleConfig(ctx, filepath.Join(module, "terragrunt.hcl"), config); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", module, err)
+	}
+
+	if config.Backend.Type == "gcs" && config.Backend.Bucket != "" {
+		bucket := config.Backend.Bucket
+		object := path.Join(config.Backend.Prefix, "default.tfstate")
+		size, err := stateObjectSize(context.Background(), bucket, object)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if size > smallStateSize {
+			return true, nil
+		}
+		data, err := readStateObject(context.Background(), config, object, 0)
+		if err != nil {
+			return false, err
+		}
+		return stateHasResources(data)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cachedTerraformDir(ctx, module), "terraform.tfstate"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return stateHasResources(data)
+}
+
+// stateHasResources reports whether a state file tracks any resources; a
+// state emptied by destroy counts as no state
+func stateHasResources(data []byte) (bool, error) {
+	if len(data) == 0 {
+		return false, nil
+	}
+	var state struct {
+		Resources []json.RawMessage `json:"resources"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return len(state.Resources) > 0, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeStateModules creates modules whose state is in GCS or local, with and
+// without resources, and stubs the GCS calls to serve the remote ones
+func writeStateModules(t *testing.T) (string, []string, *[]string) {
+	t.Helper()
+	root := t.TempDir()
+
+	gcsBackend := func(prefix string) string {
+		return "backend {\n  type   = \"gcs\"\n  bucket = \"tf-state\"\n  prefix = \"" + prefix + "\"\n}\n"
+	}
+	newDependencyModule(t, root, "gcs-large", gcsBackend("gcs-large"))
+	newDependencyModule(t, root, "gcs-small", gcsBackend("gcs-small"))
+	newDependencyModule(t, root, "gcs-empty", gcsBackend("gcs-empty"))
+	newDependencyModule(t, root, "gcs-missing", gcsBackend("gcs-missing"))
+	newDependencyModule(t, root, "local", "")
+	newDependencyModule(t, root, "local-missing", "")
+	localState := `{"version": 4, "resources": [{"mode": "managed", "type": "google_compute_network", "name": "main"}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(root, "local", "terraform.tfstate"), []byte(localState), 0644))
+
+	objects := map[string]string{
+		"gs://tf-state/gcs-small/default.tfstate": `{"version": 4, "resources": [{"mode": "managed", "type": "google_storage_bucket", "name": "b"}]}`,
+		"gs://tf-state/gcs-empty/default.tfstate": `{"version": 4, "serial": 3, "outputs": {}, "resources": []}`,
+	}
+	reads := stubDependencyIO(t, objects, "{}")
+
+	originalSize := stateObjectSize
+	stateObjectSize = func(_ context.Context, bucket, object string) (int64, error) {
+		key := "gs://" + bucket + "/" + object
+		if key == "gs://tf-state/gcs-large/default.tfstate" {
+			return smallStateSize * 10, nil
+		}
+		data, ok := objects[key]
+		if !ok {
+			return 0, storage.ErrObjectNotExist
+		}
+		return int64(len(data)), nil
+	}
+	t.Cleanup(func() { stateObjectSize = originalSize })
+
+	modules, err := findModules(&ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root})
+	require.NoError(t, err)
+	return root, modules, reads
+}
+
+func TestFilterModulesOnlyWithState(t *testing.T) {
+	root, modules, reads := writeStateModules(t)
+	config := defaultTerragruntConfig()
+	config.OnlyWithState = true
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	filtered, err := filterModulesByState(ctx, modules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "gcs-large"),
+		filepath.Join(root, "gcs-small"),
+		filepath.Join(root, "local"),
+	}, filtered)
+	assert.NotContains(t, *reads, "gs://tf-state/gcs-large/default.tfstate", "large states are not downloaded")
+}
+
+func TestFilterModulesOnlyWithoutState(t *testing.T) {
+	root, modules, _ := writeStateModules(t)
+	config := defaultTerragruntConfig()
+	config.OnlyWithoutState = true
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	filtered, err := filterModulesByState(ctx, modules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "gcs-empty"),
+		filepath.Join(root, "gcs-missing"),
+		filepath.Join(root, "local-missing"),
+	}, filtered)
+}
+
+func TestFilterModulesByStateCombinesWithExcludeDirs(t *testing.T) {
+	root, _, _ := writeStateModules(t)
+	config := defaultTerragruntConfig()
+	config.OnlyWithState = true
+	config.ExcludeDirs = []string{"gcs-"}
+	ctx := &ExecutionContext{Config: config, WorkingDir: root}
+
+	modules, err := findModules(ctx)
+	require.NoError(t, err)
+	filtered, err := filterModulesByState(ctx, modules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "local")}, filtered)
+}
+
+func TestFilterModulesByStateFlags(t *testing.T) {
+	root, modules, _ := writeStateModules(t)
+
+	filtered, err := filterModulesByState(&ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root}, modules)
+	require.NoError(t, err)
+	assert.Equal(t, modules, filtered, "without a state filter every module is kept")
+
+	config := defaultTerragruntConfig()
+	config.OnlyWithState = true
+	config.OnlyWithoutState = true
+	_, err = filterModulesByState(&ExecutionContext{Config: config, WorkingDir: root}, modules)
+	assert.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestModuleHasStateReadsTheCacheCopy(t *testing.T) {
+	root, module := writeCacheTree(t, "../../modules/vpc")
+	ctx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: module}
+	require.NoError(t, prepareTerraformDir(ctx))
+	require.NotEqual(t, module, ctx.terraformDir())
+
+	runCtx := &ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root}
+	hasState, err := moduleHasState(runCtx, module)
+	require.NoError(t, err)
+	assert.False(t, hasState)
+
+	// terraform ran, and left its state, in the cache copy
+	state := `{"version": 4, "resources": [{"mode": "managed", "type": "google_compute_network", "name": "main"}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(ctx.terraformDir(), "terraform.tfstate"), []byte(state), 0644))
+	hasState, err = moduleHasState(runCtx, module)
+	require.NoError(t, err)
+	assert.True(t, hasState)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedTerraformContext returns a context for a module whose terraform
+// is the given shell script
+func scriptedTerraformContext(t *testing.T, script string) *ExecutionContext {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	terraform := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(terraform, []byte("#!/bin/sh\n"+script), 0755))
+
+	config := defaultTerragruntConfig()
+	config.TerraformPath = terraform
+	config.RetryAttempts = 0
+	return &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Environment: map[string]string{}, shared: &runState{}}
+}
+
+func TestRunTerraformCapturesOutput(t *testing.T) {
+	ctx := scriptedTerraformContext(t, "echo \"planning $1\"\necho 'warning: deprecated' >&2\n")
+
+	result, err := runTerraform(ctx, terraformRunOptions{Capture: true}, "plan", "-input=false")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan", "-input=false"}, result.Args)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, "planning plan\n", result.Stdout)
+	assert.Equal(t, "warning: deprecated\n", result.Stderr)
+	assert.Greater(t, result.Duration, time.Duration(0))
+}
+
+func TestRunTerraformFailureResult(t *testing.T) {
+	ctx := scriptedTerraformContext(t, "echo 'Error: invalid reference' >&2\nexit 3\n")
+
+	result, err := runTerraform(ctx, terraformRunOptions{Capture: true}, "apply")
+	require.Error(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Empty(t, result.Stdout)
+	assert.Equal(t, "Error: invalid reference\n", result.Stderr)
+
+	// executeTerraform streams without keeping the output
+	assert.Error(t, executeTerraform(ctx, "apply"))
+	result, err = runTerraform(ctx, terraformRunOptions{}, "apply")
+	require.Error(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Empty(t, result.Stderr)
+}
+
+func TestRunTerraformResultAfterRetries(t *testing.T) {
+	ctx := scriptedTerraformContext(t, "echo 'Error 503: backend unavailable' >&2\nexit 1\n")
+	ctx.Config.RetryAttempts = 2
+	ctx.Config.RetryDelay = time.Millisecond
+	ctx.Config.ErrorHandling.RetryableErrors = []string{"Error 503"}
+
+	result, err := runTerraform(ctx, terraformRunOptions{Capture: true}, "plan")
+	require.Error(t, err)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "Error 503: backend unavailable\n", result.Stderr, "only the last attempt's output is kept")
+}
+
+func TestQueryTerraformRunsInDryRuns(t *testing.T) {
+	ctx := scriptedTerraformContext(t, "echo \"$1 in $(pwd)\"\n")
+	ctx.DryRun = true
+
+	// Outputs of another module are read in that module
+	module := t.TempDir()
+	output, err := runTerraformOutput(ctx, module)
+	require.NoError(t, err)
+	assert.Equal(t, "output in "+module+"\n", string(output))
+
+	failing := scriptedTerraformContext(t, "echo 'Error: no saved plan' >&2\nexit 1\n")
+	_, err = showPlanJSON(failing, "tfplan")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Error: no saved plan")
+}
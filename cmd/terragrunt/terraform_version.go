@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// terraformVersionOf reports the version of the terraform binary at
+// terraformPath; tests replace it
+var terraformVersionOf = func(terraformPath string) (string, error) {
+	output, err := runnerOutput(context.Background(), terraformRunner, runnerCommand{
+		Path: terraformPath,
+		Args: []string{"version", "-json"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s version: %w", terraformPath, err)
+	}
+
+	var info struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", fmt.Errorf("failed to parse terraform version: %w", err)
+	}
+	return info.TerraformVersion, nil
+}
+
+// listTerraformReleases returns every published terraform version; tests
+// replace it
+var listTerraformReleases = func() ([]string, error) {
+	resp, err := http.Get("https://releases.hashicorp.com/terraform/index.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch terraform releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var index struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform releases: %w", err)
+	}
+
+	versions := make([]string, 0, len(index.Versions))
+	for v := range index.Versions {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// installTerraform downloads a terraform version and returns its path; tests
+// replace it
+var installTerraform = downloadTerraform
+
+// terraformVersionConstraints collects the required terraform versions from
+// terraform_binary.version and the module's required_version settings, along
+// with a readable form of them for messages
+func terraformVersionConstraints(ctx *ExecutionContext) (goversion.Constraints, string, error) {
+	var raw []string
+	if v := strings.TrimSpace(ctx.Config.TerraformBinary.Version); v != "" && v != "latest" {
+		raw = append(raw, v)
+	}
+
+	if module, diags := tfconfig.LoadModule(ctx.terraformDir()); module != nil {
+		if diags.HasErrors() {
+			logger.Debugf("Reading required_version from %s: %v", ctx.terraformDir(), diags.Err())
+		}
+		raw = append(raw, module.RequiredCore...)
+	}
+
+	var constraints goversion.Constraints
+	for _, r := range raw {
+		c, err := goversion.NewConstraint(r)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid terraform version constraint %q: %w", r, err)
+		}
+		constraints = append(constraints, c...)
+	}
+	return constraints, strings.Join(raw, ", "), nil
+}
+
+// terraformBinaries remembers, for the modules of a run, which binary
+// satisfies each set of version constraints and which versions have been
+// installed, so each check and install happens once however many modules
+// run terraform, in parallel or not
+type terraformBinaries struct {
+	mu       sync.Mutex
+	resolved map[string]*resolvedBinary
+}
+
+// resolvedBinary is the result of resolving one key of terraformBinaries
+type resolvedBinary struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+// resolve returns the result of resolve for key, calling it only the first
+// time; concurrent callers for the same key wait for that call. A nil
+// terraformBinaries resolves every time.
+func (b *terraformBinaries) resolve(key string, resolve func() (string, error)) (string, error) {
+	if b == nil {
+		return resolve()
+	}
+	b.mu.Lock()
+	if b.resolved == nil {
+		b.resolved = make(map[string]*resolvedBinary)
+	}
+	result, ok := b.resolved[key]
+	if !ok {
+		result = &resolvedBinary{}
+		b.resolved[key] = result
+	}
+	b.mu.Unlock()
+
+	result.once.Do(func() { result.path, result.err = resolve() })
+	return result.path, result.err
+}
+
+// terraformBinaries returns the binaries resolved so far in the run of ctx,
+// or nil outside a run
+func (ctx *ExecutionContext) terraformBinaries() *terraformBinaries {
+	if ctx.shared == nil {
+		return nil
+	}
+	return &ctx.shared.binaries
+}
+
+// installTerraformOnce installs version, "" being the configured one, once
+// per run
+func installTerraformOnce(ctx *ExecutionContext, version string) (string, error) {
+	return ctx.terraformBinaries().resolve("install "+version, func() (string, error) {
+		return installTerraform(ctx, version)
+	})
+}
+
+// ensureTerraformVersion checks the terraform at terraformPath against the
+// required version constraints. When they aren't met and auto_download is
+// enabled, the newest matching release is installed and its path returned.
+// Within a run each binary and set of constraints is checked only once.
+func ensureTerraformVersion(ctx *ExecutionContext, terraformPath string) (string, error) {
+	constraints, required, err := terraformVersionConstraints(ctx)
+	if err != nil || len(constraints) == 0 {
+		return terraformPath, err
+	}
+	return ctx.terraformBinaries().resolve("check "+terraformPath+"\x00"+required, func() (string, error) {
+		return resolveTerraformVersion(ctx, terraformPath, constraints, required)
+	})
+}
+
+// resolveTerraformVersion does the work of ensureTerraformVersion
+func resolveTerraformVersion(ctx *ExecutionContext, terraformPath string, constraints goversion.Constraints, required string) (string, error) {
+
+	current := "unknown"
+	if raw, err := terraformVersionOf(terraformPath); err == nil {
+		current = raw
+		if v, err := goversion.NewVersion(raw); err == nil && constraints.Check(v) {
+			return terraformPath, nil
+		}
+	} else {
+		logger.Debugf("Could not determine terraform version: %v", err)
+	}
+
+	if !ctx.Config.TerraformBinary.AutoDownload {
+		return "", fmt.Errorf("terraform %s does not satisfy the required version %s", current, required)
+	}
+
+	target, err := matchingTerraformRelease(constraints, required)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Infof("Terraform %s does not satisfy %s, installing %s", current, required, target)
+	installed, err := installTerraformOnce(ctx, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to download terraform %s: %w", target, err)
+	}
+	return installed, nil
+}
+
+// matchingTerraformRelease picks the newest stable release that satisfies
+// constraints
+func matchingTerraformRelease(constraints goversion.Constraints, required string) (string, error) {
+	releases, err := listTerraformReleases()
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []*goversion.Version
+	for _, r := range releases {
+		v, err := goversion.NewVersion(r)
+		if err != nil || v.Prerelease() != "" || !constraints.Check(v) {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no terraform release satisfies %s", required)
+	}
+
+	sort.Sort(goversion.Collection(candidates))
+	return candidates[len(candidates)-1].Original(), nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubTerraformVersion(t *testing.T, installed string, releases []string) *[]string {
+	t.Helper()
+	originalVersion, originalReleases, originalInstall := terraformVersionOf, listTerraformReleases, installTerraform
+	t.Cleanup(func() {
+		terraformVersionOf, listTerraformReleases, installTerraform = originalVersion, originalReleases, originalInstall
+	})
+
+	var downloads []string
+	terraformVersionOf = func(string) (string, error) { return installed, nil }
+	listTerraformReleases = func() ([]string, error) { return releases, nil }
+	installTerraform = func(_ *ExecutionContext, version string) (string, error) {
+		downloads = append(downloads, version)
+		return "/opt/terraform/" + version + "/terraform", nil
+	}
+	return &downloads
+}
+
+func versionContext(t *testing.T, constraint string) *ExecutionContext {
+	t.Helper()
+	config := defaultTerragruntConfig()
+	config.TerraformBinary.Version = constraint
+	return &ExecutionContext{Config: config, WorkingDir: t.TempDir(), Logger: logger}
+}
+
+func TestEnsureTerraformVersionSatisfied(t *testing.T) {
+	downloads := stubTerraformVersion(t, "1.6.2", nil)
+	ctx := versionContext(t, ">= 1.5, < 2.0")
+
+	path, err := ensureTerraformVersion(ctx, "terraform")
+	require.NoError(t, err)
+	assert.Equal(t, "terraform", path)
+	assert.Empty(t, *downloads)
+
+	// Without any constraint the binary is used as is
+	ctx.Config.TerraformBinary.Version = ""
+	path, err = ensureTerraformVersion(ctx, "terraform")
+	require.NoError(t, err)
+	assert.Equal(t, "terraform", path)
+}
+
+func TestEnsureTerraformVersionUnsatisfied(t *testing.T) {
+	downloads := stubTerraformVersion(t, "1.6.2", nil)
+	ctx := versionContext(t, "")
+	require.NoError(t, os.WriteFile(filepath.Join(ctx.WorkingDir, "versions.tf"), []byte(`
+terraform {
+  required_version = "~> 1.7.0"
+}
+`), 0644))
+
+	_, err := ensureTerraformVersion(ctx, "terraform")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "terraform 1.6.2 does not satisfy the required version ~> 1.7.0")
+	assert.Empty(t, *downloads)
+
+	ctx.Config.TerraformBinary.Version = ">= 1.5, < 2.0"
+	_, err = ensureTerraformVersion(ctx, "terraform")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ">= 1.5, < 2.0, ~> 1.7.0")
+}
+
+func TestEnsureTerraformVersionAutoDownloads(t *testing.T) {
+	downloads := stubTerraformVersion(t, "1.4.0", []string{"1.4.0", "1.5.7", "1.9.8", "1.10.0-beta1", "2.0.0"})
+	ctx := versionContext(t, ">= 1.5, < 2.0")
+	ctx.Config.TerraformBinary.AutoDownload = true
+
+	path, err := ensureTerraformVersion(ctx, "terraform")
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/terraform/1.9.8/terraform", path)
+	assert.Equal(t, []string{"1.9.8"}, *downloads)
+
+	ctx.Config.TerraformBinary.Version = ">= 3.0"
+	_, err = ensureTerraformVersion(ctx, "terraform")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no terraform release satisfies >= 3.0")
+}
+
+func TestEnsureTerraformVersionRejectsInvalidConstraint(t *testing.T) {
+	stubTerraformVersion(t, "1.6.2", nil)
+	_, err := ensureTerraformVersion(versionContext(t, ">= banana"), "terraform")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid terraform version constraint")
+}
+
+func TestEnsureTerraformVersionResolvesOncePerRun(t *testing.T) {
+	downloads := stubTerraformVersion(t, "1.4.0", []string{"1.5.7", "1.9.8"})
+	var mu sync.Mutex
+	checks := 0
+	terraformVersionOf = func(string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		checks++
+		return "1.4.0", nil
+	}
+	install := installTerraform
+	installTerraform = func(ctx *ExecutionContext, version string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return install(ctx, version)
+	}
+
+	ctx := versionContext(t, ">= 1.5, < 2.0")
+	ctx.Config.TerraformBinary.AutoDownload = true
+	ctx.shared = &runState{}
+
+	// Modules running in parallel share one check and one install
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			moduleCtx, err := newModuleContext(ctx, ctx.WorkingDir)
+			if assert.NoError(t, err) {
+				paths[i], err = ensureTerraformVersion(moduleCtx, "terraform")
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, path := range paths {
+		assert.Equal(t, "/opt/terraform/1.9.8/terraform", path)
+	}
+	assert.Equal(t, 1, checks)
+	assert.Equal(t, []string{"1.9.8"}, *downloads)
+
+	// A new run checks again
+	ctx.shared = &runState{}
+	_, err := ensureTerraformVersion(ctx, "terraform")
+	require.NoError(t, err)
+	assert.Equal(t, 2, checks)
+}
+
+func TestDownloadTerraformReusesInstalledVersion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	installed := filepath.Join(home, ".terragrunt", "terraform", "1.9.8", "terraform")
+	if runtime.GOOS == "windows" {
+		installed += ".exe"
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(installed), 0755))
+	require.NoError(t, os.WriteFile(installed, []byte("#!/bin/sh\n"), 0755))
+
+	ctx := versionContext(t, "")
+	path, err := downloadTerraform(ctx, "1.9.8")
+	require.NoError(t, err)
+	assert.Equal(t, installed, path)
+	assert.True(t, strings.HasPrefix(ctx.Environment["PATH"], filepath.Dir(installed)))
+}
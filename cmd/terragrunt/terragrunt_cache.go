@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// terragruntCacheDir is created in each module to hold the copy terraform
+// runs in, unless --terragrunt-download-dir puts the copies elsewhere
+const terragruntCacheDir = ".terragrunt-cache"
+
+// TerraformConfig is the terraform block of terragrunt.hcl
+type TerraformConfig struct {
+	// Source is the terraform module to run: a local path, gs:// prefix or
+	// git URL, optionally followed by //subdir
+	Source string `json:"source" mapstructure:"source"`
+	// IncludeInCopy and ExcludeFromCopy are globs, relative to the source,
+	// filtering what is copied into the cache; see copyFilter
+	IncludeInCopy   []string `json:"include_in_copy" mapstructure:"include_in_copy"`
+	ExcludeFromCopy []string `json:"exclude_from_copy" mapstructure:"exclude_from_copy"`
+	// CopyTerraformLockFile copies the lock file init writes in the cache
+	// back to the module; it defaults to true
+	CopyTerraformLockFile *bool `json:"copy_terraform_lock_file" mapstructure:"copy_terraform_lock_file"`
+}
+
+// fetchGitSource clones a git source; tests replace it
+var fetchGitSource = func(repoURL, ref, dir string) error {
+	args := []string{"clone", "--depth", "1", "--quiet"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	cmd := exec.Command("git", append(args, repoURL, dir)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// terraformDir is where terraform runs for the module: the copy in its cache
+// once prepareTerraformDir has made one, the module directory otherwise
+func (ctx *ExecutionContext) terraformDir() string {
+	if ctx.TerraformDir != "" {
+		return ctx.TerraformDir
+	}
+	return ctx.WorkingDir
+}
+
+// prepareTerraformDir copies the module into its cache directory and points
+// terraform there, so init artifacts and generated files never land in the
+// source tree and modules sharing a source don't collide. Modules run in
+// place unless they have a terraform source or isolation is requested.
+func prepareTerraformDir(ctx *ExecutionContext) error {
+	terraform, err := moduleTerraformConfig(ctx)
+	if err != nil {
+		return err
+	}
+	source := terraform.Source
+	if source == "" && !ctx.Config.IsolateWorkingDir {
+		return nil
+	}
+	filter, err := newCopyFilter(terraform.IncludeInCopy, terraform.ExcludeFromCopy)
+	if err != nil {
+		return err
+	}
+
+	location, subdir := splitSourceSubdir(source)
+	if location == "" {
+		location = ctx.WorkingDir
+	}
+	cacheDir := moduleCacheDir(ctx, location)
+	workDir := filepath.Join(cacheDir, filepath.FromSlash(subdir))
+
+	// Remote sources are downloaded once next to the copies made from them
+	downloaded := filepath.Join(filepath.Dir(cacheDir), "download")
+	if ctx.Config.SourceUpdate {
+		if err := os.RemoveAll(cacheDir); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", cacheDir, err)
+		}
+	}
+
+	inPlace := false
+	if isRemoteSource(location) {
+		if err := ensureSourceDownloaded(location, downloaded, ctx.Config.SourceUpdate); err != nil {
+			return err
+		}
+		location = downloaded
+	} else {
+		if !filepath.IsAbs(location) {
+			location = filepath.Join(ctx.WorkingDir, location)
+		}
+		inPlace = filepath.Clean(location) == ctx.WorkingDir
+	}
+
+	// Sources are copied on every run so local edits show up
+	copied := make(map[string]bool)
+	if err := copyModuleFiles(location, cacheDir, filter, copied); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", location, err)
+	}
+
+	if _, err := os.Stat(workDir); err != nil {
+		return fmt.Errorf("source %s has no %s directory", source, subdir)
+	}
+
+	// The module's own files (terragrunt.hcl, tfvars, overrides) go on top
+	if !inPlace {
+		if err := copyModuleFiles(ctx.WorkingDir, workDir, filter, copied); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", ctx.WorkingDir, err)
+		}
+	}
+
+	// and configuration deleted from them since the last run goes, apart
+	// from the files generated into the copy
+	generated, err := readGeneratedManifest(workDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range generated {
+		copied[filepath.Join(workDir, filepath.FromSlash(name))] = true
+	}
+	if err := pruneModuleCopy(cacheDir, copied); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", cacheDir, err)
+	}
+
+	logger.Debugf("Running terraform for %s in %s", ctx.WorkingDir, workDir)
+	ctx.TerraformDir = workDir
+	return nil
+}
+
+// cachedTerraformDir returns the directory terraform last ran in for
+// module, its cache copy when it has one, so outputs are read where its
+// backend was initialized
+func cachedTerraformDir(ctx *ExecutionContext, module string) string {
+	config := *ctx.Config
+	config.Terraform = TerraformConfig{}
+	moduleCtx := &ExecutionContext{Config: &config, WorkingDir: module}
+
+	terraform, err := moduleTerraformConfig(moduleCtx)
+	if err != nil || (terraform.Source == "" && !config.IsolateWorkingDir) {
+		return module
+	}
+	location, subdir := splitSourceSubdir(terraform.Source)
+	if location == "" {
+		location = module
+	}
+	dir := filepath.Join(moduleCacheDir(moduleCtx, location), filepath.FromSlash(subdir))
+	if _, err := os.Stat(dir); err != nil {
+		return module
+	}
+	return dir
+}
+
+// moduleTerraformConfig returns the terraform block of the module, with
+// --terragrunt-source replacing everything but the source's //subdir
+func moduleTerraformConfig(ctx *ExecutionContext) (TerraformConfig, error) {
+	terraform := ctx.Config.Terraform
+	configPath := filepath.Join(ctx.WorkingDir, "terragrunt.hcl")
+	if _, err := os.Stat(configPath); err == nil {
+		config := defaultTerragruntConfig()
+		if err := loadModuleConfig(ctx, configPath, config); err != nil {
+			return TerraformConfig{}, fmt.Errorf("failed to parse %s: %w", configPath, err)
+		}
+		terraform = config.Terraform
+	}
+
+	if override := ctx.Config.SourceOverride; override != "" {
+		if _, subdir := splitSourceSubdir(terraform.Source); subdir != "" {
+			override = strings.TrimSuffix(override, "/") + "//" + subdir
+		}
+		terraform.Source = override
+	}
+	return terraform, nil
+}
+
+// splitSourceSubdir splits "location//subdir", leaving the // of a URL
+// scheme alone and keeping any ?query with the location
+func splitSourceSubdir(source string) (string, string) {
+	offset := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		offset = i + 3
+	}
+	i := strings.Index(source[offset:], "//")
+	if i < 0 {
+		return source, ""
+	}
+	location, subdir := source[:offset+i], source[offset+i+2:]
+	if q := strings.Index(subdir, "?"); q >= 0 {
+		location += subdir[q:]
+		subdir = subdir[:q]
+	}
+	return location, strings.Trim(subdir, "/")
+}
+
+func isRemoteSource(location string) bool {
+	return strings.Contains(location, "::") || strings.Contains(location, "://")
+}
+
+// sourceDownloads holds a lock per download directory. With a download dir
+// set, the modules sharing a source share its download, and the lock makes
+// the first of them fetch it while the others wait.
+var sourceDownloads = struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}{locks: make(map[string]*sync.Mutex)}
+
+// lockSourceDownload takes the lock of download directory dir and returns
+// the function releasing it
+func lockSourceDownload(dir string) func() {
+	sourceDownloads.mu.Lock()
+	lock, ok := sourceDownloads.locks[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		sourceDownloads.locks[dir] = lock
+	}
+	sourceDownloads.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// ensureSourceDownloaded downloads location into dir unless it is there
+// already, or afresh when update is set. The download goes to a temporary
+// directory next to dir that is then renamed into place, so another run
+// never finds half a download.
+func ensureSourceDownloaded(location, dir string, update bool) error {
+	unlock := lockSourceDownload(dir)
+	defer unlock()
+
+	if update {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", dir, err)
+		}
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+
+	logger.Infof("Downloading %s into %s", location, dir)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dir), ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	defer os.RemoveAll(staging)
+	// git clones into a directory that doesn't exist yet
+	target := filepath.Join(staging, "source")
+	if err := downloadSource(location, target); err != nil {
+		return fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	if err := os.Rename(target, dir); err != nil {
+		// Another run got there first
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	return nil
+}
+
+// downloadSource fetches a gs:// prefix or git repository into dir
+func downloadSource(location, dir string) error {
+	if strings.HasPrefix(location, "gs://") {
+		return fetchGCSCatalog(context.Background(), location, dir)
+	}
+
+	repoURL, query, _ := strings.Cut(strings.TrimPrefix(location, "git::"), "?")
+	ref := ""
+	for _, param := range strings.Split(query, "&") {
+		if value, ok := strings.CutPrefix(param, "ref="); ok {
+			ref = value
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	return fetchGitSource(repoURL, ref, dir)
+}
+
+// moduleCacheDir returns the cache directory of the module for a source,
+// keyed by both so a changed source gets a fresh copy
+func moduleCacheDir(ctx *ExecutionContext, location string) string {
+	sourceSum := sha256.Sum256([]byte(location))
+	moduleSum := sha256.Sum256([]byte(ctx.WorkingDir))
+	return filepath.Join(moduleCacheRoot(ctx), hex.EncodeToString(sourceSum[:])[:16], hex.EncodeToString(moduleSum[:])[:16])
+}
+
+// moduleCacheRoot is the module's .terragrunt-cache, or the download dir
+// when one is set
+func moduleCacheRoot(ctx *ExecutionContext) string {
+	if ctx.Config.DownloadDir != "" {
+		return downloadDir(ctx)
+	}
+	return filepath.Join(ctx.WorkingDir, terragruntCacheDir)
+}
+
+// copyModuleFiles copies the files under src that filter lets through into
+// dst, always skipping terraform and terragrunt working state and version
+// control directories. The paths written are added to copied.
+func copyModuleFiles(src, dst string, filter *copyFilter, copied map[string]bool) error {
+	src, dst = filepath.Clean(src), filepath.Clean(dst)
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			switch entry.Name() {
+			case terragruntCacheDir, ".terraform", ".git":
+				if path != src {
+					return filepath.SkipDir
+				}
+			}
+			if path == dst {
+				return filepath.SkipDir
+			}
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && !filter.copies(filepath.ToSlash(rel), entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode().IsRegular():
+			copied[target] = true
+			return copyFile(path, target)
+		}
+		return nil
+	})
+}
+
+// pruneModuleCopy removes the terraform and terragrunt configuration files
+// under dir that aren't in keep, so a file deleted from the source stops
+// being planned. The lock file and anything else, such as saved plans and
+// local state, stay.
+func pruneModuleCopy(dir string, keep map[string]bool) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			switch entry.Name() {
+			case terragruntCacheDir, ".terraform", ".git":
+				if path != dir {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if keep[path] || !requiredCopyFile(entry.Name()) || entry.Name() == ".terraform.lock.hcl" {
+			return nil
+		}
+		logger.Debugf("Removing %s, which is gone from the source", path)
+		return os.Remove(path)
+	})
+}
+
+// syncLockFile copies the lock file init wrote in the cache back to the
+// module, where it is committed
+func syncLockFile(ctx *ExecutionContext) error {
+	if ctx.terraformDir() == ctx.WorkingDir {
+		return nil
+	}
+	terraform, err := moduleTerraformConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if terraform.CopyTerraformLockFile != nil && !*terraform.CopyTerraformLockFile {
+		return nil
+	}
+	lockPath := filepath.Join(ctx.terraformDir(), ".terraform.lock.hcl")
+	if _, err := os.Stat(lockPath); os.IsNotExist(err) {
+		return nil
+	}
+	return copyFile(lockPath, filepath.Join(ctx.WorkingDir, ".terraform.lock.hcl"))
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCacheTree creates modules/vpc, a terraform module, and live/vpc, a
+// terragrunt module using it as its source
+func writeCacheTree(t *testing.T, source string) (root, module string) {
+	t.Helper()
+	root = t.TempDir()
+	files := map[string]string{
+		"modules/vpc/main.tf":       `resource "google_compute_network" "main" {}`,
+		"modules/vpc/sub/inner.tf":  "",
+		"live/vpc/terragrunt.hcl":   "terraform {\n  source = \"" + source + "\"\n}\n",
+		"live/vpc/terraform.tfvars": `name = "main"`,
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+	return root, filepath.Join(root, "live", "vpc")
+}
+
+// listFiles returns the files under dir relative to it
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	}))
+	return files
+}
+
+func TestPrepareTerraformDirRunsTerraformInCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script terraform stand-in")
+	}
+	root, module := writeCacheTree(t, "../../modules/vpc")
+
+	terraform := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\npwd > ran-in.out\nmkdir -p .terraform\necho '# lock' > .terraform.lock.hcl\n"
+	require.NoError(t, os.WriteFile(terraform, []byte(script), 0755))
+
+	config := defaultTerragruntConfig()
+	config.TerraformPath = terraform
+	config.Cache.Dir = t.TempDir()
+	config.Generate = []GenerateConfig{{Name: "provider", Path: "provider.tf", Contents: `provider "google" {}`}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: module, Environment: map[string]string{}, Logger: logger}
+
+	require.NoError(t, prepareTerraformDir(ctx))
+	cacheDir := ctx.terraformDir()
+	assert.True(t, strings.HasPrefix(cacheDir, filepath.Join(module, terragruntCacheDir)+string(os.PathSeparator)), cacheDir)
+
+	require.NoError(t, generateFiles(ctx))
+	require.NoError(t, executeTerraform(ctx, "init"))
+	require.NoError(t, syncLockFile(ctx))
+
+	ranIn, err := os.ReadFile(filepath.Join(cacheDir, "ran-in.out"))
+	require.NoError(t, err)
+	resolved, err := filepath.EvalSymlinks(cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, resolved, strings.TrimSpace(string(ranIn)))
+
+	for _, name := range []string{"main.tf", "sub/inner.tf", "terragrunt.hcl", "terraform.tfvars", "provider.tf", generatedManifestFile} {
+		assert.FileExists(t, filepath.Join(cacheDir, name))
+	}
+
+	// The sources are untouched apart from the lock file terraform wrote
+	assert.ElementsMatch(t, []string{"main.tf", "sub/inner.tf"}, listFiles(t, filepath.Join(root, "modules", "vpc")))
+	entries, err := os.ReadDir(module)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{terragruntCacheDir, ".terraform.lock.hcl", "terragrunt.hcl", "terraform.tfvars"}, names)
+
+	// Modules found under the tree don't include the cached copy
+	modules, err := findModules(&ExecutionContext{Config: defaultTerragruntConfig(), WorkingDir: root})
+	require.NoError(t, err)
+	assert.Equal(t, []string{module}, modules)
+}
+
+func TestPrepareTerraformDirWithoutSourceRunsInPlace(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(""), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(""), 0644))
+	config := defaultTerragruntConfig()
+	ctx := &ExecutionContext{Config: config, WorkingDir: dir}
+
+	require.NoError(t, prepareTerraformDir(ctx))
+	assert.Equal(t, dir, ctx.terraformDir())
+	assert.NoDirExists(t, filepath.Join(dir, terragruntCacheDir))
+
+	config.IsolateWorkingDir = true
+	require.NoError(t, prepareTerraformDir(ctx))
+	assert.NotEqual(t, dir, ctx.terraformDir())
+	assert.ElementsMatch(t, []string{"main.tf", "terragrunt.hcl"}, listFiles(t, ctx.terraformDir()))
+}
+
+func TestPrepareTerraformDirSourceOverrideAndDownloadDir(t *testing.T) {
+	root, module := writeCacheTree(t, "git::https://example.com/modules.git//sub?ref=v1.2.0")
+
+	fetchCalls := 0
+	original := fetchGitSource
+	fetchGitSource = func(repoURL, ref, dir string) error {
+		fetchCalls++
+		return nil
+	}
+	t.Cleanup(func() { fetchGitSource = original })
+
+	config := defaultTerragruntConfig()
+	config.SourceOverride = filepath.Join(root, "modules", "vpc")
+	config.DownloadDir = t.TempDir()
+	ctx := &ExecutionContext{Config: config, WorkingDir: module}
+
+	require.NoError(t, prepareTerraformDir(ctx))
+	assert.Zero(t, fetchCalls, "the local override replaces the remote source")
+	assert.True(t, strings.HasPrefix(ctx.terraformDir(), config.DownloadDir), ctx.terraformDir())
+	assert.Equal(t, "sub", filepath.Base(ctx.terraformDir()))
+	assert.ElementsMatch(t, []string{"inner.tf", "terragrunt.hcl", "terraform.tfvars"}, listFiles(t, ctx.terraformDir()))
+	assert.Equal(t, ctx.terraformDir(), cachedTerraformDir(ctx, module))
+	assert.NoDirExists(t, filepath.Join(module, terragruntCacheDir))
+}
+
+func TestPrepareTerraformDirDownloadsRemoteSourceOnce(t *testing.T) {
+	_, module := writeCacheTree(t, "git::https://example.com/modules.git//vpc?ref=v1.2.0")
+
+	var fetched []string
+	original := fetchGitSource
+	fetchGitSource = func(repoURL, ref, dir string) error {
+		fetched = append(fetched, repoURL+"@"+ref)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "vpc"), 0755))
+		return os.WriteFile(filepath.Join(dir, "vpc", "main.tf"), []byte(""), 0644)
+	}
+	t.Cleanup(func() { fetchGitSource = original })
+
+	config := defaultTerragruntConfig()
+	ctx := &ExecutionContext{Config: config, WorkingDir: module}
+	require.NoError(t, prepareTerraformDir(ctx))
+	require.NoError(t, prepareTerraformDir(ctx))
+	assert.Equal(t, []string{"https://example.com/modules.git@v1.2.0"}, fetched)
+	assert.FileExists(t, filepath.Join(ctx.terraformDir(), "main.tf"))
+	assert.FileExists(t, filepath.Join(ctx.terraformDir(), "terragrunt.hcl"))
+
+	config.SourceUpdate = true
+	require.NoError(t, prepareTerraformDir(ctx))
+	assert.Len(t, fetched, 2, "--terragrunt-source-update fetches again")
+}
+
+func TestPrepareTerraformDirSharesDownloadBetweenModules(t *testing.T) {
+	withPartialParseCache(t)
+	root := t.TempDir()
+	source := "git::https://example.com/modules.git//vpc?ref=v1.2.0"
+	var modules []string
+	for i := 0; i < 6; i++ {
+		module := filepath.Join(root, "live", fmt.Sprintf("vpc%d", i))
+		require.NoError(t, os.MkdirAll(module, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(module, "terragrunt.hcl"), []byte("terraform {\n  source = \""+source+"\"\n}\n"), 0644))
+		modules = append(modules, module)
+	}
+
+	var mu sync.Mutex
+	var fetched []string
+	original := fetchGitSource
+	fetchGitSource = func(repoURL, ref, dir string) error {
+		mu.Lock()
+		fetched = append(fetched, repoURL+"@"+ref)
+		mu.Unlock()
+		// Fetching takes a while, during which other modules must wait
+		time.Sleep(20 * time.Millisecond)
+		if err := os.MkdirAll(filepath.Join(dir, "vpc"), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, "vpc", "main.tf"), []byte(""), 0644)
+	}
+	t.Cleanup(func() { fetchGitSource = original })
+
+	config := defaultTerragruntConfig()
+	config.DownloadDir = t.TempDir()
+	var wg sync.WaitGroup
+	dirs := make([]string, len(modules))
+	for i, module := range modules {
+		wg.Add(1)
+		go func(i int, module string) {
+			defer wg.Done()
+			ctx := &ExecutionContext{Config: config, WorkingDir: module}
+			if assert.NoError(t, prepareTerraformDir(ctx)) {
+				dirs[i] = ctx.terraformDir()
+			}
+		}(i, module)
+	}
+	wg.Wait()
+
+	assert.Len(t, fetched, 1)
+	for _, dir := range dirs {
+		assert.FileExists(t, filepath.Join(dir, "main.tf"))
+	}
+}
+
+func TestPrepareTerraformDirDropsFilesDeletedFromSource(t *testing.T) {
+	root, module := writeCacheTree(t, "../../modules/vpc")
+	config := defaultTerragruntConfig()
+	config.Generate = []GenerateConfig{{Name: "provider", Path: "provider.tf", Contents: `provider "google" {}`}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: module}
+
+	require.NoError(t, prepareTerraformDir(ctx))
+	require.NoError(t, generateFiles(ctx))
+	cacheDir := ctx.terraformDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "tfplan"), []byte("plan"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, ".terraform.lock.hcl"), []byte("# lock"), 0644))
+
+	require.NoError(t, os.Remove(filepath.Join(root, "modules", "vpc", "sub", "inner.tf")))
+	require.NoError(t, os.Remove(filepath.Join(module, "terraform.tfvars")))
+	require.NoError(t, prepareTerraformDir(ctx))
+
+	assert.ElementsMatch(t, []string{"main.tf", "terragrunt.hcl", "provider.tf", generatedManifestFile, "tfplan", ".terraform.lock.hcl"},
+		listFiles(t, cacheDir), "deleted configuration is gone; generated files, plans and the lock file stay")
+}
+
+func TestCleanModuleGeneratedFilesCleansCacheCopy(t *testing.T) {
+	_, module := writeCacheTree(t, "../../modules/vpc")
+	config := defaultTerragruntConfig()
+	config.Generate = []GenerateConfig{{Name: "provider", Path: "provider.tf", Contents: `provider "google" {}`}}
+	ctx := &ExecutionContext{Config: config, WorkingDir: module}
+	require.NoError(t, prepareTerraformDir(ctx))
+	require.NoError(t, generateFiles(ctx))
+	require.FileExists(t, filepath.Join(ctx.terraformDir(), "provider.tf"))
+
+	require.NoError(t, cleanModuleGeneratedFiles(&ExecutionContext{Config: config, WorkingDir: module}))
+	assert.NoFileExists(t, filepath.Join(ctx.terraformDir(), "provider.tf"))
+	assert.FileExists(t, filepath.Join(ctx.terraformDir(), "main.tf"))
+}
+
+func TestSplitSourceSubdir(t *testing.T) {
+	cases := []struct {
+		source, location, subdir string
+	}{
+		{"../modules/vpc", "../modules/vpc", ""},
+		{"../modules//vpc", "../modules", "vpc"},
+		{"gs://bucket/modules//network/vpc", "gs://bucket/modules", "network/vpc"},
+		{"git::https://example.com/m.git//vpc?ref=v1", "git::https://example.com/m.git?ref=v1", "vpc"},
+		{"git::https://example.com/m.git?ref=v1", "git::https://example.com/m.git?ref=v1", ""},
+	}
+	for _, c := range cases {
+		location, subdir := splitSourceSubdir(c.source)
+		assert.Equal(t, c.location, location, c.source)
+		assert.Equal(t, c.subdir, subdir, c.source)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/gcp"
 )
 
@@ -90,7 +91,7 @@ func main() {
 		configBytes = []byte(*configData)
 	}
 
-	if err := json.Unmarshal(configBytes, &validationReq); err != nil {
+	if err := configschema.Decode(configBytes, &validationReq); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing configuration: %v\n", err)
 		os.Exit(1)
 	}
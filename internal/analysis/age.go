@@ -0,0 +1,143 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+)
+
+// AgeOptions sets when a resource is stale: created more than MinAge ago
+// and not modified within MaxIdle
+type AgeOptions struct {
+	MinAge  time.Duration
+	MaxIdle time.Duration
+	// GroupBy "type" sums up ages per resource type
+	GroupBy string
+	// Now is the time ages are measured at; zero means time.Now
+	Now time.Time
+}
+
+// ResourceAge is the age of one resource
+type ResourceAge struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	Region       string    `json:"region"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastModified time.Time `json:"last_modified"`
+	AgeDays      int       `json:"age_days"`
+	IdleDays     int       `json:"idle_days"`
+	Reason       string    `json:"reason"`
+}
+
+// AgeGroup sums up the ages of one resource type
+type AgeGroup struct {
+	Type          string `json:"type"`
+	Resources     int    `json:"resources"`
+	Stale         int    `json:"stale"`
+	OldestAgeDays int    `json:"oldest_age_days"`
+	MaxIdleDays   int    `json:"max_idle_days"`
+}
+
+// AgeReport lists the resources due for review because they are old and
+// haven't changed in a while, oldest first
+type AgeReport struct {
+	GeneratedAt    time.Time `json:"generated_at"`
+	MinAgeDays     int       `json:"min_age_days"`
+	MaxIdleDays    int       `json:"max_idle_days"`
+	TotalResources int       `json:"total_resources"`
+	StaleResources int       `json:"stale_resources"`
+	// UnknownAge counts resources without a creation time, which can't be
+	// judged
+	UnknownAge int           `json:"unknown_age"`
+	Stale      []ResourceAge `json:"stale"`
+	ByType     []AgeGroup    `json:"by_type,omitempty"`
+}
+
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
+
+// BuildAgeReport measures the age of resources from their creation and
+// update times. A resource never updated counts as last modified when it
+// was created.
+func BuildAgeReport(resources []core.Resource, options AgeOptions) (*AgeReport, error) {
+	if options.GroupBy != "" && options.GroupBy != "type" {
+		return nil, fmt.Errorf("unsupported group-by %q (use type)", options.GroupBy)
+	}
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	report := &AgeReport{
+		GeneratedAt:    now,
+		MinAgeDays:     days(options.MinAge),
+		MaxIdleDays:    days(options.MaxIdle),
+		TotalResources: len(resources),
+		Stale:          []ResourceAge{},
+	}
+
+	groups := make(map[string]*AgeGroup)
+	for _, resource := range resources {
+		group, ok := groups[resource.Type]
+		if !ok {
+			group = &AgeGroup{Type: resource.Type}
+			groups[resource.Type] = group
+		}
+		group.Resources++
+
+		if resource.CreatedAt.IsZero() {
+			report.UnknownAge++
+			continue
+		}
+		age := ResourceAge{
+			ID:           resource.ID,
+			Name:         resource.Name,
+			Type:         resource.Type,
+			Region:       resource.Region,
+			CreatedAt:    resource.CreatedAt,
+			LastModified: resource.CreatedAt,
+		}
+		if resource.UpdatedAt.After(resource.CreatedAt) {
+			age.LastModified = resource.UpdatedAt
+		}
+		age.AgeDays = days(now.Sub(age.CreatedAt))
+		age.IdleDays = days(now.Sub(age.LastModified))
+
+		if age.AgeDays > group.OldestAgeDays {
+			group.OldestAgeDays = age.AgeDays
+		}
+		if age.IdleDays > group.MaxIdleDays {
+			group.MaxIdleDays = age.IdleDays
+		}
+
+		if now.Sub(age.CreatedAt) >= options.MinAge && now.Sub(age.LastModified) >= options.MaxIdle {
+			age.Reason = fmt.Sprintf("created %d days ago, unchanged for %d days", age.AgeDays, age.IdleDays)
+			group.Stale++
+			report.Stale = append(report.Stale, age)
+		}
+	}
+	report.StaleResources = len(report.Stale)
+
+	sort.Slice(report.Stale, func(i, j int) bool {
+		if !report.Stale[i].CreatedAt.Equal(report.Stale[j].CreatedAt) {
+			return report.Stale[i].CreatedAt.Before(report.Stale[j].CreatedAt)
+		}
+		return report.Stale[i].ID < report.Stale[j].ID
+	})
+	if options.GroupBy == "" {
+		return report, nil
+	}
+	for _, group := range groups {
+		report.ByType = append(report.ByType, *group)
+	}
+	sort.Slice(report.ByType, func(i, j int) bool {
+		if report.ByType[i].Stale != report.ByType[j].Stale {
+			return report.ByType[i].Stale > report.ByType[j].Stale
+		}
+		return report.ByType[i].Type < report.ByType[j].Type
+	})
+	return report, nil
+}
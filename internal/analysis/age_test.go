@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+)
+
+var ageNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func ageResources() []core.Resource {
+	daysAgo := func(n int) time.Time { return ageNow.AddDate(0, 0, -n) }
+	return []core.Resource{
+		// Old and untouched since creation
+		{ID: "vm-legacy", Type: "compute.instances", CreatedAt: daysAgo(700)},
+		// Old but modified recently
+		{ID: "vm-web", Type: "compute.instances", CreatedAt: daysAgo(400), UpdatedAt: daysAgo(10)},
+		// Old, last modified long ago
+		{ID: "bucket-archive", Type: "storage.buckets", CreatedAt: daysAgo(500), UpdatedAt: daysAgo(200)},
+		// Untouched, but too young to be stale
+		{ID: "bucket-new", Type: "storage.buckets", CreatedAt: daysAgo(30)},
+		// Exactly at both thresholds
+		{ID: "sql-edge", Type: "sql.instances", CreatedAt: daysAgo(180), UpdatedAt: daysAgo(90)},
+		// No creation time to judge by
+		{ID: "net-unknown", Type: "compute.networks"},
+	}
+}
+
+func TestBuildAgeReportStaleSet(t *testing.T) {
+	report, err := BuildAgeReport(ageResources(), AgeOptions{
+		MinAge:  180 * 24 * time.Hour,
+		MaxIdle: 90 * 24 * time.Hour,
+		Now:     ageNow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"vm-legacy", "bucket-archive", "sql-edge"}
+	if len(report.Stale) != len(want) {
+		t.Fatalf("expected stale %v, got %+v", want, report.Stale)
+	}
+	for i, id := range want {
+		if report.Stale[i].ID != id {
+			t.Errorf("stale %d = %s, want %s (oldest first)", i, report.Stale[i].ID, id)
+		}
+	}
+	if report.TotalResources != 6 || report.StaleResources != 3 || report.UnknownAge != 1 {
+		t.Errorf("counts = %d total, %d stale, %d unknown", report.TotalResources, report.StaleResources, report.UnknownAge)
+	}
+
+	archive := report.Stale[1]
+	if archive.AgeDays != 500 || archive.IdleDays != 200 || !archive.LastModified.Equal(ageNow.AddDate(0, 0, -200)) {
+		t.Errorf("unexpected archive age: %+v", archive)
+	}
+	if archive.Reason != "created 500 days ago, unchanged for 200 days" {
+		t.Errorf("reason = %q", archive.Reason)
+	}
+	// A resource never updated was last modified when it was created
+	if legacy := report.Stale[0]; legacy.IdleDays != 700 {
+		t.Errorf("legacy idle days = %d, want 700", legacy.IdleDays)
+	}
+}
+
+func TestBuildAgeReportGroupsByType(t *testing.T) {
+	report, err := BuildAgeReport(ageResources(), AgeOptions{
+		MinAge:  180 * 24 * time.Hour,
+		MaxIdle: 90 * 24 * time.Hour,
+		GroupBy: "type",
+		Now:     ageNow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AgeGroup{
+		{Type: "compute.instances", Resources: 2, Stale: 1, OldestAgeDays: 700, MaxIdleDays: 700},
+		{Type: "sql.instances", Resources: 1, Stale: 1, OldestAgeDays: 180, MaxIdleDays: 90},
+		{Type: "storage.buckets", Resources: 2, Stale: 1, OldestAgeDays: 500, MaxIdleDays: 200},
+		{Type: "compute.networks", Resources: 1},
+	}
+	if len(report.ByType) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), report.ByType)
+	}
+	for i, w := range want {
+		if report.ByType[i] != w {
+			t.Errorf("group %d = %+v, want %+v", i, report.ByType[i], w)
+		}
+	}
+}
+
+func TestBuildAgeReportThresholds(t *testing.T) {
+	// A stricter idle threshold leaves only the untouched resources
+	report, err := BuildAgeReport(ageResources(), AgeOptions{
+		MinAge:  365 * 24 * time.Hour,
+		MaxIdle: 365 * 24 * time.Hour,
+		Now:     ageNow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Stale) != 1 || report.Stale[0].ID != "vm-legacy" {
+		t.Errorf("expected only vm-legacy, got %+v", report.Stale)
+	}
+	if report.MinAgeDays != 365 || report.MaxIdleDays != 365 {
+		t.Errorf("thresholds = %d/%d days", report.MinAgeDays, report.MaxIdleDays)
+	}
+}
+
+func TestBuildAgeReportGroupBy(t *testing.T) {
+	report, err := BuildAgeReport(ageResources(), AgeOptions{Now: ageNow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ByType != nil {
+		t.Errorf("expected no groups without group-by, got %+v", report.ByType)
+	}
+	if _, err := BuildAgeReport(ageResources(), AgeOptions{GroupBy: "region"}); err == nil {
+		t.Error("expected group-by region to be rejected")
+	}
+}
@@ -51,6 +51,9 @@ type AnalysisOptions struct {
 	IncludeForecasts bool
 	IncludeAnomalies bool
 	CompareBaseline  bool
+	// MaxResources, if set, fails the analysis up front when the provider
+	// counts more resources in scope than this
+	MaxResources int
 }
 
 type AnalysisResults struct {
@@ -315,6 +318,10 @@ func (a *Analyzer) Analyze(ctx context.Context, options AnalysisOptions) (*Analy
 		Timestamp:       time.Now(),
 	}
 
+	if err := a.checkResourceQuota(ctx, options); err != nil {
+		return nil, err
+	}
+
 	resources, err := a.getResourcesToAnalyze(ctx, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get resources: %w", err)
@@ -376,6 +383,35 @@ func (a *Analyzer) Analyze(ctx context.Context, options AnalysisOptions) (*Analy
 	return results, nil
 }
 
+// checkResourceQuota counts the resources in scope, which is far cheaper than
+// listing them, and fails when there are more than options.MaxResources
+func (a *Analyzer) checkResourceQuota(ctx context.Context, options AnalysisOptions) error {
+	if options.MaxResources <= 0 || len(options.ResourceIDs) > 0 {
+		return nil
+	}
+
+	filters := make(map[string]interface{})
+	if options.ResourceType != "" {
+		filters["resource_types"] = []string{options.ResourceType}
+	}
+	counts, err := a.provider.CountResources(ctx, filters)
+	if err != nil {
+		// The pre-check is advisory; the analysis itself reports real failures
+		a.logger.Warnf("Skipping resource count pre-check: %v", err)
+		return nil
+	}
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total > options.MaxResources {
+		return fmt.Errorf("analysis would cover %d resources, more than the limit of %d; narrow it to a resource type", total, options.MaxResources)
+	}
+	a.logger.Debugf("Resource count pre-check: %d resources in scope", total)
+	return nil
+}
+
 func (a *Analyzer) getResourcesToAnalyze(ctx context.Context, options AnalysisOptions) ([]core.Resource, error) {
 	var resources []core.Resource
 
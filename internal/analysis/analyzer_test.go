@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+)
+
+// countingProvider counts resources cheaply and records full listings; the
+// embedded interface panics on anything else
+type countingProvider struct {
+	providers.Provider
+	counts       map[string]int
+	countFilters []map[string]interface{}
+	listed       int
+}
+
+func (p *countingProvider) CountResources(ctx context.Context, filters map[string]interface{}) (map[string]int, error) {
+	p.countFilters = append(p.countFilters, filters)
+	if types, ok := filters["resource_types"].([]string); ok {
+		counts := make(map[string]int)
+		for _, resourceType := range types {
+			counts[resourceType] = p.counts[resourceType]
+		}
+		return counts, nil
+	}
+	return p.counts, nil
+}
+
+func (p *countingProvider) ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]core.Resource, error) {
+	p.listed++
+	return nil, nil
+}
+
+func newTestAnalyzer(provider providers.Provider) *Analyzer {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewAnalyzer(provider, logger)
+}
+
+func TestAnalyzeRejectsScopeOverMaxResources(t *testing.T) {
+	provider := &countingProvider{counts: map[string]int{"compute.instances": 1200, "storage.buckets": 40}}
+	analyzer := newTestAnalyzer(provider)
+
+	_, err := analyzer.Analyze(context.Background(), AnalysisOptions{MaxResources: 1000})
+	if err == nil || !strings.Contains(err.Error(), "1240 resources") {
+		t.Fatalf("expected the pre-check to reject 1240 resources, got %v", err)
+	}
+	if provider.listed != 0 {
+		t.Fatalf("expected no full listing after the pre-check failed, got %d", provider.listed)
+	}
+
+	// Narrowed to one type the count fits and the analysis lists resources
+	if _, err := analyzer.Analyze(context.Background(), AnalysisOptions{ResourceType: "storage.buckets", MaxResources: 1000}); err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if got := provider.countFilters[1]["resource_types"]; len(got.([]string)) != 1 || got.([]string)[0] != "storage.buckets" {
+		t.Fatalf("expected the count narrowed to storage.buckets, got %v", got)
+	}
+	if provider.listed != 1 {
+		t.Fatalf("expected one listing once the pre-check passed, got %d", provider.listed)
+	}
+}
+
+func TestAnalyzeSkipsPreCheckWithoutLimit(t *testing.T) {
+	provider := &countingProvider{counts: map[string]int{"compute.instances": 5000}}
+	if _, err := newTestAnalyzer(provider).Analyze(context.Background(), AnalysisOptions{}); err != nil {
+		t.Fatalf("Analyze returned error: %v", err)
+	}
+	if len(provider.countFilters) != 0 {
+		t.Fatal("expected no count without MaxResources")
+	}
+}
@@ -0,0 +1,220 @@
+package analysis
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+)
+
+// untaggedTeam collects spend that no team label attributes
+const untaggedTeam = "untagged"
+
+// defaultTeamLabels are the labels looked up for a team, in order
+var defaultTeamLabels = []string{"team", "cost-center", "cost_center"}
+
+// ChargebackMapping is the mapping file of a chargeback report
+type ChargebackMapping struct {
+	// LabelKeys are the labels naming the team, first match wins
+	LabelKeys []string `json:"label_keys,omitempty"`
+	// Aliases maps a team to the other label values it goes by, such as
+	// "payments": ["pay", "payments-team"]
+	Aliases map[string][]string `json:"aliases,omitempty"`
+}
+
+// LoadChargebackMapping reads a JSON or YAML mapping file
+func LoadChargebackMapping(filename string) (*ChargebackMapping, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read team mapping: %w", err)
+	}
+	var mapping ChargebackMapping
+	if err := configschema.DecodeFormat(data, configschema.DetectFormat(filename, ""), &mapping); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &mapping, nil
+}
+
+// teamResolver finds the team of a set of labels through a mapping
+type teamResolver struct {
+	keys    []string
+	aliases map[string]string
+}
+
+func newTeamResolver(mapping *ChargebackMapping) *teamResolver {
+	r := &teamResolver{keys: defaultTeamLabels, aliases: make(map[string]string)}
+	if mapping == nil {
+		return r
+	}
+	if len(mapping.LabelKeys) > 0 {
+		r.keys = mapping.LabelKeys
+	}
+	for team, aliases := range mapping.Aliases {
+		for _, alias := range aliases {
+			r.aliases[normalizeTeam(alias)] = team
+		}
+	}
+	return r
+}
+
+func normalizeTeam(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// team returns the team labels name, and false when none of the team
+// labels is set
+func (r *teamResolver) team(labels map[string]string) (string, bool) {
+	for _, key := range r.keys {
+		value := normalizeTeam(labels[key])
+		if value == "" {
+			continue
+		}
+		if team, ok := r.aliases[value]; ok {
+			return team, true
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// TeamCost is the spend charged back to one team
+type TeamCost struct {
+	Team             string             `json:"team"`
+	Cost             float64            `json:"cost"`
+	Percentage       float64            `json:"percentage"`
+	LineItems        int                `json:"line_items"`
+	ServiceBreakdown map[string]float64 `json:"service_breakdown"`
+}
+
+// ChargebackReport breaks billed cost over a period down by team
+type ChargebackReport struct {
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      time.Time  `json:"end_date"`
+	Currency     string     `json:"currency"`
+	LabelKeys    []string   `json:"label_keys"`
+	TotalCost    float64    `json:"total_cost"`
+	UntaggedCost float64    `json:"untagged_cost"`
+	Teams        []TeamCost `json:"teams"`
+}
+
+// BuildChargeback attributes the billing line items between start and end
+// to teams. A line item's own labels are used when they name a team;
+// otherwise the labels of the resource it bills, matched by ID or name,
+// are. Spend neither names is reported as untagged.
+func BuildChargeback(billing []providers.BillingData, resources []core.Resource, mapping *ChargebackMapping, start, end time.Time) *ChargebackReport {
+	resolver := newTeamResolver(mapping)
+	resourceLabels := make(map[string]map[string]string)
+	for _, resource := range resources {
+		if resource.Name != "" {
+			resourceLabels[resource.Name] = resource.Tags
+		}
+		resourceLabels[resource.ID] = resource.Tags
+	}
+
+	report := &ChargebackReport{
+		StartDate: start,
+		EndDate:   end,
+		LabelKeys: resolver.keys,
+		Teams:     []TeamCost{},
+	}
+	teams := make(map[string]*TeamCost)
+	for _, item := range billing {
+		if item.Date.Before(start) || !item.Date.Before(end) {
+			continue
+		}
+		if report.Currency == "" {
+			report.Currency = item.Currency
+		}
+
+		team, ok := resolver.team(item.Tags)
+		if !ok {
+			team, ok = resolver.team(resourceLabels[item.Resource])
+		}
+		if !ok {
+			team = untaggedTeam
+			report.UntaggedCost += item.Cost
+		}
+
+		teamCost, exists := teams[team]
+		if !exists {
+			teamCost = &TeamCost{Team: team, ServiceBreakdown: make(map[string]float64)}
+			teams[team] = teamCost
+		}
+		teamCost.Cost += item.Cost
+		teamCost.LineItems++
+		teamCost.ServiceBreakdown[item.Service] += item.Cost
+		report.TotalCost += item.Cost
+	}
+
+	for _, teamCost := range teams {
+		if report.TotalCost > 0 {
+			teamCost.Percentage = teamCost.Cost / report.TotalCost * 100
+		}
+		report.Teams = append(report.Teams, *teamCost)
+	}
+	sort.Slice(report.Teams, func(i, j int) bool {
+		if report.Teams[i].Cost != report.Teams[j].Cost {
+			return report.Teams[i].Cost > report.Teams[j].Cost
+		}
+		return report.Teams[i].Team < report.Teams[j].Team
+	})
+	return report
+}
+
+// WriteCSV writes one row per team, for import into finance tools
+func (r *ChargebackReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"team", "cost", "currency", "percentage", "line_items", "start_date", "end_date"})
+	for _, team := range r.Teams {
+		writer.Write([]string{
+			team.Team,
+			strconv.FormatFloat(team.Cost, 'f', 2, 64),
+			r.Currency,
+			strconv.FormatFloat(team.Percentage, 'f', 2, 64),
+			strconv.Itoa(team.LineItems),
+			r.StartDate.Format("2006-01-02"),
+			r.EndDate.Format("2006-01-02"),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Chargeback joins the billing data of the period in options with the
+// current resource labels into a per-team report
+func (ca *CostAnalyzer) Chargeback(ctx context.Context, options CostAnalysisOptions, mapping *ChargebackMapping) (*ChargebackReport, error) {
+	if options.StartDate.IsZero() {
+		options.StartDate = time.Now().AddDate(0, -1, 0)
+	}
+	if options.EndDate.IsZero() {
+		options.EndDate = time.Now()
+	}
+	if !options.StartDate.Before(options.EndDate) {
+		return nil, fmt.Errorf("start date %s is not before end date %s",
+			options.StartDate.Format("2006-01-02"), options.EndDate.Format("2006-01-02"))
+	}
+
+	billing, err := ca.provider.GetBillingData(ctx, options.StartDate, options.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing data: %w", err)
+	}
+	resources, err := ca.provider.ListResources(ctx, "", options.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	report := BuildChargeback(billing, resources, mapping, options.StartDate, options.EndDate)
+	if report.Currency == "" {
+		report.Currency = ca.config.Currency
+	}
+	return report, nil
+}
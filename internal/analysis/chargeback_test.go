@@ -0,0 +1,178 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+)
+
+var chargebackStart = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+func chargebackBilling() []providers.BillingData {
+	item := func(day int, service, resource string, cost float64, tags map[string]string) providers.BillingData {
+		return providers.BillingData{
+			Date:     chargebackStart.AddDate(0, 0, day),
+			Service:  service,
+			Resource: resource,
+			Cost:     cost,
+			Currency: "USD",
+			Tags:     tags,
+		}
+	}
+	return []providers.BillingData{
+		item(0, "Compute Engine", "vm-1", 100, map[string]string{"team": "Payments"}),
+		item(1, "Compute Engine", "vm-1", 100, map[string]string{"team": "pay"}),
+		item(1, "Cloud SQL", "db-1", 80, map[string]string{"cost-center": "payments-team"}),
+		// No labels on the line item; the resource's labels attribute it
+		item(2, "Cloud Storage", "bucket-1", 40, nil),
+		item(2, "Cloud Storage", "bucket-2", 25, map[string]string{"environment": "prod"}),
+		item(3, "BigQuery", "dataset-1", 20, nil),
+		// Outside the period
+		item(40, "Compute Engine", "vm-1", 500, map[string]string{"team": "payments"}),
+	}
+}
+
+func chargebackResources() []core.Resource {
+	return []core.Resource{
+		{ID: "projects/p/buckets/bucket-1", Name: "bucket-1", Tags: map[string]string{"team": "search"}},
+		{ID: "bucket-2", Tags: map[string]string{"environment": "prod"}},
+	}
+}
+
+func chargebackMapping() *ChargebackMapping {
+	return &ChargebackMapping{Aliases: map[string][]string{"payments": {"pay", "Payments-Team"}}}
+}
+
+func TestBuildChargeback(t *testing.T) {
+	end := chargebackStart.AddDate(0, 1, 0)
+	report := BuildChargeback(chargebackBilling(), chargebackResources(), chargebackMapping(), chargebackStart, end)
+
+	want := []struct {
+		team      string
+		cost      float64
+		lineItems int
+	}{
+		{"payments", 280, 3},
+		{untaggedTeam, 45, 2},
+		{"search", 40, 1},
+	}
+	if len(report.Teams) != len(want) {
+		t.Fatalf("expected %d teams, got %+v", len(want), report.Teams)
+	}
+	for i, w := range want {
+		team := report.Teams[i]
+		if team.Team != w.team || team.Cost != w.cost || team.LineItems != w.lineItems {
+			t.Errorf("team %d = %s %.2f (%d items), want %s %.2f (%d items)",
+				i, team.Team, team.Cost, team.LineItems, w.team, w.cost, w.lineItems)
+		}
+	}
+
+	if report.TotalCost != 365 {
+		t.Errorf("total cost = %.2f, want 365", report.TotalCost)
+	}
+	if report.UntaggedCost != 45 {
+		t.Errorf("untagged cost = %.2f, want 45", report.UntaggedCost)
+	}
+	if report.Currency != "USD" {
+		t.Errorf("currency = %q", report.Currency)
+	}
+	payments := report.Teams[0]
+	if payments.ServiceBreakdown["Compute Engine"] != 200 || payments.ServiceBreakdown["Cloud SQL"] != 80 {
+		t.Errorf("unexpected service breakdown for payments: %v", payments.ServiceBreakdown)
+	}
+	if payments.Percentage < 76.71 || payments.Percentage > 76.72 {
+		t.Errorf("payments share = %.2f%%", payments.Percentage)
+	}
+}
+
+func TestBuildChargebackLabelKeys(t *testing.T) {
+	end := chargebackStart.AddDate(0, 1, 0)
+	// Only cost-center counts, so team labels are ignored
+	report := BuildChargeback(chargebackBilling(), chargebackResources(), &ChargebackMapping{LabelKeys: []string{"cost-center"}}, chargebackStart, end)
+
+	if len(report.Teams) != 2 || report.Teams[0].Team != untaggedTeam || report.Teams[1].Team != "payments-team" {
+		t.Fatalf("unexpected teams: %+v", report.Teams)
+	}
+	if report.UntaggedCost != 285 {
+		t.Errorf("untagged cost = %.2f, want 285", report.UntaggedCost)
+	}
+}
+
+func TestChargebackWriteCSV(t *testing.T) {
+	end := chargebackStart.AddDate(0, 1, 0)
+	report := BuildChargeback(chargebackBilling(), chargebackResources(), chargebackMapping(), chargebackStart, end)
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected a header and 3 rows, got %v", records)
+	}
+	if got := records[1]; got[0] != "payments" || got[1] != "280.00" || got[2] != "USD" || got[4] != "3" || got[5] != "2026-09-01" || got[6] != "2026-10-01" {
+		t.Errorf("unexpected payments row: %v", got)
+	}
+	if records[2][0] != untaggedTeam {
+		t.Errorf("expected untagged spend in the CSV, got %v", records[2])
+	}
+}
+
+// billingProvider serves fixed billing data and resources; the embedded
+// interface panics on anything else
+type billingProvider struct {
+	providers.Provider
+	billing   []providers.BillingData
+	resources []core.Resource
+}
+
+func (p *billingProvider) GetBillingData(ctx context.Context, startDate, endDate time.Time) ([]providers.BillingData, error) {
+	return p.billing, nil
+}
+
+func (p *billingProvider) ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]core.Resource, error) {
+	return p.resources, nil
+}
+
+func TestCostAnalyzerChargeback(t *testing.T) {
+	provider := &billingProvider{billing: chargebackBilling(), resources: chargebackResources()}
+	analyzer := newTestCostAnalyzer()
+	analyzer.provider = provider
+
+	path := filepath.Join(t.TempDir(), "teams.yaml")
+	if err := os.WriteFile(path, []byte("aliases:\n  payments: [pay, payments-team]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := LoadChargebackMapping(path)
+	if err != nil {
+		t.Fatalf("LoadChargebackMapping: %v", err)
+	}
+
+	report, err := analyzer.Chargeback(context.Background(), CostAnalysisOptions{
+		StartDate: chargebackStart,
+		EndDate:   chargebackStart.AddDate(0, 1, 0),
+	}, mapping)
+	if err != nil {
+		t.Fatalf("Chargeback: %v", err)
+	}
+	if report.Teams[0].Team != "payments" || report.Teams[0].Cost != 280 {
+		t.Errorf("unexpected top team: %+v", report.Teams[0])
+	}
+
+	if _, err := analyzer.Chargeback(context.Background(), CostAnalysisOptions{
+		StartDate: chargebackStart,
+		EndDate:   chargebackStart,
+	}, nil); err == nil {
+		t.Error("expected an empty period to be rejected")
+	}
+}
@@ -8,11 +8,9 @@ import (
 	"time"
 
 	billing "cloud.google.com/go/billing/apiv1"
-	"cloud.google.com/go/billing/apiv1/billingpb"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
 	"go.uber.org/zap"
 	"google.golang.org/api/cloudbilling/v1"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -20,6 +18,7 @@ import (
 type Calculator struct {
 	billingClient  *billing.CloudCatalogClient
 	billingService *cloudbilling.APIService
+	pricing        PricingSource
 	logger         *zap.Logger
 	cache          *PriceCache
 	projectID      string
@@ -44,8 +43,9 @@ type PriceInfo struct {
 	LastUpdated  time.Time
 }
 
-// NewCalculator creates a new cost calculator
-func NewCalculator(projectID string, opts ...option.ClientOption) (*Calculator, error) {
+// NewCalculator creates a new cost calculator, estimating from the list
+// prices of the pricing source config selects
+func NewCalculator(projectID string, config PricingConfig, opts ...option.ClientOption) (*Calculator, error) {
 	ctx := context.Background()
 
 	// Create billing catalog client
@@ -61,11 +61,18 @@ func NewCalculator(projectID string, opts ...option.ClientOption) (*Calculator,
 		return nil, fmt.Errorf("failed to create billing service: %w", err)
 	}
 
+	pricing, err := configuredPricingSource(config, catalogClient{billingClient})
+	if err != nil {
+		billingClient.Close()
+		return nil, err
+	}
+
 	logger := zap.L().Named("cost-calculator")
 
 	return &Calculator{
 		billingClient:  billingClient,
 		billingService: billingService,
+		pricing:        pricing,
 		logger:         logger,
 		projectID:      projectID,
 		cache: &PriceCache{
@@ -75,6 +82,22 @@ func NewCalculator(projectID string, opts ...option.ClientOption) (*Calculator,
 	}, nil
 }
 
+// NewCalculatorWithPricing creates a cost calculator estimating from the
+// list prices of pricing, such as one chosen by NewPricingSource. It works
+// without billing export data, so resources can be priced before they are
+// deployed.
+func NewCalculatorWithPricing(projectID string, pricing PricingSource) *Calculator {
+	return &Calculator{
+		pricing:   pricing,
+		logger:    zap.L().Named("cost-calculator"),
+		projectID: projectID,
+		cache: &PriceCache{
+			prices: make(map[string]*PriceInfo),
+			ttl:    24 * time.Hour,
+		},
+	}
+}
+
 // CalculateResourceCost calculates the cost for a single resource
 func (c *Calculator) CalculateResourceCost(ctx context.Context, resource core.Resource) (float64, error) {
 	c.logger.Debug("Calculating cost for resource",
@@ -89,6 +112,17 @@ func (c *Calculator) CalculateResourceCost(ctx context.Context, resource core.Re
 		return 0.0, nil // Return 0 for unknown types instead of error
 	}
 
+	// Instances of a known machine type are priced from their cores and memory
+	if machineType, ok := resource.Properties["machine_type"].(string); ok && strings.Contains(resource.Type, "compute_instance") {
+		cost, err := EstimateInstanceMonthlyCost(ctx, c.pricing, machineType, resourceRegion(resource))
+		if err == nil {
+			return cost, nil
+		}
+		c.logger.Debug("Falling back to service pricing",
+			zap.String("machine_type", machineType),
+			zap.Error(err))
+	}
+
 	// Get pricing information
 	priceInfo, err := c.getPricing(ctx, service, resource.Type)
 	if err != nil {
@@ -195,7 +229,7 @@ func (c *Calculator) mapResourceTypeToService(resourceType string) string {
 	return ""
 }
 
-// getPricing retrieves pricing information from GCP Billing API with caching
+// getPricing retrieves pricing information from the pricing source with caching
 func (c *Calculator) getPricing(ctx context.Context, service string, resourceType string) (*PriceInfo, error) {
 	cacheKey := fmt.Sprintf("%s:%s", service, resourceType)
 
@@ -210,46 +244,11 @@ func (c *Calculator) getPricing(ctx context.Context, service string, resourceTyp
 	}
 	c.cache.mu.RUnlock()
 
-	// Fetch from API
-	c.logger.Debug("Fetching pricing from API", zap.String("service", service))
-
-	req := &billingpb.ListSkusRequest{
-		Parent: service,
-	}
-
-	it := c.billingClient.ListSkus(ctx, req)
-
-	// Get first SKU (simplified - in production, you'd filter by region, etc.)
-	sku, err := it.Next()
-	if err == iterator.Done {
-		return nil, fmt.Errorf("no SKUs found for service: %s", service)
-	}
+	priceInfo, err := c.pricing.Price(ctx, PriceQuery{Service: service})
 	if err != nil {
-		return nil, fmt.Errorf("listing SKUs: %w", err)
-	}
-
-	// Extract pricing information
-	priceInfo := &PriceInfo{
-		SKU:         sku.Name,
-		Description: sku.Description,
-		Currency:    "USD",
-		LastUpdated: time.Now(),
-	}
-
-	// Get price from pricing info
-	if len(sku.PricingInfo) > 0 {
-		pricingInfo := sku.PricingInfo[0]
-		if pricingInfo.PricingExpression != nil {
-			if len(pricingInfo.PricingExpression.TieredRates) > 0 {
-				rate := pricingInfo.PricingExpression.TieredRates[0]
-				if rate.UnitPrice != nil {
-					// Convert to float (units + nanos)
-					priceInfo.PricePerUnit = float64(rate.UnitPrice.Units) + float64(rate.UnitPrice.Nanos)/1e9
-				}
-			}
-			priceInfo.Unit = pricingInfo.PricingExpression.UsageUnit
-		}
+		return nil, err
 	}
+	priceInfo.LastUpdated = time.Now()
 
 	// Cache the result
 	c.cache.mu.Lock()
@@ -308,6 +307,9 @@ func (c *Calculator) calculateCostFromConfig(resource core.Resource, priceInfo *
 
 // GetCurrentBillingInfo retrieves current billing information for the project
 func (c *Calculator) GetCurrentBillingInfo(ctx context.Context) (*cloudbilling.ProjectBillingInfo, error) {
+	if c.billingService == nil {
+		return nil, fmt.Errorf("getting billing info: no billing service configured")
+	}
 	projectName := fmt.Sprintf("projects/%s", c.projectID)
 
 	billingInfo, err := c.billingService.Projects.GetBillingInfo(projectName).Context(ctx).Do()
@@ -318,6 +320,18 @@ func (c *Calculator) GetCurrentBillingInfo(ctx context.Context) (*cloudbilling.P
 	return billingInfo, nil
 }
 
+// resourceRegion returns the region of a resource, deriving it from the
+// zone when only that is set
+func resourceRegion(resource core.Resource) string {
+	if resource.Region != "" || resource.Zone == "" {
+		return resource.Region
+	}
+	if i := strings.LastIndex(resource.Zone, "-"); i > 0 {
+		return resource.Zone[:i]
+	}
+	return resource.Zone
+}
+
 // Close closes the calculator and releases resources
 func (c *Calculator) Close() error {
 	if c.billingClient != nil {
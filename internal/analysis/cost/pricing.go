@@ -0,0 +1,443 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	billing "cloud.google.com/go/billing/apiv1"
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	// PricingSourceCatalog prices from Cloud Billing Catalog list prices
+	PricingSourceCatalog = "catalog"
+	// PricingSourceStatic prices from a cached price file, or the built-in
+	// prices when there is none, without calling any API
+	PricingSourceStatic = "static"
+
+	computeEngineService = "services/6F81-5844-456A"
+	onDemandUsage        = "OnDemand"
+	hoursPerMonth        = 730
+)
+
+// PricingConfig selects where list prices come from
+type PricingConfig struct {
+	// Source is catalog (the default) or static
+	Source string `json:"source" yaml:"source"`
+	// CacheFile holds prices fetched from the catalog, so they can be used
+	// offline with the static source or when the catalog is unreachable
+	CacheFile string `json:"cache_file" yaml:"cache_file"`
+	// CacheTTL is how long catalog SKUs are reused before being fetched again
+	CacheTTL time.Duration `json:"cache_ttl" yaml:"cache_ttl"`
+}
+
+// PriceQuery identifies a list price
+type PriceQuery struct {
+	// Service is the catalog service name, e.g. services/6F81-5844-456A
+	Service string
+	// Description is a prefix of the SKU description, e.g. "N1 Predefined
+	// Instance Core"; empty matches any SKU of the service
+	Description string
+	// Region the SKU must be offered in; empty matches any region
+	Region string
+	// UsageType defaults to OnDemand
+	UsageType string
+}
+
+// PricingSource looks up the list price of a SKU
+type PricingSource interface {
+	Price(ctx context.Context, query PriceQuery) (*PriceInfo, error)
+}
+
+// ErrPriceNotFound is returned when no SKU matches a query
+var ErrPriceNotFound = errors.New("price not found")
+
+// NewPricingSource returns the pricing source selected by config. The
+// catalog source falls back to the static prices when the catalog can't be
+// reached.
+func NewPricingSource(ctx context.Context, config PricingConfig, opts ...option.ClientOption) (PricingSource, error) {
+	var catalog SKUCatalog
+	if config.Source == "" || config.Source == PricingSourceCatalog {
+		client, err := billing.NewCloudCatalogClient(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create billing catalog client: %w", err)
+		}
+		catalog = catalogClient{client}
+	}
+	return configuredPricingSource(config, catalog)
+}
+
+// configuredPricingSource returns the pricing source config selects, reading
+// list prices from catalog for the catalog source
+func configuredPricingSource(config PricingConfig, catalog SKUCatalog) (PricingSource, error) {
+	static, err := NewStaticPricingSource(config.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Source {
+	case PricingSourceStatic:
+		return static, nil
+	case "", PricingSourceCatalog:
+		return &fallbackPricingSource{primary: NewCatalogPricingSource(catalog, config), fallback: static}, nil
+	default:
+		return nil, fmt.Errorf("unknown pricing source %q, expected %s or %s", config.Source, PricingSourceCatalog, PricingSourceStatic)
+	}
+}
+
+// SKUCatalog lists the SKUs of a Cloud Billing Catalog service
+type SKUCatalog interface {
+	ListSkus(ctx context.Context, service string) ([]*billingpb.Sku, error)
+}
+
+type catalogClient struct {
+	client *billing.CloudCatalogClient
+}
+
+func (c catalogClient) ListSkus(ctx context.Context, service string) ([]*billingpb.Sku, error) {
+	var skus []*billingpb.Sku
+	it := c.client.ListSkus(ctx, &billingpb.ListSkusRequest{Parent: service})
+	for {
+		sku, err := it.Next()
+		if err == iterator.Done {
+			return skus, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing SKUs: %w", err)
+		}
+		skus = append(skus, sku)
+	}
+}
+
+// CatalogPricingSource prices from the list prices of the Cloud Billing
+// Catalog, keeping each service's SKUs for the cache TTL
+type CatalogPricingSource struct {
+	catalog   SKUCatalog
+	cacheFile string
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	skus    map[string][]*billingpb.Sku
+	fetched map[string]time.Time
+}
+
+// NewCatalogPricingSource creates a pricing source reading SKUs from catalog
+func NewCatalogPricingSource(catalog SKUCatalog, config PricingConfig) *CatalogPricingSource {
+	ttl := config.CacheTTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	return &CatalogPricingSource{
+		catalog:   catalog,
+		cacheFile: config.CacheFile,
+		ttl:       ttl,
+		skus:      make(map[string][]*billingpb.Sku),
+		fetched:   make(map[string]time.Time),
+	}
+}
+
+// Price returns the list price of the first SKU matching query
+func (s *CatalogPricingSource) Price(ctx context.Context, query PriceQuery) (*PriceInfo, error) {
+	skus, err := s.serviceSkus(ctx, query.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sku := range skus {
+		if sku.Category == nil {
+			continue
+		}
+		if !query.matches(sku.Description, sku.Category.UsageType, sku.ServiceRegions) {
+			continue
+		}
+		price, unit, ok := listPrice(sku)
+		if !ok {
+			continue
+		}
+		return &PriceInfo{
+			SKU:          sku.Name,
+			Description:  sku.Description,
+			PricePerUnit: price,
+			Unit:         unit,
+			Currency:     "USD",
+			LastUpdated:  time.Now(),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPriceNotFound, query)
+}
+
+func (s *CatalogPricingSource) serviceSkus(ctx context.Context, service string) ([]*billingpb.Sku, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if skus, ok := s.skus[service]; ok && time.Since(s.fetched[service]) < s.ttl {
+		return skus, nil
+	}
+
+	skus, err := s.catalog.ListSkus(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	s.skus[service] = skus
+	s.fetched[service] = time.Now()
+
+	if s.cacheFile != "" {
+		if err := s.writeCache(); err != nil {
+			return nil, err
+		}
+	}
+	return skus, nil
+}
+
+// writeCache saves the list prices of every fetched SKU for the static
+// source to read
+func (s *CatalogPricingSource) writeCache() error {
+	var prices []StaticPrice
+	for service, skus := range s.skus {
+		for _, sku := range skus {
+			price, unit, ok := listPrice(sku)
+			if !ok || sku.Category == nil {
+				continue
+			}
+			for _, region := range sku.ServiceRegions {
+				prices = append(prices, StaticPrice{
+					Service:      service,
+					SKU:          sku.Name,
+					Description:  sku.Description,
+					Region:       region,
+					UsageType:    sku.Category.UsageType,
+					Unit:         unit,
+					PricePerUnit: price,
+				})
+			}
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if prices[i].SKU != prices[j].SKU {
+			return prices[i].SKU < prices[j].SKU
+		}
+		return prices[i].Region < prices[j].Region
+	})
+
+	data, err := json.MarshalIndent(prices, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding price cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cacheFile), 0o755); err != nil {
+		return fmt.Errorf("writing price cache: %w", err)
+	}
+	if err := os.WriteFile(s.cacheFile, data, 0o644); err != nil {
+		return fmt.Errorf("writing price cache: %w", err)
+	}
+	return nil
+}
+
+// listPrice returns the current on-demand unit price of a SKU, skipping
+// free tiers
+func listPrice(sku *billingpb.Sku) (float64, string, bool) {
+	if len(sku.PricingInfo) == 0 {
+		return 0, "", false
+	}
+	expression := sku.PricingInfo[len(sku.PricingInfo)-1].PricingExpression
+	if expression == nil {
+		return 0, "", false
+	}
+	for _, rate := range expression.TieredRates {
+		if rate.UnitPrice == nil {
+			continue
+		}
+		price := float64(rate.UnitPrice.Units) + float64(rate.UnitPrice.Nanos)/1e9
+		if price > 0 {
+			return price, expression.UsageUnit, true
+		}
+	}
+	return 0, "", false
+}
+
+// StaticPrice is one list price of a static pricing source
+type StaticPrice struct {
+	Service      string  `json:"service"`
+	SKU          string  `json:"sku,omitempty"`
+	Description  string  `json:"description"`
+	Region       string  `json:"region"`
+	UsageType    string  `json:"usage_type"`
+	Unit         string  `json:"unit"`
+	PricePerUnit float64 `json:"price_per_unit"`
+}
+
+// defaultStaticPrices are us-central1 on-demand list prices used when no
+// price cache has been written yet
+var defaultStaticPrices = []StaticPrice{
+	{Service: computeEngineService, Description: "N1 Predefined Instance Core running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "h", PricePerUnit: 0.031611},
+	{Service: computeEngineService, Description: "N1 Predefined Instance Ram running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "GiBy.h", PricePerUnit: 0.004237},
+	{Service: computeEngineService, Description: "N2 Instance Core running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "h", PricePerUnit: 0.031611},
+	{Service: computeEngineService, Description: "N2 Instance Ram running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "GiBy.h", PricePerUnit: 0.004237},
+	{Service: computeEngineService, Description: "N2D AMD Instance Core running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "h", PricePerUnit: 0.027502},
+	{Service: computeEngineService, Description: "N2D AMD Instance Ram running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "GiBy.h", PricePerUnit: 0.003686},
+	{Service: computeEngineService, Description: "E2 Instance Core running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "h", PricePerUnit: 0.021811},
+	{Service: computeEngineService, Description: "E2 Instance Ram running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "GiBy.h", PricePerUnit: 0.002923},
+	{Service: computeEngineService, Description: "Compute optimized Core running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "h", PricePerUnit: 0.03398},
+	{Service: computeEngineService, Description: "Compute optimized Ram running in Americas", Region: "us-central1", UsageType: onDemandUsage, Unit: "GiBy.h", PricePerUnit: 0.00455},
+}
+
+// StaticPricingSource prices from a fixed price list and never calls an API
+type StaticPricingSource struct {
+	prices []StaticPrice
+}
+
+// NewStaticPricingSource loads prices from the cache file written by the
+// catalog source. With no path, or no file yet, the built-in prices are used.
+func NewStaticPricingSource(path string) (*StaticPricingSource, error) {
+	if path == "" {
+		return &StaticPricingSource{prices: defaultStaticPrices}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &StaticPricingSource{prices: defaultStaticPrices}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading price cache: %w", err)
+	}
+
+	var prices []StaticPrice
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("parsing price cache %s: %w", path, err)
+	}
+	return &StaticPricingSource{prices: prices}, nil
+}
+
+// Price returns the first static price matching query
+func (s *StaticPricingSource) Price(_ context.Context, query PriceQuery) (*PriceInfo, error) {
+	for _, price := range s.prices {
+		if price.Service != query.Service {
+			continue
+		}
+		if !query.matches(price.Description, price.UsageType, []string{price.Region}) {
+			continue
+		}
+		return &PriceInfo{
+			SKU:          price.SKU,
+			Description:  price.Description,
+			PricePerUnit: price.PricePerUnit,
+			Unit:         price.Unit,
+			Currency:     "USD",
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPriceNotFound, query)
+}
+
+// fallbackPricingSource prices from fallback when primary fails for any
+// reason other than the price not existing
+type fallbackPricingSource struct {
+	primary  PricingSource
+	fallback PricingSource
+}
+
+func (s *fallbackPricingSource) Price(ctx context.Context, query PriceQuery) (*PriceInfo, error) {
+	info, err := s.primary.Price(ctx, query)
+	if err == nil || errors.Is(err, ErrPriceNotFound) || ctx.Err() != nil {
+		return info, err
+	}
+	if info, fallbackErr := s.fallback.Price(ctx, query); fallbackErr == nil {
+		return info, nil
+	}
+	return nil, err
+}
+
+func (q PriceQuery) matches(description, usageType string, regions []string) bool {
+	if !strings.HasPrefix(description, q.Description) {
+		return false
+	}
+
+	wantUsage := q.UsageType
+	if wantUsage == "" {
+		wantUsage = onDemandUsage
+	}
+	if usageType != wantUsage {
+		return false
+	}
+
+	if q.Region == "" {
+		return true
+	}
+	for _, region := range regions {
+		if region == q.Region {
+			return true
+		}
+	}
+	return false
+}
+
+func (q PriceQuery) String() string {
+	s := q.Service
+	if q.Description != "" {
+		s += " " + strconv.Quote(q.Description)
+	}
+	if q.Region != "" {
+		s += " in " + q.Region
+	}
+	return s
+}
+
+// machineFamily describes how a machine series is billed: the SKU
+// description prefixes of its cores and memory, and the memory per vCPU of
+// each predefined shape
+type machineFamily struct {
+	core   string
+	ram    string
+	shapes map[string]float64
+}
+
+var machineFamilies = map[string]machineFamily{
+	"n1":  {core: "N1 Predefined Instance Core", ram: "N1 Predefined Instance Ram", shapes: map[string]float64{"standard": 3.75, "highmem": 6.5, "highcpu": 0.9}},
+	"n2":  {core: "N2 Instance Core", ram: "N2 Instance Ram", shapes: map[string]float64{"standard": 4, "highmem": 8, "highcpu": 1}},
+	"n2d": {core: "N2D AMD Instance Core", ram: "N2D AMD Instance Ram", shapes: map[string]float64{"standard": 4, "highmem": 8, "highcpu": 1}},
+	"e2":  {core: "E2 Instance Core", ram: "E2 Instance Ram", shapes: map[string]float64{"standard": 4, "highmem": 8, "highcpu": 1}},
+	"c2":  {core: "Compute optimized Core", ram: "Compute optimized Ram", shapes: map[string]float64{"standard": 4}},
+}
+
+// EstimateInstanceMonthlyCost estimates the monthly cost of a Compute
+// Engine instance of a predefined machine type, such as n2-standard-4,
+// running all month at the region's on-demand list prices
+func EstimateInstanceMonthlyCost(ctx context.Context, source PricingSource, machineType, region string) (float64, error) {
+	parts := strings.Split(machineType, "-")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("unsupported machine type %q", machineType)
+	}
+	family, ok := machineFamilies[parts[0]]
+	if !ok {
+		return 0, fmt.Errorf("unsupported machine family %q", parts[0])
+	}
+	memoryPerCPU, ok := family.shapes[parts[1]]
+	if !ok {
+		return 0, fmt.Errorf("unsupported machine type %q", machineType)
+	}
+	cpus, err := strconv.Atoi(parts[2])
+	if err != nil || cpus <= 0 {
+		return 0, fmt.Errorf("unsupported machine type %q", machineType)
+	}
+
+	core, err := source.Price(ctx, PriceQuery{Service: computeEngineService, Description: family.core, Region: region})
+	if err != nil {
+		return 0, err
+	}
+	ram, err := source.Price(ctx, PriceQuery{Service: computeEngineService, Description: family.ram, Region: region})
+	if err != nil {
+		return 0, err
+	}
+
+	hourly := float64(cpus)*core.PricePerUnit + float64(cpus)*memoryPerCPU*ram.PricePerUnit
+	return hourly * hoursPerMonth, nil
+}
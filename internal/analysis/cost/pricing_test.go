@@ -0,0 +1,202 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/billing/apiv1/billingpb"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"google.golang.org/genproto/googleapis/type/money"
+)
+
+type fakeCatalog struct {
+	skus  map[string][]*billingpb.Sku
+	err   error
+	calls int
+}
+
+func (c *fakeCatalog) ListSkus(_ context.Context, service string) ([]*billingpb.Sku, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.skus[service], nil
+}
+
+func fakeSku(id, description, usageType string, nanos int32, regions ...string) *billingpb.Sku {
+	return &billingpb.Sku{
+		Name:           computeEngineService + "/skus/" + id,
+		Description:    description,
+		Category:       &billingpb.Category{ResourceFamily: "Compute", UsageType: usageType},
+		ServiceRegions: regions,
+		PricingInfo: []*billingpb.PricingInfo{{
+			PricingExpression: &billingpb.PricingExpression{
+				UsageUnit: "h",
+				TieredRates: []*billingpb.PricingExpression_TierRate{
+					{UnitPrice: &money.Money{CurrencyCode: "USD", Nanos: nanos}},
+				},
+			},
+		}},
+	}
+}
+
+func newFakeCatalog() *fakeCatalog {
+	return &fakeCatalog{skus: map[string][]*billingpb.Sku{
+		computeEngineService: {
+			fakeSku("PREEMPT", "N2 Instance Core running in Americas", "Preemptible", 7_000_000, "us-central1"),
+			fakeSku("SOLE", "N2 Sole Tenancy Instance Core running in Americas", onDemandUsage, 50_000_000, "us-central1"),
+			fakeSku("EU-CORE", "N2 Instance Core running in EMEA", onDemandUsage, 34_800_000, "europe-west1"),
+			fakeSku("CORE", "N2 Instance Core running in Americas", onDemandUsage, 31_611_000, "us-central1", "us-east1"),
+			fakeSku("RAM", "N2 Instance Ram running in Americas", onDemandUsage, 4_237_000, "us-central1", "us-east1"),
+		},
+	}}
+}
+
+func TestEstimateInstanceMonthlyCostFromCatalog(t *testing.T) {
+	source := NewCatalogPricingSource(newFakeCatalog(), PricingConfig{})
+
+	got, err := EstimateInstanceMonthlyCost(context.Background(), source, "n2-standard-4", "us-central1")
+	if err != nil {
+		t.Fatalf("EstimateInstanceMonthlyCost: %v", err)
+	}
+
+	// 4 vCPUs and 16 GB at on-demand list prices for 730 hours
+	want := (4*0.031611 + 16*0.004237) * 730
+	if math.Abs(got-want) > 1e-6 {
+		t.Errorf("monthly cost = %f, want %f", got, want)
+	}
+}
+
+func TestEstimateInstanceMonthlyCostErrors(t *testing.T) {
+	source := NewCatalogPricingSource(newFakeCatalog(), PricingConfig{})
+
+	for _, machineType := range []string{"n2-standard", "z9-standard-4", "n2-ultramem-4", "n2-standard-x"} {
+		if _, err := EstimateInstanceMonthlyCost(context.Background(), source, machineType, "us-central1"); err == nil {
+			t.Errorf("%s: expected an error", machineType)
+		}
+	}
+
+	_, err := EstimateInstanceMonthlyCost(context.Background(), source, "n2-standard-4", "asia-east1")
+	if !errors.Is(err, ErrPriceNotFound) {
+		t.Errorf("unknown region: expected ErrPriceNotFound, got %v", err)
+	}
+}
+
+func TestCatalogPricingSourceCachesSkus(t *testing.T) {
+	catalog := newFakeCatalog()
+	source := NewCatalogPricingSource(catalog, PricingConfig{})
+
+	for i := 0; i < 3; i++ {
+		if _, err := source.Price(context.Background(), PriceQuery{Service: computeEngineService, Description: "N2 Instance Core"}); err != nil {
+			t.Fatalf("Price: %v", err)
+		}
+	}
+	if catalog.calls != 1 {
+		t.Errorf("catalog listed %d times, want 1", catalog.calls)
+	}
+}
+
+func TestStaticPricingSourceReadsCatalogCache(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "prices", "cache.json")
+	catalog := NewCatalogPricingSource(newFakeCatalog(), PricingConfig{CacheFile: cacheFile})
+	online, err := EstimateInstanceMonthlyCost(context.Background(), catalog, "n2-standard-2", "us-east1")
+	if err != nil {
+		t.Fatalf("catalog estimate: %v", err)
+	}
+
+	static, err := NewPricingSource(context.Background(), PricingConfig{Source: PricingSourceStatic, CacheFile: cacheFile})
+	if err != nil {
+		t.Fatalf("NewPricingSource: %v", err)
+	}
+	offline, err := EstimateInstanceMonthlyCost(context.Background(), static, "n2-standard-2", "us-east1")
+	if err != nil {
+		t.Fatalf("static estimate: %v", err)
+	}
+	if math.Abs(online-offline) > 1e-6 {
+		t.Errorf("static estimate %f differs from catalog estimate %f", offline, online)
+	}
+}
+
+func TestStaticPricingSourceDefaults(t *testing.T) {
+	source, err := NewStaticPricingSource(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("NewStaticPricingSource: %v", err)
+	}
+	cost, err := EstimateInstanceMonthlyCost(context.Background(), source, "e2-standard-2", "us-central1")
+	if err != nil {
+		t.Fatalf("EstimateInstanceMonthlyCost: %v", err)
+	}
+	if cost <= 0 {
+		t.Errorf("expected a built-in price, got %f", cost)
+	}
+}
+
+func TestFallbackPricingSource(t *testing.T) {
+	catalog := NewCatalogPricingSource(&fakeCatalog{err: errors.New("connection refused")}, PricingConfig{})
+	source := &fallbackPricingSource{primary: catalog, fallback: &StaticPricingSource{prices: defaultStaticPrices}}
+
+	info, err := source.Price(context.Background(), PriceQuery{Service: computeEngineService, Description: "N1 Predefined Instance Core", Region: "us-central1"})
+	if err != nil {
+		t.Fatalf("Price: %v", err)
+	}
+	if info.PricePerUnit != 0.031611 {
+		t.Errorf("price = %f, want the static price", info.PricePerUnit)
+	}
+
+	if _, err := NewPricingSource(context.Background(), PricingConfig{Source: "billing-export"}); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+}
+
+func TestConfiguredPricingSourceFallsBackToCacheFile(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+	online, err := EstimateInstanceMonthlyCost(context.Background(), NewCatalogPricingSource(newFakeCatalog(), PricingConfig{CacheFile: cacheFile}), "n2-standard-2", "us-east1")
+	if err != nil {
+		t.Fatalf("catalog estimate: %v", err)
+	}
+
+	source, err := configuredPricingSource(PricingConfig{CacheFile: cacheFile}, &fakeCatalog{err: errors.New("connection refused")})
+	if err != nil {
+		t.Fatalf("configuredPricingSource: %v", err)
+	}
+	offline, err := EstimateInstanceMonthlyCost(context.Background(), source, "n2-standard-2", "us-east1")
+	if err != nil {
+		t.Fatalf("fallback estimate: %v", err)
+	}
+	if math.Abs(online-offline) > 1e-6 {
+		t.Errorf("fallback estimate %f differs from cached catalog estimate %f", offline, online)
+	}
+
+	static, err := configuredPricingSource(PricingConfig{Source: PricingSourceStatic}, nil)
+	if err != nil {
+		t.Fatalf("configuredPricingSource: %v", err)
+	}
+	if _, ok := static.(*StaticPricingSource); !ok {
+		t.Errorf("static source = %T, want *StaticPricingSource", static)
+	}
+	if _, err := configuredPricingSource(PricingConfig{Source: "billing-export"}, nil); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+}
+
+func TestCalculatorPricesInstanceFromMachineType(t *testing.T) {
+	calculator := NewCalculatorWithPricing("my-project", NewCatalogPricingSource(newFakeCatalog(), PricingConfig{}))
+
+	cost, err := calculator.CalculateResourceCost(context.Background(), core.Resource{
+		ID:         "web-1",
+		Type:       "google_compute_instance",
+		Zone:       "us-central1-a",
+		Properties: map[string]interface{}{"machine_type": "n2-standard-2"},
+	})
+	if err != nil {
+		t.Fatalf("CalculateResourceCost: %v", err)
+	}
+
+	want := (2*0.031611 + 8*0.004237) * 730
+	if math.Abs(cost-want) > 1e-6 {
+		t.Errorf("cost = %f, want %f", cost, want)
+	}
+}
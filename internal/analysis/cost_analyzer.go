@@ -32,6 +32,8 @@ type CostAnalyzerConfig struct {
 	PricingTiers       map[string]PricingTier
 	CostAlerts         []CostAlert
 	BudgetLimits       map[string]float64
+	AnomalyWindow      int
+	AnomalyThreshold   float64
 }
 
 type PricingTier struct {
@@ -80,6 +82,7 @@ type CostAnalysisOptions struct {
 type CostAnalysisResults struct {
 	Summary         CostAnalysisSummary       `json:"summary"`
 	Breakdown       CostBreakdown             `json:"breakdown"`
+	Groups          []CostGroup               `json:"groups,omitempty"`
 	Timeline        []CostTimelineEntry       `json:"timeline"`
 	Forecast        CostForecast              `json:"forecast,omitempty"`
 	Optimizations   []CostOptimizationOption  `json:"optimizations"`
@@ -435,6 +438,8 @@ func NewCostAnalyzer(provider providers.Provider, logger *logrus.Logger) *CostAn
 			PricingTiers:         make(map[string]PricingTier),
 			CostAlerts:           []CostAlert{},
 			BudgetLimits:         make(map[string]float64),
+			AnomalyWindow:        defaultAnomalyWindow,
+			AnomalyThreshold:     defaultAnomalyThreshold,
 		},
 		cache: &CostCache{
 			costs: make(map[string]*CachedCost),
@@ -452,6 +457,10 @@ func (ca *CostAnalyzer) AnalyzeCosts(ctx context.Context, options CostAnalysisOp
 	if options.EndDate.IsZero() {
 		options.EndDate = time.Now()
 	}
+	dimension, labelKey, err := parseGroupBy(options.GroupBy)
+	if err != nil {
+		return nil, err
+	}
 
 	results := &CostAnalysisResults{
 		Timeline:        []CostTimelineEntry{},
@@ -469,6 +478,18 @@ func (ca *CostAnalyzer) AnalyzeCosts(ctx context.Context, options CostAnalysisOp
 
 	results.Summary = ca.calculateSummary(ctx, resources, options)
 	results.Breakdown = ca.calculateBreakdown(ctx, resources, options)
+	if dimension == "label" {
+		billing, err := ca.provider.GetBillingData(ctx, options.StartDate, options.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get billing data: %w", err)
+		}
+		results.Groups = groupBillingByLabel(billing, labelKey, options.StartDate, options.EndDate)
+	} else {
+		results.Groups, err = ca.groupCosts(resources, options.GroupBy)
+		if err != nil {
+			return nil, err
+		}
+	}
 	results.Timeline = ca.generateTimeline(ctx, resources, options)
 
 	if options.IncludeForecast {
@@ -733,10 +754,6 @@ func (ca *CostAnalyzer) generateTimeline(ctx context.Context, resources []core.R
 			entry.DeltaPercent = (entry.Delta / previousDayCost) * 100
 		}
 
-		if entry.DeltaPercent > 30 || entry.DeltaPercent < -30 {
-			entry.AnomalyDetected = true
-		}
-
 		timeline = append(timeline, entry)
 		previousDayCost = dailyCost
 		currentDate = currentDate.AddDate(0, 0, 1)
@@ -1021,57 +1038,6 @@ func (ca *CostAnalyzer) analyzeTrends(timeline []CostTimelineEntry) CostTrends {
 	return trends
 }
 
-func (ca *CostAnalyzer) detectAnomalies(timeline []CostTimelineEntry) []CostAnomaly {
-	anomalies := []CostAnomaly{}
-
-	if len(timeline) < 7 {
-		return anomalies
-	}
-
-	movingAverage := ca.calculateMovingAverage(timeline, 7)
-	stdDev := ca.calculateStandardDeviation(timeline)
-
-	for i, entry := range timeline {
-		if i < 7 {
-			continue
-		}
-
-		expectedCost := movingAverage[i-7]
-		deviation := math.Abs(entry.TotalCost - expectedCost)
-		deviationPercent := (deviation / expectedCost) * 100
-
-		if deviation > stdDev*2 {
-			severity := "LOW"
-			if deviation > stdDev*3 {
-				severity = "HIGH"
-			} else if deviation > stdDev*2.5 {
-				severity = "MEDIUM"
-			}
-
-			anomalies = append(anomalies, CostAnomaly{
-				ID:               fmt.Sprintf("anomaly-%s", entry.Date.Format("20060102")),
-				DetectedAt:       entry.Date,
-				Type:             "COST_SPIKE",
-				Severity:         severity,
-				ExpectedCost:     expectedCost,
-				ActualCost:       entry.TotalCost,
-				Deviation:        deviation,
-				DeviationPercent: deviationPercent,
-				Description:      fmt.Sprintf("Cost deviation of %.1f%% detected", deviationPercent),
-				PossibleCauses: []string{
-					"Unexpected resource scaling",
-					"New resource deployments",
-					"Traffic spike",
-					"Configuration change",
-				},
-				Investigation: "Review resource changes and usage patterns for this period",
-			})
-		}
-	}
-
-	return anomalies
-}
-
 func (ca *CostAnalyzer) generateRecommendations(results *CostAnalysisResults) []CostRecommendation {
 	recommendations := []CostRecommendation{}
 
@@ -1122,6 +1088,8 @@ func (ca *CostAnalyzer) generateRecommendations(results *CostAnalysisResults) []
 		}
 	}
 
+	recommendations = append(recommendations, ca.anomalyRecommendations(results.Anomalies)...)
+
 	return recommendations
 }
 
@@ -1727,37 +1695,3 @@ func (ca *CostAnalyzer) analyzeGrowthRate(timeline []CostTimelineEntry) GrowthAn
 
 	return growth
 }
-
-func (ca *CostAnalyzer) calculateMovingAverage(timeline []CostTimelineEntry, window int) []float64 {
-	movingAvg := []float64{}
-
-	for i := window - 1; i < len(timeline); i++ {
-		sum := 0.0
-		for j := i - window + 1; j <= i; j++ {
-			sum += timeline[j].TotalCost
-		}
-		movingAvg = append(movingAvg, sum/float64(window))
-	}
-
-	return movingAvg
-}
-
-func (ca *CostAnalyzer) calculateStandardDeviation(timeline []CostTimelineEntry) float64 {
-	if len(timeline) < 2 {
-		return 0
-	}
-
-	mean := 0.0
-	for _, entry := range timeline {
-		mean += entry.TotalCost
-	}
-	mean /= float64(len(timeline))
-
-	variance := 0.0
-	for _, entry := range timeline {
-		variance += (entry.TotalCost - mean) * (entry.TotalCost - mean)
-	}
-	variance /= float64(len(timeline) - 1)
-
-	return math.Sqrt(variance)
-}
\ No newline at end of file
@@ -0,0 +1,183 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// defaultAnomalyWindow is the number of trailing days used as a baseline
+	defaultAnomalyWindow = 7
+	// defaultAnomalyThreshold is the z-score beyond which a day is anomalous
+	defaultAnomalyThreshold = 3.0
+	// minAnomalyStdDevRatio floors the baseline deviation at a fraction of the
+	// baseline mean so a perfectly flat history does not flag rounding noise
+	minAnomalyStdDevRatio = 0.01
+)
+
+// detectAnomalies flags days whose cost deviates from the rolling baseline of
+// the preceding window by more than the z-score threshold. Flagged days are
+// left out of later baselines so one spike does not mask the next, and each
+// anomaly is attributed to the service whose cost moved the most.
+func (ca *CostAnalyzer) detectAnomalies(timeline []CostTimelineEntry) []CostAnomaly {
+	anomalies := []CostAnomaly{}
+
+	window := ca.config.AnomalyWindow
+	if window <= 1 {
+		window = defaultAnomalyWindow
+	}
+	threshold := ca.config.AnomalyThreshold
+	if threshold <= 0 {
+		threshold = defaultAnomalyThreshold
+	}
+
+	var baseline []int
+	for i := range timeline {
+		if len(baseline) < window {
+			baseline = append(baseline, i)
+			continue
+		}
+
+		entry := &timeline[i]
+		mean, stdDev := ca.baselineStats(timeline, baseline)
+		if floor := math.Max(mean*minAnomalyStdDevRatio, 0.01); stdDev < floor {
+			stdDev = floor
+		}
+
+		deviation := entry.TotalCost - mean
+		zScore := deviation / stdDev
+		if math.Abs(zScore) <= threshold {
+			baseline = append(baseline[1:], i)
+			continue
+		}
+
+		entry.AnomalyDetected = true
+		service, serviceDelta := ca.attributeAnomaly(timeline, baseline, entry, deviation > 0)
+
+		anomalyType := "COST_SPIKE"
+		if deviation < 0 {
+			anomalyType = "COST_DROP"
+		}
+
+		severity := "LOW"
+		if math.Abs(zScore) > threshold*2 {
+			severity = "HIGH"
+		} else if math.Abs(zScore) > threshold*1.5 {
+			severity = "MEDIUM"
+		}
+
+		deviationPercent := 0.0
+		if mean > 0 {
+			deviationPercent = (deviation / mean) * 100
+		}
+
+		anomalies = append(anomalies, CostAnomaly{
+			ID:               fmt.Sprintf("anomaly-%s", entry.Date.Format("20060102")),
+			DetectedAt:       entry.Date,
+			Type:             anomalyType,
+			Severity:         severity,
+			Service:          service,
+			ExpectedCost:     mean,
+			ActualCost:       entry.TotalCost,
+			Deviation:        math.Abs(deviation),
+			DeviationPercent: deviationPercent,
+			Description: fmt.Sprintf("Daily cost of %.2f is %.1f standard deviations from the %d-day baseline of %.2f; %s changed by %.2f",
+				entry.TotalCost, zScore, window, mean, service, serviceDelta),
+			PossibleCauses: []string{
+				"Unexpected resource scaling",
+				"New resource deployments",
+				"Traffic spike",
+				"Configuration change",
+			},
+			Investigation: fmt.Sprintf("Review %s usage and recent changes on %s", service, entry.Date.Format("2006-01-02")),
+		})
+	}
+
+	return anomalies
+}
+
+// baselineStats returns the mean and sample standard deviation of the daily
+// totals at the given timeline indexes
+func (ca *CostAnalyzer) baselineStats(timeline []CostTimelineEntry, indexes []int) (mean, stdDev float64) {
+	for _, i := range indexes {
+		mean += timeline[i].TotalCost
+	}
+	mean /= float64(len(indexes))
+
+	if len(indexes) < 2 {
+		return mean, 0
+	}
+
+	variance := 0.0
+	for _, i := range indexes {
+		variance += (timeline[i].TotalCost - mean) * (timeline[i].TotalCost - mean)
+	}
+	variance /= float64(len(indexes) - 1)
+
+	return mean, math.Sqrt(variance)
+}
+
+// attributeAnomaly returns the service with the largest move against its own
+// baseline in the direction of the anomaly, and that move
+func (ca *CostAnalyzer) attributeAnomaly(timeline []CostTimelineEntry, baseline []int, entry *CostTimelineEntry, spike bool) (string, float64) {
+	serviceBaseline := make(map[string]float64)
+	for _, i := range baseline {
+		for service, cost := range timeline[i].ServiceCosts {
+			serviceBaseline[service] += cost / float64(len(baseline))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var services []string
+	for _, costs := range []map[string]float64{serviceBaseline, entry.ServiceCosts} {
+		for service := range costs {
+			if !seen[service] {
+				seen[service] = true
+				services = append(services, service)
+			}
+		}
+	}
+	sort.Strings(services)
+
+	best, bestDelta := "unknown", 0.0
+	for _, service := range services {
+		delta := entry.ServiceCosts[service] - serviceBaseline[service]
+		if !spike {
+			delta = -delta
+		}
+		if delta > bestDelta {
+			best, bestDelta = service, delta
+		}
+	}
+
+	if !spike {
+		bestDelta = -bestDelta
+	}
+	return best, bestDelta
+}
+
+// anomalyRecommendations turns cost spikes into findings to investigate
+func (ca *CostAnalyzer) anomalyRecommendations(anomalies []CostAnomaly) []CostRecommendation {
+	recommendations := []CostRecommendation{}
+	for _, anomaly := range anomalies {
+		if anomaly.Type != "COST_SPIKE" {
+			continue
+		}
+
+		priority := "MEDIUM"
+		if anomaly.Severity == "HIGH" {
+			priority = "HIGH"
+		}
+
+		recommendations = append(recommendations, CostRecommendation{
+			Type: "ANOMALY",
+			Description: fmt.Sprintf("Investigate %s cost spike on %s (%.2f vs expected %.2f)",
+				anomaly.Service, anomaly.DetectedAt.Format("2006-01-02"), anomaly.ActualCost, anomaly.ExpectedCost),
+			Savings:  anomaly.Deviation,
+			Effort:   "LOW",
+			Priority: priority,
+		})
+	}
+	return recommendations
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+)
+
+// dailyTimeline builds a timeline with a slightly noisy compute and storage
+// cost per day; spikes adds extra cost to a service on a given day
+func dailyTimeline(days int, spikes map[int]map[string]float64) []CostTimelineEntry {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	timeline := make([]CostTimelineEntry, days)
+	for i := range timeline {
+		services := map[string]float64{
+			"compute": 100 + float64(i%3),
+			"storage": 20 + float64(i%2),
+		}
+		for service, extra := range spikes[i] {
+			services[service] += extra
+		}
+
+		total := 0.0
+		for _, cost := range services {
+			total += cost
+		}
+		timeline[i] = CostTimelineEntry{Date: start.AddDate(0, 0, i), TotalCost: total, ServiceCosts: services}
+	}
+	return timeline
+}
+
+func TestDetectAnomaliesFlagsAndAttributesSpike(t *testing.T) {
+	timeline := dailyTimeline(21, map[int]map[string]float64{
+		14: {"storage": 80, "compute": 5},
+	})
+
+	ca := newTestCostAnalyzer()
+	anomalies := ca.detectAnomalies(timeline)
+	if len(anomalies) != 1 {
+		t.Fatalf("expected exactly one anomaly, got %d: %+v", len(anomalies), anomalies)
+	}
+
+	anomaly := anomalies[0]
+	if !anomaly.DetectedAt.Equal(timeline[14].Date) {
+		t.Errorf("expected anomaly on %s, got %s", timeline[14].Date, anomaly.DetectedAt)
+	}
+	if anomaly.Type != "COST_SPIKE" || anomaly.Severity != "HIGH" {
+		t.Errorf("expected a HIGH cost spike, got %s %s", anomaly.Severity, anomaly.Type)
+	}
+	if anomaly.Service != "storage" {
+		t.Errorf("expected spike attributed to storage, got %q", anomaly.Service)
+	}
+	if anomaly.ExpectedCost < 120 || anomaly.ExpectedCost > 123 {
+		t.Errorf("expected baseline near 121.5, got %.2f", anomaly.ExpectedCost)
+	}
+	if !timeline[14].AnomalyDetected {
+		t.Error("expected the timeline entry to be marked")
+	}
+	for i, entry := range timeline {
+		if i != 14 && entry.AnomalyDetected {
+			t.Errorf("day %d should not be marked", i)
+		}
+	}
+
+	recommendations := ca.anomalyRecommendations(anomalies)
+	if len(recommendations) != 1 || recommendations[0].Type != "ANOMALY" || recommendations[0].Priority != "HIGH" {
+		t.Fatalf("expected one HIGH priority anomaly recommendation, got %+v", recommendations)
+	}
+}
+
+func TestDetectAnomaliesIgnoresSteadyCosts(t *testing.T) {
+	if anomalies := newTestCostAnalyzer().detectAnomalies(dailyTimeline(30, nil)); len(anomalies) != 0 {
+		t.Fatalf("expected no anomalies for steady costs, got %+v", anomalies)
+	}
+}
+
+func TestDetectAnomaliesExcludesSpikesFromBaseline(t *testing.T) {
+	// Two spikes a day apart: the first must not inflate the baseline enough
+	// to hide the second
+	timeline := dailyTimeline(20, map[int]map[string]float64{
+		10: {"compute": 60},
+		11: {"compute": 60},
+	})
+
+	anomalies := newTestCostAnalyzer().detectAnomalies(timeline)
+	if len(anomalies) != 2 {
+		t.Fatalf("expected both spikes to be flagged, got %d", len(anomalies))
+	}
+	for _, anomaly := range anomalies {
+		if anomaly.Service != "compute" {
+			t.Errorf("expected compute attribution, got %q", anomaly.Service)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+)
+
+// unlabeledGroup collects cost from line items without the grouped label
+const unlabeledGroup = "unlabeled"
+
+// CostGroup is the cost aggregated for one value of the --group-by dimension
+type CostGroup struct {
+	Dimension        string             `json:"dimension"`
+	Value            string             `json:"value"`
+	TotalCost        float64            `json:"total_cost"`
+	ResourceCount    int                `json:"resource_count"`
+	Percentage       float64            `json:"percentage"`
+	ServiceBreakdown map[string]float64 `json:"service_breakdown"`
+}
+
+// parseGroupBy splits a --group-by value into its dimension and, for
+// "label:<key>", the label key
+func parseGroupBy(groupBy string) (dimension, labelKey string, err error) {
+	dimension, labelKey, _ = strings.Cut(strings.TrimSpace(groupBy), ":")
+	switch dimension {
+	case "", "service", "region", "project", "resource":
+		if labelKey != "" {
+			return "", "", fmt.Errorf("group-by %q does not take a key", dimension)
+		}
+	case "label":
+		// Labels overlap, so shares of spend only add up per key
+		if labelKey == "" {
+			return "", "", fmt.Errorf("group-by label needs a key, such as label:team")
+		}
+	default:
+		return "", "", fmt.Errorf("unsupported group-by %q (use service, region, project, resource or label:<key>)", groupBy)
+	}
+	if dimension == "" {
+		dimension = "service"
+	}
+	return dimension, labelKey, nil
+}
+
+// groupCosts aggregates resource costs by the service, region, project or
+// resource --group-by dimension
+func (ca *CostAnalyzer) groupCosts(resources []core.Resource, groupBy string) ([]CostGroup, error) {
+	dimension, _, err := parseGroupBy(groupBy)
+	if err != nil {
+		return nil, err
+	}
+	if dimension == "label" {
+		return nil, fmt.Errorf("group-by %s is computed from billing data", groupBy)
+	}
+
+	groups := make(map[string]*CostGroup)
+	add := func(value string, cost float64, serviceName string) {
+		group, ok := groups[value]
+		if !ok {
+			group = &CostGroup{Dimension: dimension, Value: value, ServiceBreakdown: make(map[string]float64)}
+			groups[value] = group
+		}
+		group.TotalCost += cost
+		group.ResourceCount++
+		group.ServiceBreakdown[serviceName] += cost
+	}
+
+	totalCost := 0.0
+	for _, resource := range resources {
+		if resource.Cost == nil {
+			continue
+		}
+
+		cost := resource.Cost.MonthlyCost
+		serviceName := ca.getServiceFromResourceType(resource.Type)
+		totalCost += cost
+
+		switch dimension {
+		case "service":
+			add(serviceName, cost, serviceName)
+		case "region":
+			add(resource.Region, cost, serviceName)
+		case "project":
+			project := resource.Account.ID
+			if p, ok := resource.Tags["project"]; ok {
+				project = p
+			}
+			add(project, cost, serviceName)
+		case "resource":
+			add(resource.ID, cost, serviceName)
+		}
+	}
+	return sortCostGroups(groups, totalCost), nil
+}
+
+// groupBillingByLabel aggregates the billing line items between start and
+// end by the value of the labelKey label each carries, as in the billing
+// export. Line items without the label are counted as "unlabeled", so the
+// percentages add up to the whole spend.
+func groupBillingByLabel(billing []providers.BillingData, labelKey string, start, end time.Time) []CostGroup {
+	groups := make(map[string]*CostGroup)
+	resources := make(map[string]map[string]bool)
+	totalCost := 0.0
+	for _, item := range billing {
+		if item.Date.Before(start) || !item.Date.Before(end) {
+			continue
+		}
+
+		value := item.Tags[labelKey]
+		if value == "" {
+			value = unlabeledGroup
+		}
+		group, ok := groups[value]
+		if !ok {
+			group = &CostGroup{Dimension: "label:" + labelKey, Value: value, ServiceBreakdown: make(map[string]float64)}
+			groups[value] = group
+			resources[value] = make(map[string]bool)
+		}
+		group.TotalCost += item.Cost
+		group.ServiceBreakdown[item.Service] += item.Cost
+		if item.Resource != "" && !resources[value][item.Resource] {
+			resources[value][item.Resource] = true
+			group.ResourceCount++
+		}
+		totalCost += item.Cost
+	}
+	return sortCostGroups(groups, totalCost)
+}
+
+// sortCostGroups sets each group's share of totalCost and orders the groups
+// most expensive first
+func sortCostGroups(groups map[string]*CostGroup, totalCost float64) []CostGroup {
+	result := make([]CostGroup, 0, len(groups))
+	for _, group := range groups {
+		if totalCost > 0 {
+			group.Percentage = (group.TotalCost / totalCost) * 100
+		}
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalCost != result[j].TotalCost {
+			return result[i].TotalCost > result[j].TotalCost
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
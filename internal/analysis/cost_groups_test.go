@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+)
+
+func labeledBillingRows() []providers.BillingData {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	row := func(resource, service string, cost float64, labels map[string]string) providers.BillingData {
+		return providers.BillingData{Date: day, Service: service, Resource: resource, Cost: cost, Currency: "USD", Tags: labels}
+	}
+	return []providers.BillingData{
+		row("vm-1", "Compute Engine", 60, map[string]string{"team": "payments", "cost-center": "cc-1"}),
+		row("vm-1", "Compute Engine", 40, map[string]string{"team": "payments", "cost-center": "cc-1"}),
+		row("vm-2", "Compute Engine", 50, map[string]string{"team": "search"}),
+		row("db-1", "Cloud SQL", 200, map[string]string{"team": "payments"}),
+		row("bucket-1", "Cloud Storage", 30, map[string]string{"cost-center": "cc-1"}),
+		row("bucket-2", "Cloud Storage", 20, nil),
+		// Line items outside the period are left out
+		{Date: day.AddDate(0, -2, 0), Service: "Compute Engine", Resource: "vm-1", Cost: 500, Tags: map[string]string{"team": "payments"}},
+	}
+}
+
+func newTestCostAnalyzer() *CostAnalyzer {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewCostAnalyzer(nil, logger)
+}
+
+func TestGroupBillingByLabelKey(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	groups := groupBillingByLabel(labeledBillingRows(), "team", start, start.AddDate(0, 1, 0))
+
+	want := []struct {
+		value     string
+		total     float64
+		resources int
+	}{
+		{"payments", 300, 2},
+		{"search", 50, 1},
+		{unlabeledGroup, 50, 2},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), groups)
+	}
+	for i, w := range want {
+		g := groups[i]
+		if g.Value != w.value || g.TotalCost != w.total || g.ResourceCount != w.resources {
+			t.Errorf("group %d = %s %.2f (%d resources), want %s %.2f (%d resources)",
+				i, g.Value, g.TotalCost, g.ResourceCount, w.value, w.total, w.resources)
+		}
+		if g.Dimension != "label:team" {
+			t.Errorf("group %d has dimension %q", i, g.Dimension)
+		}
+	}
+
+	if groups[0].ServiceBreakdown["Compute Engine"] != 100 || groups[0].ServiceBreakdown["Cloud SQL"] != 200 {
+		t.Errorf("unexpected service breakdown for payments: %v", groups[0].ServiceBreakdown)
+	}
+	percentages := 0.0
+	for _, g := range groups {
+		percentages += g.Percentage
+	}
+	if groups[0].Percentage != 75 || percentages != 100 {
+		t.Errorf("expected payments to be 75%% of cost and the groups 100%%, got %.2f and %.2f", groups[0].Percentage, percentages)
+	}
+}
+
+func TestGroupCostsLeavesLabelsToBillingData(t *testing.T) {
+	if _, err := newTestCostAnalyzer().groupCosts(nil, "label:team"); err == nil {
+		t.Error("expected label grouping of resources to fail")
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	for _, groupBy := range []string{"", "service", "region", "project", "resource", "label:cost-center"} {
+		if _, _, err := parseGroupBy(groupBy); err != nil {
+			t.Errorf("parseGroupBy(%q) returned error: %v", groupBy, err)
+		}
+	}
+	// Bare label is rejected: labels overlap, so their shares don't add up
+	for _, groupBy := range []string{"team", "service:compute", "label"} {
+		if _, _, err := parseGroupBy(groupBy); err == nil {
+			t.Errorf("parseGroupBy(%q) should fail", groupBy)
+		}
+	}
+}
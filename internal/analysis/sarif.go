@@ -0,0 +1,286 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLog is a SARIF 2.1.0 log, the format GitHub and GitLab code
+// scanning import
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name,omitempty"`
+	ShortDescription     SARIFMessage        `json:"shortDescription"`
+	FullDescription      *SARIFMessage       `json:"fullDescription,omitempty"`
+	Help                 *SARIFMessage       `json:"help,omitempty"`
+	DefaultConfiguration SARIFConfiguration  `json:"defaultConfiguration"`
+	Properties           SARIFRuleProperties `json:"properties"`
+}
+
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFRuleProperties carries the tags and the 0.0-10.0 security-severity
+// code scanning ranks alerts by
+type SARIFRuleProperties struct {
+	Tags             []string `json:"tags,omitempty"`
+	SecuritySeverity string   `json:"security-severity"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             SARIFMessage       `json:"message"`
+	Locations           []SARIFLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints,omitempty"`
+	Suppressions        []SARIFSuppression `json:"suppressions,omitempty"`
+}
+
+// SARIFSuppression marks a result accepted outside the scanned code, in a
+// suppression file
+type SARIFSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// SARIFLocation points at the cloud resource, which has no file, through
+// its resource name as artifact URI and as logical location
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+// sarifSeverities maps finding severities to SARIF levels and
+// security-severity scores
+var sarifSeverities = map[string]struct {
+	level string
+	score string
+}{
+	"CRITICAL": {"error", "9.5"},
+	"HIGH":     {"error", "8.0"},
+	"MEDIUM":   {"warning", "5.5"},
+	"LOW":      {"note", "2.0"},
+	"INFO":     {"note", "0.0"},
+}
+
+// failedControlSeverity is used for failed compliance controls, which carry
+// no severity of their own
+const failedControlSeverity = "HIGH"
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(s string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// findingCheck splits a finding description of the form "check: detail"
+func findingCheck(finding core.SecurityFinding) (check, detail string) {
+	if name, rest, ok := strings.Cut(finding.Description, ": "); ok {
+		return name, rest
+	}
+	return finding.Description, ""
+}
+
+// findingRuleID is the stable rule ID of a finding, from its type and check
+func findingRuleID(finding core.SecurityFinding) string {
+	check, _ := findingCheck(finding)
+	return fmt.Sprintf("finding/%s/%s", slug(finding.Type), slug(check))
+}
+
+// sarifSeverity returns the level and score of severity, treating unknown
+// severities as MEDIUM
+func sarifSeverity(severity string) (string, string) {
+	s, ok := sarifSeverities[strings.ToUpper(severity)]
+	if !ok {
+		s = sarifSeverities["MEDIUM"]
+	}
+	return s.level, s.score
+}
+
+// sarifBuilder collects rules and results, adding each rule once
+type sarifBuilder struct {
+	rules   []SARIFRule
+	index   map[string]int
+	results []SARIFResult
+}
+
+func (b *sarifBuilder) rule(rule SARIFRule) int {
+	if i, ok := b.index[rule.ID]; ok {
+		return i
+	}
+	b.index[rule.ID] = len(b.rules)
+	b.rules = append(b.rules, rule)
+	return len(b.rules) - 1
+}
+
+func (b *sarifBuilder) add(rule SARIFRule, severity, message string, resources []string) {
+	level, _ := sarifSeverity(severity)
+	result := SARIFResult{
+		RuleID:    rule.ID,
+		RuleIndex: b.rule(rule),
+		Level:     level,
+		Message:   SARIFMessage{Text: message},
+		Locations: []SARIFLocation{},
+	}
+	for _, resource := range resources {
+		result.Locations = append(result.Locations, SARIFLocation{
+			PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: resource}},
+			LogicalLocations: []SARIFLogicalLocation{{Name: resource, FullyQualifiedName: resource, Kind: "resource"}},
+		})
+	}
+	// Alerts are matched across runs by rule and resources, not by the
+	// per-run finding IDs
+	result.PartialFingerprints = map[string]string{
+		"resourceRule/v1": rule.ID + ":" + strings.Join(resources, ","),
+	}
+	b.results = append(b.results, result)
+}
+
+// ToSARIF converts the findings and failed compliance controls of the
+// results into a SARIF log. Rule IDs are derived from the finding type and
+// check name or the framework and control ID, so they stay the same across
+// runs; findings are ordered by rule and resource for stable output.
+func (r *SecurityAnalysisResults) ToSARIF(toolName, toolVersion string) *SARIFLog {
+	b := &sarifBuilder{index: make(map[string]int)}
+
+	findings := append([]core.SecurityFinding(nil), r.Findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Type != findings[j].Type {
+			return findings[i].Type < findings[j].Type
+		}
+		if findings[i].Description != findings[j].Description {
+			return findings[i].Description < findings[j].Description
+		}
+		return findings[i].Resource < findings[j].Resource
+	})
+	for _, finding := range findings {
+		check, detail := findingCheck(finding)
+		level, score := sarifSeverity(finding.Severity)
+		rule := SARIFRule{
+			ID:                   findingRuleID(finding),
+			Name:                 check,
+			ShortDescription:     SARIFMessage{Text: check},
+			DefaultConfiguration: SARIFConfiguration{Level: level},
+			Properties:           SARIFRuleProperties{Tags: []string{"security", strings.ToLower(finding.Type)}, SecuritySeverity: score},
+		}
+		if finding.Risk != "" {
+			rule.FullDescription = &SARIFMessage{Text: finding.Risk}
+		}
+		if finding.Remediation != "" {
+			rule.Help = &SARIFMessage{Text: finding.Remediation}
+		}
+		message := finding.Description
+		if detail != "" {
+			message = detail
+		}
+		b.add(rule, finding.Severity, message, []string{finding.Resource})
+		if finding.Suppressed {
+			b.results[len(b.results)-1].Suppressions = []SARIFSuppression{{Kind: "external", Justification: finding.Justification}}
+		}
+	}
+
+	for _, control := range r.Compliance.Controls {
+		if control.Status != "FAILED" {
+			continue
+		}
+		level, score := sarifSeverity(failedControlSeverity)
+		rule := SARIFRule{
+			ID:                   fmt.Sprintf("compliance/%s/%s", slug(r.Compliance.Framework), slug(control.ID)),
+			Name:                 control.Name,
+			ShortDescription:     SARIFMessage{Text: control.Name},
+			DefaultConfiguration: SARIFConfiguration{Level: level},
+			Properties:           SARIFRuleProperties{Tags: []string{"compliance", slug(r.Compliance.Framework)}, SecuritySeverity: score},
+		}
+		if control.Description != "" {
+			rule.FullDescription = &SARIFMessage{Text: control.Description}
+		}
+		if control.Remediation != "" {
+			rule.Help = &SARIFMessage{Text: control.Remediation}
+		}
+
+		var resources []string
+		seen := make(map[string]bool)
+		for _, evidence := range control.Evidence {
+			if evidence.Resource != "" && !seen[evidence.Resource] {
+				seen[evidence.Resource] = true
+				resources = append(resources, evidence.Resource)
+			}
+		}
+		sort.Strings(resources)
+		if len(resources) == 0 {
+			// Code scanning needs a location for every result
+			resources = []string{"compliance/" + slug(r.Compliance.Framework)}
+		}
+		b.add(rule, failedControlSeverity, fmt.Sprintf("%s control %s failed: %s", r.Compliance.Framework, control.ID, control.Name), resources)
+	}
+
+	return &SARIFLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []SARIFRun{{
+			Tool:    SARIFTool{Driver: SARIFDriver{Name: toolName, Version: toolVersion, Rules: append([]SARIFRule{}, b.rules...)}},
+			Results: append([]SARIFResult{}, b.results...),
+		}},
+	}
+}
+
+// MarshalSARIF returns the SARIF log of the results as indented JSON
+func (r *SecurityAnalysisResults) MarshalSARIF(toolName, toolVersion string) ([]byte, error) {
+	data, err := json.MarshalIndent(r.ToSARIF(toolName, toolVersion), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SARIF: %w", err)
+	}
+	return data, nil
+}
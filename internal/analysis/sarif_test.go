@@ -0,0 +1,196 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+)
+
+// sarifSchemaFile is the official SARIF 2.1.0 schema, from
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/errata01/os/schemas/sarif-schema-2.1.0.json
+const sarifSchemaFile = "testdata/sarif-schema-2.1.0.json"
+
+// sarifLog is the part of a SARIF log code scanning reads, written out
+// independently of the SARIF types
+type sarifLog struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name           string `json:"name"`
+				Version        string `json:"version"`
+				InformationURI string `json:"informationUri"`
+				Rules          []struct {
+					ID               string `json:"id"`
+					Name             string `json:"name"`
+					ShortDescription struct {
+						Text string `json:"text"`
+					} `json:"shortDescription"`
+					FullDescription struct {
+						Text string `json:"text"`
+					} `json:"fullDescription"`
+					Help struct {
+						Text string `json:"text"`
+					} `json:"help"`
+					DefaultConfiguration struct {
+						Level string `json:"level"`
+					} `json:"defaultConfiguration"`
+					Properties struct {
+						Tags             []string `json:"tags"`
+						SecuritySeverity string   `json:"security-severity"`
+					} `json:"properties"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID    string `json:"ruleId"`
+			RuleIndex int    `json:"ruleIndex"`
+			Level     string `json:"level"`
+			Message   struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+				} `json:"physicalLocation"`
+				LogicalLocations []struct {
+					Name               string `json:"name"`
+					FullyQualifiedName string `json:"fullyQualifiedName"`
+					Kind               string `json:"kind"`
+				} `json:"logicalLocations"`
+			} `json:"locations"`
+			PartialFingerprints map[string]string `json:"partialFingerprints"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func sampleSecurityResults() *SecurityAnalysisResults {
+	return &SecurityAnalysisResults{
+		Findings: []core.SecurityFinding{
+			{ID: "finding-1", Type: "CONFIGURATION", Severity: "CRITICAL", Resource: "logs (storage.buckets)", Description: "Public bucket access: allUsers can read objects", Remediation: "Remove allUsers bindings"},
+			{ID: "finding-2", Type: "CONFIGURATION", Severity: "MEDIUM", Resource: "web-1 (compute.instances)", Description: "Serial port enabled: serial port access is on"},
+			{ID: "finding-3", Type: "CONFIGURATION", Severity: "LOW", Resource: "web-2 (compute.instances)", Description: "Serial port enabled: serial port access is on"},
+			{ID: "finding-4", Type: "IAM", Severity: "HIGH", Resource: "ci@acme.iam.gserviceaccount.com", Description: "Owner role on a service account"},
+		},
+		Compliance: ComplianceResults{
+			Framework: "CIS",
+			Controls: []ComplianceControl{
+				{ID: "1.4", Name: "Ensure service account keys are rotated", Status: "FAILED", Evidence: []Evidence{{Resource: "ci@acme.iam.gserviceaccount.com"}}},
+				{ID: "3.1", Name: "Ensure the default network does not exist", Status: "PASSED"},
+				{ID: "3.6", Name: "Ensure SSH access is restricted", Status: "FAILED"},
+			},
+		},
+	}
+}
+
+func TestToSARIFMatchesSchema(t *testing.T) {
+	data, err := sampleSecurityResults().MarshalSARIF("cloudrecon", "1.2.3")
+	if err != nil {
+		t.Fatalf("MarshalSARIF: %v", err)
+	}
+
+	schema, err := jsonschema.NewCompiler().Compile(sarifSchemaFile)
+	if err != nil {
+		t.Fatalf("compiling the SARIF schema: %v", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if err := schema.Validate(instance); err != nil {
+		t.Errorf("log doesn't match the SARIF 2.1.0 schema: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if log.Version != "2.1.0" || log.Schema == "" {
+		t.Fatalf("version = %q, $schema = %q", log.Version, log.Schema)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "cloudrecon" || run.Tool.Driver.Version != "1.2.3" {
+		t.Errorf("driver = %+v", run.Tool.Driver)
+	}
+	// Four findings of three rules and two failed controls
+	if len(run.Results) != 6 || len(run.Tool.Driver.Rules) != 5 {
+		t.Fatalf("got %d results and %d rules, want 6 and 5", len(run.Results), len(run.Tool.Driver.Rules))
+	}
+
+	levels := map[string]bool{"none": true, "note": true, "warning": true, "error": true}
+	for i, result := range run.Results {
+		if !levels[result.Level] {
+			t.Errorf("result %d: invalid level %q", i, result.Level)
+		}
+		if result.RuleIndex < 0 || result.RuleIndex >= len(run.Tool.Driver.Rules) || run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+			t.Errorf("result %d: ruleIndex %d doesn't point at rule %s", i, result.RuleIndex, result.RuleID)
+		}
+		if len(result.Locations) == 0 || result.Locations[0].PhysicalLocation.ArtifactLocation.URI == "" {
+			t.Errorf("result %d (%s) has no location", i, result.RuleID)
+		}
+		if result.Message.Text == "" {
+			t.Errorf("result %d (%s) has no message", i, result.RuleID)
+		}
+	}
+}
+
+func TestToSARIFSeverityLevels(t *testing.T) {
+	log := sampleSecurityResults().ToSARIF("cloudrecon", "dev")
+	run := log.Runs[0]
+
+	got := make(map[string]string)
+	for _, result := range run.Results {
+		got[result.Locations[0].PhysicalLocation.ArtifactLocation.URI+" "+result.RuleID] = result.Level
+	}
+	want := map[string]string{
+		"logs (storage.buckets) finding/configuration/public-bucket-access":           "error",
+		"web-1 (compute.instances) finding/configuration/serial-port-enabled":         "warning",
+		"web-2 (compute.instances) finding/configuration/serial-port-enabled":         "note",
+		"ci@acme.iam.gserviceaccount.com finding/iam/owner-role-on-a-service-account": "error",
+		"ci@acme.iam.gserviceaccount.com compliance/cis/1-4":                          "error",
+		"compliance/cis compliance/cis/3-6":                                           "error",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("levels = %v, want %v", got, want)
+	}
+
+	scores := make(map[string]string)
+	for _, rule := range run.Tool.Driver.Rules {
+		scores[rule.ID] = rule.Properties.SecuritySeverity
+	}
+	if scores["finding/configuration/public-bucket-access"] != "9.5" || scores["finding/iam/owner-role-on-a-service-account"] != "8.0" {
+		t.Errorf("security-severity scores = %v", scores)
+	}
+}
+
+func TestToSARIFRuleIDsAreStable(t *testing.T) {
+	first := sampleSecurityResults()
+	second := sampleSecurityResults()
+	// Finding IDs change on every run and the order isn't guaranteed
+	for i := range second.Findings {
+		second.Findings[i].ID = "finding-rerun"
+	}
+	second.Findings[0], second.Findings[3] = second.Findings[3], second.Findings[0]
+
+	a, err := first.MarshalSARIF("cloudrecon", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := second.MarshalSARIF("cloudrecon", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("SARIF differs between equivalent runs:\n%s\n---\n%s", a, b)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
 	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/suppress"
 )
 
 type SecurityAnalyzer struct {
@@ -92,6 +93,8 @@ type SecurityOptions struct {
 	ResourceTypes       []string
 	Severity            []string
 	Categories          []string
+	// Suppressions marks accepted findings as suppressed
+	Suppressions *suppress.List
 }
 
 type SecurityAnalysisResults struct {
@@ -114,6 +117,7 @@ type SecurityAnalysisSummary struct {
 	HighFindings        int                    `json:"high_findings"`
 	MediumFindings      int                    `json:"medium_findings"`
 	LowFindings         int                    `json:"low_findings"`
+	SuppressedFindings  int                    `json:"suppressed_findings,omitempty"`
 	SecurityScore       int                    `json:"security_score"`
 	ComplianceScore     int                    `json:"compliance_score"`
 	RiskLevel           string                 `json:"risk_level"`
@@ -922,6 +926,9 @@ func (sa *SecurityAnalyzer) AnalyzeSecurity(ctx context.Context, options Securit
 		results.Vulnerabilities = append(results.Vulnerabilities, vuln)
 	}
 
+	for _, expired := range results.applySuppressions(options.Suppressions, time.Now()) {
+		sa.logger.Warnf("Suppression %s has expired; its findings are no longer suppressed", expired)
+	}
 	results.Summary = sa.calculateSummary(results.Findings, results.Vulnerabilities)
 
 	if options.ComplianceFramework != "" {
@@ -974,7 +981,6 @@ func (sa *SecurityAnalyzer) scanVulnerabilities(ctx context.Context, resource co
 
 	for _, vuln := range vulnData {
 		vulnerability := Vulnerability{
-			ID:          fmt.Sprintf("vuln-%s", sa.generateID()),
 			Type:        "CONFIGURATION",
 			Resource:    resource.ID,
 			Service:     sa.getServiceFromResourceType(resource.Type),
@@ -999,6 +1005,7 @@ func (sa *SecurityAnalyzer) scanVulnerabilities(ctx context.Context, resource co
 			vulnerability.Description = description
 		}
 
+		vulnerability.ID = fmt.Sprintf("vuln-%s", sa.generateID(vulnerability.Type, resource.ID, vulnerability.CVE, vulnerability.Description))
 		vulnerabilities = append(vulnerabilities, vulnerability)
 	}
 
@@ -1013,6 +1020,10 @@ func (sa *SecurityAnalyzer) calculateSummary(findings []core.SecurityFinding, vu
 	}
 
 	for _, finding := range findings {
+		if finding.Suppressed {
+			summary.SuppressedFindings++
+			continue
+		}
 		switch finding.Severity {
 		case "CRITICAL":
 			summary.CriticalFindings++
@@ -1232,7 +1243,7 @@ func (sa *SecurityAnalyzer) generateRemediations(results *SecurityAnalysisResult
 	for _, finding := range results.Findings {
 		if finding.Severity == "CRITICAL" || finding.Severity == "HIGH" {
 			remediation := core.Remediation{
-				ID:          fmt.Sprintf("rem-%s", sa.generateID()),
+				ID:          fmt.Sprintf("rem-%s", sa.generateID(finding.ID)),
 				Type:        finding.Type,
 				Priority:    finding.Severity,
 				Description: fmt.Sprintf("Remediate %s", finding.ID),
@@ -1352,7 +1363,7 @@ func (sa *SecurityAnalyzer) executeCheck(ctx context.Context, resource core.Reso
 	for _, issue := range compliance {
 		if checkID, ok := issue["check_id"].(string); ok && checkID == check.ID {
 			return &core.SecurityFinding{
-				ID:           fmt.Sprintf("finding-%s", sa.generateID()),
+				ID:           fmt.Sprintf("finding-%s", sa.generateID("CONFIGURATION", check.ID, resource.ID)),
 				Type:         "CONFIGURATION",
 				Severity:     sa.getSeverity(issue),
 				Resource:     fmt.Sprintf("%s (%s)", resource.Name, resource.Type),
@@ -1469,7 +1480,7 @@ func (sa *SecurityAnalyzer) calculateRisk(finding core.SecurityFinding) Risk {
 	}
 
 	return Risk{
-		ID:          fmt.Sprintf("risk-%s", sa.generateID()),
+		ID:          fmt.Sprintf("risk-%s", sa.generateID(finding.ID)),
 		Name:        finding.ID,
 		Category:    finding.Type,
 		Severity:    finding.Severity,
@@ -1577,9 +1588,10 @@ func (sa *SecurityAnalyzer) getServiceFromResourceType(resourceType string) stri
 	return "unknown"
 }
 
-func (sa *SecurityAnalyzer) generateID() string {
-	timestamp := time.Now().UnixNano()
-	hash := sha256.Sum256([]byte(fmt.Sprintf("%d", timestamp)))
+// generateID derives an ID from what identifies the item, so the same
+// finding keeps its ID across runs and suppressions by ID keep matching
+func (sa *SecurityAnalyzer) generateID(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
 	return hex.EncodeToString(hash[:8])
 }
 
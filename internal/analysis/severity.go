@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/suppress"
+)
+
+// severityRanks orders finding severities from least to most severe
+var severityRanks = map[string]int{
+	"INFO":     0,
+	"LOW":      1,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
+
+// ParseSeverity normalizes a severity given on the command line, such as
+// "high", to the form findings use
+func ParseSeverity(severity string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(severity))
+	if _, ok := severityRanks[normalized]; !ok {
+		return "", fmt.Errorf("unknown severity %q (expected critical, high, medium, low or info)", severity)
+	}
+	return normalized, nil
+}
+
+// AtLeastSeverity reports whether severity is min or more severe. Unknown
+// severities rank as MEDIUM, as they do in SARIF output.
+func AtLeastSeverity(severity, min string) bool {
+	rank, ok := severityRanks[strings.ToUpper(severity)]
+	if !ok {
+		rank = severityRanks["MEDIUM"]
+	}
+	return rank >= severityRanks[strings.ToUpper(min)]
+}
+
+// FilterSeverity returns a copy of the results with only the findings at
+// or above min, and the finding counts of the summary recounted. Scores
+// still reflect every finding.
+func (r *SecurityAnalysisResults) FilterSeverity(min string) *SecurityAnalysisResults {
+	filtered := *r
+	filtered.Findings = []core.SecurityFinding{}
+	for _, finding := range r.Findings {
+		if AtLeastSeverity(finding.Severity, min) {
+			filtered.Findings = append(filtered.Findings, finding)
+		}
+	}
+
+	filtered.Summary.TotalFindings = len(filtered.Findings)
+	filtered.Summary.CriticalFindings = 0
+	filtered.Summary.HighFindings = 0
+	filtered.Summary.MediumFindings = 0
+	filtered.Summary.LowFindings = 0
+	filtered.Summary.SuppressedFindings = 0
+	for _, finding := range filtered.Findings {
+		if finding.Suppressed {
+			filtered.Summary.SuppressedFindings++
+			continue
+		}
+		switch strings.ToUpper(finding.Severity) {
+		case "CRITICAL":
+			filtered.Summary.CriticalFindings++
+		case "HIGH":
+			filtered.Summary.HighFindings++
+		case "MEDIUM":
+			filtered.Summary.MediumFindings++
+		case "LOW":
+			filtered.Summary.LowFindings++
+		}
+	}
+	return &filtered
+}
+
+// CheckFailOn returns an error when any finding that isn't suppressed is at
+// or above failOn, so a CI run can fail on it
+func (r *SecurityAnalysisResults) CheckFailOn(failOn string) error {
+	count := 0
+	for _, finding := range r.Findings {
+		if !finding.Suppressed && AtLeastSeverity(finding.Severity, failOn) {
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%d security finding(s) at or above %s", count, failOn)
+	}
+	return nil
+}
+
+// applySuppressions marks the findings list accepts as suppressed, matching
+// them by ID or by resource and SARIF rule ID, and returns the expired
+// suppressions
+func (r *SecurityAnalysisResults) applySuppressions(list *suppress.List, at time.Time) []suppress.Suppression {
+	for i := range r.Findings {
+		finding := &r.Findings[i]
+		if s, ok := list.Match(finding.ID, finding.Resource, findingRuleID(*finding), at); ok {
+			finding.Suppressed = true
+			finding.Justification = s.Justification
+		}
+	}
+	return list.Expired(at)
+}
@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/core"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/providers"
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/suppress"
+)
+
+func severityTestResults() *SecurityAnalysisResults {
+	findings := []core.SecurityFinding{
+		{ID: "f1", Severity: "CRITICAL", Resource: "bucket/public"},
+		{ID: "f2", Severity: "HIGH", Resource: "vm/web"},
+		{ID: "f3", Severity: "MEDIUM", Resource: "vm/web"},
+		{ID: "f4", Severity: "LOW", Resource: "vm/db"},
+		{ID: "f5", Severity: "INFO", Resource: "vm/db"},
+	}
+	return &SecurityAnalysisResults{
+		Findings: findings,
+		Summary: SecurityAnalysisSummary{
+			TotalFindings: 5, CriticalFindings: 1, HighFindings: 1, MediumFindings: 1, LowFindings: 1,
+			SecurityScore: 55,
+		},
+	}
+}
+
+func findingIDs(findings []core.SecurityFinding) string {
+	var ids []string
+	for _, finding := range findings {
+		ids = append(ids, finding.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFilterSeverity(t *testing.T) {
+	results := severityTestResults()
+
+	tests := []struct {
+		min  string
+		want string
+	}{
+		{"CRITICAL", "f1"},
+		{"HIGH", "f1,f2"},
+		{"MEDIUM", "f1,f2,f3"},
+		{"LOW", "f1,f2,f3,f4"},
+		{"INFO", "f1,f2,f3,f4,f5"},
+	}
+	for _, tt := range tests {
+		filtered := results.FilterSeverity(tt.min)
+		if got := findingIDs(filtered.Findings); got != tt.want {
+			t.Errorf("FilterSeverity(%s) = %s, want %s", tt.min, got, tt.want)
+		}
+	}
+
+	filtered := results.FilterSeverity("HIGH")
+	summary := filtered.Summary
+	if summary.TotalFindings != 2 || summary.CriticalFindings != 1 || summary.HighFindings != 1 || summary.MediumFindings != 0 || summary.LowFindings != 0 {
+		t.Errorf("filtered summary counts = %+v", summary)
+	}
+	if summary.SecurityScore != 55 {
+		t.Errorf("security score changed to %d", summary.SecurityScore)
+	}
+	// The SARIF output only carries the shown findings
+	if got := len(filtered.ToSARIF("cloudrecon", "test").Runs[0].Results); got != 2 {
+		t.Errorf("SARIF results = %d, want 2", got)
+	}
+	if len(results.Findings) != 5 {
+		t.Errorf("FilterSeverity changed the original results")
+	}
+}
+
+func TestCheckFailOn(t *testing.T) {
+	results := severityTestResults()
+
+	if err := results.CheckFailOn("HIGH"); err == nil || !strings.Contains(err.Error(), "2 security finding(s) at or above HIGH") {
+		t.Errorf("CheckFailOn(HIGH) = %v", err)
+	}
+
+	onlyLow := &SecurityAnalysisResults{Findings: []core.SecurityFinding{{Severity: "LOW"}, {Severity: "INFO"}}}
+	if err := onlyLow.CheckFailOn("MEDIUM"); err != nil {
+		t.Errorf("CheckFailOn(MEDIUM) with low findings = %v", err)
+	}
+	if err := onlyLow.CheckFailOn("LOW"); err == nil {
+		t.Error("CheckFailOn(LOW) with a low finding passed")
+	}
+	// The gate runs on all findings, not just the shown ones
+	if err := results.FilterSeverity("CRITICAL").CheckFailOn("MEDIUM"); err == nil || !strings.Contains(err.Error(), "1 security finding(s)") {
+		t.Errorf("CheckFailOn(MEDIUM) on the shown findings = %v", err)
+	}
+	if err := results.CheckFailOn("MEDIUM"); err == nil || !strings.Contains(err.Error(), "3 security finding(s)") {
+		t.Errorf("CheckFailOn(MEDIUM) = %v", err)
+	}
+}
+
+func TestAtLeastSeverity(t *testing.T) {
+	if !AtLeastSeverity("high", "MEDIUM") {
+		t.Error("lowercase severities should be ranked")
+	}
+	// Unknown severities rank as MEDIUM
+	if !AtLeastSeverity("SEVERE", "MEDIUM") || AtLeastSeverity("SEVERE", "HIGH") {
+		t.Error("unknown severity should rank as MEDIUM")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	got, err := ParseSeverity(" High ")
+	if err != nil || got != "HIGH" {
+		t.Errorf("ParseSeverity(High) = %q, %v", got, err)
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Error("ParseSeverity(urgent) should fail")
+	}
+}
+
+func TestSuppressedFindingsDoNotFailTheGate(t *testing.T) {
+	results := severityTestResults()
+	results.Findings[0].Type = "STORAGE"
+	results.Findings[0].Description = "Public bucket: allUsers can read"
+	list, err := suppress.Parse([]byte(`{"suppressions": [
+		{"rule": "finding/storage/public-bucket", "resource": "bucket/*", "expires": "2026-06-30", "justification": "public docs"},
+		{"id": "f2", "expires": "2026-01-31", "justification": "until the VM is rebuilt"}
+	]}`), "json")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	expired := results.applySuppressions(list, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(expired) != 1 || expired[0].ID != "f2" {
+		t.Errorf("expired = %+v", expired)
+	}
+	if !results.Findings[0].Suppressed || results.Findings[0].Justification != "public docs" {
+		t.Errorf("critical finding not suppressed: %+v", results.Findings[0])
+	}
+	// The expired suppression leaves the high finding failing the gate
+	if results.Findings[1].Suppressed {
+		t.Error("finding suppressed by an expired suppression")
+	}
+	if err := results.CheckFailOn("CRITICAL"); err != nil {
+		t.Errorf("CheckFailOn(CRITICAL) with the critical finding suppressed = %v", err)
+	}
+	if err := results.CheckFailOn("HIGH"); err == nil || !strings.Contains(err.Error(), "1 security finding(s)") {
+		t.Errorf("CheckFailOn(HIGH) = %v", err)
+	}
+
+	// Suppressed findings are still listed
+	filtered := results.FilterSeverity("CRITICAL")
+	if len(filtered.Findings) != 1 || filtered.Summary.SuppressedFindings != 1 || filtered.Summary.CriticalFindings != 0 {
+		t.Errorf("filtered = %+v, summary %+v", filtered.Findings, filtered.Summary)
+	}
+	sarif := filtered.ToSARIF("cloudrecon", "test").Runs[0].Results
+	if len(sarif) != 1 || len(sarif[0].Suppressions) != 1 || sarif[0].Suppressions[0].Justification != "public docs" {
+		t.Errorf("SARIF results = %+v", sarif)
+	}
+}
+
+// complianceProvider reports the same failed check for every resource
+type complianceProvider struct {
+	providers.Provider
+}
+
+func (complianceProvider) CheckResourceCompliance(ctx context.Context, resourceID string, resourceType string) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{{"check_id": "public-access", "severity": "HIGH"}}, nil
+}
+
+func TestFindingIDsAreStableAcrossRuns(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	analyzer := NewSecurityAnalyzer(complianceProvider{}, logger)
+	check := SecurityCheck{ID: "public-access", Name: "Public access"}
+	logs := core.Resource{ID: "projects/p/buckets/logs", Name: "logs", Type: "storage.buckets"}
+	assets := core.Resource{ID: "projects/p/buckets/assets", Name: "assets", Type: "storage.buckets"}
+
+	first := analyzer.executeCheck(context.Background(), logs, check)
+	second := analyzer.executeCheck(context.Background(), logs, check)
+	other := analyzer.executeCheck(context.Background(), assets, check)
+	if first == nil || second == nil || other == nil {
+		t.Fatal("expected findings for the failed check")
+	}
+	if first.ID != second.ID {
+		t.Errorf("expected the same ID on every run, got %s and %s", first.ID, second.ID)
+	}
+	if first.ID == other.ID {
+		t.Errorf("expected findings on different resources to get different IDs, both got %s", first.ID)
+	}
+	if risk := analyzer.calculateRisk(*first); risk.ID != analyzer.calculateRisk(*second).ID {
+		t.Errorf("expected risks of the same finding to share an ID")
+	}
+}
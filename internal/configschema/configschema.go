@@ -0,0 +1,451 @@
+// Package configschema loads the JSON and YAML config files of the analyze,
+// backup, deploy, monitor, serve and validate commands strictly, and
+// generates JSON Schemas to validate them against, so misspelled keys and
+// wrong-typed values are reported instead of silently decoding to zero values.
+package configschema
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema needed to describe config structs. An
+// empty schema accepts any value.
+type Schema struct {
+	Schema     string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is false for structs, whose keys are all known,
+	// and the value schema for maps
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Generate returns the schema of the JSON that decodes into v's type, as
+// encoding/json decodes it
+func Generate(v interface{}) *Schema {
+	t := reflect.TypeOf(v)
+	schema := generate(t, make(map[reflect.Type]bool))
+	schema.Schema = Draft
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil {
+		schema.Title = t.Name()
+	}
+	return schema
+}
+
+func generate(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case reflect.PtrTo(t).Implements(jsonUnmarshalerType):
+		// Custom decoding accepts shapes reflection can't see
+		return &Schema{}
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: generate(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: generate(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// A recursive type; its nested occurrences accept any object
+			return &Schema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		addFields(schema, t, visiting)
+		return schema
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds the JSON fields of struct type t, including those promoted
+// from embedded structs
+func addFields(schema *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(schema, ft, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, exists := schema.Properties[name]; !exists {
+			schema.Properties[name] = generate(field.Type, visiting)
+		}
+	}
+}
+
+// Problem is one way a config file doesn't match its schema
+type Problem struct {
+	// Path locates the value, such as "resources[2].thresholds"
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Validate checks the JSON config data against the schema of v, returning
+// every unknown key and type mismatch. It errors only when data isn't JSON.
+func Validate(data []byte, v interface{}) ([]Problem, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var problems []Problem
+	validate(Generate(v), value, "", &problems)
+	return problems, nil
+}
+
+func validate(schema *Schema, value interface{}, path string, problems *[]Problem) {
+	if value == nil || schema.Type == "" {
+		// encoding/json leaves a field alone when it is null
+		return
+	}
+
+	mismatch := func() {
+		*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf("expected %s, got %s", schema.Type, jsonType(value))})
+	}
+
+	switch schema.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			mismatch()
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			mismatch()
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf("%q is not an RFC 3339 date-time", s)})
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			mismatch()
+			return
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf("expected integer, got %s", n)})
+			}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			mismatch()
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		for i, item := range items {
+			validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		validateObject(schema, object, path, problems)
+	}
+}
+
+func validateObject(schema *Schema, object map[string]interface{}, path string, problems *[]Problem) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		if property, ok := schema.Properties[key]; ok {
+			validate(property, object[key], keyPath, problems)
+			continue
+		}
+		switch additional := schema.AdditionalProperties.(type) {
+		case *Schema:
+			validate(additional, object[key], keyPath, problems)
+		case bool:
+			if additional {
+				continue
+			}
+			message := fmt.Sprintf("unknown key %q", key)
+			if suggestion := closest(key, schema.Properties); suggestion != "" {
+				message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			*problems = append(*problems, Problem{Path: keyPath, Message: message})
+		}
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+// closest returns the property most like key, if any is close enough to be
+// a likely misspelling
+func closest(key string, properties map[string]*Schema) string {
+	best, bestDistance := "", 0
+	for name := range properties {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if best == "" || distance < bestDistance || (distance == bestDistance && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	limit := len(key) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if best == "" || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Decode unmarshals the JSON config data into v like json.Unmarshal, but
+// rejects keys v has no field for. Errors name the offending key or value
+// and the line it is on.
+func Decode(data []byte, v interface{}) error {
+	return decode(data, v, jsonLines(data))
+}
+
+// lines places decoding errors in the config source, returning 0 when a
+// position is unknown
+type lines struct {
+	// key returns the line of the first occurrence of a key
+	key func(key string) int
+	// offset returns the line of a byte offset in the decoded JSON
+	offset func(offset int64) int
+}
+
+func jsonLines(data []byte) lines {
+	return lines{
+		key: func(key string) int {
+			if loc := regexp.MustCompile(`"` + regexp.QuoteMeta(key) + `"\s*:`).FindIndex(data); loc != nil {
+				return lineAt(data, int64(loc[0]))
+			}
+			return 0
+		},
+		offset: func(offset int64) int { return lineAt(data, offset) },
+	}
+}
+
+// decode strictly decodes the JSON data, placing errors with at
+func decode(data []byte, v interface{}, at lines) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		if _, err := decoder.Token(); err != io.EOF {
+			return lineError(at.offset(decoder.InputOffset()), "unexpected data after the config")
+		}
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return lineError(at.offset(syntaxErr.Offset), syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		return lineError(at.offset(typeErr.Offset), fmt.Sprintf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value))
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		key, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+		if unquoteErr != nil {
+			return err
+		}
+		return lineError(at.key(key), unknownKeyMessage(data, v, key))
+	}
+	return err
+}
+
+func lineError(line int, message string) error {
+	if line == 0 {
+		return errors.New(message)
+	}
+	return fmt.Errorf("line %d: %s", line, message)
+}
+
+// unknownFieldPrefix starts the error encoding/json returns for a key
+// rejected by DisallowUnknownFields
+const unknownFieldPrefix = "json: unknown field "
+
+// unknownKeyMessage describes an unknown key, using the schema problems to
+// place it and suggest the key that was probably meant
+func unknownKeyMessage(data []byte, v interface{}, key string) string {
+	if problems, err := Validate(data, v); err == nil {
+		for _, problem := range problems {
+			if (problem.Path == key || strings.HasSuffix(problem.Path, "."+key)) && strings.HasPrefix(problem.Message, "unknown key") {
+				return problem.String()
+			}
+		}
+	}
+	return fmt.Sprintf("unknown key %q", key)
+}
+
+// lineAt returns the 1-based line of the byte offset in data
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// Print writes the schema of v as indented JSON
+func Print(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Generate(v))
+}
+
+// ValidateFile validates the config file at path, in format or the format
+// its extension implies, against the schema of v, writes the problems found
+// to w, and returns the exit code for the command's -validate-config flag
+func ValidateFile(w io.Writer, path, format string, v interface{}) int {
+	if path == "" {
+		fmt.Fprintln(w, "Error: -validate-config needs a config file (-config)")
+		return 1
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(w, "Error reading config file: %v\n", err)
+		return 1
+	}
+	data, err = ToJSON(data, DetectFormat(path, format))
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", path, err)
+		return 1
+	}
+	problems, err := Validate(data, v)
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", path, err)
+		return 1
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "%s: valid\n", path)
+		return 0
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(w, "%s: %s\n", path, problem)
+	}
+	return 1
+}
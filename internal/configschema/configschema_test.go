@@ -0,0 +1,199 @@
+package configschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testThreshold struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+}
+
+type testEmbedded struct {
+	Owner string `json:"owner"`
+}
+
+type testConfig struct {
+	testEmbedded
+	ProjectID  string                 `json:"project_id"`
+	Region     string                 `json:"region"`
+	Workers    int                    `json:"workers"`
+	Enabled    bool                   `json:"enabled"`
+	Interval   time.Duration          `json:"interval"`
+	StartTime  time.Time              `json:"start_time"`
+	Labels     map[string]string      `json:"labels"`
+	Thresholds []testThreshold        `json:"thresholds"`
+	Extra      map[string]interface{} `json:"extra"`
+	Ignored    string                 `json:"-"`
+	internal   string
+}
+
+func problemStrings(problems []Problem) []string {
+	var out []string
+	for _, problem := range problems {
+		out = append(out, problem.String())
+	}
+	return out
+}
+
+func TestValidateCatchesUnknownKeysAndTypeMismatches(t *testing.T) {
+	data := []byte(`{
+		"project_id": "demo",
+		"regoin": "us-central1",
+		"workers": "four",
+		"enabled": true,
+		"owner": "platform",
+		"start_time": "yesterday",
+		"labels": {"team": 7},
+		"thresholds": [{"metric": "cpu", "value": 80}, {"metirc": "memory", "value": 1.5}],
+		"extra": {"anything": [1, "two"]}
+	}`)
+
+	problems, err := Validate(data, testConfig{})
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	want := []string{
+		`labels.team: expected string, got number`,
+		`regoin: unknown key "regoin" (did you mean "region"?)`,
+		`start_time: "yesterday" is not an RFC 3339 date-time`,
+		`thresholds[1].metirc: unknown key "metirc" (did you mean "metric"?)`,
+		`workers: expected integer, got string`,
+	}
+	got := problemStrings(problems)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	data := []byte(`{"project_id": "demo", "workers": 4, "interval": 30000000000, "start_time": "2024-01-01T00:00:00Z", "labels": null}`)
+
+	problems, err := Validate(data, &testConfig{})
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problemStrings(problems))
+	}
+
+	problems, _ = Validate([]byte(`{"workers": 1.5, "unrelatedsetting": true}`), testConfig{})
+	got := problemStrings(problems)
+	if len(got) != 2 || got[0] != `unrelatedsetting: unknown key "unrelatedsetting"` || got[1] != "workers: expected integer, got 1.5" {
+		t.Fatalf("unexpected problems: %v", got)
+	}
+
+	if _, err := Validate([]byte(`{"project_id":`), testConfig{}); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	schema := Generate(testConfig{})
+
+	if schema.Schema != Draft || schema.Title != "testConfig" || schema.AdditionalProperties != false {
+		t.Fatalf("unexpected root schema: %+v", schema)
+	}
+	for _, name := range []string{"owner", "project_id", "interval", "thresholds"} {
+		if schema.Properties[name] == nil {
+			t.Errorf("missing property %s", name)
+		}
+	}
+	for _, name := range []string{"Ignored", "internal", "-", "testEmbedded"} {
+		if schema.Properties[name] != nil {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+	if schema.Properties["interval"].Type != "integer" || schema.Properties["start_time"].Format != "date-time" {
+		t.Errorf("unexpected field schemas: %+v %+v", schema.Properties["interval"], schema.Properties["start_time"])
+	}
+	if items := schema.Properties["thresholds"].Items; items == nil || items.Properties["value"].Type != "number" {
+		t.Errorf("unexpected thresholds schema: %+v", schema.Properties["thresholds"])
+	}
+
+	var buf bytes.Buffer
+	if err := Print(&buf, testConfig{}); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	var printed map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &printed); err != nil {
+		t.Fatalf("printed schema isn't JSON: %v", err)
+	}
+	if printed["additionalProperties"] != false {
+		t.Errorf("printed schema allows unknown keys: %s", buf.String())
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"projectid": "demo"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := ValidateFile(&out, path, "", testConfig{}); code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), `unknown key "projectid" (did you mean "project_id"?)`) {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+
+	os.WriteFile(path, []byte(`{"project_id": "demo"}`), 0644)
+	out.Reset()
+	if code := ValidateFile(&out, path, "", testConfig{}); code != 0 {
+		t.Fatalf("exit code = %d, want 0: %s", code, out.String())
+	}
+}
+
+func TestDecodeRejectsUnknownKeys(t *testing.T) {
+	data := []byte("{\n  \"projct_id\": \"demo\",\n  \"workers\": 2\n}")
+
+	var config testConfig
+	err := Decode(data, &config)
+	if err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+	if want := `line 2: projct_id: unknown key "projct_id" (did you mean "project_id"?)`; err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+
+	data = []byte("{\n  \"project_id\": \"demo\",\n  \"thresholds\": [\n    {\"metric\": \"cpu\", \"valeu\": 80}\n  ]\n}")
+	err = Decode(data, &config)
+	if err == nil || err.Error() != `line 4: thresholds[0].valeu: unknown key "valeu" (did you mean "value"?)` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDecodeReportsLines(t *testing.T) {
+	var config testConfig
+
+	err := Decode([]byte("{\n  \"project_id\": \"demo\",\n  \"workers\": \"two\"\n}"), &config)
+	if err == nil || err.Error() != "line 3: workers: expected int, got string" {
+		t.Fatalf("unexpected type error: %v", err)
+	}
+
+	err = Decode([]byte("{\n  \"project_id\": \"demo\",\n}"), &config)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Fatalf("unexpected syntax error: %v", err)
+	}
+
+	err = Decode([]byte(`{"project_id": "demo"} {}`), &config)
+	if err == nil || !strings.Contains(err.Error(), "unexpected data") {
+		t.Fatalf("expected trailing data to be rejected, got %v", err)
+	}
+
+	config = testConfig{}
+	if err := Decode([]byte(`{"project_id": "demo", "owner": "platform", "extra": {"any": 1}}`), &config); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if config.ProjectID != "demo" || config.Owner != "platform" {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+}
@@ -0,0 +1,104 @@
+package configschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats config files can be written in
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// DetectFormat returns format unless it is empty or "auto", in which case
+// path's extension decides: .yaml and .yml files are YAML, anything else JSON
+func DetectFormat(path, format string) string {
+	if format = strings.ToLower(format); format != "" && format != "auto" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// DecodeFormat is Decode for config data in format. YAML is converted to
+// JSON first, so both formats share the structs' json tags and strictness.
+func DecodeFormat(data []byte, format string, v interface{}) error {
+	switch format {
+	case FormatJSON:
+		return Decode(data, v)
+	case FormatYAML:
+		converted, err := ToJSON(data, format)
+		if err != nil {
+			return err
+		}
+		return decode(converted, v, yamlLines(data))
+	}
+	return fmt.Errorf("unsupported config format %q (expected auto, json or yaml)", format)
+}
+
+// ToJSON converts config data in format to JSON
+func ToJSON(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return data, nil
+	case FormatYAML:
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		converted, err := json.Marshal(jsonValue(value))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert YAML config: %w", err)
+		}
+		return converted, nil
+	}
+	return nil, fmt.Errorf("unsupported config format %q (expected auto, json or yaml)", format)
+}
+
+// jsonValue makes a decoded YAML value encodable as JSON, whose object keys
+// must be strings
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+		return v
+	}
+	return value
+}
+
+// yamlLines places errors in YAML source. Offsets point into the converted
+// JSON, so only keys can be placed.
+func yamlLines(data []byte) lines {
+	return lines{
+		key: func(key string) int {
+			pattern := `(?m)^[ \t-]*["']?` + regexp.QuoteMeta(key) + `["']?[ \t]*:`
+			if loc := regexp.MustCompile(pattern).FindIndex(data); loc != nil {
+				return lineAt(data, int64(loc[0]))
+			}
+			return 0
+		},
+		offset: func(int64) int { return 0 },
+	}
+}
@@ -0,0 +1,110 @@
+package configschema
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jsonConfig = `{
+  "project_id": "demo",
+  "owner": "platform",
+  "workers": 4,
+  "enabled": true,
+  "interval": 30000000000,
+  "start_time": "2024-01-01T00:00:00Z",
+  "labels": {"team": "infra", "env": "prod"},
+  "thresholds": [{"metric": "cpu", "value": 80}, {"metric": "memory", "value": 85.5}],
+  "extra": {"nested": {"list": [1, "two", true]}}
+}`
+
+const yamlConfig = `
+project_id: demo
+owner: platform
+workers: 4
+enabled: true
+interval: 30000000000
+start_time: "2024-01-01T00:00:00Z"
+labels:
+  team: infra
+  env: prod
+thresholds:
+  - metric: cpu
+    value: 80
+  - metric: memory
+    value: 85.5
+extra:
+  nested:
+    list: [1, two, true]
+`
+
+func TestDecodeFormatYAMLMatchesJSON(t *testing.T) {
+	var fromJSON, fromYAML testConfig
+	if err := DecodeFormat([]byte(jsonConfig), FormatJSON, &fromJSON); err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	if err := DecodeFormat([]byte(yamlConfig), FormatYAML, &fromYAML); err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Fatalf("configs differ:\nJSON: %+v\nYAML: %+v", fromJSON, fromYAML)
+	}
+	if fromYAML.ProjectID != "demo" || len(fromYAML.Thresholds) != 2 || fromYAML.Interval.Seconds() != 30 {
+		t.Fatalf("unexpected config: %+v", fromYAML)
+	}
+}
+
+func TestDecodeFormatYAMLErrors(t *testing.T) {
+	var config testConfig
+
+	err := DecodeFormat([]byte("project_id: demo\nthresholds:\n  - metric: cpu\n    valeu: 80\n"), FormatYAML, &config)
+	if err == nil || err.Error() != `line 4: thresholds[0].valeu: unknown key "valeu" (did you mean "value"?)` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = DecodeFormat([]byte("workers: many\n"), FormatYAML, &config)
+	if err == nil || err.Error() != "workers: expected int, got string" {
+		t.Fatalf("unexpected type error: %v", err)
+	}
+
+	if err := DecodeFormat([]byte("project_id: [unclosed\n"), FormatYAML, &config); err == nil || !strings.Contains(err.Error(), "invalid YAML") {
+		t.Fatalf("unexpected syntax error: %v", err)
+	}
+	if err := DecodeFormat([]byte("{}"), "toml", &config); err == nil {
+		t.Fatal("expected an unsupported format error")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[[2]string]string{
+		{"config.yaml", ""}:     FormatYAML,
+		{"config.YML", "auto"}:  FormatYAML,
+		{"config.json", ""}:     FormatJSON,
+		{"config", ""}:          FormatJSON,
+		{"config.json", "YAML"}: FormatYAML,
+	}
+	for in, want := range cases {
+		if got := DetectFormat(in[0], in[1]); got != want {
+			t.Errorf("DetectFormat(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
+
+func TestValidateFileYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("project_id: demo\nregoin: us-east1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := ValidateFile(&out, path, "", testConfig{}); code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), `unknown key "regoin" (did you mean "region"?)`) {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
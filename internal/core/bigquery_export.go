@@ -0,0 +1,335 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+// BigQueryClient is the subset of BigQuery the exporter needs
+type BigQueryClient interface {
+	DatasetExists(ctx context.Context, dataset string) (bool, error)
+	CreateDataset(ctx context.Context, dataset string) error
+	// TableMetadata returns nil metadata when the table does not exist
+	TableMetadata(ctx context.Context, dataset, table string) (*bigquery.TableMetadata, error)
+	CreateTable(ctx context.Context, dataset, table string, metadata *bigquery.TableMetadata) error
+	UpdateSchema(ctx context.Context, dataset, table string, schema bigquery.Schema, etag string) error
+	Insert(ctx context.Context, dataset, table string, rows []bigquery.ValueSaver) error
+	Close() error
+}
+
+// resourceTableSchema is the BigQuery representation of a Resource; columns
+// are only ever added so existing tables can be evolved in place
+var resourceTableSchema = bigquery.Schema{
+	{Name: "id", Type: bigquery.StringFieldType, Required: true},
+	{Name: "name", Type: bigquery.StringFieldType},
+	{Name: "type", Type: bigquery.StringFieldType},
+	{Name: "region", Type: bigquery.StringFieldType},
+	{Name: "zone", Type: bigquery.StringFieldType},
+	{Name: "status", Type: bigquery.StringFieldType},
+	{Name: "account_id", Type: bigquery.StringFieldType},
+	{Name: "account_name", Type: bigquery.StringFieldType},
+	{Name: "tags", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+		{Name: "key", Type: bigquery.StringFieldType},
+		{Name: "value", Type: bigquery.StringFieldType},
+	}},
+	{Name: "properties", Type: bigquery.StringFieldType, Description: "JSON-encoded resource properties"},
+	{Name: "monthly_cost", Type: bigquery.FloatFieldType},
+	{Name: "currency", Type: bigquery.StringFieldType},
+	{Name: "dependencies", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+		{Name: "resource_id", Type: bigquery.StringFieldType},
+		{Name: "resource_type", Type: bigquery.StringFieldType},
+		{Name: "dependency_type", Type: bigquery.StringFieldType},
+		{Name: "direction", Type: bigquery.StringFieldType},
+	}},
+	{Name: "created_at", Type: bigquery.TimestampFieldType},
+	{Name: "updated_at", Type: bigquery.TimestampFieldType},
+	{Name: "discovered_at", Type: bigquery.TimestampFieldType, Required: true},
+}
+
+// resourceTablePartitioning partitions inventory snapshots by discovery date
+var resourceTablePartitioning = &bigquery.TimePartitioning{
+	Type:  bigquery.DayPartitioningType,
+	Field: "discovered_at",
+}
+
+func (e *Exporter) exportToBigQuery(ctx context.Context, data interface{}, options ExportOptions) error {
+	if options.Dataset == "" || options.Table == "" {
+		return fmt.Errorf("dataset and table are required for BigQuery export")
+	}
+
+	resources, err := exportableResources(data)
+	if err != nil {
+		return err
+	}
+
+	project := options.Project
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+
+	client, err := e.newBigQueryClient(ctx, project)
+	if err != nil {
+		return fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	defer client.Close()
+
+	if err := e.ensureResourceTable(ctx, client, options.Dataset, options.Table); err != nil {
+		return err
+	}
+
+	exportTime := time.Now().UTC()
+	rows := make([]bigquery.ValueSaver, 0, len(resources))
+	for _, resource := range resources {
+		rows = append(rows, resourceRow{resource: resource, exportTime: exportTime})
+	}
+
+	for i := 0; i < len(rows); i += e.config.BatchSize {
+		end := i + e.config.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := client.Insert(ctx, options.Dataset, options.Table, rows[i:end]); err != nil {
+			return fmt.Errorf("failed to insert batch %d-%d: %w", i, end, err)
+		}
+		e.logger.Debugf("Inserted batch %d-%d of %d records", i, end, len(rows))
+	}
+
+	e.logger.Infof("Successfully exported %d records to BigQuery: %s.%s.%s",
+		len(rows), project, options.Dataset, options.Table)
+	return nil
+}
+
+// ensureResourceTable creates the dataset and table if needed, and adds any
+// columns the table is missing
+func (e *Exporter) ensureResourceTable(ctx context.Context, client BigQueryClient, dataset, table string) error {
+	exists, err := client.DatasetExists(ctx, dataset)
+	if err != nil {
+		return fmt.Errorf("failed to look up dataset %s: %w", dataset, err)
+	}
+	if !exists {
+		e.logger.Infof("Creating BigQuery dataset %s", dataset)
+		if err := client.CreateDataset(ctx, dataset); err != nil {
+			return fmt.Errorf("failed to create dataset %s: %w", dataset, err)
+		}
+	}
+
+	metadata, err := client.TableMetadata(ctx, dataset, table)
+	if err != nil {
+		return fmt.Errorf("failed to look up table %s.%s: %w", dataset, table, err)
+	}
+	if metadata == nil {
+		e.logger.Infof("Creating BigQuery table %s.%s", dataset, table)
+		err := client.CreateTable(ctx, dataset, table, &bigquery.TableMetadata{
+			Schema:           resourceTableSchema,
+			TimePartitioning: resourceTablePartitioning,
+			Description:      "Cloud resource inventory exported by cloudrecon",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create table %s.%s: %w", dataset, table, err)
+		}
+		return nil
+	}
+
+	merged, added, err := mergeSchemas(metadata.Schema, resourceTableSchema)
+	if err != nil {
+		return fmt.Errorf("incompatible schema for %s.%s: %w", dataset, table, err)
+	}
+	if len(added) > 0 {
+		e.logger.Infof("Adding columns to %s.%s: %v", dataset, table, added)
+		if err := client.UpdateSchema(ctx, dataset, table, merged, metadata.ETag); err != nil {
+			return fmt.Errorf("failed to update schema of %s.%s: %w", dataset, table, err)
+		}
+	}
+	return nil
+}
+
+// mergeSchemas appends the fields of desired missing from existing. Added
+// fields are always nullable since existing rows have no value for them.
+func mergeSchemas(existing, desired bigquery.Schema) (bigquery.Schema, []string, error) {
+	byName := make(map[string]*bigquery.FieldSchema, len(existing))
+	for _, field := range existing {
+		byName[field.Name] = field
+	}
+
+	merged := append(bigquery.Schema{}, existing...)
+	var added []string
+	for _, field := range desired {
+		current, ok := byName[field.Name]
+		if !ok {
+			copied := *field
+			copied.Required = false
+			merged = append(merged, &copied)
+			added = append(added, field.Name)
+			continue
+		}
+		if current.Type != field.Type || current.Repeated != field.Repeated {
+			return nil, nil, fmt.Errorf("column %s is %s, expected %s", field.Name, current.Type, field.Type)
+		}
+		if field.Type == bigquery.RecordFieldType {
+			nested, nestedAdded, err := mergeSchemas(current.Schema, field.Schema)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s.%w", field.Name, err)
+			}
+			if len(nestedAdded) > 0 {
+				copied := *current
+				copied.Schema = nested
+				for i := range merged {
+					if merged[i].Name == field.Name {
+						merged[i] = &copied
+					}
+				}
+				for _, name := range nestedAdded {
+					added = append(added, field.Name+"."+name)
+				}
+			}
+		}
+	}
+	return merged, added, nil
+}
+
+func exportableResources(data interface{}) ([]Resource, error) {
+	switch v := data.(type) {
+	case *DiscoveryResults:
+		return v.Resources, nil
+	case []Resource:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type for export: %T", data)
+	}
+}
+
+// resourceRow saves a Resource as a BigQuery row
+type resourceRow struct {
+	resource   Resource
+	exportTime time.Time
+}
+
+func (r resourceRow) Save() (map[string]bigquery.Value, string, error) {
+	res := r.resource
+
+	discoveredAt := res.DiscoveredAt
+	if discoveredAt.IsZero() {
+		discoveredAt = r.exportTime
+	}
+
+	keys := make([]string, 0, len(res.Tags))
+	for key := range res.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := make([]bigquery.Value, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, map[string]bigquery.Value{"key": key, "value": res.Tags[key]})
+	}
+
+	dependencies := make([]bigquery.Value, 0, len(res.Dependencies))
+	for _, dep := range res.Dependencies {
+		dependencies = append(dependencies, map[string]bigquery.Value{
+			"resource_id":     dep.ResourceID,
+			"resource_type":   dep.ResourceType,
+			"dependency_type": dep.DependencyType,
+			"direction":       dep.Direction,
+		})
+	}
+
+	row := map[string]bigquery.Value{
+		"id":            res.ID,
+		"name":          res.Name,
+		"type":          res.Type,
+		"region":        res.Region,
+		"zone":          res.Zone,
+		"status":        res.Status,
+		"account_id":    res.Account.ID,
+		"account_name":  res.Account.Name,
+		"tags":          tags,
+		"dependencies":  dependencies,
+		"discovered_at": discoveredAt,
+	}
+	if !res.CreatedAt.IsZero() {
+		row["created_at"] = res.CreatedAt
+	}
+	if !res.UpdatedAt.IsZero() {
+		row["updated_at"] = res.UpdatedAt
+	}
+	if res.Cost != nil {
+		row["monthly_cost"] = res.Cost.MonthlyCost
+		row["currency"] = res.Cost.Currency
+	}
+	if len(res.Properties) > 0 {
+		properties, err := json.Marshal(res.Properties)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode properties of %s: %w", res.ID, err)
+		}
+		row["properties"] = string(properties)
+	}
+
+	// One insert ID per resource per discovery day lets BigQuery drop a
+	// retried insert, but its streaming dedup is best-effort and only spans
+	// about a minute, so the table can still hold duplicates to query around
+	insertID := res.ID + "@" + discoveredAt.Format("2006-01-02")
+	return row, insertID, nil
+}
+
+// bigQueryClient adapts *bigquery.Client to BigQueryClient
+type bigQueryClient struct {
+	client *bigquery.Client
+}
+
+func newBigQueryClient(ctx context.Context, project string) (BigQueryClient, error) {
+	client, err := bigquery.NewClient(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	return &bigQueryClient{client: client}, nil
+}
+
+func (c *bigQueryClient) DatasetExists(ctx context.Context, dataset string) (bool, error) {
+	_, err := c.client.Dataset(dataset).Metadata(ctx)
+	if isBigQueryNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *bigQueryClient) CreateDataset(ctx context.Context, dataset string) error {
+	return c.client.Dataset(dataset).Create(ctx, &bigquery.DatasetMetadata{})
+}
+
+func (c *bigQueryClient) TableMetadata(ctx context.Context, dataset, table string) (*bigquery.TableMetadata, error) {
+	metadata, err := c.client.Dataset(dataset).Table(table).Metadata(ctx)
+	if isBigQueryNotFound(err) {
+		return nil, nil
+	}
+	return metadata, err
+}
+
+func (c *bigQueryClient) CreateTable(ctx context.Context, dataset, table string, metadata *bigquery.TableMetadata) error {
+	return c.client.Dataset(dataset).Table(table).Create(ctx, metadata)
+}
+
+func (c *bigQueryClient) UpdateSchema(ctx context.Context, dataset, table string, schema bigquery.Schema, etag string) error {
+	_, err := c.client.Dataset(dataset).Table(table).Update(ctx, bigquery.TableMetadataToUpdate{Schema: schema}, etag)
+	return err
+}
+
+func (c *bigQueryClient) Insert(ctx context.Context, dataset, table string, rows []bigquery.ValueSaver) error {
+	return c.client.Dataset(dataset).Table(table).Inserter().Put(ctx, rows)
+}
+
+func (c *bigQueryClient) Close() error {
+	return c.client.Close()
+}
+
+func isBigQueryNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// fakeBigQuery records the calls the exporter makes
+type fakeBigQuery struct {
+	datasets map[string]bool
+	tables   map[string]*bigquery.TableMetadata
+	updates  []bigquery.Schema
+	inserts  [][]bigquery.ValueSaver
+	project  string
+	closed   bool
+}
+
+func newFakeBigQuery() *fakeBigQuery {
+	return &fakeBigQuery{datasets: make(map[string]bool), tables: make(map[string]*bigquery.TableMetadata)}
+}
+
+func (f *fakeBigQuery) DatasetExists(ctx context.Context, dataset string) (bool, error) {
+	return f.datasets[dataset], nil
+}
+
+func (f *fakeBigQuery) CreateDataset(ctx context.Context, dataset string) error {
+	f.datasets[dataset] = true
+	return nil
+}
+
+func (f *fakeBigQuery) TableMetadata(ctx context.Context, dataset, table string) (*bigquery.TableMetadata, error) {
+	return f.tables[dataset+"."+table], nil
+}
+
+func (f *fakeBigQuery) CreateTable(ctx context.Context, dataset, table string, metadata *bigquery.TableMetadata) error {
+	f.tables[dataset+"."+table] = metadata
+	return nil
+}
+
+func (f *fakeBigQuery) UpdateSchema(ctx context.Context, dataset, table string, schema bigquery.Schema, etag string) error {
+	f.updates = append(f.updates, schema)
+	f.tables[dataset+"."+table].Schema = schema
+	return nil
+}
+
+func (f *fakeBigQuery) Insert(ctx context.Context, dataset, table string, rows []bigquery.ValueSaver) error {
+	f.inserts = append(f.inserts, rows)
+	return nil
+}
+
+func (f *fakeBigQuery) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newFakeExporter(fake *fakeBigQuery) *Exporter {
+	e := NewExporter(quietLogger())
+	e.newBigQueryClient = func(ctx context.Context, project string) (BigQueryClient, error) {
+		fake.project = project
+		return fake, nil
+	}
+	return e
+}
+
+func TestExportToBigQueryCreatesPartitionedTable(t *testing.T) {
+	fake := newFakeBigQuery()
+	e := newFakeExporter(fake)
+	e.config.BatchSize = 2
+
+	discovered := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	results := &DiscoveryResults{Resources: []Resource{
+		{
+			ID: "projects/p/instances/web", Name: "web", Type: "compute.instances", Region: "us-central1",
+			Account:      Account{ID: "p", Name: "Project P"},
+			Tags:         map[string]string{"env": "prod", "app": "web"},
+			Properties:   map[string]interface{}{"machine_type": "e2-small"},
+			Cost:         &ResourceCost{MonthlyCost: 12.5, Currency: "USD"},
+			Dependencies: []ResourceDependency{{ResourceID: "projects/p/networks/vpc", DependencyType: "network"}},
+			DiscoveredAt: discovered,
+		},
+		{ID: "projects/p/buckets/logs", Name: "logs", Type: "storage.buckets"},
+		{ID: "projects/p/networks/vpc", Name: "vpc", Type: "compute.networks"},
+	}}
+
+	err := e.Export(context.Background(), results, ExportOptions{
+		Destination: "bq", Format: "json", Project: "p", Dataset: "inventory", Table: "resources",
+	})
+	if err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+
+	if fake.project != "p" || !fake.closed {
+		t.Fatalf("expected client for project p to be closed, got project %q closed %v", fake.project, fake.closed)
+	}
+	if !fake.datasets["inventory"] {
+		t.Fatal("expected dataset to be created")
+	}
+	table := fake.tables["inventory.resources"]
+	if table == nil {
+		t.Fatal("expected table to be created")
+	}
+	if table.TimePartitioning == nil || table.TimePartitioning.Field != "discovered_at" || table.TimePartitioning.Type != bigquery.DayPartitioningType {
+		t.Fatalf("expected daily partitioning on discovered_at, got %+v", table.TimePartitioning)
+	}
+	if len(fake.inserts) != 2 || len(fake.inserts[0]) != 2 || len(fake.inserts[1]) != 1 {
+		t.Fatalf("expected batches of 2 and 1 rows, got %d batches", len(fake.inserts))
+	}
+
+	row, insertID, err := fake.inserts[0][0].Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insertID != "projects/p/instances/web@2026-03-14" {
+		t.Errorf("unexpected insert ID %q", insertID)
+	}
+	if row["discovered_at"] != discovered || row["account_name"] != "Project P" || row["monthly_cost"] != 12.5 {
+		t.Errorf("unexpected row: %v", row)
+	}
+	if row["properties"] != `{"machine_type":"e2-small"}` {
+		t.Errorf("expected JSON properties, got %v", row["properties"])
+	}
+	tags := row["tags"].([]bigquery.Value)
+	if len(tags) != 2 || tags[0].(map[string]bigquery.Value)["key"] != "app" {
+		t.Errorf("expected tags sorted by key, got %v", tags)
+	}
+
+	// Resources without a discovery time are stamped with the export time
+	row, _, _ = fake.inserts[0][1].Save()
+	if at, ok := row["discovered_at"].(time.Time); !ok || at.IsZero() {
+		t.Errorf("expected discovered_at to default to the export time, got %v", row["discovered_at"])
+	}
+	if _, ok := row["monthly_cost"]; ok {
+		t.Error("expected no cost columns for a resource without cost")
+	}
+}
+
+func TestExportToBigQueryEvolvesSchemaAdditively(t *testing.T) {
+	fake := newFakeBigQuery()
+	fake.datasets["inventory"] = true
+	fake.tables["inventory.resources"] = &bigquery.TableMetadata{
+		ETag: "v1",
+		Schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.StringFieldType, Required: true},
+			{Name: "name", Type: bigquery.StringFieldType},
+			{Name: "legacy", Type: bigquery.StringFieldType},
+			{Name: "tags", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+				{Name: "key", Type: bigquery.StringFieldType},
+			}},
+		},
+	}
+	e := newFakeExporter(fake)
+
+	err := e.Export(context.Background(), []Resource{{ID: "a", Name: "a"}}, ExportOptions{
+		Destination: "bigquery", Format: "json", Dataset: "inventory", Table: "resources",
+	})
+	if err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	if len(fake.updates) != 1 {
+		t.Fatalf("expected one schema update, got %d", len(fake.updates))
+	}
+
+	schema := fake.updates[0]
+	if schema[2].Name != "legacy" {
+		t.Errorf("expected existing columns to be kept in place, got %s", schema[2].Name)
+	}
+	if len(schema) != len(resourceTableSchema)+1 {
+		t.Errorf("expected %d columns, got %d", len(resourceTableSchema)+1, len(schema))
+	}
+	for _, field := range schema[4:] {
+		if field.Required {
+			t.Errorf("added column %s must be nullable", field.Name)
+		}
+	}
+	if tags := schema[3].Schema; len(tags) != 2 || tags[1].Name != "value" {
+		t.Errorf("expected tags.value to be added, got %v", tags)
+	}
+
+	// A second export against the evolved table changes nothing
+	if err := e.Export(context.Background(), []Resource{{ID: "b"}}, ExportOptions{
+		Destination: "bigquery", Format: "json", Dataset: "inventory", Table: "resources",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.updates) != 1 {
+		t.Fatalf("expected no further schema updates, got %d", len(fake.updates))
+	}
+}
+
+func TestMergeSchemasRejectsTypeChanges(t *testing.T) {
+	existing := bigquery.Schema{{Name: "monthly_cost", Type: bigquery.StringFieldType}}
+	if _, _, err := mergeSchemas(existing, resourceTableSchema); err == nil {
+		t.Fatal("expected a type conflict error")
+	}
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCheckpointInterval is how often a running discovery saves its checkpoint
+const DefaultCheckpointInterval = 30 * time.Second
+
+// discoveryCheckpoint is the resume file: the partial results of every scope
+// (a resource type, or a type in one zone) that finished listing
+type discoveryCheckpoint struct {
+	StartTime time.Time             `json:"start_time"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Completed map[string][]Resource `json:"completed"`
+}
+
+// checkpointWriter tracks completed scopes and saves them atomically
+type checkpointWriter struct {
+	mu    sync.Mutex
+	path  string
+	state discoveryCheckpoint
+	dirty bool
+}
+
+// openCheckpoint starts a checkpoint at path, continuing the one already
+// there when resume is set
+func openCheckpoint(path string, resume bool) (*checkpointWriter, error) {
+	cp := &checkpointWriter{
+		path:  path,
+		state: discoveryCheckpoint{StartTime: time.Now(), Completed: make(map[string][]Resource)},
+	}
+	if !resume {
+		return cp, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp.state); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if cp.state.Completed == nil {
+		cp.state.Completed = make(map[string][]Resource)
+	}
+	return cp, nil
+}
+
+// scopeKey names a listing scope in the checkpoint
+func scopeKey(resourceType string, filters map[string]interface{}) string {
+	if zone, ok := filters["zone"].(string); ok && zone != "" {
+		return resourceType + "@" + zone
+	}
+	return resourceType
+}
+
+// done reports whether the scope finished in an earlier run
+func (c *checkpointWriter) done(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.state.Completed[key]
+	return ok
+}
+
+// complete records the resources a scope found
+func (c *checkpointWriter) complete(key string, resources []Resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if resources == nil {
+		resources = []Resource{}
+	}
+	c.state.Completed[key] = resources
+	c.dirty = true
+}
+
+// resources returns everything found by completed scopes
+func (c *checkpointWriter) resources() (int, []Resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var all []Resource
+	for _, resources := range c.state.Completed {
+		all = append(all, resources...)
+	}
+	return len(c.state.Completed), all
+}
+
+// save writes the checkpoint when it changed, through a temporary file and a
+// rename so a crash never leaves a half-written checkpoint
+func (c *checkpointWriter) save() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	c.state.UpdatedAt = time.Now()
+	data, err := json.Marshal(c.state)
+	c.dirty = false
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(c.path, data); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// remove deletes the checkpoint once a discovery completes
+func (c *checkpointWriter) remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// failingProvider fails listings of the scopes in fail and records every call
+type failingProvider struct {
+	throttlingProvider
+	fail  map[string]bool
+	calls []string
+}
+
+func (p *failingProvider) ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]Resource, error) {
+	key := scopeKey(resourceType, filters)
+	p.mu.Lock()
+	p.calls = append(p.calls, key)
+	p.mu.Unlock()
+
+	if p.fail[key] {
+		return nil, errors.New("googleapi: Error 403: permission denied")
+	}
+	return []Resource{{ID: key, Type: resourceType, Status: "running"}}, nil
+}
+
+func TestDiscoverResumesFromCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	zones := []string{"europe-west1-b", "europe-west1-c"}
+	options := DiscoveryOptions{
+		MaxWorkers:    8,
+		RateLimit:     10000,
+		RetryAttempts: 1,
+		RetryDelay:    time.Millisecond,
+		Zones:         zones,
+		Checkpoint:    path,
+	}
+
+	// The first run fails on one type and one zone of another
+	first := &failingProvider{fail: map[string]bool{"sql.instances": true, "compute.instances@europe-west1-c": true}}
+	results, err := NewDiscoverer(first, quietLogger(), options).Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(results.Errors) != 2 {
+		t.Fatalf("expected 2 failed scopes, got %d", len(results.Errors))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected a checkpoint after a failed run: %v", err)
+	}
+	var checkpoint discoveryCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		t.Fatalf("checkpoint is not valid JSON: %v", err)
+	}
+	types := len(NewDiscoverer(first, quietLogger(), options).getResourceTypes())
+	// Every type, plus a second zone for each zonal type, less the failures
+	scopes := types + len(zonalResourceTypes) - 2
+	if len(checkpoint.Completed) != scopes {
+		t.Fatalf("expected %d completed scopes in the checkpoint, got %d", scopes, len(checkpoint.Completed))
+	}
+	if _, ok := checkpoint.Completed["compute.instances@europe-west1-b"]; !ok {
+		t.Fatal("expected the zone that succeeded to be checkpointed")
+	}
+	if matches, _ := filepath.Glob(path + ".tmp-*"); len(matches) != 0 {
+		t.Fatalf("temporary checkpoint files left behind: %v", matches)
+	}
+
+	// Resuming only scans what failed, and a complete run removes the checkpoint
+	second := &failingProvider{}
+	options.Resume = true
+	results, err = NewDiscoverer(second, quietLogger(), options).Discover(context.Background())
+	if err != nil {
+		t.Fatalf("resumed Discover returned error: %v", err)
+	}
+	sort.Strings(second.calls)
+	if want := []string{"compute.instances@europe-west1-c", "sql.instances"}; len(second.calls) != 2 || second.calls[0] != want[0] || second.calls[1] != want[1] {
+		t.Fatalf("expected only the failed scopes to be re-scanned, got %v", second.calls)
+	}
+	if len(results.Errors) != 0 || results.Summary.TotalResources != scopes+2 {
+		t.Fatalf("expected %d resources and no errors, got %d and %d errors", scopes+2, results.Summary.TotalResources, len(results.Errors))
+	}
+	if got := results.Metadata["resumed_scopes"]; got != scopes {
+		t.Fatalf("expected %d resumed scopes recorded, got %v", scopes, got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the checkpoint removed after a complete run, got %v", err)
+	}
+}
+
+func TestDiscoverCheckpointsPeriodically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	release := make(chan struct{})
+	provider := &blockingProvider{block: "sql.instances", release: release}
+	d := NewDiscoverer(provider, quietLogger(), DiscoveryOptions{
+		MaxWorkers:         8,
+		RateLimit:          10000,
+		Checkpoint:         path,
+		CheckpointInterval: 5 * time.Millisecond,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = d.Discover(context.Background())
+	}()
+
+	// While one type hangs, the others reach the checkpoint on disk
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var checkpoint discoveryCheckpoint
+			if err := json.Unmarshal(data, &checkpoint); err != nil {
+				t.Fatalf("checkpoint is not valid JSON: %v", err)
+			}
+			if len(checkpoint.Completed) == len(d.getResourceTypes())-1 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a periodic checkpoint")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	<-done
+}
+
+// blockingProvider hangs listings of one type until release is closed
+type blockingProvider struct {
+	throttlingProvider
+	block   string
+	release chan struct{}
+}
+
+func (p *blockingProvider) ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]Resource, error) {
+	if resourceType == p.block {
+		<-p.release
+	}
+	return []Resource{{ID: resourceType, Type: resourceType}}, nil
+}
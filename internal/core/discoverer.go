@@ -2,18 +2,26 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
 type Discoverer struct {
-	provider Provider
-	logger   *logrus.Logger
-	options  DiscoveryOptions
-	mutex    sync.RWMutex
-	cache    *ResourceCache
+	provider    Provider
+	logger      *logrus.Logger
+	options     DiscoveryOptions
+	mutex       sync.RWMutex
+	cache       *ResourceCache
+	limiter     *adaptiveLimiter
+	typeLimiter *typeLimiter
+	rateLimiter *rate.Limiter
+	progress    DiscoveryProgress
+	timings     map[string]TypeTiming
+	checkpoint  *checkpointWriter
 }
 
 type DiscoveryOptions struct {
@@ -32,6 +40,32 @@ type DiscoveryOptions struct {
 	RateLimit       int
 	BatchSize       int
 	FollowPageToken bool
+	// Progress, if set, is called as resources are scanned and types complete
+	Progress ProgressFunc
+	// Zones, if set, splits zonal resource types into one listing per zone
+	Zones []string
+	// TypeLimits caps in-flight calls per resource type and DefaultTypeLimit
+	// applies to the rest; zero leaves a type bounded only by MaxWorkers
+	TypeLimits       map[string]int
+	DefaultTypeLimit int
+	// Checkpoint, if set, is where partial results are saved every
+	// CheckpointInterval; with Resume, scopes it records as finished are
+	// loaded from it instead of being scanned again
+	Checkpoint         string
+	CheckpointInterval time.Duration
+	Resume             bool
+}
+
+// zonalResourceTypes are listed once per zone when DiscoveryOptions.Zones is set
+var zonalResourceTypes = map[string]bool{
+	"compute.instances": true,
+	"compute.disks":     true,
+}
+
+// TypeTiming is how long discovering one resource type took
+type TypeTiming struct {
+	Duration time.Duration `json:"duration"`
+	Calls    int           `json:"calls"`
 }
 
 type DiscoveryResults struct {
@@ -42,6 +76,7 @@ type DiscoveryResults struct {
 	EndTime         time.Time               `json:"end_time"`
 	Duration        time.Duration           `json:"duration"`
 	Metadata        map[string]interface{}  `json:"metadata,omitempty"`
+	TypeTimings     map[string]TypeTiming   `json:"type_timings,omitempty"`
 }
 
 type DiscoverySummary struct {
@@ -84,11 +119,17 @@ func NewDiscoverer(provider Provider, logger *logrus.Logger, options DiscoveryOp
 	if options.RateLimit <= 0 {
 		options.RateLimit = 100
 	}
+	if options.CheckpointInterval <= 0 {
+		options.CheckpointInterval = DefaultCheckpointInterval
+	}
 
 	discoverer := &Discoverer{
-		provider: provider,
-		logger:   logger,
-		options:  options,
+		provider:    provider,
+		logger:      logger,
+		options:     options,
+		limiter:     newAdaptiveLimiter(options.MaxWorkers),
+		typeLimiter: newTypeLimiter(options.TypeLimits, options.DefaultTypeLimit),
+		rateLimiter: newRateLimiter(options.RateLimit),
 	}
 
 	if options.CacheEnabled {
@@ -120,17 +161,36 @@ func (d *Discoverer) Discover(ctx context.Context) (*DiscoveryResults, error) {
 	ctx, cancel := context.WithTimeout(ctx, d.options.Timeout)
 	defer cancel()
 
+	d.checkpoint = nil
+	if d.options.Checkpoint != "" {
+		checkpoint, err := openCheckpoint(d.options.Checkpoint, d.options.Resume)
+		if err != nil {
+			return nil, err
+		}
+		d.checkpoint = checkpoint
+	}
+
 	resourceTypes := d.getResourceTypes()
 	d.logger.Infof("Starting discovery for %d resource types", len(resourceTypes))
 
 	var wg sync.WaitGroup
 	resourceChan := make(chan Resource, d.options.BatchSize)
 	errorChan := make(chan DiscoveryError, d.options.MaxWorkers)
-	semaphore := make(chan struct{}, d.options.MaxWorkers)
+
+	d.mutex.Lock()
+	d.progress = DiscoveryProgress{TypesTotal: len(resourceTypes)}
+	d.timings = make(map[string]TypeTiming, len(resourceTypes))
+	d.mutex.Unlock()
+
+	var collectors sync.WaitGroup
+	collectors.Add(2)
 
 	go func() {
+		defer collectors.Done()
 		for resource := range resourceChan {
 			d.mutex.Lock()
+			d.progress.ResourcesScanned++
+			d.progress.CurrentType = resource.Type
 			results.Resources = append(results.Resources, resource)
 			results.Summary.TotalResources++
 			results.Summary.ResourcesByType[resource.Type]++
@@ -141,10 +201,12 @@ func (d *Discoverer) Discover(ctx context.Context) (*DiscoveryResults, error) {
 				results.Summary.EstimatedMonthlyCost += resource.Cost.EstimatedAnnualCost / 12
 			}
 			d.mutex.Unlock()
+			d.reportProgress()
 		}
 	}()
 
 	go func() {
+		defer collectors.Done()
 		for err := range errorChan {
 			d.mutex.Lock()
 			results.Errors = append(results.Errors, err)
@@ -152,20 +214,67 @@ func (d *Discoverer) Discover(ctx context.Context) (*DiscoveryResults, error) {
 		}
 	}()
 
+	// Resumed scopes count as discovered without another API call
+	if d.checkpoint != nil {
+		scopes, resumed := d.checkpoint.resources()
+		if scopes > 0 {
+			d.logger.Infof("Resuming from %s: %d scopes and %d resources already discovered", d.options.Checkpoint, scopes, len(resumed))
+			results.Metadata["resumed_scopes"] = scopes
+		}
+		for _, resource := range resumed {
+			resourceChan <- resource
+		}
+	}
+
+	stopCheckpoints := d.saveCheckpoints()
+
+	// Concurrency is bounded per API call by the adaptive limiter rather than
+	// per resource type, so throttled types back off without holding a slot
 	for _, resourceType := range resourceTypes {
 		wg.Add(1)
 		go func(rt string) {
 			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
+			start := time.Now()
 			d.discoverResourceType(ctx, rt, resourceChan, errorChan)
+			elapsed := time.Since(start)
+
+			d.mutex.Lock()
+			d.progress.TypesCompleted++
+			timing := d.timings[rt]
+			timing.Duration = elapsed
+			d.timings[rt] = timing
+			d.mutex.Unlock()
+			d.logger.Debugf("Discovered %s in %v (%d calls)", rt, elapsed, timing.Calls)
+			d.reportProgress()
 		}(resourceType)
 	}
 
 	wg.Wait()
 	close(resourceChan)
 	close(errorChan)
+	collectors.Wait()
+	stopCheckpoints()
+
+	// A complete discovery has nothing to resume; otherwise the checkpoint
+	// keeps what finished so a rerun only scans the rest
+	if d.checkpoint != nil {
+		var err error
+		if len(results.Errors) == 0 && ctx.Err() == nil {
+			err = d.checkpoint.remove()
+		} else {
+			err = d.checkpoint.save()
+		}
+		if err != nil {
+			d.logger.Warnf("Checkpoint %s: %v", d.options.Checkpoint, err)
+		}
+	}
+
+	if _, throttled := d.limiter.Stats(); throttled > 0 {
+		results.Metadata["throttled_requests"] = throttled
+	}
+	d.mutex.RLock()
+	results.TypeTimings = d.timings
+	d.mutex.RUnlock()
 
 	results.EndTime = time.Now()
 	results.Duration = results.EndTime.Sub(results.StartTime)
@@ -201,6 +310,43 @@ func (d *Discoverer) discoverResourceType(ctx context.Context, resourceType stri
 		}
 	}
 
+	var wg sync.WaitGroup
+	for _, filters := range d.listScopes(resourceType) {
+		if d.checkpoint != nil && d.checkpoint.done(scopeKey(resourceType, filters)) {
+			continue
+		}
+		wg.Add(1)
+		go func(filters map[string]interface{}) {
+			defer wg.Done()
+			d.discoverScope(ctx, resourceType, filters, resourceChan, errorChan)
+		}(filters)
+	}
+	wg.Wait()
+}
+
+// listScopes returns the filters for each listing of resourceType: one per
+// zone for zonal types when zones are configured, otherwise just one
+func (d *Discoverer) listScopes(resourceType string) []map[string]interface{} {
+	if len(d.options.Zones) == 0 || !zonalResourceTypes[resourceType] {
+		return []map[string]interface{}{d.options.Filters}
+	}
+
+	scopes := make([]map[string]interface{}, 0, len(d.options.Zones))
+	for _, zone := range d.options.Zones {
+		filters := make(map[string]interface{}, len(d.options.Filters)+1)
+		for key, value := range d.options.Filters {
+			filters[key] = value
+		}
+		filters["zone"] = zone
+		scopes = append(scopes, filters)
+	}
+	return scopes
+}
+
+// discoverScope lists one scope of resourceType with retries
+func (d *Discoverer) discoverScope(ctx context.Context, resourceType string, filters map[string]interface{},
+	resourceChan chan<- Resource, errorChan chan<- DiscoveryError) {
+
 	var lastErr error
 	for attempt := 0; attempt <= d.options.RetryAttempts; attempt++ {
 		if attempt > 0 {
@@ -211,11 +357,9 @@ func (d *Discoverer) discoverResourceType(ctx context.Context, resourceType stri
 			}
 		}
 
-		// ListResources not available in Provider interface - using DiscoverResources instead
-		var resources []Resource
-		var err error
-		// resources, err := d.provider.ListResources(ctx, resourceType, d.options.Filters)
+		resources, err := d.listResources(ctx, resourceType, filters)
 		if err == nil {
+			var included []Resource
 			for _, resource := range resources {
 				if d.shouldIncludeResource(resource) {
 					if d.options.DeepScan {
@@ -231,8 +375,12 @@ func (d *Discoverer) discoverResourceType(ctx context.Context, resourceType stri
 					case <-ctx.Done():
 						return
 					}
+					included = append(included, resource)
 				}
 			}
+			if d.checkpoint != nil {
+				d.checkpoint.complete(scopeKey(resourceType, filters), included)
+			}
 			return
 		}
 
@@ -243,8 +391,12 @@ func (d *Discoverer) discoverResourceType(ctx context.Context, resourceType stri
 	}
 
 	if lastErr != nil {
+		resource := resourceType
+		if zone, ok := filters["zone"].(string); ok {
+			resource = fmt.Sprintf("%s in %s", resourceType, zone)
+		}
 		errorChan <- DiscoveryError{
-			Resource: resourceType, // Using Resource field
+			Resource: resource, // Using Resource field
 			Error:        lastErr.Error(),
 			Timestamp:    time.Now(),
 			Retryable:    d.isRetryableError(lastErr),
@@ -252,6 +404,87 @@ func (d *Discoverer) discoverResourceType(ctx context.Context, resourceType stri
 	}
 }
 
+// listResources makes one rate-limited, concurrency-limited API call. The
+// type's own slot is taken before a global one so a type at its limit
+// doesn't hold global slots other types could use.
+func (d *Discoverer) listResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]Resource, error) {
+	lister, ok := d.provider.(ResourceLister)
+	if !ok {
+		return nil, nil
+	}
+
+	if err := d.typeLimiter.Acquire(ctx, resourceType); err != nil {
+		return nil, err
+	}
+	defer d.typeLimiter.Release(resourceType)
+	if err := d.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if err := d.limiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	d.progress.CurrentType = resourceType
+	if d.timings != nil {
+		timing := d.timings[resourceType]
+		timing.Calls++
+		d.timings[resourceType] = timing
+	}
+	d.mutex.Unlock()
+
+	resources, err := lister.ListResources(ctx, resourceType, filters)
+	throttled := isThrottleError(err)
+	d.limiter.Release(throttled)
+	if throttled {
+		limit, _ := d.limiter.Stats()
+		d.logger.Debugf("Throttled listing %s, concurrency reduced to %d", resourceType, limit)
+	}
+	return resources, err
+}
+
+// saveCheckpoints saves the checkpoint every CheckpointInterval until the
+// returned stop func is called
+func (d *Discoverer) saveCheckpoints() (stop func()) {
+	if d.checkpoint == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(d.options.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.checkpoint.save(); err != nil {
+					d.logger.Warnf("Checkpoint %s: %v", d.options.Checkpoint, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// reportProgress sends the current progress snapshot to the Progress callback
+func (d *Discoverer) reportProgress() {
+	if d.options.Progress == nil {
+		return
+	}
+	d.mutex.RLock()
+	progress := d.progress
+	d.mutex.RUnlock()
+	progress.Concurrency, progress.Throttled = d.limiter.Stats()
+	d.options.Progress(progress)
+}
+
 func (d *Discoverer) shouldIncludeResource(resource Resource) bool {
 	if d.options.Filters == nil {
 		return true
@@ -12,7 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/bigquery"
 	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +19,9 @@ import (
 type Exporter struct {
 	logger *logrus.Logger
 	config ExporterConfig
+
+	// newBigQueryClient is replaced in tests with a fake
+	newBigQueryClient func(ctx context.Context, project string) (BigQueryClient, error)
 }
 
 type ExporterConfig struct {
@@ -34,6 +36,7 @@ type ExportOptions struct {
 	Format          string
 	Destination     string
 	Bucket          string
+	Project         string
 	Dataset         string
 	Table           string
 	Path            string
@@ -66,6 +69,7 @@ func NewExporter(logger *logrus.Logger) *Exporter {
 			RetryAttempts:    3,
 			RetryDelay:       2 * time.Second,
 		},
+		newBigQueryClient: newBigQueryClient,
 	}
 }
 
@@ -119,6 +123,8 @@ func (e *Exporter) exportToFile(ctx context.Context, data interface{}, options E
 		content, err = e.marshalCSV(data)
 	case "terraform", "tf":
 		content, err = e.marshalTerraform(data)
+	case "infracost":
+		content, err = e.marshalInfracost(data)
 	case "yaml":
 		content, err = e.marshalYAML(data)
 	case "html":
@@ -206,61 +212,6 @@ func (e *Exporter) exportToGCS(ctx context.Context, data interface{}, options Ex
 	return nil
 }
 
-func (e *Exporter) exportToBigQuery(ctx context.Context, data interface{}, options ExportOptions) error {
-	if options.Dataset == "" || options.Table == "" {
-		return fmt.Errorf("dataset and table are required for BigQuery export")
-	}
-
-	client, err := bigquery.NewClient(ctx, os.Getenv("GOOGLE_CLOUD_PROJECT"))
-	if err != nil {
-		return fmt.Errorf("failed to create BigQuery client: %w", err)
-	}
-	defer client.Close()
-
-	dataset := client.Dataset(options.Dataset)
-	table := dataset.Table(options.Table)
-
-	schema, err := e.inferBigQuerySchema(data)
-	if err != nil {
-		return fmt.Errorf("failed to infer schema: %w", err)
-	}
-
-	metadata := &bigquery.TableMetadata{
-		Schema: schema,
-	}
-
-	if err := table.Create(ctx, metadata); err != nil {
-		if !strings.Contains(err.Error(), "Already Exists") {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
-	}
-
-	inserter := table.Inserter()
-
-	records, err := e.prepareBigQueryRecords(data)
-	if err != nil {
-		return fmt.Errorf("failed to prepare records: %w", err)
-	}
-
-	for i := 0; i < len(records); i += e.config.BatchSize {
-		end := i + e.config.BatchSize
-		if end > len(records) {
-			end = len(records)
-		}
-
-		batch := records[i:end]
-		if err := inserter.Put(ctx, batch); err != nil {
-			return fmt.Errorf("failed to insert batch %d-%d: %w", i, end, err)
-		}
-
-		e.logger.Debugf("Inserted batch %d-%d of %d records", i, end, len(records))
-	}
-
-	e.logger.Infof("Successfully exported %d records to BigQuery: %s.%s",
-		len(records), options.Dataset, options.Table)
-	return nil
-}
-
 func (e *Exporter) exportToStdout(data interface{}, options ExportOptions) error {
 	content, err := e.prepareContent(data, options)
 	if err != nil {
@@ -282,6 +233,8 @@ func (e *Exporter) prepareContent(data interface{}, options ExportOptions) ([]by
 		content, err = e.marshalCSV(data)
 	case "terraform", "tf":
 		content, err = e.marshalTerraform(data)
+	case "infracost":
+		content, err = e.marshalInfracost(data)
 	case "yaml":
 		content, err = e.marshalYAML(data)
 	default:
@@ -593,68 +546,9 @@ func (e *Exporter) compressData(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (e *Exporter) inferBigQuerySchema(data interface{}) (bigquery.Schema, error) {
-	switch v := data.(type) {
-	case *DiscoveryResults:
-		return bigquery.Schema{
-			{Name: "id", Type: bigquery.StringFieldType},
-			{Name: "name", Type: bigquery.StringFieldType},
-			{Name: "type", Type: bigquery.StringFieldType},
-			{Name: "region", Type: bigquery.StringFieldType},
-			{Name: "status", Type: bigquery.StringFieldType},
-			{Name: "created_at", Type: bigquery.TimestampFieldType},
-			{Name: "modified_at", Type: bigquery.TimestampFieldType},
-			{Name: "cost", Type: bigquery.FloatFieldType},
-		}, nil
-
-	case []Resource:
-		if len(v) == 0 {
-			return nil, fmt.Errorf("cannot infer schema from empty resource list")
-		}
-		return e.inferBigQuerySchema(&DiscoveryResults{Resources: v})
-
-	default:
-		return nil, fmt.Errorf("unsupported data type for BigQuery: %T", data)
-	}
-}
-
-func (e *Exporter) prepareBigQueryRecords(data interface{}) ([]interface{}, error) {
-	var records []interface{}
-
-	switch v := data.(type) {
-	case *DiscoveryResults:
-		for _, resource := range v.Resources {
-			cost := 0.0
-			if resource.Cost != nil {
-				cost = resource.Cost.MonthlyCost
-			}
-
-			record := map[string]interface{}{
-				"id":          resource.ID,
-				"name":        resource.Name,
-				"type":        resource.Type,
-				"region":      resource.Region,
-				"status":      resource.Status,
-				"created_at":  resource.CreatedAt,
-				"modified_at": resource.UpdatedAt, // Using UpdatedAt
-				"cost":        cost,
-			}
-			records = append(records, record)
-		}
-
-	case []Resource:
-		return e.prepareBigQueryRecords(&DiscoveryResults{Resources: v})
-
-	default:
-		return nil, fmt.Errorf("unsupported data type: %T", data)
-	}
-
-	return records, nil
-}
-
 func (e *Exporter) getFileExtension(format string) string {
 	switch strings.ToLower(format) {
-	case "json":
+	case "json", "infracost":
 		return "json"
 	case "csv":
 		return "csv"
@@ -673,7 +567,7 @@ func (e *Exporter) getFileExtension(format string) string {
 
 func (e *Exporter) getContentType(format string) string {
 	switch strings.ToLower(format) {
-	case "json":
+	case "json", "infracost":
 		return "application/json"
 	case "csv":
 		return "text/csv"
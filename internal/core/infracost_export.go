@@ -0,0 +1,242 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// Infracost and similar cost tools price Terraform plans, so the infracost
+// format writes discovered resources as the planned values of a Terraform
+// JSON plan (https://developer.hashicorp.com/terraform/internals/json-format).
+
+// googleProvider is the provider address resources in the plan belong to
+const googleProvider = "registry.terraform.io/hashicorp/google"
+
+// planFormatVersion is the Terraform JSON plan format version the export follows
+const planFormatVersion = "1.2"
+
+// InfracostPlan is a Terraform JSON plan holding only planned values
+type InfracostPlan struct {
+	FormatVersion    string              `json:"format_version"`
+	TerraformVersion string              `json:"terraform_version"`
+	PlannedValues    InfracostValues     `json:"planned_values"`
+	ResourceChanges  []InfracostChange   `json:"resource_changes"`
+	Configuration    InfracostConfigRoot `json:"configuration"`
+}
+
+type InfracostValues struct {
+	RootModule InfracostModule `json:"root_module"`
+}
+
+type InfracostModule struct {
+	Resources []InfracostResource `json:"resources"`
+}
+
+// InfracostResource is one resource of the plan; Values holds the
+// attributes cost tools price it by
+type InfracostResource struct {
+	Address      string                 `json:"address"`
+	Mode         string                 `json:"mode"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	ProviderName string                 `json:"provider_name"`
+	Values       map[string]interface{} `json:"values"`
+}
+
+// InfracostChange marks a resource as existing, so tools report its cost
+// without treating it as new
+type InfracostChange struct {
+	Address      string               `json:"address"`
+	Mode         string               `json:"mode"`
+	Type         string               `json:"type"`
+	Name         string               `json:"name"`
+	ProviderName string               `json:"provider_name"`
+	Change       InfracostChangeValue `json:"change"`
+}
+
+type InfracostChangeValue struct {
+	Actions []string               `json:"actions"`
+	Before  map[string]interface{} `json:"before"`
+	After   map[string]interface{} `json:"after"`
+}
+
+type InfracostConfigRoot struct {
+	ProviderConfig map[string]InfracostProvider `json:"provider_config"`
+}
+
+type InfracostProvider struct {
+	Name string `json:"name"`
+}
+
+// infracostMapping maps a discovered resource type to its Terraform type and
+// the cost-relevant attributes taken from its properties
+type infracostMapping struct {
+	terraformType string
+	values        func(resource Resource) map[string]interface{}
+}
+
+var infracostMappings = map[string]infracostMapping{
+	"compute.instances": {"google_compute_instance", func(r Resource) map[string]interface{} {
+		return map[string]interface{}{
+			"machine_type": lastSegment(r.Properties["machineType"]),
+			"zone":         zoneOf(r),
+			"scheduling":   []interface{}{map[string]interface{}{"preemptible": r.Properties["preemptible"] == true}},
+		}
+	}},
+	"compute.disks": {"google_compute_disk", func(r Resource) map[string]interface{} {
+		return map[string]interface{}{
+			"type": lastSegment(r.Properties["type"]),
+			"size": r.Properties["sizeGb"],
+			"zone": zoneOf(r),
+		}
+	}},
+	"storage.buckets": {"google_storage_bucket", func(r Resource) map[string]interface{} {
+		location := r.Properties["location"]
+		if location == nil {
+			location = r.Region
+		}
+		return map[string]interface{}{
+			"location":      location,
+			"storage_class": r.Properties["storageClass"],
+		}
+	}},
+	"compute.addresses": {"google_compute_address", func(r Resource) map[string]interface{} {
+		return map[string]interface{}{
+			"address_type": r.Properties["addressType"],
+			"region":       r.Region,
+		}
+	}},
+	"sql.instances": {"google_sql_database_instance", func(r Resource) map[string]interface{} {
+		return map[string]interface{}{
+			"database_version": r.Properties["databaseVersion"],
+			"region":           r.Region,
+			"settings": []interface{}{map[string]interface{}{
+				"tier":              r.Properties["tier"],
+				"availability_type": r.Properties["availabilityType"],
+				"disk_size":         r.Properties["diskSize"],
+				"disk_type":         r.Properties["diskType"],
+			}},
+		}
+	}},
+	"container.clusters": {"google_container_cluster", func(r Resource) map[string]interface{} {
+		location := zoneOf(r)
+		if location == "" {
+			location = r.Region
+		}
+		return map[string]interface{}{"location": location}
+	}},
+	// Free resources are listed so the export covers the whole inventory
+	"compute.networks": {"google_compute_network", func(r Resource) map[string]interface{} {
+		return map[string]interface{}{}
+	}},
+	"compute.firewalls": {"google_compute_firewall", func(r Resource) map[string]interface{} {
+		return map[string]interface{}{"network": lastSegment(r.Properties["network"])}
+	}},
+}
+
+// lastSegment returns the name at the end of a GCP resource URL, such as the
+// machine type of an instance's machineType
+func lastSegment(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return value
+	}
+	return path.Base(s)
+}
+
+func zoneOf(r Resource) string {
+	if r.Zone != "" {
+		return path.Base(r.Zone)
+	}
+	if zone, ok := r.Properties["zone"].(string); ok {
+		return path.Base(zone)
+	}
+	return ""
+}
+
+// buildInfracostPlan converts resources into a plan. Resources whose types
+// have no Terraform mapping are left out and returned as skipped.
+func (e *Exporter) buildInfracostPlan(resources []Resource) (*InfracostPlan, []string) {
+	plan := &InfracostPlan{
+		FormatVersion:    planFormatVersion,
+		TerraformVersion: "1.5.0",
+		PlannedValues:    InfracostValues{RootModule: InfracostModule{Resources: []InfracostResource{}}},
+		ResourceChanges:  []InfracostChange{},
+		Configuration: InfracostConfigRoot{ProviderConfig: map[string]InfracostProvider{
+			"google": {Name: "google"},
+		}},
+	}
+
+	sorted := append([]Resource(nil), resources...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Type != sorted[j].Type {
+			return sorted[i].Type < sorted[j].Type
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var skipped []string
+	used := make(map[string]int)
+	for _, resource := range sorted {
+		mapping, ok := infracostMappings[resource.Type]
+		if !ok {
+			skipped = append(skipped, resource.Type+"/"+resource.Name)
+			continue
+		}
+
+		name := e.sanitizeTerraformName(resource.Name)
+		if name == "" {
+			name = "resource"
+		}
+		address := mapping.terraformType + "." + name
+		if used[address]++; used[address] > 1 {
+			name = fmt.Sprintf("%s_%d", name, used[address])
+			address = mapping.terraformType + "." + name
+		}
+
+		values := mapping.values(resource)
+		values["name"] = resource.Name
+		if len(resource.Tags) > 0 {
+			values["labels"] = resource.Tags
+		}
+		for key, value := range values {
+			if value == nil || value == "" {
+				delete(values, key)
+			}
+		}
+
+		plan.PlannedValues.RootModule.Resources = append(plan.PlannedValues.RootModule.Resources, InfracostResource{
+			Address:      address,
+			Mode:         "managed",
+			Type:         mapping.terraformType,
+			Name:         name,
+			ProviderName: googleProvider,
+			Values:       values,
+		})
+		plan.ResourceChanges = append(plan.ResourceChanges, InfracostChange{
+			Address:      address,
+			Mode:         "managed",
+			Type:         mapping.terraformType,
+			Name:         name,
+			ProviderName: googleProvider,
+			Change:       InfracostChangeValue{Actions: []string{"no-op"}, Before: values, After: values},
+		})
+	}
+
+	return plan, skipped
+}
+
+func (e *Exporter) marshalInfracost(data interface{}) ([]byte, error) {
+	resources, err := exportableResources(data)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, skipped := e.buildInfracostPlan(resources)
+	if len(skipped) > 0 {
+		e.logger.Warnf("Infracost export skipped %d resources without a Terraform mapping: %v", len(skipped), skipped)
+	}
+	return json.MarshalIndent(plan, "", "  ")
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
+)
+
+// terraformPlanSchema is the part of the Terraform JSON plan format Infracost
+// reads, written out independently of the exporter's types
+type terraformPlanSchema struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	PlannedValues    struct {
+		RootModule struct {
+			Resources []terraformPlanResource `json:"resources"`
+		} `json:"root_module"`
+	} `json:"planned_values"`
+	ResourceChanges []struct {
+		Address      string `json:"address"`
+		Mode         string `json:"mode"`
+		Type         string `json:"type"`
+		Name         string `json:"name"`
+		ProviderName string `json:"provider_name"`
+		Change       struct {
+			Actions []string               `json:"actions"`
+			Before  map[string]interface{} `json:"before"`
+			After   map[string]interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+	Configuration struct {
+		ProviderConfig map[string]struct {
+			Name string `json:"name"`
+		} `json:"provider_config"`
+	} `json:"configuration"`
+}
+
+type terraformPlanResource struct {
+	Address      string                 `json:"address"`
+	Mode         string                 `json:"mode"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	ProviderName string                 `json:"provider_name"`
+	Values       map[string]interface{} `json:"values"`
+}
+
+func smallInventory() []Resource {
+	return []Resource{
+		{
+			Name: "web-1",
+			Type: "compute.instances",
+			Zone: "us-central1-a",
+			Tags: map[string]string{"team": "web"},
+			Properties: map[string]interface{}{
+				"machineType": "https://www.googleapis.com/compute/v1/projects/p/zones/us-central1-a/machineTypes/e2-standard-4",
+			},
+		},
+		{
+			Name: "web-1-data",
+			Type: "compute.disks",
+			Zone: "us-central1-a",
+			Properties: map[string]interface{}{
+				"sizeGb": "200",
+				"type":   "https://www.googleapis.com/compute/v1/projects/p/zones/us-central1-a/diskTypes/pd-ssd",
+			},
+		},
+		{
+			Name:       "assets",
+			Type:       "storage.buckets",
+			Properties: map[string]interface{}{"location": "US", "storageClass": "STANDARD"},
+		},
+		{Name: "default", Type: "compute.networks"},
+		{Name: "topic", Type: "pubsub.topics"},
+	}
+}
+
+func TestInfracostExportMatchesPlanSchema(t *testing.T) {
+	e := NewExporter(quietLogger())
+	content, err := e.prepareContent(smallInventory(), ExportOptions{Format: "infracost"})
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	problems, err := configschema.Validate(content, &terraformPlanSchema{})
+	if err != nil {
+		t.Fatalf("export is not JSON: %v", err)
+	}
+	for _, problem := range problems {
+		t.Errorf("schema problem: %s", problem)
+	}
+
+	var plan terraformPlanSchema
+	if err := json.Unmarshal(content, &plan); err != nil {
+		t.Fatalf("decoding export: %v", err)
+	}
+	if plan.FormatVersion == "" {
+		t.Error("format_version is missing")
+	}
+	if _, ok := plan.Configuration.ProviderConfig["google"]; !ok {
+		t.Error("google provider config is missing")
+	}
+
+	resources := plan.PlannedValues.RootModule.Resources
+	if len(resources) != 4 {
+		t.Fatalf("expected 4 mapped resources, got %d: %+v", len(resources), resources)
+	}
+	if len(plan.ResourceChanges) != len(resources) {
+		t.Errorf("expected a resource change per resource, got %d", len(plan.ResourceChanges))
+	}
+
+	byAddress := make(map[string]terraformPlanResource)
+	for _, resource := range resources {
+		if resource.Address != resource.Type+"."+resource.Name {
+			t.Errorf("address %q doesn't match %s.%s", resource.Address, resource.Type, resource.Name)
+		}
+		if resource.Mode != "managed" || resource.ProviderName != googleProvider {
+			t.Errorf("%s: unexpected mode %q or provider %q", resource.Address, resource.Mode, resource.ProviderName)
+		}
+		byAddress[resource.Address] = resource
+	}
+
+	instance, ok := byAddress["google_compute_instance.web_1"]
+	if !ok {
+		t.Fatalf("instance missing from %v", byAddress)
+	}
+	if instance.Values["machine_type"] != "e2-standard-4" || instance.Values["zone"] != "us-central1-a" {
+		t.Errorf("unexpected instance values: %v", instance.Values)
+	}
+	if labels, _ := instance.Values["labels"].(map[string]interface{}); labels["team"] != "web" {
+		t.Errorf("expected instance labels, got %v", instance.Values["labels"])
+	}
+
+	disk := byAddress["google_compute_disk.web_1_data"]
+	if disk.Values["type"] != "pd-ssd" || disk.Values["size"] != "200" {
+		t.Errorf("unexpected disk values: %v", disk.Values)
+	}
+
+	bucket := byAddress["google_storage_bucket.assets"]
+	if bucket.Values["location"] != "US" || bucket.Values["storage_class"] != "STANDARD" {
+		t.Errorf("unexpected bucket values: %v", bucket.Values)
+	}
+
+	if _, ok := byAddress["google_compute_network.default"]; !ok {
+		t.Error("free network resource should still be exported")
+	}
+}
+
+func TestInfracostExportNamesAreUnique(t *testing.T) {
+	e := NewExporter(quietLogger())
+	plan, skipped := e.buildInfracostPlan([]Resource{
+		{Name: "data", Type: "storage.buckets"},
+		{Name: "data", Type: "storage.buckets"},
+		{Name: "queue", Type: "pubsub.topics"},
+	})
+
+	if len(skipped) != 1 || skipped[0] != "pubsub.topics/queue" {
+		t.Errorf("expected the topic to be skipped, got %v", skipped)
+	}
+
+	resources := plan.PlannedValues.RootModule.Resources
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(resources))
+	}
+	if resources[0].Address == resources[1].Address {
+		t.Errorf("duplicate address %q", resources[0].Address)
+	}
+}
@@ -13,6 +13,13 @@ type Provider interface {
 	GetConfig() interface{}
 }
 
+// ResourceCounter is implemented by providers that can count resources by
+// type without fetching them, which summaries use instead of a full listing.
+// The counts apply the same filters discovery does.
+type ResourceCounter interface {
+	CountResources(ctx context.Context, filters map[string]interface{}) (map[string]int, error)
+}
+
 type CloudProvider interface {
 	Provider
 	GetRegions(ctx context.Context) ([]string, error)
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyFilePrefix names the report snapshots kept in a history directory
+const historyFilePrefix = "report-"
+
+// TrendPoint is one report's headline numbers
+type TrendPoint struct {
+	GeneratedAt    time.Time `json:"generated_at"`
+	TotalResources int       `json:"total_resources"`
+	MonthlyCost    float64   `json:"monthly_cost"`
+	SecurityScore  int       `json:"security_score"`
+}
+
+// TrendSection compares a report with the ones persisted before it
+type TrendSection struct {
+	// Points holds the history oldest first, ending with this report
+	Points              []TrendPoint `json:"points"`
+	ResourceChange      int          `json:"resource_change"`
+	CostChange          float64      `json:"cost_change"`
+	SecurityScoreChange int          `json:"security_score_change"`
+	// Note explains a trend that couldn't be computed or skipped snapshots
+	Note string `json:"note,omitempty"`
+}
+
+// trendPointOf returns the headline numbers of a report
+func trendPointOf(report *Report) TrendPoint {
+	return TrendPoint{
+		GeneratedAt:    report.GeneratedAt,
+		TotalResources: report.Executive.TotalResources,
+		MonthlyCost:    report.Executive.MonthlyCost,
+		SecurityScore:  report.Executive.SecurityScore,
+	}
+}
+
+// LoadReportHistory reads the report snapshots in dir, oldest first. A
+// missing directory is an empty history; snapshots that can't be read are
+// skipped and counted.
+func LoadReportHistory(dir string) ([]TrendPoint, int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var points []TrendPoint
+	skipped := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, historyFilePrefix) || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			skipped++
+			continue
+		}
+		var report Report
+		if err := json.Unmarshal(data, &report); err != nil || report.GeneratedAt.IsZero() {
+			skipped++
+			continue
+		}
+		points = append(points, trendPointOf(&report))
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].GeneratedAt.Before(points[j].GeneratedAt) })
+	return points, skipped, nil
+}
+
+// SaveReportSnapshot persists report to dir so later reports can trend
+// against it
+func SaveReportSnapshot(dir string, report *Report) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	name := historyFilePrefix + report.GeneratedAt.UTC().Format("20060102T150405Z") + ".json"
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// buildTrend appends report to history and compares it with the latest
+// earlier snapshot
+func buildTrend(history []TrendPoint, skipped int, report *Report) *TrendSection {
+	current := trendPointOf(report)
+	trend := &TrendSection{Points: append(append([]TrendPoint{}, history...), current)}
+
+	var notes []string
+	if len(history) == 0 {
+		notes = append(notes, "no earlier reports to compare with yet")
+	} else {
+		previous := history[len(history)-1]
+		trend.ResourceChange = current.TotalResources - previous.TotalResources
+		trend.CostChange = current.MonthlyCost - previous.MonthlyCost
+		trend.SecurityScoreChange = current.SecurityScore - previous.SecurityScore
+	}
+	if skipped > 0 {
+		notes = append(notes, fmt.Sprintf("%d unreadable snapshots skipped", skipped))
+	}
+	trend.Note = strings.Join(notes, "; ")
+	return trend
+}
+
+// trendCharts returns line charts of each headline number over time
+func trendCharts(trend *TrendSection) []ChartData {
+	dates := make([]string, len(trend.Points))
+	resources := make([]int, len(trend.Points))
+	costs := make([]float64, len(trend.Points))
+	scores := make([]int, len(trend.Points))
+	for i, point := range trend.Points {
+		dates[i] = point.GeneratedAt.Format("2006-01-02")
+		resources[i] = point.TotalResources
+		costs[i] = point.MonthlyCost
+		scores[i] = point.SecurityScore
+	}
+
+	line := func(title, yAxis string, values interface{}) ChartData {
+		return ChartData{
+			Type:   "line",
+			Title:  title,
+			Data:   map[string]interface{}{"labels": dates, "values": values},
+			Config: map[string]interface{}{"xAxis": "Date", "yAxis": yAxis},
+		}
+	}
+	return []ChartData{
+		line("Resource Count Over Time", "Resources", resources),
+		line("Monthly Cost Over Time", "Cost (USD)", costs),
+		line("Security Score Over Time", "Score", scores),
+	}
+}
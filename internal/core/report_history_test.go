@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func historicalReport(at time.Time, resources int, cost float64, score int) *Report {
+	return &Report{
+		GeneratedAt: at,
+		Executive:   ExecutiveSummary{TotalResources: resources, MonthlyCost: cost, SecurityScore: score},
+	}
+}
+
+func TestReportTrendReflectsHistory(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	// Written out of order; the trend sorts by generation time
+	for _, report := range []*Report{
+		historicalReport(start.AddDate(0, 2, 0), 14, 900, 70),
+		historicalReport(start, 10, 1000, 60),
+		historicalReport(start.AddDate(0, 1, 0), 12, 950, 65),
+	} {
+		if err := SaveReportSnapshot(dir, report); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report-broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &countingReportProvider{counts: map[string]int{"compute.instances": 12, "storage.buckets": 3}}
+	report, err := NewReporter(provider, quietLogger()).GenerateReport(context.Background(), ReportOptions{
+		Sections:      []string{"executive"},
+		IncludeCharts: true,
+		HistoryDir:    dir,
+	})
+	if err != nil {
+		t.Fatalf("GenerateReport returned error: %v", err)
+	}
+
+	trend := report.Trend
+	if trend == nil || len(trend.Points) != 4 {
+		t.Fatalf("expected three snapshots plus this report in the trend, got %+v", trend)
+	}
+	var resources []int
+	for _, point := range trend.Points {
+		resources = append(resources, point.TotalResources)
+	}
+	if !reflect.DeepEqual(resources, []int{10, 12, 14, 15}) {
+		t.Fatalf("expected the resource series oldest first, got %v", resources)
+	}
+	last := trend.Points[len(trend.Points)-1]
+	if trend.ResourceChange != 1 || trend.CostChange != last.MonthlyCost-900 || trend.SecurityScoreChange != last.SecurityScore-70 {
+		t.Fatalf("unexpected changes against the latest snapshot: %+v", trend)
+	}
+	if !strings.Contains(trend.Note, "1 unreadable snapshots skipped") {
+		t.Fatalf("expected the broken snapshot noted, got %q", trend.Note)
+	}
+
+	var chart *ChartData
+	for i := range report.Charts {
+		if report.Charts[i].Title == "Resource Count Over Time" {
+			chart = &report.Charts[i]
+		}
+	}
+	if chart == nil {
+		t.Fatal("expected a resource count trend chart")
+	}
+	if values := chart.Data.(map[string]interface{})["values"]; !reflect.DeepEqual(values, []int{10, 12, 14, 15}) {
+		t.Fatalf("unexpected chart series: %v", values)
+	}
+
+	// The report joins the history for next time
+	history, skipped, err := LoadReportHistory(dir)
+	if err != nil || len(history) != 4 || skipped != 1 {
+		t.Fatalf("expected the report saved to history, got %d snapshots, %d skipped, %v", len(history), skipped, err)
+	}
+}
+
+func TestReportTrendWithoutHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	report, err := NewReporter(&countingReportProvider{}, quietLogger()).GenerateReport(context.Background(), ReportOptions{
+		Sections:      []string{"executive"},
+		IncludeCharts: true,
+		HistoryDir:    dir,
+	})
+	if err != nil {
+		t.Fatalf("GenerateReport returned error: %v", err)
+	}
+	if report.Trend == nil || len(report.Trend.Points) != 1 || !strings.Contains(report.Trend.Note, "no earlier reports") {
+		t.Fatalf("expected a single-point trend with a note, got %+v", report.Trend)
+	}
+	for _, chart := range report.Charts {
+		if strings.HasSuffix(chart.Title, "Over Time") {
+			t.Fatalf("expected no trend chart from a single point, got %q", chart.Title)
+		}
+	}
+
+	markdown, err := NewReporter(&countingReportProvider{}, quietLogger()).ToMarkdown(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(markdown), "## Trend") || !strings.Contains(string(markdown), "no earlier reports") {
+		t.Fatalf("expected the trend note in markdown, got:\n%s", markdown)
+	}
+}
@@ -38,6 +38,9 @@ type ReportOptions struct {
 	StartDate     time.Time
 	EndDate       time.Time
 	Filters       map[string]interface{}
+	// HistoryDir, if set, holds earlier report snapshots to trend against;
+	// each report adds its own
+	HistoryDir string
 }
 
 type Report struct {
@@ -56,6 +59,7 @@ type Report struct {
 	Resources     []ResourceDetail       `json:"resources"`
 	Metrics       map[string]interface{} `json:"metrics"`
 	Charts        []ChartData            `json:"charts,omitempty"`
+	Trend         *TrendSection          `json:"trend,omitempty"`
 }
 
 type ReportPeriod struct {
@@ -234,6 +238,10 @@ type SecurityFinding struct {
 	Remediation  string    `json:"remediation"`
 	FirstDetected time.Time `json:"first_detected"`
 	LastSeen     time.Time `json:"last_seen"`
+	// Suppressed findings were accepted in a suppression file; they are
+	// reported but don't fail severity gates
+	Suppressed    bool      `json:"suppressed,omitempty"`
+	Justification string    `json:"justification,omitempty"`
 }
 
 type IAMInfo struct {
@@ -436,6 +444,10 @@ func (r *Reporter) GenerateReport(ctx context.Context, options ReportOptions) (*
 		}
 	}
 
+	if options.HistoryDir != "" {
+		r.generateTrend(report, options.HistoryDir)
+	}
+
 	if options.IncludeCharts && r.config.IncludeCharts {
 		r.generateCharts(report)
 	}
@@ -517,8 +529,16 @@ func (r *Reporter) generateExecutiveSummary(ctx context.Context, report *Report,
 	monthlyCost := totalCost * 30
 	annualCost := monthlyCost * 12
 
+	totalResources := len(resources)
+	if counts := r.countResources(ctx, report, options); counts != nil {
+		totalResources = 0
+		for _, count := range counts {
+			totalResources += count
+		}
+	}
+
 	report.Executive = ExecutiveSummary{
-		TotalResources:      len(resources),
+		TotalResources:      totalResources,
 		TotalCost:          totalCost,
 		MonthlyCost:        monthlyCost,
 		ProjectedAnnualCost: annualCost,
@@ -537,6 +557,28 @@ func (r *Reporter) generateExecutiveSummary(ctx context.Context, report *Report,
 	return nil
 }
 
+// countResources returns per-type counts when the provider can count without
+// listing, or nil when it can't. Counts are kept in the report's metrics so
+// every section shares one count.
+func (r *Reporter) countResources(ctx context.Context, report *Report, options ReportOptions) map[string]int {
+	if counts, ok := report.Metrics["resource_counts"].(map[string]int); ok {
+		return counts
+	}
+	counter, ok := r.provider.(ResourceCounter)
+	if !ok {
+		return nil
+	}
+	counts, err := counter.CountResources(ctx, options.Filters)
+	if err != nil {
+		r.logger.Warnf("Failed to count resources: %v", err)
+		return nil
+	}
+	if report.Metrics != nil {
+		report.Metrics["resource_counts"] = counts
+	}
+	return counts
+}
+
 func (r *Reporter) generateInfrastructureSummary(ctx context.Context, report *Report, options ReportOptions) error {
 	// ListResources method not available on Provider interface
 	// resources, err := r.provider.ListResources(ctx, "", options.Filters)
@@ -554,6 +596,9 @@ func (r *Reporter) generateInfrastructureSummary(ctx context.Context, report *Re
 		resourcesByRegion[resource.Region]++
 		resourcesByStatus[resource.Status]++
 	}
+	if counts := r.countResources(ctx, report, options); counts != nil {
+		resourcesByType = counts
+	}
 
 	report.Infrastructure = InfrastructureSummary{
 		ResourcesByType:   resourcesByType,
@@ -797,9 +842,30 @@ func (r *Reporter) generateCharts(report *Report) {
 		},
 	}
 
+	if report.Trend != nil && len(report.Trend.Points) > 1 {
+		charts = append(charts, trendCharts(report.Trend)...)
+	}
+
 	report.Charts = charts
 }
 
+// generateTrend compares the report with the snapshots in historyDir and
+// then adds it to them. History problems never fail the report.
+func (r *Reporter) generateTrend(report *Report, historyDir string) {
+	history, skipped, err := LoadReportHistory(historyDir)
+	if err != nil {
+		r.logger.Warnf("Failed to load report history: %v", err)
+	}
+	report.Trend = buildTrend(history, skipped, report)
+
+	snapshot := *report
+	snapshot.Trend = nil
+	snapshot.Charts = nil
+	if err := SaveReportSnapshot(historyDir, &snapshot); err != nil {
+		r.logger.Warnf("Failed to save report to history: %v", err)
+	}
+}
+
 func (r *Reporter) generateRecommendations(report *Report) {
 	recommendations := []Recommendation{}
 
@@ -934,6 +1000,31 @@ func (r *Reporter) ToHTML(report *Report) ([]byte, error) {
         </table>
         {{end}}
 
+        {{if .Trend}}
+        <h2>Trend</h2>
+        {{if .Trend.Note}}<p>{{.Trend.Note}}</p>{{end}}
+        <table>
+            <thead>
+                <tr>
+                    <th>Date</th>
+                    <th>Resources</th>
+                    <th>Monthly Cost</th>
+                    <th>Security Score</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range .Trend.Points}}
+                <tr>
+                    <td>{{.GeneratedAt.Format "2006-01-02 15:04"}}</td>
+                    <td>{{.TotalResources}}</td>
+                    <td>${{printf "%.2f" .MonthlyCost}}</td>
+                    <td>{{.SecurityScore}}</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+        {{end}}
+
         {{if .Recommendations}}
         <h2>Recommendations</h2>
         <table>
@@ -1008,6 +1099,24 @@ func (r *Reporter) ToMarkdown(report *Report) ([]byte, error) {
 		buf.WriteString("\n")
 	}
 
+	if report.Trend != nil {
+		buf.WriteString("## Trend\n\n")
+		if len(report.Trend.Points) > 1 {
+			buf.WriteString(fmt.Sprintf("- **Resources:** %+d\n", report.Trend.ResourceChange))
+			buf.WriteString(fmt.Sprintf("- **Monthly Cost:** %+.2f\n", report.Trend.CostChange))
+			buf.WriteString(fmt.Sprintf("- **Security Score:** %+d\n\n", report.Trend.SecurityScoreChange))
+			buf.WriteString("| Date | Resources | Monthly Cost | Security Score |\n|---|---|---|---|\n")
+			for _, point := range report.Trend.Points {
+				buf.WriteString(fmt.Sprintf("| %s | %d | $%.2f | %d |\n", point.GeneratedAt.Format("2006-01-02 15:04"),
+					point.TotalResources, point.MonthlyCost, point.SecurityScore))
+			}
+			buf.WriteString("\n")
+		}
+		if report.Trend.Note != "" {
+			buf.WriteString(fmt.Sprintf("_%s_\n\n", report.Trend.Note))
+		}
+	}
+
 	if len(report.Recommendations) > 0 {
 		buf.WriteString("## Recommendations\n\n")
 		for _, rec := range report.Recommendations {
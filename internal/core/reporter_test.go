@@ -0,0 +1,44 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// countingReportProvider counts resources without listing them
+type countingReportProvider struct {
+	throttlingProvider
+	counts     map[string]int
+	countCalls int
+}
+
+func (p *countingReportProvider) CountResources(ctx context.Context, filters map[string]interface{}) (map[string]int, error) {
+	p.countCalls++
+	return p.counts, nil
+}
+
+func TestReportSummariesUseResourceCounts(t *testing.T) {
+	provider := &countingReportProvider{counts: map[string]int{"compute.instances": 12, "storage.buckets": 3}}
+	reporter := NewReporter(provider, quietLogger())
+
+	report, err := reporter.GenerateReport(context.Background(), ReportOptions{
+		Project:  "demo",
+		Sections: []string{"executive", "infrastructure"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateReport returned error: %v", err)
+	}
+
+	if report.Executive.TotalResources != 15 {
+		t.Fatalf("expected 15 resources in the executive summary, got %d", report.Executive.TotalResources)
+	}
+	if got := report.Infrastructure.ResourcesByType; got["compute.instances"] != 12 || got["storage.buckets"] != 3 {
+		t.Fatalf("unexpected resources by type: %v", got)
+	}
+	if provider.countCalls != 1 {
+		t.Fatalf("expected sections to share one count, got %d calls", provider.countCalls)
+	}
+	if provider.calls != 0 {
+		t.Fatalf("expected no listing calls, got %d", provider.calls)
+	}
+}
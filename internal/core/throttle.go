@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// ResourceLister is implemented by providers that can list a single resource
+// type; the discoverer throttles and retries each call
+type ResourceLister interface {
+	ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]Resource, error)
+}
+
+// DiscoveryProgress is a snapshot of a running discovery
+type DiscoveryProgress struct {
+	ResourcesScanned int
+	TypesCompleted   int
+	TypesTotal       int
+	CurrentType      string
+	Concurrency      int
+	Throttled        int
+}
+
+// ProgressFunc receives progress snapshots; it must not block
+type ProgressFunc func(DiscoveryProgress)
+
+// adaptiveLimiter caps in-flight API calls. The cap halves whenever a call is
+// throttled (429/503) and grows back by one after a full window of successful
+// calls, up to the configured maximum (AIMD).
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+	throttled int
+	changed   chan struct{}
+}
+
+func newAdaptiveLimiter(max int) *adaptiveLimiter {
+	if max <= 0 {
+		max = 1
+	}
+	return &adaptiveLimiter{limit: max, max: max, changed: make(chan struct{})}
+}
+
+// Acquire blocks until a call slot is free or ctx is done
+func (l *adaptiveLimiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inFlight < l.limit {
+			l.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees a slot and adapts the limit to the call's outcome
+func (l *adaptiveLimiter) Release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if throttled {
+		l.throttled++
+		l.successes = 0
+		if l.limit > 1 {
+			l.limit /= 2
+		}
+	} else if l.limit < l.max {
+		l.successes++
+		if l.successes >= l.limit {
+			l.limit++
+			l.successes = 0
+		}
+	}
+
+	// Wake every waiter so they re-check against the new limit
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Stats returns the current limit and the number of throttled calls so far
+func (l *adaptiveLimiter) Stats() (limit, throttled int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.throttled
+}
+
+// typeLimiter caps in-flight calls per resource type so one slow type, such
+// as compute scanned zone by zone, can't take every global slot
+type typeLimiter struct {
+	mu       sync.Mutex
+	limits   map[string]int
+	fallback int
+	slots    map[string]chan struct{}
+}
+
+func newTypeLimiter(limits map[string]int, fallback int) *typeLimiter {
+	return &typeLimiter{limits: limits, fallback: fallback, slots: make(map[string]chan struct{})}
+}
+
+// slot returns the semaphore for resourceType, or nil when it is unlimited
+func (l *typeLimiter) slot(resourceType string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slot, ok := l.slots[resourceType]; ok {
+		return slot
+	}
+	limit, ok := l.limits[resourceType]
+	if !ok {
+		limit = l.fallback
+	}
+	var slot chan struct{}
+	if limit > 0 {
+		slot = make(chan struct{}, limit)
+	}
+	l.slots[resourceType] = slot
+	return slot
+}
+
+// Acquire blocks until resourceType has a free slot or ctx is done
+func (l *typeLimiter) Acquire(ctx context.Context, resourceType string) error {
+	slot := l.slot(resourceType)
+	if slot == nil {
+		return nil
+	}
+	select {
+	case slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *typeLimiter) Release(resourceType string) {
+	if slot := l.slot(resourceType); slot != nil {
+		<-slot
+	}
+}
+
+// newRateLimiter returns a QPS limiter shared by all workers
+func newRateLimiter(qps int) *rate.Limiter {
+	if qps <= 0 {
+		return rate.NewLimiter(rate.Inf, 1)
+	}
+	return rate.NewLimiter(rate.Limit(qps), 1)
+}
+
+// isThrottleError reports whether err means the API is shedding load
+func isThrottleError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{"429", "503", "too many requests", "rate limit", "ratelimitexceeded", "quota exceeded", "service unavailable"} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// throttlingProvider rejects the first calls for every resource type with a
+// 429 and tracks how many calls are in flight at once
+type throttlingProvider struct {
+	mu             sync.Mutex
+	failuresByType map[string]int
+	failFirst      int
+	inFlight       int
+	maxInFlight    int
+	calls          int
+	callTimes      []time.Time
+}
+
+func (p *throttlingProvider) Name() string { return "fake" }
+func (p *throttlingProvider) DiscoverAccounts(ctx context.Context) ([]Account, error) {
+	return nil, nil
+}
+func (p *throttlingProvider) DiscoverResources(ctx context.Context, account Account) ([]Resource, error) {
+	return nil, nil
+}
+func (p *throttlingProvider) ValidateConfig() error  { return nil }
+func (p *throttlingProvider) GetConfig() interface{} { return nil }
+
+func (p *throttlingProvider) ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]Resource, error) {
+	p.mu.Lock()
+	p.calls++
+	p.callTimes = append(p.callTimes, time.Now())
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	throttle := p.failuresByType[resourceType] < p.failFirst
+	if throttle {
+		p.failuresByType[resourceType]++
+	}
+	p.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+
+	if throttle {
+		return nil, errors.New("googleapi: Error 429: Quota exceeded for quota metric 'Read requests'")
+	}
+	return []Resource{
+		{ID: resourceType + "/a", Type: resourceType, Status: "running"},
+		{ID: resourceType + "/b", Type: resourceType, Status: "running"},
+	}, nil
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestDiscoverThrottlesOn429AndCompletes(t *testing.T) {
+	provider := &throttlingProvider{failuresByType: make(map[string]int), failFirst: 2}
+
+	var mu sync.Mutex
+	var snapshots []DiscoveryProgress
+	d := NewDiscoverer(provider, quietLogger(), DiscoveryOptions{
+		MaxWorkers:    8,
+		RetryAttempts: 3,
+		RetryDelay:    time.Millisecond,
+		RateLimit:     10000,
+		Progress: func(p DiscoveryProgress) {
+			mu.Lock()
+			snapshots = append(snapshots, p)
+			mu.Unlock()
+		},
+	})
+
+	results, err := d.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+
+	types := len(d.getResourceTypes())
+	if len(results.Errors) != 0 {
+		t.Fatalf("expected every type to succeed after retries, got %d errors: %v", len(results.Errors), results.Errors[0].Error)
+	}
+	if results.Summary.TotalResources != 2*types {
+		t.Fatalf("expected %d resources, got %d", 2*types, results.Summary.TotalResources)
+	}
+	if provider.calls != 3*types {
+		t.Fatalf("expected %d calls (two throttled per type), got %d", 3*types, provider.calls)
+	}
+	if provider.maxInFlight > 8 {
+		t.Fatalf("in-flight calls exceeded MaxWorkers: %d", provider.maxInFlight)
+	}
+	if got := results.Metadata["throttled_requests"]; got != 2*types {
+		t.Fatalf("expected %d throttled requests recorded, got %v", 2*types, got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	minConcurrency := 8
+	for _, p := range snapshots {
+		if p.Concurrency < minConcurrency {
+			minConcurrency = p.Concurrency
+		}
+	}
+	if minConcurrency >= 8 {
+		t.Fatalf("expected concurrency to back off below 8 while throttled, min observed %d", minConcurrency)
+	}
+	last := snapshots[len(snapshots)-1]
+	if last.TypesCompleted != types || last.TypesTotal != types || last.ResourcesScanned != 2*types {
+		t.Fatalf("unexpected final progress: %+v", last)
+	}
+}
+
+func TestAdaptiveLimiterBacksOffAndRecovers(t *testing.T) {
+	l := newAdaptiveLimiter(8)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := l.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		l.Release(true)
+	}
+	if limit, throttled := l.Stats(); limit != 2 || throttled != 2 {
+		t.Fatalf("expected limit 2 after two throttles, got limit %d throttled %d", limit, throttled)
+	}
+
+	// A window of successes grows the limit by one at a time
+	for i := 0; i < 2; i++ {
+		_ = l.Acquire(ctx)
+		l.Release(false)
+	}
+	if limit, _ := l.Stats(); limit != 3 {
+		t.Fatalf("expected limit 3 after a window of successes, got %d", limit)
+	}
+
+	// Waiters block at the limit and respect cancellation
+	for i := 0; i < 3; i++ {
+		_ = l.Acquire(ctx)
+	}
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(cancelled); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Acquire to block until the deadline, got %v", err)
+	}
+}
+
+func TestDiscoverHonorsQPS(t *testing.T) {
+	provider := &throttlingProvider{failuresByType: make(map[string]int)}
+	d := NewDiscoverer(provider, quietLogger(), DiscoveryOptions{
+		MaxWorkers:    10,
+		ResourceTypes: "compute.instances",
+		RateLimit:     50,
+	})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := d.listResources(context.Background(), "compute.instances", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Burst of one, then one call every 20ms
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected QPS limit to space out calls, 6 calls took %v", elapsed)
+	}
+}
+
+func TestIsThrottleError(t *testing.T) {
+	cases := map[string]bool{
+		"googleapi: Error 429: rateLimitExceeded": true,
+		"googleapi: Error 503: backendError":      true,
+		"Quota exceeded for quota metric":         true,
+		"googleapi: Error 404: notFound":          false,
+	}
+	for msg, want := range cases {
+		if got := isThrottleError(errors.New(msg)); got != want {
+			t.Errorf("isThrottleError(%q) = %v, want %v", msg, got, want)
+		}
+	}
+	if isThrottleError(context.Canceled) {
+		t.Error("context cancellation is not throttling")
+	}
+}
+
+// latencyProvider sleeps a per-type latency on every call and tracks
+// in-flight calls per type and overall
+type latencyProvider struct {
+	throttlingProvider
+	latency       map[string]time.Duration
+	inFlightType  map[string]int
+	maxInFlightBy map[string]int
+	zones         map[string][]string
+}
+
+func (p *latencyProvider) ListResources(ctx context.Context, resourceType string, filters map[string]interface{}) ([]Resource, error) {
+	p.mu.Lock()
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.inFlightType[resourceType]++
+	if p.inFlightType[resourceType] > p.maxInFlightBy[resourceType] {
+		p.maxInFlightBy[resourceType] = p.inFlightType[resourceType]
+	}
+	zone, _ := filters["zone"].(string)
+	p.zones[resourceType] = append(p.zones[resourceType], zone)
+	p.mu.Unlock()
+
+	latency, ok := p.latency[resourceType]
+	if !ok {
+		latency = time.Millisecond
+	}
+	time.Sleep(latency)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.inFlightType[resourceType]--
+	p.mu.Unlock()
+
+	return []Resource{{ID: resourceType + "/" + zone, Type: resourceType, Zone: zone, Status: "running"}}, nil
+}
+
+func TestDiscoverRespectsPerTypeAndGlobalLimits(t *testing.T) {
+	provider := &latencyProvider{
+		latency:       map[string]time.Duration{"compute.instances": 10 * time.Millisecond, "compute.disks": 5 * time.Millisecond},
+		inFlightType:  make(map[string]int),
+		maxInFlightBy: make(map[string]int),
+		zones:         make(map[string][]string),
+	}
+	zones := []string{"us-central1-a", "us-central1-b", "us-central1-c", "us-central1-f", "us-east1-b", "us-east1-c"}
+	d := NewDiscoverer(provider, quietLogger(), DiscoveryOptions{
+		MaxWorkers:       4,
+		RateLimit:        10000,
+		Zones:            zones,
+		TypeLimits:       map[string]int{"compute.instances": 2},
+		DefaultTypeLimit: 1,
+	})
+
+	results, err := d.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+
+	if provider.maxInFlight > 4 {
+		t.Fatalf("in-flight calls exceeded the global limit of 4: %d", provider.maxInFlight)
+	}
+	if got := provider.maxInFlightBy["compute.instances"]; got != 2 {
+		t.Fatalf("expected compute.instances to run at its limit of 2, got %d", got)
+	}
+	for resourceType, max := range provider.maxInFlightBy {
+		if resourceType != "compute.instances" && max > 1 {
+			t.Fatalf("%s exceeded the default per-type limit of 1: %d", resourceType, max)
+		}
+	}
+
+	// Zonal types are listed once per zone, other types once
+	if got := len(provider.zones["compute.instances"]); got != len(zones) {
+		t.Fatalf("expected compute.instances listed in %d zones, got %d", len(zones), got)
+	}
+	if got := provider.zones["storage.buckets"]; len(got) != 1 || got[0] != "" {
+		t.Fatalf("expected storage.buckets listed once without a zone, got %v", got)
+	}
+	if results.Summary.ResourcesByType["compute.disks"] != len(zones) {
+		t.Fatalf("expected one disk per zone, got %d", results.Summary.ResourcesByType["compute.disks"])
+	}
+
+	timing := results.TypeTimings["compute.instances"]
+	if timing.Calls != len(zones) {
+		t.Fatalf("expected %d compute.instances calls timed, got %d", len(zones), timing.Calls)
+	}
+	// Six 10ms calls two at a time take at least three rounds
+	if timing.Duration < 30*time.Millisecond {
+		t.Fatalf("expected compute.instances to take at least 30ms at a limit of 2, took %v", timing.Duration)
+	}
+	if len(results.TypeTimings) != len(d.getResourceTypes()) {
+		t.Fatalf("expected a timing for every type, got %d", len(results.TypeTimings))
+	}
+}
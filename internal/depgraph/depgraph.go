@@ -0,0 +1,78 @@
+// Package depgraph orders nodes with dependencies, such as the resources of
+// a deployment or the operations of an API batch, into batches whose members
+// can run in parallel.
+package depgraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CycleError reports the nodes that can't be ordered because their
+// dependencies form a cycle or depend on one
+type CycleError struct {
+	Nodes []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle among %s", strings.Join(e.Nodes, ", "))
+}
+
+// Batches orders graph, which maps each node to the nodes it depends on, into
+// batches where every node's dependencies are in earlier batches. Dependencies
+// that aren't nodes of graph are treated as already satisfied. Each batch is
+// sorted so the order is stable.
+func Batches(graph map[string][]string) ([][]string, error) {
+	remaining := make(map[string]int, len(graph))
+	dependents := make(map[string][]string)
+	for node, deps := range graph {
+		remaining[node] = 0
+		seen := make(map[string]bool)
+		for _, dep := range deps {
+			if _, ok := graph[dep]; !ok || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			remaining[node]++
+			dependents[dep] = append(dependents[dep], node)
+		}
+	}
+
+	var batches [][]string
+	var ready []string
+	for node, count := range remaining {
+		if count == 0 {
+			ready = append(ready, node)
+		}
+	}
+	done := 0
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		batches = append(batches, ready)
+		done += len(ready)
+
+		var next []string
+		for _, node := range ready {
+			for _, dependent := range dependents[node] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ready = next
+	}
+
+	if done < len(graph) {
+		var cycle []string
+		for node, count := range remaining {
+			if count > 0 {
+				cycle = append(cycle, node)
+			}
+		}
+		sort.Strings(cycle)
+		return batches, &CycleError{Nodes: cycle}
+	}
+	return batches, nil
+}
@@ -0,0 +1,61 @@
+package depgraph
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBatchesOrdersDependenciesFirst(t *testing.T) {
+	graph := map[string][]string{
+		"compute.web":    {"network.vpc", "storage.assets"},
+		"storage.assets": nil,
+		"network.vpc":    {},
+		"network.subnet": {"network.vpc", "external.thing"},
+		"dns.record":     {"compute.web", "compute.web"},
+	}
+
+	batches, err := Batches(graph)
+	if err != nil {
+		t.Fatalf("Batches: %v", err)
+	}
+
+	want := [][]string{
+		{"network.vpc", "storage.assets"},
+		{"compute.web", "network.subnet"},
+		{"dns.record"},
+	}
+	if !reflect.DeepEqual(batches, want) {
+		t.Fatalf("batches = %v, want %v", batches, want)
+	}
+}
+
+func TestBatchesReportsCycles(t *testing.T) {
+	graph := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": nil,
+		"e": {"a"},
+	}
+
+	batches, err := Batches(graph)
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected a CycleError, got %v", err)
+	}
+	// e only waits on the cycle, so it can't run either
+	if !reflect.DeepEqual(cycle.Nodes, []string{"a", "b", "c", "e"}) {
+		t.Fatalf("cycle nodes = %v", cycle.Nodes)
+	}
+	if !reflect.DeepEqual(batches, [][]string{{"d"}}) {
+		t.Fatalf("batches before the cycle = %v", batches)
+	}
+}
+
+func TestBatchesEmpty(t *testing.T) {
+	batches, err := Batches(nil)
+	if err != nil || len(batches) != 0 {
+		t.Fatalf("Batches(nil) = %v, %v", batches, err)
+	}
+}
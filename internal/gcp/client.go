@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -134,8 +135,14 @@ type ClientConfig struct {
 
 // Validate validates the client configuration
 func (c *ClientConfig) Validate() error {
-	if c.ProjectID == "" {
-		return fmt.Errorf("project ID is required")
+	if err := validateProjectID(c.ProjectID); err != nil {
+		return err
+	}
+	if c.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if c.Zone == "" {
+		return fmt.Errorf("zone is required")
 	}
 	if c.MaxRetries < 0 {
 		return fmt.Errorf("max retries cannot be negative")
@@ -149,6 +156,24 @@ func (c *ClientConfig) Validate() error {
 	return nil
 }
 
+// projectIDPattern matches GCP project IDs: a lowercase letter followed by
+// lowercase letters, digits and hyphens, not ending in a hyphen
+var projectIDPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*[a-z0-9]$`)
+
+// validateProjectID checks that id is a well-formed GCP project ID
+func validateProjectID(id string) error {
+	if id == "" {
+		return fmt.Errorf("project ID is required")
+	}
+	if len(id) < 6 || len(id) > 30 {
+		return fmt.Errorf("project ID %q must be between 6 and 30 characters", id)
+	}
+	if !projectIDPattern.MatchString(id) {
+		return fmt.Errorf("project ID %q must start with a lowercase letter and contain only lowercase letters, numbers and hyphens", id)
+	}
+	return nil
+}
+
 // SetDefaults sets default values for unspecified configuration fields
 func (c *ClientConfig) SetDefaults() {
 	if c.Region == "" {
@@ -314,6 +339,10 @@ type ClientOption func(*Client) error
 
 // NewClient creates a new GCP client with the specified configuration
 func NewClient(ctx context.Context, config *ClientConfig, opts ...ClientOption) (*Client, error) {
+	if err := validateProjectID(config.ProjectID); err != nil {
+		return nil, err
+	}
+
 	client := &Client{
 		projectID:    config.ProjectID,
 		region:       config.Region,
@@ -8,7 +8,6 @@ import (
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/option"
 )
 
 func TestNewClient(t *testing.T) {
@@ -37,9 +36,10 @@ func TestNewClient(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			config := &ClientConfig{
-				ProjectID: tt.projectID,
-				Region:    "us-central1",
-				Zone:      "us-central1-a",
+				ProjectID:   tt.projectID,
+				Region:      "us-central1",
+				Zone:        "us-central1-a",
+				DisableAuth: true,
 			}
 
 			client, err := NewClient(context.Background(), config)
@@ -788,17 +788,16 @@ func (cs *ComputeService) CreateInstance(ctx context.Context, config *InstanceCo
 		cs.metrics.mu.Lock()
 		cs.metrics.ErrorCounts["instance_create"]++
 		cs.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to create instance: %w", err)
+		return nil, NewGCPError("CreateInstance", config.Name, fmt.Errorf("failed to create instance: %w", err))
 	}
 
 	// Wait for operation to complete
-	// op.Name is a pointer to string
-	if op.Name != nil {
+	if op.Name() != "" {
 		if err := cs.waitForZoneOperation(ctx, config.Zone, op.Name()); err != nil {
 			return nil, fmt.Errorf("instance creation operation failed: %w", err)
 		}
 	} else {
-		return nil, fmt.Errorf("operation name is nil")
+		return nil, fmt.Errorf("operation name is empty")
 	}
 
 	// Get the created instance
@@ -810,7 +809,7 @@ func (cs *ComputeService) CreateInstance(ctx context.Context, config *InstanceCo
 
 	createdInstance, err := cs.instancesClient.Get(ctx, getReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get created instance: %w", err)
+		return nil, NewGCPError("CreateInstance", config.Name, fmt.Errorf("failed to get created instance: %w", err))
 	}
 
 	// Update cache
@@ -865,7 +864,7 @@ func (cs *ComputeService) GetInstance(ctx context.Context, zone, name string) (*
 		cs.metrics.mu.Lock()
 		cs.metrics.ErrorCounts["instance_get"]++
 		cs.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to get instance: %w", err)
+		return nil, NewGCPError("GetInstance", name, fmt.Errorf("failed to get instance: %w", err))
 	}
 
 	// Update cache
@@ -905,7 +904,7 @@ func (cs *ComputeService) ListInstances(ctx context.Context, zone string, filter
 				cs.metrics.mu.Lock()
 				cs.metrics.ErrorCounts["instance_list"]++
 				cs.metrics.mu.Unlock()
-				return nil, fmt.Errorf("failed to list instances: %w", err)
+				return nil, NewGCPError("ListInstances", zone, fmt.Errorf("failed to list instances: %w", err))
 			}
 			instances = append(instances, instance)
 		}
@@ -926,7 +925,7 @@ func (cs *ComputeService) ListInstances(ctx context.Context, zone string, filter
 				cs.metrics.mu.Lock()
 				cs.metrics.ErrorCounts["instance_list"]++
 				cs.metrics.mu.Unlock()
-				return nil, fmt.Errorf("failed to list instances: %w", err)
+				return nil, NewGCPError("ListInstances", zone, fmt.Errorf("failed to list instances: %w", err))
 			}
 			if pair.Value.Instances != nil {
 				instances = append(instances, pair.Value.Instances...)
@@ -941,6 +940,51 @@ func (cs *ComputeService) ListInstances(ctx context.Context, zone string, filter
 	return instances, nil
 }
 
+// SetInstanceLabels replaces the labels of an instance. fingerprint is the
+// instance's label fingerprint as last read; compute rejects the change with
+// 412 once the labels have changed since, so concurrent writers can't
+// overwrite each other.
+func (cs *ComputeService) SetInstanceLabels(ctx context.Context, zone, name string, labels map[string]string, fingerprint string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	// Apply rate limiting
+	<-cs.rateLimiter.writeLimiter.C
+
+	req := &computepb.SetLabelsInstanceRequest{
+		Project:  cs.client.projectID,
+		Zone:     zone,
+		Instance: name,
+		InstancesSetLabelsRequestResource: &computepb.InstancesSetLabelsRequest{
+			Labels:           labels,
+			LabelFingerprint: &fingerprint,
+		},
+	}
+
+	op, err := cs.instancesClient.SetLabels(ctx, req)
+	if err != nil {
+		cs.metrics.mu.Lock()
+		cs.metrics.ErrorCounts["instance_set_labels"]++
+		cs.metrics.mu.Unlock()
+		return NewGCPError("SetInstanceLabels", name, fmt.Errorf("failed to set instance labels: %w", err))
+	}
+	if op.Name() == "" {
+		return fmt.Errorf("operation name is empty")
+	}
+	if err := cs.waitForZoneOperation(ctx, zone, op.Name()); err != nil {
+		return fmt.Errorf("instance set labels operation failed: %w", err)
+	}
+
+	// The cached instance carries the old fingerprint
+	cacheKey := fmt.Sprintf("%s/%s/%s", cs.client.projectID, zone, name)
+	cs.cache.mu.Lock()
+	delete(cs.cache.instances, cacheKey)
+	delete(cs.cache.lastUpdate, cacheKey)
+	cs.cache.mu.Unlock()
+
+	return nil
+}
+
 // DeleteInstance deletes an instance
 func (cs *ComputeService) DeleteInstance(ctx context.Context, zone, name string) error {
 	cs.mu.Lock()
@@ -965,13 +1009,12 @@ func (cs *ComputeService) DeleteInstance(ctx context.Context, zone, name string)
 		cs.metrics.mu.Lock()
 		cs.metrics.ErrorCounts["instance_delete"]++
 		cs.metrics.mu.Unlock()
-		return fmt.Errorf("failed to delete instance: %w", err)
+		return NewGCPError("DeleteInstance", name, fmt.Errorf("failed to delete instance: %w", err))
 	}
 
 	// Wait for operation to complete
-	// op.Name is a pointer to string
-	if op.Name == nil {
-		return fmt.Errorf("operation name is nil")
+	if op.Name() == "" {
+		return fmt.Errorf("operation name is empty")
 	}
 	if err := cs.waitForZoneOperation(ctx, zone, op.Name()); err != nil {
 		return fmt.Errorf("instance deletion operation failed: %w", err)
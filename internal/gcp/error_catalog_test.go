@@ -0,0 +1,87 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+func TestErrorCatalogCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   ErrorCode
+		status int
+	}{
+		{"not found", &googleapi.Error{Code: 404}, ErrorCodeNotFound, http.StatusNotFound},
+		{"too many requests", &googleapi.Error{Code: 429}, ErrorCodeRateLimited, http.StatusTooManyRequests},
+		{"rate limit reason", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, ErrorCodeRateLimited, http.StatusTooManyRequests},
+		{"quota reason", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, ErrorCodeQuotaExceeded, http.StatusTooManyRequests},
+		{"permission denied", &googleapi.Error{Code: 403, Message: "Access denied"}, ErrorCodePermissionDenied, http.StatusForbidden},
+		{"wrapped", fmt.Errorf("failed to get bucket: %w", &googleapi.Error{Code: 404}), ErrorCodeNotFound, http.StatusNotFound},
+		{"storage sentinel", storage.ErrObjectNotExist, ErrorCodeNotFound, http.StatusNotFound},
+		{"validation", NewValidationError("name", "must not be empty"), ErrorCodeValidationFailed, http.StatusBadRequest},
+		{"plain", errors.New("boom"), ErrorCodeInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gcpErr := AsError(tt.err)
+			if gcpErr.Code != string(tt.want) {
+				t.Errorf("AsError().Code = %v, want %v", gcpErr.Code, tt.want)
+			}
+			if got := gcpErr.HTTPStatus(); got != tt.status {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestStorageServiceReturnsCatalogErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorCode
+	}{
+		{http.StatusNotFound, ErrorCodeNotFound},
+		{http.StatusTooManyRequests, ErrorCodeRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"error": {"code": %d, "message": "%s"}}`, tt.status, http.StatusText(tt.status))
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			ss, err := NewStorageService(ctx, "test-project", option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatalf("NewStorageService() error = %v", err)
+			}
+			defer ss.Close()
+			ss.client.SetRetry(storage.WithPolicy(storage.RetryNever))
+
+			_, err = ss.GetBucket(ctx, "missing-bucket")
+			var gcpErr *Error
+			if !errors.As(err, &gcpErr) {
+				t.Fatalf("GetBucket() error = %v, want a catalog error", err)
+			}
+			if gcpErr.Code != string(tt.want) {
+				t.Errorf("GetBucket() code = %v, want %v", gcpErr.Code, tt.want)
+			}
+			if gcpErr.Operation != "GetBucket" || gcpErr.Resource != "missing-bucket" {
+				t.Errorf("GetBucket() error context = %s %s", gcpErr.Operation, gcpErr.Resource)
+			}
+		})
+	}
+}
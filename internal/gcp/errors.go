@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"cloud.google.com/go/storage"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -116,6 +117,11 @@ const (
 	ErrorCodePreconditionFailed  ErrorCode = "PRECONDITION_FAILED"
 	ErrorCodeBadRequest          ErrorCode = "BAD_REQUEST"
 	ErrorCodeUnknown             ErrorCode = "UNKNOWN"
+
+	// Catalog codes that API and CLI consumers can branch on
+	ErrorCodeQuotaExceeded    ErrorCode = "QUOTA_EXCEEDED"
+	ErrorCodeRateLimited      ErrorCode = "RATE_LIMITED"
+	ErrorCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
 )
 
 // ErrorHandler handles and categorizes GCP errors
@@ -176,9 +182,11 @@ func (e *Error) GetCategory() ErrorCategory {
 		return ErrorCategoryAuthentication
 	case string(ErrorCodePermissionDenied):
 		return ErrorCategoryAuthorization
-	case string(ErrorCodeResourceExhausted), string(ErrorCodeTooManyRequests):
+	case string(ErrorCodeResourceExhausted), string(ErrorCodeTooManyRequests), string(ErrorCodeRateLimited):
 		return ErrorCategoryRateLimit
-	case string(ErrorCodeInvalidArgument), string(ErrorCodeOutOfRange), string(ErrorCodeBadRequest):
+	case string(ErrorCodeQuotaExceeded):
+		return ErrorCategoryQuota
+	case string(ErrorCodeInvalidArgument), string(ErrorCodeOutOfRange), string(ErrorCodeBadRequest), string(ErrorCodeValidationFailed):
 		return ErrorCategoryValidation
 	case string(ErrorCodeNotFound), string(ErrorCodeAlreadyExists), string(ErrorCodeConflict):
 		return ErrorCategoryResource
@@ -510,7 +518,9 @@ func (h *ErrorHandler) categoryToCode(category ErrorCategory) ErrorCode {
 	case ErrorCategoryAuthorization:
 		return ErrorCodePermissionDenied
 	case ErrorCategoryRateLimit:
-		return ErrorCodeResourceExhausted
+		return ErrorCodeRateLimited
+	case ErrorCategoryQuota:
+		return ErrorCodeQuotaExceeded
 	case ErrorCategoryValidation:
 		return ErrorCodeInvalidArgument
 	case ErrorCategoryResource:
@@ -526,6 +536,11 @@ func (h *ErrorHandler) categoryToCode(category ErrorCategory) ErrorCode {
 
 // statusToCode converts HTTP status to error code
 func (h *ErrorHandler) statusToCode(status int) ErrorCode {
+	return CodeForStatus(status)
+}
+
+// CodeForStatus converts an HTTP status to its catalog error code
+func CodeForStatus(status int) ErrorCode {
 	switch status {
 	case http.StatusBadRequest:
 		return ErrorCodeBadRequest
@@ -540,7 +555,7 @@ func (h *ErrorHandler) statusToCode(status int) ErrorCode {
 	case http.StatusPreconditionFailed:
 		return ErrorCodePreconditionFailed
 	case http.StatusTooManyRequests:
-		return ErrorCodeTooManyRequests
+		return ErrorCodeRateLimited
 	case http.StatusInternalServerError:
 		return ErrorCodeInternal
 	case http.StatusServiceUnavailable:
@@ -737,7 +752,8 @@ func WrapError(err error, code ErrorCode, message string) *Error {
 func isRetryableCode(code ErrorCode) bool {
 	switch code {
 	case ErrorCodeUnavailable, ErrorCodeAborted, ErrorCodeDeadlineExceeded,
-	     ErrorCodeResourceExhausted, ErrorCodeTooManyRequests:
+	     ErrorCodeResourceExhausted, ErrorCodeTooManyRequests,
+	     ErrorCodeRateLimited:
 		return true
 	default:
 		return false
@@ -750,6 +766,11 @@ func classifyError(err error) ErrorCode {
 		return ErrorCodeInternal
 	}
 
+	// The storage client reports missing buckets and objects as sentinels
+	if errors.Is(err, storage.ErrBucketNotExist) || errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrorCodeNotFound
+	}
+
 	// Check for Google API errors
 	var apiErr *googleapi.Error
 	if errors.As(err, &apiErr) {
@@ -759,13 +780,21 @@ func classifyError(err error) ErrorCode {
 		case 401:
 			return ErrorCodeUnauthenticated
 		case 403:
+			if code := limitCode(apiErr); code != "" {
+				return code
+			}
 			return ErrorCodePermissionDenied
 		case 404:
 			return ErrorCodeNotFound
 		case 409:
 			return ErrorCodeAlreadyExists
+		case 412:
+			return ErrorCodePreconditionFailed
 		case 429:
-			return ErrorCodeResourceExhausted
+			if code := limitCode(apiErr); code != "" {
+				return code
+			}
+			return ErrorCodeRateLimited
 		case 499:
 			return ErrorCodeCancelled
 		case 500:
@@ -795,7 +824,10 @@ func classifyError(err error) ErrorCode {
 		case codes.PermissionDenied:
 			return ErrorCodePermissionDenied
 		case codes.ResourceExhausted:
-			return ErrorCodeResourceExhausted
+			if strings.Contains(strings.ToLower(st.Message()), "rate") {
+				return ErrorCodeRateLimited
+			}
+			return ErrorCodeQuotaExceeded
 		case codes.FailedPrecondition:
 			return ErrorCodeFailedPrecondition
 		case codes.Aborted:
@@ -815,26 +847,100 @@ func classifyError(err error) ErrorCode {
 	return ErrorCodeInternal
 }
 
+// limitCode tells quota exhaustion from rate limiting by the reasons and
+// message of a 403 or 429, returning "" when the error is neither
+func limitCode(apiErr *googleapi.Error) ErrorCode {
+	text := strings.ToLower(apiErr.Message)
+	for _, item := range apiErr.Errors {
+		text += " " + strings.ToLower(item.Reason+" "+item.Message)
+	}
+	switch {
+	case strings.Contains(text, "ratelimit") || strings.Contains(text, "rate limit"):
+		return ErrorCodeRateLimited
+	case strings.Contains(text, "quota") || strings.Contains(text, "limitexceeded"):
+		return ErrorCodeQuotaExceeded
+	default:
+		return ""
+	}
+}
+
 // NewGCPError creates a GCP error by classifying the provided error
 func NewGCPError(operation, resource string, err error) *Error {
 	code := classifyError(err)
 
-	// Determine if retryable
+	// Determine if retryable. An exhausted quota stays exhausted until it
+	// resets or is raised, so unlike rate limiting it isn't retried.
 	retryable := false
 	switch code {
-	case ErrorCodeUnavailable, ErrorCodeDeadlineExceeded, ErrorCodeResourceExhausted, ErrorCodeTooManyRequests:
+	case ErrorCodeUnavailable, ErrorCodeDeadlineExceeded, ErrorCodeResourceExhausted, ErrorCodeTooManyRequests,
+		ErrorCodeRateLimited:
 		retryable = true
 	}
 
-	return &Error{
-		Code:      string(code),
-		Message:   err.Error(),
-		Operation: operation,
-		Resource:  resource,
-		Cause:     err,
-		Timestamp: time.Now(),
-		Retryable: retryable,
+	gcpErr := &Error{
+		Code:          string(code),
+		Message:       err.Error(),
+		Operation:     operation,
+		Resource:      resource,
+		Cause:         err,
+		Timestamp:     time.Now(),
+		Retryable:     retryable,
+		RateLimited:   code == ErrorCodeRateLimited,
+		QuotaExceeded: code == ErrorCodeQuotaExceeded,
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		gcpErr.Status = apiErr.Code
+		for _, item := range apiErr.Errors {
+			gcpErr.Details = append(gcpErr.Details, ErrorDetail{Reason: item.Reason})
+		}
+	}
+
+	return gcpErr
+}
+
+// AsError returns err as a catalog error: the *Error in its chain if there is
+// one, otherwise err classified by NewGCPError
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var gcpErr *Error
+	if errors.As(err, &gcpErr) {
+		return gcpErr
+	}
+	return NewGCPError("", "", err)
+}
+
+// HTTPStatus returns the HTTP status an API should answer the error with
+func (e *Error) HTTPStatus() int {
+	switch ErrorCode(e.Code) {
+	case ErrorCodeNotFound:
+		return http.StatusNotFound
+	case ErrorCodeRateLimited, ErrorCodeQuotaExceeded, ErrorCodeTooManyRequests, ErrorCodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case ErrorCodeValidationFailed, ErrorCodeInvalidArgument, ErrorCodeBadRequest, ErrorCodeOutOfRange, ErrorCodeFailedPrecondition:
+		return http.StatusBadRequest
+	case ErrorCodeUnauthenticated:
+		return http.StatusUnauthorized
+	case ErrorCodePermissionDenied:
+		return http.StatusForbidden
+	case ErrorCodeAlreadyExists, ErrorCodeConflict, ErrorCodeAborted:
+		return http.StatusConflict
+	case ErrorCodePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrorCodeUnimplemented:
+		return http.StatusNotImplemented
+	case ErrorCodeUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrorCodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	if e.Status >= 400 {
+		return e.Status
 	}
+	return http.StatusInternalServerError
 }
 
 // NewNotFoundError creates a NOT_FOUND error
@@ -860,10 +966,10 @@ func NewPermissionError(operation, resource string) *Error {
 	}
 }
 
-// NewValidationError creates an INVALID_ARGUMENT error
+// NewValidationError creates a VALIDATION_FAILED error
 func NewValidationError(field, reason string) *Error {
 	return &Error{
-		Code:      string(ErrorCodeInvalidArgument),
+		Code:      string(ErrorCodeValidationFailed),
 		Message:   fmt.Sprintf("Validation failed for field '%s': %s", field, reason),
 		Timestamp: time.Now(),
 		Retryable: false,
@@ -880,7 +986,7 @@ func NewValidationError(field, reason string) *Error {
 // NewQuotaError creates a quota exceeded error
 func NewQuotaError(metric string, limit, usage int64) *Error {
 	return &Error{
-		Code:          string(ErrorCodeResourceExhausted),
+		Code:          string(ErrorCodeQuotaExceeded),
 		Message:       fmt.Sprintf("Quota exceeded for metric '%s': usage %d exceeds limit %d", metric, usage, limit),
 		QuotaExceeded: true,
 		QuotaMetric:   metric,
@@ -894,7 +1000,7 @@ func NewQuotaError(metric string, limit, usage int64) *Error {
 // NewRateLimitError creates a rate limit error
 func NewRateLimitError(retryAfter time.Duration) *Error {
 	return &Error{
-		Code:        string(ErrorCodeTooManyRequests),
+		Code:        string(ErrorCodeRateLimited),
 		Message:     "Rate limit exceeded",
 		RateLimited: true,
 		RetryAfter:  time.Now().Add(retryAfter),
@@ -24,6 +24,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -798,7 +799,7 @@ func (ss *SecretsService) CreateSecret(ctx context.Context, projectID string, co
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["secret_create"]++
 		ss.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to create secret: %w", err)
+		return nil, NewGCPError("CreateSecret", config.SecretID, fmt.Errorf("failed to create secret: %w", err))
 	}
 
 	// Update cache
@@ -891,7 +892,7 @@ func (ss *SecretsService) AddSecretVersion(ctx context.Context, secretName strin
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["version_add"]++
 		ss.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to add secret version: %w", err)
+		return nil, NewGCPError("AddSecretVersion", secretName, fmt.Errorf("failed to add secret version: %w", err))
 	}
 
 	// Update version cache
@@ -1006,7 +1007,7 @@ func (ss *SecretsService) AccessSecretVersion(ctx context.Context, versionName s
 
 		// Log failed access
 		ss.logSecretAccess(versionName, principal, "ERROR", err)
-		return nil, fmt.Errorf("failed to access secret version: %w", err)
+		return nil, NewGCPError("AccessSecretVersion", versionName, fmt.Errorf("failed to access secret version: %w", err))
 	}
 
 	// Decrypt data if encrypted
@@ -1249,6 +1250,28 @@ func (ss *SecretsService) RotateSecret(ctx context.Context, secretName string, n
 	return nil
 }
 
+// GetSecret returns the secret named name (projects/*/secrets/*)
+func (ss *SecretsService) GetSecret(ctx context.Context, name string) (*secretmanagerpb.Secret, error) {
+	secret, err := ss.client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: name})
+	if err != nil {
+		return nil, NewGCPError("GetSecret", name, fmt.Errorf("failed to get secret: %w", err))
+	}
+	return secret, nil
+}
+
+// UpdateSecretLabels replaces the labels of a secret if its etag is still
+// etag; Secret Manager rejects the update otherwise
+func (ss *SecretsService) UpdateSecretLabels(ctx context.Context, name string, labels map[string]string, etag string) (*secretmanagerpb.Secret, error) {
+	secret, err := ss.client.UpdateSecret(ctx, &secretmanagerpb.UpdateSecretRequest{
+		Secret:     &secretmanagerpb.Secret{Name: name, Labels: labels, Etag: etag},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}},
+	})
+	if err != nil {
+		return nil, NewGCPError("UpdateSecretLabels", name, fmt.Errorf("failed to update secret labels: %w", err))
+	}
+	return secret, nil
+}
+
 // Helper methods
 
 // validateSecretExists validates that a secret exists
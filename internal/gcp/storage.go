@@ -598,7 +598,7 @@ func (ss *StorageService) CreateBucket(ctx context.Context, config *BucketConfig
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["bucket_create"]++
 		ss.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to create bucket: %w", err)
+		return nil, NewGCPError("CreateBucket", config.Name, fmt.Errorf("failed to create bucket: %w", err))
 	}
 
 	// Set predefined ACL if specified
@@ -613,7 +613,7 @@ func (ss *StorageService) CreateBucket(ctx context.Context, config *BucketConfig
 	// Get the created bucket attributes
 	createdAttrs, err := bucket.Attrs(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get bucket attributes: %w", err)
+		return nil, NewGCPError("CreateBucket", config.Name, fmt.Errorf("failed to get bucket attributes: %w", err))
 	}
 
 	// Update cache
@@ -674,7 +674,7 @@ func (ss *StorageService) GetBucket(ctx context.Context, bucketName string) (*st
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["bucket_get"]++
 		ss.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to get bucket attributes: %w", err)
+		return nil, NewGCPError("GetBucket", bucketName, fmt.Errorf("failed to get bucket attributes: %w", err))
 	}
 
 	// Update cache
@@ -686,6 +686,48 @@ func (ss *StorageService) GetBucket(ctx context.Context, bucketName string) (*st
 	return attrs, nil
 }
 
+// UpdateBucketLabels replaces the labels of a bucket if its metageneration
+// is still metageneration, failing with 412 otherwise
+func (ss *StorageService) UpdateBucketLabels(ctx context.Context, bucketName string, labels map[string]string, metageneration int64) (*storage.BucketAttrs, error) {
+	current, err := ss.GetBucket(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Apply rate limiting
+	<-ss.rateLimiter.writeLimiter.C
+
+	var update storage.BucketAttrsToUpdate
+	for key := range current.Labels {
+		if _, ok := labels[key]; !ok {
+			update.DeleteLabel(key)
+		}
+	}
+	for key, value := range labels {
+		update.SetLabel(key, value)
+	}
+
+	bucket := ss.client.Bucket(bucketName).If(storage.BucketConditions{MetagenerationMatch: metageneration})
+	attrs, err := bucket.Update(ctx, update)
+
+	// Whether or not it went through, the cached attributes are stale now
+	ss.bucketCache.mu.Lock()
+	delete(ss.bucketCache.buckets, bucketName)
+	delete(ss.bucketCache.lastUpdate, bucketName)
+	ss.bucketCache.mu.Unlock()
+
+	if err != nil {
+		ss.metrics.mu.Lock()
+		ss.metrics.ErrorCounts["bucket_update"]++
+		ss.metrics.mu.Unlock()
+		return nil, NewGCPError("UpdateBucketLabels", bucketName, fmt.Errorf("failed to update bucket labels: %w", err))
+	}
+	return attrs, nil
+}
+
 // ListBuckets lists all buckets in the project
 func (ss *StorageService) ListBuckets(ctx context.Context, prefix string) ([]*storage.BucketAttrs, error) {
 	ss.mu.RLock()
@@ -710,7 +752,7 @@ func (ss *StorageService) ListBuckets(ctx context.Context, prefix string) ([]*st
 			ss.metrics.mu.Lock()
 			ss.metrics.ErrorCounts["bucket_list"]++
 			ss.metrics.mu.Unlock()
-			return nil, fmt.Errorf("failed to list buckets: %w", err)
+			return nil, NewGCPError("ListBuckets", prefix, fmt.Errorf("failed to list buckets: %w", err))
 		}
 		buckets = append(buckets, attrs)
 
@@ -759,7 +801,7 @@ func (ss *StorageService) DeleteBucket(ctx context.Context, bucketName string, f
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["bucket_delete"]++
 		ss.metrics.mu.Unlock()
-		return fmt.Errorf("failed to delete bucket: %w", err)
+		return NewGCPError("DeleteBucket", bucketName, fmt.Errorf("failed to delete bucket: %w", err))
 	}
 
 	// Remove from cache
@@ -1161,7 +1203,7 @@ func (ss *StorageService) ListObjects(ctx context.Context, bucketName string, pr
 			ss.metrics.mu.Lock()
 			ss.metrics.ErrorCounts["object_list"]++
 			ss.metrics.mu.Unlock()
-			return nil, "", fmt.Errorf("failed to list objects: %w", err)
+			return nil, "", NewGCPError("ListObjects", bucketName, fmt.Errorf("failed to list objects: %w", err))
 		}
 		objects = append(objects, attrs)
 
@@ -1214,7 +1256,7 @@ func (ss *StorageService) DeleteObject(ctx context.Context, bucketName string, o
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["object_delete"]++
 		ss.metrics.mu.Unlock()
-		return fmt.Errorf("failed to delete object: %w", err)
+		return NewGCPError("DeleteObject", bucketName + "/" + objectName, fmt.Errorf("failed to delete object: %w", err))
 	}
 
 	// Remove from cache
@@ -1478,7 +1520,7 @@ func (ss *StorageService) GetBucketIAMPolicy(ctx context.Context, bucketName str
 		ss.metrics.mu.Lock()
 		ss.metrics.ErrorCounts["iam_get"]++
 		ss.metrics.mu.Unlock()
-		return nil, fmt.Errorf("failed to get IAM policy: %w", err)
+		return nil, NewGCPError("GetBucketIAMPolicy", bucketName, fmt.Errorf("failed to get IAM policy: %w", err))
 	}
 
 	// Update cache
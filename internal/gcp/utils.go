@@ -51,6 +51,8 @@ type UtilsService struct {
 	cacheMutex             sync.RWMutex
 	lastCacheUpdate        time.Time
 	cacheExpiry            time.Duration
+	parallelOperations     int
+	validation             validationCache
 	// Types not defined
 	// metrics                *ServiceMetrics
 	// logger                 *ServiceLogger
@@ -740,6 +742,7 @@ func NewUtilsService(client *Client, config *UtilsConfig) (*UtilsService, error)
 		quotaCache:             make(map[string]*QuotaInfo),
 		costCache:              make(map[string]*CostInfo),
 		cacheExpiry:            config.CacheExpiry,
+		parallelOperations:     config.ParallelOperations,
 		// metrics and logger fields not in struct
 		// metrics:                metrics,
 		// logger:                 logger,
@@ -834,6 +837,115 @@ func (s *UtilsService) ValidateResource(ctx context.Context, resource interface{
 	return result, nil
 }
 
+// ValidateResources validates items against the same rules concurrently,
+// returning results in item order. At most ParallelOperations items are
+// validated at once.
+func (s *UtilsService) ValidateResources(ctx context.Context, items []interface{}, rules []ValidationRule) ([]*ValidationResult, error) {
+	workers := s.parallelOperations
+	if workers <= 0 {
+		workers = defaultValidationWorkers
+	}
+
+	results := make([]*ValidationResult, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int, item interface{}) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = s.ValidateResource(ctx, item, rules)
+		}(i, item)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+const defaultValidationWorkers = 10
+
+// validationCache keeps compiled rule patterns and struct field indexes so
+// validating many resources doesn't recompile and re-reflect for each one.
+// It is safe for concurrent use.
+type validationCache struct {
+	mu       sync.RWMutex
+	patterns map[string]compiledPattern
+	fields   map[validationField][]int
+	compiles int
+}
+
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+type validationField struct {
+	typ  reflect.Type
+	name string
+}
+
+// pattern returns expr compiled, compiling it on first use. Invalid
+// patterns are cached too, so their error is reported without recompiling.
+func (c *validationCache) pattern(expr string) (*regexp.Regexp, error) {
+	c.mu.RLock()
+	compiled, ok := c.patterns[expr]
+	c.mu.RUnlock()
+	if ok {
+		return compiled.re, compiled.err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if compiled, ok := c.patterns[expr]; ok {
+		return compiled.re, compiled.err
+	}
+	if c.patterns == nil {
+		c.patterns = make(map[string]compiledPattern)
+	}
+	re, err := regexp.Compile(expr)
+	c.compiles++
+	c.patterns[expr] = compiledPattern{re: re, err: err}
+	return re, err
+}
+
+// fieldIndex returns the index of the named field of a struct type
+func (c *validationCache) fieldIndex(typ reflect.Type, name string) ([]int, bool) {
+	key := validationField{typ: typ, name: name}
+	c.mu.RLock()
+	index, ok := c.fields[key]
+	c.mu.RUnlock()
+	if ok {
+		return index, index != nil
+	}
+
+	field, found := typ.FieldByName(name)
+	if found {
+		index = field.Index
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fields == nil {
+		c.fields = make(map[validationField][]int)
+	}
+	c.fields[key] = index
+	return index, found
+}
+
 func (s *UtilsService) validateField(resourceValue reflect.Value, resourceType reflect.Type, rule ValidationRule, result *ValidationResult) error {
 	var fieldValue interface{}
 	var exists bool
@@ -842,12 +954,12 @@ func (s *UtilsService) validateField(resourceValue reflect.Value, resourceType r
 		mapValue := resourceValue.Interface().(map[string]interface{})
 		fieldValue, exists = mapValue[rule.Field]
 	} else {
-		_, found := resourceType.FieldByName(rule.Field)
+		index, found := s.validation.fieldIndex(resourceType, rule.Field)
 		if !found {
 			return fmt.Errorf("field %s not found", rule.Field)
 		}
 
-		fieldVal := resourceValue.FieldByName(rule.Field)
+		fieldVal := resourceValue.FieldByIndex(index)
 		if !fieldVal.IsValid() {
 			exists = false
 		} else {
@@ -913,11 +1025,11 @@ func (s *UtilsService) validateField(resourceValue reflect.Value, resourceType r
 		}
 
 		if rule.Pattern != "" {
-			matched, err := regexp.MatchString(rule.Pattern, strVal)
+			pattern, err := s.validation.pattern(rule.Pattern)
 			if err != nil {
 				return fmt.Errorf("invalid pattern for field %s: %w", rule.Field, err)
 			}
-			if !matched {
+			if !pattern.MatchString(strVal) {
 				result.Errors = append(result.Errors, ValidationError{
 					Field:   rule.Field,
 					Message: fmt.Sprintf("Field %s does not match required pattern", rule.Field),
@@ -1050,11 +1162,11 @@ func (s *UtilsService) validateField(resourceValue reflect.Value, resourceType r
 		}
 
 		emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-		matched, err := regexp.MatchString(emailRegex, strVal)
+		pattern, err := s.validation.pattern(emailRegex)
 		if err != nil {
 			return fmt.Errorf("email validation error for field %s: %w", rule.Field, err)
 		}
-		if !matched {
+		if !pattern.MatchString(strVal) {
 			result.Errors = append(result.Errors, ValidationError{
 				Field:   rule.Field,
 				Message: fmt.Sprintf("Field %s must be a valid email address", rule.Field),
@@ -1141,11 +1253,11 @@ func (s *UtilsService) validateField(resourceValue reflect.Value, resourceType r
 		}
 
 		uuidRegex := `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
-		matched, err := regexp.MatchString(uuidRegex, strVal)
+		pattern, err := s.validation.pattern(uuidRegex)
 		if err != nil {
 			return fmt.Errorf("UUID validation error for field %s: %w", rule.Field, err)
 		}
-		if !matched {
+		if !pattern.MatchString(strVal) {
 			result.Errors = append(result.Errors, ValidationError{
 				Field:   rule.Field,
 				Message: fmt.Sprintf("Field %s must be a valid UUID", rule.Field),
@@ -3,7 +3,9 @@ package gcp
 import (
 	"context"
 	// "encoding/json"
-	// "strings"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -992,6 +994,110 @@ func BenchmarkUtilsService_ValidateResource(b *testing.B) {
 	}
 }
 
+func batchValidationFixture() ([]interface{}, []ValidationRule) {
+	type bucket struct {
+		Name     string
+		Location string
+		Owner    string
+		Size     int
+	}
+
+	items := make([]interface{}, 0, 50)
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("bucket-%d", i)
+		if i%7 == 0 {
+			name = fmt.Sprintf("Bucket_%d", i)
+		}
+		owner := "team@example.com"
+		if i%5 == 0 {
+			owner = "not-an-email"
+		}
+		if i%2 == 0 {
+			items = append(items, &bucket{Name: name, Location: "US", Owner: owner, Size: i})
+		} else {
+			items = append(items, map[string]interface{}{"Name": name, "Location": "EU", "Owner": owner, "Size": i})
+		}
+	}
+
+	rules := []ValidationRule{
+		{Field: "Name", Type: "string", Required: true, Pattern: `^[a-z0-9-]+$`},
+		{Field: "Location", Type: "string", AllowedVals: []string{"US", "EU"}},
+		{Field: "Owner", Type: "email"},
+		{Field: "Size", Type: "number", MaxValue: 40},
+	}
+	return items, rules
+}
+
+func TestUtilsService_ValidateResources(t *testing.T) {
+	items, rules := batchValidationFixture()
+	ctx := context.Background()
+
+	batch := &UtilsService{parallelOperations: 4}
+	results, err := batch.ValidateResources(ctx, items, rules)
+	if err != nil {
+		t.Fatalf("ValidateResources: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+
+	single := &UtilsService{}
+	for i, item := range items {
+		want, err := single.ValidateResource(ctx, item, rules)
+		if err != nil {
+			t.Fatalf("ValidateResource(%d): %v", i, err)
+		}
+		if results[i].Valid != want.Valid || !reflect.DeepEqual(results[i].Errors, want.Errors) {
+			t.Errorf("item %d: batch result %+v differs from %+v", i, results[i].Errors, want.Errors)
+		}
+	}
+
+	// The name pattern and the email pattern, each compiled once
+	if batch.validation.compiles != 2 {
+		t.Errorf("patterns compiled %d times, want 2", batch.validation.compiles)
+	}
+}
+
+func TestUtilsService_ValidateResourcesErrors(t *testing.T) {
+	items, rules := batchValidationFixture()
+	items[3] = "not a resource"
+
+	s := &UtilsService{}
+	if _, err := s.ValidateResources(context.Background(), items, rules); err == nil || !strings.Contains(err.Error(), "resource 3") {
+		t.Errorf("expected an error for resource 3, got %v", err)
+	}
+
+	badRules := []ValidationRule{{Field: "Name", Type: "string", Pattern: `([a-z`}}
+	items, _ = batchValidationFixture()
+	s = &UtilsService{}
+	if _, err := s.ValidateResources(context.Background(), items, badRules); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+	if s.validation.compiles != 1 {
+		t.Errorf("invalid pattern compiled %d times, want 1", s.validation.compiles)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = &UtilsService{parallelOperations: 1}
+	if _, err := s.ValidateResources(ctx, items, rules); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func BenchmarkUtilsService_ValidateResources(b *testing.B) {
+	items, rules := batchValidationFixture()
+	s := &UtilsService{}
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.ValidateResources(ctx, items, rules); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestUtilsErrorHandling(t *testing.T) {
 	// Test various error scenarios
 	tests := []struct {
@@ -1024,7 +1130,7 @@ func TestUtilsErrorHandling(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			gcpErr := NewGCPError("TestOperation", "test-resource", tt.err)
-			if gcpErr.Code != tt.wantCode {
+			if gcpErr.Code != string(tt.wantCode) {
 				t.Errorf("Error classification = %v, want %v", gcpErr.Code, tt.wantCode)
 			}
 		})
@@ -0,0 +1,221 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultMaxPayloadBytes caps a webhook export when Webhook.MaxBytes is unset
+const DefaultMaxPayloadBytes = 256 * 1024
+
+// SignatureHeader carries the signature of a webhook with a secret: the hex
+// HMAC-SHA256 of the TimestampHeader value, a dot and the body, prefixed
+// "sha256=". Receivers recompute it with Sign, and reject stale timestamps
+// to stop a captured request being replayed.
+const SignatureHeader = "X-Signature-256"
+
+// TimestampHeader carries the Unix time, in seconds, a webhook was sent at
+const TimestampHeader = "X-Signature-Timestamp"
+
+// redacted replaces the values of secret-looking keys in exported payloads
+const redacted = "[REDACTED]"
+
+// secretKeyParts mark a payload key as holding a secret
+var secretKeyParts = []string{"password", "secret", "token", "api_key", "apikey", "private_key", "credential", "authorization"}
+
+// Webhook exports command results, such as analysis summaries and monitoring
+// results, to an HTTP endpoint for ChatOps and dashboards
+//
+//	{"url": "https://example.com/hook", "secret": "...", "template": "{\"text\": {{json .summary}}}"}
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret signs the timestamp and body in SignatureHeader
+	Secret string `json:"secret,omitempty"`
+	// Template is a text/template rendering the body from the payload's
+	// JSON form; the json func quotes values, and a key missing from the
+	// payload fails. The payload's JSON is sent when it is empty.
+	Template   string            `json:"template,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	MaxBytes   int               `json:"max_bytes,omitempty"`
+	Retries    int               `json:"retries,omitempty"`
+	RetryDelay time.Duration     `json:"retry_delay,omitempty"`
+}
+
+// WebhookSecretEnv holds the signing secret of webhooks set up from flags,
+// keeping it out of process listings
+const WebhookSecretEnv = "WEBHOOK_SECRET"
+
+// NewWebhook returns a webhook for url, with its template read from
+// templateFile when set and its secret from WebhookSecretEnv
+func NewWebhook(url, templateFile string) (*Webhook, error) {
+	webhook := &Webhook{URL: url, Secret: os.Getenv(WebhookSecretEnv)}
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook template: %w", err)
+		}
+		webhook.Template = string(data)
+	}
+	return webhook, nil
+}
+
+// Export redacts payload, renders it and POSTs it, retrying 429s, 5xx
+// responses and network errors with exponential backoff
+func (w Webhook) Export(ctx context.Context, payload interface{}) error {
+	if w.URL == "" {
+		return fmt.Errorf("webhook url is required")
+	}
+
+	body, err := w.render(payload)
+	if err != nil {
+		return err
+	}
+	limit := w.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxPayloadBytes
+	}
+	if len(body) > limit {
+		return fmt.Errorf("webhook payload is %d bytes, over the limit of %d", len(body), limit)
+	}
+
+	retries := w.Retries
+	if retries <= 0 {
+		retries = 3
+	}
+	delay := w.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay << (attempt - 1)):
+			}
+		}
+
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// render redacts payload and produces the request body
+func (w Webhook) render(payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	generic = Redact(generic)
+
+	if w.Template == "" {
+		return json.Marshal(generic)
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+	}).Option("missingkey=error").Parse(w.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, generic); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (w Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.Headers {
+		req.Header.Set(key, value)
+	}
+	if w.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(w.Secret, timestamp, body))
+	}
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("POST %s returned %s", w.URL, resp.Status)
+}
+
+// Sign returns the SignatureHeader value for body sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Redact replaces the values of secret-looking keys in a decoded JSON value
+func Redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSecretKey(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = Redact(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = Redact(item)
+		}
+	}
+	return value
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
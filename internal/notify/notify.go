@@ -0,0 +1,174 @@
+// Package notify delivers alert messages to the notification channels the
+// monitor and analyze commands are configured with, and exports their
+// results to webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Channel is a notification destination. It has the same shape as the
+// monitor's alert actions: a type plus type-specific config.
+//
+//	{"type": "webhook", "config": {"url": "https://example.com/hook"}}
+//	{"type": "slack", "config": {"webhook_url": "https://hooks.slack.com/..."}}
+//	{"type": "pagerduty", "config": {"routing_key": "..."}}
+type Channel struct {
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// Message is a single notification
+type Message struct {
+	Title    string                 `json:"title"`
+	Text     string                 `json:"text"`
+	Severity string                 `json:"severity"`
+	Source   string                 `json:"source"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	SentAt   time.Time              `json:"sent_at"`
+}
+
+// HTTPClient posts notifications; tests replace it
+var HTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// PagerDutyEventsURL is the Events API v2 endpoint pagerduty channels post
+// to unless their config sets a url
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Send delivers msg to every channel and returns the errors of the channels
+// that failed
+func Send(ctx context.Context, channels []Channel, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		if err := send(ctx, channel, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s notification: %w", channel.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func send(ctx context.Context, channel Channel, msg Message) error {
+	switch channel.Type {
+	case "webhook":
+		url := channel.configString("url")
+		if url == "" {
+			return fmt.Errorf("config.url is required")
+		}
+		return postJSON(ctx, url, msg)
+	case "slack":
+		url := channel.configString("webhook_url")
+		if url == "" {
+			url = channel.configString("url")
+		}
+		if url == "" {
+			return fmt.Errorf("config.webhook_url is required")
+		}
+		return postJSON(ctx, url, map[string]string{"text": slackText(msg)})
+	case "pagerduty":
+		key := channel.configString("routing_key")
+		if key == "" {
+			return fmt.Errorf("config.routing_key is required")
+		}
+		url := channel.configString("url")
+		if url == "" {
+			url = PagerDutyEventsURL
+		}
+		return postJSON(ctx, url, pagerDutyEvent(key, msg))
+	default:
+		return fmt.Errorf("unsupported channel type %q", channel.Type)
+	}
+}
+
+func (c Channel) configString(key string) string {
+	value, _ := c.Config[key].(string)
+	return value
+}
+
+// slackText renders msg as Slack mrkdwn with fields in a stable order
+func slackText(msg Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*", msg.Title)
+	if msg.Severity != "" {
+		fmt.Fprintf(&b, " [%s]", strings.ToUpper(msg.Severity))
+	}
+	if msg.Text != "" {
+		fmt.Fprintf(&b, "\n%s", msg.Text)
+	}
+
+	keys := make([]string, 0, len(msg.Fields))
+	for key := range msg.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\n• %s: %v", key, msg.Fields[key])
+	}
+	return b.String()
+}
+
+// pagerDutyEvent renders msg as an Events API v2 trigger
+func pagerDutyEvent(routingKey string, msg Message) map[string]interface{} {
+	severity := strings.ToLower(msg.Severity)
+	switch severity {
+	case "critical", "error", "warning", "info":
+	case "high":
+		severity = "error"
+	default:
+		severity = "info"
+	}
+	summary := msg.Title
+	if msg.Text != "" {
+		summary += ": " + msg.Text
+	}
+	source := msg.Source
+	if source == "" {
+		source = "terragrunt-gcp"
+	}
+	return map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": "trigger",
+		"payload": map[string]interface{}{
+			"summary":        summary,
+			"source":         source,
+			"severity":       severity,
+			"timestamp":      msg.SentAt.Format(time.RFC3339),
+			"custom_details": msg.Fields,
+		},
+	}
+}
+
+func postJSON(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s returned %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendWebhookAndSlack(t *testing.T) {
+	var webhook Message
+	var slack map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.URL.Path {
+		case "/webhook":
+			err = json.NewDecoder(r.Body).Decode(&webhook)
+		case "/slack":
+			err = json.NewDecoder(r.Body).Decode(&slack)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	channels := []Channel{
+		{Type: "webhook", Config: map[string]interface{}{"url": server.URL + "/webhook"}},
+		{Type: "slack", Config: map[string]interface{}{"webhook_url": server.URL + "/slack"}},
+	}
+	msg := Message{
+		Title:    "Budget alert",
+		Text:     "Spend crossed 80%",
+		Severity: "warning",
+		Fields:   map[string]interface{}{"threshold": 80, "basis": "current"},
+	}
+	if err := Send(context.Background(), channels, msg); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if webhook.Title != "Budget alert" || webhook.SentAt.IsZero() {
+		t.Errorf("unexpected webhook payload: %+v", webhook)
+	}
+	want := "*Budget alert* [WARNING]\nSpend crossed 80%\n• basis: current\n• threshold: 80"
+	if slack["text"] != want {
+		t.Errorf("slack text = %q, want %q", slack["text"], want)
+	}
+}
+
+func TestSendPagerDuty(t *testing.T) {
+	var event struct {
+		RoutingKey  string `json:"routing_key"`
+		EventAction string `json:"event_action"`
+		Payload     struct {
+			Summary  string                 `json:"summary"`
+			Source   string                 `json:"source"`
+			Severity string                 `json:"severity"`
+			Details  map[string]interface{} `json:"custom_details"`
+		} `json:"payload"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	channel := Channel{Type: "pagerduty", Config: map[string]interface{}{"routing_key": "R0UT1NG", "url": server.URL}}
+	msg := Message{Title: "Backup failed", Text: "1 of 2 targets failed", Severity: "error", Source: "backup", Fields: map[string]interface{}{"failed": 1}}
+	if err := Send(context.Background(), []Channel{channel}, msg); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if event.RoutingKey != "R0UT1NG" || event.EventAction != "trigger" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Payload.Summary != "Backup failed: 1 of 2 targets failed" || event.Payload.Source != "backup" || event.Payload.Severity != "error" {
+		t.Errorf("unexpected payload: %+v", event.Payload)
+	}
+	if event.Payload.Details["failed"] != float64(1) {
+		t.Errorf("custom_details = %v", event.Payload.Details)
+	}
+
+	if err := Send(context.Background(), []Channel{{Type: "pagerduty"}}, msg); err == nil || !strings.Contains(err.Error(), "routing_key") {
+		t.Errorf("expected a missing routing_key error, got %v", err)
+	}
+}
+
+func TestSendReportsEveryFailedChannel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := Send(context.Background(), []Channel{
+		{Type: "webhook", Config: map[string]interface{}{"url": server.URL}},
+		{Type: "webhook"},
+		{Type: "pager"},
+	}, Message{Title: "x"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, part := range []string{"500 Internal Server Error", "config.url is required", `unsupported channel type "pager"`} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected error to mention %q, got %v", part, err)
+		}
+	}
+}
+
+func TestWebhookExportSignsAndRetries(t *testing.T) {
+	var attempts int
+	var body []byte
+	var signature, timestamp string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		timestamp = r.Header.Get(TimestampHeader)
+	}))
+	defer server.Close()
+
+	webhook := Webhook{URL: server.URL, Secret: "s3cr3t", RetryDelay: time.Millisecond}
+	payload := map[string]interface{}{
+		"project_id": "demo",
+		"summary":    map[string]interface{}{"total_resources": 42},
+		"database":   map[string]interface{}{"host": "10.0.0.5", "password": "hunter2"},
+		"api-token":  "abc",
+	}
+	if err := webhook.Export(context.Background(), payload); err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("expected two 502s to be retried, got %d attempts", attempts)
+	}
+	if signature != Sign("s3cr3t", timestamp, body) {
+		t.Fatalf("signature %q doesn't match the delivered timestamp and body", signature)
+	}
+	if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Fatalf("unexpected timestamp %q", timestamp)
+	}
+	if signature == Sign("s3cr3t", "0", body) {
+		t.Fatal("expected the timestamp to be signed")
+	}
+	var delivered map[string]interface{}
+	if err := json.Unmarshal(body, &delivered); err != nil {
+		t.Fatal(err)
+	}
+	if delivered["api-token"] != "[REDACTED]" || delivered["database"].(map[string]interface{})["password"] != "[REDACTED]" {
+		t.Fatalf("expected secrets redacted, got %s", body)
+	}
+	if delivered["database"].(map[string]interface{})["host"] != "10.0.0.5" {
+		t.Fatalf("expected other values kept, got %s", body)
+	}
+}
+
+func TestWebhookExportTemplateAndLimits(t *testing.T) {
+	var attempts int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	payload := map[string]interface{}{"project_id": "demo", "summary": map[string]interface{}{"score": 87}}
+	webhook := Webhook{URL: server.URL, Template: `{"text": "{{.project_id}} scored {{.summary.score}}", "raw": {{json .summary}}}`, RetryDelay: time.Millisecond}
+
+	// Client errors aren't retried
+	err := webhook.Export(context.Background(), payload)
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request") {
+		t.Fatalf("expected the 400 to be returned, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected a single attempt for a 400, got %d", attempts)
+	}
+	if body != `{"text": "demo scored 87", "raw": {"score":87}}` {
+		t.Fatalf("unexpected templated body: %s", body)
+	}
+
+	missing := webhook
+	missing.Template = `{"text": "{{.summary.grade}}"}`
+	if err := missing.Export(context.Background(), payload); err == nil || !strings.Contains(err.Error(), "failed to render webhook template") {
+		t.Fatalf("expected a missing key to fail, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatal("expected a failed render not to be sent")
+	}
+
+	webhook.MaxBytes = 10
+	if err := webhook.Export(context.Background(), payload); err == nil || !strings.Contains(err.Error(), "over the limit of 10") {
+		t.Fatalf("expected the size cap to reject the payload, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatal("expected an oversized payload not to be sent")
+	}
+}
@@ -0,0 +1,223 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/iterator"
+)
+
+// countPageSize is the largest page the list APIs return
+const countPageSize = 500
+
+// countableResourceTypes are the types CountResources knows how to count
+var countableResourceTypes = []string{
+	"compute.instances",
+	"compute.disks",
+	"compute.networks",
+	"compute.firewalls",
+	"storage.buckets",
+	"iam.serviceAccounts",
+}
+
+// countFilter holds the discovery filters that apply to counts: labels,
+// status, creation time and, for zonal types, zone. They match as they do
+// for discovered resources, so a resource without labels fails a label
+// filter and types without a status of their own count as ACTIVE.
+type countFilter struct {
+	labels        map[string]string
+	status        []string
+	createdAfter  time.Time
+	createdBefore time.Time
+	zone          string
+}
+
+func newCountFilter(filters map[string]interface{}) countFilter {
+	var f countFilter
+	f.labels, _ = filters["labels"].(map[string]string)
+	f.status, _ = filters["status"].([]string)
+	if after, ok := filters["created_after"].(string); ok {
+		f.createdAfter, _ = time.Parse(time.RFC3339, after)
+	}
+	if before, ok := filters["created_before"].(string); ok {
+		f.createdBefore, _ = time.Parse(time.RFC3339, before)
+	}
+	f.zone, _ = filters["zone"].(string)
+	return f
+}
+
+// fields reports whether the filter looks at more than names, so list
+// calls need to fetch labels, status and creation time too
+func (f countFilter) fields() bool {
+	return len(f.labels) > 0 || len(f.status) > 0 || !f.createdAfter.IsZero() || !f.createdBefore.IsZero()
+}
+
+// inZone reports whether an aggregated list scope such as
+// "zones/us-east1-b" passes the zone filter
+func (f countFilter) inZone(scope string) bool {
+	return f.zone == "" || scope == "zones/"+f.zone
+}
+
+// match reports whether a resource with these labels, status and GCP
+// creation timestamp passes the filter
+func (f countFilter) match(labels map[string]string, status, created string) bool {
+	for key, value := range f.labels {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	if len(f.status) > 0 {
+		found := false
+		for _, s := range f.status {
+			found = found || s == status
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.createdAfter.IsZero() || !f.createdBefore.IsZero() {
+		createdAt, _ := time.Parse(time.RFC3339, created)
+		if !f.createdAfter.IsZero() && createdAt.Before(f.createdAfter) {
+			return false
+		}
+		if !f.createdBefore.IsZero() && createdAt.After(f.createdBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// CountResources returns how many resources of each type the project has.
+// Zonal types use aggregated lists and every call asks only for names in
+// full pages, plus what the filters look at, so counting is far cheaper
+// than ListResources. Set filters["resource_types"] to a []string to count
+// only some types; the labels, status, created_after, created_before and
+// zone filters apply as they do to discovery.
+func (p *GCPProvider) CountResources(ctx context.Context, filters map[string]interface{}) (map[string]int, error) {
+	types := countableResourceTypes
+	if selected, ok := filters["resource_types"].([]string); ok && len(selected) > 0 {
+		types = selected
+	}
+	filter := newCountFilter(filters)
+
+	counts := make(map[string]int, len(types))
+	for _, resourceType := range types {
+		p.waitForRateLimit()
+
+		count, err := p.countResources(ctx, resourceType, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", resourceType, err)
+		}
+		counts[resourceType] = count
+	}
+	return counts, nil
+}
+
+func (p *GCPProvider) countResources(ctx context.Context, resourceType string, filter countFilter) (int, error) {
+	// Fetch what the filter looks at alongside the names
+	itemFields := func(kind, filtered string) googleapi.Field {
+		if filter.fields() {
+			return googleapi.Field(kind + "(name," + filtered + ")")
+		}
+		return googleapi.Field(kind + "/name")
+	}
+
+	count := 0
+	switch resourceType {
+	case "compute.instances":
+		err := p.computeService.Instances.AggregatedList(p.project).
+			MaxResults(countPageSize).ReturnPartialSuccess(true).
+			Fields("nextPageToken", itemFields("items/*/instances", "labels,status,creationTimestamp")).
+			Pages(ctx, func(page *compute.InstanceAggregatedList) error {
+				for scope, scoped := range page.Items {
+					if !filter.inZone(scope) {
+						continue
+					}
+					for _, instance := range scoped.Instances {
+						if filter.match(instance.Labels, instance.Status, instance.CreationTimestamp) {
+							count++
+						}
+					}
+				}
+				return nil
+			})
+		return count, err
+	case "compute.disks":
+		err := p.computeService.Disks.AggregatedList(p.project).
+			MaxResults(countPageSize).ReturnPartialSuccess(true).
+			Fields("nextPageToken", itemFields("items/*/disks", "labels,status,creationTimestamp")).
+			Pages(ctx, func(page *compute.DiskAggregatedList) error {
+				for scope, scoped := range page.Items {
+					if !filter.inZone(scope) {
+						continue
+					}
+					for _, disk := range scoped.Disks {
+						if filter.match(disk.Labels, disk.Status, disk.CreationTimestamp) {
+							count++
+						}
+					}
+				}
+				return nil
+			})
+		return count, err
+	case "compute.networks":
+		err := p.computeService.Networks.List(p.project).
+			MaxResults(countPageSize).Fields("nextPageToken", itemFields("items", "creationTimestamp")).
+			Pages(ctx, func(page *compute.NetworkList) error {
+				for _, network := range page.Items {
+					if filter.match(nil, "ACTIVE", network.CreationTimestamp) {
+						count++
+					}
+				}
+				return nil
+			})
+		return count, err
+	case "compute.firewalls":
+		err := p.computeService.Firewalls.List(p.project).
+			MaxResults(countPageSize).Fields("nextPageToken", itemFields("items", "creationTimestamp")).
+			Pages(ctx, func(page *compute.FirewallList) error {
+				for _, firewall := range page.Items {
+					if filter.match(nil, "ACTIVE", firewall.CreationTimestamp) {
+						count++
+					}
+				}
+				return nil
+			})
+		return count, err
+	case "storage.buckets":
+		// The bucket iterator can't select fields, but listing alone skips
+		// the per-bucket cost lookups ListResources makes
+		it := p.storageClient.Buckets(ctx, p.project)
+		it.PageInfo().MaxSize = countPageSize
+		for {
+			bucket, err := it.Next()
+			if err == iterator.Done {
+				return count, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+			if filter.match(bucket.Labels, "ACTIVE", bucket.Created.Format(time.RFC3339)) {
+				count++
+			}
+		}
+	case "iam.serviceAccounts":
+		err := p.iamService.Projects.ServiceAccounts.List(fmt.Sprintf("projects/%s", p.project)).
+			PageSize(100).Fields("nextPageToken", "accounts/name").
+			Pages(ctx, func(page *iam.ListServiceAccountsResponse) error {
+				for range page.Accounts {
+					if filter.match(nil, "ACTIVE", "") {
+						count++
+					}
+				}
+				return nil
+			})
+		return count, err
+	default:
+		return 0, fmt.Errorf("unsupported resource type: %s", resourceType)
+	}
+}
@@ -0,0 +1,186 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+)
+
+// newCountTestProvider returns a provider whose API clients talk to handler
+func newCountTestProvider(t *testing.T, handler http.HandlerFunc) *GCPProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	opts := func(path string) []option.ClientOption {
+		return []option.ClientOption{option.WithEndpoint(server.URL + path), option.WithoutAuthentication()}
+	}
+	computeService, err := compute.NewService(ctx, opts("/compute/v1/")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storageClient, err := storage.NewClient(ctx, opts("/storage/v1/")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iamService, err := iam.NewService(ctx, opts("/")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &GCPProvider{
+		project:        "demo",
+		computeService: computeService,
+		storageClient:  storageClient,
+		iamService:     iamService,
+		rateLimiter:    &RateLimiter{tokens: 1000, maxTokens: 1000, refillRate: 1000, lastRefill: time.Now()},
+	}
+}
+
+func TestCountResourcesUsesNameOnlyPages(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	provider := newCountTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+
+		var body interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/aggregated/instances") && r.URL.Query().Get("pageToken") == "":
+			body = map[string]interface{}{
+				"items": map[string]interface{}{
+					"zones/europe-west1-b": map[string]interface{}{"instances": []interface{}{map[string]string{"name": "a"}, map[string]string{"name": "b"}}},
+					"zones/europe-west1-c": map[string]interface{}{"warning": map[string]string{"code": "NO_RESULTS_ON_PAGE"}},
+				},
+				"nextPageToken": "page-2",
+			}
+		case strings.HasSuffix(r.URL.Path, "/aggregated/instances"):
+			body = map[string]interface{}{
+				"items": map[string]interface{}{
+					"zones/us-east1-b": map[string]interface{}{"instances": []interface{}{map[string]string{"name": "c"}}},
+				},
+			}
+		case strings.HasSuffix(r.URL.Path, "/global/networks"):
+			body = map[string]interface{}{"items": []interface{}{map[string]string{"name": "default"}}}
+		case strings.HasSuffix(r.URL.Path, "/serviceAccounts"):
+			body = map[string]interface{}{"accounts": []interface{}{map[string]string{"name": "sa-1"}, map[string]string{"name": "sa-2"}}}
+		default:
+			http.Error(w, "unexpected request "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	counts, err := provider.CountResources(context.Background(), map[string]interface{}{
+		"resource_types": []string{"compute.instances", "compute.networks", "iam.serviceAccounts"},
+	})
+	if err != nil {
+		t.Fatalf("CountResources returned error: %v", err)
+	}
+
+	want := map[string]int{"compute.instances": 3, "compute.networks": 1, "iam.serviceAccounts": 2}
+	for resourceType, count := range want {
+		if counts[resourceType] != count {
+			t.Errorf("expected %d %s, got %d", count, resourceType, counts[resourceType])
+		}
+	}
+
+	// Only list calls, each restricted to names
+	if len(requests) != 4 {
+		t.Fatalf("expected 4 list requests, got %d", len(requests))
+	}
+	for _, r := range requests {
+		fields := r.URL.Query().Get("fields")
+		if !strings.Contains(fields, "name") || !strings.Contains(fields, "nextPageToken") {
+			t.Errorf("%s fetched full objects, fields=%q", r.URL.Path, fields)
+		}
+		if r.URL.Query().Get("maxResults") == "" && r.URL.Query().Get("pageSize") == "" {
+			t.Errorf("%s didn't request a full page", r.URL.Path)
+		}
+	}
+}
+
+func TestCountResourcesRejectsUnknownTypes(t *testing.T) {
+	provider := newCountTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	})
+	_, err := provider.CountResources(context.Background(), map[string]interface{}{"resource_types": []string{"sql.instances"}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported resource type") {
+		t.Fatalf("expected an unsupported type error, got %v", err)
+	}
+}
+
+func TestCountResourcesAppliesFilters(t *testing.T) {
+	var fields []string
+	provider := newCountTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		fields = append(fields, r.URL.Query().Get("fields"))
+		instance := func(name, status, created string, labels map[string]string) map[string]interface{} {
+			return map[string]interface{}{"name": name, "status": status, "creationTimestamp": created, "labels": labels}
+		}
+		var body interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/aggregated/instances"):
+			body = map[string]interface{}{
+				"items": map[string]interface{}{
+					"zones/europe-west1-b": map[string]interface{}{"instances": []interface{}{
+						instance("web-1", "RUNNING", "2026-01-10T08:00:00.000-07:00", map[string]string{"env": "prod"}),
+						instance("web-2", "TERMINATED", "2026-01-10T08:00:00.000-07:00", map[string]string{"env": "prod"}),
+						instance("dev-1", "RUNNING", "2026-01-10T08:00:00.000-07:00", map[string]string{"env": "dev"}),
+						instance("old-1", "RUNNING", "2024-01-10T08:00:00.000-07:00", map[string]string{"env": "prod"}),
+						instance("bare", "RUNNING", "2026-01-10T08:00:00.000-07:00", nil),
+					}},
+					"zones/us-east1-b": map[string]interface{}{"instances": []interface{}{
+						instance("web-3", "RUNNING", "2026-01-10T08:00:00.000-07:00", map[string]string{"env": "prod"}),
+					}},
+				},
+			}
+		case strings.HasSuffix(r.URL.Path, "/global/networks"):
+			body = map[string]interface{}{"items": []interface{}{map[string]string{"name": "default", "creationTimestamp": "2026-01-10T08:00:00.000-07:00"}}}
+		default:
+			http.Error(w, "unexpected request "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	filters := map[string]interface{}{
+		"resource_types": []string{"compute.instances", "compute.networks"},
+		"labels":         map[string]string{"env": "prod"},
+		"status":         []string{"RUNNING"},
+		"created_after":  "2025-01-01T00:00:00Z",
+	}
+	counts, err := provider.CountResources(context.Background(), filters)
+	if err != nil {
+		t.Fatalf("CountResources returned error: %v", err)
+	}
+	// web-1 and web-3; networks have no labels
+	if counts["compute.instances"] != 2 || counts["compute.networks"] != 0 {
+		t.Errorf("expected 2 instances and 0 networks, got %v", counts)
+	}
+	if !strings.Contains(fields[0], "labels") || !strings.Contains(fields[0], "status") {
+		t.Errorf("expected the filtered fields to be fetched, fields=%q", fields[0])
+	}
+
+	filters["zone"] = "us-east1-b"
+	counts, err = provider.CountResources(context.Background(), filters)
+	if err != nil {
+		t.Fatalf("CountResources returned error: %v", err)
+	}
+	if counts["compute.instances"] != 1 {
+		t.Errorf("expected 1 instance in us-east1-b, got %d", counts["compute.instances"])
+	}
+}
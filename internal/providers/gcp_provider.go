@@ -93,8 +93,12 @@ func NewGCPProvider(ctx context.Context, project, region string, opts ...option.
 	// Extract zone from region (e.g., us-central1-a from us-central1)
 	provider.zone = fmt.Sprintf("%s-a", region)
 
-	// Initialize Google Cloud clients
-	var err error
+	// Initialize Google Cloud clients, sharing one HTTP client that retries
+	// transient failures of list and get calls
+	opts, err := retryingClientOptions(ctx, provider.config, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	provider.computeService, err = compute.NewService(ctx, opts...)
 	if err != nil {
@@ -759,10 +763,20 @@ func (p *GCPProvider) listAllResources(ctx context.Context, filters map[string]i
 	return allResources, nil
 }
 
+// listZone returns the zone a zonal listing covers: the "zone" filter the
+// discoverer sets when scanning zones in parallel, or the provider's zone
+func (p *GCPProvider) listZone(filters map[string]interface{}) string {
+	if zone, ok := filters["zone"].(string); ok && zone != "" {
+		return zone
+	}
+	return p.zone
+}
+
 func (p *GCPProvider) listComputeInstances(ctx context.Context, filters map[string]interface{}) ([]core.Resource, error) {
 	var resources []core.Resource
+	zone := p.listZone(filters)
 
-	instanceList, err := p.computeService.Instances.List(p.project, p.zone).Context(ctx).Do()
+	instanceList, err := p.computeService.Instances.List(p.project, zone).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list instances: %w", err)
 	}
@@ -775,7 +789,7 @@ func (p *GCPProvider) listComputeInstances(ctx context.Context, filters map[stri
 			// Provider field not available in Resource struct
 			// Provider: "gcp",
 			Region:   p.region,
-			Zone:     p.zone,
+			Zone:     zone,
 			Status:   instance.Status,
 			CreatedAt: parseGCPTimestamp(instance.CreationTimestamp),
 			UpdatedAt: parseGCPTimestamp(instance.LastStartTimestamp),
@@ -898,8 +912,9 @@ func (p *GCPProvider) listNetworks(ctx context.Context, filters map[string]inter
 
 func (p *GCPProvider) listDisks(ctx context.Context, filters map[string]interface{}) ([]core.Resource, error) {
 	var resources []core.Resource
+	zone := p.listZone(filters)
 
-	diskList, err := p.computeService.Disks.List(p.project, p.zone).Context(ctx).Do()
+	diskList, err := p.computeService.Disks.List(p.project, zone).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list disks: %w", err)
 	}
@@ -911,7 +926,7 @@ func (p *GCPProvider) listDisks(ctx context.Context, filters map[string]interfac
 			Type:       "compute.disks",
 			// Provider:   "gcp",
 			Region:     p.region,
-			Zone:       p.zone,
+			Zone:       zone,
 			Status:     disk.Status,
 			CreatedAt:  parseGCPTimestamp(disk.CreationTimestamp),
 			UpdatedAt: parseGCPTimestamp(disk.LastAttachTimestamp),
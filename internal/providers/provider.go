@@ -23,6 +23,9 @@ type Provider interface {
 	CreateResource(ctx context.Context, resource *core.Resource) error
 	UpdateResource(ctx context.Context, resource *core.Resource) error
 	DeleteResource(ctx context.Context, resourceID string) error
+	// CountResources returns resource counts by type without fetching the
+	// resources, for summaries and quota pre-checks
+	CountResources(ctx context.Context, filters map[string]interface{}) (map[string]int, error)
 
 	// Resource metadata and configuration
 	GetResourceTags(ctx context.Context, resourceID string, resourceType string) (map[string]string, error)
@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// maxRetryDelay caps a single backoff, including one asked for by Retry-After
+const maxRetryDelay = 30 * time.Second
+
+// retryTransport retries idempotent API requests (GET and HEAD, which is
+// every list and get call) that fail with 429, 5xx or a network error,
+// backing off exponentially with jitter and honoring Retry-After
+type retryTransport struct {
+	base     http.RoundTripper
+	attempts int
+	delay    time.Duration
+	// timeout bounds the time spent retrying one request
+	timeout time.Duration
+}
+
+func newRetryTransport(base http.RoundTripper, config ProviderConfig) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &retryTransport{base: base, attempts: config.RetryAttempts, delay: config.RetryDelay, timeout: config.Timeout}
+	if t.attempts <= 0 {
+		t.attempts = 3
+	}
+	if t.delay <= 0 {
+		t.delay = time.Second
+	}
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || (req.Body != nil && req.Body != http.NoBody) {
+		return t.base.RoundTrip(req)
+	}
+
+	var deadline time.Time
+	if t.timeout > 0 {
+		deadline = time.Now().Add(t.timeout)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if !retryableResponse(req.Context(), resp, err) || attempt >= t.attempts {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+			}
+		}
+		if wait > maxRetryDelay {
+			wait = maxRetryDelay
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before retry attempt+1: the base delay doubled
+// per attempt, jittered to between half and all of it
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.delay << attempt
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func retryableResponse(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryingClientOptions builds one authenticated HTTP client for opts whose
+// transport retries transient failures, and returns opts with it added so
+// every REST client the provider creates shares it
+func retryingClientOptions(ctx context.Context, config ProviderConfig, opts []option.ClientOption) ([]option.ClientOption, error) {
+	clientOpts := append([]option.ClientOption{option.WithScopes("https://www.googleapis.com/auth/cloud-platform")}, opts...)
+	client, _, err := htransport.NewClient(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	retrying := *client
+	retrying.Transport = newRetryTransport(client.Transport, config)
+	return append(opts[:len(opts):len(opts)], option.WithHTTPClient(&retrying)), nil
+}
@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// scriptedTransport answers requests with the given statuses in order and
+// records when each request arrived
+type scriptedTransport struct {
+	statuses []int
+	headers  []http.Header
+	body     string
+	calls    []time.Time
+}
+
+func (t *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := len(t.calls)
+	t.calls = append(t.calls, time.Now())
+	if i >= len(t.statuses) {
+		return nil, errors.New("unexpected request")
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	if i < len(t.headers) && t.headers[i] != nil {
+		header = t.headers[i]
+	}
+	return &http.Response{
+		StatusCode: t.statuses[i],
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func retryTestConfig() ProviderConfig {
+	return ProviderConfig{RetryAttempts: 3, RetryDelay: time.Millisecond, Timeout: time.Minute}
+}
+
+func newRetryTestCompute(t *testing.T, transport http.RoundTripper) *compute.Service {
+	t.Helper()
+	service, err := compute.NewService(context.Background(),
+		option.WithHTTPClient(&http.Client{Transport: newRetryTransport(transport, retryTestConfig())}),
+		option.WithEndpoint("https://compute.example.test/compute/v1/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestRetryTransportRetriesThrottledListCalls(t *testing.T) {
+	fake := &scriptedTransport{
+		statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK},
+		body:     `{"items": [{"name": "default"}]}`,
+	}
+
+	networks, err := newRetryTestCompute(t, fake).Networks.List("demo").Do()
+	if err != nil {
+		t.Fatalf("expected the call to succeed after retries, got %v", err)
+	}
+	if len(networks.Items) != 1 || networks.Items[0].Name != "default" {
+		t.Fatalf("unexpected response: %+v", networks.Items)
+	}
+	if len(fake.calls) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(fake.calls))
+	}
+}
+
+func TestRetryTransportDoesNotRetryPermanentErrors(t *testing.T) {
+	fake := &scriptedTransport{statuses: []int{http.StatusForbidden, http.StatusOK}, body: `{"error": {"code": 403, "message": "denied"}}`}
+
+	_, err := newRetryTestCompute(t, fake).Networks.List("demo").Do()
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Fatalf("expected the 403 to be returned, got %v", err)
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("expected a single attempt, got %d", len(fake.calls))
+	}
+}
+
+func TestRetryTransportDoesNotRetryMutations(t *testing.T) {
+	fake := &scriptedTransport{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, body: `{}`}
+
+	_, err := newRetryTestCompute(t, fake).Networks.Delete("demo", "default").Do()
+	if err == nil {
+		t.Fatal("expected the 503 to be returned for a delete")
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("expected a single attempt, got %d", len(fake.calls))
+	}
+}
+
+func TestRetryTransportHonorsRetryAfter(t *testing.T) {
+	fake := &scriptedTransport{
+		statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+		headers:  []http.Header{{"Retry-After": []string{"1"}}},
+		body:     `{}`,
+	}
+
+	if _, err := newRetryTestCompute(t, fake).Networks.List("demo").Do(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.calls) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(fake.calls))
+	}
+	if waited := fake.calls[1].Sub(fake.calls[0]); waited < time.Second {
+		t.Fatalf("expected to wait the 1s Retry-After, waited %v", waited)
+	}
+}
+
+func TestRetryTransportGivesUpAfterMaxAttempts(t *testing.T) {
+	fake := &scriptedTransport{statuses: []int{503, 503, 503, 503, 503}, body: `{}`}
+
+	if _, err := newRetryTestCompute(t, fake).Networks.List("demo").Do(); err == nil {
+		t.Fatal("expected the last 503 to be returned")
+	}
+	if len(fake.calls) != 4 {
+		t.Fatalf("expected 1 attempt and 3 retries, got %d", len(fake.calls))
+	}
+}
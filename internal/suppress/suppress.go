@@ -0,0 +1,156 @@
+// Package suppress reads suppression files, which accept known findings of
+// security checks so they stop failing gates while still being reported.
+package suppress
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/terragrunt-gcp/terragrunt-gcp/internal/configschema"
+)
+
+// Suppression accepts the finding with ID, or the findings of Rule on
+// Resource. Resource may be a path.Match pattern such as
+// "google_storage_bucket.*".
+type Suppression struct {
+	ID       string `json:"id,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+	// Expires is a date (2006-01-02, the end of that day in UTC) or an
+	// RFC3339 time after which the suppression no longer applies
+	Expires       string `json:"expires,omitempty"`
+	Justification string `json:"justification,omitempty"`
+
+	expires time.Time
+}
+
+// File is the layout of a suppression file
+type File struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// List is a parsed suppression file; a nil List suppresses nothing
+type List struct {
+	suppressions []Suppression
+}
+
+// Load reads a JSON or YAML suppression file
+func Load(filename string) (*List, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppressions: %w", err)
+	}
+	list, err := Parse(data, configschema.DetectFormat(filename, ""))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return list, nil
+}
+
+// Parse parses suppression file data in format (json or yaml)
+func Parse(data []byte, format string) (*List, error) {
+	var file File
+	if err := configschema.DecodeFormat(data, format, &file); err != nil {
+		return nil, err
+	}
+	if format == configschema.FormatYAML {
+		// YAML reads an unquoted date as a midnight timestamp; take expiries
+		// as written so a date lasts through its day
+		var raw File
+		if err := yaml.Unmarshal(data, &raw); err == nil && len(raw.Suppressions) == len(file.Suppressions) {
+			for i := range file.Suppressions {
+				file.Suppressions[i].Expires = raw.Suppressions[i].Expires
+			}
+		}
+	}
+	for i := range file.Suppressions {
+		s := &file.Suppressions[i]
+		if s.ID == "" && (s.Resource == "" || s.Rule == "") {
+			return nil, fmt.Errorf("suppression %d: needs an id, or a resource and a rule", i+1)
+		}
+		if s.Resource != "" {
+			if _, err := path.Match(s.Resource, ""); err != nil {
+				return nil, fmt.Errorf("suppression %d: invalid resource pattern %q", i+1, s.Resource)
+			}
+		}
+		if s.Expires != "" {
+			expires, err := parseExpiry(s.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("suppression %d: %w", i+1, err)
+			}
+			s.expires = expires
+		}
+	}
+	return &List{suppressions: file.Suppressions}, nil
+}
+
+func parseExpiry(value string) (time.Time, error) {
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day.Add(24 * time.Hour), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expires %q (use 2006-01-02 or RFC3339)", value)
+	}
+	return t, nil
+}
+
+// Expired reports whether the suppression no longer applies at t
+func (s Suppression) Expired(t time.Time) bool {
+	return !s.expires.IsZero() && !t.Before(s.expires)
+}
+
+func (s Suppression) matches(id, resource, rule string) bool {
+	if s.ID != "" {
+		return s.ID == id
+	}
+	if s.Rule != rule {
+		return false
+	}
+	matched, _ := path.Match(s.Resource, resource)
+	return matched
+}
+
+// Match returns the suppression covering the finding with id, or of rule on
+// resource, at time t. Expired suppressions don't match.
+func (l *List) Match(id, resource, rule string, t time.Time) (Suppression, bool) {
+	if l == nil {
+		return Suppression{}, false
+	}
+	for _, s := range l.suppressions {
+		if !s.Expired(t) && s.matches(id, resource, rule) {
+			return s, true
+		}
+	}
+	return Suppression{}, false
+}
+
+// Expired returns the suppressions that have expired by t, to warn about
+func (l *List) Expired(t time.Time) []Suppression {
+	if l == nil {
+		return nil
+	}
+	var expired []Suppression
+	for _, s := range l.suppressions {
+		if s.Expired(t) {
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
+
+// String names the suppression in warnings
+func (s Suppression) String() string {
+	name := s.ID
+	if name == "" {
+		name = s.Rule + " on " + s.Resource
+	}
+	if s.Expires != "" {
+		name += " (expires " + s.Expires + ")"
+	}
+	return name
+}
@@ -0,0 +1,112 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSuppressions = `
+suppressions:
+  - id: finding-123
+    justification: false positive, the bucket holds public docs
+  - rule: public_buckets
+    resource: google_storage_bucket_iam_member.docs*
+    expires: 2026-06-30
+    justification: docs site, accepted by security until the CDN migration
+  - rule: allowed_regions
+    resource: google_compute_instance.legacy
+    expires: 2026-01-01T00:00:00Z
+`
+
+func TestMatch(t *testing.T) {
+	list, err := Parse([]byte(testSuppressions), "yaml")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	at := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	s, ok := list.Match("finding-123", "anything", "any-rule", at)
+	if !ok || !strings.HasPrefix(s.Justification, "false positive") {
+		t.Errorf("Match by id = %+v, %v", s, ok)
+	}
+	if _, ok := list.Match("", "google_storage_bucket_iam_member.docs_public", "public_buckets", at); !ok {
+		t.Error("resource pattern and rule should match")
+	}
+	if _, ok := list.Match("", "google_storage_bucket_iam_member.data", "public_buckets", at); ok {
+		t.Error("other resources should not match")
+	}
+	if _, ok := list.Match("", "google_storage_bucket_iam_member.docs", "required_labels", at); ok {
+		t.Error("other rules should not match")
+	}
+	// A date expiry lasts through that day
+	if _, ok := list.Match("", "google_storage_bucket_iam_member.docs", "public_buckets", at.Add(12*time.Hour)); ok {
+		t.Error("expired suppression matched")
+	}
+	if _, ok := list.Match("", "google_compute_instance.legacy", "allowed_regions", at); ok {
+		t.Error("expired suppression matched")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	list, err := Parse([]byte(testSuppressions), "yaml")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	expired := list.Expired(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(expired) != 1 || expired[0].Resource != "google_compute_instance.legacy" {
+		t.Fatalf("Expired = %+v", expired)
+	}
+	if got := expired[0].String(); got != "allowed_regions on google_compute_instance.legacy (expires 2026-01-01T00:00:00Z)" {
+		t.Errorf("String = %q", got)
+	}
+	if got := len(list.Expired(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))); got != 2 {
+		t.Errorf("Expired after June = %d suppressions, want 2", got)
+	}
+}
+
+func TestNilListSuppressesNothing(t *testing.T) {
+	var list *List
+	if _, ok := list.Match("id", "resource", "rule", time.Now()); ok {
+		t.Error("nil list matched")
+	}
+	if len(list.Expired(time.Now())) != 0 {
+		t.Error("nil list has expired suppressions")
+	}
+}
+
+func TestParseRejectsInvalidSuppressions(t *testing.T) {
+	tests := map[string]string{
+		"no target":    `{"suppressions": [{"rule": "public_buckets", "justification": "x"}]}`,
+		"bad expiry":   `{"suppressions": [{"id": "f1", "expires": "next year"}]}`,
+		"bad pattern":  `{"suppressions": [{"rule": "r", "resource": "bucket["}]}`,
+		"unknown key":  `{"suppressions": [{"id": "f1", "reason": "x"}]}`,
+		"invalid json": `{"suppressions": [`,
+	}
+	for name, data := range tests {
+		if _, err := Parse([]byte(data), "json"); err == nil {
+			t.Errorf("%s: Parse accepted %s", name, data)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suppressions.json")
+	if err := os.WriteFile(path, []byte(`{"suppressions": [{"id": "f1"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := list.Match("f1", "", "", time.Now()); !ok {
+		t.Error("loaded suppression did not match")
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
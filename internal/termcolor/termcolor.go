@@ -0,0 +1,130 @@
+// Package termcolor decides whether CLI output may carry ANSI colors and
+// emoji, and strips them from output when it may not.
+package termcolor
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ansiPattern matches CSI escape sequences such as color and cursor codes
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// Enabled reports whether colored and emoji output should be written to f.
+// It is disabled by the --no-color flag, a non-empty NO_COLOR environment
+// variable, TERM=dumb, or when f is not a terminal (e.g. redirected output).
+func Enabled(noColor bool, f *os.File) bool {
+	if noColor {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(f)
+}
+
+// IsTerminal reports whether f is attached to a character device
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Strip removes ANSI escape sequences and emoji from s. A single space
+// following a removed emoji is dropped too so "✅ done" becomes "done".
+func Strip(s string) string {
+	s = ansiPattern.ReplaceAllString(s, "")
+
+	var b strings.Builder
+	b.Grow(len(s))
+	skipSpace := false
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		if isEmoji(r) || isEmojiModifier(r) {
+			skipSpace = true
+			continue
+		}
+		if skipSpace && r == ' ' {
+			skipSpace = false
+			continue
+		}
+		skipSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isEmoji reports whether r falls in the pictograph and symbol blocks the
+// CLIs use as status icons
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x2300 && r <= 0x23FF:
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r == 0x2139:
+		return true
+	}
+	return false
+}
+
+// isEmojiModifier reports whether r is a joiner or presentation selector
+// that only has meaning next to an emoji
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x200D
+}
+
+// Writer strips colors and emoji from everything written through it when
+// color output is disabled
+type Writer struct {
+	w       io.Writer
+	enabled bool
+}
+
+// NewWriter wraps w. When enabled is true writes pass through unchanged.
+func NewWriter(w io.Writer, enabled bool) *Writer {
+	return &Writer{w: w, enabled: enabled}
+}
+
+// NewReportWriter wraps w for a report in format. Only the human-readable
+// text and table formats are stripped; json and the other machine-readable
+// formats always pass through, since their values are data.
+func NewReportWriter(w io.Writer, format string, enabled bool) *Writer {
+	if format != "text" && format != "table" {
+		enabled = true
+	}
+	return NewWriter(w, enabled)
+}
+
+// Enabled reports whether colors and emoji pass through the writer
+func (w *Writer) Enabled() bool {
+	return w.enabled
+}
+
+// Write implements io.Writer
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.enabled {
+		return w.w.Write(p)
+	}
+	if _, err := io.WriteString(w.w, Strip(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
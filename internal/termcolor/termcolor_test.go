@@ -0,0 +1,112 @@
+package termcolor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnabledHonorsNoColorEnv(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("TERM", "xterm-256color")
+
+	if Enabled(false, os.Stdout) {
+		t.Error("Enabled() should be false when NO_COLOR is set")
+	}
+}
+
+func TestEnabledHonorsFlag(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	if Enabled(true, os.Stdout) {
+		t.Error("Enabled() should be false when --no-color is set")
+	}
+}
+
+func TestEnabledFalseWhenRedirected(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm-256color")
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if Enabled(false, f) {
+		t.Error("Enabled() should be false for a regular file")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if Enabled(false, w) {
+		t.Error("Enabled() should be false for a pipe")
+	}
+}
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"✅ Deployment completed successfully", "Deployment completed successfully"},
+		{"  ⚠️ disk usage high", "  disk usage high"},
+		{"\x1b[31mfailed\x1b[0m", "failed"},
+		{"📊 Summary: 3 resources", "Summary: 3 resources"},
+		{"plain ascii text", "plain ascii text"},
+		{"ℹ️ info", "info"},
+	}
+
+	for _, tt := range tests {
+		if got := Strip(tt.in); got != tt.want {
+			t.Errorf("Strip(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false)
+
+	n, err := fmt.Fprintf(w, "🚀 Starting deployment for environment: %s\n", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len("🚀 Starting deployment for environment: dev\n") {
+		t.Errorf("Write() returned %d, want length of the original input", n)
+	}
+	if got := buf.String(); got != "Starting deployment for environment: dev\n" {
+		t.Errorf("disabled writer output = %q", got)
+	}
+
+	buf.Reset()
+	w = NewWriter(&buf, true)
+	fmt.Fprint(w, "✅ ok")
+	if got := buf.String(); got != "✅ ok" {
+		t.Errorf("enabled writer output = %q, want passthrough", got)
+	}
+}
+
+func TestReportWriterLeavesMachineFormatsAlone(t *testing.T) {
+	for _, format := range []string{"json", "html", "prometheus"} {
+		var buf bytes.Buffer
+		fmt.Fprint(NewReportWriter(&buf, format, false), `{"name": "🚀 launch"}`)
+		if got := buf.String(); got != `{"name": "🚀 launch"}` {
+			t.Errorf("%s report output = %q, want passthrough", format, got)
+		}
+	}
+	for _, format := range []string{"text", "table"} {
+		var buf bytes.Buffer
+		fmt.Fprint(NewReportWriter(&buf, format, false), "🚀 launch")
+		if got := buf.String(); got != "launch" {
+			t.Errorf("%s report output = %q, want stripped", format, got)
+		}
+	}
+}